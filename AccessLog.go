@@ -0,0 +1,202 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var InvalidAccessLogLineError = errors.New("invalid access log line")
+
+//AccessLogEntry is the part of an access log line used to find the most requested URLs
+type AccessLogEntry struct {
+	Method string
+	//Target is the request target, a path with query or an absolute URL
+	Target string
+	//Host is the requested host if the log records it
+	Host string
+	//Status is the status code of the response, 0 if the log does not record it
+	Status int
+}
+
+//combinedLogLine matches the common and the combined log format, the fields after the size are ignored
+var combinedLogLine = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]*\] "([^"]*)" (\d{3}|-) `)
+
+//ParseCombinedLog parses a line in the common or combined log format of Apache and nginx
+//
+//	127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /articles/1 HTTP/1.1" 200 2326 "-" "Mozilla/5.0"
+func ParseCombinedLog(line string) (AccessLogEntry, error) {
+
+	match := combinedLogLine.FindStringSubmatch(line + " ")
+	if match == nil {
+		return AccessLogEntry{}, InvalidAccessLogLineError
+	}
+	request := strings.Fields(match[1])
+	if len(request) < 2 {
+		return AccessLogEntry{}, fmt.Errorf("%w: request %q", InvalidAccessLogLineError, match[1])
+	}
+	status, _ := strconv.Atoi(match[2])
+	return AccessLogEntry{Method: request[0], Target: request[1], Status: status}, nil
+}
+
+//jsonLogFields are the field names ParseJSONLog reads the entry from, the first present name wins
+var jsonLogFields = struct {
+	method, target, host, status []string
+}{
+	method: []string{"method", "request_method", "http_method"},
+	target: []string{"url", "uri", "request_uri", "path"},
+	host:   []string{"host", "http_host", "server_name"},
+	status: []string{"status", "status_code", "response_status"},
+}
+
+//ParseJSONLog parses a line of a JSON lines access log, the field names used by nginx, Caddy and common logging
+//libraries are understood, e.g. {"method":"GET","uri":"/articles/1","host":"example.com","status":200}. A request
+//field holding "GET /articles/1 HTTP/1.1" is used if there is no method or URL field
+func ParseJSONLog(line string) (AccessLogEntry, error) {
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return AccessLogEntry{}, fmt.Errorf("%w: %v", InvalidAccessLogLineError, err)
+	}
+
+	entry := AccessLogEntry{
+		Method: jsonLogString(fields, jsonLogFields.method),
+		Target: jsonLogString(fields, jsonLogFields.target),
+		Host:   jsonLogString(fields, jsonLogFields.host),
+	}
+	if request := strings.Fields(jsonLogString(fields, []string{"request"})); len(request) >= 2 {
+		if entry.Method == "" {
+			entry.Method = request[0]
+		}
+		if entry.Target == "" {
+			entry.Target = request[1]
+		}
+	}
+	if entry.Target == "" {
+		return AccessLogEntry{}, fmt.Errorf("%w: no URL", InvalidAccessLogLineError)
+	}
+	if entry.Method == "" {
+		entry.Method = http.MethodGet
+	}
+	status := jsonLogString(fields, jsonLogFields.status)
+	entry.Status, _ = strconv.Atoi(status)
+	return entry, nil
+}
+
+//jsonLogString returns the value of the first of names present in fields as string
+func jsonLogString(fields map[string]interface{}, names []string) string {
+	for _, name := range names {
+		switch value := fields[name].(type) {
+		case string:
+			return value
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+	return ""
+}
+
+//AccessLogOptions selects how TopURLs reads an access log
+type AccessLogOptions struct {
+	//Parse parses a line, ParseCombinedLog if nil. Lines it fails for are skipped
+	Parse func(line string) (AccessLogEntry, error)
+	//BaseURL is the scheme and host of the request targets which are paths, e.g. https://example.com. If the log
+	//records the host only the scheme of BaseURL is used, https if BaseURL is empty
+	BaseURL string
+	//TopN is the number of URLs returned, all if 0
+	TopN int
+}
+
+//TopURLs returns the URLs of the GET and HEAD requests in the access log read from r which got a successful or
+//redirect response, the most requested first. Requests for the same URL with differently ordered query parameters
+//are counted together
+func TopURLs(r io.Reader, options AccessLogOptions) ([]string, error) {
+
+	parse := options.Parse
+	if parse == nil {
+		parse = ParseCombinedLog
+	}
+	base, err := url.Parse(options.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, err := parse(scanner.Text())
+		if err != nil {
+			continue
+		}
+		if entry.Method != http.MethodGet && entry.Method != http.MethodHead || entry.Status >= http.StatusBadRequest {
+			continue
+		}
+		if requestURL, ok := accessLogURL(entry, base); ok {
+			counts[requestURL]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0, len(counts))
+	for requestURL := range counts {
+		urls = append(urls, requestURL)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if counts[urls[i]] != counts[urls[j]] {
+			return counts[urls[i]] > counts[urls[j]]
+		}
+		return urls[i] < urls[j]
+	})
+	if options.TopN > 0 && len(urls) > options.TopN {
+		urls = urls[:options.TopN]
+	}
+	return urls, nil
+}
+
+//accessLogURL returns the absolute URL of entry with its query parameters sorted
+func accessLogURL(entry AccessLogEntry, base *url.URL) (string, bool) {
+
+	target, err := url.Parse(entry.Target)
+	if err != nil {
+		return "", false
+	}
+	if !target.IsAbs() {
+		switch {
+		case entry.Host != "":
+			scheme := base.Scheme
+			if scheme == "" {
+				scheme = "https"
+			}
+			target = (&url.URL{Scheme: scheme, Host: entry.Host}).ResolveReference(target)
+		case base.IsAbs():
+			target = base.ResolveReference(target)
+		default:
+			return "", false
+		}
+	}
+	target.RawQuery = target.Query().Encode()
+	target.Fragment = ""
+	return target.String(), true
+}
+
+//PrefetchAccessLog requests the TopURLs of the access log read from r, e.g. so a new deployment starts with the
+//content hot in production
+func (p *Prefetcher) PrefetchAccessLog(ctx context.Context, r io.Reader, options AccessLogOptions) ([]PrefetchResult, error) {
+	urls, err := TopURLs(r, options)
+	if err != nil {
+		return nil, err
+	}
+	return p.Prefetch(ctx, urls), nil
+}
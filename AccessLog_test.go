@@ -0,0 +1,124 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCombinedLog(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		line  string
+		entry AccessLogEntry
+		err   error
+	}{
+		{"combined", `127.0.0.1 - frank [10/Oct/2024:13:55:36 +0000] "GET /a?x=1 HTTP/1.1" 200 2326 "-" "Mozilla/5.0"`, AccessLogEntry{Method: "GET", Target: "/a?x=1", Status: 200}, nil},
+		{"common", `127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "HEAD /b HTTP/1.0" 304 -`, AccessLogEntry{Method: "HEAD", Target: "/b", Status: 304}, nil},
+		{"no status", `127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /c HTTP/1.1" - -`, AccessLogEntry{Method: "GET", Target: "/c"}, nil},
+		{"invalid request", `127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "-" 400 0`, AccessLogEntry{}, InvalidAccessLogLineError},
+		{"invalid", "not a log line", AccessLogEntry{}, InvalidAccessLogLineError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := ParseCombinedLog(test.line)
+			if !errors.Is(err, test.err) || entry != test.entry {
+				t.Error("expected", test.entry, test.err, "got", entry, err)
+			}
+		})
+	}
+}
+
+func TestParseJSONLog(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		line  string
+		entry AccessLogEntry
+		err   error
+	}{
+		{"fields", `{"method":"GET","uri":"/a","host":"example.com","status":200}`, AccessLogEntry{Method: "GET", Target: "/a", Host: "example.com", Status: 200}, nil},
+		{"request", `{"request":"GET /b HTTP/2.0","http_host":"example.com","status":"404"}`, AccessLogEntry{Method: "GET", Target: "/b", Host: "example.com", Status: 404}, nil},
+		{"no url", `{"method":"GET"}`, AccessLogEntry{}, InvalidAccessLogLineError},
+		{"invalid", `{"method":`, AccessLogEntry{}, InvalidAccessLogLineError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry, err := ParseJSONLog(test.line)
+			if !errors.Is(err, test.err) || entry != test.entry {
+				t.Error("expected", test.entry, test.err, "got", entry, err)
+			}
+		})
+	}
+}
+
+func TestTopURLs(t *testing.T) {
+
+	log := strings.Join([]string{
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /b HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /a?y=2&x=1 HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /a?x=1&y=2 HTTP/1.1" 304 -`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /c HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /c HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /c HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "POST /d HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "POST /d HTTP/1.1" 200 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /missing HTTP/1.1" 404 1`,
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /missing HTTP/1.1" 404 1`,
+		`garbage`,
+	}, "\n")
+
+	urls, err := TopURLs(strings.NewReader(log), AccessLogOptions{BaseURL: "https://example.com", TopN: 2})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := []string{"https://example.com/c", "https://example.com/a?x=1&y=2"}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Error("expected", expected, "got", urls)
+	}
+
+	if urls, _ := TopURLs(strings.NewReader(log), AccessLogOptions{}); len(urls) != 0 {
+		t.Error("expected no URLs without host got", urls)
+	}
+
+	jsonLog := `{"method":"GET","uri":"/a","host":"example.com","status":200}` + "\n" + `{"method":"GET","url":"http://other.com/b"}`
+	urls, err = TopURLs(strings.NewReader(jsonLog), AccessLogOptions{Parse: ParseJSONLog, BaseURL: "http://"})
+	expected = []string{"http://example.com/a", "http://other.com/b"}
+	if err != nil || !reflect.DeepEqual(urls, expected) {
+		t.Error("expected", expected, "got", urls, err)
+	}
+}
+
+func TestPrefetcher_PrefetchAccessLog(t *testing.T) {
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		writer.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer server.Close()
+
+	cache := NewMapCache()
+	client := &http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport}}
+	log := `127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /hot HTTP/1.1" 200 1` + "\n" +
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /hot HTTP/1.1" 200 1` + "\n" +
+		`127.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /cold HTTP/1.1" 200 1`
+
+	results, err := NewPrefetcher(client, 1).PrefetchAccessLog(context.Background(), strings.NewReader(log), AccessLogOptions{BaseURL: server.URL, TopN: 1})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(results) != 1 || results[0].Err != nil || !reflect.DeepEqual(paths, []string{"/hot"}) {
+		t.Error("expected the most requested URL to be prefetched got", results, paths)
+	}
+	if len(cache.Keys()) != 1 {
+		t.Error("expected the response to be cached got", len(cache.Keys()))
+	}
+}
@@ -0,0 +1,569 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//adminBodyPreviewLimit is the number of body bytes shown in the entry details
+const adminBodyPreviewLimit = 64 << 10
+
+//AdminHandler serves a single page admin UI and its JSON API for a cache, mount it with a trailing slash, e.g.
+//
+//	http.Handle("/debug/cache/", http.StripPrefix("/debug/cache", NewAdminHandler(cache)))
+//
+//The UI and all assets are part of the handler. The API is served below api/:
+//
+//	GET    api/keys?q=     keys containing q, a page of limit keys starting after cursor, see KeyPage
+//	GET    api/entry?key=  status, headers and body preview of an entry
+//	DELETE api/entry?key=  deletes an entry
+//	POST   api/purge?q=    deletes all keys containing q, all keys without q
+//	GET    api/stats       entry count, body bytes and entries per host and status
+//
+//With Authorize set, operations on a single key which are not allowed get 403 Forbidden and the others only include
+//the allowed keys
+type AdminHandler struct {
+	Cache Inspector
+	//Variants adds the variant counts per URL to the stats and forgets the variants of deleted keys if not nil
+	Variants *VariantTracker
+	//Authorize restricts the operations on the keys if not nil, see AdminRules
+	Authorize AdminAuthorizer
+}
+
+//NewAdminHandler creates an AdminHandler for cache, e.g. a MapCache, LRUCache or FileCache
+func NewAdminHandler(cache Inspector) *AdminHandler {
+	return &AdminHandler{Cache: cache}
+}
+
+type adminKey struct {
+	Key    string
+	Method string `json:",omitempty"`
+	Target string `json:",omitempty"`
+	Host   string `json:",omitempty"`
+}
+
+type adminEntry struct {
+	adminKey
+	Status       string
+	Proto        string
+	Header       http.Header
+	Body         string
+	BodyEncoding string
+	BodySize     int
+	Truncated    bool
+}
+
+//AdminStats summarizes the entries of a cache
+type AdminStats struct {
+	Entries int
+	//Bytes is the sum of the body sizes
+	Bytes int64
+	//Hosts and Statuses count the entries per host and status code
+	Hosts    map[string]int
+	Statuses map[string]int
+	//Variants are the variant counts of the VariantTracker if there is one
+	Variants map[string]int `json:",omitempty"`
+}
+
+func (a *AdminHandler) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/api/keys"):
+		if !allowMethods(writer, req, http.MethodGet) {
+			return
+		}
+		a.serveKeys(writer, req)
+	case strings.HasSuffix(req.URL.Path, "/api/entry"):
+		if !allowMethods(writer, req, http.MethodGet, http.MethodDelete) {
+			return
+		}
+		if req.Method == http.MethodDelete {
+			a.deleteEntry(writer, req)
+			return
+		}
+		a.serveEntry(writer, req)
+	case strings.HasSuffix(req.URL.Path, "/api/purge"):
+		if !allowMethods(writer, req, http.MethodPost) {
+			return
+		}
+		a.purge(writer, req)
+	case strings.HasSuffix(req.URL.Path, "/api/stats"):
+		if !allowMethods(writer, req, http.MethodGet) {
+			return
+		}
+		a.serveStats(writer, req)
+	default:
+		if !allowMethods(writer, req, http.MethodGet, http.MethodHead) {
+			return
+		}
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+		_, _ = io.WriteString(writer, adminPage)
+	}
+}
+
+//allowMethods reports if req uses one of methods, otherwise it answers with 405 Method Not Allowed
+func allowMethods(writer http.ResponseWriter, req *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if req.Method == method {
+			return true
+		}
+	}
+	writer.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+//serveKeys serves a page of the matching keys, the Link header points to the following page and X-Total-Count
+//estimates the number of keys of all pages
+func (a *AdminHandler) serveKeys(writer http.ResponseWriter, req *http.Request) {
+
+	query := req.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			http.Error(writer, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	page, err := pageKeys(a.Authorize.keys(req.Context(), AdminListOperation, matchingKeys(a.Cache, query.Get("q"))), query.Get("cursor"), limit)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	keys := make([]adminKey, 0, len(page.Keys))
+	for _, key := range page.Keys {
+		keys = append(keys, summarizeKey(key))
+	}
+	if page.Next != "" {
+		next := url.Values{"q": {query.Get("q")}, "cursor": {page.Next}}
+		if limit > 0 {
+			next.Set("limit", strconv.Itoa(limit))
+		}
+		//relative to api/keys so it works at any mount path
+		writer.Header().Set("Link", "<keys?"+next.Encode()+`>; rel="next"`)
+	}
+	writer.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	writeJSON(writer, http.StatusOK, keys)
+}
+
+func (a *AdminHandler) serveEntry(writer http.ResponseWriter, req *http.Request) {
+
+	key := req.URL.Query().Get("key")
+	if !a.Authorize.allows(req.Context(), AdminGetOperation, key) {
+		http.Error(writer, AdminForbiddenError.Error(), http.StatusForbidden)
+		return
+	}
+	res, err := a.Cache.GetKey(key)
+	if err == NotInCacheError {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var body []byte
+	if res.Body != nil {
+		body, err = ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	entry := adminEntry{
+		adminKey: summarizeKey(key),
+		Status:   res.Status,
+		Proto:    res.Proto,
+		Header:   res.Header,
+		BodySize: len(body),
+	}
+	if len(body) > adminBodyPreviewLimit {
+		body = body[:adminBodyPreviewLimit]
+		entry.Truncated = true
+	}
+	if utf8.Valid(body) {
+		entry.Body, entry.BodyEncoding = string(body), "text"
+	} else {
+		entry.Body, entry.BodyEncoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+
+	writeJSON(writer, http.StatusOK, entry)
+}
+
+func (a *AdminHandler) deleteEntry(writer http.ResponseWriter, req *http.Request) {
+
+	key := req.URL.Query().Get("key")
+	if !a.Authorize.allows(req.Context(), AdminDeleteOperation, key) {
+		http.Error(writer, AdminForbiddenError.Error(), http.StatusForbidden)
+		return
+	}
+	err := a.Cache.DeleteKey(key)
+	if err == nil {
+		a.Variants.Evicted(key)
+	}
+	if err == NotInCacheError {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AdminHandler) purge(writer http.ResponseWriter, req *http.Request) {
+
+	keys := a.Authorize.keys(req.Context(), AdminPurgeOperation, matchingKeys(a.Cache, req.URL.Query().Get("q")))
+	deleted, err := deleteKeys(a.Cache, a.Variants, keys)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(writer, http.StatusOK, map[string]int{"Deleted": deleted})
+}
+
+func (a *AdminHandler) serveStats(writer http.ResponseWriter, req *http.Request) {
+	writeJSON(writer, http.StatusOK, authorizedStats(req.Context(), a.Authorize, a.Cache, a.Variants))
+}
+
+//authorizedStats summarizes the entries of cache authorize allows AdminStatsOperation on, the variant counts are
+//added if it is allowed on the empty key
+func authorizedStats(ctx context.Context, authorize AdminAuthorizer, cache Inspector, variants *VariantTracker) AdminStats {
+	if !authorize.allows(ctx, AdminStatsOperation, "") {
+		variants = nil
+	}
+	return cacheStats(cache, authorize.keys(ctx, AdminStatsOperation, cache.Keys()), variants)
+}
+
+//cacheStats reads the entries of keys to summarize them, caches with ByteAccountingCapability are peeked without
+//reading the bodies
+func cacheStats(cache Inspector, keys []string, variants *VariantTracker) AdminStats {
+
+	stats := AdminStats{Hosts: map[string]int{}, Statuses: map[string]int{}}
+
+	peeker, peek := cache.(Peeker)
+	if cacher, ok := cache.(Cacher); ok && peek {
+		peek = CapabilitiesOf(cacher).Has(ByteAccountingCapability)
+	}
+	for _, key := range keys {
+		var res *http.Response
+		size := int64(-1)
+		var err error
+		if peek {
+			res, size, _, err = peeker.PeekKey(key)
+		} else {
+			res, err = cache.GetKey(key)
+		}
+		if err != nil {
+			//the entry was deleted since listing the keys
+			continue
+		}
+		stats.Entries++
+		if size >= 0 {
+			stats.Bytes += size
+		} else if res.Body != nil {
+			size, _ := io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+			stats.Bytes += size
+		}
+		host := summarizeKey(key).Host
+		if host == "" {
+			host = "unknown"
+		}
+		stats.Hosts[host]++
+		stats.Statuses[strconv.Itoa(res.StatusCode)]++
+	}
+
+	if variants != nil {
+		stats.Variants = variants.Counts()
+	}
+
+	return stats
+}
+
+//CacheStats reads all entries of cache to summarize them like the stats of AdminHandler
+func CacheStats(cache Inspector) AdminStats {
+	return cacheStats(cache, cache.Keys(), nil)
+}
+
+//PurgeExpired deletes the entries which have been stale for longer than maxStale and returns their number, shared
+//selects the freshness rules of a shared cache. Entries without expiration are kept
+func PurgeExpired(cache Inspector, shared bool, maxStale time.Duration) (int, error) {
+	return PurgeExpiredWithClock(cache, shared, maxStale, nil)
+}
+
+//PurgeExpiredWithClock deletes the entries like PurgeExpired at the time of clock, SystemClock if nil
+func PurgeExpiredWithClock(cache Inspector, shared bool, maxStale time.Duration, clock Clock) (int, error) {
+	deleted, _, err := purgeExpired(cache, shared, maxStale, clockOrDefault(clock).Now(), nil)
+	return deleted, err
+}
+
+//purgeExpired deletes the entries like PurgeExpired at now and returns their number and the size of their bodies,
+//entries of unknown size are not counted. The entries of a Peeker are checked without reading them
+func purgeExpired(cache Inspector, shared bool, maxStale time.Duration, now time.Time, variants *VariantTracker) (int, int64, error) {
+
+	peeker, _ := cache.(Peeker)
+	deadline := now.Add(-maxStale)
+	deleted := 0
+	var reclaimed int64
+	for _, key := range cache.Keys() {
+		var res *http.Response
+		size := int64(-1)
+		var err error
+		if peeker != nil {
+			res, size, _, err = peeker.PeekKey(key)
+		} else {
+			res, err = cache.GetKey(key)
+			if err == nil {
+				size = res.ContentLength
+			}
+		}
+		if err == NotInCacheError {
+			continue
+		}
+		if err != nil {
+			return deleted, reclaimed, err
+		}
+		if res.Body != nil {
+			_ = res.Body.Close()
+		}
+		if policy.IsFresh(res, shared, deadline) {
+			continue
+		}
+		err = cache.DeleteKey(key)
+		if err == NotInCacheError {
+			continue
+		}
+		if err != nil {
+			return deleted, reclaimed, err
+		}
+		variants.Evicted(key)
+		deleted++
+		if size > 0 {
+			reclaimed += size
+		}
+	}
+	return deleted, reclaimed, nil
+}
+
+//deleteKeys deletes keys and returns how many were deleted, keys deleted meanwhile are not counted. The variants of
+//the deleted keys are forgotten by variants
+func deleteKeys(cache Inspector, variants *VariantTracker, keys []string) (int, error) {
+
+	deleted := 0
+	for _, key := range keys {
+		err := cache.DeleteKey(key)
+		if err == NotInCacheError {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		variants.Evicted(key)
+		deleted++
+	}
+	return deleted, nil
+}
+
+//matchingKeys returns the keys containing query ignoring the case, all keys for an empty query
+func matchingKeys(cache Inspector, query string) []string {
+
+	keys := cache.Keys()
+	if query == "" {
+		return keys
+	}
+
+	query = strings.ToLower(query)
+	var matching []string
+	for _, key := range keys {
+		if strings.Contains(strings.ToLower(key), query) {
+			matching = append(matching, key)
+		}
+	}
+	return matching
+}
+
+//summarizeKey extracts the method, request target and host of keys in the request dump format, other keys are
+//returned without them
+//SummarizeKey returns the method, request target and host of a key in the request dump format, empty strings for
+//other keys
+func SummarizeKey(key string) (method string, target string, host string) {
+	summary := summarizeKey(key)
+	return summary.Method, summary.Target, summary.Host
+}
+
+func summarizeKey(key string) adminKey {
+
+	summary := adminKey{Key: key}
+
+	lines := strings.Split(key, "\r\n")
+	if len(lines) < 2 {
+		return summary
+	}
+	requestLine := strings.Fields(lines[0])
+	if len(requestLine) < 2 {
+		return summary
+	}
+	summary.Method, summary.Target = requestLine[0], requestLine[1]
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "Host: ") {
+			summary.Host = strings.TrimPrefix(line, "Host: ")
+			break
+		}
+	}
+	return summary
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(value)
+}
+
+//adminPage is the admin UI, it only uses relative URLs so the handler can be mounted at any path
+const adminPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Cache admin</title>
+<style>
+body{font-family:sans-serif;margin:0;display:flex;height:100vh}
+#side{width:45%;overflow:auto;border-right:1px solid #ccc;padding:8px}
+#main{flex:1;overflow:auto;padding:8px}
+table{border-collapse:collapse;width:100%;font-size:13px}
+td{padding:2px 4px;border-bottom:1px solid #eee;cursor:pointer;word-break:break-all}
+tr:hover{background:#f0f4ff}
+pre{background:#f6f6f6;padding:6px;white-space:pre-wrap;word-break:break-all}
+.bar{background:#4a7bd0;height:12px;display:inline-block;vertical-align:middle}
+.chart td{cursor:default}
+</style>
+</head>
+<body>
+<div id="side">
+<input id="q" placeholder="search keys" size="30">
+<button id="search">Search</button>
+<button id="purge">Purge matching</button>
+<span id="count"></span>
+<table id="keys"></table>
+<button id="more" style="display: none">More</button>
+</div>
+<div id="main">
+<h3>Stats</h3>
+<div id="stats"></div>
+<h3>Entry</h3>
+<div id="entry">Select a key</div>
+</div>
+<script>
+function el(tag, text) {
+	var e = document.createElement(tag);
+	if (text !== undefined) e.textContent = text;
+	return e;
+}
+function chart(title, counts) {
+	var box = el("div"), table = el("table"), max = 0, names = Object.keys(counts || {});
+	table.className = "chart";
+	names.sort(function (a, b) { return counts[b] - counts[a] || (a < b ? -1 : 1); });
+	names.forEach(function (n) { max = Math.max(max, counts[n]); });
+	names.slice(0, 20).forEach(function (n) {
+		var tr = el("tr"), bar = el("span");
+		bar.className = "bar";
+		bar.style.width = Math.round(200 * counts[n] / max) + "px";
+		tr.appendChild(el("td", n));
+		var td = el("td");
+		td.appendChild(bar);
+		td.appendChild(document.createTextNode(" " + counts[n]));
+		tr.appendChild(td);
+		table.appendChild(tr);
+	});
+	box.appendChild(el("h4", title));
+	box.appendChild(table);
+	return box;
+}
+function loadStats() {
+	fetch("api/stats").then(function (r) { return r.json(); }).then(function (s) {
+		var stats = document.getElementById("stats");
+		stats.textContent = s.Entries + " entries, " + s.Bytes + " body bytes";
+		stats.appendChild(chart("Entries per host", s.Hosts));
+		stats.appendChild(chart("Entries per status", s.Statuses));
+		if (s.Variants) stats.appendChild(chart("Variants per URL", s.Variants));
+	});
+}
+function loadKeys(cursor) {
+	var q = document.getElementById("q").value, next = null, total = "";
+	var path = "api/keys?q=" + encodeURIComponent(q) + (cursor ? "&cursor=" + encodeURIComponent(cursor) : "");
+	fetch(path).then(function (r) {
+		var link = /cursor=([^&>]*)/.exec(r.headers.get("Link") || "");
+		next = link ? decodeURIComponent(link[1]) : null;
+		total = r.headers.get("X-Total-Count") || "";
+		return r.json();
+	}).then(function (keys) {
+		var table = document.getElementById("keys"), more = document.getElementById("more");
+		if (!cursor) table.textContent = "";
+		table.shown = (cursor ? table.shown : 0) + keys.length;
+		document.getElementById("count").textContent = table.shown + " of " + total + " keys";
+		more.style.display = next ? "" : "none";
+		more.onclick = function () { loadKeys(next); };
+		keys.forEach(function (k) {
+			var tr = el("tr");
+			tr.appendChild(el("td", k.Method || ""));
+			tr.appendChild(el("td", k.Host ? k.Host + k.Target : k.Key));
+			tr.onclick = function () { loadEntry(k.Key); };
+			table.appendChild(tr);
+		});
+	});
+}
+function loadEntry(key) {
+	fetch("api/entry?key=" + encodeURIComponent(key)).then(function (r) { return r.json(); }).then(function (e) {
+		var entry = document.getElementById("entry"), del = el("button", "Delete entry");
+		entry.textContent = "";
+		entry.appendChild(el("pre", e.Key));
+		entry.appendChild(el("p", e.Proto + " " + e.Status + ", " + e.BodySize + " body bytes" + (e.Truncated ? " (preview truncated)" : "")));
+		del.onclick = function () {
+			if (!confirm("Delete this entry?")) return;
+			fetch("api/entry?key=" + encodeURIComponent(key), {method: "DELETE"}).then(refresh);
+			entry.textContent = "Deleted";
+		};
+		entry.appendChild(del);
+		var headers = Object.keys(e.Header || {}).sort().map(function (n) {
+			return e.Header[n].map(function (v) { return n + ": " + v; }).join("\n");
+		}).join("\n");
+		entry.appendChild(el("h4", "Headers"));
+		entry.appendChild(el("pre", headers));
+		entry.appendChild(el("h4", "Body (" + e.BodyEncoding + ")"));
+		entry.appendChild(el("pre", e.Body));
+	});
+}
+function refresh() {
+	loadKeys();
+	loadStats();
+}
+document.getElementById("search").onclick = function () { loadKeys(); };
+document.getElementById("q").onkeydown = function (ev) { if (ev.key === "Enter") loadKeys(); };
+document.getElementById("purge").onclick = function () {
+	var q = document.getElementById("q").value;
+	if (!confirm(q ? "Delete all keys containing \"" + q + "\"?" : "Delete ALL entries?")) return;
+	fetch("api/purge?q=" + encodeURIComponent(q), {method: "POST"}).then(refresh);
+};
+refresh();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,93 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+//AdminOperation names the operations of AdminHandler and AdminService checked by an AdminAuthorizer
+type AdminOperation string
+
+const (
+	//AdminListOperation lists a key
+	AdminListOperation AdminOperation = "list"
+	//AdminGetOperation reads the entry of a key
+	AdminGetOperation AdminOperation = "get"
+	//AdminPutOperation stores an entry under a key
+	AdminPutOperation AdminOperation = "put"
+	//AdminDeleteOperation deletes a single key
+	AdminDeleteOperation AdminOperation = "delete"
+	//AdminPurgeOperation deletes a key matched by a query, the purge of AdminHandler and Invalidate of AdminService
+	AdminPurgeOperation AdminOperation = "purge"
+	//AdminExportOperation exports the entry of a key
+	AdminExportOperation AdminOperation = "export"
+	//AdminStatsOperation summarizes the entry of a key in the stats, the empty key stands for the variant counts
+	AdminStatsOperation AdminOperation = "stats"
+)
+
+//AdminForbiddenError is returned by AdminService for operations its Authorize does not allow
+var AdminForbiddenError = errors.New("admin operation not allowed")
+
+//AdminAuthorizer reports if operation is allowed on key. ctx is the context of the request to AdminHandler or the one
+//given to AdminService.WithContext, e.g. holding the identity of the caller. Operations on many keys like listing,
+//purging, exporting and the stats only include the allowed keys
+type AdminAuthorizer func(ctx context.Context, operation AdminOperation, key string) bool
+
+//AdminRule allows Operations, all if empty, on the keys matching Keys, all if nil
+type AdminRule struct {
+	Operations []AdminOperation
+	Keys       *regexp.Regexp
+}
+
+//AdminRules returns an AdminAuthorizer allowing the operations allowed by one of rules, e.g. to restrict purging to
+//the keys of one host
+//
+//	AdminRules(
+//		AdminRule{Operations: []AdminOperation{AdminListOperation, AdminGetOperation, AdminStatsOperation}},
+//		AdminRule{Operations: []AdminOperation{AdminPurgeOperation}, Keys: regexp.MustCompile(`\r\nHost: api\.example\.com\r\n`)},
+//	)
+func AdminRules(rules ...AdminRule) AdminAuthorizer {
+	return func(ctx context.Context, operation AdminOperation, key string) bool {
+		for _, rule := range rules {
+			if rule.allows(operation, key) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (r AdminRule) allows(operation AdminOperation, key string) bool {
+	if r.Keys != nil && !r.Keys.MatchString(key) {
+		return false
+	}
+	if len(r.Operations) == 0 {
+		return true
+	}
+	for _, allowed := range r.Operations {
+		if allowed == operation {
+			return true
+		}
+	}
+	return false
+}
+
+//allows reports if operation is allowed on key, everything is allowed without an authorizer
+func (authorize AdminAuthorizer) allows(ctx context.Context, operation AdminOperation, key string) bool {
+	return authorize == nil || authorize(ctx, operation, key)
+}
+
+//keys returns the keys operation is allowed on
+func (authorize AdminAuthorizer) keys(ctx context.Context, operation AdminOperation, keys []string) []string {
+	if authorize == nil {
+		return keys
+	}
+	var allowed []string
+	for _, key := range keys {
+		if authorize(ctx, operation, key) {
+			allowed = append(allowed, key)
+		}
+	}
+	return allowed
+}
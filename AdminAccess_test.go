@@ -0,0 +1,134 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAdminRules(t *testing.T) {
+
+	authorize := AdminRules(
+		AdminRule{Operations: []AdminOperation{AdminListOperation, AdminGetOperation}},
+		AdminRule{Operations: []AdminOperation{AdminPurgeOperation}, Keys: regexp.MustCompile(`/public/`)},
+		AdminRule{Keys: regexp.MustCompile(`/admin/`)},
+	)
+
+	tests := []struct {
+		operation AdminOperation
+		key       string
+		expected  bool
+	}{
+		{AdminListOperation, "GET /private/a", true},
+		{AdminGetOperation, "GET /private/a", true},
+		{AdminPurgeOperation, "GET /private/a", false},
+		{AdminPurgeOperation, "GET /public/a", true},
+		{AdminDeleteOperation, "GET /public/a", false},
+		{AdminDeleteOperation, "GET /admin/a", true},
+		{AdminStatsOperation, "", false},
+	}
+	for _, tt := range tests {
+		if allowed := authorize(context.Background(), tt.operation, tt.key); allowed != tt.expected {
+			t.Errorf("%s %q allowed = %v, want %v", tt.operation, tt.key, allowed, tt.expected)
+		}
+	}
+}
+
+type adminCallerContextKey struct{}
+
+func TestAdminService_Authorize(t *testing.T) {
+
+	service := NewAdminService(NewLRUCache(LRUCacheOptions{}))
+	keys := map[string]string{}
+	for _, path := range []string{"/public/a", "/private/b"} {
+		req, err := http.NewRequest("GET", "http://example.com"+path, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("body"))}
+		if keys[path], err = service.Put(req, res); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	//the operator may do everything, other callers only read the public keys
+	service.Authorize = func(ctx context.Context, operation AdminOperation, key string) bool {
+		if ctx.Value(adminCallerContextKey{}) == "operator" {
+			return true
+		}
+		return operation != AdminPurgeOperation && strings.Contains(key, "/public/")
+	}
+	guest := service.WithContext(context.WithValue(context.Background(), adminCallerContextKey{}, "guest"))
+	operator := service.WithContext(context.WithValue(context.Background(), adminCallerContextKey{}, "operator"))
+
+	if listed := guest.List(""); len(listed) != 1 || listed[0] != keys["/public/a"] {
+		t.Error("expected the guest to list the public key, got", listed)
+	}
+	if _, err := guest.Get(keys["/private/b"]); !errors.Is(err, AdminForbiddenError) {
+		t.Error("expected AdminForbiddenError, got", err)
+	}
+	if stats := guest.Stats(); stats.Entries != 1 {
+		t.Error("expected the stats of the public entry, got", stats)
+	}
+	exported := 0
+	_ = guest.Export("", func(key string, res *http.Response) error {
+		exported++
+		return nil
+	})
+	if exported != 1 {
+		t.Error("expected the guest to export 1 entry, got", exported)
+	}
+	if deleted, err := guest.InvalidateAll(); err != nil || deleted != 0 {
+		t.Error("expected the guest not to invalidate entries, got", deleted, err)
+	}
+	if deleted, err := operator.InvalidateAll(); err != nil || deleted != 2 {
+		t.Error("expected the operator to invalidate all entries, got", deleted, err)
+	}
+}
+
+func TestAdminHandler_Authorize(t *testing.T) {
+
+	cache := NewLRUCache(LRUCacheOptions{})
+	keys := map[string]string{}
+	for _, path := range []string{"/public/a", "/private/b"} {
+		req := lruTestRequest(t, path)
+		if err := cache.Set(req, lruTestResponse("body")); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		keys[path], _ = cache.Key(req)
+	}
+
+	handler := NewAdminHandler(cache)
+	handler.Authorize = AdminRules(
+		AdminRule{Operations: []AdminOperation{AdminListOperation, AdminGetOperation}},
+		AdminRule{Operations: []AdminOperation{AdminPurgeOperation, AdminDeleteOperation}, Keys: regexp.MustCompile(`/public/`)},
+	)
+
+	do := func(method string, path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, "http://admin"+path, nil))
+		return recorder.Code
+	}
+
+	if code := do("GET", "/api/entry?key="+url.QueryEscape(keys["/private/b"])); code != http.StatusOK {
+		t.Error("expected the entry to be readable, got", code)
+	}
+	if code := do("DELETE", "/api/entry?key="+url.QueryEscape(keys["/private/b"])); code != http.StatusForbidden {
+		t.Error("expected deleting the private entry to be forbidden, got", code)
+	}
+	if code := do("POST", "/api/purge"); code != http.StatusOK {
+		t.Error("expected the purge to succeed, got", code)
+	}
+	if remaining := cache.Keys(); len(remaining) != 1 || remaining[0] != keys["/private/b"] {
+		t.Error("expected the purge to delete only the public entry, got", remaining)
+	}
+}
@@ -0,0 +1,66 @@
+package CachedHttpClient
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+)
+
+//DefaultAdminPageSize is the number of keys of a page without a limit, MaxAdminPageSize caps the limit
+const (
+	DefaultAdminPageSize = 1000
+	MaxAdminPageSize     = 10000
+)
+
+var InvalidCursorError = errors.New("invalid cursor")
+
+//KeyPage is a page of keys listed by AdminService.ListPage or the keys API of AdminHandler
+type KeyPage struct {
+	//Keys are sorted, the pages continue where the previous page ended so they stay stable while entries are stored
+	//or deleted
+	Keys []string
+	//Next is the cursor of the following page, empty on the last page
+	Next string
+	//Total estimates the number of keys of all pages, it is counted at the time of the page and changes with the
+	//entries stored or deleted while paging
+	Total int
+}
+
+//pageKeys returns the page of keys following cursor with up to limit keys, DefaultAdminPageSize for a limit of 0.
+//The cursor is the last key of the previous page, keys stored before it after it was handed out are skipped
+func pageKeys(keys []string, cursor string, limit int) (KeyPage, error) {
+
+	switch {
+	case limit <= 0:
+		limit = DefaultAdminPageSize
+	case limit > MaxAdminPageSize:
+		limit = MaxAdminPageSize
+	}
+
+	if !sort.StringsAreSorted(keys) {
+		keys = append([]string(nil), keys...)
+		sort.Strings(keys)
+	}
+
+	start := 0
+	if cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil || len(after) == 0 {
+			return KeyPage{}, InvalidCursorError
+		}
+		//the first key after the last key of the previous page
+		start = sort.Search(len(keys), func(i int) bool {
+			return keys[i] > string(after)
+		})
+	}
+
+	page := KeyPage{Keys: []string{}, Total: len(keys)}
+	end := start + limit
+	if end >= len(keys) {
+		end = len(keys)
+	} else {
+		page.Next = base64.RawURLEncoding.EncodeToString([]byte(keys[end-1]))
+	}
+	page.Keys = append(page.Keys, keys[start:end]...)
+	return page, nil
+}
@@ -0,0 +1,126 @@
+package CachedHttpClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPageKeys(t *testing.T) {
+
+	keys := []string{"e", "a", "d", "c", "b"}
+
+	var pages [][]string
+	cursor := ""
+	for {
+		page, err := pageKeys(keys, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 5 {
+			t.Error("expected a total of 5, got", page.Total)
+		}
+		pages = append(pages, page.Keys)
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+	if fmt.Sprint(pages) != "[[a b] [c d] [e]]" {
+		t.Error("wrong pages", pages)
+	}
+
+	//keys stored and deleted while paging neither repeat nor skip the others
+	first, _ := pageKeys(keys, "", 2)
+	page, err := pageKeys([]string{"a", "aa", "c", "d", "e", "f"}, first.Next, 2)
+	if err != nil || fmt.Sprint(page.Keys) != "[c d]" || page.Total != 6 {
+		t.Error("wrong page after changes", page, err)
+	}
+
+	if page, _ := pageKeys(keys, "", 0); len(page.Keys) != 5 || page.Next != "" {
+		t.Error("expected all keys on the default page", page)
+	}
+	if page, _ := pageKeys(nil, "", 0); page.Keys == nil || len(page.Keys) != 0 {
+		t.Error("expected an empty page", page)
+	}
+	if _, err := pageKeys(keys, "not base64!", 2); err != InvalidCursorError {
+		t.Error("expected InvalidCursorError, got", err)
+	}
+}
+
+func TestAdminHandler_KeysPages(t *testing.T) {
+
+	cache := NewMapCache()
+	for i := 0; i < 5; i++ {
+		if err := cache.Set(lruTestRequest(t, fmt.Sprint("/", i)), lruTestResponse("body")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	admin := httptest.NewServer(http.StripPrefix("/debug/cache", NewAdminHandler(cache)))
+	defer admin.Close()
+
+	var targets []string
+	next := admin.URL + "/debug/cache/api/keys?q=example&limit=2"
+	for next != "" {
+		res, err := http.Get(next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []adminKey
+		err = json.NewDecoder(res.Body).Decode(&keys)
+		_ = res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total := res.Header.Get("X-Total-Count"); total != "5" {
+			t.Error("expected a total of 5, got", total)
+		}
+		for _, key := range keys {
+			targets = append(targets, key.Target)
+		}
+
+		next = ""
+		if link := res.Header.Get("Link"); link != "" {
+			if !strings.HasPrefix(link, "<keys?") || !strings.HasSuffix(link, `>; rel="next"`) {
+				t.Fatal("wrong Link header", link)
+			}
+			next = admin.URL + "/debug/cache/api/" + strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+	}
+	if strings.Join(targets, ",") != "/0,/1,/2,/3,/4" {
+		t.Error("wrong keys", targets)
+	}
+
+	for _, query := range []string{"limit=x", "cursor=%21"} {
+		res, err := http.Get(admin.URL + "/debug/cache/api/keys?" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Error("expected 400 for", query, "got", res.StatusCode)
+		}
+	}
+}
+
+func TestAdminService_ListPage(t *testing.T) {
+
+	service := NewAdminService(NewLRUCache(LRUCacheOptions{}))
+	for _, path := range []string{"/a", "/b", "/c"} {
+		if _, err := service.Put(lruTestRequest(t, path), lruTestResponse("body")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := service.ListPage("", "", 2)
+	if err != nil || len(page.Keys) != 2 || page.Next == "" || page.Total != 3 {
+		t.Fatal("wrong first page", page, err)
+	}
+	last, err := service.ListPage("", page.Next, 2)
+	if err != nil || len(last.Keys) != 1 || last.Next != "" || !strings.Contains(last.Keys[0], "/c ") {
+		t.Error("wrong last page", last, err)
+	}
+}
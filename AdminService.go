@@ -0,0 +1,128 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+//AdminPurgeAllError is returned by AdminService.Invalidate for an empty query, InvalidateAll deletes all keys
+var AdminPurgeAllError = errors.New("invalidating all keys requires InvalidateAll")
+
+//AdminCache is a cache which can be managed through AdminService, e.g. a MapCache, LRUCache or FileCache
+type AdminCache interface {
+	Cacher
+	Keyer
+	Inspector
+}
+
+//AdminService implements the cache management API described in proto/cache_admin.proto independent of the
+//transport, the gRPC server of the grpcadmin module delegates each call to the method with the same name.
+//The Export stream is passed as callback. With Authorize set, the server calls the methods of WithContext(ctx) with the
+//context of the call, operations on a single key which are not allowed return AdminForbiddenError and the others only
+//include the allowed keys
+type AdminService struct {
+	Cache AdminCache
+	//Variants adds the variant counts per URL to the stats and forgets the variants of deleted keys if not nil
+	Variants *VariantTracker
+	//Authorize restricts the operations on the keys if not nil, see AdminRules
+	Authorize AdminAuthorizer
+
+	ctx context.Context
+}
+
+//NewAdminService creates an AdminService for cache
+func NewAdminService(cache AdminCache) *AdminService {
+	return &AdminService{Cache: cache}
+}
+
+//WithContext returns a copy of the service passing ctx to Authorize
+func (s *AdminService) WithContext(ctx context.Context) *AdminService {
+	service := *s
+	service.ctx = ctx
+	return &service
+}
+
+func (s *AdminService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+//List returns the keys containing query ignoring the case, all keys for an empty query
+func (s *AdminService) List(query string) []string {
+	return s.Authorize.keys(s.context(), AdminListOperation, matchingKeys(s.Cache, query))
+}
+
+//ListPage returns the page of the keys containing query which follows cursor with up to limit keys, the first page
+//for an empty cursor and DefaultAdminPageSize keys for a limit of 0. Pass KeyPage.Next as cursor of the next page
+func (s *AdminService) ListPage(query string, cursor string, limit int) (KeyPage, error) {
+	return pageKeys(s.List(query), cursor, limit)
+}
+
+//Get returns the response stored under key, NotInCacheError if there is none
+func (s *AdminService) Get(key string) (*http.Response, error) {
+	if !s.Authorize.allows(s.context(), AdminGetOperation, key) {
+		return nil, AdminForbiddenError
+	}
+	return s.Cache.GetKey(key)
+}
+
+//Put stores res for req and returns the key it is stored under
+func (s *AdminService) Put(req *http.Request, res *http.Response) (string, error) {
+
+	key, err := s.Cache.Key(req)
+	if err != nil {
+		return "", err
+	}
+	if !s.Authorize.allows(s.context(), AdminPutOperation, key) {
+		return "", AdminForbiddenError
+	}
+	if res.Request == nil {
+		res.Request = req
+	}
+	return key, s.Cache.Set(req, res)
+}
+
+//Invalidate deletes the keys containing query and returns how many were deleted, AdminPurgeAllError for an empty
+//query
+func (s *AdminService) Invalidate(query string) (int, error) {
+	if query == "" {
+		return 0, AdminPurgeAllError
+	}
+	return deleteKeys(s.Cache, s.Variants, s.Authorize.keys(s.context(), AdminPurgeOperation, matchingKeys(s.Cache, query)))
+}
+
+//InvalidateAll deletes all keys and returns how many were deleted
+func (s *AdminService) InvalidateAll() (int, error) {
+	return deleteKeys(s.Cache, s.Variants, s.Authorize.keys(s.context(), AdminPurgeOperation, matchingKeys(s.Cache, "")))
+}
+
+//Stats summarizes the entries of the cache
+func (s *AdminService) Stats() AdminStats {
+	return authorizedStats(s.context(), s.Authorize, s.Cache, s.Variants)
+}
+
+//Export calls send with the entries of the keys containing query, it stops at the first error of send
+func (s *AdminService) Export(query string, send func(key string, res *http.Response) error) error {
+
+	for _, key := range s.Authorize.keys(s.context(), AdminExportOperation, matchingKeys(s.Cache, query)) {
+		res, err := s.Cache.GetKey(key)
+		if err == NotInCacheError {
+			//the entry was deleted since listing the keys
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = send(key, res)
+		if res.Body != nil {
+			_ = res.Body.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdminService(t *testing.T) {
+
+	service := NewAdminService(NewLRUCache(LRUCacheOptions{}))
+
+	keys := map[string]string{}
+	for _, path := range []string{"/a", "/b", "/other"} {
+		req, err := http.NewRequest("GET", "http://example.com"+path, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("body of " + path))}
+		key, err := service.Put(req, res)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		keys[path] = key
+	}
+
+	if listed := service.List("/OTHER"); len(listed) != 1 || listed[0] != keys["/other"] {
+		t.Error("expected the key of /other, got", listed)
+	}
+
+	res, err := service.Get(keys["/a"])
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != "body of /a" {
+		t.Error("unexpected body", string(body))
+	}
+	if _, err := service.Get("missing"); err != NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+
+	if stats := service.Stats(); stats.Entries != 3 || stats.Hosts["example.com"] != 3 || stats.Bytes != int64(len("body of /a")*2+len("body of /other")) {
+		t.Error("unexpected stats", stats)
+	}
+
+	var exported []string
+	err = service.Export("", func(key string, res *http.Response) error {
+		body, err := ioutil.ReadAll(res.Body)
+		exported = append(exported, string(body))
+		return err
+	})
+	if err != nil || strings.Join(exported, ",") != "body of /a,body of /b,body of /other" {
+		t.Error("unexpected export", exported, err)
+	}
+	stop := errors.New("stop")
+	if err := service.Export("", func(string, *http.Response) error { return stop }); err != stop {
+		t.Error("expected the send error to stop the export, got", err)
+	}
+
+	if deleted, err := service.Invalidate("/other"); err != nil || deleted != 1 {
+		t.Error("expected 1 deleted key, got", deleted, err)
+	}
+	if deleted, err := service.Invalidate(""); err != AdminPurgeAllError || deleted != 0 || len(service.List("")) != 2 {
+		t.Error("expected an empty query not to delete keys, got", deleted, err)
+	}
+	if deleted, err := service.InvalidateAll(); err != nil || deleted != 2 || len(service.List("")) != 0 {
+		t.Error("expected all keys to be deleted, got", deleted, err)
+	}
+}
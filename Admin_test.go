@@ -0,0 +1,148 @@
+package CachedHttpClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+
+	origin := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			writer.Header().Set("Cache-Control", "max-age=60")
+			http.NotFound(writer, r)
+			return
+		}
+		fmt.Fprint(writer, "body of ", r.URL.Path)
+	}))
+	defer origin.Close()
+
+	cache := NewMapCache(MapCacheOptions{IgnoreRequestBody: true, DontIncludeAllRequestHeaders: true})
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport}}
+	for _, path := range []string{"/a", "/b", "/missing"} {
+		res, err := client.Get(origin.URL + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_ = res.Body.Close()
+	}
+
+	admin := httptest.NewServer(http.StripPrefix("/debug/cache", NewAdminHandler(cache)))
+	defer admin.Close()
+	base := admin.URL + "/debug/cache/"
+
+	do := func(method, path string, value interface{}) int {
+		req, err := http.NewRequest(method, base+path, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		defer res.Body.Close()
+		if value != nil {
+			if err := json.NewDecoder(res.Body).Decode(value); err != nil {
+				t.Error(method, path, err)
+			}
+		}
+		return res.StatusCode
+	}
+
+	t.Run("page", func(t *testing.T) {
+		res, err := http.Get(base)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		page, _ := ioutil.ReadAll(res.Body)
+		if !strings.Contains(string(page), "api/keys") || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/html") {
+			t.Error("expected the admin page, got", res.Header.Get("Content-Type"))
+		}
+	})
+
+	var keys []adminKey
+	t.Run("keys", func(t *testing.T) {
+		if status := do("GET", "api/keys?q=%2FA", &keys); status != http.StatusOK {
+			t.Error("unexpected status", status)
+		}
+		if len(keys) != 1 || keys[0].Method != "GET" || keys[0].Target != "/a" || keys[0].Host != origin.Listener.Addr().String() {
+			t.Error("expected the key of /a, got", keys)
+			t.FailNow()
+		}
+	})
+
+	t.Run("entry", func(t *testing.T) {
+		var entry adminEntry
+		if status := do("GET", "api/entry?key="+url.QueryEscape(keys[0].Key), &entry); status != http.StatusOK {
+			t.Error("unexpected status", status)
+		}
+		if entry.Body != "body of /a" || entry.BodyEncoding != "text" || entry.BodySize != len("body of /a") || entry.Truncated {
+			t.Error("unexpected entry", entry)
+		}
+		if status := do("GET", "api/entry?key=missing", nil); status != http.StatusNotFound {
+			t.Error("expected 404 for a missing key, got", status)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		var stats AdminStats
+		do("GET", "api/stats", &stats)
+		if stats.Entries != 3 || stats.Hosts[origin.Listener.Addr().String()] != 3 || stats.Statuses["404"] != 1 || stats.Statuses["200"] != 2 {
+			t.Error("unexpected stats", stats)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if status := do("DELETE", "api/entry?key="+url.QueryEscape(keys[0].Key), nil); status != http.StatusNoContent {
+			t.Error("unexpected status", status)
+		}
+		if status := do("POST", "api/entry?key="+url.QueryEscape(keys[0].Key), nil); status != http.StatusMethodNotAllowed {
+			t.Error("expected 405, got", status)
+		}
+		if len(cache.Keys()) != 2 {
+			t.Error("expected 2 remaining keys, got", cache.Keys())
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		var purged map[string]int
+		do("POST", "api/purge?q=missing", &purged)
+		if purged["Deleted"] != 1 || len(cache.Keys()) != 1 {
+			t.Error("expected the missing entry to be purged, got", purged, cache.Keys())
+		}
+		do("POST", "api/purge", &purged)
+		if purged["Deleted"] != 1 || len(cache.Keys()) != 0 {
+			t.Error("expected all entries to be purged, got", purged, cache.Keys())
+		}
+	})
+}
+
+func TestSummarizeKey(t *testing.T) {
+
+	tests := []struct {
+		key      string
+		expected adminKey
+	}{
+		{"GET /path?q=1 HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n\r\n", adminKey{Method: "GET", Target: "/path?q=1", Host: "example.com"}},
+		{"HEAD /\r\nHost: example.com\r\n\r\n", adminKey{Method: "HEAD", Target: "/", Host: "example.com"}},
+		{"custom-key", adminKey{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			tt.expected.Key = tt.key
+			if summary := summarizeKey(tt.key); summary != tt.expected {
+				t.Error("expected", tt.expected, "got", summary)
+			}
+		})
+	}
+}
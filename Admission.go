@@ -0,0 +1,68 @@
+package CachedHttpClient
+
+import (
+	"mime"
+	"net/http"
+)
+
+//AdmissionPolicy decides which cacheable responses are stored, e.g. to keep large or cheap responses out of the cache
+type AdmissionPolicy interface {
+	//Admit reports if res is stored for req, size is the length of the body of res
+	Admit(req *http.Request, res *http.Response, size int64) bool
+}
+
+//AdmissionFunc is an AdmissionPolicy implemented by a function
+type AdmissionFunc func(req *http.Request, res *http.Response, size int64) bool
+
+func (f AdmissionFunc) Admit(req *http.Request, res *http.Response, size int64) bool {
+	return f(req, res, size)
+}
+
+//Admission admits responses by the size of their body and their content type. Media types in the lists match the
+//Content-Type without parameters, entries ending with "/" match all subtypes, e.g. "video/"
+type Admission struct {
+	//MinBodyBytes is the size below which bodies are not worth storing, 0 stores empty bodies
+	MinBodyBytes int64
+	//MaxBodyBytes is the size above which bodies are not stored, 0 means no limit
+	MaxBodyBytes int64
+	//ContentTypeAllow lists the media types which are stored, all if empty. Responses without a valid Content-Type
+	//are only stored if it is empty
+	ContentTypeAllow []string
+	//ContentTypeDeny lists the media types which are not stored, it takes precedence over ContentTypeAllow
+	ContentTypeDeny []string
+}
+
+func (a *Admission) Admit(req *http.Request, res *http.Response, size int64) bool {
+
+	if size < a.MinBodyBytes || (a.MaxBodyBytes > 0 && size > a.MaxBodyBytes) {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return len(a.ContentTypeAllow) == 0
+	}
+	if matchesMediaType(mediaType, a.ContentTypeDeny) {
+		return false
+	}
+	return len(a.ContentTypeAllow) == 0 || matchesMediaType(mediaType, a.ContentTypeAllow)
+}
+
+//admit reports if the Admission of c stores cacheable, the response stored for response. The body of response is
+//buffered if its size is unknown
+func (c *CachedTransport) admit(req *http.Request, response *http.Response, cacheable *http.Response) (bool, error) {
+
+	if c.Admission == nil {
+		return true, nil
+	}
+	size := response.ContentLength
+	if size < 0 {
+		body, err := bufferBody(response)
+		if err != nil {
+			return false, err
+		}
+		//cacheable is response or a copy with other headers reading the same body
+		cacheable.Body = response.Body
+		size = int64(len(body))
+	}
+	return c.Admission.Admit(req, cacheable, size), nil
+}
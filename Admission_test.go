@@ -0,0 +1,83 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAdmission_Admit(t *testing.T) {
+
+	admission := &Admission{
+		MinBodyBytes:     2,
+		MaxBodyBytes:     10,
+		ContentTypeAllow: []string{"application/json", "video/"},
+		ContentTypeDeny:  []string{"video/mp4"},
+	}
+
+	tests := []struct {
+		name        string
+		admission   *Admission
+		contentType string
+		size        int64
+		expected    bool
+	}{
+		{"allowed", admission, "application/json; charset=utf-8", 5, true},
+		{"allowed subtype", admission, "video/webm", 5, true},
+		{"denied subtype", admission, "video/mp4", 5, false},
+		{"not allowed", admission, "text/html", 5, false},
+		{"no content type", admission, "", 5, false},
+		{"too small", admission, "application/json", 1, false},
+		{"too large", admission, "application/json", 11, false},
+		{"limits", admission, "application/json", 10, true},
+		{"no lists", &Admission{}, "", 0, true},
+		{"deny only", &Admission{ContentTypeDeny: []string{"video/"}}, "video/mp4", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{Header: http.Header{}}
+			if tt.contentType != "" {
+				res.Header.Set("Content-Type", tt.contentType)
+			}
+			if admitted := tt.admission.Admit(nil, res, tt.size); admitted != tt.expected {
+				t.Errorf("Admit = %v, want %v", admitted, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_Admission(t *testing.T) {
+
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		body := strings.Repeat("x", len(req.URL.Path))
+		//the size of chunked bodies is unknown
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(body)), ContentLength: -1, Request: req}, nil
+	})
+	cache := NewMapCache()
+	client := http.Client{Transport: &CachedTransport{
+		Cache:     cache,
+		Fallback:  origin,
+		Admission: &Admission{MaxBodyBytes: 4},
+	}}
+
+	for _, path := range []string{"/a", "/large"} {
+		response, err := client.Get("http://example.com" + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if string(body) != strings.Repeat("x", len(path)) {
+			t.Errorf("unexpected body %q for %s", body, path)
+		}
+		_, err = cache.Get(lruTestRequest(t, path))
+		if stored := err == nil; stored != (path == "/a") {
+			t.Errorf("stored %s = %v", path, stored)
+		}
+	}
+}
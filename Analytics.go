@@ -0,0 +1,425 @@
+package CachedHttpClient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+//DefaultAnalyticsWindow is the window of Analytics without Window
+const DefaultAnalyticsWindow = time.Hour
+
+//analyticsSlots is the number of slots the window is divided into, it slides by one slot at a time
+const analyticsSlots = 60
+
+//analyticsSizeBounds are the upper bounds of the buckets of the size histogram, the last bucket is unbounded
+var analyticsSizeBounds = [...]int64{1 << 10, 10 << 10, 100 << 10, 1 << 20, 10 << 20}
+
+//Analytics aggregates how the requests of a CachedTransport were answered per key and per host over a sliding
+//window, e.g. to find the endpoints which deserve longer TTLs. Keys are the method and URL of the requests, the
+//variants of a URL are counted together. Failed origin requests are not counted. See Report.
+//All methods are safe for concurrent use and do nothing on a nil Analytics
+type Analytics struct {
+	//Window is the time covered by reports, DefaultAnalyticsWindow if 0. It slides by a 60th of the window
+	Window time.Duration
+	//TopN is the number of keys in reports, 10 if 0
+	TopN int
+	//Clock tells the time requests are counted at, SystemClock if nil
+	Clock Clock
+
+	mutex sync.Mutex
+	slots [analyticsSlots]analyticsSlot
+}
+
+//analyticsSlot holds the counters of a part of the window
+type analyticsSlot struct {
+	//index is the number of the slot since the epoch, slots of older indexes are reset before they are reused
+	index int64
+	keys  map[string]*analyticsCounters
+	//sizes counts the responses by the buckets of analyticsSizeBounds
+	sizes [len(analyticsSizeBounds) + 1]analyticsSize
+}
+
+type analyticsCounters struct {
+	host          string
+	hits          int64
+	staleHits     int64
+	misses        int64
+	revalidations int64
+	bytesSaved    int64
+	hitLatency    time.Duration
+	originLatency time.Duration
+}
+
+type analyticsSize struct {
+	responses int64
+	hits      int64
+}
+
+//NewAnalytics creates Analytics reporting on the requests of the last window
+func NewAnalytics(window time.Duration) *Analytics {
+	return &Analytics{Window: window}
+}
+
+func (a *Analytics) window() time.Duration {
+	if a.Window <= 0 {
+		return DefaultAnalyticsWindow
+	}
+	return a.Window
+}
+
+func (a *Analytics) slotDuration() time.Duration {
+	if slot := a.window() / analyticsSlots; slot > 0 {
+		return slot
+	}
+	return 1
+}
+
+//record adds a request for req to the counters of the current slot
+func (a *Analytics) record(req *http.Request, size int64, update func(counters *analyticsCounters), hit bool) {
+
+	index := clockOrDefault(a.Clock).Now().UnixNano() / int64(a.slotDuration())
+	key := req.Method + " " + req.URL.String()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	slot := &a.slots[index%analyticsSlots]
+	if slot.index != index || slot.keys == nil {
+		*slot = analyticsSlot{index: index, keys: map[string]*analyticsCounters{}}
+	}
+	counters, ok := slot.keys[key]
+	if !ok {
+		counters = &analyticsCounters{host: requestHost(req)}
+		slot.keys[key] = counters
+	}
+	update(counters)
+	if size >= 0 {
+		bucket := sort.Search(len(analyticsSizeBounds), func(i int) bool { return size <= analyticsSizeBounds[i] })
+		slot.sizes[bucket].responses++
+		if hit {
+			slot.sizes[bucket].hits++
+		}
+	}
+}
+
+//hit counts a response served from the cache for req after latency
+func (a *Analytics) hit(req *http.Request, res *http.Response, stale bool, latency time.Duration) {
+	if a == nil {
+		return
+	}
+	size := bodySize(res)
+	a.record(req, size, func(counters *analyticsCounters) {
+		if stale {
+			counters.staleHits++
+		} else {
+			counters.hits++
+		}
+		if size > 0 {
+			counters.bytesSaved += size
+		}
+		counters.hitLatency += latency
+	}, true)
+}
+
+//origin counts the response of the origin for req received after latency, stale is the revalidated response of
+//conditional requests. The body of a stale response the origin did not modify counts as saved
+func (a *Analytics) origin(req *http.Request, res *http.Response, stale *http.Response, latency time.Duration) {
+	if a == nil {
+		return
+	}
+	size := bodySize(res)
+	notModified := stale != nil && res.StatusCode == http.StatusNotModified
+	if notModified {
+		size = bodySize(stale)
+	}
+	a.record(req, size, func(counters *analyticsCounters) {
+		if stale != nil {
+			counters.revalidations++
+		} else {
+			counters.misses++
+		}
+		if notModified && size > 0 {
+			counters.bytesSaved += size
+		}
+		counters.originLatency += latency
+	}, false)
+}
+
+//AnalyticsStats are the requests counted for a key, a host or all requests of an AnalyticsReport
+type AnalyticsStats struct {
+	//Requests are the hits and the origin requests, background refreshes included
+	Requests int64
+	//Hits are responses served fresh from the cache
+	Hits int64
+	//StaleHits are stale responses served from the cache
+	StaleHits int64
+	//Misses are requests sent to the origin unconditionally
+	Misses int64
+	//Revalidations are conditional requests sent to the origin for a stale response
+	Revalidations int64
+	//HitRatio is the share of the requests served from the cache, fresh or stale
+	HitRatio float64
+	//BytesSaved are the body bytes of the responses served from the cache or not modified by the origin
+	BytesSaved int64
+	//OriginLatency is the mean latency of the origin requests, the latency of the host for keys without any
+	OriginLatency time.Duration
+	//LatencySaved is the OriginLatency the hits would have taken less the time they took
+	LatencySaved time.Duration
+}
+
+//KeyAnalytics are the statistics of the requests for a method and URL
+type KeyAnalytics struct {
+	Key  string
+	Host string
+	AnalyticsStats
+}
+
+//HostAnalytics are the statistics of the requests for a host
+type HostAnalytics struct {
+	Host string
+	AnalyticsStats
+}
+
+//SizeAnalytics counts the responses with a body of at most MaxBytes and more than the bound of the previous bucket,
+//MaxBytes is 0 for the last bucket which is unbounded
+type SizeAnalytics struct {
+	MaxBytes  int64
+	Responses int64
+	Hits      int64
+}
+
+//AnalyticsReport summarizes the requests between Start and End
+type AnalyticsReport struct {
+	Start time.Time
+	End   time.Time
+	//AnalyticsStats are the statistics of all requests
+	AnalyticsStats
+	//Keys are the TopN keys with the most requests
+	Keys []KeyAnalytics
+	//Hosts are all hosts by their number of requests
+	Hosts []HostAnalytics
+	//Sizes is the histogram of the body sizes of the responses, responses of unknown size are not counted
+	Sizes []SizeAnalytics
+}
+
+//analyticsSums are the counters of a key or host summed over the slots of the window
+type analyticsSums struct {
+	analyticsCounters
+	//latency is the mean origin latency the latency saved by hits is computed with
+	latency time.Duration
+}
+
+func (s *analyticsSums) add(counters *analyticsCounters) {
+	s.host = counters.host
+	s.hits += counters.hits
+	s.staleHits += counters.staleHits
+	s.misses += counters.misses
+	s.revalidations += counters.revalidations
+	s.bytesSaved += counters.bytesSaved
+	s.hitLatency += counters.hitLatency
+	s.originLatency += counters.originLatency
+}
+
+func (s *analyticsSums) originRequests() int64 {
+	return s.misses + s.revalidations
+}
+
+func (s *analyticsSums) meanOriginLatency() time.Duration {
+	if s.originRequests() == 0 {
+		return 0
+	}
+	return s.originLatency / time.Duration(s.originRequests())
+}
+
+func (s *analyticsSums) stats() AnalyticsStats {
+	served := s.hits + s.staleHits
+	stats := AnalyticsStats{
+		Requests:      served + s.originRequests(),
+		Hits:          s.hits,
+		StaleHits:     s.staleHits,
+		Misses:        s.misses,
+		Revalidations: s.revalidations,
+		BytesSaved:    s.bytesSaved,
+		OriginLatency: s.latency,
+	}
+	if stats.Requests > 0 {
+		stats.HitRatio = float64(served) / float64(stats.Requests)
+	}
+	if saved := s.latency*time.Duration(served) - s.hitLatency; saved > 0 {
+		stats.LatencySaved = saved
+	}
+	return stats
+}
+
+//Report returns the statistics of the requests within the window
+func (a *Analytics) Report() AnalyticsReport {
+
+	if a == nil {
+		return AnalyticsReport{}
+	}
+	end := clockOrDefault(a.Clock).Now()
+	slotDuration := a.slotDuration()
+	current := end.UnixNano() / int64(slotDuration)
+	report := AnalyticsReport{Start: time.Unix(0, (current-analyticsSlots+1)*int64(slotDuration)), End: end}
+
+	keys := map[string]*analyticsSums{}
+	var sizes [len(analyticsSizeBounds) + 1]analyticsSize
+	a.mutex.Lock()
+	for i := range a.slots {
+		slot := &a.slots[i]
+		if slot.keys == nil || slot.index <= current-analyticsSlots || slot.index > current {
+			continue
+		}
+		for key, counters := range slot.keys {
+			sums, ok := keys[key]
+			if !ok {
+				sums = &analyticsSums{}
+				keys[key] = sums
+			}
+			sums.add(counters)
+		}
+		for bucket, size := range slot.sizes {
+			sizes[bucket].responses += size.responses
+			sizes[bucket].hits += size.hits
+		}
+	}
+	a.mutex.Unlock()
+
+	hosts := map[string]*analyticsSums{}
+	for _, sums := range keys {
+		host, ok := hosts[sums.host]
+		if !ok {
+			host = &analyticsSums{}
+			hosts[sums.host] = host
+		}
+		host.add(&sums.analyticsCounters)
+	}
+	for _, host := range hosts {
+		host.latency = host.meanOriginLatency()
+	}
+	for key, sums := range keys {
+		sums.latency = sums.meanOriginLatency()
+		if sums.originRequests() == 0 {
+			//keys only served from the cache within the window are compared with their host
+			sums.latency = hosts[sums.host].latency
+		}
+		report.Keys = append(report.Keys, KeyAnalytics{Key: key, Host: sums.host, AnalyticsStats: sums.stats()})
+	}
+	var total analyticsSums
+	var latencySaved time.Duration
+	for name, host := range hosts {
+		total.add(&host.analyticsCounters)
+		stats := host.stats()
+		report.Hosts = append(report.Hosts, HostAnalytics{Host: name, AnalyticsStats: stats})
+		latencySaved += stats.LatencySaved
+	}
+	total.latency = total.meanOriginLatency()
+	report.AnalyticsStats = total.stats()
+	//the origins of the hosts differ, the latency saved is summed per host
+	report.LatencySaved = latencySaved
+
+	sort.Slice(report.Keys, func(i, j int) bool {
+		if report.Keys[i].Requests != report.Keys[j].Requests {
+			return report.Keys[i].Requests > report.Keys[j].Requests
+		}
+		return report.Keys[i].Key < report.Keys[j].Key
+	})
+	topN := a.TopN
+	if topN <= 0 {
+		topN = 10
+	}
+	if len(report.Keys) > topN {
+		report.Keys = report.Keys[:topN]
+	}
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		if report.Hosts[i].Requests != report.Hosts[j].Requests {
+			return report.Hosts[i].Requests > report.Hosts[j].Requests
+		}
+		return report.Hosts[i].Host < report.Hosts[j].Host
+	})
+	for bucket, size := range sizes {
+		var maxBytes int64
+		if bucket < len(analyticsSizeBounds) {
+			maxBytes = analyticsSizeBounds[bucket]
+		}
+		report.Sizes = append(report.Sizes, SizeAnalytics{MaxBytes: maxBytes, Responses: size.responses, Hits: size.hits})
+	}
+	return report
+}
+
+//WriteJSON writes the report to w as JSON
+func (r AnalyticsReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+//WriteText writes the report to w as aligned tables
+//
+//	requests 120, hit ratio 75.0%, saved 1.2 MiB and 8.4s of origin latency
+func (r AnalyticsReport) WriteText(w io.Writer) error {
+
+	_, err := fmt.Fprintf(w, "%s - %s\nrequests %d, hit ratio %.1f%%, saved %s and %s of origin latency\n",
+		r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339), r.Requests, r.HitRatio*100,
+		formatBytes(r.BytesSaved), r.LatencySaved.Round(time.Millisecond))
+	if err != nil {
+		return err
+	}
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	row := func(name string, stats AnalyticsStats) {
+		_, _ = fmt.Fprintf(table, "%s\t%d\t%.1f%%\t%d\t%d\t%s\t%s\t%s\n", name, stats.Requests, stats.HitRatio*100,
+			stats.Misses, stats.Revalidations, formatBytes(stats.BytesSaved), stats.OriginLatency.Round(time.Millisecond),
+			stats.LatencySaved.Round(time.Millisecond))
+	}
+	_, _ = fmt.Fprintf(table, "\nKEY\tREQUESTS\tHIT RATIO\tMISSES\tREVALIDATIONS\tBYTES SAVED\tORIGIN LATENCY\tLATENCY SAVED\n")
+	for _, key := range r.Keys {
+		row(key.Key, key.AnalyticsStats)
+	}
+	_, _ = fmt.Fprintf(table, "\nHOST\tREQUESTS\tHIT RATIO\tMISSES\tREVALIDATIONS\tBYTES SAVED\tORIGIN LATENCY\tLATENCY SAVED\n")
+	for _, host := range r.Hosts {
+		row(host.Host, host.AnalyticsStats)
+	}
+	_, _ = fmt.Fprintf(table, "\nSIZE\tRESPONSES\tHITS\n")
+	for _, size := range r.Sizes {
+		bucket := "> " + formatBytes(analyticsSizeBounds[len(analyticsSizeBounds)-1])
+		if size.MaxBytes > 0 {
+			bucket = "<= " + formatBytes(size.MaxBytes)
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%d\t%d\n", bucket, size.Responses, size.Hits)
+	}
+	return table.Flush()
+}
+
+//Handler returns a handler serving the report as text, or as JSON for requests with ?format=json
+func (a *Analytics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = a.Report().WriteJSON(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = a.Report().WriteText(w)
+	})
+}
+
+//formatBytes formats n with a binary unit
+func formatBytes(n int64) string {
+	if n < 1<<10 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, unit := float64(n)/(1<<10), "KiB"
+	for _, next := range []string{"MiB", "GiB", "TiB"} {
+		if value < 1<<10 {
+			break
+		}
+		value, unit = value/(1<<10), next
+	}
+	return fmt.Sprintf("%.1f %s", value, unit)
+}
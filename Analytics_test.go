@@ -0,0 +1,74 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnalytics_Report(t *testing.T) {
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	analytics := &Analytics{Window: time.Hour, TopN: 1, Clock: clock}
+	transport := &CachedTransport{Cache: NewMapCache(), Clock: clock, Analytics: analytics,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			time.Sleep(5 * time.Millisecond)
+			res := lruTestResponse("content")
+			res.ContentLength = int64(len("content"))
+			res.Header.Set("Cache-Control", "max-age=600")
+			return res, nil
+		})}
+
+	for _, url := range []string{"http://example.com/a", "http://example.com/a", "http://example.com/a", "http://other.example.com/b"} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		clock.Advance(time.Minute)
+	}
+
+	report := analytics.Report()
+	if report.Requests != 4 || report.Hits != 2 || report.Misses != 2 || report.HitRatio != 0.5 || report.BytesSaved != 14 {
+		t.Errorf("unexpected totals %+v", report.AnalyticsStats)
+	}
+	if report.LatencySaved <= 0 || report.OriginLatency < 5*time.Millisecond {
+		t.Errorf("expected the hits to save the origin latency, got %+v", report.AnalyticsStats)
+	}
+	if len(report.Keys) != 1 || report.Keys[0].Key != "GET http://example.com/a" || report.Keys[0].Requests != 3 {
+		t.Errorf("expected the top key, got %+v", report.Keys)
+	}
+	if len(report.Hosts) != 2 || report.Hosts[0].Host != "example.com" || report.Hosts[1].HitRatio != 0 {
+		t.Errorf("unexpected hosts %+v", report.Hosts)
+	}
+	if report.Sizes[0].Responses != 4 || report.Sizes[0].Hits != 2 || report.Sizes[len(report.Sizes)-1].MaxBytes != 0 {
+		t.Errorf("unexpected size histogram %+v", report.Sizes)
+	}
+
+	var text bytes.Buffer
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"requests 4, hit ratio 50.0%, saved 14 B", "GET http://example.com/a", "other.example.com", "<= 1.0 KiB"} {
+		if !strings.Contains(text.String(), expected) {
+			t.Errorf("expected %q in the report\n%s", expected, text.String())
+		}
+	}
+	var json bytes.Buffer
+	if err := report.WriteJSON(&json); err != nil || !strings.Contains(json.String(), `"HitRatio": 0.5`) {
+		t.Error("unexpected JSON report", json.String(), err)
+	}
+
+	//the requests slide out of the window
+	clock.Advance(time.Hour - 2*time.Minute)
+	if report := analytics.Report(); report.Requests != 1 || report.Hosts[0].Host != "other.example.com" {
+		t.Errorf("expected the last request within the window, got %+v", report.AnalyticsStats)
+	}
+	clock.Advance(time.Hour)
+	if report := analytics.Report(); report.Requests != 0 || len(report.Keys) != 0 {
+		t.Errorf("expected an empty report, got %+v", report)
+	}
+}
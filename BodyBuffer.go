@@ -0,0 +1,79 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+//maxPooledBuffer is the capacity above which buffers are not returned to bufferPool, so a single large body does not
+//keep its memory alive
+const maxPooledBuffer = 4 << 20
+
+//maxPresizedBody is the largest Content-Length a body is read into a slice of that size for, a bogus Content-Length
+//does not allocate more
+const maxPresizedBody = 256 << 20
+
+//bufferPool holds the buffers entries are encoded and bodies of unknown length are read into
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+//readBody reads the rest of body without closing it. The unread bytes of bodies served from the in memory caches are
+//returned without copying them, they are shared read-only. Bodies of a known length are read into a slice of that
+//size, others into a pooled buffer copied to a slice of their exact size, so large bodies do not grow a buffer again
+//and again
+func readBody(body io.Reader, contentLength int64) ([]byte, error) {
+
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	if body, ok := body.(*bytesBody); ok {
+		if body.Len() == 0 {
+			body.trailer.fill()
+			return nil, nil
+		}
+		remaining := body.data[len(body.data)-body.Len():]
+		_, _ = body.Seek(0, io.SeekEnd)
+		body.trailer.fill()
+		return remaining, nil
+	}
+	var data []byte
+	if contentLength > 0 && contentLength <= maxPresizedBody {
+		data = make([]byte, contentLength)
+		n, err := io.ReadFull(body, data)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			//the body is shorter than its Content-Length
+			if n == 0 {
+				return nil, nil
+			}
+			return data[:n], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	_, err := buf.ReadFrom(body)
+	if err != nil {
+		return nil, err
+	}
+	if buf.Len() == 0 {
+		return data, nil
+	}
+	//the pooled buffer is reused, the body gets its own copy
+	return append(data, buf.Bytes()...), nil
+}
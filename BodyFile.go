@@ -0,0 +1,158 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+//fileBody is the body of a response stored in its own file by FileCache, the file is opened on the first Read
+type fileBody struct {
+	dir  string
+	name string
+	file *os.File
+	//trailer is filled once the body was read to the end if not nil, see deferTrailer
+	trailer *deferredTrailer
+}
+
+func (b *fileBody) Read(p []byte) (int, error) {
+	if err := b.open(); err != nil {
+		return 0, err
+	}
+	n, err := b.file.Read(p)
+	if err == io.EOF {
+		b.trailer.fill()
+	}
+	return n, err
+}
+
+func (b *fileBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	return b.file.Close()
+}
+
+//reopen returns an unread body for the same file
+func (b *fileBody) reopen() *fileBody {
+	return &fileBody{dir: b.dir, name: b.name}
+}
+
+//streamingBody returns the body of a response to the caller while writing it to a temporary file, once the body was
+//read completely the file is moved to its final name and commit stores the entry
+type streamingBody struct {
+	reader io.Reader
+	body   io.Closer
+	//file is nil once the body was committed or writing failed
+	file   *os.File
+	path   string
+	commit func() error
+	//err is the error of the commit, it is returned by Close
+	err error
+}
+
+func (s *streamingBody) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	if n > 0 && s.file != nil {
+		if _, writeErr := s.file.Write(p[:n]); writeErr != nil {
+			s.abort()
+		}
+	}
+	if err == io.EOF && s.file != nil {
+		s.err = s.finish()
+	}
+	return n, err
+}
+
+//Close stops storing the body if it was not read completely and returns the error of storing the entry
+func (s *streamingBody) Close() error {
+	if s.file != nil {
+		s.abort()
+	}
+	err := s.body.Close()
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+func (s *streamingBody) finish() error {
+	file := s.file
+	s.file = nil
+
+	err := file.Close()
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	err = os.Rename(file.Name(), s.path)
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return err
+	}
+	return s.commit()
+}
+
+func (s *streamingBody) abort() {
+	_ = s.file.Close()
+	_ = os.Remove(s.file.Name())
+	s.file = nil
+}
+
+//bodyDir is the directory the bodies larger than BodyThreshold of the cache file filePath are stored in
+func bodyDir(filePath string) string {
+	return filePath + ".bodies"
+}
+
+//setLarge stores res once the caller read its body, prefix holds the bytes already read from the body
+func (f *FileCache) setLarge(key string, res *http.Response, prefix []byte) error {
+
+	body := res.Body
+	stored := *res
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+
+	dir := bodyDir(f.filePath)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return err
+	}
+
+	name := hexDigest(f.Digester, []byte(key))
+	stored.Body = &fileBody{dir: dir, name: name}
+
+	res.Body = &streamingBody{
+		reader: res.Body,
+		body:   body,
+		file:   file,
+		path:   filepath.Join(dir, name),
+		commit: func() error {
+			err := f.write(key, &stored)
+			if err != nil {
+				return err
+			}
+			f.MapCache.mutex.Lock()
+			f.cache[key] = &stored
+			f.MapCache.mutex.Unlock()
+			return nil
+		},
+	}
+
+	return nil
+}
+
+//removeBodyFile deletes the body file of res if it has one
+func removeBodyFile(res *http.Response) {
+	if body, ok := res.Body.(*fileBody); ok {
+		_ = os.Remove(filepath.Join(body.dir, body.name))
+	}
+}
@@ -0,0 +1,132 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileCache_BodyThreshold(t *testing.T) {
+
+	large := strings.Repeat("0123456789", 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			_, _ = writer.Write([]byte("small"))
+			return
+		}
+		_, _ = writer.Write([]byte(large))
+	}))
+	defer server.Close()
+
+	fileCache, err := NewFileCache("tmp/streaming.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	fileCache.BodyThreshold = 1024
+	client := http.Client{Transport: &CachedTransport{Cache: fileCache, Fallback: http.DefaultTransport}}
+
+	get := func(path string) *http.Response {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return res
+	}
+
+	t.Run("partially read body is not stored", func(t *testing.T) {
+		res := get("/aborted")
+		_, _ = res.Body.Read(make([]byte, 10))
+		if err := res.Body.Close(); err != nil {
+			t.Error(err)
+		}
+		if len(fileCache.Keys()) != 0 {
+			t.Error("expected no entry, got", fileCache.Keys())
+		}
+		files, _ := ioutil.ReadDir(bodyDir("tmp/streaming.cache"))
+		if len(files) != 0 {
+			t.Error("expected the temporary file to be removed, got", len(files), "files")
+		}
+	})
+
+	t.Run("large body", func(t *testing.T) {
+		res := get("/large")
+		if len(fileCache.Keys()) != 0 {
+			t.Error("expected the entry to be stored after reading the body")
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if err := res.Body.Close(); err != nil {
+			t.Error(err)
+		}
+		if string(body) != large {
+			t.Error("the caller received", len(body), "bytes instead of", len(large))
+		}
+		if len(fileCache.Keys()) != 1 {
+			t.Error("expected 1 entry, got", fileCache.Keys())
+		}
+
+		for i := 0; i < 2; i++ {
+			cached, err := ioutil.ReadAll(get("/large").Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if string(cached) != large {
+				t.Error("the cache returned", len(cached), "bytes instead of", len(large))
+			}
+		}
+	})
+
+	t.Run("small body", func(t *testing.T) {
+		body, _ := ioutil.ReadAll(get("/small").Body)
+		if string(body) != "small" || len(fileCache.Keys()) != 2 {
+			t.Error("expected the small body to be stored directly, got", string(body), len(fileCache.Keys()))
+		}
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		info, err := os.Stat("tmp/streaming.cache")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if info.Size() > int64(len(large)) {
+			t.Error("expected the large body not to be embedded in the cache file, size", info.Size())
+		}
+
+		reopened, err := OpenFileCache("tmp/streaming.cache")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		for _, key := range reopened.Keys() {
+			res, err := reopened.GetKey(key)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			if !strings.Contains(key, "/large") {
+				continue
+			}
+			if string(body) != large {
+				t.Error("the reopened cache returned", len(body), "bytes instead of", len(large))
+			}
+			if err := reopened.DeleteKey(key); err != nil {
+				t.Error(err)
+			}
+		}
+		files, _ := filepath.Glob(filepath.Join(bodyDir("tmp/streaming.cache"), "*"))
+		if len(files) != 0 {
+			t.Error("expected the body file to be deleted with its entry, got", files)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"net/url"
+)
+
+//CacheStatusHeader tells how a response was served if CachedTransport.StatusHeaders is set, see CacheHit
+const CacheStatusHeader = "X-Cache"
+
+//CacheKeyHeader is the query escaped key of the entry a response was served from or stored under if
+//CachedTransport.StatusHeaders is set and the cache implements Keyer. url.QueryUnescape returns the key, it can be
+//passed to the admin UI as the key parameter as is
+const CacheKeyHeader = "X-Cache-Key"
+
+//The values of CacheStatusHeader
+const (
+	//CacheHit is a fresh response served from the cache
+	CacheHit = "HIT"
+	//CacheMiss is a response of the origin, stored or not
+	CacheMiss = "MISS"
+	//CacheStale is a stale response served from the cache, e.g. within stale-while-revalidate or stale-if-error
+	CacheStale = "STALE"
+	//CacheRevalidated is a stored response the origin confirmed with 304 Not Modified
+	CacheRevalidated = "REVALIDATED"
+	//CacheBypass is a response of a request the cache is not used for, e.g. a POST or a request WithNoCache
+	CacheBypass = "BYPASS"
+)
+
+//withStatus returns res with the CacheStatusHeader status and the CacheKeyHeader of keyReq if StatusHeaders is set.
+//The header of res is copied so the stored response is not changed
+func (c *CachedTransport) withStatus(res *http.Response, keyReq *http.Request, status string) *http.Response {
+
+	if !c.StatusHeaders || res == nil {
+		return res
+	}
+	res.Header = res.Header.Clone()
+	if res.Header == nil {
+		res.Header = http.Header{}
+	}
+	res.Header.Set(CacheStatusHeader, status)
+	res.Header.Del(CacheKeyHeader)
+	if keyReq != nil {
+		if key, ok := cacheKey(c.Cache, keyReq); ok {
+			res.Header.Set(CacheKeyHeader, url.QueryEscape(key))
+		}
+	}
+	return res
+}
@@ -0,0 +1,115 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_StatusHeaders(t *testing.T) {
+
+	var maxAge string
+	transport := &CachedTransport{Cache: NewMapCache(), StatusHeaders: true,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res := lruTestResponse("content")
+			res.Header.Set("Cache-Control", maxAge)
+			res.Header.Set("ETag", `"1"`)
+			if req.Header.Get("If-None-Match") == `"1"` {
+				res.StatusCode = http.StatusNotModified
+				res.Body = http.NoBody
+			}
+			res.Request = req
+			return res, nil
+		})}
+
+	request := func(method string) *http.Request {
+		req, err := http.NewRequest(method, "http://example.com/status", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	steps := []struct {
+		name     string
+		maxAge   string
+		expected string
+	}{
+		{"miss", "max-age=0", CacheMiss},
+		{"revalidated", "max-age=60", CacheRevalidated},
+		{"hit", "max-age=60", CacheHit},
+	}
+	for _, step := range steps {
+		maxAge = step.maxAge
+		res, err := transport.RoundTrip(request(http.MethodGet))
+		if err != nil {
+			t.Fatal(step.name, err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		if status := res.Header.Get(CacheStatusHeader); status != step.expected || string(body) != "content" {
+			t.Errorf("%s: expected %s, got %s with %q", step.name, step.expected, status, body)
+		}
+		key, err := url.QueryUnescape(res.Header.Get(CacheKeyHeader))
+		if err != nil || !strings.HasPrefix(key, "GET /status") {
+			t.Errorf("%s: wrong key %q", step.name, key)
+		}
+	}
+
+	//the stored response is not annotated
+	stored, err := transport.Cache.Get(request(http.MethodGet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Header.Get(CacheStatusHeader) != "" || stored.Header.Get(CacheKeyHeader) != "" {
+		t.Error("the status headers were stored", stored.Header)
+	}
+
+	res, err := transport.RoundTrip(request(http.MethodPost))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if res.Header.Get(CacheStatusHeader) != CacheBypass || res.Header.Get(CacheKeyHeader) != "" {
+		t.Error("expected a bypass without key, got", res.Header)
+	}
+}
+
+func TestCachedTransport_StatusHeaders_Stale(t *testing.T) {
+
+	failing := false
+	transport := &CachedTransport{Cache: NewMapCache(), StatusHeaders: true, StaleIfError: time.Hour,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if failing {
+				return nil, errors.New("origin down")
+			}
+			res := lruTestResponse("content")
+			res.Header.Set("Cache-Control", "max-age=0")
+			return res, nil
+		})}
+
+	for _, expected := range []string{CacheMiss, CacheStale} {
+		res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if status := res.Header.Get(CacheStatusHeader); status != expected {
+			t.Error("expected", expected, "got", status)
+		}
+		failing = true
+	}
+
+	//without StatusHeaders nothing is added
+	transport.StatusHeaders = false
+	res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if _, ok := res.Header[CacheStatusHeader]; ok {
+		t.Error("unexpected status header", res.Header)
+	}
+}
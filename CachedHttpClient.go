@@ -2,22 +2,144 @@
 package CachedHttpClient
 
 import (
-	"bytes"
 	"errors"
-	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
+	"sync/atomic"
+	"time"
 )
 
+//Cacher stores the responses of a CachedTransport. Get and Set are called with the request of the caller, caches
+//using a remote store should give up once its context is done so a cancelled caller is not blocked, see KVCache
 type Cacher interface {
 	Get(req *http.Request) (*http.Response, error)
 	Set(req *http.Request, res *http.Response) error
 }
 
+//Keyer is implemented by caches exposing the key the response for a request is stored under
+type Keyer interface {
+	Key(req *http.Request) (string, error)
+}
+
+//Inspector is implemented by caches whose entries can be listed, read and deleted by their key, see AdminHandler
+type Inspector interface {
+	Keys() []string
+	GetKey(key string) (*http.Response, error)
+	DeleteKey(key string) error
+}
+
 type CachedTransport struct {
 	Cache                         Cacher
 	Fallback                      http.RoundTripper
 	ContinueRoundTripWithSetError func(transport *CachedTransport, err error, request *http.Request, response *http.Response) bool
+	//Shared applies the rules of a shared cache (RFC 7234), private responses are not stored and s-maxage is honored
+	Shared bool
+	//URLRewrites maps an old origin host to the host requests are sent to and cached for instead,
+	//use MigrateCache to move entries cached before the rewrite was configured
+	URLRewrites map[string]string
+	//HostAliases maps hosts serving the same content to a canonical host, requests are sent to their own host but
+	//cached under the canonical one. See AliasHosts
+	HostAliases map[string]string
+	//StaleWhileRevalidate and StaleIfError are used as the stale-while-revalidate and stale-if-error
+	//(RFC 5861) windows of responses which do not set the directives themselves
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+	//Verifier checks responses before they are stored and before they are served from the cache,
+	//e.g. VerifyContentDigest
+	Verifier ResponseVerifier
+	//Signer is applied to every request sent to the origin after the cache key was computed, e.g. NewHTTPSigner
+	Signer RequestSigner
+	//VaryNormalizers compare the listed request headers by their normalized value when matching the Vary header
+	//of cached responses, e.g. AcceptClassNormalizers
+	VaryNormalizers map[string]HeaderNormalizer
+	//Variants counts the variants stored per URL if not nil
+	Variants *VariantTracker
+	//Coalescer deduplicates concurrent origin requests for the same key if not nil
+	Coalescer *Coalescer
+	//ErrorCapture stores the non-2xx responses of the origin with truncated bodies for inspection if not nil
+	ErrorCapture *ErrorCapture
+	//NoiseHeaders are removed from the request used for the cache key, Vary matching and variant tracking and from
+	//stored responses, the Date of responses is kept. See DefaultNoiseHeaders
+	NoiseHeaders []string
+	//Hooks are called on cache hits, misses, revalidations, stores and errors if not nil
+	Hooks *Hooks
+	//Events publishes the stores, hits, expirations and invalidations of entries to its subscribers if not nil
+	Events *EventStream
+	//Tracer traces RoundTrip and the origin requests if not nil, e.g. with an OpenTelemetry adapter
+	Tracer Tracer
+	//Refresher refreshes frequently hit responses in the background before they expire if not nil
+	Refresher *Refresher
+	//EarlyExpiration refreshes fresh responses in the background with a probability growing towards their expiry if
+	//not nil, so hot entries do not expire for all requests at once
+	EarlyExpiration *EarlyExpiration
+	//NegativeCaching caches error responses without freshness information for short TTLs if not nil
+	NegativeCaching *NegativeCaching
+	//TTLExtractors find the freshness lifetime of responses without freshness information in their body by their
+	//media type, entries ending with "/" match all subtypes. E.g. DNSMessageTTL for DNSMessageMediaType
+	TTLExtractors map[string]TTLExtractor
+	//Retry retries failing origin requests of cache misses with exponential backoff if not nil
+	Retry *Retry
+	//LoadShedder bounds the concurrent origin requests if the hit rate collapsed if not nil
+	LoadShedder *LoadShedder
+	//RateLimiter limits the origin requests per host if not nil
+	RateLimiter *RateLimiter
+	//CircuitBreaker stops sending requests to failing hosts if not nil
+	CircuitBreaker *CircuitBreaker
+	//ConnectionBackoff fails origin requests fast after connection failures of their host if not nil
+	ConnectionBackoff *ConnectionBackoff
+	//SoftDelete keeps invalidated entries restorable for a window if not nil, see Restore
+	SoftDelete *SoftDelete
+	//Admission decides which cacheable responses are stored if not nil, e.g. an *Admission limiting the body size
+	Admission AdmissionPolicy
+	//StoreTransform rewrites responses before they are stored if not nil, e.g. to remove Set-Cookie or to redact
+	//tokens from bodies. The response returned to the caller of a miss is not transformed. Responses failing to
+	//transform are not stored, the error is handled like an error of Set
+	StoreTransform Transform
+	//ServeTransform rewrites stored responses before they are used if not nil, the counterpart of StoreTransform.
+	//Revalidated responses are stored again and pass StoreTransform another time
+	ServeTransform Transform
+	//PostCaching caches the responses of selected POST requests keyed by a digest of their body if not nil
+	PostCaching *PostCaching
+	//RefreshTimeout bounds the background refreshes of stale and hot responses, DefaultRefreshTimeout if 0. They are
+	//detached from the cancellation of the request which started them but keep its context values
+	RefreshTimeout time.Duration
+	//StaleOnDeadline serves stale responses if the deadline of a request is too short to reach the origin if not nil
+	StaleOnDeadline *StaleOnDeadline
+	//Offline serves all requests from the cache, stale responses included, and never contacts the origin. Requests
+	//without a stored response fail with CacheMissError
+	Offline bool
+	//Metrics counts hits, misses, revalidations and store errors if not nil
+	Metrics *Metrics
+	//Analytics aggregates the hit ratio, bytes saved and latency saved per key and host if not nil, see Analytics.Report
+	Analytics *Analytics
+	//HeaderLimits caps the size and number of the header fields of stored responses if not nil
+	HeaderLimits *HeaderLimits
+	//Guardrails bound the freshness lifetimes of responses and the serving of stale responses if not nil
+	Guardrails *Guardrails
+	//Rules override the caching of the requests matching them if not nil, see CacheRule
+	Rules *Rules
+	//ContentDecoding decodes the gzip and deflate bodies of origin responses before they are stored if not nil, see
+	//ContentDecoding.KeepEncoded to store them encoded and decode them for clients not accepting the coding
+	ContentDecoding *ContentDecoding
+	//Principal partitions the cache by the principal of the requests if not nil, e.g. AuthorizationPrincipal. The
+	//requests of a principal only read and write its partition, which is private to it: responses with
+	//Cache-Control: private or to requests with Authorization are stored there. Responses to anonymous requests are
+	//stored following the rules of a shared cache. See WithPrincipal to set the principal of a single request
+	Principal PrincipalFunc
+	//Clock tells the time the freshness of responses is computed with, SystemClock if nil. A ManualClock lets tests
+	//expire responses without sleeping
+	Clock Clock
+	//StatusHeaders annotates the returned responses with X-Cache: HIT, MISS, STALE, REVALIDATED or BYPASS and the
+	//X-Cache-Key of their entry like CDNs do, e.g. to assert on the caching in tests. See CacheStatusHeader
+	StatusHeaders bool
+	//PoolResponses recycles the responses of fresh LRUCache hits once their body is closed, so serving a hit does not
+	//allocate. The body has to be closed once and the response, its header and its body must not be used after
+	PoolResponses bool
+
+	//index holds the *urlIndex of the URLs of the entries once they are invalidated, see InvalidateURL
+	index atomic.Value
+	//revalidations holds the *revalidations of the stale responses once one is revalidated in the background
+	revalidations atomic.Value
 }
 
 var DefaultCashedClient = &http.Client{
@@ -29,29 +151,357 @@ var DefaultCachedTransport = &CachedTransport{
 	ContinueRoundTripWithSetError: nil,
 }
 
-//RoundTrip checks if the cache has a response for the request and return it, if not save the response of the fallback
-//RoundTripper to the cache. If the set function returns a error ContinueRoundTripWithSetError will be called if not nil
+//RoundTrip checks if the cache has a fresh response for the request and return it, if not save the response of the
+//fallback RoundTripper to the cache if it is cacheable following the Cache-Control, Expires, Date and Age headers.
+//Requests to a host in URLRewrites are sent to and cached for the new host, requests to a host in HostAliases are
+//cached for the canonical host.
+//Cached responses are only used if the request headers named in their Vary header match.
+//Stale responses with an ETag or Last-Modified header are revalidated with a conditional request. Within their
+//stale-while-revalidate window stale responses are served while they are refreshed in the background, within their
+//stale-if-error window they are served if the origin fails. With StaleOnDeadline they are also served if the
+//deadline of the request is too short to reach the origin.
+//Unsafe requests like POST are sent to the origin and invalidate the cached responses for their URL if they succeed,
+//unless they are cached by PostCaching.
+//Requests with Cache-Control: only-if-cached get a 504 response if there is no fresh stored response.
+//Range requests are served from a stored complete response, else 206 responses are stored for their range.
+//The caching of single requests is controlled with WithTTL, WithNoCache and WithForceRefresh or declared by Rules.
+//If the set function returns a error ContinueRoundTripWithSetError will be called if not nil
 func (c *CachedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
-	if res, err := c.Cache.Get(req); err == nil {
-		res.Request = req
-		return res, nil
+	if err := req.Context().Err(); err != nil {
+		//the caller gave up already, neither the cache nor the origin is asked
+		return nil, err
+	}
+	req = c.Rules.apply(req)
+	if isRangeRequest(req) {
+		return c.roundTripRange(req)
+	}
+	req = rewriteRequest(req, c.URLRewrites)
+	//keyReq is used for all cache operations, req is sent to the origin
+	keyReq := c.keyRequest(req)
+
+	ctx, span := c.startSpan(req.Context(), RoundTripSpan)
+	defer span.End()
+	if c.Tracer != nil {
+		req = req.WithContext(ctx)
+		keyReq = keyReq.WithContext(ctx)
+	}
+
+	req, keyReq, err := c.PostCaching.requests(req, keyReq)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if noCacheFromContext(req.Context()) || !isSafeMethod(req.Method) && !isPostCached(keyReq) {
+		span.SetAttribute(ResultAttribute, "bypass")
+		if c.Offline {
+			span.RecordError(CacheMissError)
+			return nil, CacheMissError
+		}
+		signed, err := c.sign(req)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		response, err := c.roundTripOrigin(signed, false)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		if !isSafeMethod(req.Method) {
+			c.invalidateAfterUnsafe(req, response)
+		}
+		return c.withStatus(response, nil, CacheBypass), nil
+	}
+	c.setKeyAttribute(span, keyReq)
+
+	start := time.Now()
+	var stale *http.Response
+	var res *http.Response
+	err = NotInCacheError
+	if !forceRefreshFromContext(req.Context()) || c.Offline {
+		res, err = c.lookup(keyReq)
+		err = c.storeError("get", keyReq, err)
+		if errors.Is(err, DecodeError) {
+			//entries which can not be decoded, e.g. corrupted or written by a newer version, are replaced from the origin
+			c.Hooks.error(c.Cache, keyReq, err, start)
+			err = NotInCacheError
+		}
+		if err == nil && c.ServeTransform != nil {
+			res, err = c.ServeTransform(keyReq, res)
+		}
+	}
+	if err == nil && c.SoftDelete.hides(c.Cache, keyReq) {
+		err = NotInCacheError
+	}
+	if err == nil && c.verify(res) != nil {
+		//entries failing verification are never served but replaced from the origin
+		err = NotInCacheError
+	}
+	if err == nil && !varyMatches(keyReq, res, c.VaryNormalizers) {
+		err = NotInCacheError
+	}
+	if err == nil {
+		now := c.now()
+		res = c.Guardrails.response(res, c.Shared)
+		if c.isFresh(keyReq, res, now) {
+			c.Refresher.hit(c, req, keyReq, res, now)
+			c.EarlyExpiration.hit(c, req, keyReq, res, now)
+			res = reusedResponse(res, now)
+			res.Request = req
+			res = c.withStatus(c.ContentDecoding.serve(req, res), keyReq, CacheHit)
+			c.Hooks.hit(c.Cache, keyReq, res, false, start)
+			c.Events.response(HitEvent, c.Cache, keyReq, res, false)
+			c.Analytics.hit(keyReq, res, false, time.Since(start))
+			c.LoadShedder.hit()
+			span.SetAttribute(ResultAttribute, "hit")
+			if c.PoolResponses {
+				poolResponse(res)
+			}
+			return c.Metrics.hit(res, false), nil
+		}
+		stale = res
+		c.Events.response(ExpiredEvent, c.Cache, keyReq, stale, false)
+
+		//a client accepting the stale response with max-stale does not refresh it
+		acceptsStale := c.acceptsStale(keyReq, stale, now)
+		if c.Offline || c.Guardrails.allowsStale(keyReq, stale, c.Shared, now) &&
+			(acceptsStale || c.servesWhileRevalidating(req, keyReq, stale, now)) {
+			if !acceptsStale {
+				if err := c.revalidateInBackground(req, keyReq, stale); err != nil {
+					return nil, err
+				}
+			}
+			res = c.withStatus(c.ContentDecoding.serve(req, serveStale(req, stale, c.now())), keyReq, CacheStale)
+			c.Hooks.hit(c.Cache, keyReq, res, true, start)
+			c.Events.response(HitEvent, c.Cache, keyReq, res, true)
+			c.Analytics.hit(keyReq, res, true, time.Since(start))
+			c.LoadShedder.hit()
+			span.SetAttribute(ResultAttribute, "stale")
+			return c.Metrics.hit(res, true), nil
+		}
 
 	} else if !errors.Is(err, NotInCacheError) {
+		c.Hooks.error(c.Cache, keyReq, err, start)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if c.Offline {
+		span.SetAttribute(ResultAttribute, "miss")
+		span.RecordError(CacheMissError)
+		return nil, CacheMissError
+	}
+	if storedOnlyFromContext(req.Context()) {
+		span.SetAttribute(ResultAttribute, "miss")
+		return nil, NotInCacheError
+	}
+	if onlyIfCached(req) {
+		span.SetAttribute(ResultAttribute, "miss")
+		return c.withStatus(gatewayTimeout(req), nil, CacheMiss), nil
+	}
+
+	//shed misses fail like the origin so stale responses are served within their stale-if-error window
+	var response *http.Response
+	release, err := c.LoadShedder.acquire()
+	if err == nil {
+		response, err = c.coalescedFetch(req, keyReq, stale)
+		release()
+	}
+
+	if stale != nil && isOriginError(response, err) && c.Guardrails.allowsStale(keyReq, stale, c.Shared, c.now()) && (c.CircuitBreaker.servesStale(err) || c.ConnectionBackoff.servesStale(err) ||
+		canServeStale(stale, c.Shared, c.now(), staleWindow(stale, "stale-if-error", ruleStaleWindow(req, "stale-if-error", c.StaleIfError)))) {
+		if err == nil {
+			_ = response.Body.Close()
+		}
+		res = c.withStatus(c.ContentDecoding.serve(req, serveStale(req, stale, c.now())), keyReq, CacheStale)
+		c.Hooks.hit(c.Cache, keyReq, res, true, start)
+		c.Events.response(HitEvent, c.Cache, keyReq, res, true)
+		c.Analytics.hit(keyReq, res, true, time.Since(start))
+		span.SetAttribute(ResultAttribute, "stale")
+		return c.Metrics.hit(res, true), nil
+	}
+
+	span.SetAttribute(ResultAttribute, "miss")
+	if err != nil {
+		if stale != nil {
+			if stale.Body != nil {
+				_ = stale.Body.Close()
+			}
+			err = withStaleEntry(req, err)
+		}
+		span.RecordError(err)
+	}
+	//a coalesced response may have been requested with another Accept-Encoding
+	return c.ContentDecoding.serve(req, response), err
+}
+
+//keyRequest returns the request the cache operations for req use, req is already rewritten by URLRewrites
+func (c *CachedTransport) keyRequest(req *http.Request) *http.Request {
+	return c.partitioned(req, rangeKeyRequest(stripNoiseHeaders(rewriteRequest(req, c.HostAliases), c.NoiseHeaders)))
+}
+
+//requestKey returns the cache key of req like RoundTrip computes it, the key of MapCache if Cache is no Keyer
+func (c *CachedTransport) requestKey(req *http.Request) (string, error) {
+	keyReq := c.keyRequest(rewriteRequest(req, c.URLRewrites))
+	if key, ok := cacheKey(c.Cache, keyReq); ok {
+		return key, nil
+	}
+	return MapCacheOptions{}.key(keyReq)
+}
+
+//fetch requests req from the fallback, revalidating the stale response if there is one, and stores the response
+func (c *CachedTransport) fetch(req *http.Request, keyReq *http.Request, stale *http.Response) (*http.Response, error) {
+
+	if conditional, ok := conditionalRequest(req, stale); ok {
+		c.Metrics.revalidation()
+		conditional, err := rewindBody(conditional, keyReq)
+		if err != nil {
+			return nil, err
+		}
+		conditional, err = c.sign(conditional)
+		if err != nil {
+			return nil, err
+		}
+		start, requested := time.Now(), c.now()
+		response, err := c.roundTripOrigin(conditional, true)
+		c.Hooks.originResponse(c.Cache, keyReq, response, err, true, start)
+		if err != nil {
+			return nil, err
+		}
+		c.Analytics.origin(keyReq, response, stale, time.Since(start))
+		if response.StatusCode != http.StatusNotModified {
+			response, err = c.store(keyReq, response, requested)
+			return c.withStatus(response, keyReq, CacheMiss), err
+		}
+		err = response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		revalidated := mergeNotModified(stale, response)
+		revalidated.Request = req
+		revalidated, err = c.store(keyReq, revalidated, requested)
+		return c.withStatus(revalidated, keyReq, CacheRevalidated), err
+	}
+
+	c.Metrics.miss()
+	req, err := rewindBody(req, keyReq)
+	if err != nil {
 		return nil, err
 	}
-	response, err := c.Fallback.RoundTrip(req)
+	signed, err := c.sign(req)
+	if err != nil {
+		return nil, err
+	}
+	start, requested := time.Now(), c.now()
+	response, err := c.Retry.roundTrip(signed, func(req *http.Request) (*http.Response, error) {
+		return c.roundTripOrigin(req, false)
+	})
+	c.Hooks.originResponse(c.Cache, keyReq, response, err, false, start)
 
 	if err != nil {
 		return nil, err
 	}
+	c.Analytics.origin(keyReq, response, nil, time.Since(start))
+
+	response, err = c.store(keyReq, response, requested)
+	return c.withStatus(response, keyReq, CacheMiss), err
+}
+
+//store saves the response to the cache if it is cacheable
+func (c *CachedTransport) store(req *http.Request, response *http.Response, requested time.Time) (*http.Response, error) {
+
+	undeclared := response.Trailer == nil
+	if undeclared {
+		//net/http fills the trailers into the Trailer map of response once its body was read to the end, so the
+		//copies stored by the caches see trailers not declared by the Trailer header too
+		response.Trailer = http.Header{}
+	}
+	stored, err := c.storeResponse(req, response, requested)
+	if undeclared && stored != nil && len(stored.Trailer) == 0 {
+		//the caller gets the response without Trailer like from the origin, net/http still sets one if the unread
+		//body has trailers
+		stored.Trailer = nil
+	}
+	return stored, err
+}
+
+//storeResponse saves the response with a Trailer map to the cache if it is cacheable
+func (c *CachedTransport) storeResponse(req *http.Request, response *http.Response, requested time.Time) (*http.Response, error) {
+
+	responded := c.now()
+
+	if err := c.verify(response); err != nil {
+		_ = response.Body.Close()
+		return nil, err
+	}
 
-	err = c.Cache.Set(req, response)
+	//capturing is only done for inspection, the response is served even if it fails
+	_ = c.ErrorCapture.capture(req, response)
+	//the digests are verified against the encoded body, the decoded one is stored and served
+	response = c.ContentDecoding.received(response)
+
+	if response.StatusCode != http.StatusPartialContent {
+		//the origin ignored the range, the complete response is stored for all ranges
+		req = completeKeyRequest(req)
+	}
+	//cacheable is response with the TTL of NegativeCaching for error responses, the caller gets the origin headers
+	cacheable := c.NegativeCaching.response(response, c.Shared)
+	cacheable, err := c.extractTTL(response, cacheable)
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, err
+	}
+	if !isCacheable(req, cacheable, c.sharedFor(req)) {
+		return response, nil
+	}
+	admitted, err := c.admit(req, response, cacheable)
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, err
+	}
+	if !admitted {
+		return response, nil
+	}
+	cacheable = c.Guardrails.response(cacheable, c.sharedFor(req))
+
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+	if _, err := http.ParseTime(response.Header.Get("Date")); err != nil {
+		//RFC 7231 7.1.1.2: responses without a valid Date get the time they were received
+		response.Header.Set("Date", responded.UTC().Format(http.TimeFormat))
+		if cacheable != response {
+			cacheable.Header.Set("Date", response.Header.Get("Date"))
+		}
+	}
+
+	stored, ok := c.HeaderLimits.limit(storedResponse(cacheable, c.sharedFor(req), c.NoiseHeaders))
+	if !ok {
+		return response, nil
+	}
+	stored = withClock(stored, requested, responded)
+	start := time.Now()
+	transformed, err := c.storeTransform(req, stored)
+	if err == nil {
+		err = c.storeError("set", req, c.Cache.Set(req, transformed))
+	}
+	c.Hooks.store(c.Cache, req, transformed, err, start)
+	//Set replaces the body of the stored response with one the caller can still read, the trailers read by Set are
+	//filled once the caller read it
+	response.Body = stored.Body
+	response = deferTrailer(response)
 
 	if err == nil {
+		c.Events.response(StoredEvent, c.Cache, req, response, false)
+		c.SoftDelete.stored(c.Cache, req)
+		c.Variants.track(c.Cache, req, response, c.VaryNormalizers)
+		c.indexStored(req)
 		return response, nil
 
 	}
+	c.Metrics.storeError()
 	if c.ContinueRoundTripWithSetError == nil {
 		return nil, err
 	}
@@ -64,6 +514,7 @@ func (c *CachedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 }
 
 var NotInCacheError = errors.New("request not in the cache")
+var MigrationNotSupportedError = errors.New("the cache does not support migrating entries")
 
 //DumpRequest dumps the request to bytes using httputil.DumpRequest if includeAllHeaders httputil.DumpRequestOut is used
 func DumpRequest(req *http.Request, ignoreBody bool, dontIncludeAllHeaders bool) ([]byte, error) {
@@ -71,6 +522,9 @@ func DumpRequest(req *http.Request, ignoreBody bool, dontIncludeAllHeaders bool)
 	var dump []byte
 	var err error
 	if !dontIncludeAllHeaders {
+		if dump, ok := dumpRequestOut(req); ok {
+			return []byte(dump), nil
+		}
 		dump, err = httputil.DumpRequestOut(req, !ignoreBody)
 
 	} else {
@@ -84,7 +538,9 @@ func DumpRequest(req *http.Request, ignoreBody bool, dontIncludeAllHeaders bool)
 }
 
 //CopyResponse creates a light copy of the response and the body and
-//reads the body of the input response into a buffer and places a ReaderCloser of the buffers content in both responses
+//reads the body of the input response into a buffer and places a ReaderCloser of the buffers content in both responses.
+//Bodies stored in a file by FileCache or in memory by the caches are opened again instead of being buffered. The
+//bodies of the copies are SeekableBody
 func CopyResponse(response *http.Response) (*http.Response, error) {
 
 	cRes := *response
@@ -92,14 +548,26 @@ func CopyResponse(response *http.Response) (*http.Response, error) {
 	if response.Body == http.NoBody {
 		return &cRes, nil
 	}
-	var buf bytes.Buffer
-	_, err := buf.ReadFrom(response.Body)
+	//the copies of bodies with a deferred trailer get their own trailer, see deferTrailer
+	switch body := response.Body.(type) {
+	case *fileBody:
+		reopened := body.reopen()
+		reopened.trailer = copyTrailer(&cRes, body.trailer)
+		cRes.Body = reopened
+		return &cRes, nil
+	case *bytesBody:
+		reopened := body.reopen()
+		reopened.trailer = copyTrailer(&cRes, body.trailer)
+		cRes.Body = reopened
+		return &cRes, nil
+	}
+	body, err := readBody(response.Body, response.ContentLength)
 	if err != nil {
 		return nil, err
 	}
 
-	response.Body = ioutil.NopCloser(&buf)
-	cRes.Body = ioutil.NopCloser(bytes.NewBuffer(buf.Bytes()))
+	response.Body = newBytesBody(body)
+	cRes.Body = newBytesBody(body)
 	return &cRes, nil
 
 }
@@ -5,11 +5,13 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
 	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -322,6 +324,79 @@ func BenchmarkNewJSONResponse(b *testing.B) {
 	}
 }
 
+//TestNewJsonResponse_SharesCachedBody checks that converting a response served from MapCache does not copy its body
+func TestNewJsonResponse_SharesCachedBody(t *testing.T) {
+
+	cache := NewMapCache()
+	request, err := http.NewRequest("GET", "http://example.com/large", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := strings.Repeat("0123456789abcdef", 1<<12)
+	if err := cache.Set(request, &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body))}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := cache.Get(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := res.Body.(*bytesBody).data
+
+	response, err := NewJsonResponse(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Body) != body || &response.Body[0] != &stored[0] {
+		t.Error("expected the body of the JsonResponse to share the stored bytes")
+	}
+	read, err := ioutil.ReadAll(res.Body)
+	if err != nil || string(read) != body {
+		t.Error("expected the response to be readable after the conversion", err)
+	}
+
+	allocations := testing.AllocsPerRun(100, func() {
+		res, _ := cache.Get(request)
+		_, _ = NewJsonResponse(res)
+	})
+	if allocations > 10 {
+		t.Errorf("converting a cached response allocates %v objects, expected at most 10", allocations)
+	}
+}
+
+//BenchmarkNewJsonResponse_Body compares reading a body of known and unknown length with converting a cached one
+func BenchmarkNewJsonResponse_Body(b *testing.B) {
+
+	body := strings.Repeat("0123456789abcdef", 1<<16)
+	benchmarks := []struct {
+		name     string
+		response func() *http.Response
+	}{
+		{"ContentLength", func() *http.Response {
+			return &http.Response{Header: http.Header{}, ContentLength: int64(len(body)), Body: ioutil.NopCloser(strings.NewReader(body))}
+		}},
+		{"Chunked", func() *http.Response {
+			return &http.Response{Header: http.Header{}, ContentLength: -1, Body: ioutil.NopCloser(strings.NewReader(body))}
+		}},
+		{"Cached", func() *http.Response {
+			return &http.Response{Header: http.Header{}, ContentLength: int64(len(body)), Body: newBytesBody([]byte(body))}
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				res := bm.response()
+				b.StartTimer()
+				if _, err := NewJsonResponse(res); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkJSONResponse_ToResponse(b *testing.B) {
 	benchmarks := []struct {
 		name string
@@ -369,3 +444,124 @@ func BenchmarkJSONResponse_ToResponse(b *testing.B) {
 		})
 	}
 }
+
+//hitTestTransport returns a CachedTransport with the response for hitTestRequest stored in cache
+func hitTestTransport(t testing.TB, cache Cacher) (*CachedTransport, *http.Request) {
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=600")
+		header.Set("Content-Type", "application/json")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(`{"id":1}`)), Request: req}, nil
+	})
+	transport := &CachedTransport{Cache: cache, Fallback: origin}
+	req, err := http.NewRequest("GET", "https://example.com/articles/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return transport, req
+}
+
+//BenchmarkCachedTransport_RoundTrip_Hit measures fresh hits of the in memory caches. A LRUCache hit allocates the
+//response with its body and reader in one object, its header map and the header values, with PoolResponses nothing.
+//MapCache copies the stored response and allocates its key
+func BenchmarkCachedTransport_RoundTrip_Hit(b *testing.B) {
+
+	caches := []struct {
+		name  string
+		cache Cacher
+		pool  bool
+	}{
+		{"MapCache", NewMapCache(), false},
+		{"LRUCache", NewLRUCache(LRUCacheOptions{MaxEntries: 1000}), false},
+		{"LRUCache pooled", NewLRUCache(LRUCacheOptions{MaxEntries: 1000}), true},
+	}
+	for _, c := range caches {
+		b.Run(c.name, func(b *testing.B) {
+			transport, req := hitTestTransport(b, c.cache)
+			transport.PoolResponses = c.pool
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				res, err := transport.RoundTrip(req)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, _ = io.Copy(ioutil.Discard, res.Body)
+				_ = res.Body.Close()
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_HitAllocations(t *testing.T) {
+
+	if raceEnabled {
+		t.Skip("the race detector drops pooled objects")
+	}
+	tests := []struct {
+		name          string
+		pool          bool
+		allocations   float64
+		allocatedWith string
+	}{
+		//the response with its body and reader in one object, the header map and the slice of its values
+		{"not pooled", false, 4, "the response, its header and the header values"},
+		{"pooled", true, 0, "nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, req := hitTestTransport(t, NewLRUCache(LRUCacheOptions{MaxEntries: 1000}))
+			transport.PoolResponses = tt.pool
+			allocations := testing.AllocsPerRun(100, func() {
+				res, err := transport.RoundTrip(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _ = io.Copy(ioutil.Discard, res.Body)
+				_ = res.Body.Close()
+			})
+			if allocations != tt.allocations {
+				t.Errorf("a hit allocates %v objects, expected %v for %s", allocations, tt.allocations, tt.allocatedWith)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_PoolResponses(t *testing.T) {
+
+	transport, req := hitTestTransport(t, NewLRUCache(LRUCacheOptions{MaxEntries: 1000}))
+	transport.PoolResponses = true
+	for i := 0; i < 3; i++ {
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		if string(body) != `{"id":1}` || res.Header.Get("Age") != "0" || res.Header.Get("Content-Type") != "application/json" ||
+			res.Request != req {
+			t.Errorf("unexpected hit %d: %s %v", i, body, res.Header)
+		}
+		res.Header.Set("Content-Type", "text/plain")
+		res.Header.Add("Age", "1")
+		_ = res.Body.Close()
+		//closing again before the response is reused does nothing
+		_ = res.Body.Close()
+	}
+
+	//a stale response passed to the origin is not recycled
+	transport.Clock = NewManualClock(time.Now().Add(time.Hour))
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+	if hitOf(res) != nil {
+		t.Error("expected the response of the origin not to be a pooled hit")
+	}
+}
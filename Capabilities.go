@@ -0,0 +1,86 @@
+package CachedHttpClient
+
+import (
+	"strings"
+)
+
+//CapabilitySet is a set of the features a cache backend supports, see CapabilitiesOf
+type CapabilitySet uint
+
+const (
+	//TTLCapability means the backend expires entries by itself, e.g. a KVCache in Redis
+	TTLCapability CapabilitySet = 1 << iota
+	//IterationCapability means the keys of all entries can be listed with Inspector.Keys, InvalidateMatching and the
+	//admin UI need it
+	IterationCapability
+	//LockingCapability means the backend offers exclusive locks on keys, e.g. to coordinate writers of several
+	//processes. None of the caches of the module has them
+	LockingCapability
+	//CASCapability means the backend can replace an entry only if it did not change since it was read. None of the
+	//caches of the module supports it
+	CASCapability
+	//ByteAccountingCapability means the backend knows the body sizes of its entries without reading them, e.g. through
+	//Peeker
+	ByteAccountingCapability
+)
+
+//capabilityNames are the names of the capabilities in the order of their bits
+var capabilityNames = []string{"ttl", "iteration", "locking", "cas", "byte-accounting"}
+
+//Capable is implemented by caches reporting their capabilities, wrappers like TieredStore report the capabilities of
+//the caches they wrap. Caches implementing their own Capable can remove capabilities CapabilitiesOf would infer, e.g.
+//IterationCapability for a store which can not list its keys
+type Capable interface {
+	Capabilities() CapabilitySet
+}
+
+//Has reports if all capabilities of capability are in the set
+func (s CapabilitySet) Has(capability CapabilitySet) bool {
+	return s&capability == capability
+}
+
+func (s CapabilitySet) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if s.Has(1 << uint(i)) {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+//CapabilitiesOf returns the capabilities of cache. Caches which are no Capable are inspected: an Inspector supports
+//iteration and a Peeker byte accounting
+func CapabilitiesOf(cache Cacher) CapabilitySet {
+
+	if capable, ok := cache.(Capable); ok {
+		return capable.Capabilities()
+	}
+	var capabilities CapabilitySet
+	if _, ok := cache.(Inspector); ok {
+		capabilities |= IterationCapability
+	}
+	if _, ok := cache.(Peeker); ok {
+		capabilities |= ByteAccountingCapability
+	}
+	return capabilities
+}
+
+//commonCapabilities returns the capabilities all caches have, a wrapper storing its entries in several caches has
+//only them
+func commonCapabilities(caches ...Cacher) CapabilitySet {
+	capabilities := ^CapabilitySet(0)
+	for _, cache := range caches {
+		capabilities &= CapabilitiesOf(cache)
+	}
+	return capabilities
+}
+
+//iterableCache returns cache as Inspector if its keys can be listed
+func iterableCache(cache Cacher) (Inspector, bool) {
+	inspector, ok := cache.(Inspector)
+	if !ok || !CapabilitiesOf(cache).Has(IterationCapability) {
+		return nil, false
+	}
+	return inspector, true
+}
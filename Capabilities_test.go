@@ -0,0 +1,72 @@
+package CachedHttpClient
+
+import (
+	"testing"
+)
+
+//unscannableKVStore is a KVStore which can not list its keys like memcached
+type unscannableKVStore struct {
+	*memoryKVStore
+}
+
+func (u unscannableKVStore) SupportsScan() bool {
+	return false
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		cache    Cacher
+		expected CapabilitySet
+	}{
+		{"map", NewMapCache(), IterationCapability | ByteAccountingCapability},
+		{"lru", NewLRUCache(LRUCacheOptions{}), IterationCapability | ByteAccountingCapability},
+		{"kv", NewKVCache(newMemoryKVStore()), TTLCapability | IterationCapability},
+		{"kv without scan", NewKVCache(unscannableKVStore{newMemoryKVStore()}), TTLCapability},
+		{"tiered", NewTieredStore(NewMapCache(), NewKVCache(newMemoryKVStore())), IterationCapability},
+		{"tiered without scan", NewTieredStore(NewMapCache(), NewKVCache(unscannableKVStore{newMemoryKVStore()})), 0},
+		{"no inspector", failingCache{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if capabilities := CapabilitiesOf(tt.cache); capabilities != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, capabilities)
+			}
+		})
+	}
+
+	if s := (TTLCapability | CASCapability).String(); s != "ttl,cas" {
+		t.Error("wrong string", s)
+	}
+}
+
+func TestCachedTransport_InvalidateMatching_WithoutIteration(t *testing.T) {
+
+	transport := &CachedTransport{Cache: NewKVCache(unscannableKVStore{newMemoryKVStore()})}
+	if err := transport.Cache.Set(lruTestRequest(t, "/"), lruTestResponse("content")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transport.InvalidateURL("http://example.com/"); err != InvalidationNotSupportedError {
+		t.Error("expected InvalidationNotSupportedError instead of deleting nothing, got", err)
+	}
+}
+
+func TestCacheStats_ByteAccounting(t *testing.T) {
+
+	cache := NewLRUCache(LRUCacheOptions{})
+	response := lruTestResponse("content")
+	response.Header.Set("Cache-Control", "max-age=60")
+	if err := cache.Set(lruTestRequest(t, "/"), response); err != nil {
+		t.Fatal(err)
+	}
+	stats := CacheStats(cache)
+	if stats.Entries != 1 || stats.Bytes != int64(len("content")) || stats.Statuses["200"] != 1 {
+		t.Error("wrong stats", stats)
+	}
+	//peeking is no use of the entry
+	key, _ := cache.Key(lruTestRequest(t, "/"))
+	if _, _, hits, _ := cache.PeekKey(key); hits != 0 {
+		t.Error("the stats counted", hits, "hits")
+	}
+}
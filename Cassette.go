@@ -0,0 +1,277 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+//NoMatchingInteractionError is returned by Replayer for requests without a matching recorded interaction
+var NoMatchingInteractionError = errors.New("no recorded interaction matches the request")
+
+//Cassette is the content of a fixture file written by Recorder and replayed by Replayer
+type Cassette struct {
+	Interactions []*Interaction
+}
+
+//Interaction is a recorded request and the response of the origin to it
+type Interaction struct {
+	Request  *JsonRequest
+	Response *JsonResponse
+}
+
+//JsonRequest is the recorded part of a request
+type JsonRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte `json:",omitempty"`
+}
+
+//newJsonRequest converts req, its body is read and replaced by a copy
+func newJsonRequest(req *http.Request) (*JsonRequest, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	return &JsonRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: body}, nil
+}
+
+//readRequestBody reads the body of req and replaces it by a copy
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+//LoadCassette reads the fixture file at filePath
+func LoadCassette(filePath string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	err = json.Unmarshal(content, &cassette)
+	if err != nil {
+		return nil, err
+	}
+	return &cassette, nil
+}
+
+//Save writes the cassette as indented JSON to filePath so fixtures can be reviewed in diffs
+func (c *Cassette) Save(filePath string) error {
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, append(content, '\n'), 0644)
+}
+
+//Recorder is a http.RoundTripper sending the requests to Fallback and recording the interactions, Save writes them
+//to the fixture file
+type Recorder struct {
+	Fallback http.RoundTripper
+	filePath string
+	mutex    sync.Mutex
+	cassette Cassette
+}
+
+//NewRecorder creates a Recorder for the fixture file at filePath, fallback is http.DefaultTransport if nil
+func NewRecorder(filePath string, fallback http.RoundTripper) *Recorder {
+	if fallback == nil {
+		fallback = http.DefaultTransport
+	}
+	return &Recorder{Fallback: fallback, filePath: filePath}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	request, err := newJsonRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.Fallback.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := NewJsonResponse(res)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{Request: request, Response: response})
+	return res, nil
+}
+
+//Save writes the recorded interactions to the fixture file
+func (r *Recorder) Save() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.cassette.Save(r.filePath)
+}
+
+//Matcher compares a request with a recorded one and returns an error describing the difference if they do not match
+type Matcher func(req *http.Request, body []byte, recorded *JsonRequest) error
+
+//DefaultMatchers match requests by method and URL
+var DefaultMatchers = []Matcher{MatchMethod, MatchURL}
+
+//MatchMethod matches the request method
+func MatchMethod(req *http.Request, body []byte, recorded *JsonRequest) error {
+	return diff("method", recorded.Method, req.Method)
+}
+
+//MatchURL matches the full request URL including the query
+func MatchURL(req *http.Request, body []byte, recorded *JsonRequest) error {
+	return diff("url", recorded.URL, req.URL.String())
+}
+
+//MatchBody matches the request body byte by byte
+func MatchBody(req *http.Request, body []byte, recorded *JsonRequest) error {
+	return diff("body", string(recorded.Body), string(body))
+}
+
+//MatchHeaders returns a Matcher matching the values of the named request headers
+func MatchHeaders(names ...string) Matcher {
+	return func(req *http.Request, body []byte, recorded *JsonRequest) error {
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			err := diff("header "+name, strings.Join(recorded.Header[name], ", "), strings.Join(req.Header[name], ", "))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//diff returns an error showing the recorded and the requested value of field if they differ
+func diff(field string, recorded string, requested string) error {
+	if recorded == requested {
+		return nil
+	}
+	return fmt.Errorf("%s:\n-\t%s\n+\t%s", field, recorded, requested)
+}
+
+//Replayer is a http.RoundTripper answering requests with the responses of a Cassette without contacting an origin.
+//Every interaction is replayed once in recorded order before interactions are repeated
+type Replayer struct {
+	cassette *Cassette
+	matchers []Matcher
+	mutex    sync.Mutex
+	replayed []bool
+}
+
+//NewReplayer creates a Replayer for cassette matching requests with matchers, DefaultMatchers if none are given
+func NewReplayer(cassette *Cassette, matchers ...Matcher) *Replayer {
+	if len(matchers) == 0 {
+		matchers = DefaultMatchers
+	}
+	return &Replayer{cassette: cassette, matchers: matchers, replayed: make([]bool, len(cassette.Interactions))}
+}
+
+//OpenReplayer creates a Replayer for the fixture file at filePath, see NewReplayer
+func OpenReplayer(filePath string, matchers ...Matcher) (*Replayer, error) {
+	cassette, err := LoadCassette(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(cassette, matchers...), nil
+}
+
+//RoundTrip returns the response of the matching interaction, requests without one fail with
+//NoMatchingInteractionError and the differences to the closest recorded request
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	index := -1
+	var closest []error
+	for i, interaction := range r.cassette.Interactions {
+		mismatches := r.mismatches(req, body, interaction.Request)
+		if len(mismatches) == 0 {
+			if !r.replayed[i] {
+				index = i
+				break
+			}
+			if index < 0 {
+				index = i
+			}
+			continue
+		}
+		if closest == nil || len(mismatches) < len(closest) {
+			closest = mismatches
+		}
+	}
+	if index >= 0 {
+		r.replayed[index] = true
+	}
+	r.mutex.Unlock()
+
+	if index < 0 {
+		return nil, mismatchError(req, closest)
+	}
+
+	res, err := r.cassette.Interactions[index].Response.Parse()
+	if err != nil {
+		return nil, err
+	}
+	res.Request = req
+	return res, nil
+}
+
+//mismatches returns the errors of the matchers not matching recorded
+func (r *Replayer) mismatches(req *http.Request, body []byte, recorded *JsonRequest) []error {
+	var mismatches []error
+	for _, matcher := range r.matchers {
+		if err := matcher(req, body, recorded); err != nil {
+			mismatches = append(mismatches, err)
+		}
+	}
+	return mismatches
+}
+
+//mismatchError wraps NoMatchingInteractionError with the differences to the closest recorded request
+func mismatchError(req *http.Request, closest []error) error {
+	if closest == nil {
+		return fmt.Errorf("%w: %s %s, the cassette is empty", NoMatchingInteractionError, req.Method, req.URL)
+	}
+	differences := make([]string, len(closest))
+	for i, mismatch := range closest {
+		differences[i] = mismatch.Error()
+	}
+	return fmt.Errorf("%w: %s %s, closest recorded request differs in\n%s", NoMatchingInteractionError, req.Method, req.URL, strings.Join(differences, "\n"))
+}
+
+//Used reports if every recorded interaction was replayed, e.g. to detect stale fixtures at the end of a test
+func (r *Replayer) Used() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, replayed := range r.replayed {
+		if !replayed {
+			return false
+		}
+	}
+	return true
+}
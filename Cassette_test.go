@@ -0,0 +1,130 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_Replayer(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		writer.Header().Set("X-Method", r.Method)
+		_, _ = io.WriteString(writer, r.URL.Path+" "+string(body))
+	}))
+	defer server.Close()
+
+	fixture := "tmp/fixture.json"
+	recorder := NewRecorder(fixture, server.Client().Transport)
+	client := http.Client{Transport: recorder}
+
+	response, err := client.Get(server.URL + "/a")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = response.Body.Close()
+	response, err = client.Post(server.URL+"/b", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(response.Body); string(body) != "/b payload" {
+		t.Error("expected the origin response got", string(body))
+	}
+	_ = response.Body.Close()
+
+	err = recorder.Save()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	server.Close()
+
+	replayer, err := OpenReplayer(fixture, MatchMethod, MatchURL, MatchBody)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	client = http.Client{Transport: replayer}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		expected string
+		diff     string
+	}{
+		{"get", "GET", "/a", "", "/a ", ""},
+		{"post", "POST", "/b", "payload", "/b payload", ""},
+		{"repeated", "GET", "/a", "", "/a ", ""},
+		{"other body", "POST", "/b", "other", "", "body:\n-\tpayload\n+\tother"},
+		{"other url", "GET", "/c", "", "", "url:\n-\t" + server.URL + "/a\n+\t" + server.URL + "/c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			response, err := client.Do(request)
+			if tt.diff != "" {
+				if !errors.Is(err, NoMatchingInteractionError) {
+					t.Error("expected NoMatchingInteractionError got", err)
+				} else if !strings.Contains(err.Error(), tt.diff) {
+					t.Errorf("expected the diff %q in %q", tt.diff, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if body, _ := ioutil.ReadAll(response.Body); string(body) != tt.expected {
+				t.Error("expected", tt.expected, "got", string(body))
+			}
+			if response.Header.Get("X-Method") != tt.method {
+				t.Error("expected the recorded headers got", response.Header)
+			}
+		})
+	}
+
+	if !replayer.Used() {
+		t.Error("expected all interactions to be replayed")
+	}
+}
+
+func TestMatchHeaders(t *testing.T) {
+
+	recorded := &JsonRequest{Method: "GET", URL: "http://example.com/", Header: http.Header{"Accept": {"application/json"}}}
+	matcher := MatchHeaders("accept")
+
+	tests := []struct {
+		name    string
+		accept  string
+		matches bool
+	}{
+		{"same", "application/json", true},
+		{"different", "text/html", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, _ := http.NewRequest("GET", "http://example.com/", nil)
+			if tt.accept != "" {
+				request.Header.Set("Accept", tt.accept)
+			}
+			if err := matcher(request, nil, recorded); (err == nil) != tt.matches {
+				t.Error("expected match", tt.matches, "got", err)
+			}
+		})
+	}
+}
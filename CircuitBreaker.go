@@ -0,0 +1,192 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var CircuitOpenError = errors.New("the circuit breaker of the host is open")
+
+//CircuitState is the state of the circuit of a host
+type CircuitState int
+
+const (
+	//CircuitClosed sends requests to the host
+	CircuitClosed CircuitState = iota
+	//CircuitOpen fails requests to the host without sending them
+	CircuitOpen
+	//CircuitHalfOpen sends probe requests to the host to decide if the circuit is closed again
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+//CircuitBreaker stops sending requests to a failing host. After FailureThreshold consecutive failures, errors or 5xx
+//responses, the circuit of the host opens and origin requests fail with CircuitOpenError, stale responses are served
+//within their stale-if-error window or always with ServeStale. After OpenTimeout up to HalfOpenRequests probes are
+//sent, the circuit closes if they succeed and opens again if one fails
+type CircuitBreaker struct {
+	//FailureThreshold is the number of consecutive failures opening the circuit, 5 if 0
+	FailureThreshold int
+	//OpenTimeout is the time the circuit stays open before probes are sent, 30s if 0
+	OpenTimeout time.Duration
+	//HalfOpenRequests is the number of concurrent probes in the half-open state, 1 if 0
+	HalfOpenRequests int
+	//ServeStale serves stale responses while the circuit is open even outside their stale-if-error window
+	ServeStale bool
+	//OnStateChange is called when the circuit of a host changes its state if not nil
+	OnStateChange func(host string, from CircuitState, to CircuitState)
+	//Clock tells the time circuits open at and when OpenTimeout passed, SystemClock if nil
+	Clock Clock
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func (b *CircuitBreaker) failureThreshold() int {
+	if b.FailureThreshold <= 0 {
+		return 5
+	}
+	return b.FailureThreshold
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	if b.OpenTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return b.OpenTimeout
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	return clockOrDefault(b.Clock).Now()
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return b.HalfOpenRequests
+}
+
+//State returns the state of the circuit of host
+func (b *CircuitBreaker) State(host string) CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if c, ok := b.circuits[host]; ok {
+		if c.state == CircuitOpen && b.now().Sub(c.openedAt) >= b.openTimeout() {
+			return CircuitHalfOpen
+		}
+		return c.state
+	}
+	return CircuitClosed
+}
+
+//allow admits a request to host, done records its outcome. Requests to an open circuit fail with CircuitOpenError
+func (b *CircuitBreaker) allow(host string) (done func(res *http.Response, err error), err error) {
+
+	if b == nil {
+		return func(*http.Response, error) {}, nil
+	}
+
+	b.mutex.Lock()
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+	var changed func()
+	if c.state == CircuitOpen && b.now().Sub(c.openedAt) >= b.openTimeout() {
+		changed = b.setState(host, c, CircuitHalfOpen)
+	}
+	probe := c.state == CircuitHalfOpen
+	if c.state == CircuitOpen || probe && c.probes >= b.halfOpenRequests() {
+		retryAt := c.openedAt.Add(b.openTimeout())
+		b.mutex.Unlock()
+		if changed != nil {
+			changed()
+		}
+		return nil, fmt.Errorf("%w: %s until %s", CircuitOpenError, host, retryAt.Format(time.RFC3339))
+	}
+	if probe {
+		c.probes++
+	}
+	b.mutex.Unlock()
+	if changed != nil {
+		changed()
+	}
+
+	return func(res *http.Response, err error) {
+		b.record(host, c, probe, res, err)
+	}, nil
+}
+
+//record updates the circuit c of host with the outcome of a request
+func (b *CircuitBreaker) record(host string, c *circuit, probe bool, res *http.Response, err error) {
+
+	//requests cancelled by the caller say nothing about the host
+	cancelled := errors.Is(err, context.Canceled)
+
+	b.mutex.Lock()
+	if probe {
+		c.probes--
+	}
+	var changed func()
+	switch {
+	case cancelled:
+	case !isOriginError(res, err):
+		c.failures = 0
+		if c.state != CircuitClosed {
+			changed = b.setState(host, c, CircuitClosed)
+		}
+	default:
+		c.failures++
+		if c.state == CircuitHalfOpen || c.state == CircuitClosed && c.failures >= b.failureThreshold() {
+			c.openedAt = b.now()
+			changed = b.setState(host, c, CircuitOpen)
+		}
+	}
+	b.mutex.Unlock()
+	if changed != nil {
+		changed()
+	}
+}
+
+//setState changes the state of c and returns the call of OnStateChange to make after the mutex is released
+func (b *CircuitBreaker) setState(host string, c *circuit, state CircuitState) func() {
+	from := c.state
+	c.state = state
+	if b.OnStateChange == nil || from == state {
+		return nil
+	}
+	return func() { b.OnStateChange(host, from, state) }
+}
+
+//servesStale reports if the stale response is served for err although it is outside its stale-if-error window
+func (b *CircuitBreaker) servesStale(err error) bool {
+	return b != nil && b.ServeStale && errors.Is(err, CircuitOpenError)
+}
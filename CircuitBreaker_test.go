@@ -0,0 +1,98 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_CircuitBreaker(t *testing.T) {
+
+	requests := 0
+	failing := true
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		if failing {
+			return nil, errors.New("connection refused")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+	var changes []string
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	breaker := &CircuitBreaker{FailureThreshold: 2, OpenTimeout: time.Minute, Clock: clock,
+		OnStateChange: func(host string, from CircuitState, to CircuitState) { changes = append(changes, to.String()) }}
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, CircuitBreaker: breaker}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get("http://example.com/"); err == nil || errors.Is(err, CircuitOpenError) {
+			t.Error("expected the origin error got", err)
+		}
+	}
+	if _, err := client.Get("http://example.com/"); !errors.Is(err, CircuitOpenError) {
+		t.Error("expected CircuitOpenError got", err)
+	}
+	if requests != 2 || breaker.State("example.com") != CircuitOpen {
+		t.Error("expected the open circuit to stop the requests got", requests, breaker.State("example.com"))
+	}
+	if _, err := client.Get("http://other.com/"); errors.Is(err, CircuitOpenError) {
+		t.Error("expected a circuit per host")
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := client.Get("http://example.com/"); err == nil || errors.Is(err, CircuitOpenError) {
+		t.Error("expected the failing probe to reach the origin got", err)
+	}
+	if breaker.State("example.com") != CircuitOpen {
+		t.Error("expected the failed probe to open the circuit again got", breaker.State("example.com"))
+	}
+
+	clock.Advance(time.Minute)
+	failing = false
+	if _, err := client.Get("http://example.com/"); err != nil {
+		t.Error(err)
+	}
+	if breaker.State("example.com") != CircuitClosed {
+		t.Error("expected the successful probe to close the circuit got", breaker.State("example.com"))
+	}
+	expected := "open,half-open,open,half-open,closed"
+	if strings.Join(changes, ",") != expected {
+		t.Error("expected the state changes", expected, "got", changes)
+	}
+}
+
+func TestCachedTransport_RoundTrip_CircuitBreakerServeStale(t *testing.T) {
+
+	failing := false
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=0")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("stale")), Request: req}, nil
+	})
+	breaker := &CircuitBreaker{FailureThreshold: 1, OpenTimeout: time.Hour, ServeStale: true}
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, CircuitBreaker: breaker}}
+
+	if _, err := client.Get("http://example.com/"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	failing = true
+	response, err := client.Get("http://example.com/")
+	if err != nil || response.StatusCode != http.StatusServiceUnavailable {
+		t.Error("expected the origin error without stale-if-error window got", err)
+		t.FailNow()
+	}
+	response, err = client.Get("http://example.com/")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(response.Body); string(body) != "stale" {
+		t.Error("expected the stale response while the circuit is open got", response.StatusCode, string(body))
+	}
+}
@@ -0,0 +1,130 @@
+package CachedHttpClient
+
+import (
+	"sync"
+	"time"
+)
+
+//Clock tells the time the freshness of responses is computed with and drives the periodic work of Janitor and
+//CollectSoftDeletedEvery. SystemClock is used if nil, tests can set a ManualClock to let entries expire without
+//sleeping
+type Clock interface {
+	Now() time.Time
+	//NewTicker returns a Ticker sending the time every d like time.NewTicker
+	NewTicker(d time.Duration) Ticker
+}
+
+//Ticker delivers ticks of a Clock like a time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+//SystemClock is the Clock of the time package
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+func clockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+	return clock
+}
+
+//now returns the time of the Clock of the transport
+func (c *CachedTransport) now() time.Time {
+	return clockOrDefault(c.Clock).Now()
+}
+
+//ManualClock is a Clock which only moves when it is advanced, e.g. to let cached responses expire in tests. Its
+//tickers tick when Advance passes their times, like a time.Ticker it drops ticks for slow receivers
+type ManualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*manualTicker
+}
+
+//NewManualClock returns a ManualClock standing at now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (m *ManualClock) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.now
+}
+
+//Advance moves the clock forward by d and delivers the ticks which became due, a ticker passed several times ticks
+//once
+func (m *ManualClock) Advance(d time.Duration) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.now = m.now.Add(d)
+
+	for _, ticker := range m.tickers {
+		if ticker.next.After(m.now) {
+			continue
+		}
+		select {
+		case ticker.c <- ticker.next:
+		default:
+		}
+		ticker.next = ticker.next.Add((m.now.Sub(ticker.next)/ticker.period + 1) * ticker.period)
+	}
+}
+
+func (m *ManualClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for ManualClock.NewTicker")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ticker := &manualTicker{clock: m, c: make(chan time.Time, 1), period: d, next: m.now.Add(d)}
+	m.tickers = append(m.tickers, ticker)
+	return ticker
+}
+
+type manualTicker struct {
+	clock  *ManualClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	for i, ticker := range t.clock.tickers {
+		if ticker == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
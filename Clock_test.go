@@ -0,0 +1,114 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_Clock(t *testing.T) {
+
+	//the clock is far in the past, the responses would be stale for the system clock
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	origin := 0
+	transport := &CachedTransport{Cache: NewMapCache(), Clock: clock, StatusHeaders: true,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			origin++
+			res := lruTestResponse("content")
+			res.Header.Set("Cache-Control", "max-age=60")
+			return res, nil
+		})}
+
+	steps := []struct {
+		advance  time.Duration
+		expected string
+		origin   int
+	}{
+		{0, CacheMiss, 1},
+		{59 * time.Second, CacheHit, 1},
+		{2 * time.Second, CacheMiss, 2},
+		{30 * time.Second, CacheHit, 2},
+	}
+	for i, step := range steps {
+		clock.Advance(step.advance)
+		res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if status := res.Header.Get(CacheStatusHeader); status != step.expected || origin != step.origin {
+			t.Errorf("step %d: expected %s after %d origin requests, got %s after %d", i, step.expected, step.origin, status, origin)
+		}
+	}
+}
+
+func TestManualClock_NewTicker(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	ticker := clock.NewTicker(10 * time.Second)
+
+	clock.Advance(5 * time.Second)
+	select {
+	case tick := <-ticker.C():
+		t.Error("unexpected tick", tick)
+	default:
+	}
+
+	//the ticks at 10, 20 and 30 seconds are delivered as one
+	clock.Advance(25 * time.Second)
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(10 * time.Second)) {
+			t.Error("expected the first due tick, got", tick)
+		}
+	default:
+		t.Error("expected a tick")
+	}
+	select {
+	case tick := <-ticker.C():
+		t.Error("unexpected tick", tick)
+	default:
+	}
+
+	clock.Advance(10 * time.Second)
+	if tick := <-ticker.C(); !tick.Equal(start.Add(40 * time.Second)) {
+		t.Error("expected the tick at 40 seconds, got", tick)
+	}
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	select {
+	case tick := <-ticker.C():
+		t.Error("unexpected tick after Stop", tick)
+	default:
+	}
+}
+
+func TestJanitor_Clock(t *testing.T) {
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache := NewMapCache()
+	response := lruTestResponse("content")
+	response.Header.Set("Cache-Control", "max-age=60")
+	response.Header.Set("Date", clock.Now().Format(http.TimeFormat))
+	if err := cache.Set(lruTestRequest(t, "/"), response); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := NewMetrics()
+	janitor := &Janitor{Cache: cache, Interval: time.Minute, Clock: clock, Metrics: metrics}
+	if deleted, _, err := janitor.Collect(); err != nil || deleted != 0 {
+		t.Error("expected the fresh entry to be kept, got", deleted, err)
+	}
+
+	janitor.Start()
+	defer janitor.Stop()
+	clock.Advance(2 * time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.Stats().Reclaimed == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := metrics.Stats(); stats.Reclaimed != 1 {
+		t.Errorf("expected the expired entry to be reclaimed on the tick, got %+v", stats)
+	}
+}
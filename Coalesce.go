@@ -0,0 +1,137 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//Coalescer deduplicates concurrent origin requests for the same cache key, only the first request is sent and its
+//response is shared with all requests arriving while it is in flight. The zero value is ready to use
+type Coalescer struct {
+	mutex sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+//NewCoalescer creates a Coalescer for CachedTransport.Coalescer
+func NewCoalescer() *Coalescer {
+	return &Coalescer{}
+}
+
+type coalescedCall struct {
+	done    chan struct{}
+	waiters int
+
+	response *http.Response
+	body     []byte
+	err      error
+}
+
+//do calls fetch for the first caller of key, concurrent callers with the same key wait for its result and receive a
+//copy of the response with their own body reader. shared reports if the response was fetched by another caller
+func (g *Coalescer) do(ctx context.Context, key string, fetch func() (*http.Response, error)) (res *http.Response, shared bool, err error) {
+
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*coalescedCall{}
+	}
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		g.mutex.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+		if call.err != nil {
+			return nil, true, call.err
+		}
+		return call.copyResponse(), true, nil
+	}
+
+	call := &coalescedCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mutex.Unlock()
+
+	call.response, call.err = fetch()
+
+	g.mutex.Lock()
+	delete(g.calls, key)
+	waiters := call.waiters
+	g.mutex.Unlock()
+
+	//the body is only buffered if there is someone to share it with
+	if waiters > 0 && call.err == nil {
+		call.body, call.err = bufferBody(call.response)
+	}
+	close(call.done)
+
+	return call.response, false, call.err
+}
+
+//copyResponse returns a copy of the response with its own header and body
+func (call *coalescedCall) copyResponse() *http.Response {
+	res := *call.response
+	res.Header = call.response.Header.Clone()
+	if call.response.Body == http.NoBody {
+		return &res
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(call.body))
+	return &res
+}
+
+//coalescedFetch runs fetch through the Coalescer of the transport if there is one
+func (c *CachedTransport) coalescedFetch(req *http.Request, keyReq *http.Request, stale *http.Response) (*http.Response, error) {
+
+	if c.Coalescer == nil || !policy.CacheableMethod(req.Method) && !isPostCached(keyReq) {
+		return c.fetch(req, keyReq, stale)
+	}
+
+	key, err := c.coalescingKey(keyReq)
+	if err != nil {
+		return c.fetch(req, keyReq, stale)
+	}
+
+	res, shared, err := c.Coalescer.do(req.Context(), key, func() (*http.Response, error) {
+		return c.fetch(req, keyReq, stale)
+	})
+	if !shared {
+		return res, err
+	}
+
+	//a cancelled leader or a response for another variant is no reason to fail, the request is sent on its own
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) && req.Context().Err() == nil {
+		return c.fetch(req, keyReq, stale)
+	}
+	if err == nil && !varyMatches(keyReq, res, c.VaryNormalizers) {
+		_ = res.Body.Close()
+		return c.fetch(req, keyReq, stale)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res.Request = req
+	return res, nil
+}
+
+//coalescingKey returns the key requests are deduplicated by, the cache key if the cache exposes it
+func (c *CachedTransport) coalescingKey(req *http.Request) (string, error) {
+
+	if keyer, ok := c.Cache.(Keyer); ok {
+		return keyer.Key(req)
+	}
+
+	dump, err := DumpRequest(req, true, false)
+	if err != nil {
+		return "", err
+	}
+	//the principal may only be known from the context, the responses of two partitions are never shared
+	return withKeyLine(string(dump), PrincipalHeader, principalFromContext(req.Context())), nil
+}
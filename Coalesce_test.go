@@ -0,0 +1,72 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_Coalescing(t *testing.T) {
+
+	var requests int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		_, _ = writer.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	coalescer := NewCoalescer()
+	client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport, Coalescer: coalescer}}
+
+	const clients = 10
+	var wg sync.WaitGroup
+	bodies := make([]string, clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			body, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			bodies[i] = string(body)
+		}(i)
+	}
+
+	//wait until all clients either wait for the first request or are in flight
+	waiting := func() int {
+		coalescer.mutex.Lock()
+		defer coalescer.mutex.Unlock()
+		for _, call := range coalescer.calls {
+			return call.waiters
+		}
+		return 0
+	}
+	for i := 0; i < 500 && waiting() < clients-1; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if requests != 1 {
+		t.Error("expected one origin request, got", requests)
+	}
+	for _, body := range bodies {
+		if body != "content" {
+			t.Error("wrong body", body)
+		}
+	}
+
+}
@@ -0,0 +1,112 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+//Codec encodes the entries of a cache file, see JSONCodec and GobCodec
+type Codec interface {
+	//Encode writes entry to w with a single Write
+	Encode(w io.Writer, entry *FileCacheEntry) error
+	//NewDecoder returns a decoder reading the entries written by Encode from r
+	NewDecoder(r io.Reader) EntryDecoder
+}
+
+//EntryDecoder reads the entries of a cache file one after another, Decode returns io.EOF after the last entry
+type EntryDecoder interface {
+	Decode(entry *FileCacheEntry) error
+}
+
+//JSONCodec stores one JSON encoded entry per line, it is the default of FileCache
+var JSONCodec Codec = jsonCodec{}
+
+//GobCodec stores the entries encoded with encoding/gob, bodies are stored as they are instead of base64 encoded
+var GobCodec Codec = gobCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, entry *FileCacheEntry) error {
+	//Encoder ends the line with a newline like the entries were written with json.Marshal before
+	line := getBuffer()
+	defer putBuffer(line)
+	err := json.NewEncoder(line).Encode(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(line.Bytes())
+	return err
+}
+
+func (jsonCodec) NewDecoder(r io.Reader) EntryDecoder {
+	return jsonDecoder{json.NewDecoder(r)}
+}
+
+type jsonDecoder struct {
+	decoder *json.Decoder
+}
+
+func (d jsonDecoder) Decode(entry *FileCacheEntry) error {
+	return d.decoder.Decode(entry)
+}
+
+//gobCodec prefixes every entry with its length, each entry is encoded by its own gob.Encoder so the file stays
+//readable when entries are appended after reopening it
+type gobCodec struct{}
+
+func (gobCodec) Encode(w io.Writer, entry *FileCacheEntry) error {
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	err := gob.NewEncoder(buf).Encode(entry)
+	if err != nil {
+		return err
+	}
+
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(buf.Len()))
+	out := getBuffer()
+	defer putBuffer(out)
+	out.Write(length[:n])
+	out.Write(buf.Bytes())
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+func (gobCodec) NewDecoder(r io.Reader) EntryDecoder {
+	return &gobDecoder{reader: bufio.NewReader(r)}
+}
+
+type gobDecoder struct {
+	reader *bufio.Reader
+}
+
+func (d *gobDecoder) Decode(entry *FileCacheEntry) error {
+
+	length, err := binary.ReadUvarint(d.reader)
+	if err != nil {
+		return err
+	}
+
+	if length > math.MaxInt32 {
+		return errors.New("gob entry too large")
+	}
+	//the buffer grows with the bytes read, a corrupted length does not allocate it at once. The decoded entry copies
+	//what it keeps so the buffer is reused
+	encoded := getBuffer()
+	defer putBuffer(encoded)
+	_, err = io.CopyN(encoded, d.reader, int64(length))
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	return gob.NewDecoder(encoded).Decode(entry)
+}
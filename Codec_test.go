@@ -0,0 +1,154 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileCache_Codec(t *testing.T) {
+
+	body := bytes.Repeat([]byte{0, 1, 2, 250, 251, 252}, 10000)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		_, _ = writer.Write(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		codec Codec
+		//tls is false for the codecs not storing the TLS state
+		tls bool
+	}{
+		{"json", JSONCodec, true},
+		{"gob", GobCodec, true},
+		{"wire", WireCodec, false},
+	}
+	sizes := map[string]int64{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := "tmp/codec-" + tt.name + ".cache"
+			fileCache, err := NewFileCache(filePath, FileCacheOptions{Codec: tt.codec})
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			client := http.Client{Transport: &CachedTransport{Cache: fileCache, Fallback: server.Client().Transport}}
+			for _, path := range []string{"/a", "/b"} {
+				res, err := client.Get(server.URL + path)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				_ = res.Body.Close()
+			}
+			for _, key := range fileCache.Keys() {
+				if strings.Contains(key, "/b") {
+					if err := fileCache.DeleteKey(key); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+
+			reopened, err := OpenFileCache(filePath, FileCacheOptions{Codec: tt.codec})
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			keys := reopened.Keys()
+			if len(keys) != 1 {
+				t.Error("expected 1 entry after reopening, got", keys)
+				t.FailNow()
+			}
+			res, err := reopened.GetKey(keys[0])
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			cached, _ := ioutil.ReadAll(res.Body)
+			if !bytes.Equal(cached, body) {
+				t.Error("the body changed after reopening")
+			}
+			if tt.tls && (res.TLS == nil || len(res.TLS.PeerCertificates) == 0) {
+				t.Error("expected the TLS state to be restored")
+			}
+
+			info, err := os.Stat(filePath)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			sizes[tt.name] = info.Size()
+		})
+	}
+
+	if sizes["gob"] >= sizes["json"] {
+		t.Error("expected the gob file to be smaller than the JSON file", sizes)
+	}
+	if sizes["wire"] >= sizes["json"] {
+		t.Error("expected the wire file to be smaller than the JSON file", sizes)
+	}
+}
+
+//codecBenchmarkEntry returns an entry with a body of size bytes
+func codecBenchmarkEntry(size int) *FileCacheEntry {
+	header := http.Header{}
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set("Cache-Control", "max-age=600")
+	response := &JsonResponse{Status: "200 OK", StatusCode: http.StatusOK, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+		Header: header, Body: bytes.Repeat([]byte{0, 1, 2, 250, 251, 252, 'a', 'b'}, size/8), ContentLength: int64(size)}
+	return &FileCacheEntry{Version: EntrySchemaVersion, Request: "GET http://example.com/large", Response: response}
+}
+
+func BenchmarkCodec_Encode(b *testing.B) {
+
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{{"json", JSONCodec}, {"gob", GobCodec}, {"wire", WireCodec}}
+	for _, c := range codecs {
+		for _, size := range []int{1 << 10, 1 << 20} {
+			entry := codecBenchmarkEntry(size)
+			b.Run(fmt.Sprintf("%s/%d", c.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					if err := c.codec.Encode(ioutil.Discard, entry); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkCodec_Decode(b *testing.B) {
+
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{{"json", JSONCodec}, {"gob", GobCodec}, {"wire", WireCodec}}
+	for _, c := range codecs {
+		for _, size := range []int{1 << 10, 1 << 20} {
+			var encoded bytes.Buffer
+			if err := c.codec.Encode(&encoded, codecBenchmarkEntry(size)); err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("%s/%d", c.name, size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(size))
+				for i := 0; i < b.N; i++ {
+					var entry FileCacheEntry
+					if err := c.codec.NewDecoder(bytes.NewReader(encoded.Bytes())).Decode(&entry); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime"
+	"strings"
+	"sync"
+)
+
+//Compressor compresses the bodies of stored entries, see GzipCompressor
+type Compressor interface {
+	//Encoding names the compression in stored entries, e.g. "gzip"
+	Encoding() string
+	Compress(body []byte) ([]byte, error)
+	Decompress(body []byte) ([]byte, error)
+}
+
+//UnknownCompressionError is returned for entries compressed by a Compressor which is not registered
+var UnknownCompressionError = errors.New("unknown body compression")
+
+//GzipCompressor compresses bodies with gzip
+var GzipCompressor Compressor = gzipCompressor{level: gzip.DefaultCompression}
+
+var compressorsMutex sync.RWMutex
+var compressors = map[string]Compressor{GzipCompressor.Encoding(): GzipCompressor}
+
+//RegisterCompressor makes the entries compressed by compressor readable, GzipCompressor is registered by default
+func RegisterCompressor(compressor Compressor) {
+	compressorsMutex.Lock()
+	defer compressorsMutex.Unlock()
+	compressors[compressor.Encoding()] = compressor
+}
+
+func compressorFor(encoding string) (Compressor, error) {
+	compressorsMutex.RLock()
+	defer compressorsMutex.RUnlock()
+	compressor, ok := compressors[encoding]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", UnknownCompressionError, encoding)
+	}
+	return compressor, nil
+}
+
+//Compression selects the bodies which are stored compressed
+type Compression struct {
+	//Compressor is GzipCompressor if nil, other compressors have to be registered with RegisterCompressor
+	Compressor Compressor
+	//MinSize is the size in bytes below which bodies are stored uncompressed
+	MinSize int
+	//ContentTypes are the media types which are compressed, entries ending with "/" match all subtypes, e.g.
+	//"text/". All content types are compressed if empty
+	ContentTypes []string
+	//Adaptive skips bodies of compressed media types like images, video and archives and bodies whose sampled byte
+	//entropy shows they are already compressed. The decision is recorded in JsonResponse.CompressionDecision
+	Adaptive bool
+	//Headers stores the header fields of every entry encoded with a static table of common fields, independent of
+	//the selection of the body. It saves most of the size of entries with tiny bodies, see JsonResponse.CompressedHeader
+	Headers bool
+}
+
+//entropySampleSize is the number of bytes at the start of a body the entropy is computed of
+const entropySampleSize = 4096
+
+//maxCompressibleEntropy is the byte entropy in bits above which Adaptive compression skips a body, compressed and
+//encrypted data is close to 8
+const maxCompressibleEntropy = 7.5
+
+//compressedMediaTypes are media types whose content is compressed by their format, entries ending with "/" match all
+//subtypes
+var compressedMediaTypes = []string{
+	"image/", "video/", "audio/", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2", "application/x-xz",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/zstd", "application/pdf",
+}
+
+//uncompressedMediaTypes are exceptions of compressedMediaTypes
+var uncompressedMediaTypes = []string{"image/svg+xml", "image/bmp", "image/x-icon"}
+
+//compress compresses the body of response if it is selected and the header fields if Headers is set
+func (c *Compression) compress(response *JsonResponse) error {
+
+	if c == nil {
+		return nil
+	}
+	//the header fields are compressed last, selecting the body reads them
+	if err := c.compressBody(response); err != nil {
+		return err
+	}
+	if c.Headers {
+		compressHeader(response)
+	}
+	return nil
+}
+
+//compressBody replaces the body of response with its compressed form if it is selected and gets smaller
+func (c *Compression) compressBody(response *JsonResponse) error {
+
+	if len(response.Body) == 0 || len(response.Body) < c.MinSize || response.BodyCompression != "" {
+		return nil
+	}
+	//bodies the origin already encoded gain nothing from a second compression
+	if response.Header.Get("Content-Encoding") != "" || !c.compressesContentType(response.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	if c.Adaptive {
+		if decision, skip := adaptiveDecision(response.Header.Get("Content-Type"), response.Body); skip {
+			response.CompressionDecision = decision
+			return nil
+		}
+	}
+
+	compressor := c.Compressor
+	if compressor == nil {
+		compressor = GzipCompressor
+	}
+	compressed, err := compressor.Compress(response.Body)
+	if err != nil {
+		return err
+	}
+	if len(compressed) >= len(response.Body) {
+		if c.Adaptive {
+			response.CompressionDecision = "skipped: no size reduction"
+		}
+		return nil
+	}
+
+	if c.Adaptive {
+		response.CompressionDecision = fmt.Sprintf("compressed: %d of %d bytes", len(compressed), len(response.Body))
+	}
+	response.Body = compressed
+	response.BodyCompression = compressor.Encoding()
+	return nil
+}
+
+//adaptiveDecision reports if a body should be stored uncompressed because of its content type or entropy and why
+func adaptiveDecision(contentType string, body []byte) (string, bool) {
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && matchesMediaType(mediaType, compressedMediaTypes) && !matchesMediaType(mediaType, uncompressedMediaTypes) {
+		return "skipped: compressed media type " + mediaType, true
+	}
+	sample := body
+	if len(sample) > entropySampleSize {
+		sample = sample[:entropySampleSize]
+	}
+	if entropy := byteEntropy(sample); entropy > maxCompressibleEntropy {
+		return fmt.Sprintf("skipped: entropy %.2f bits per byte", entropy), true
+	}
+	return "", false
+}
+
+//byteEntropy returns the Shannon entropy of the bytes of data in bits per byte
+func byteEntropy(data []byte) float64 {
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+//matchesMediaType reports if mediaType is one of mediaTypes, entries ending with "/" match all subtypes
+func matchesMediaType(mediaType string, mediaTypes []string) bool {
+	for _, candidate := range mediaTypes {
+		candidate = strings.ToLower(candidate)
+		if mediaType == candidate || (strings.HasSuffix(candidate, "/") && strings.HasPrefix(mediaType, candidate)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Compression) compressesContentType(contentType string) bool {
+
+	if len(c.ContentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return matchesMediaType(mediaType, c.ContentTypes)
+}
+
+//decompressBody returns the body of response in its original form
+func decompressBody(response *JsonResponse) ([]byte, error) {
+
+	if response.BodyCompression == "" {
+		return response.Body, nil
+	}
+	compressor, err := compressorFor(response.BodyCompression)
+	if err != nil {
+		return nil, err
+	}
+	return compressor.Decompress(response.Body)
+}
+
+type gzipCompressor struct {
+	level int
+}
+
+func (gzipCompressor) Encoding() string {
+	return "gzip"
+}
+
+func (g gzipCompressor) Compress(body []byte) ([]byte, error) {
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, g.level)
+	if err != nil {
+		return nil, err
+	}
+	_, err = writer.Write(body)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCompressor) Decompress(body []byte) ([]byte, error) {
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
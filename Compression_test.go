@@ -0,0 +1,195 @@
+package CachedHttpClient
+
+import (
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompression_compress(t *testing.T) {
+
+	text := strings.Repeat(`{"key":"value"}`, 100)
+
+	tests := []struct {
+		name        string
+		compression *Compression
+		header      http.Header
+		body        string
+		compressed  bool
+	}{
+		{"all content types", &Compression{}, http.Header{"Content-Type": {"image/png"}}, text, true},
+		{"allowed subtype", &Compression{ContentTypes: []string{"application/json"}}, http.Header{"Content-Type": {"application/json; charset=utf-8"}}, text, true},
+		{"allowed type", &Compression{ContentTypes: []string{"text/"}}, http.Header{"Content-Type": {"text/html"}}, text, true},
+		{"not allowed", &Compression{ContentTypes: []string{"text/"}}, http.Header{"Content-Type": {"application/json"}}, text, false},
+		{"below min size", &Compression{MinSize: 10000}, http.Header{}, text, false},
+		{"content encoding", &Compression{}, http.Header{"Content-Encoding": {"br"}}, text, false},
+		{"incompressible", &Compression{}, http.Header{}, "a", false},
+		{"disabled", nil, http.Header{}, text, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &JsonResponse{Header: tt.header, Body: []byte(tt.body)}
+			if err := tt.compression.compress(response); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if (response.BodyCompression == "gzip") != tt.compressed {
+				t.Error("expected compressed", tt.compressed, "got", response.BodyCompression)
+			}
+			res, err := response.Parse()
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if body, _ := ioutil.ReadAll(res.Body); string(body) != tt.body {
+				t.Error("the body changed")
+			}
+		})
+	}
+
+	_, err := (&JsonResponse{Body: []byte("x"), BodyCompression: "zstd"}).Parse()
+	if !errors.Is(err, UnknownCompressionError) {
+		t.Error("expected UnknownCompressionError, got", err)
+	}
+}
+
+func TestCompression_compress_Adaptive(t *testing.T) {
+
+	text := strings.Repeat(`{"key":"value"}`, 100)
+	random := make([]byte, 8192)
+	if _, err := rand.Read(random); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		compressed  bool
+		decision    string
+	}{
+		{"text", "application/json", []byte(text), true, "compressed: "},
+		{"image", "image/png", []byte(text), false, "skipped: compressed media type image/png"},
+		{"svg", "image/svg+xml", []byte(text), true, "compressed: "},
+		{"archive", "application/zip", []byte(text), false, "skipped: compressed media type application/zip"},
+		{"high entropy", "application/octet-stream", random, false, "skipped: entropy "},
+		{"no size reduction", "text/plain", []byte("ab"), false, "skipped: no size reduction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := &JsonResponse{Header: http.Header{"Content-Type": {tt.contentType}}, Body: tt.body}
+			if err := (&Compression{Adaptive: true}).compress(response); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if (response.BodyCompression == "gzip") != tt.compressed {
+				t.Error("expected compressed", tt.compressed, "got", response.BodyCompression)
+			}
+			if !strings.HasPrefix(response.CompressionDecision, tt.decision) {
+				t.Errorf("expected decision %q got %q", tt.decision, response.CompressionDecision)
+			}
+		})
+	}
+}
+
+func TestFileCache_Compression(t *testing.T) {
+
+	text := strings.Repeat("<p>compressible</p>", 1000)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		_, _ = writer.Write([]byte(text))
+	}))
+	defer server.Close()
+
+	options := FileCacheOptions{Compression: &Compression{MinSize: 1024, ContentTypes: []string{"text/"}}}
+	fileCache, err := NewFileCache("tmp/compression.cache", options)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	client := http.Client{Transport: &CachedTransport{Cache: fileCache, Fallback: http.DefaultTransport}}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != text {
+		t.Error("the caller received a changed body")
+	}
+
+	info, err := os.Stat("tmp/compression.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if info.Size() >= int64(len(text)) {
+		t.Error("expected the cache file to be smaller than the body, size", info.Size())
+	}
+
+	reopened, err := OpenFileCache("tmp/compression.cache", options)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	cached, err := reopened.Get(res.Request)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(cached.Body); string(body) != text {
+		t.Error("the reopened cache returned a changed body")
+	}
+}
+
+func TestCompression_Headers(t *testing.T) {
+
+	header := http.Header{
+		"Content-Type":   {"application/json; charset=utf-8"},
+		"Cache-Control":  {"max-age=60"},
+		"Date":           {"Mon, 02 Jan 2006 15:04:05 GMT"},
+		"Vary":           {"Accept-Encoding", "Origin"},
+		"X-Custom":       {"a", ""},
+		"Content-Length": {"0"},
+	}
+	response := &JsonResponse{StatusCode: http.StatusNoContent, Header: header.Clone()}
+	if err := (&Compression{Headers: true}).compress(response); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if response.Header != nil || len(response.CompressedHeader) == 0 {
+		t.Error("expected the header to be compressed, got", response.Header, response.CompressedHeader)
+	}
+	var size int
+	for name, values := range header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+		}
+	}
+	if len(response.CompressedHeader) > size/2 {
+		t.Errorf("expected the header to shrink to less than half of %d bytes, got %d", size, len(response.CompressedHeader))
+	}
+
+	res, err := response.Parse()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !reflect.DeepEqual(res.Header, header) {
+		t.Errorf("expected %v, got %v", header, res.Header)
+	}
+
+	for _, encoded := range [][]byte{{0xff}, {0x7f, 0x01, 'a'}, {0x00, 0x05, 'a'}, {0x20}} {
+		if _, err := (&JsonResponse{CompressedHeader: encoded}).Parse(); !errors.Is(err, InvalidCompressedHeaderError) {
+			t.Errorf("expected InvalidCompressedHeaderError for %v, got %v", encoded, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//InvalidConfigurationError is matched by the errors of NewCachedTransport and CachedTransport.Validate
+var InvalidConfigurationError = errors.New("invalid configuration")
+
+//ConfigurationError describes an option, or a combination of options, which would misbehave at runtime
+type ConfigurationError struct {
+	//Option is the misconfigured option, e.g. "CachedTransport.Refresher"
+	Option string
+	//Problem tells what is wrong with the option
+	Problem string
+	//Fix tells how to correct it
+	Fix string
+	Err error
+}
+
+func (e *ConfigurationError) Error() string {
+	message := fmt.Sprintf("%s: %s: %s", InvalidConfigurationError, e.Option, e.Problem)
+	if e.Fix != "" {
+		message += ", " + e.Fix
+	}
+	if e.Err != nil {
+		message += fmt.Sprintf(": %v", e.Err)
+	}
+	return message
+}
+
+func (e *ConfigurationError) Is(target error) bool {
+	return target == InvalidConfigurationError
+}
+
+func (e *ConfigurationError) Unwrap() error {
+	return e.Err
+}
+
+//ConfigurationErrors are all problems found by CachedTransport.Validate
+type ConfigurationErrors []*ConfigurationError
+
+func (e ConfigurationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e ConfigurationErrors) Is(target error) bool {
+	return target == InvalidConfigurationError
+}
+
+//As sets a *ConfigurationError target to the first problem
+func (e ConfigurationErrors) As(target interface{}) bool {
+	if first, ok := target.(**ConfigurationError); ok && len(e) > 0 {
+		*first = e[0]
+		return true
+	}
+	return false
+}
+
+//NewCachedTransport returns a copy of transport after checking its options with Validate
+//
+//	transport, err := NewCachedTransport(CachedTransport{Cache: NewMapCache(), Refresher: NewRefresher(0.1, 10)})
+func NewCachedTransport(transport CachedTransport) (*CachedTransport, error) {
+	if err := transport.Validate(); err != nil {
+		return nil, err
+	}
+	return &transport, nil
+}
+
+//Validate checks the options of the transport and their combinations for mistakes which would not fail but
+//misbehave silently at runtime, e.g. a Refresher without its background worker or a stale-if-error window longer
+//than the cache keeps expired entries. It returns ConfigurationErrors listing all problems or nil
+func (c *CachedTransport) Validate() error {
+
+	var problems ConfigurationErrors
+	problem := func(option string, problem string, fix string) {
+		problems = append(problems, &ConfigurationError{Option: "CachedTransport." + option, Problem: problem, Fix: fix})
+	}
+
+	if c.Cache == nil {
+		problem("Cache", "no cache is set", "set a Cacher, e.g. NewMapCache()")
+	}
+
+	for option, window := range map[string]time.Duration{
+		"StaleWhileRevalidate": c.StaleWhileRevalidate,
+		"StaleIfError":         c.StaleIfError,
+		"RefreshTimeout":       c.RefreshTimeout,
+	} {
+		if window < 0 {
+			problem(option, "it is negative", "use 0 to disable it")
+		}
+	}
+	if kvCache, ok := c.Cache.(*KVCache); ok && kvCache.Expire {
+		for option, window := range map[string]time.Duration{
+			"StaleWhileRevalidate": c.StaleWhileRevalidate,
+			"StaleIfError":         c.StaleIfError,
+		} {
+			if window > kvCache.MaxStale {
+				problem(option, fmt.Sprintf("the KVCache expires entries %s after they stop being fresh, before the window closes",
+					kvCache.MaxStale), "set KVCacheOptions.MaxStale to at least the window or disable Expire")
+			}
+		}
+	}
+	if lruCache, ok := c.Cache.(*LRUCache); ok && lruCache.OversizedPrefix > 0 && lruCache.MaxBytes <= 0 {
+		problems = append(problems, &ConfigurationError{Option: "LRUCacheOptions.OversizedPrefix",
+			Problem: "no body is oversized without MaxBytes", Fix: "set MaxBytes"})
+	}
+	if fileCache, ok := c.Cache.(*FileCache); ok && fileCache.BodyThreshold > 0 && fileCache.encrypted() {
+		problems = append(problems, &ConfigurationError{Option: "FileCache.BodyThreshold",
+			Problem: "body files are not encrypted, the bodies are kept in the encrypted cache file instead",
+			Fix: "set it to 0 with an encrypted Codec"})
+	}
+
+	if r := c.Refresher; r != nil {
+		r.mutex.Lock()
+		started, closed := r.queue != nil, r.closed
+		r.mutex.Unlock()
+		if !started {
+			problem("Refresher", "it has no background worker running the refreshes", "create it with NewRefresher")
+		} else if closed {
+			problem("Refresher", "it was shut down and refreshes nothing", "create a new one with NewRefresher")
+		}
+		if r.Threshold <= 0 || r.Threshold > 1 {
+			problem("Refresher.Threshold", fmt.Sprintf("%v is no share of the freshness lifetime", r.Threshold),
+				"use a value in (0, 1], e.g. 0.1")
+		}
+	}
+	if e := c.EarlyExpiration; e != nil {
+		if e.Beta < 0 {
+			problem("EarlyExpiration.Beta", "it is negative and replaced by 1", "use a positive value or 0 for 1")
+		}
+		if e.Delta < 0 {
+			problem("EarlyExpiration.Delta", "it is negative and replaced by the default", "use a positive value or 0")
+		}
+	}
+	if g := c.Guardrails; g != nil {
+		for option, bound := range map[string]time.Duration{
+			"Guardrails.MaxAge":   g.MaxAge,
+			"Guardrails.MinTTL":   g.MinTTL,
+			"Guardrails.MaxStale": g.MaxStale,
+		} {
+			if bound < 0 {
+				problem(option, "it is negative", "use 0 to disable it")
+			}
+		}
+		if g.MaxAge > 0 && g.MinTTL > g.MaxAge {
+			problem("Guardrails.MinTTL", fmt.Sprintf("it is longer than MaxAge %s, which wins", g.MaxAge),
+				"use a MinTTL of at most MaxAge")
+		}
+	}
+	if c.SoftDelete != nil && c.SoftDelete.Window <= 0 {
+		problem("SoftDelete.Window", "no soft deleted entry can be restored", "create it with NewSoftDelete(window)")
+	}
+	if c.ErrorCapture != nil && c.ErrorCapture.Cache == nil {
+		problem("ErrorCapture.Cache", "the error responses have nowhere to be stored", "create it with NewErrorCapture(cache, maxBodySize)")
+	}
+
+	for option, hosts := range map[string]map[string]string{"URLRewrites": c.URLRewrites, "HostAliases": c.HostAliases} {
+		for _, from := range sortedKeys(hosts) {
+			if hosts[from] == "" {
+				problem(option, fmt.Sprintf("%q is mapped to an empty host", from), "remove it or map it to a host")
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	//the maps above are iterated in random order
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Option < problems[j].Option
+	})
+	return problems
+}
+
+//sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
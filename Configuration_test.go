@@ -0,0 +1,90 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_Validate(t *testing.T) {
+
+	stopped := NewRefresher(0.1, 1)
+	if err := stopped.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		transport CachedTransport
+		//options are the misconfigured options, none for a valid transport
+		options []string
+	}{
+		{"valid", CachedTransport{Cache: NewMapCache(), Refresher: NewRefresher(0.1, 1), StaleIfError: time.Minute}, nil},
+		{"no cache", CachedTransport{}, []string{"CachedTransport.Cache"}},
+		{"refresher without worker", CachedTransport{Cache: NewMapCache(), Refresher: &Refresher{Threshold: 0.1}},
+			[]string{"CachedTransport.Refresher"}},
+		{"refresher shut down", CachedTransport{Cache: NewMapCache(), Refresher: stopped}, []string{"CachedTransport.Refresher"}},
+		{"refresher threshold", CachedTransport{Cache: NewMapCache(), Refresher: NewRefresher(10, 1)},
+			[]string{"CachedTransport.Refresher.Threshold"}},
+		{"stale-if-error beyond expiry", CachedTransport{
+			Cache:        NewKVCache(newMemoryKVStore(), KVCacheOptions{Expire: true, MaxStale: time.Minute}),
+			StaleIfError: time.Hour,
+		}, []string{"CachedTransport.StaleIfError"}},
+		{"stale-if-error within expiry", CachedTransport{
+			Cache:        NewKVCache(newMemoryKVStore(), KVCacheOptions{Expire: true, MaxStale: time.Hour}),
+			StaleIfError: time.Hour,
+		}, nil},
+		{"negative window", CachedTransport{Cache: NewMapCache(), StaleWhileRevalidate: -time.Second},
+			[]string{"CachedTransport.StaleWhileRevalidate"}},
+		{"oversized prefix", CachedTransport{Cache: NewLRUCache(LRUCacheOptions{OversizedPrefix: 1024})},
+			[]string{"LRUCacheOptions.OversizedPrefix"}},
+		{"guardrails", CachedTransport{Cache: NewMapCache(), Guardrails: &Guardrails{MaxAge: time.Minute, MinTTL: time.Hour}},
+			[]string{"CachedTransport.Guardrails.MinTTL"}},
+		{"encrypted body files", CachedTransport{Cache: encryptedFileCache(t, 1024)}, []string{"FileCache.BodyThreshold"}},
+		{"soft delete", CachedTransport{Cache: NewMapCache(), SoftDelete: &SoftDelete{}}, []string{"CachedTransport.SoftDelete.Window"}},
+		{"empty alias", CachedTransport{Cache: NewMapCache(), HostAliases: map[string]string{"a.example.com": ""}},
+			[]string{"CachedTransport.HostAliases"}},
+		{"several", CachedTransport{EarlyExpiration: &EarlyExpiration{Beta: -1}, ErrorCapture: &ErrorCapture{}},
+			[]string{"CachedTransport.Cache", "CachedTransport.EarlyExpiration.Beta", "CachedTransport.ErrorCapture.Cache"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := NewCachedTransport(tt.transport)
+			if tt.options == nil {
+				if err != nil || transport == nil {
+					t.Fatal("expected a valid transport, got", err)
+				}
+				return
+			}
+
+			var problems ConfigurationErrors
+			if !errors.Is(err, InvalidConfigurationError) || !errors.As(err, &problems) {
+				t.Fatal("expected ConfigurationErrors, got", err)
+			}
+			var options []string
+			for _, problem := range problems {
+				options = append(options, problem.Option)
+				if problem.Fix == "" {
+					t.Error("no fix for", problem.Option)
+				}
+			}
+			if strings.Join(options, ",") != strings.Join(tt.options, ",") {
+				t.Error("expected problems with", tt.options, "got", err)
+			}
+			var first *ConfigurationError
+			if !errors.As(err, &first) || first != problems[0] {
+				t.Error("expected the first problem for a *ConfigurationError")
+			}
+		})
+	}
+}
+
+func TestNewEncryptedCodec_ConfigurationError(t *testing.T) {
+	_, err := NewEncryptedCodec(JSONCodec, EncryptionKeys{})
+	var problem *ConfigurationError
+	if !errors.As(err, &problem) || problem.Option != "EncryptionKeys.Keys" || !errors.Is(err, UnknownEncryptionKeyError) {
+		t.Error("expected a ConfigurationError for the missing keys, got", err)
+	}
+}
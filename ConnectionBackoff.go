@@ -0,0 +1,176 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ConnectionBackoffError = errors.New("the connection to the host failed recently")
+
+//ConnectionFailureError is returned for origin requests to a host whose connection failed within its backoff. It is
+//ConnectionBackoffError for errors.Is and unwraps to the error of the failed connection
+type ConnectionFailureError struct {
+	//Host is the host:port the connection failed to
+	Host string
+	//Until is the end of the backoff, the next request after it opens a new connection
+	Until time.Time
+	Err   error
+}
+
+func (e *ConnectionFailureError) Error() string {
+	return fmt.Sprintf("%s: %s until %s: %v", ConnectionBackoffError, e.Host, e.Until.Format(time.RFC3339), e.Err)
+}
+
+func (e *ConnectionFailureError) Is(target error) bool {
+	return target == ConnectionBackoffError
+}
+
+func (e *ConnectionFailureError) Unwrap() error {
+	return e.Err
+}
+
+//ConnectionBackoff remembers connection level failures per host:port, like refused or timed out dials, DNS errors and
+//failed TLS handshakes. Origin requests to the host fail with a ConnectionFailureError without dialing for a backoff
+//which doubles with every consecutive failure, so thousands of queued requests do not repeat the doomed handshake.
+//Stale responses are served within their stale-if-error window or always with ServeStale. The first request after
+//the backoff dials again, a success forgets the failures. Responses, even 5xx ones, are no connection failures, see
+//CircuitBreaker for them
+type ConnectionBackoff struct {
+	//Backoff is the backoff after the first failure, 1s if 0
+	Backoff time.Duration
+	//MaxBackoff limits the backoff, 1m if 0
+	MaxBackoff time.Duration
+	//ServeStale serves stale responses during the backoff even outside their stale-if-error window
+	ServeStale bool
+	//Clock tells the time the backoffs start and end at, SystemClock if nil
+	Clock Clock
+
+	mutex sync.Mutex
+	hosts map[string]*connectionFailure
+}
+
+type connectionFailure struct {
+	failures int
+	until    time.Time
+	err      error
+}
+
+func (b *ConnectionBackoff) backoff(failures int) time.Duration {
+	backoff, maxBackoff := b.Backoff, b.MaxBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = time.Minute
+	}
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+//Failure returns the end of the backoff of host, a host:port, and the error of its last failed connection. err is
+//nil if the host is not in a backoff
+func (b *ConnectionBackoff) Failure(host string) (until time.Time, err error) {
+	if b == nil {
+		return time.Time{}, nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	failure, ok := b.hosts[host]
+	if !ok || !clockOrDefault(b.Clock).Now().Before(failure.until) {
+		return time.Time{}, nil
+	}
+	return failure.until, failure.err
+}
+
+//allow fails requests to req.URL.Host during its backoff, done records the outcome of the request
+func (b *ConnectionBackoff) allow(req *http.Request) (done func(err error), err error) {
+
+	if b == nil {
+		return func(error) {}, nil
+	}
+	host := hostPort(req.URL)
+	if until, cause := b.Failure(host); cause != nil {
+		return nil, &ConnectionFailureError{Host: host, Until: until, Err: cause}
+	}
+	return func(err error) {
+		b.record(req.Context(), host, err)
+	}, nil
+}
+
+//record starts or extends the backoff of host after a connection failure and forgets the failures after a success
+func (b *ConnectionBackoff) record(ctx context.Context, host string, err error) {
+
+	if err != nil && (ctx.Err() != nil || !isConnectionError(err)) {
+		//errors of the caller or after the connection was established say nothing about the connection
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err == nil {
+		delete(b.hosts, host)
+		return
+	}
+	if b.hosts == nil {
+		b.hosts = map[string]*connectionFailure{}
+	}
+	failure, ok := b.hosts[host]
+	if !ok {
+		failure = &connectionFailure{}
+		b.hosts[host] = failure
+	}
+	failure.failures++
+	failure.err = err
+	failure.until = clockOrDefault(b.Clock).Now().Add(b.backoff(failure.failures))
+}
+
+//servesStale reports if the stale response is served for err although it is outside its stale-if-error window
+func (b *ConnectionBackoff) servesStale(err error) bool {
+	return b != nil && b.ServeStale && errors.Is(err, ConnectionBackoffError)
+}
+
+//hostPort returns the host of u with the default port of its scheme if it has none
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+//isConnectionError reports if err failed the connection to the origin before a request was sent on it: dials, DNS
+//lookups and TLS handshakes
+func isConnectionError(err error) bool {
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "remote error") {
+		//remote errors are the TLS alerts sent by the host
+		return true
+	}
+	var dnsErr *net.DNSError
+	var recordHeaderErr tls.RecordHeaderError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &dnsErr) || errors.As(err, &recordHeaderErr) || errors.As(err, &unknownAuthorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+	//the error of http.Transport is not exported
+	return strings.Contains(err.Error(), "TLS handshake timeout")
+}
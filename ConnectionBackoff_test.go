@@ -0,0 +1,124 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_ConnectionBackoff(t *testing.T) {
+
+	//a port nobody listens on refuses the connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	dials := 0
+	fallback := &http.Transport{DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials++
+		return (&net.Dialer{}).DialContext(ctx, network, address)
+	}}
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	backoff := &ConnectionBackoff{Backoff: time.Minute, Clock: clock}
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, ConnectionBackoff: backoff}}
+
+	if _, err := client.Get("http://" + address + "/"); err == nil || errors.Is(err, ConnectionBackoffError) {
+		t.Error("expected the connection error got", err)
+	}
+	for i := 0; i < 10; i++ {
+		_, err := client.Get("http://" + address + "/")
+		if !errors.Is(err, ConnectionBackoffError) || !errors.Is(err, syscall.ECONNREFUSED) {
+			t.Error("expected the remembered connection error got", err)
+		}
+	}
+	if dials != 1 {
+		t.Error("expected one dial during the backoff got", dials)
+	}
+	if until, err := backoff.Failure(address); err == nil || until.IsZero() {
+		t.Error("expected the failure of the host got", until, err)
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := client.Get("http://" + address + "/"); errors.Is(err, ConnectionBackoffError) {
+		t.Error("expected a dial after the backoff got", err)
+	}
+	if failure, ok := backoff.hosts[address]; dials != 2 || !ok || failure.failures != 2 {
+		t.Error("expected the second failure to be counted got", dials)
+	}
+
+	//the handshake with an untrusted certificate fails
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("expected the TLS handshake to fail")
+	}
+	if _, err := client.Get(server.URL); !errors.Is(err, ConnectionBackoffError) {
+		t.Error("expected the failed handshake to be remembered got", err)
+	}
+}
+
+func TestCachedTransport_RoundTrip_ConnectionBackoffStale(t *testing.T) {
+
+	failing := false
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=0")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("stale")), Request: req}, nil
+	})
+	backoff := &ConnectionBackoff{Backoff: time.Minute, ServeStale: true}
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, ConnectionBackoff: backoff}}
+
+	if _, err := client.Get("https://example.com/"); err != nil {
+		t.Error(err)
+	}
+	failing = true
+	if _, err := client.Get("https://example.com/"); err == nil {
+		t.Error("expected the connection error outside the stale-if-error window")
+	}
+	res, err := client.Get("https://example.com/")
+	if err != nil {
+		t.Error("expected the stale response during the backoff got", err)
+		t.FailNow()
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if string(body) != "stale" {
+		t.Error("expected the stale response got", string(body))
+	}
+	if until, _ := backoff.Failure("example.com:443"); until.IsZero() {
+		t.Error("expected the backoff of the host with the default port")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"tls alert", &net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")}, true},
+		{"dns", &net.DNSError{Err: "no such host", Name: "example.invalid"}, true},
+		{"read", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, false},
+		{"other", errors.New("unexpected EOF"), false},
+	}
+	for _, tt := range tests {
+		if got := isConnectionError(tt.err); got != tt.want {
+			t.Errorf("isConnectionError(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package CachedHttpClient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+//ConnectionStats describes the connections used for the origin requests to one host, see Metrics.Connections
+type ConnectionStats struct {
+	//Requests are the origin requests which got a connection
+	Requests int64
+	//Reused are the requests which got an idle keep-alive connection instead of a new one
+	Reused int64
+	//Dials are the new connections opened
+	Dials int64
+	//TLSHandshakes are the TLS handshakes done for new connections
+	TLSHandshakes int64
+	//PeakConcurrency is the largest number of concurrent origin requests
+	PeakConcurrency int64
+}
+
+//ReuseRatio returns the share of requests which reused a connection, a low ratio means misses pay for new connections
+func (s ConnectionStats) ReuseRatio() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Reused) / float64(s.Requests)
+}
+
+//SuggestedMaxIdleConnsPerHost returns a MaxIdleConnsPerHost for the host keeping a connection for every concurrent
+//request seen idle, the default of http.Transport is 2
+func (s ConnectionStats) SuggestedMaxIdleConnsPerHost() int {
+	if s.PeakConcurrency < 2 {
+		return 2
+	}
+	return int(s.PeakConcurrency)
+}
+
+//hostConnections are the counters of ConnectionStats for a host, guarded by Metrics.connectionsMutex
+type hostConnections struct {
+	ConnectionStats
+	active int64
+}
+
+//Connections returns the connection statistics of the origin requests by host
+func (m *Metrics) Connections() map[string]ConnectionStats {
+	if m == nil {
+		return nil
+	}
+	m.connectionsMutex.Lock()
+	defer m.connectionsMutex.Unlock()
+
+	connections := make(map[string]ConnectionStats, len(m.connections))
+	for host, stats := range m.connections {
+		connections[host] = stats.ConnectionStats
+	}
+	return connections
+}
+
+//traceConnections returns req with a httptrace.ClientTrace counting its connection and a function to call once the
+//origin request is done
+func (m *Metrics) traceConnections(req *http.Request) (*http.Request, func()) {
+
+	if m == nil {
+		return req, func() {}
+	}
+
+	host := req.URL.Host
+	m.updateConnections(host, func(stats *hostConnections) {
+		stats.active++
+		if stats.active > stats.PeakConcurrency {
+			stats.PeakConcurrency = stats.active
+		}
+	})
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			m.updateConnections(host, func(stats *hostConnections) {
+				stats.Requests++
+				if info.Reused {
+					stats.Reused++
+				}
+			})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				m.updateConnections(host, func(stats *hostConnections) { stats.Dials++ })
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err == nil {
+				m.updateConnections(host, func(stats *hostConnections) { stats.TLSHandshakes++ })
+			}
+		},
+	}
+
+	done := func() {
+		m.updateConnections(host, func(stats *hostConnections) { stats.active-- })
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), done
+}
+
+func (m *Metrics) updateConnections(host string, update func(stats *hostConnections)) {
+	m.connectionsMutex.Lock()
+	defer m.connectionsMutex.Unlock()
+	if m.connections == nil {
+		m.connections = map[string]*hostConnections{}
+	}
+	stats, ok := m.connections[host]
+	if !ok {
+		stats = &hostConnections{}
+		m.connections[host] = stats
+	}
+	update(stats)
+}
+
+//TransportOptions are the keep-alive settings of the http.Transport created by NewTransport, zero values keep the
+//settings of http.DefaultTransport
+type TransportOptions struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	//KeepAlive is the interval of TCP keep-alive probes
+	KeepAlive time.Duration
+}
+
+//NewTransport creates a Fallback transport from http.DefaultTransport with the keep-alive settings of options,
+//e.g. with the SuggestedMaxIdleConnsPerHost of the origin
+func NewTransport(options TransportOptions) *http.Transport {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.MaxIdleConns > 0 {
+		transport.MaxIdleConns = options.MaxIdleConns
+	}
+	if options.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	}
+	if options.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = options.MaxConnsPerHost
+	}
+	if options.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = options.IdleConnTimeout
+	}
+	if options.KeepAlive > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: options.KeepAlive}
+		transport.DialContext = dialer.DialContext
+	}
+	return transport
+}
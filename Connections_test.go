@@ -0,0 +1,84 @@
+package CachedHttpClient
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics_Connections(t *testing.T) {
+
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Cache-Control", "no-store")
+		_, _ = io.WriteString(writer, "body")
+	})
+
+	tests := []struct {
+		name       string
+		server     *httptest.Server
+		handshakes int64
+	}{
+		{"http", httptest.NewServer(handler), 0},
+		{"https", httptest.NewTLSServer(handler), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.Close()
+
+			metrics := NewMetrics()
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: tt.server.Client().Transport, Metrics: metrics}}
+
+			for i := 0; i < 3; i++ {
+				response, err := client.Get(tt.server.URL)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				_, _ = ioutil.ReadAll(response.Body)
+				_ = response.Body.Close()
+			}
+
+			serverURL, _ := url.Parse(tt.server.URL)
+			stats := metrics.Connections()[serverURL.Host]
+			expected := ConnectionStats{Requests: 3, Reused: 2, Dials: 1, TLSHandshakes: tt.handshakes, PeakConcurrency: 1}
+			if stats != expected {
+				t.Errorf("expected %+v got %+v", expected, stats)
+			}
+			if ratio := stats.ReuseRatio(); ratio < 0.66 || ratio > 0.67 {
+				t.Error("expected a reuse ratio of 2/3 got", ratio)
+			}
+			if suggested := stats.SuggestedMaxIdleConnsPerHost(); suggested != 2 {
+				t.Error("expected the default of 2 idle connections got", suggested)
+			}
+
+			var prometheus strings.Builder
+			if err := metrics.WritePrometheus(&prometheus); err != nil {
+				t.Error(err)
+			}
+			line := `cachedhttpclient_origin_reused_connections_total{host="` + serverURL.Host + `"} 2`
+			if !strings.Contains(prometheus.String(), line) {
+				t.Errorf("expected %q in %s", line, prometheus.String())
+			}
+		})
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+
+	transport := NewTransport(TransportOptions{MaxIdleConnsPerHost: 32, IdleConnTimeout: time.Minute, KeepAlive: time.Second})
+	if transport.MaxIdleConnsPerHost != 32 || transport.IdleConnTimeout != time.Minute || transport.DialContext == nil {
+		t.Error("expected the options to be applied", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if transport.MaxIdleConns != http.DefaultTransport.(*http.Transport).MaxIdleConns {
+		t.Error("expected the other settings of http.DefaultTransport to be kept")
+	}
+	if transport == http.DefaultTransport {
+		t.Error("expected a copy of http.DefaultTransport")
+	}
+}
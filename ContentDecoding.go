@@ -0,0 +1,197 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//ContentDecoder returns a reader of the body decoded from the encoded body, see RegisterContentDecoder
+type ContentDecoder func(body io.Reader) (io.ReadCloser, error)
+
+var contentDecodersMutex sync.RWMutex
+var contentDecoders = map[string]ContentDecoder{
+	"gzip":    decodeGzip,
+	"x-gzip":  decodeGzip,
+	"deflate": decodeDeflate,
+}
+
+//RegisterContentDecoder makes the responses with the content coding decodable by ContentDecoding, gzip, x-gzip and
+//deflate are registered by default. The standard library has no decoder for br, register the decoder of a brotli
+//package for it. Responses with an unknown coding are stored and served as received
+func RegisterContentDecoder(coding string, decoder ContentDecoder) {
+	contentDecodersMutex.Lock()
+	defer contentDecodersMutex.Unlock()
+	contentDecoders[strings.ToLower(coding)] = decoder
+}
+
+func contentDecoderFor(coding string) (ContentDecoder, bool) {
+	contentDecodersMutex.RLock()
+	defer contentDecodersMutex.RUnlock()
+	decoder, ok := contentDecoders[coding]
+	return decoder, ok
+}
+
+func decodeGzip(body io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(body)
+}
+
+//decodeDeflate decodes zlib streams (RFC 1950) and the raw deflate streams (RFC 1951) some servers send instead
+func decodeDeflate(body io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+//headersOfEncodedContent describe the encoded body and are removed from decoded responses, the digests are computed
+//over the encoded content
+var headersOfEncodedContent = []string{"Content-Encoding", "Content-Length", "Content-Digest", "Repr-Digest", "Content-Md5"}
+
+//ContentDecoding decodes the bodies of origin responses with a Content-Encoding like gzip, e.g. when some clients
+//send Accept-Encoding and others do not. Without it a gzip body stored for one client is served to every client
+type ContentDecoding struct {
+	//KeepEncoded stores the bodies as received with their Content-Encoding, they are served as is to requests
+	//accepting the coding and decoded for the others. Otherwise the decoded bodies are stored and served
+	KeepEncoded bool
+}
+
+//received returns the origin response res with its body decoded unless KeepEncoded is set
+func (d *ContentDecoding) received(res *http.Response) *http.Response {
+	if d == nil || d.KeepEncoded {
+		return res
+	}
+	return decodedResponse(res)
+}
+
+//serve returns the response res for req with its body decoded if req does not accept its Content-Encoding
+func (d *ContentDecoding) serve(req *http.Request, res *http.Response) *http.Response {
+	if d == nil || res == nil {
+		return res
+	}
+	for _, coding := range contentCodings(res.Header) {
+		if !acceptsEncoding(req, coding) {
+			return decodedResponse(res)
+		}
+	}
+	return res
+}
+
+//decodedResponse returns a copy of res with the decoded body, marked as Uncompressed. res is returned unchanged if
+//it has no body, is partial or one of its codings has no ContentDecoder
+func decodedResponse(res *http.Response) *http.Response {
+
+	codings := contentCodings(res.Header)
+	if len(codings) == 0 || res.Body == nil || res.Body == http.NoBody || res.StatusCode == http.StatusPartialContent {
+		return res
+	}
+	decoders := make([]ContentDecoder, len(codings))
+	for i, coding := range codings {
+		decoder, ok := contentDecoderFor(coding)
+		if !ok {
+			return res
+		}
+		decoders[i] = decoder
+	}
+
+	decoded := *res
+	decoded.Header = res.Header.Clone()
+	for _, field := range headersOfEncodedContent {
+		decoded.Header.Del(field)
+	}
+	decoded.ContentLength = -1
+	decoded.Uncompressed = true
+	decoded.Body = &decodingBody{encoded: res.Body, decoders: decoders}
+	return &decoded
+}
+
+//contentCodings returns the content codings of header in the order they were applied, identity is left out
+func contentCodings(header http.Header) []string {
+	var codings []string
+	for _, line := range header["Content-Encoding"] {
+		for _, coding := range strings.Split(line, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding != "" && coding != "identity" {
+				codings = append(codings, coding)
+			}
+		}
+	}
+	return codings
+}
+
+//acceptsEncoding reports if the Accept-Encoding of req accepts the content coding (RFC 7231 5.3.4), requests
+//without Accept-Encoding accept no coding
+func acceptsEncoding(req *http.Request, coding string) bool {
+
+	quality := -1.0
+	wildcard := -1.0
+	for _, line := range req.Header["Accept-Encoding"] {
+		for _, element := range strings.Split(line, ",") {
+			parts := strings.Split(element, ";")
+			name := strings.ToLower(strings.TrimSpace(parts[0]))
+			q := 1.0
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "q=") {
+					if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+			switch {
+			case name == coding || coding == "gzip" && name == "x-gzip" || coding == "x-gzip" && name == "gzip":
+				quality = q
+			case name == "*":
+				wildcard = q
+			}
+		}
+	}
+	if quality < 0 {
+		quality = wildcard
+	}
+	return quality > 0
+}
+
+//decodingBody decodes the encoded body when it is first read so the errors of the decoders are errors of Read
+type decodingBody struct {
+	encoded  io.ReadCloser
+	decoders []ContentDecoder
+	decoded  io.Reader
+	closers  []io.Closer
+	err      error
+}
+
+func (b *decodingBody) Read(p []byte) (int, error) {
+	if b.decoded == nil && b.err == nil {
+		var reader io.Reader = b.encoded
+		//the last coding was applied last and is removed first
+		for i := len(b.decoders) - 1; i >= 0 && b.err == nil; i-- {
+			var decoder io.ReadCloser
+			decoder, b.err = b.decoders[i](reader)
+			if b.err == nil {
+				b.closers = append(b.closers, decoder)
+				reader = decoder
+			}
+		}
+		b.decoded = reader
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.decoded.Read(p)
+}
+
+func (b *decodingBody) Close() error {
+	for _, closer := range b.closers {
+		_ = closer.Close()
+	}
+	return b.encoded.Close()
+}
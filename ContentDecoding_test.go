@@ -0,0 +1,148 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func encodeTestBody(t *testing.T, coding string, body string) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch coding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "zlib":
+		writer = zlib.NewWriter(&buf)
+	default:
+		var err error
+		writer, err = flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = writer.Write([]byte(body))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCachedTransport_ContentDecoding(t *testing.T) {
+
+	encoded := encodeTestBody(t, "gzip", "content")
+	newTransport := func(decoding *ContentDecoding) *CachedTransport {
+		//the keys ignore Accept-Encoding like a shared proxy
+		cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})})
+		return &CachedTransport{Cache: cache, ContentDecoding: decoding, Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res := lruTestResponse("")
+			res.Header.Set("Cache-Control", "max-age=60")
+			res.Header.Set("Content-Encoding", "gzip")
+			res.Header.Set("Content-Length", "27")
+			res.Body = ioutil.NopCloser(bytes.NewReader(encoded))
+			res.ContentLength = int64(len(encoded))
+			return res, nil
+		})}
+	}
+	get := func(transport *CachedTransport, acceptEncoding string) (*http.Response, []byte) {
+		req := lruTestRequest(t, "/")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		return res, body
+	}
+
+	t.Run("decoded", func(t *testing.T) {
+		transport := newTransport(&ContentDecoding{})
+		for _, acceptEncoding := range []string{"gzip", "", "gzip"} {
+			res, body := get(transport, acceptEncoding)
+			if string(body) != "content" || res.Header.Get("Content-Encoding") != "" || res.Header.Get("Content-Length") != "" || !res.Uncompressed {
+				t.Errorf("Accept-Encoding %q: expected the decoded body, got %q %v", acceptEncoding, body, res.Header)
+			}
+		}
+	})
+
+	t.Run("keep encoded", func(t *testing.T) {
+		transport := newTransport(&ContentDecoding{KeepEncoded: true})
+		steps := []struct {
+			acceptEncoding string
+			decoded        bool
+		}{
+			{"gzip, deflate", false},
+			{"", true},
+			{"gzip;q=0, identity", true},
+			{"*", false},
+		}
+		for _, step := range steps {
+			res, body := get(transport, step.acceptEncoding)
+			if step.decoded && (string(body) != "content" || res.Header.Get("Content-Encoding") != "") {
+				t.Errorf("Accept-Encoding %q: expected the decoded body, got %q", step.acceptEncoding, body)
+			}
+			if !step.decoded && (!bytes.Equal(body, encoded) || res.Header.Get("Content-Encoding") != "gzip") {
+				t.Errorf("Accept-Encoding %q: expected the encoded body, got %q", step.acceptEncoding, body)
+			}
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		transport := newTransport(nil)
+		get(transport, "gzip")
+		if _, body := get(transport, ""); !bytes.Equal(body, encoded) {
+			t.Errorf("expected the stored gzip body without ContentDecoding, got %q", body)
+		}
+	})
+}
+
+func TestDecodedResponse(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		coding   string
+		body     []byte
+		expected string
+	}{
+		{"zlib deflate", "deflate", encodeTestBody(t, "zlib", "content"), "content"},
+		{"raw deflate", "deflate", encodeTestBody(t, "flate", "content"), "content"},
+		{"x-gzip", "x-gzip", encodeTestBody(t, "gzip", "content"), "content"},
+		{"several", "deflate, gzip", func() []byte {
+			var buf bytes.Buffer
+			writer := gzip.NewWriter(&buf)
+			_, _ = writer.Write(encodeTestBody(t, "zlib", "content"))
+			_ = writer.Close()
+			return buf.Bytes()
+		}(), "content"},
+		{"unknown", "br", []byte("brotli"), "brotli"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := lruTestResponse("")
+			res.Header.Set("Content-Encoding", tt.coding)
+			res.Body = ioutil.NopCloser(bytes.NewReader(tt.body))
+			body, err := ioutil.ReadAll(decodedResponse(res).Body)
+			if err != nil || string(body) != tt.expected {
+				t.Errorf("expected %q, got %q %v", tt.expected, body, err)
+			}
+		})
+	}
+
+	res := lruTestResponse("")
+	res.Header.Set("Content-Encoding", "gzip")
+	res.Body = ioutil.NopCloser(bytes.NewReader([]byte("not gzip")))
+	if _, err := ioutil.ReadAll(decodedResponse(res).Body); err == nil {
+		t.Error("expected the error of the decoder")
+	}
+}
@@ -0,0 +1,114 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+type contextKey int
+
+const (
+	ttlContextKey contextKey = iota
+	noCacheContextKey
+	forceRefreshContextKey
+	generationContextKey
+	bodyDigestContextKey
+	rangeContextKey
+	storedOnlyContextKey
+	namespaceContextKey
+	syncWriteContextKey
+	ruleContextKey
+	principalContextKey
+	requestBodyContextKey
+)
+
+//WithTTL returns a context making cached responses fresh for ttl after their creation for requests using it,
+//regardless of the freshness information of the origin. no-store and private are still honored
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, ttlContextKey, ttl)
+}
+
+//WithNoCache returns a context bypassing the cache for requests using it, nothing is looked up or stored
+func WithNoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheContextKey, true)
+}
+
+//WithForceRefresh returns a context making requests using it skip the lookup, the response of the origin is stored
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshContextKey, true)
+}
+
+func ttlFromContext(ctx context.Context) (time.Duration, bool) {
+	ttl, ok := ctx.Value(ttlContextKey).(time.Duration)
+	return ttl, ok
+}
+
+func noCacheFromContext(ctx context.Context) bool {
+	noCache, _ := ctx.Value(noCacheContextKey).(bool)
+	return noCache
+}
+
+func forceRefreshFromContext(ctx context.Context) bool {
+	forceRefresh, _ := ctx.Value(forceRefreshContextKey).(bool)
+	return forceRefresh
+}
+
+//isFresh reports if res is fresh for req taking a TTL set with WithTTL and the request directives no-cache, max-age
+//and min-fresh into account
+func (c *CachedTransport) isFresh(req *http.Request, res *http.Response, now time.Time) bool {
+	if ttl, ok := ttlFromContext(req.Context()); ok {
+		return policy.RequestAccepts(req, ttl, policy.CurrentAge(res, now))
+	}
+	if !policy.IsFresh(res, c.Shared, now) {
+		return false
+	}
+	if _, ok := req.Header["Cache-Control"]; !ok && req.Header.Get("Pragma") == "" {
+		//the freshness lifetime is not computed twice for requests without directives
+		return true
+	}
+	return policy.RequestAccepts(req, policy.FreshnessLifetime(res, c.Shared), policy.CurrentAge(res, now))
+}
+
+//acceptsStale reports if the stale response res is served for req within its max-stale directive
+func (c *CachedTransport) acceptsStale(req *http.Request, res *http.Response, now time.Time) bool {
+	if _, ok := req.Header["Cache-Control"]; !ok {
+		return false
+	}
+	lifetime := policy.FreshnessLifetime(res, c.Shared)
+	if ttl, ok := ttlFromContext(req.Context()); ok {
+		lifetime = ttl
+	}
+	return policy.RequestAcceptsStale(req, res, lifetime, policy.CurrentAge(res, now), c.Shared)
+}
+
+//DefaultRefreshTimeout bounds the background refreshes of a CachedTransport without RefreshTimeout
+const DefaultRefreshTimeout = time.Minute
+
+//detachedContext keeps the values of its parent but is never cancelled and has no deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+//detach returns a context with the values of ctx which is not cancelled with it
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
@@ -0,0 +1,149 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_Context(t *testing.T) {
+
+	counter := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		counter++
+		writer.Header().Set("Cache-Control", r.URL.Query().Get("cc"))
+		fmt.Fprint(writer, counter)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		query  string
+		ctx    func(ctx context.Context) context.Context
+		cached bool
+	}{
+		{"ttl on uncacheable response", "?cc=max-age=0", func(ctx context.Context) context.Context { return WithTTL(ctx, time.Minute) }, true},
+		{"ttl overrides max-age", "?cc=max-age=60", func(ctx context.Context) context.Context { return WithTTL(ctx, 0) }, false},
+		{"ttl honors no-store", "?cc=no-store", func(ctx context.Context) context.Context { return WithTTL(ctx, time.Minute) }, false},
+		{"no cache", "?cc=max-age=60", WithNoCache, false},
+		{"force refresh", "?cc=max-age=60", WithForceRefresh, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+
+			var bodies []string
+			for i := 0; i < 2; i++ {
+				request, err := http.NewRequestWithContext(test.ctx(context.Background()), "GET", server.URL+test.query, nil)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				response, err := client.Do(request)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				body, err := ioutil.ReadAll(response.Body)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				bodies = append(bodies, string(body))
+			}
+
+			if (bodies[0] == bodies[1]) != test.cached {
+				t.Error("expected cached", test.cached, "got bodies", bodies)
+			}
+		})
+	}
+
+	//force refresh updates the entry, no cache leaves it untouched
+	client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+	get := func(ctx context.Context) string {
+		request, err := http.NewRequestWithContext(ctx, "GET", server.URL+"?cc=max-age=60", nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return string(body)
+	}
+
+	get(context.Background())
+	refreshed := get(WithForceRefresh(context.Background()))
+	get(WithNoCache(context.Background()))
+	if body := get(context.Background()); body != refreshed {
+		t.Error("expected the refreshed response", refreshed, "got", body)
+	}
+
+}
+
+func TestCachedTransport_RoundTrip_Cancelled(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		t.Error("expected the origin not to be asked")
+		return nil, errors.New("unexpected request")
+	})
+	client := &http.Client{Transport: &CachedTransport{Cache: failingCache{}, Fallback: fallback}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+	if _, err := client.Do(request); !errors.Is(err, context.Canceled) {
+		t.Error("expected the error of the context got", err)
+	}
+}
+
+func TestCachedTransport_refresh_Context(t *testing.T) {
+
+	type valueKey struct{}
+	refreshed := make(chan *http.Request, 1)
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		refreshed <- req
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback, RefreshTimeout: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), valueKey{}, "value"))
+	request, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		transport.refresh(request, request, lruTestResponse("stale"))
+		close(done)
+	}()
+
+	req := <-refreshed
+	if req.Context().Value(valueKey{}) != "value" {
+		t.Error("expected the values of the context of the caller")
+	}
+	if _, ok := req.Context().Deadline(); !ok {
+		t.Error("expected the refresh to have a deadline")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the refresh to end after RefreshTimeout")
+	}
+	if !errors.Is(req.Context().Err(), context.DeadlineExceeded) {
+		t.Error("expected the refresh to be stopped by its deadline not by the caller got", req.Context().Err())
+	}
+}
@@ -0,0 +1,216 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//CorpusResponse is a tricky real-world response of TrickyResponses
+type CorpusResponse struct {
+	Name string
+	//Description tells what is tricky about the response
+	Description string
+	//New returns a new copy of the response, its Request is a GET of https://example.com/corpus/Name
+	New func() *http.Response
+}
+
+//corpusDate is the Date of the corpus responses so their dumps are stable
+const corpusDate = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+//TrickyResponses returns a corpus of responses which broke caches before: header fields with many values, bodies in
+//odd encodings, a huge set of cookies, a TLS 1.3 state without server name and weak validators. The codec, policy and
+//replay tests run on them, applications validate their hooks, transforms and verifiers against them. The dumps of the
+//responses are in testdata/corpus of the module
+func TrickyResponses() []CorpusResponse {
+	return []CorpusResponse{
+		{
+			Name:        "multi-value-headers",
+			Description: "Cache-Control, Vary and Link split over several lines and repeated directives",
+			New: func() *http.Response {
+				return corpusResponse("multi-value-headers", http.StatusOK, http.Header{
+					"Cache-Control": {"public", "max-age=60, max-age=120", "stale-while-revalidate=30"},
+					"Vary":          {"Accept", "accept-encoding,  Accept-Language"},
+					"Link":          {"</a.css>; rel=preload; as=style", "</b.js>; rel=preload; as=script"},
+					"Content-Type":  {"text/plain; charset=utf-8"},
+				}, []byte("multiple values"))
+			},
+		},
+		{
+			Name:        "latin1-body",
+			Description: "an ISO-8859-1 body and header value which are no valid UTF-8, JSON replaces the invalid bytes of the header",
+			New: func() *http.Response {
+				return corpusResponse("latin1-body", http.StatusOK, http.Header{
+					"Cache-Control":       {"max-age=60"},
+					"Content-Type":        {"text/plain; charset=ISO-8859-1"},
+					"Content-Disposition": {"attachment; filename=\"caf\xe9.txt\""},
+				}, []byte("caf\xe9 cr\xe8me br\xfbl\xe9e"))
+			},
+		},
+		{
+			Name:        "binary-body",
+			Description: "a body with NUL, CR LF and invalid UTF-8 bytes looking like the end of a header",
+			New: func() *http.Response {
+				return corpusResponse("binary-body", http.StatusOK, http.Header{
+					"Cache-Control": {"max-age=60"},
+					"Content-Type":  {"application/octet-stream"},
+				}, []byte{0, 1, '\r', '\n', '\r', '\n', 0xff, 0xfe, 'H', 'T', 'T', 'P', '/', '1', '.', '1', 0})
+			},
+		},
+		{
+			Name:        "utf8-bom-json",
+			Description: "a JSON body with a byte order mark and escaped line separators",
+			New: func() *http.Response {
+				return corpusResponse("utf8-bom-json", http.StatusOK, http.Header{
+					"Cache-Control": {"max-age=60"},
+					"Content-Type":  {"application/json"},
+				}, []byte("\xef\xbb\xbf{\"text\":\"line\\u2028separator\"}"))
+			},
+		},
+		{
+			Name:        "huge-cookie-set",
+			Description: "200 Set-Cookie fields which must neither be merged nor served to other users",
+			New: func() *http.Response {
+				header := http.Header{"Cache-Control": {"private, max-age=60"}, "Content-Type": {"text/plain"}}
+				for i := 0; i < 200; i++ {
+					header.Add("Set-Cookie", fmt.Sprintf("c%03d=%0100d; Path=/; Secure; HttpOnly; SameSite=Lax", i, i))
+				}
+				return corpusResponse("huge-cookie-set", http.StatusOK, header, []byte("cookies"))
+			},
+		},
+		{
+			Name:        "weak-etag",
+			Description: "a weak ETag with no-cache, every use is a revalidation with If-None-Match",
+			New: func() *http.Response {
+				return corpusResponse("weak-etag", http.StatusOK, http.Header{
+					"Cache-Control": {"no-cache"},
+					"Etag":          {`W/"0815"`},
+					"Last-Modified": {"Sun, 01 Jan 2006 00:00:00 GMT"},
+					"Content-Type":  {"text/plain"},
+				}, []byte("weak validator"))
+			},
+		},
+		{
+			Name:        "invalid-expires",
+			Description: "Expires: 0 and an Age overflowing 32 bits which both mean already expired",
+			New: func() *http.Response {
+				return corpusResponse("invalid-expires", http.StatusOK, http.Header{
+					"Expires":      {"0"},
+					"Age":          {"4294967296"},
+					"Content-Type": {"text/plain"},
+				}, []byte("expired"))
+			},
+		},
+		{
+			Name:        "vary-star",
+			Description: "Vary: * which no request matches",
+			New: func() *http.Response {
+				return corpusResponse("vary-star", http.StatusOK, http.Header{
+					"Cache-Control": {"max-age=60"},
+					"Vary":          {"*"},
+					"Content-Type":  {"text/plain"},
+				}, []byte("never reused"))
+			},
+		},
+		{
+			Name:        "no-content",
+			Description: "a 204 without body and Content-Length with an empty header value",
+			New: func() *http.Response {
+				res := corpusResponse("no-content", http.StatusNoContent, http.Header{
+					"Cache-Control": {"max-age=60"},
+					"X-Empty":       {""},
+				}, nil)
+				res.ContentLength = 0
+				return res
+			},
+		},
+		{
+			Name:        "esni-tls",
+			Description: "a TLS 1.3 state of a resumed session with encrypted SNI, no server name and an Ed25519 certificate",
+			New: func() *http.Response {
+				res := corpusResponse("esni-tls", http.StatusOK, http.Header{
+					"Cache-Control": {"max-age=60"},
+					"Content-Type":  {"text/plain"},
+				}, []byte("encrypted server name"))
+				res.Proto, res.ProtoMajor, res.ProtoMinor = "HTTP/2.0", 2, 0
+				res.TLS = &tls.ConnectionState{
+					Version:                    tls.VersionTLS13,
+					HandshakeComplete:          true,
+					DidResume:                  true,
+					CipherSuite:                tls.TLS_AES_128_GCM_SHA256,
+					NegotiatedProtocol:         "h2",
+					NegotiatedProtocolIsMutual: true,
+				}
+				if certificate := corpusCertificate(); certificate != nil {
+					res.TLS.PeerCertificates = []*x509.Certificate{certificate}
+				}
+				return res
+			},
+		},
+	}
+}
+
+//corpusResponse returns a response with body to the corpus request for name
+func corpusResponse(name string, status int, header http.Header, body []byte) *http.Response {
+
+	header.Set("Date", corpusDate)
+	res := &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          http.NoBody,
+		ContentLength: int64(len(body)),
+		Request: &http.Request{
+			Method:     http.MethodGet,
+			URL:        &url.URL{Scheme: "https", Host: "example.com", Path: "/corpus/" + name},
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Accept": {"*/*"}, "Accept-Encoding": {"gzip"}, "Accept-Language": {"de-CH, en;q=0.5"}},
+			Host:       "example.com",
+		},
+	}
+	if len(body) > 0 {
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return res
+}
+
+var (
+	corpusCertificateOnce sync.Once
+	corpusCert            *x509.Certificate
+)
+
+//corpusCertificate returns a self-signed Ed25519 certificate derived from a fixed seed, it is the same in every run.
+//It is nil if the platform can not create it
+func corpusCertificate() *x509.Certificate {
+	corpusCertificateOnce.Do(func() {
+		key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{42}, ed25519.SeedSize))
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(2006),
+			Subject:      pkix.Name{CommonName: "example.com"},
+			DNSNames:     []string{"example.com"},
+			NotBefore:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			NotAfter:     time.Date(2106, 1, 2, 15, 4, 5, 0, time.UTC),
+		}
+		//Ed25519 signatures are deterministic and do not read the random source
+		der, err := x509.CreateCertificate(bytes.NewReader(nil), template, template, key.Public(), key)
+		if err == nil {
+			corpusCert, _ = x509.ParseCertificate(der)
+		}
+	})
+	return corpusCert
+}
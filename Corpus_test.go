@@ -0,0 +1,194 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTrickyResponses_Dumps(t *testing.T) {
+	for _, corpus := range TrickyResponses() {
+		t.Run(corpus.Name, func(t *testing.T) {
+			dump, err := httputil.DumpResponse(corpus.New(), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := ioutil.ReadFile("testdata/corpus/" + corpus.Name + ".http")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(dump, expected) {
+				t.Errorf("the dump differs from testdata/corpus/%s.http:\n%q", corpus.Name, dump)
+			}
+		})
+	}
+}
+
+func TestTrickyResponses_Codecs(t *testing.T) {
+
+	codecs := []struct {
+		name  string
+		codec Codec
+		//tls is false for the codecs not storing the TLS state
+		tls bool
+		//utf8 is true for the codecs replacing invalid UTF-8 in header values
+		utf8 bool
+	}{
+		{"json", JSONCodec, true, true},
+		{"gob", GobCodec, true, false},
+		{"wire", WireCodec, false, false},
+	}
+	for _, codec := range codecs {
+		for _, corpus := range TrickyResponses() {
+			t.Run(codec.name+"/"+corpus.Name, func(t *testing.T) {
+				original := corpus.New()
+				response, err := NewJsonResponse(corpus.New())
+				if err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				if err := codec.codec.Encode(&buf, &FileCacheEntry{Request: "key", Response: response}); err != nil {
+					t.Fatal(err)
+				}
+				var entry FileCacheEntry
+				if err := codec.codec.NewDecoder(&buf).Decode(&entry); err != nil {
+					t.Fatal(err)
+				}
+				decoded, err := entry.Response.Parse()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				header := original.Header
+				if codec.utf8 {
+					header = validUTF8Header(header)
+				}
+				if decoded.StatusCode != original.StatusCode || !reflect.DeepEqual(decoded.Header, header) {
+					t.Error("the response changed", decoded.StatusCode, decoded.Header)
+				}
+				body, _ := ioutil.ReadAll(decoded.Body)
+				expected, _ := ioutil.ReadAll(original.Body)
+				if !bytes.Equal(body, expected) {
+					t.Errorf("the body changed: %q", body)
+				}
+				if codec.tls && original.TLS != nil {
+					if decoded.TLS == nil || decoded.TLS.Version != original.TLS.Version || decoded.TLS.ServerName != "" ||
+						len(decoded.TLS.PeerCertificates) != len(original.TLS.PeerCertificates) {
+						t.Fatal("the TLS state changed", decoded.TLS)
+					}
+					for i, certificate := range original.TLS.PeerCertificates {
+						if !certificate.Equal(decoded.TLS.PeerCertificates[i]) {
+							t.Error("the certificate changed")
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestTrickyResponses_Caching(t *testing.T) {
+
+	//revalidated responses are requested again, the others are served from the cache
+	revalidated := map[string]bool{"weak-etag": true, "invalid-expires": true, "vary-star": true}
+
+	for _, corpus := range TrickyResponses() {
+		t.Run(corpus.Name, func(t *testing.T) {
+			var requests int32
+			var conditional string
+			transport := &CachedTransport{Cache: NewMapCache(), Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&requests, 1)
+				conditional = req.Header.Get("If-None-Match")
+				res := corpus.New()
+				//the corpus is dated 2006, it is served as if it was sent now
+				res.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+				res.Request = req
+				return res, nil
+			})}
+
+			for i := 0; i < 2; i++ {
+				res, err := transport.RoundTrip(corpus.New().Request)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = res.Body.Close()
+			}
+			expected := int32(1)
+			if revalidated[corpus.Name] {
+				expected = 2
+			}
+			if requests != expected {
+				t.Error("expected", expected, "origin requests, got", requests)
+			}
+			if corpus.Name == "weak-etag" && conditional != `W/"0815"` {
+				t.Error("expected a revalidation with the weak ETag, got", conditional)
+			}
+		})
+	}
+}
+
+func TestTrickyResponses_Replay(t *testing.T) {
+
+	corpora := TrickyResponses()
+	fixture := "tmp/corpus.json"
+	recorder := NewRecorder(fixture, roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		for _, corpus := range corpora {
+			if req.URL.Path == "/corpus/"+corpus.Name {
+				res := corpus.New()
+				res.Request = req
+				return res, nil
+			}
+		}
+		return nil, NoMatchingInteractionError
+	}))
+	for _, corpus := range corpora {
+		res, err := recorder.RoundTrip(corpus.New().Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := OpenReplayer(fixture, MatchMethod, MatchURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, corpus := range corpora {
+		t.Run(corpus.Name, func(t *testing.T) {
+			original := corpus.New()
+			res, err := replayer.RoundTrip(original.Request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			//cassettes are JSON
+			if res.StatusCode != original.StatusCode || !reflect.DeepEqual(res.Header, validUTF8Header(original.Header)) {
+				t.Error("the replayed response differs", res.StatusCode, res.Header)
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			expected, _ := ioutil.ReadAll(original.Body)
+			if !bytes.Equal(body, expected) {
+				t.Errorf("the replayed body differs: %q", body)
+			}
+		})
+	}
+}
+
+//validUTF8Header returns header with the invalid UTF-8 in its values replaced like encoding/json does
+func validUTF8Header(header http.Header) http.Header {
+	valid := http.Header{}
+	for name, values := range header {
+		for _, value := range values {
+			valid[name] = append(valid[name], strings.ToValidUTF8(value, "\uFFFD"))
+		}
+	}
+	return valid
+}
@@ -0,0 +1,118 @@
+package CachedHttpClient
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"time"
+)
+
+//DNSMessageMediaType is the media type of DNS over HTTPS messages (RFC 8484)
+const DNSMessageMediaType = "application/dns-message"
+
+//InvalidDNSMessageError is returned for DNS messages which can not be parsed
+var InvalidDNSMessageError = errors.New("invalid DNS message")
+
+const (
+	dnsHeaderSize = 12
+	dnsTypeSOA    = 6
+)
+
+//DNSMessageTTL is a TTLExtractor for DNSMessageMediaType responses. The lifetime is the smallest TTL of the answer
+//records, for answers without records the negative caching TTL of the SOA record in the authority section (RFC 2308
+//5). Messages without either are not cached by it
+func DNSMessageTTL(res *http.Response, body []byte) (time.Duration, bool) {
+	ttl, err := dnsMessageTTL(body)
+	if err != nil || ttl < 0 {
+		return 0, false
+	}
+	return ttl, true
+}
+
+//NormalizeDNSMessage is a Normalize function for PostCaching which sets the ID of DNS queries to 0 before the digest
+//is computed, so queries sent with POST only differing in their ID share a response. The ID of served responses is
+//the one of the first query, RFC 8484 4.1 asks clients to use 0
+func NormalizeDNSMessage(body []byte) ([]byte, error) {
+	if len(body) < dnsHeaderSize {
+		return nil, InvalidDNSMessageError
+	}
+	normalized := append([]byte{0, 0}, body[2:]...)
+	return normalized, nil
+}
+
+//dnsMessageTTL returns the TTL of the DNS message as described by DNSMessageTTL, -1 if there is none
+func dnsMessageTTL(message []byte) (time.Duration, error) {
+
+	if len(message) < dnsHeaderSize {
+		return 0, InvalidDNSMessageError
+	}
+	questions := int(binary.BigEndian.Uint16(message[4:]))
+	answers := int(binary.BigEndian.Uint16(message[6:]))
+	authorities := int(binary.BigEndian.Uint16(message[8:]))
+
+	offset := dnsHeaderSize
+	var err error
+	for i := 0; i < questions; i++ {
+		if offset, err = skipDNSName(message, offset); err != nil {
+			return 0, err
+		}
+		//type and class
+		offset += 4
+	}
+
+	ttl := int64(-1)
+	for i := 0; i < answers+authorities; i++ {
+		if offset, err = skipDNSName(message, offset); err != nil {
+			return 0, err
+		}
+		if offset+10 > len(message) {
+			return 0, InvalidDNSMessageError
+		}
+		recordType := binary.BigEndian.Uint16(message[offset:])
+		recordTTL := int64(binary.BigEndian.Uint32(message[offset+4:]))
+		length := int(binary.BigEndian.Uint16(message[offset+8:]))
+		offset += 10
+		if offset+length > len(message) {
+			return 0, InvalidDNSMessageError
+		}
+		data := message[offset : offset+length]
+		offset += length
+
+		if i >= answers {
+			if answers > 0 || recordType != dnsTypeSOA || len(data) < 4 {
+				continue
+			}
+			//the negative caching TTL is the smaller of the TTL of the SOA record and its MINIMUM field
+			if minimum := int64(binary.BigEndian.Uint32(data[len(data)-4:])); minimum < recordTTL {
+				recordTTL = minimum
+			}
+		}
+		if ttl < 0 || recordTTL < ttl {
+			ttl = recordTTL
+		}
+	}
+	if ttl < 0 {
+		return -1, nil
+	}
+	return time.Duration(ttl) * time.Second, nil
+}
+
+//skipDNSName returns the offset after the domain name starting at offset
+func skipDNSName(message []byte, offset int) (int, error) {
+	for {
+		if offset >= len(message) {
+			return 0, InvalidDNSMessageError
+		}
+		length := int(message[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			//a compression pointer ends the name
+			return offset + 2, nil
+		case length&0xc0 != 0:
+			return 0, InvalidDNSMessageError
+		}
+		offset += 1 + length
+	}
+}
@@ -0,0 +1,98 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+//dnsTestMessage returns a response for example.com with A records of answerTTLs and, if soa is not nil, a SOA record
+//with the TTL soa[0] and the MINIMUM soa[1] in the authority section
+func dnsTestMessage(answerTTLs []uint32, soa *[2]uint32) []byte {
+
+	var message bytes.Buffer
+	authorities := 0
+	if soa != nil {
+		authorities = 1
+	}
+	header := make([]byte, dnsHeaderSize)
+	binary.BigEndian.PutUint16(header[0:], 0xabcd)
+	binary.BigEndian.PutUint16(header[2:], 0x8180)
+	binary.BigEndian.PutUint16(header[4:], 1)
+	binary.BigEndian.PutUint16(header[6:], uint16(len(answerTTLs)))
+	binary.BigEndian.PutUint16(header[8:], uint16(authorities))
+	message.Write(header)
+	//question example.com IN A
+	message.Write([]byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0, 0, 1, 0, 1})
+
+	record := func(recordType uint16, ttl uint32, data []byte) {
+		//compression pointer to the name of the question
+		fields := make([]byte, 12)
+		binary.BigEndian.PutUint16(fields[0:], 0xc000|dnsHeaderSize)
+		binary.BigEndian.PutUint16(fields[2:], recordType)
+		binary.BigEndian.PutUint16(fields[4:], 1)
+		binary.BigEndian.PutUint32(fields[6:], ttl)
+		binary.BigEndian.PutUint16(fields[10:], uint16(len(data)))
+		message.Write(fields)
+		message.Write(data)
+	}
+	for _, ttl := range answerTTLs {
+		record(1, ttl, []byte{93, 184, 216, 34})
+	}
+	if soa != nil {
+		data := []byte{2, 'n', 's', 0, 4, 'r', 'o', 'o', 't', 0}
+		//serial, refresh, retry, expire and minimum
+		numbers := make([]byte, 20)
+		binary.BigEndian.PutUint32(numbers[16:], soa[1])
+		record(dnsTypeSOA, soa[0], append(data, numbers...))
+	}
+	return message.Bytes()
+}
+
+func TestDNSMessageTTL(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		message  []byte
+		expected time.Duration
+		ok       bool
+	}{
+		{"smallest answer", dnsTestMessage([]uint32{300, 60, 120}, nil), time.Minute, true},
+		{"answer before SOA", dnsTestMessage([]uint32{300}, &[2]uint32{30, 10}), 5 * time.Minute, true},
+		{"negative SOA TTL", dnsTestMessage(nil, &[2]uint32{3600, 900}), 15 * time.Minute, true},
+		{"negative SOA minimum", dnsTestMessage(nil, &[2]uint32{60, 900}), time.Minute, true},
+		{"no records", dnsTestMessage(nil, nil), 0, false},
+		{"truncated", dnsTestMessage([]uint32{300}, nil)[:40], 0, false},
+		{"too short", []byte{0, 1, 2}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ttl, ok := DNSMessageTTL(nil, tt.message)
+			if ok != tt.ok || ttl != tt.expected {
+				t.Errorf("DNSMessageTTL = %v, %v, want %v, %v", ttl, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNormalizeDNSMessage(t *testing.T) {
+
+	message := dnsTestMessage([]uint32{300}, nil)
+	normalized, err := NormalizeDNSMessage(message)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if normalized[0] != 0 || normalized[1] != 0 || !bytes.Equal(normalized[2:], message[2:]) {
+		t.Error("expected only the ID to be cleared, got", normalized)
+	}
+	if message[0] != 0xab {
+		t.Error("the message was modified")
+	}
+	if _, err := NormalizeDNSMessage([]byte{1}); !errors.Is(err, InvalidDNSMessageError) {
+		t.Error("expected InvalidDNSMessageError, got", err)
+	}
+}
@@ -0,0 +1,58 @@
+package CachedHttpClient
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+)
+
+//Digester is the hash algorithm used for content addressing and checksums, e.g. the names of the body files of
+//FileCache, the body hash of partial entries and HashedHeaders of NewKeyFunc. Other algorithms like BLAKE3 are
+//added with NewDigester
+type Digester interface {
+	//Name identifies the algorithm in stored digests, e.g. "sha256"
+	Name() string
+	New() hash.Hash
+}
+
+//SHA256Digester is the default Digester
+var SHA256Digester Digester = NewDigester("sha256", sha256.New)
+
+//SHA512Digester hashes with SHA-512, which is faster than SHA-256 on 64-bit CPUs without SHA extensions
+var SHA512Digester Digester = NewDigester("sha512", sha512.New)
+
+//NewDigester creates a Digester named name from a hash constructor, e.g. of a BLAKE3 implementation
+//
+//	digester := NewDigester("blake3", func() hash.Hash { return blake3.New(32, nil) })
+func NewDigester(name string, newHash func() hash.Hash) Digester {
+	return hashDigester{name: name, newHash: newHash}
+}
+
+type hashDigester struct {
+	name    string
+	newHash func() hash.Hash
+}
+
+func (d hashDigester) Name() string {
+	return d.name
+}
+
+func (d hashDigester) New() hash.Hash {
+	return d.newHash()
+}
+
+//digestOrDefault returns digester or SHA256Digester if it is nil
+func digestOrDefault(digester Digester) Digester {
+	if digester == nil {
+		return SHA256Digester
+	}
+	return digester
+}
+
+//hexDigest returns the hex encoded digest of data, SHA256Digester is used if digester is nil
+func hexDigest(digester Digester, data []byte) string {
+	h := digestOrDefault(digester).New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
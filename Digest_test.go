@@ -0,0 +1,43 @@
+package CachedHttpClient
+
+import (
+	"crypto/md5"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDigester(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		digester Digester
+		header   string
+		sha256   bool
+	}{
+		{"default", nil, "sha256=ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", true},
+		{"sha512", SHA512Digester, "sha512=ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f", false},
+		{"custom", NewDigester("md5", md5.New), "md5=900150983cd24fb0d6963f7d28e17f72", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+			partial := partialResponse(res, []byte("abc"), 1, tt.digester)
+			if digest := partial.Header.Get(BodyDigestHeader); digest != tt.header {
+				t.Error("expected", tt.header, "got", digest)
+			}
+			if (partial.Header.Get(BodySHA256Header) != "") != tt.sha256 {
+				t.Error("expected the SHA-256 header", tt.sha256, "got", partial.Header.Get(BodySHA256Header))
+			}
+
+			request, _ := http.NewRequest("GET", "http://example.com/", nil)
+			request.Header.Set("Authorization", "abc")
+			key := NewKeyFunc(KeyOptions{HashedHeaders: []string{"Authorization"}, Digester: tt.digester})(request)
+			if !strings.Contains(key, "Authorization: "+tt.header+"\r\n") {
+				t.Errorf("expected the hashed header %q in %q", tt.header, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//DefaultDiskBatchBytes is the size of the pending entries a batch is flushed at if DiskBatchOptions.MaxPendingBytes
+//is 0
+const DefaultDiskBatchBytes = 4 << 20
+
+//DefaultDiskBatchEntrySize is the size of the largest entry buffered if DiskBatchOptions.MaxEntrySize is 0
+const DefaultDiskBatchEntrySize = 64 << 10
+
+//DiskSyncPolicy selects when DiskCache calls fsync for the entry files it writes
+type DiskSyncPolicy int
+
+const (
+	//SyncEachEntry syncs every entry file before it replaces the previous one and its directory after, entries survive
+	//a power loss once Set or Flush returned. Flush syncs the buffered entries one by one
+	SyncEachEntry DiskSyncPolicy = iota
+	//SyncNever leaves the write back to the operating system. A power loss may lose the entries written in the last
+	//seconds or leave truncated entry files, which are read as errors and replaced by the next store
+	SyncNever
+	//SyncBatch makes Flush write all buffered entries to temporary files, fsync them before the first rename, rename
+	//them and sync every directory once instead of after each entry. Flush returns the first error of a sync and
+	//replaces no entry then, entries survive a power loss once Flush returned without error. Entries written before
+	//Set returns are synced like with SyncEachEntry
+	SyncBatch
+)
+
+//DiskBatchOptions makes DiskCache buffer small entries in memory and write them in batches, which saves the writes of
+//entries stored again before the batch is flushed and spreads the IOPS on network filesystems and EBS volumes.
+//Buffered entries are served by Get like written ones but are lost on a crash, every entry is still written
+//atomically. Large entries and stores with WithSyncWrite are written before Set returns
+type DiskBatchOptions struct {
+	//MaxPendingBytes is the size of the buffered entries which makes Set flush the batch, DefaultDiskBatchBytes if 0
+	MaxPendingBytes int
+	//MaxEntrySize is the size of the largest entry buffered, DefaultDiskBatchEntrySize if 0
+	MaxEntrySize int
+}
+
+func (o *DiskBatchOptions) maxPendingBytes() int {
+	if o.MaxPendingBytes <= 0 {
+		return DefaultDiskBatchBytes
+	}
+	return o.MaxPendingBytes
+}
+
+func (o *DiskBatchOptions) maxEntrySize() int {
+	if o.MaxEntrySize <= 0 {
+		return DefaultDiskBatchEntrySize
+	}
+	return o.MaxEntrySize
+}
+
+//diskBatch holds the entries of a DiskCache waiting to be written
+type diskBatch struct {
+	//flushMutex serializes the flushes with the writes bypassing the batch, so an older buffered entry never
+	//replaces a newer one
+	flushMutex sync.Mutex
+
+	mutex sync.Mutex
+	//pending holds the content of the buffered entry files by path
+	pending map[string][]byte
+	bytes   int
+	//flushing holds the entries being written by Flush, they are served from memory until they are written
+	flushing map[string][]byte
+}
+
+//WithSyncWrite returns a context making DiskCache write the responses of requests using it before Set returns, even
+//with DiskCacheOptions.Batch
+func WithSyncWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, syncWriteContextKey, true)
+}
+
+func syncWriteFromContext(ctx context.Context) bool {
+	syncWrite, _ := ctx.Value(syncWriteContextKey).(bool)
+	return syncWrite
+}
+
+//write writes the entry file at path now or buffers it with DiskCacheOptions.Batch
+func (d *DiskCache) write(ctx context.Context, path string, content []byte) error {
+
+	if d.Batch == nil {
+		return writeFileAtomic(path, content, d.Sync)
+	}
+	if len(content) > d.Batch.maxEntrySize() || syncWriteFromContext(ctx) {
+		d.batch.flushMutex.Lock()
+		defer d.batch.flushMutex.Unlock()
+		d.batch.mutex.Lock()
+		d.dropPending(path)
+		d.batch.mutex.Unlock()
+		return writeFileAtomic(path, content, d.Sync)
+	}
+
+	d.batch.mutex.Lock()
+	d.dropPending(path)
+	if d.batch.pending == nil {
+		d.batch.pending = map[string][]byte{}
+	}
+	d.batch.pending[path] = content
+	d.batch.bytes += len(content)
+	full := d.batch.bytes >= d.Batch.maxPendingBytes()
+	d.batch.mutex.Unlock()
+	if full {
+		return d.Flush()
+	}
+	return nil
+}
+
+//dropPending removes the buffered entry file at path, d.batch.mutex has to be held
+func (d *DiskCache) dropPending(path string) bool {
+	content, ok := d.batch.pending[path]
+	if ok {
+		delete(d.batch.pending, path)
+		d.batch.bytes -= len(content)
+	}
+	return ok
+}
+
+//pendingContent returns the content of the buffered entry file at path
+func (d *DiskCache) pendingContent(path string) ([]byte, bool) {
+	d.batch.mutex.Lock()
+	defer d.batch.mutex.Unlock()
+	content, ok := d.batch.pending[path]
+	if !ok {
+		content, ok = d.batch.flushing[path]
+	}
+	return content, ok
+}
+
+//pendingMetadata returns the metadata of the buffered entries
+func (d *DiskCache) pendingMetadata() []*DiskEntryMetadata {
+	d.batch.mutex.Lock()
+	defer d.batch.mutex.Unlock()
+	var metadata []*DiskEntryMetadata
+	for _, pending := range []map[string][]byte{d.batch.pending, d.batch.flushing} {
+		for _, content := range pending {
+			if entry, err := parseDiskMetadata(content); err == nil {
+				metadata = append(metadata, entry)
+			}
+		}
+	}
+	return metadata
+}
+
+//Flush writes the buffered entries. Entries failing to be written are dropped, the first error is returned
+func (d *DiskCache) Flush() error {
+
+	d.batch.flushMutex.Lock()
+	defer d.batch.flushMutex.Unlock()
+	d.batch.mutex.Lock()
+	pending := d.batch.pending
+	d.batch.pending, d.batch.bytes, d.batch.flushing = nil, 0, pending
+	d.batch.mutex.Unlock()
+
+	var first error
+	if d.Sync == SyncBatch {
+		first = writeBatch(pending)
+	} else {
+		for path, content := range pending {
+			if err := writeFileAtomic(path, content, d.Sync); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	d.batch.mutex.Lock()
+	d.batch.flushing = nil
+	d.batch.mutex.Unlock()
+	return first
+}
+
+//writeBatch writes the entry files of pending with SyncBatch. Entries failing to be written are dropped, the first
+//error is returned. No entry replaces its previous version if the files can not be synced
+func writeBatch(pending map[string][]byte) error {
+
+	var first error
+	fail := func(err error) {
+		if first == nil {
+			first = err
+		}
+	}
+	temps := make(map[string]string, len(pending))
+	defer func() {
+		for _, temp := range temps {
+			_ = os.Remove(temp)
+		}
+	}()
+	for path, content := range pending {
+		if err := makeDirs(filepath.Dir(path), true); err != nil {
+			fail(err)
+			continue
+		}
+		temp, err := writeTemp(path, content, false)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		temps[path] = temp
+	}
+
+	names := make([]string, 0, len(temps))
+	for _, temp := range temps {
+		names = append(names, temp)
+	}
+	for _, name := range names {
+		if err := syncFile(name); err != nil {
+			return err
+		}
+	}
+
+	dirs := map[string]bool{}
+	for path, temp := range temps {
+		if err := os.Rename(temp, path); err != nil {
+			fail(err)
+			continue
+		}
+		delete(temps, path)
+		dirs[filepath.Dir(path)] = true
+	}
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			fail(err)
+		}
+	}
+	return first
+}
+
+//syncFile fsyncs the file name
+func syncFile(name string) error {
+
+	//Windows only flushes files opened for writing
+	file, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//FlushEvery flushes the buffered entries every interval of the Clock until stop is called
+func (d *DiskCache) FlushEvery(interval time.Duration) (stop func()) {
+
+	ticker := clockOrDefault(d.Clock).NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				_ = d.Flush()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+//parseDiskMetadata parses the metadata line at the start of the content of an entry file
+func parseDiskMetadata(content []byte) (*DiskEntryMetadata, error) {
+	newline := bytes.IndexByte(content, '\n')
+	if newline < 0 {
+		return nil, wrapDecodeError(io.ErrUnexpectedEOF)
+	}
+	var metadata DiskEntryMetadata
+	if err := json.Unmarshal(content[:newline], &metadata); err != nil {
+		return nil, wrapDecodeError(err)
+	}
+	return &metadata, nil
+}
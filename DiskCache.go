@@ -0,0 +1,402 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//diskTempPrefix starts the names of the files DiskCache writes before renaming them to their entry file
+const diskTempPrefix = ".tmp-"
+
+//DiskCache stores every entry in its own file below a directory. The files are sharded into subdirectories by the
+//hash of their key, e.g. ab/cd/abcd..., so directories stay small with millions of entries. Entries are written to
+//a temporary file which is renamed to the entry file so a crash never leaves a partial entry. Every file starts with
+//a metadata line readable without decoding the response, see DiskEntryMetadata. With DiskCacheOptions.Batch small
+//entries are buffered and written in batches
+type DiskCache struct {
+	dir   string
+	batch diskBatch
+	DiskCacheOptions
+}
+
+type DiskCacheOptions struct {
+	MapCacheOptions
+	//Codec encodes the entries after the metadata line, JSONCodec if nil
+	Codec Codec
+	//Compression compresses the bodies if not nil
+	Compression *Compression
+	//Digester hashes the keys to the file names, SHA256Digester if nil
+	Digester Digester
+	//ShardLevels is the number of subdirectory levels named by two hex digits of the key hash, 2 if 0. Changing it
+	//hides the existing entries
+	ShardLevels int
+	//Shared selects the freshness rules of a shared cache for DiskEntryMetadata.Expires
+	Shared bool
+	//Batch buffers small entries and writes them in batches by Flush if not nil, see DiskBatchOptions
+	Batch *DiskBatchOptions
+	//Sync selects when the entry files are synced to the disk, SyncEachEntry by default
+	Sync DiskSyncPolicy
+	//Clock tells the time of DiskEntryMetadata.StoredAt and Expires and of PurgeExpired and schedules FlushEvery,
+	//SystemClock if nil
+	Clock Clock
+}
+
+//DiskEntryMetadata is the first line of every entry file of DiskCache
+type DiskEntryMetadata struct {
+	Key string
+	//Expires is when the response stops being fresh, zero for entries written without it
+	Expires time.Time `json:",omitempty"`
+	//Size is the size of the uncompressed body in bytes
+	Size int64
+	//Vary are the request headers named in the Vary header of the response
+	Vary     http.Header `json:",omitempty"`
+	StoredAt time.Time
+}
+
+//NewDiskCache creates a DiskCache storing its entries below dir, existing entries are kept
+func NewDiskCache(dir string, options ...DiskCacheOptions) (*DiskCache, error) {
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	diskCache := &DiskCache{dir: dir}
+	if options != nil {
+		diskCache.DiskCacheOptions = options[0]
+	}
+	return diskCache, nil
+}
+
+func (o DiskCacheOptions) codec() Codec {
+	if o.Codec == nil {
+		return JSONCodec
+	}
+	return o.Codec
+}
+
+//Key returns the key the response for req is stored under
+func (d *DiskCache) Key(req *http.Request) (string, error) {
+	return d.MapCacheOptions.key(req)
+}
+
+//path returns the file the entry for key is stored in
+func (d *DiskCache) path(key string) string {
+
+	hash := hexDigest(d.Digester, []byte(key))
+	levels := d.ShardLevels
+	if levels <= 0 {
+		levels = 2
+	}
+	parts := []string{d.dir}
+	for level := 0; level < levels && 2*level+2 <= len(hash); level++ {
+		parts = append(parts, hash[2*level:2*level+2])
+	}
+	return filepath.Join(append(parts, hash)...)
+}
+
+func (d *DiskCache) Get(req *http.Request) (*http.Response, error) {
+
+	key, err := d.Key(req)
+	if err != nil {
+		return nil, err
+	}
+	return d.GetKey(key)
+}
+
+func (d *DiskCache) Set(req *http.Request, res *http.Response) error {
+
+	key, err := d.Key(req)
+	if err != nil {
+		return err
+	}
+
+	response, err := NewJsonResponse(res)
+	if err != nil {
+		return err
+	}
+	now := clockOrDefault(d.Clock).Now()
+	metadata := DiskEntryMetadata{Key: key, Size: int64(len(response.Body)), Vary: response.VaryHeaders, StoredAt: now.UTC()}
+	metadata.Expires = now.Add(policy.FreshnessLifetime(res, d.Shared) - policy.CurrentAge(res, now)).UTC()
+	err = d.Compression.compress(response)
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	err = json.NewEncoder(&content).Encode(metadata)
+	if err != nil {
+		return err
+	}
+	err = d.codec().Encode(&content, &FileCacheEntry{Version: EntrySchemaVersion, Request: key, Response: response})
+	if err != nil {
+		return err
+	}
+	return d.write(req.Context(), d.path(key), content.Bytes())
+}
+
+//writeFileAtomic replaces the file at path by content, readers see the old or the new content but never a part.
+//Unless policy is SyncNever the file is synced before it replaces the old one and its directory after, so the entry
+//survives a power loss once writeFileAtomic returned
+func writeFileAtomic(path string, content []byte, policy DiskSyncPolicy) error {
+
+	sync := policy != SyncNever
+	err := makeDirs(filepath.Dir(path), sync)
+	if err != nil {
+		return err
+	}
+	temp, err := writeTemp(path, content, sync)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+
+	err = os.Rename(temp, path)
+	if err != nil || !sync {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+//writeTemp writes content to a new temporary file in the directory of path and returns its name, the file is synced
+//if sync is set
+func writeTemp(path string, content []byte, sync bool) (string, error) {
+
+	file, err := ioutil.TempFile(filepath.Dir(path), diskTempPrefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(content)
+	if err == nil && sync {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = file.Chmod(0644)
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+//makeDirs creates dir and its missing parents, with sync the parent of every created directory is synced so the
+//directory survives a power loss
+func makeDirs(dir string, sync bool) error {
+
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+	parent := filepath.Dir(dir)
+	if parent != dir {
+		if err := makeDirs(parent, sync); err != nil {
+			return err
+		}
+	}
+	err := os.Mkdir(dir, 0755)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil || !sync {
+		return err
+	}
+	return syncDir(parent)
+}
+
+//syncDir syncs the directory entries of dir, e.g. the names of the files renamed into it
+func syncDir(dir string) error {
+
+	if runtime.GOOS == "windows" {
+		//directories can not be opened for syncing, NTFS journals the renames
+		return nil
+	}
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//Keys returns the sorted keys of all entries, the metadata line of every entry file is read
+func (d *DiskCache) Keys() []string {
+
+	seen := map[string]bool{}
+	var keys []string
+	add := func(metadata *DiskEntryMetadata) {
+		if !seen[metadata.Key] {
+			seen[metadata.Key] = true
+			keys = append(keys, metadata.Key)
+		}
+	}
+	for _, metadata := range d.pendingMetadata() {
+		add(metadata)
+	}
+	_ = d.walk(func(path string, metadata *DiskEntryMetadata) error {
+		add(metadata)
+		return nil
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+//walk calls visit with the metadata of every entry file, files which can not be read are skipped
+func (d *DiskCache) walk(visit func(path string, metadata *DiskEntryMetadata) error) error {
+
+	return filepath.Walk(d.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasPrefix(info.Name(), diskTempPrefix) {
+			return nil
+		}
+		metadata, err := readDiskMetadata(path)
+		if err != nil {
+			return nil
+		}
+		return visit(path, metadata)
+	})
+}
+
+//readDiskMetadata reads the metadata line of the entry file at path
+func readDiskMetadata(path string) (*DiskEntryMetadata, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	return parseDiskMetadata(line)
+}
+
+//Metadata returns the metadata of the entry stored under key without decoding the response
+func (d *DiskCache) Metadata(key string) (*DiskEntryMetadata, error) {
+
+	var metadata *DiskEntryMetadata
+	var err error
+	if content, ok := d.pendingContent(d.path(key)); ok {
+		metadata, err = parseDiskMetadata(content)
+	} else {
+		metadata, err = readDiskMetadata(d.path(key))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, NotInCacheError
+	}
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Key != key {
+		return nil, NotInCacheError
+	}
+	return metadata, nil
+}
+
+//GetKey returns the response stored under key
+func (d *DiskCache) GetKey(key string) (*http.Response, error) {
+
+	content, ok := d.pendingContent(d.path(key))
+	var err error
+	if !ok {
+		content, err = ioutil.ReadFile(d.path(key))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, NotInCacheError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newline := bytes.IndexByte(content, '\n')
+	if newline < 0 {
+		return nil, wrapDecodeError(io.ErrUnexpectedEOF)
+	}
+	var entry FileCacheEntry
+	err = decodeEntry(d.codec().NewDecoder(bytes.NewReader(content[newline+1:])), &entry)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Request != key || entry.Response == nil {
+		return nil, NotInCacheError
+	}
+	res, err := entry.Response.Parse()
+	return res, wrapDecodeError(err)
+}
+
+//DeleteKey removes the file of the entry stored under key
+func (d *DiskCache) DeleteKey(key string) error {
+
+	if _, err := d.Metadata(key); err != nil {
+		return err
+	}
+	if d.Batch != nil {
+		//a flush in progress could write the entry again
+		d.batch.flushMutex.Lock()
+		defer d.batch.flushMutex.Unlock()
+	}
+	d.batch.mutex.Lock()
+	dropped := d.dropPending(d.path(key))
+	d.batch.mutex.Unlock()
+	err := os.Remove(d.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		if dropped {
+			return nil
+		}
+		return NotInCacheError
+	}
+	return err
+}
+
+//PurgeExpired deletes the entries which expired more than maxStale ago using only their metadata and returns their
+//number
+func (d *DiskCache) PurgeExpired(maxStale time.Duration) (int, error) {
+	deleted, _, err := d.purgeExpired(maxStale, clockOrDefault(d.Clock).Now(), nil)
+	return deleted, err
+}
+
+//purgeExpired deletes the entries like PurgeExpired at now and returns their number and the size of their bodies, their
+//variants are forgotten by variants
+func (d *DiskCache) purgeExpired(maxStale time.Duration, now time.Time, variants *VariantTracker) (int, int64, error) {
+
+	deadline := now.Add(-maxStale)
+	deleted := 0
+	var reclaimed int64
+	err := d.walk(func(path string, metadata *DiskEntryMetadata) error {
+		if metadata.Expires.IsZero() || metadata.Expires.After(deadline) {
+			return nil
+		}
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		variants.Evicted(metadata.Key)
+		deleted++
+		reclaimed += metadata.Size
+		return nil
+	})
+	return deleted, reclaimed, err
+}
@@ -0,0 +1,270 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+
+	dir := "tmp/disk"
+	if err := os.RemoveAll(dir); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	options := DiskCacheOptions{MapCacheOptions: MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})}, Compression: &Compression{}}
+	cache, err := NewDiskCache(dir, options)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	entries := []struct {
+		path         string
+		cacheControl string
+	}{{"/fresh", "max-age=3600"}, {"/expired", "max-age=1"}, {"/forever", ""}}
+	for _, entry := range entries {
+		request := lruTestRequest(t, entry.path)
+		request.Header.Set("Accept", "text/plain")
+		response := lruTestResponse(strings.Repeat(entry.path, 100))
+		response.Header.Set("Vary", "Accept")
+		response.Header.Set("Date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		if entry.cacheControl != "" {
+			response.Header.Set("Cache-Control", entry.cacheControl)
+		}
+		response.Request = request
+		if err := cache.Set(request, response); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	key, _ := cache.Key(lruTestRequest(t, "/fresh"))
+	path := cache.path(key)
+	if relative, _ := filepath.Rel(dir, path); strings.Count(relative, string(filepath.Separator)) != 2 {
+		t.Error("expected the entry file two levels below the directory got", relative)
+	}
+	temporary, _ := filepath.Glob(filepath.Join(dir, "*", "*", diskTempPrefix+"*"))
+	if len(temporary) != 0 {
+		t.Error("expected no temporary files got", temporary)
+	}
+
+	reopened, err := NewDiskCache(dir, options)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	response, err := reopened.Get(lruTestRequest(t, "/fresh"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(response.Body); string(body) != strings.Repeat("/fresh", 100) {
+		t.Error("expected the stored body got", string(body))
+	}
+	if response.Request == nil || response.Request.Header.Get("Accept") != "text/plain" {
+		t.Error("expected the vary headers of the request")
+	}
+
+	metadata, err := reopened.Metadata(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if metadata.Size != 600 || metadata.Vary.Get("Accept") != "text/plain" || time.Until(metadata.Expires) < 58*time.Minute {
+		t.Errorf("expected size, vary headers and expiry in the metadata got %+v", metadata)
+	}
+
+	if keys := reopened.Keys(); len(keys) != 3 {
+		t.Error("expected 3 keys got", len(keys))
+	}
+	deleted, err := reopened.PurgeExpired(0)
+	if err != nil || deleted != 1 {
+		t.Error("expected the expired entry to be purged got", deleted, err)
+	}
+	if err := reopened.DeleteKey(key); err != nil {
+		t.Error(err)
+	}
+	if _, err := reopened.Get(lruTestRequest(t, "/fresh")); !errors.Is(err, NotInCacheError) {
+		t.Error("expected NotInCacheError got", err)
+	}
+	if keys := reopened.Keys(); len(keys) != 1 {
+		t.Error("expected the entry without expiry to remain got", keys)
+	}
+}
+
+func TestDiskCache_Batch(t *testing.T) {
+
+	dir := "tmp/disk-batch"
+	if err := os.RemoveAll(dir); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	options := DiskCacheOptions{Batch: &DiskBatchOptions{MaxPendingBytes: 4 << 10, MaxEntrySize: 1 << 10}, Sync: SyncNever}
+	cache, err := NewDiskCache(dir, options)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	files := func() int {
+		count := 0
+		_ = cache.walk(func(path string, metadata *DiskEntryMetadata) error {
+			count++
+			return nil
+		})
+		return count
+	}
+	get := func(path string) string {
+		res, err := cache.Get(lruTestRequest(t, path))
+		if err != nil {
+			return err.Error()
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		return string(body)
+	}
+
+	for _, body := range []string{"first", "second"} {
+		if err := cache.Set(lruTestRequest(t, "/small"), lruTestResponse(body)); err != nil {
+			t.Error(err)
+		}
+	}
+	if files() != 0 || get("/small") != "second" || len(cache.Keys()) != 1 {
+		t.Error("expected the small entry to be buffered and served from memory, files", files(), "keys", cache.Keys())
+	}
+
+	if err := cache.Set(lruTestRequest(t, "/large"), lruTestResponse(strings.Repeat("large", 1000))); err != nil {
+		t.Error(err)
+	}
+	req := lruTestRequest(t, "/synced")
+	if err := cache.Set(req.WithContext(WithSyncWrite(req.Context())), lruTestResponse("synced")); err != nil {
+		t.Error(err)
+	}
+	if files() != 2 {
+		t.Error("expected the large entry and the synced entry to be written, got", files())
+	}
+
+	if err := cache.Flush(); err != nil {
+		t.Error(err)
+	}
+	if files() != 3 || get("/small") != "second" {
+		t.Error("expected the flush to write the last version of the small entry, got", files(), get("/small"))
+	}
+
+	//the pending bytes reaching MaxPendingBytes flush the batch
+	for i := 0; i < 20; i++ {
+		path := "/entry" + strings.Repeat("x", i)
+		if err := cache.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+			t.Error(err)
+		}
+	}
+	if files() < 4 {
+		t.Error("expected the full batch to be flushed, got", files())
+	}
+	if err := cache.Set(lruTestRequest(t, "/deleted"), lruTestResponse("deleted")); err != nil {
+		t.Error(err)
+	}
+	key, _ := cache.Key(lruTestRequest(t, "/deleted"))
+	if err := cache.DeleteKey(key); err != nil {
+		t.Error(err)
+	}
+	if err := cache.Flush(); err != nil {
+		t.Error(err)
+	}
+	if _, err := cache.GetKey(key); err != NotInCacheError {
+		t.Error("expected the deleted buffered entry not to be written, got", err)
+	}
+	if len(cache.Keys()) != 23 {
+		t.Error("expected all entries to be written, got", len(cache.Keys()))
+	}
+}
+
+func BenchmarkDiskCache_GetKey(b *testing.B) {
+
+	dir := "tmp/disk-benchmark"
+	if err := os.RemoveAll(dir); err != nil {
+		b.Fatal(err)
+	}
+	for _, codec := range []struct {
+		name  string
+		codec Codec
+	}{{"json", JSONCodec}, {"gob", GobCodec}} {
+		cache, err := NewDiskCache(filepath.Join(dir, codec.name), DiskCacheOptions{Codec: codec.codec})
+		if err != nil {
+			b.Fatal(err)
+		}
+		request, err := http.NewRequest("GET", "http://example.com/large", nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		body := strings.Repeat("0123456789abcdef", 1<<16)
+		if err := cache.Set(request, lruTestResponse(body)); err != nil {
+			b.Fatal(err)
+		}
+		key, err := cache.Key(request)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(codec.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				res, err := cache.GetKey(key)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, _ = io.Copy(ioutil.Discard, res.Body)
+			}
+		})
+	}
+}
+
+func TestDiskCache_SyncBatch(t *testing.T) {
+
+	dir := "tmp/disk-sync-batch"
+	if err := os.RemoveAll(dir); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	cache, err := NewDiskCache(dir, DiskCacheOptions{Batch: &DiskBatchOptions{}, Sync: SyncBatch})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i := 0; i < 10; i++ {
+		path := "/entry" + strconv.Itoa(i)
+		if err := cache.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := cache.Flush(); err != nil {
+		t.Error(err)
+	}
+	if keys := cache.Keys(); len(keys) != 10 {
+		t.Error("expected the flush to write all entries, got", len(keys))
+	}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasPrefix(info.Name(), diskTempPrefix) {
+			t.Error("expected no temporary file to remain, got", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	res, err := cache.Get(lruTestRequest(t, "/entry3"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != "/entry3" {
+		t.Error("expected the flushed entry, got", string(body))
+	}
+}
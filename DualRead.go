@@ -0,0 +1,225 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+//DefaultDualReadHeaders are the headers compared by DualReadCache if Headers is nil
+var DefaultDualReadHeaders = []string{"Content-Type", "Content-Encoding", "Cache-Control", "ETag", "Last-Modified", "Vary"}
+
+//DualReadCache verifies a migration from the Old to the New cache: every Get reads both, serves the response of the
+//preferred one and reports if they diverge. Set writes to both so the New cache is filled by the traffic. Once no
+//divergence is reported the New cache can replace the DualReadCache
+type DualReadCache struct {
+	Old Cacher
+	New Cacher
+	DualReadOptions
+
+	mutex sync.Mutex
+	stats DualReadStats
+}
+
+type DualReadOptions struct {
+	//PreferNew serves the responses of the New cache, the ones of the Old cache otherwise
+	PreferNew bool
+	//Headers are the headers whose values must match, DefaultDualReadHeaders if nil. Status code and body are always
+	//compared
+	Headers []string
+	//OnDivergence is called with the reason if the responses of the caches for req differ
+	OnDivergence func(req *http.Request, reason string)
+	//OnWriteError is called if the cache which is not preferred fails to store a response, the error is not
+	//returned by Set
+	OnWriteError func(req *http.Request, err error)
+}
+
+//DualReadStats counts the reads of a DualReadCache
+type DualReadStats struct {
+	Reads       int64
+	Divergences int64
+}
+
+func NewDualReadCache(oldCache Cacher, newCache Cacher, options ...DualReadOptions) *DualReadCache {
+
+	dualReadCache := &DualReadCache{Old: oldCache, New: newCache}
+
+	if options != nil {
+		dualReadCache.DualReadOptions = options[0]
+	}
+
+	return dualReadCache
+}
+
+//Stats returns the number of reads and divergences so far
+func (d *DualReadCache) Stats() DualReadStats {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.stats
+}
+
+//caches returns the preferred cache first
+func (d *DualReadCache) caches() (Cacher, Cacher) {
+	if d.PreferNew {
+		return d.New, d.Old
+	}
+	return d.Old, d.New
+}
+
+func (d *DualReadCache) Get(req *http.Request) (*http.Response, error) {
+
+	preferred, other := d.caches()
+	res, body, err := d.read(preferred, req)
+	otherRes, otherBody, otherErr := d.read(other, req)
+
+	oldRead, newRead := dualRead{res, body, err}, dualRead{otherRes, otherBody, otherErr}
+	if d.PreferNew {
+		oldRead, newRead = newRead, oldRead
+	}
+	reason := d.diverges(oldRead, newRead)
+
+	d.mutex.Lock()
+	d.stats.Reads++
+	if reason != "" {
+		d.stats.Divergences++
+	}
+	d.mutex.Unlock()
+	if reason != "" && d.OnDivergence != nil {
+		d.OnDivergence(req, reason)
+	}
+
+	return res, err
+}
+
+//read returns the response of cache with its buffered body so it can be compared and served
+func (d *DualReadCache) read(cache Cacher, req *http.Request) (*http.Response, []byte, error) {
+	res, err := cache.Get(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := bufferBody(res)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, body, nil
+}
+
+//dualRead is the result of reading one of the caches
+type dualRead struct {
+	res  *http.Response
+	body []byte
+	err  error
+}
+
+//diverges returns why the responses of the Old and the New cache differ or an empty string if they match
+func (d *DualReadCache) diverges(oldRead dualRead, newRead dualRead) string {
+
+	oldMiss, newMiss := errors.Is(oldRead.err, NotInCacheError), errors.Is(newRead.err, NotInCacheError)
+	switch {
+	case oldRead.err != nil && !oldMiss:
+		return fmt.Sprintf("old cache failed: %v", oldRead.err)
+	case newRead.err != nil && !newMiss:
+		return fmt.Sprintf("new cache failed: %v", newRead.err)
+	case oldMiss && newMiss:
+		return ""
+	case newMiss:
+		return "missing in the new cache"
+	case oldMiss:
+		return "missing in the old cache"
+	}
+
+	oldRes, newRes := oldRead.res, newRead.res
+	if oldRes.StatusCode != newRes.StatusCode {
+		return fmt.Sprintf("status code %d != %d", oldRes.StatusCode, newRes.StatusCode)
+	}
+	headers := d.Headers
+	if headers == nil {
+		headers = DefaultDualReadHeaders
+	}
+	for _, name := range headers {
+		if oldValue, newValue := oldRes.Header.Get(name), newRes.Header.Get(name); oldValue != newValue {
+			return fmt.Sprintf("header %s %q != %q", name, oldValue, newValue)
+		}
+	}
+	if !bytes.Equal(oldRead.body, newRead.body) {
+		return "body differs"
+	}
+	return ""
+}
+
+//Set stores res in both caches, only the error of the preferred cache is returned
+func (d *DualReadCache) Set(req *http.Request, res *http.Response) error {
+
+	preferred, other := d.caches()
+	copied, err := CopyResponse(res)
+	if err != nil {
+		return err
+	}
+	copied.Header = res.Header.Clone()
+	err = preferred.Set(req, res)
+	if err != nil {
+		return err
+	}
+	if err := other.Set(req, copied); err != nil && d.OnWriteError != nil {
+		d.OnWriteError(req, err)
+	}
+	return nil
+}
+
+//Key returns the key of the preferred cache if it implements Keyer
+func (d *DualReadCache) Key(req *http.Request) (string, error) {
+	preferred, _ := d.caches()
+	if keyer, ok := preferred.(Keyer); ok {
+		return keyer.Key(req)
+	}
+	return MapCacheOptions{}.key(req)
+}
+
+//Capabilities returns the capabilities both caches have
+func (d *DualReadCache) Capabilities() CapabilitySet {
+	return commonCapabilities(d.Old, d.New) &^ ByteAccountingCapability
+}
+
+//Keys returns the keys of the preferred cache
+func (d *DualReadCache) Keys() []string {
+	preferred, _ := d.caches()
+	if inspector, ok := preferred.(Inspector); ok {
+		return inspector.Keys()
+	}
+	return nil
+}
+
+//GetKey returns the response stored under key in the preferred cache
+func (d *DualReadCache) GetKey(key string) (*http.Response, error) {
+	preferred, _ := d.caches()
+	if inspector, ok := preferred.(Inspector); ok {
+		return inspector.GetKey(key)
+	}
+	return nil, NotInCacheError
+}
+
+//DeleteKey deletes key from both caches, NotInCacheError is only returned if neither had it
+func (d *DualReadCache) DeleteKey(key string) error {
+
+	deleted := false
+	for _, cache := range []Cacher{d.Old, d.New} {
+		inspector, ok := cache.(Inspector)
+		if !ok {
+			continue
+		}
+		err := inspector.DeleteKey(key)
+		if errors.Is(err, NotInCacheError) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		deleted = true
+	}
+	if !deleted {
+		return NotInCacheError
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestDualReadCache(t *testing.T) {
+
+	oldCache, newCache := NewMapCache(), NewMapCache()
+	var reasons []string
+	cache := NewDualReadCache(oldCache, newCache, DualReadOptions{OnDivergence: func(req *http.Request, reason string) {
+		reasons = append(reasons, req.URL.Path+": "+reason)
+	}})
+
+	if err := cache.Set(lruTestRequest(t, "/same"), lruTestResponse("same")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(oldCache.Keys()) != 1 || len(newCache.Keys()) != 1 {
+		t.Error("expected the response to be written to both caches got", len(oldCache.Keys()), len(newCache.Keys()))
+	}
+	_ = oldCache.Set(lruTestRequest(t, "/missing"), lruTestResponse("old"))
+	_ = oldCache.Set(lruTestRequest(t, "/body"), lruTestResponse("old"))
+	_ = newCache.Set(lruTestRequest(t, "/body"), lruTestResponse("new"))
+	header := lruTestResponse("same")
+	header.Header.Set("Content-Type", "text/plain")
+	_ = oldCache.Set(lruTestRequest(t, "/header"), lruTestResponse("same"))
+	_ = newCache.Set(lruTestRequest(t, "/header"), header)
+
+	tests := []struct {
+		path   string
+		body   string
+		reason string
+	}{
+		{"/same", "same", ""},
+		{"/missing", "old", "/missing: missing in the new cache"},
+		{"/body", "old", "/body: body differs"},
+		{"/header", "same", `/header: header Content-Type "" != "text/plain"`},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			reasons = nil
+			res, err := cache.Get(lruTestRequest(t, test.path))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if body, _ := ioutil.ReadAll(res.Body); string(body) != test.body {
+				t.Error("expected the response of the old cache got", string(body))
+			}
+			if test.reason == "" && len(reasons) != 0 || test.reason != "" && (len(reasons) != 1 || reasons[0] != test.reason) {
+				t.Error("expected the divergence", test.reason, "got", reasons)
+			}
+		})
+	}
+	if stats := cache.Stats(); stats.Reads != 4 || stats.Divergences != 3 {
+		t.Error("expected 4 reads and 3 divergences got", stats)
+	}
+
+	cache.PreferNew = true
+	if _, err := cache.Get(lruTestRequest(t, "/missing")); !errors.Is(err, NotInCacheError) {
+		t.Error("expected the miss of the new cache got", err)
+	}
+	if res, _ := cache.Get(lruTestRequest(t, "/body")); res != nil {
+		if body, _ := ioutil.ReadAll(res.Body); string(body) != "new" {
+			t.Error("expected the response of the new cache got", string(body))
+		}
+	}
+	keys := oldCache.Keys()
+	if err := cache.DeleteKey(keys[0]); err != nil {
+		t.Error(err)
+	}
+	if len(oldCache.Keys()) != 3 {
+		t.Error("expected the key to be deleted from the old cache got", len(oldCache.Keys()))
+	}
+}
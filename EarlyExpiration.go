@@ -0,0 +1,114 @@
+package CachedHttpClient
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//DefaultEarlyExpirationDelta is the Delta of an EarlyExpiration without one
+const DefaultEarlyExpirationDelta = 100 * time.Millisecond
+
+//EarlyExpiration prevents stampedes at the expiry of hot entries with probabilistic early recomputation (XFetch,
+//Vattani et al. "Optimal Probabilistic Cache Stampede Prevention"). A fresh hit refreshes the response in the
+//background if age - Delta * Beta * ln(rand) >= lifetime, where Delta is the time the origin took to answer. The
+//probability grows as the response approaches its expiry, so a small and increasing share of hits refreshes it while
+//the others keep serving it and the entry is replaced before all requests miss at once. Only one refresh per key runs
+//at a time
+type EarlyExpiration struct {
+	//Beta scales Delta, values above 1 refresh earlier, 1 if 0
+	Beta float64
+	//Delta is the minimum recomputation time, it is used for responses stored without the times their request was
+	//sent and their response received and for origins answering faster. DefaultEarlyExpirationDelta if 0
+	Delta time.Duration
+	//Random returns numbers in (0, 1], a uniform random number if nil
+	Random func() float64
+
+	mutex sync.Mutex
+	//pending holds the keys refreshed in the background
+	pending map[string]bool
+}
+
+//NewEarlyExpiration creates an EarlyExpiration for CachedTransport.EarlyExpiration refreshing with beta
+func NewEarlyExpiration(beta float64) *EarlyExpiration {
+	return &EarlyExpiration{Beta: beta}
+}
+
+//hit refreshes the fresh response res in the background if it expires early
+func (e *EarlyExpiration) hit(c *CachedTransport, req *http.Request, keyReq *http.Request, res *http.Response, now time.Time) {
+
+	if e == nil || c.Offline {
+		return
+	}
+	lifetime := policy.FreshnessLifetime(res, c.Shared)
+	if lifetime <= 0 {
+		return
+	}
+	if !e.expires(policy.CurrentAge(res, now), lifetime, e.delta(res)) {
+		return
+	}
+
+	key := refreshKey(c.Cache, keyReq)
+	e.mutex.Lock()
+	if e.pending[key] {
+		e.mutex.Unlock()
+		return
+	}
+	if e.pending == nil {
+		e.pending = map[string]bool{}
+	}
+	e.pending[key] = true
+	e.mutex.Unlock()
+
+	background, err := CopyResponse(res)
+	if err != nil {
+		e.done(key)
+		return
+	}
+	go func() {
+		defer e.done(key)
+		c.refresh(req, keyReq, background)
+	}()
+}
+
+func (e *EarlyExpiration) done(key string) {
+	e.mutex.Lock()
+	delete(e.pending, key)
+	e.mutex.Unlock()
+}
+
+//expires reports if a response of age expires early, its lifetime is shortened by delta * beta * -ln(rand)
+func (e *EarlyExpiration) expires(age time.Duration, lifetime time.Duration, delta time.Duration) bool {
+
+	beta := e.Beta
+	if beta <= 0 {
+		beta = 1
+	}
+	random := e.Random
+	if random == nil {
+		random = func() float64 {
+			//rand.Float64 may return 0 whose logarithm would always expire
+			return 1 - rand.Float64()
+		}
+	}
+	return float64(age)-float64(delta)*beta*math.Log(random()) >= float64(lifetime)
+}
+
+//delta returns the time the origin took to answer res, at least Delta
+func (e *EarlyExpiration) delta(res *http.Response) time.Duration {
+
+	delta := e.Delta
+	if delta <= 0 {
+		delta = DefaultEarlyExpirationDelta
+	}
+	requested, requestErr := time.Parse(time.RFC3339Nano, res.Header.Get(policy.RequestTimeHeader))
+	responded, responseErr := time.Parse(time.RFC3339Nano, res.Header.Get(policy.ResponseTimeHeader))
+	if requestErr == nil && responseErr == nil && responded.Sub(requested) > delta {
+		return responded.Sub(requested)
+	}
+	return delta
+}
@@ -0,0 +1,89 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEarlyExpiration_Expires(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		age      time.Duration
+		random   float64
+		expected bool
+	}{
+		{"expired", 10 * time.Second, 1, true},
+		{"far from expiry", time.Second, 0.01, false},
+		{"close to expiry and unlucky", 9 * time.Second, 0.9, false},
+		{"close to expiry and lucky", 9 * time.Second, 0.1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			early := &EarlyExpiration{Random: func() float64 { return tt.random }}
+			if expires := early.expires(tt.age, 10*time.Second, time.Second); expires != tt.expected {
+				t.Error("expected", tt.expected, "got", expires)
+			}
+		})
+	}
+
+	//the share of refreshing hits grows towards the expiry
+	early := &EarlyExpiration{}
+	share := func(age time.Duration) int {
+		refreshes := 0
+		for i := 0; i < 10000; i++ {
+			if early.expires(age, 10*time.Second, time.Second) {
+				refreshes++
+			}
+		}
+		return refreshes
+	}
+	if far, near := share(5*time.Second), share(9*time.Second); far > 100 || near < 3000 || near > 4500 {
+		t.Error("unexpected shares of refreshes", far, near)
+	}
+}
+
+func TestCachedTransport_RoundTrip_EarlyExpiration(t *testing.T) {
+
+	var requests int32
+	refreshed := make(chan struct{}, 10)
+	random := 1.0
+	transport := &CachedTransport{
+		Cache: NewMapCache(),
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&requests, 1) > 1 {
+				refreshed <- struct{}{}
+			}
+			res := lruTestResponse("content")
+			res.Header.Set("Cache-Control", "max-age=60")
+			return res, nil
+		}),
+		EarlyExpiration: &EarlyExpiration{Delta: time.Second, Random: func() float64 { return random }},
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := transport.RoundTrip(lruTestRequest(t, "/")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatal("expected the fresh response to be served, got", n, "origin requests")
+	}
+
+	//-ln(1e-30) is about 69s, more than the lifetime
+	random = 1e-30
+	res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Header.Get("Warning") != "" {
+		t.Error("the cached response is served as fresh", res.Header)
+	}
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the response was not refreshed")
+	}
+}
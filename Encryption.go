@@ -0,0 +1,167 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+//UnknownEncryptionKeyError is returned for entries encrypted with a key which is not in EncryptionKeys
+var UnknownEncryptionKeyError = errors.New("unknown encryption key")
+
+//DecryptionError is returned for entries which can not be decrypted, e.g. because they were modified
+var DecryptionError = errors.New("cache entry decryption failed")
+
+//EncryptionKeys are the AES keys of EncryptedCodec by their ID. Entries are encrypted with the key CurrentID and can
+//be read with any of the keys, to rotate keys add the new key as CurrentID, keep the old key until the entries
+//encrypted with it are rewritten, e.g. with FileCache.Compact, and remove it afterwards
+type EncryptionKeys struct {
+	CurrentID string
+	//Keys are 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256
+	Keys map[string][]byte
+}
+
+//encryptedCodec encrypts the entries of codec with AES-GCM, every entry is stored as
+//
+//	uvarint(len(keyID)) keyID uvarint(len(sealed)) sealed
+//
+//where sealed is the random nonce followed by the ciphertext, the key ID is authenticated as additional data
+type encryptedCodec struct {
+	codec     Codec
+	currentID string
+	aeads     map[string]cipher.AEAD
+}
+
+//NewEncryptedCodec returns a Codec encrypting the entries encoded by codec with AES-GCM, e.g. for
+//FileCacheOptions.Codec. Body files are not encrypted, so a FileCache with it ignores BodyThreshold and
+//CachedTransport.Validate reports the combination
+func NewEncryptedCodec(codec Codec, keys EncryptionKeys) (Codec, error) {
+
+	if len(keys.Keys) == 0 {
+		return nil, &ConfigurationError{Option: "EncryptionKeys.Keys", Problem: "no key is set",
+			Fix: "add a 16, 24 or 32 byte key under CurrentID", Err: UnknownEncryptionKeyError}
+	}
+	if _, ok := keys.Keys[keys.CurrentID]; !ok {
+		return nil, &ConfigurationError{Option: "EncryptionKeys.CurrentID", Problem: "it names no key of Keys",
+			Fix: "set it to the ID of the key encrypting new entries", Err: fmt.Errorf("%w %q", UnknownEncryptionKeyError, keys.CurrentID)}
+	}
+
+	aeads := map[string]cipher.AEAD{}
+	for id, key := range keys.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, &ConfigurationError{Option: fmt.Sprintf("EncryptionKeys.Keys[%q]", id),
+				Problem: fmt.Sprintf("the key is %d bytes long", len(key)), Fix: "use 16, 24 or 32 bytes", Err: err}
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		aeads[id] = aead
+	}
+
+	return &encryptedCodec{codec: codec, currentID: keys.CurrentID, aeads: aeads}, nil
+}
+
+func (e *encryptedCodec) Encode(w io.Writer, entry *FileCacheEntry) error {
+
+	var plaintext bytes.Buffer
+	err := e.codec.Encode(&plaintext, entry)
+	if err != nil {
+		return err
+	}
+
+	aead := e.aeads[e.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext.Bytes(), []byte(e.currentID))
+
+	frame := make([]byte, 0, 2*binary.MaxVarintLen64+len(e.currentID)+len(sealed))
+	frame = appendUvarint(frame, uint64(len(e.currentID)))
+	frame = append(frame, e.currentID...)
+	frame = appendUvarint(frame, uint64(len(sealed)))
+	frame = append(frame, sealed...)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	varint := make([]byte, binary.MaxVarintLen64)
+	return append(buf, varint[:binary.PutUvarint(varint, x)]...)
+}
+
+func (e *encryptedCodec) NewDecoder(r io.Reader) EntryDecoder {
+	return &encryptedDecoder{codec: e, reader: bufio.NewReader(r)}
+}
+
+type encryptedDecoder struct {
+	codec  *encryptedCodec
+	reader *bufio.Reader
+}
+
+func (d *encryptedDecoder) Decode(entry *FileCacheEntry) error {
+
+	keyID, err := d.readField()
+	if err != nil {
+		return err
+	}
+	sealed, err := d.readField()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+
+	aead, ok := d.codec.aeads[string(keyID)]
+	if !ok {
+		return fmt.Errorf("%w %q", UnknownEncryptionKeyError, keyID)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return DecryptionError
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], keyID)
+	if err != nil {
+		return DecryptionError
+	}
+
+	return d.codec.codec.NewDecoder(bytes.NewReader(plaintext)).Decode(entry)
+}
+
+//readField reads a length prefixed field of a frame. The field is read in chunks so a corrupted length does not
+//allocate more than the remaining input
+func (d *encryptedDecoder) readField() ([]byte, error) {
+
+	length, err := binary.ReadUvarint(d.reader)
+	if err != nil {
+		return nil, err
+	}
+	var field bytes.Buffer
+	copied, err := io.CopyN(&field, d.reader, int64(length&math.MaxInt64))
+	if uint64(copied) < length && (err == nil || err == io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return field.Bytes(), err
+}
+
+//encrypted reports if the entries of the cache file are encrypted by NewEncryptedCodec, also within
+//NewVerboseCertificatesCodec
+func (f *FileCache) encrypted() bool {
+	codec := f.codec()
+	if verbose, ok := codec.(*verboseCertificatesCodec); ok {
+		codec = verbose.codec
+	}
+	_, ok := codec.(*encryptedCodec)
+	return ok
+}
@@ -0,0 +1,190 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestEncryptedCodec(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		_, _ = writer.Write([]byte("secret body " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	key1 := bytes.Repeat([]byte{1}, 32)
+	key2 := bytes.Repeat([]byte{2}, 16)
+	filePath := "tmp/encrypted.cache"
+
+	codec := func(keys EncryptionKeys) FileCacheOptions {
+		codec, err := NewEncryptedCodec(GobCodec, keys)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return FileCacheOptions{Codec: codec}
+	}
+	get := func(fileCache *FileCache, path string) {
+		client := http.Client{Transport: &CachedTransport{Cache: fileCache, Fallback: http.DefaultTransport}}
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_ = res.Body.Close()
+	}
+	reopen := func(options FileCacheOptions) (*FileCache, error) {
+		return OpenFileCache(filePath, options)
+	}
+
+	old := codec(EncryptionKeys{CurrentID: "1", Keys: map[string][]byte{"1": key1}})
+	fileCache, err := NewFileCache(filePath, old)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	get(fileCache, "/old")
+
+	written, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if bytes.Contains(written, []byte("secret body")) || bytes.Contains(written, []byte("/old")) {
+		t.Error("expected the cache file to be encrypted")
+	}
+
+	rotated := codec(EncryptionKeys{CurrentID: "2", Keys: map[string][]byte{"1": key1, "2": key2}})
+	fileCache, err = reopen(rotated)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	get(fileCache, "/new")
+
+	retired := codec(EncryptionKeys{CurrentID: "2", Keys: map[string][]byte{"2": key2}})
+	if _, err := reopen(retired); !errors.Is(err, UnknownEncryptionKeyError) {
+		t.Error("expected UnknownEncryptionKeyError before compacting, got", err)
+	}
+
+	if err := fileCache.Compact(); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	fileCache, err = reopen(retired)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, key := range fileCache.Keys() {
+		res, err := fileCache.GetKey(key)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		if !bytes.HasPrefix(body, []byte("secret body /")) {
+			t.Error("unexpected body", string(body))
+		}
+	}
+	if len(fileCache.Keys()) != 2 {
+		t.Error("expected 2 entries after compacting, got", len(fileCache.Keys()))
+	}
+
+	written, err = ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	written[len(written)-1] ^= 0xff
+	if err := ioutil.WriteFile(filePath, written, 0644); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := reopen(retired); !errors.Is(err, DecryptionError) {
+		t.Error("expected DecryptionError for a modified entry, got", err)
+	}
+
+	if _, err := NewEncryptedCodec(JSONCodec, EncryptionKeys{CurrentID: "1", Keys: map[string][]byte{"1": []byte("short")}}); err == nil {
+		t.Error("expected an error for an invalid key size")
+	}
+	if _, err := NewEncryptedCodec(JSONCodec, EncryptionKeys{CurrentID: "missing"}); !errors.Is(err, UnknownEncryptionKeyError) {
+		t.Error("expected UnknownEncryptionKeyError for a missing current key, got", err)
+	}
+}
+
+//encryptedFileCache creates an encrypted FileCache with BodyThreshold threshold
+func encryptedFileCache(t *testing.T, threshold int64) *FileCache {
+
+	codec, err := NewEncryptedCodec(GobCodec, EncryptionKeys{CurrentID: "1", Keys: map[string][]byte{"1": bytes.Repeat([]byte{1}, 32)}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	filePath := "tmp/encrypted-bodies.cache"
+	if err := os.RemoveAll(bodyDir(filePath)); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	fileCache, err := NewFileCache(filePath, FileCacheOptions{Codec: codec})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	fileCache.BodyThreshold = threshold
+	return fileCache
+}
+
+func TestEncryptedCodec_BodyThreshold(t *testing.T) {
+
+	fileCache := encryptedFileCache(t, 4)
+	req := lruTestRequest(t, "/large")
+	if err := fileCache.Set(req, lruTestResponse("secret large body")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := os.Stat(bodyDir(fileCache.filePath)); !os.IsNotExist(err) {
+		t.Error("expected no body file to be written, got", err)
+	}
+	written, err := ioutil.ReadFile(fileCache.filePath)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if bytes.Contains(written, []byte("secret large body")) {
+		t.Error("expected the body to be encrypted in the cache file")
+	}
+	res, err := fileCache.Get(req)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != "secret large body" {
+		t.Error("unexpected body", string(body))
+	}
+}
+
+func TestEncryptedCodec_CorruptedLength(t *testing.T) {
+
+	codec, err := NewEncryptedCodec(GobCodec, EncryptionKeys{CurrentID: "1", Keys: map[string][]byte{"1": bytes.Repeat([]byte{1}, 16)}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, frame := range [][]byte{
+		appendUvarint(nil, math.MaxUint64),
+		append(appendUvarint([]byte{1, '1'}, 1<<40), 0, 1, 2),
+	} {
+		var entry FileCacheEntry
+		if err := codec.NewDecoder(bytes.NewReader(frame)).Decode(&entry); err != io.ErrUnexpectedEOF {
+			t.Error("expected io.ErrUnexpectedEOF for a length beyond the input, got", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+var InspectionNotSupportedError = errors.New("the cache does not support inspecting entries")
+
+//Peeker is implemented by caches reading a stored response without its body and without counting it as a use, e.g.
+//without moving it in the LRU order. MapCache, LRUCache, ShardedCache and FileCache implement it
+type Peeker interface {
+	//PeekKey returns the response stored under key with an empty body, the size of its body, -1 if unknown, and the
+	//hits on the entry since it was stored
+	PeekKey(key string) (res *http.Response, size int64, hits int64, err error)
+}
+
+//EntryMetadata is the metadata of a stored response, see CachedTransport.Peek
+type EntryMetadata struct {
+	Key        string
+	StatusCode int
+	Header     http.Header
+	//StoredAt is when the response was received, zero for entries stored without the time
+	StoredAt time.Time
+	//ExpiresAt is when the response stops being fresh
+	ExpiresAt time.Time
+	//Hits are the uses of the entry since it was stored, caches which are no Peeker do not count them
+	Hits int64
+	//Size is the size of the body in bytes, -1 if unknown
+	Size int64
+	//Vary are the request header fields named in the Vary header with the values of the request the response was
+	//stored for
+	Vary http.Header
+	//OriginDuration is the time the origin took to answer, 0 for entries stored without the time
+	OriginDuration time.Duration
+}
+
+//Peek returns the metadata of the entry stored under key without reading its body. With a Peeker cache it is not
+//counted as a use, other caches have to implement Inspector. It fails with NotInCacheError if there is no entry
+func (c *CachedTransport) Peek(key string) (*EntryMetadata, error) {
+
+	var res *http.Response
+	size, hits := int64(-1), int64(0)
+	var err error
+	switch cache := c.Cache.(type) {
+	case Peeker:
+		res, size, hits, err = cache.PeekKey(key)
+	case Inspector:
+		res, err = cache.GetKey(key)
+		if err == nil {
+			if res.ContentLength >= 0 {
+				size = res.ContentLength
+			}
+			if res.Body != nil {
+				_ = res.Body.Close()
+			}
+		}
+	default:
+		return nil, InspectionNotSupportedError
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newEntryMetadata(key, res, size, hits, c.Shared, c.now()), nil
+}
+
+//newEntryMetadata describes the response res stored under key at now
+func newEntryMetadata(key string, res *http.Response, size int64, hits int64, shared bool, now time.Time) *EntryMetadata {
+
+	entry := &EntryMetadata{
+		Key:        key,
+		StatusCode: res.StatusCode,
+		Header:     res.Header.Clone(),
+		Hits:       hits,
+		Size:       size,
+		Vary:       varyHeaders(res),
+	}
+
+	requested, requestErr := time.Parse(time.RFC3339Nano, res.Header.Get(policy.RequestTimeHeader))
+	responded, responseErr := time.Parse(time.RFC3339Nano, res.Header.Get(policy.ResponseTimeHeader))
+	if responseErr == nil {
+		entry.StoredAt = responded
+		if requestErr == nil && responded.After(requested) {
+			entry.OriginDuration = responded.Sub(requested)
+		}
+	}
+
+	entry.ExpiresAt = now.Add(policy.FreshnessLifetime(res, shared) - policy.CurrentAge(res, now))
+	return entry
+}
+
+//peekedResponse returns a copy of the stored response res without its body
+func peekedResponse(res *http.Response) *http.Response {
+	peeked := *res
+	peeked.Header = res.Header.Clone()
+	peeked.Body = http.NoBody
+	return &peeked
+}
@@ -0,0 +1,122 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_Peek(t *testing.T) {
+
+	disk, err := NewDiskCache("tmp/peek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caches := []struct {
+		name  string
+		cache Cacher
+		//hits is false for the caches not counting hits
+		hits bool
+	}{
+		{"map", NewMapCache(), true},
+		{"lru", NewLRUCache(LRUCacheOptions{}), true},
+		{"sharded", NewShardedCache(ShardedCacheOptions{}), true},
+		{"disk", disk, false},
+	}
+	for _, tt := range caches {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &CachedTransport{Cache: tt.cache, Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				res := lruTestResponse("content")
+				res.Header.Set("Cache-Control", "max-age=60")
+				res.Header.Set("Vary", "Accept-Language")
+				res.ContentLength = int64(len("content"))
+				res.Request = req
+				return res, nil
+			})}
+			request := lruTestRequest(t, "/peek")
+			request.Header.Set("Accept-Language", "de")
+			key, err := transport.requestKey(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := transport.Peek(key); !errors.Is(err, NotInCacheError) {
+				t.Fatal("expected NotInCacheError before the request, got", err)
+			}
+
+			before := time.Now()
+			for i := 0; i < 3; i++ {
+				res, err := transport.RoundTrip(request)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = res.Body.Close()
+			}
+
+			entry, err := transport.Peek(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if entry.StatusCode != http.StatusOK || entry.Size != int64(len("content")) {
+				t.Error("wrong entry", entry.StatusCode, entry.Size)
+			}
+			if tt.hits && entry.Hits != 2 {
+				t.Error("expected 2 hits, got", entry.Hits)
+			}
+			if entry.StoredAt.Before(before.Add(-time.Second)) || entry.OriginDuration < 0 {
+				t.Error("wrong stored at", entry.StoredAt, entry.OriginDuration)
+			}
+			if expires := entry.StoredAt.Add(time.Minute); entry.ExpiresAt.Sub(expires) > time.Second || expires.Sub(entry.ExpiresAt) > time.Second {
+				t.Error("expected the entry to expire a minute after it was stored, got", entry.ExpiresAt)
+			}
+			if entry.Vary.Get("Accept-Language") != "de" {
+				t.Error("wrong vary", entry.Vary)
+			}
+
+			if again, err := transport.Peek(key); err != nil || again.Hits != entry.Hits {
+				t.Error("peeking counted a hit", again, err)
+			}
+		})
+	}
+	_ = os.RemoveAll("tmp/peek")
+}
+
+func TestLRUCache_PeekKey(t *testing.T) {
+
+	cache := NewLRUCache(LRUCacheOptions{MaxEntries: 2})
+	for _, path := range []string{"/a", "/b"} {
+		if err := cache.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key, err := cache.Key(lruTestRequest(t, "/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, size, hits, err := cache.PeekKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Body != http.NoBody || size != 2 || hits != 0 {
+		t.Error("wrong peeked entry", res.Body, size, hits)
+	}
+
+	//peeking did not use /a, it is still the least recently used
+	if err := cache.Set(lruTestRequest(t, "/c"), lruTestResponse("/c")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get(lruTestRequest(t, "/a")); !errors.Is(err, NotInCacheError) {
+		t.Error("expected /a to be evicted, got", err)
+	}
+	if _, err := cache.Get(lruTestRequest(t, "/b")); err != nil {
+		t.Error("expected /b to be kept, got", err)
+	}
+}
+
+func TestCachedTransport_Peek_NotSupported(t *testing.T) {
+	transport := &CachedTransport{Cache: failingCache{}}
+	if _, err := transport.Peek("key"); !errors.Is(err, InspectionNotSupportedError) {
+		t.Error("expected InspectionNotSupportedError, got", err)
+	}
+}
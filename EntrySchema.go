@@ -0,0 +1,80 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"fmt"
+)
+
+//EntrySchemaVersion is the version of the schema of the FileCacheEntry written by this version of the package.
+//Entries without a version were written before versions were introduced and have version 1
+const EntrySchemaVersion = 2
+
+//UnsupportedSchemaError is matched by the errors of reading entries written with a newer schema, e.g. by a newer
+//version of the package sharing the store. They are DecodeError as well
+var UnsupportedSchemaError = errors.New("the cache entry was written with a newer schema")
+
+//InvalidEntryError is matched by the errors of reading entries which were decoded but describe no valid response,
+//e.g. corrupted entries. They are DecodeError as well
+var InvalidEntryError = errors.New("the cache entry is invalid")
+
+//entryMigrations upgrade an entry of the version of their index plus 1 to the next version
+var entryMigrations = []func(entry *FileCacheEntry) error{
+	//version 2 added the Version
+	func(entry *FileCacheEntry) error { return nil },
+}
+
+//decodeEntry decodes the next entry of decoder, migrates it to EntrySchemaVersion and validates it. Entries which can
+//not be used are DecodeError, a panic of the decoder is recovered as one. io.EOF is returned after the last entry
+func decodeEntry(decoder EntryDecoder, entry *FileCacheEntry) (err error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = wrapDecodeError(fmt.Errorf("decoder panicked: %v", recovered))
+		}
+	}()
+	if err := decoder.Decode(entry); err != nil {
+		return wrapDecodeError(err)
+	}
+	if err := migrateEntry(entry); err != nil {
+		return wrapDecodeError(err)
+	}
+	return wrapDecodeError(validateEntry(entry))
+}
+
+//migrateEntry upgrades entry to EntrySchemaVersion
+func migrateEntry(entry *FileCacheEntry) error {
+
+	version := entry.Version
+	if version == 0 {
+		version = 1
+	}
+	if version < 0 || version > EntrySchemaVersion {
+		return fmt.Errorf("schema version %d: %w", entry.Version, UnsupportedSchemaError)
+	}
+	for ; version < EntrySchemaVersion; version++ {
+		if err := entryMigrations[version-1](entry); err != nil {
+			return err
+		}
+	}
+	entry.Version = EntrySchemaVersion
+	return nil
+}
+
+//validateEntry checks the fields of entry a response can not be served with
+func validateEntry(entry *FileCacheEntry) error {
+
+	response := entry.Response
+	if response == nil {
+		//the entry deletes its key
+		return nil
+	}
+	switch {
+	case response.StatusCode < 100 || response.StatusCode > 999:
+		return fmt.Errorf("status code %d: %w", response.StatusCode, InvalidEntryError)
+	case response.ProtoMajor < 0 || response.ProtoMinor < 0:
+		return fmt.Errorf("protocol version %d.%d: %w", response.ProtoMajor, response.ProtoMinor, InvalidEntryError)
+	case response.ContentLength < -1:
+		return fmt.Errorf("content length %d: %w", response.ContentLength, InvalidEntryError)
+	}
+	return nil
+}
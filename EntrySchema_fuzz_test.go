@@ -0,0 +1,52 @@
+//go:build go1.18
+// +build go1.18
+
+package CachedHttpClient
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+//FuzzDecodeEntry feeds arbitrary bytes to the decoders of the codecs, every entry has to be rejected as DecodeError
+//or decode to a valid response. Run it with go test -fuzz FuzzDecodeEntry
+func FuzzDecodeEntry(f *testing.F) {
+
+	codecs := []Codec{JSONCodec, GobCodec, WireCodec}
+	entry := &FileCacheEntry{Version: EntrySchemaVersion, Request: "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		Response: &JsonResponse{StatusCode: 200, ProtoMajor: 1, ProtoMinor: 1, Header: http.Header{"Cache-Control": {"max-age=60"}},
+			Body: []byte("content"), ContentLength: 7, Request: &JsonRequest{Method: "GET", URL: "http://example.com/"}}}
+	for _, codec := range codecs {
+		var buf bytes.Buffer
+		if err := codec.Encode(&buf, entry); err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf.Bytes())
+		f.Add(buf.Bytes()[:buf.Len()/2])
+	}
+	f.Add([]byte(`{"Version":99,"Request":"key","Response":{"StatusCode":200}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, codec := range codecs {
+			decoder := codec.NewDecoder(bytes.NewReader(data))
+			//a few entries are enough, a corrupted stream may not advance
+			for i := 0; i < 8; i++ {
+				var entry FileCacheEntry
+				if err := decodeEntry(decoder, &entry); err != nil {
+					break
+				}
+				if entry.Version != EntrySchemaVersion {
+					t.Fatalf("entry of version %d was not migrated", entry.Version)
+				}
+				if entry.Response == nil {
+					continue
+				}
+				res, err := entry.Response.Parse()
+				if err == nil && (res.StatusCode < 100 || res.StatusCode > 999) {
+					t.Fatalf("invalid status code %d", res.StatusCode)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeEntry(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		line     string
+		expected error
+	}{
+		{"legacy", `{"Request":"key","Response":{"StatusCode":200,"ContentLength":-1}}`, nil},
+		{"current", `{"Version":2,"Request":"key","Response":{"StatusCode":200}}`, nil},
+		{"deletion", `{"Request":"key"}`, nil},
+		{"newer", `{"Version":3,"Request":"key","Response":{"StatusCode":200}}`, UnsupportedSchemaError},
+		{"negative version", `{"Version":-1,"Request":"key"}`, UnsupportedSchemaError},
+		{"status", `{"Request":"key","Response":{"StatusCode":0}}`, InvalidEntryError},
+		{"protocol", `{"Request":"key","Response":{"StatusCode":200,"ProtoMajor":-1}}`, InvalidEntryError},
+		{"content length", `{"Request":"key","Response":{"StatusCode":200,"ContentLength":-2}}`, InvalidEntryError},
+		{"truncated", `{"Request":"key","Response":{"Stat`, DecodeError},
+		{"wrong type", `{"Request":"key","Response":{"StatusCode":"200"}}`, DecodeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entry FileCacheEntry
+			err := decodeEntry(JSONCodec.NewDecoder(strings.NewReader(tt.line)), &entry)
+			if tt.expected == nil {
+				if err != nil || entry.Version != EntrySchemaVersion || entry.Request != "key" {
+					t.Errorf("expected the entry migrated to version %d, got %+v %v", EntrySchemaVersion, entry, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expected) || !errors.Is(err, DecodeError) {
+				t.Errorf("expected %v as DecodeError, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	var entry FileCacheEntry
+	if err := decodeEntry(JSONCodec.NewDecoder(strings.NewReader("")), &entry); err != io.EOF {
+		t.Error("expected io.EOF after the last entry, got", err)
+	}
+	if err := decodeEntry(panickingDecoder{}, &entry); !errors.Is(err, DecodeError) {
+		t.Error("expected the panic as DecodeError, got", err)
+	}
+}
+
+//panickingDecoder fails like a decoder with a bug hit by a corrupted entry
+type panickingDecoder struct{}
+
+func (panickingDecoder) Decode(entry *FileCacheEntry) error {
+	panic("index out of range")
+}
+
+func TestEntrySchemaVersion_Codecs(t *testing.T) {
+
+	for name, codec := range map[string]Codec{"json": JSONCodec, "gob": GobCodec, "wire": WireCodec} {
+		var buf bytes.Buffer
+		err := codec.Encode(&buf, &FileCacheEntry{Version: EntrySchemaVersion, Request: "key", Response: &JsonResponse{StatusCode: 200, Header: http.Header{}}})
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		var entry FileCacheEntry
+		if err := codec.NewDecoder(&buf).Decode(&entry); err != nil || entry.Version != EntrySchemaVersion {
+			t.Errorf("%s: expected the version to be kept, got %d %v", name, entry.Version, err)
+		}
+	}
+}
+
+func TestCachedTransport_NewerSchema(t *testing.T) {
+
+	dir := "tmp/disk-schema"
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := 0
+	transport := &CachedTransport{Cache: cache, Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		origin++
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=60")
+		return res, nil
+	})}
+	get := func() {
+		res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := ioutil.ReadAll(res.Body); string(body) != "content" {
+			t.Error("wrong body", string(body))
+		}
+	}
+
+	get()
+	key, _ := cache.Key(lruTestRequest(t, "/"))
+	content, err := ioutil.ReadFile(cache.path(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte(`"Version":2`)) {
+		t.Fatalf("expected the schema version in the entry, got %s", content)
+	}
+	//a newer version of the package replaced the entry
+	content = bytes.Replace(content, []byte(`"Version":2`), []byte(`"Version":9`), 1)
+	if err := ioutil.WriteFile(cache.path(key), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetKey(key); !errors.Is(err, UnsupportedSchemaError) {
+		t.Error("expected UnsupportedSchemaError, got", err)
+	}
+	get()
+	get()
+	if origin != 2 {
+		t.Error("expected the entry to be replaced once, got", origin, "origin requests")
+	}
+}
@@ -0,0 +1,70 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+//EntryClassHeader is set on the entries stored by ErrorCapture to tell them apart from cached responses
+const EntryClassHeader = "X-Cache-Entry-Class"
+
+//ErrorEntryClass is the EntryClassHeader value of captured non-2xx responses
+const ErrorEntryClass = "error"
+
+//BodyTruncatedHeader is set to "true" on captured entries whose body was truncated
+const BodyTruncatedHeader = "X-Cache-Body-Truncated"
+
+//ErrorCapture stores the non-2xx responses of the origin with their body truncated to MaxBodySize, so the error
+//pages an origin returned can be inspected without storing them completely. The captured entries are kept apart
+//from the cached responses and are never served
+type ErrorCapture struct {
+	//Cache stores the captured responses, e.g. an LRUCache which can be inspected with AdminHandler
+	Cache Cacher
+	//MaxBodySize is the number of body bytes stored, 0 stores only the status and the headers
+	MaxBodySize int64
+}
+
+//NewErrorCapture creates an ErrorCapture for CachedTransport.ErrorCapture storing the responses in cache
+func NewErrorCapture(cache Cacher, maxBodySize int64) *ErrorCapture {
+	return &ErrorCapture{Cache: cache, MaxBodySize: maxBodySize}
+}
+
+//capture stores a copy of res with the body truncated if res is not a 2xx response, the body of res is left
+//complete for the caller
+func (e *ErrorCapture) capture(req *http.Request, res *http.Response) error {
+
+	if e == nil || (res.StatusCode >= 200 && res.StatusCode < 300) {
+		return nil
+	}
+
+	var prefix []byte
+	if res.Body != nil && res.Body != http.NoBody {
+		var err error
+		body := res.Body
+		prefix, err = ioutil.ReadAll(io.LimitReader(body, e.MaxBodySize+1))
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(prefix), body), body}
+		if err != nil {
+			return err
+		}
+	}
+
+	captured := *res
+	captured.Header = res.Header.Clone()
+	if captured.Header == nil {
+		captured.Header = http.Header{}
+	}
+	captured.Header.Set(EntryClassHeader, ErrorEntryClass)
+	if int64(len(prefix)) > e.MaxBodySize {
+		prefix = prefix[:e.MaxBodySize]
+		captured.Header.Set(BodyTruncatedHeader, "true")
+	}
+	captured.ContentLength = int64(len(prefix))
+	captured.Body = ioutil.NopCloser(bytes.NewReader(prefix))
+
+	return e.Cache.Set(req, &captured)
+}
@@ -0,0 +1,87 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_ErrorCapture(t *testing.T) {
+
+	errorPage := strings.Repeat("<p>error</p>", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, _ = writer.Write([]byte(errorPage))
+		case "/missing":
+			//error responses are only cached with an explicit lifetime
+			writer.Header().Set("Cache-Control", "max-age=60")
+			http.NotFound(writer, r)
+		default:
+			_, _ = writer.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	capture := NewErrorCapture(NewLRUCache(LRUCacheOptions{MaxEntries: 10}), 16)
+	cache := NewMapCache()
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport, ErrorCapture: capture}}
+
+	tests := []struct {
+		path      string
+		captured  bool
+		body      string
+		truncated bool
+	}{
+		{"/error", true, errorPage[:16], true},
+		{"/missing", true, "404 page not found\n"[:16], true},
+		{"/ok", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res, err := client.Get(server.URL + tt.path)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if tt.path == "/error" && string(body) != errorPage {
+				t.Error("expected the caller to receive the complete body, got", len(body), "bytes")
+			}
+			if res.Header.Get(EntryClassHeader) != "" {
+				t.Error("expected the response of the caller to be unchanged")
+			}
+
+			req, _ := http.NewRequest("GET", server.URL+tt.path, nil)
+			captured, err := capture.Cache.Get(req)
+			if !tt.captured {
+				if err != NotInCacheError {
+					t.Error("expected no captured entry, got", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			capturedBody, _ := ioutil.ReadAll(captured.Body)
+			if string(capturedBody) != tt.body {
+				t.Errorf("expected the captured body %q, got %q", tt.body, capturedBody)
+			}
+			if captured.Header.Get(EntryClassHeader) != ErrorEntryClass || (captured.Header.Get(BodyTruncatedHeader) == "true") != tt.truncated {
+				t.Error("unexpected captured headers", captured.Header)
+			}
+		})
+	}
+
+	if len(cache.Keys()) != 2 {
+		t.Error("expected the cacheable 404 and 200 responses in the cache, got", cache.Keys())
+	}
+}
@@ -0,0 +1,114 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+//StoreUnavailableError is matched by the errors of RoundTrip if the Cache failed to read or store a response, e.g.
+//because its Redis can not be reached. Entries which can not be decoded are DecodeError instead
+var StoreUnavailableError = errors.New("the cache store is unavailable")
+
+//DecodeError is matched by the errors of caches reading a stored entry which can not be decoded, e.g. a truncated
+//file of a DiskCache, an entry written with another Codec or with a newer schema, see EntrySchemaVersion. Misses are
+//NotInCacheError. RoundTrip treats such entries as misses and replaces them from the origin
+var DecodeError = errors.New("the cache entry can not be decoded")
+
+//StaleEntryError is matched by the errors of RoundTrip if the origin failed while a stale response was stored which
+//was not served, e.g. outside its stale-if-error window. Retrying with Cache-Control: max-stale serves it
+var StaleEntryError = errors.New("the origin failed and the stored response is stale")
+
+//StoreError is returned by RoundTrip if the Cache failed to get or set the response of a request. It is
+//StoreUnavailableError for errors.Is unless the entry could not be decoded, then it is DecodeError. It unwraps to the
+//error of the Cache
+type StoreError struct {
+	//Op is the failed operation, "get" or "set"
+	Op string
+	//Key is the key of the request, empty if the Cache is no Keyer
+	Key string
+	Err error
+}
+
+func (e *StoreError) Error() string {
+	return "cache " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *StoreError) Is(target error) bool {
+	return target == StoreUnavailableError && !errors.Is(e.Err, DecodeError)
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+//storeError wraps the error of the Cache operation op for req in a StoreError, misses and the errors of requests the
+//caller gave up on are returned as they are
+func (c *CachedTransport) storeError(op string, req *http.Request, err error) error {
+
+	if err == nil || errors.Is(err, NotInCacheError) || req.Context().Err() != nil {
+		return err
+	}
+	var storeErr *StoreError
+	if errors.As(err, &storeErr) {
+		return err
+	}
+	key, _ := cacheKey(c.Cache, req)
+	return &StoreError{Op: op, Key: key, Err: err}
+}
+
+//decodeError is the error of decoding a stored entry, it is DecodeError for errors.Is and unwraps to the error of the
+//decoder
+type decodeError struct {
+	err error
+}
+
+func (e decodeError) Error() string {
+	return "decode cache entry: " + e.err.Error()
+}
+
+func (e decodeError) Is(target error) bool {
+	return target == DecodeError
+}
+
+func (e decodeError) Unwrap() error {
+	return e.err
+}
+
+//wrapDecodeError wraps err of decoding an entry so it is DecodeError, the end of the entries and misses are returned
+//as they are
+func wrapDecodeError(err error) error {
+	if err == nil || err == io.EOF || errors.Is(err, NotInCacheError) || errors.Is(err, DecodeError) {
+		return err
+	}
+	return decodeError{err}
+}
+
+//staleEntryError is the error of the origin for a request with a stale response stored which was not served, it is
+//StaleEntryError for errors.Is and unwraps to the error of the origin
+type staleEntryError struct {
+	err error
+}
+
+func (e staleEntryError) Error() string {
+	return e.err.Error() + " (a stale response is stored)"
+}
+
+func (e staleEntryError) Is(target error) bool {
+	return target == StaleEntryError
+}
+
+func (e staleEntryError) Unwrap() error {
+	return e.err
+}
+
+//withStaleEntry wraps the error of the origin for a request with a stale stored response, errors of the Cache and of
+//requests the caller gave up on are returned as they are
+func withStaleEntry(req *http.Request, err error) error {
+	var storeErr *StoreError
+	if errors.Is(err, context.Canceled) || errors.As(err, &storeErr) || req.Context().Err() != nil {
+		return err
+	}
+	return staleEntryError{err}
+}
@@ -0,0 +1,156 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+)
+
+//unavailableCache fails like a cache whose remote store can not be reached
+type unavailableCache struct {
+	getErr error
+	setErr error
+}
+
+func (u unavailableCache) Get(req *http.Request) (*http.Response, error) {
+	return nil, u.getErr
+}
+
+func (u unavailableCache) Set(req *http.Request, res *http.Response) error {
+	return u.setErr
+}
+
+func TestCachedTransport_RoundTrip_StoreErrors(t *testing.T) {
+
+	refused := errors.New("connection refused")
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=60")
+		return res, nil
+	})
+
+	tests := []struct {
+		name        string
+		cache       unavailableCache
+		op          string
+		unavailable bool
+	}{
+		{"get", unavailableCache{getErr: refused}, "get", true},
+		{"set", unavailableCache{getErr: NotInCacheError, setErr: refused}, "set", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &CachedTransport{Cache: tt.cache, Fallback: origin}
+			_, err := transport.RoundTrip(lruTestRequest(t, "/"))
+
+			var storeErr *StoreError
+			if !errors.As(err, &storeErr) || storeErr.Op != tt.op {
+				t.Fatal("expected a StoreError of", tt.op, "got", err)
+			}
+			if errors.Is(err, StoreUnavailableError) != tt.unavailable || errors.Is(err, DecodeError) == tt.unavailable {
+				t.Error("wrong error kind", err)
+			}
+			if tt.unavailable && !errors.Is(err, refused) {
+				t.Error("expected the error of the cache to be wrapped, got", err)
+			}
+		})
+	}
+
+	//entries which can not be decoded are misses, the error is reported to the hooks
+	var reported error
+	transport := &CachedTransport{Cache: unavailableCache{getErr: wrapDecodeError(errors.New("unexpected EOF"))}, Fallback: origin,
+		Hooks: &Hooks{OnError: func(event Event) {
+			reported = event.Err
+		}}}
+	if _, err := transport.RoundTrip(lruTestRequest(t, "/")); err != nil {
+		t.Error("expected the response of the origin, got", err)
+	}
+	var storeErr *StoreError
+	if !errors.As(reported, &storeErr) || storeErr.Op != "get" || !errors.Is(reported, DecodeError) || errors.Is(reported, StoreUnavailableError) {
+		t.Error("expected a DecodeError to be reported, got", reported)
+	}
+}
+
+func TestDiskCache_DecodeError(t *testing.T) {
+
+	dir := "tmp/disk-decode"
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := lruTestRequest(t, "/")
+	response := lruTestResponse("content")
+	response.Header.Set("Cache-Control", "max-age=60")
+	if err := cache.Set(request, response); err != nil {
+		t.Fatal(err)
+	}
+	key, err := cache.Key(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//a crash truncated the file after the metadata line
+	content, err := ioutil.ReadFile(cache.path(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(cache.path(key), content[:len(content)-10], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.GetKey(key); !errors.Is(err, DecodeError) {
+		t.Error("expected DecodeError, got", err)
+	}
+	transport := &CachedTransport{Cache: cache, Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return lruTestResponse("content"), nil
+	})}
+	//the truncated entry is a miss and replaced from the origin
+	if _, err = transport.RoundTrip(lruTestRequest(t, "/")); err != nil {
+		t.Error("expected the response of the origin, got", err)
+	}
+	if _, err := cache.GetKey(key); err != nil {
+		t.Error("expected the entry to be replaced, got", err)
+	}
+}
+
+func TestCachedTransport_RoundTrip_StaleEntryError(t *testing.T) {
+
+	down := errors.New("origin down")
+	failing := false
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return nil, down
+		}
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=0")
+		res.Header.Set("ETag", `"1"`)
+		return res, nil
+	})}
+
+	if _, err := transport.RoundTrip(lruTestRequest(t, "/")); err != nil {
+		t.Fatal(err)
+	}
+	failing = true
+	_, err := transport.RoundTrip(lruTestRequest(t, "/"))
+	if !errors.Is(err, StaleEntryError) || !errors.Is(err, down) {
+		t.Fatal("expected StaleEntryError wrapping the origin error, got", err)
+	}
+
+	request := lruTestRequest(t, "/")
+	request.Header.Set("Cache-Control", "max-stale")
+	res, err := transport.RoundTrip(request)
+	if err != nil {
+		t.Fatal("expected the stale response for max-stale, got", err)
+	}
+	if body, _ := ioutil.ReadAll(res.Body); string(body) != "content" {
+		t.Error("wrong body", string(body))
+	}
+
+	if _, err := (&CachedTransport{Cache: NewMapCache(), Fallback: transport.Fallback}).RoundTrip(lruTestRequest(t, "/")); errors.Is(err, StaleEntryError) || err != down {
+		t.Error("expected the origin error without a stored response, got", err)
+	}
+}
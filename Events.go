@@ -0,0 +1,130 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//CacheEventType is the kind of a CacheEvent
+type CacheEventType string
+
+const (
+	//StoredEvent is published when a response was stored
+	StoredEvent CacheEventType = "stored"
+	//HitEvent is published when a response was served from the cache, fresh or stale
+	HitEvent CacheEventType = "hit"
+	//ExpiredEvent is published when a lookup found a stale entry, before it is revalidated, refreshed or served stale
+	ExpiredEvent CacheEventType = "expired"
+	//EvictedEvent is published by EventStream.Evicted, e.g. as LRUCacheOptions.OnEvict
+	EvictedEvent CacheEventType = "evicted"
+	//InvalidatedEvent is published when an entry was deleted or soft deleted by an invalidation
+	InvalidatedEvent CacheEventType = "invalidated"
+)
+
+//CacheEvent describes a change or use of a cache entry published by an EventStream
+type CacheEvent struct {
+	Type CacheEventType
+	//Key is the key of the entry, it is empty for stores and hits if the cache does not implement Keyer
+	Key string
+	//URL is the URL of the request, empty for evictions and invalidations
+	URL string
+	//Size is the size of the body in bytes, -1 if it is unknown
+	Size int64
+	//Stale is set for hits on stale responses
+	Stale bool
+	Time  time.Time
+}
+
+//EventStream publishes the CacheEvents of a CachedTransport to its subscribers, e.g. to update a secondary index or
+//to start a pipeline for stored responses without polling the cache. Events are sent without blocking the requests,
+//events a subscriber has no room for in its buffer are dropped and counted by Dropped
+type EventStream struct {
+	mutex       sync.RWMutex
+	subscribers map[chan CacheEvent]struct{}
+	dropped     int64
+}
+
+func NewEventStream() *EventStream {
+	return &EventStream{subscribers: map[chan CacheEvent]struct{}{}}
+}
+
+//Subscribe returns a channel receiving the events published from now on with room for buffer events. cancel ends the
+//subscription and closes the channel
+func (s *EventStream) Subscribe(buffer int) (events <-chan CacheEvent, cancel func()) {
+
+	channel := make(chan CacheEvent, buffer)
+	s.mutex.Lock()
+	if s.subscribers == nil {
+		s.subscribers = map[chan CacheEvent]struct{}{}
+	}
+	s.subscribers[channel] = struct{}{}
+	s.mutex.Unlock()
+
+	var once sync.Once
+	return channel, func() {
+		once.Do(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			delete(s.subscribers, channel)
+			close(channel)
+		})
+	}
+}
+
+//Dropped returns the number of events not delivered because a subscriber was not ready
+func (s *EventStream) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+//Evicted publishes an EvictedEvent, it matches LRUCacheOptions.OnEvict
+func (s *EventStream) Evicted(key string) {
+	if s != nil && s.subscribed() {
+		s.publish(CacheEvent{Type: EvictedEvent, Key: key, Size: -1, Time: time.Now()})
+	}
+}
+
+func (s *EventStream) subscribed() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.subscribers) > 0
+}
+
+func (s *EventStream) publish(event CacheEvent) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	}
+}
+
+//response publishes an event of eventType for the response res of req, the key is only computed with subscribers
+func (s *EventStream) response(eventType CacheEventType, cache Cacher, req *http.Request, res *http.Response, stale bool) {
+	if s == nil || !s.subscribed() {
+		return
+	}
+	key, _ := cacheKey(cache, req)
+	s.publish(CacheEvent{Type: eventType, Key: key, URL: req.URL.String(), Size: bodySize(res), Stale: stale, Time: time.Now()})
+}
+
+func (s *EventStream) invalidated(key string) {
+	if s != nil && s.subscribed() {
+		s.publish(CacheEvent{Type: InvalidatedEvent, Key: key, Size: -1, Time: time.Now()})
+	}
+}
+
+//bodySize returns the size of the body of res without reading it, -1 if it is unknown
+func bodySize(res *http.Response) int64 {
+	if body, ok := res.Body.(interface{ Size() int64 }); ok {
+		return body.Size()
+	}
+	if res.ContentLength >= 0 {
+		return res.ContentLength
+	}
+	return -1
+}
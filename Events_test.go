@@ -0,0 +1,70 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEventStream(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age="+req.URL.Query().Get("max-age"))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+	stream := NewEventStream()
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback, Events: stream}
+	client := http.Client{Transport: transport}
+
+	events, cancel := stream.Subscribe(16)
+	_, cancelDropping := stream.Subscribe(0)
+	defer cancelDropping()
+
+	for _, url := range []string{"http://example.com/?max-age=60", "http://example.com/?max-age=60", "http://example.com/?max-age=0"} {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_ = res.Body.Close()
+	}
+	if _, err := client.Get("http://example.com/?max-age=0"); err != nil {
+		t.Error(err)
+	}
+	if _, err := transport.InvalidateURL("http://example.com/"); err != nil {
+		t.Error(err)
+	}
+	stream.Evicted("evicted key")
+	cancel()
+
+	var received []CacheEvent
+	for event := range events {
+		received = append(received, event)
+	}
+	expected := []CacheEventType{StoredEvent, HitEvent, StoredEvent, ExpiredEvent, StoredEvent, InvalidatedEvent, InvalidatedEvent, EvictedEvent}
+	if len(received) != len(expected) {
+		t.Error("expected", expected, "got", received)
+		t.FailNow()
+	}
+	for i, event := range received {
+		if event.Type != expected[i] {
+			t.Error("expected", expected[i], "got", event.Type, "at", i)
+		}
+	}
+	if hit := received[1]; hit.Key == "" || hit.URL != "http://example.com/?max-age=60" || hit.Size != 4 || hit.Time.IsZero() {
+		t.Error("expected the key, URL, size and time of the hit, got", hit)
+	}
+	if evicted := received[7]; evicted.Key != "evicted key" || evicted.Size != -1 {
+		t.Error("expected the evicted key, got", evicted)
+	}
+
+	if dropped := stream.Dropped(); dropped != int64(len(expected)) {
+		t.Error("expected the events of the full subscriber to be dropped, got", dropped)
+	}
+	stream.Evicted("after cancel")
+	if dropped := stream.Dropped(); dropped != int64(len(expected))+1 {
+		t.Error("expected the canceled subscriber to receive nothing, got", dropped)
+	}
+}
@@ -0,0 +1,100 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+//Export writes all entries to w as one JSON entry per line, the format of a FileCache file with JSONCodec.
+//The result is loaded with Import or opened as cache file with OpenFileCache, e.g. to ship a pre-filled cache
+func (m *MapCache) Export(w io.Writer) error {
+	return exportEntries(w, m)
+}
+
+//Import adds the entries of an Export to the cache, entries with the same key are replaced. It returns the number of
+//imported entries
+func (m *MapCache) Import(r io.Reader) (int, error) {
+	return importEntries(r, func(key string, res *http.Response) error {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.cache[key] = res
+		return nil
+	})
+}
+
+//Import adds the entries of an Export to the cache and appends them to the cache file, entries with the same key
+//are replaced. It returns the number of imported entries
+func (f *FileCache) Import(r io.Reader) (int, error) {
+	return importEntries(r, func(key string, res *http.Response) error {
+		err := f.write(key, res)
+		if err != nil {
+			return err
+		}
+		f.MapCache.mutex.Lock()
+		replaced := f.cache[key]
+		f.cache[key] = res
+		f.MapCache.mutex.Unlock()
+		if replaced != nil {
+			removeBodyFile(replaced)
+		}
+		return nil
+	})
+}
+
+//exportEntries writes the entries of cache to w with JSONCodec, bodies in their own file are embedded
+func exportEntries(w io.Writer, cache Inspector) error {
+
+	for _, key := range cache.Keys() {
+		res, err := cache.GetKey(key)
+		if err == NotInCacheError {
+			//the entry was deleted since listing the keys
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		response, err := NewJsonResponse(res)
+		if err != nil {
+			return err
+		}
+		err = JSONCodec.Encode(w, &FileCacheEntry{Version: EntrySchemaVersion, Request: key, Response: response})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//importEntries decodes the entries of r with JSONCodec and passes them to set, the deletion entries only cache files
+//contain are skipped
+func importEntries(r io.Reader, set func(key string, res *http.Response) error) (int, error) {
+
+	decoder := JSONCodec.NewDecoder(r)
+	imported := 0
+	for {
+		var entry FileCacheEntry
+		err := decodeEntry(decoder, &entry)
+		if err == io.EOF {
+			return imported, nil
+		}
+		if err != nil {
+			return imported, err
+		}
+		if entry.Response == nil {
+			continue
+		}
+		if entry.Response.BodyFile != "" {
+			return imported, fmt.Errorf("the body of %q is stored in the file %s, export the cache to embed it", entry.Request, entry.Response.BodyFile)
+		}
+		res, err := entry.Response.Parse()
+		if err != nil {
+			return imported, err
+		}
+		err = set(entry.Request, res)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+}
@@ -0,0 +1,93 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMapCache_Export_Import(t *testing.T) {
+
+	source := NewMapCache()
+	for _, path := range []string{"/a", "/b"} {
+		if err := source.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	var export bytes.Buffer
+	if err := source.Export(&export); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if lines := strings.Count(export.String(), "\n"); lines != 2 {
+		t.Error("expected one line per entry got", lines)
+	}
+
+	fileCache, err := NewFileCache("tmp/import.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	tests := []struct {
+		name  string
+		cache interface {
+			Inspector
+			Import(r io.Reader) (int, error)
+		}
+	}{
+		{"MapCache", NewMapCache()},
+		{"FileCache", fileCache},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imported, err := tt.cache.Import(bytes.NewReader(export.Bytes()))
+			if err != nil || imported != 2 {
+				t.Error("expected 2 imported entries got", imported, err)
+			}
+			assertSameEntries(t, source, tt.cache)
+		})
+	}
+
+	reopened, err := OpenFileCache("tmp/import.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	assertSameEntries(t, source, reopened)
+}
+
+func TestMapCache_Import_BodyFile(t *testing.T) {
+
+	line := `{"Request":"key","Response":{"StatusCode":200,"BodyFile":"0001"}}` + "\n"
+	imported, err := NewMapCache().Import(strings.NewReader(line))
+	if err == nil || imported != 0 {
+		t.Error("expected an error for an entry without embedded body got", imported, err)
+	}
+}
+
+//assertSameEntries fails if actual does not hold the keys and bodies of expected
+func assertSameEntries(t *testing.T, expected Inspector, actual Inspector) {
+
+	keys := expected.Keys()
+	if len(actual.Keys()) != len(keys) {
+		t.Error("expected", len(keys), "entries got", len(actual.Keys()))
+	}
+	for _, key := range keys {
+		want, _ := expected.GetKey(key)
+		got, err := actual.GetKey(key)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		wantBody, _ := ioutil.ReadAll(want.Body)
+		gotBody, _ := ioutil.ReadAll(got.Body)
+		if !bytes.Equal(wantBody, gotBody) {
+			t.Errorf("expected the body %q got %q", wantBody, gotBody)
+		}
+	}
+}
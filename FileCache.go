@@ -1,17 +1,52 @@
 package CachedHttpClient
 
 import (
-	"bufio"
-	"encoding/json"
+	"bytes"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sync"
 )
 
 type FileCache struct {
 	*MapCache
 	filePath string
 	file     *os.File
+	//fileMutex serializes appending entries to file
+	fileMutex sync.Mutex
+	//BodyThreshold stores bodies larger than BodyThreshold bytes in their own file next to the cache file instead of
+	//the cache file and memory. They are written while the caller reads the body and stored once it was read
+	//completely, 0 keeps all bodies in the cache file
+	BodyThreshold int64
+	FileCacheOptions
+	//provenances are the recorded Provenance of the entries by key, guarded by provenanceMutex
+	provenances     map[string]*Provenance
+	provenanceMutex sync.Mutex
+}
+
+type FileCacheOptions struct {
+	//Codec encodes the entries of the cache file, JSONCodec if nil. A cache file has to be opened with the codec
+	//it was created with
+	Codec Codec
+	//Compression compresses the bodies in the cache file if not nil
+	Compression *Compression
+	//Digester names the body files of BodyThreshold by the hash of their key, SHA256Digester if nil
+	Digester Digester
+	//RecordProvenance stores the Provenance of the writer with every entry, see FileCache.Provenance
+	RecordProvenance bool
+	//Policy describes the caching rules of the writer, e.g. the version of its configuration, it is part of
+	//Provenance.PolicyHash
+	Policy string
+}
+
+func (o FileCacheOptions) codec() Codec {
+	if o.Codec == nil {
+		return JSONCodec
+	}
+	return o.Codec
 }
 
 func (f *FileCache) Get(req *http.Request) (*http.Response, error) {
@@ -20,58 +55,228 @@ func (f *FileCache) Get(req *http.Request) (*http.Response, error) {
 
 }
 
+//FileCacheEntry is a line of the cache file, entries without Response mark their key as deleted
 type FileCacheEntry struct {
+	//Version is the schema version the entry was written with, 0 for entries written before versions were
+	//introduced. See EntrySchemaVersion
+	Version  int `json:",omitempty"`
 	Request  string
 	Response *JsonResponse
+	//Provenance describes the writer of the entry if FileCacheOptions.RecordProvenance was set
+	Provenance *Provenance `json:",omitempty"`
 }
 
 func (f *FileCache) Set(req *http.Request, res *http.Response) error {
 
-	dumpRequest, err := DumpRequest(req, !f.IgnoreRequestBody, f.DontIncludeAllRequestHeaders)
+	key, err := f.Key(req)
+
+	if err != nil {
+		return err
+	}
+
+	//body files are not encrypted, an encrypted codec keeps all bodies in the cache file
+	if f.BodyThreshold > 0 && !f.encrypted() && res.Body != nil && res.Body != http.NoBody {
+		prefix, err := ioutil.ReadAll(io.LimitReader(res.Body, f.BodyThreshold+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(prefix)) > f.BodyThreshold {
+			return f.setLarge(key, res, prefix)
+		}
+		err = res.Body.Close()
+		if err != nil {
+			return err
+		}
+		res.Body = ioutil.NopCloser(bytes.NewReader(prefix))
+	}
+
+	f.MapCache.mutex.Lock()
+	replaced := f.cache[key]
+	f.MapCache.mutex.Unlock()
+
+	err = f.write(key, res)
+	if err != nil {
+		return err
+	}
+
+	err = f.MapCache.Set(req, res)
+	if err != nil {
+		return err
+	}
+	if replaced != nil {
+		removeBodyFile(replaced)
+	}
+	return nil
+
+}
+
+//write appends the entry for key to the cache file
+func (f *FileCache) write(key string, res *http.Response) error {
 
+	entry, err := f.entry(key, res)
 	if err != nil {
 		return err
 	}
+	if f.RecordProvenance {
+		entry.Provenance = f.provenance()
+	}
 
-	newJSONResponse, err := NewJsonResponse(res)
+	f.fileMutex.Lock()
+	defer f.fileMutex.Unlock()
+
+	err = f.codec().Encode(f.file, entry)
 	if err != nil {
 		return err
 	}
+	f.setProvenance(key, entry.Provenance)
+	return nil
+}
+
+//entry converts res to the entry stored for key
+func (f *FileCache) entry(key string, res *http.Response) (*FileCacheEntry, error) {
 
-	err = json.NewEncoder(f.file).Encode(FileCacheEntry{
-		Request:  string(dumpRequest),
+	var newJSONResponse *JsonResponse
+	var err error
+	if body, ok := res.Body.(*fileBody); ok {
+		newJSONResponse, err = newJsonResponseHead(res)
+		if err != nil {
+			return nil, err
+		}
+		newJSONResponse.BodyFile = body.name
+	} else {
+		newJSONResponse, err = NewJsonResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		err = f.Compression.compress(newJSONResponse)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &FileCacheEntry{
+		Version:  EntrySchemaVersion,
+		Request:  key,
 		Response: newJSONResponse,
-	})
+	}, nil
+}
 
-	err = f.MapCache.Set(req, res)
+//Compact rewrites the cache file with the current entries only, replaced and deleted entries are dropped and all
+//entries are encoded with the current Codec and Compression, e.g. to retire a key of EncryptedCodec
+func (f *FileCache) Compact() error {
+
+	f.MapCache.mutex.Lock()
+	defer f.MapCache.mutex.Unlock()
+	f.fileMutex.Lock()
+	defer f.fileMutex.Unlock()
+
+	compacted, err := ioutil.TempFile(filepath.Dir(f.filePath), filepath.Base(f.filePath)+".compact-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(compacted.Name())
+	err = compacted.Chmod(0644)
+	if err != nil {
+		_ = compacted.Close()
+		return err
+	}
+
+	for key, res := range f.cache {
+		stored, err := CopyResponse(res)
+		if err != nil {
+			_ = compacted.Close()
+			return err
+		}
+		entry, err := f.entry(key, stored)
+		if err != nil {
+			_ = compacted.Close()
+			return err
+		}
+		//the provenance describes the writer which stored the response, not the one compacting the file
+		entry.Provenance, _ = f.Provenance(key)
+		err = f.codec().Encode(compacted, entry)
+		if err != nil {
+			_ = compacted.Close()
+			return err
+		}
+	}
+
+	err = compacted.Sync()
+	if err != nil {
+		_ = compacted.Close()
+		return err
+	}
+	err = compacted.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(compacted.Name(), f.filePath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.filePath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_ = f.file.Close()
+	f.file = file
+	return nil
+}
+
+//DeleteKey removes the entry stored under key and appends the deletion to the cache file
+func (f *FileCache) DeleteKey(key string) error {
+
+	f.MapCache.mutex.Lock()
+	deleted := f.cache[key]
+	f.MapCache.mutex.Unlock()
 
+	err := f.MapCache.DeleteKey(key)
 	if err != nil {
 		return err
 	}
+	removeBodyFile(deleted)
+	f.setProvenance(key, nil)
 
-	return err
+	f.fileMutex.Lock()
+	defer f.fileMutex.Unlock()
 
+	return f.codec().Encode(f.file, &FileCacheEntry{Version: EntrySchemaVersion, Request: key})
 }
 
-func newFileCache(filePath string, file *os.File, cache *MapCache) *FileCache {
+func newFileCache(filePath string, file *os.File, cache *MapCache, options []FileCacheOptions) *FileCache {
 
-	return &FileCache{
+	fileCache := &FileCache{
 		filePath: filePath,
 		file:     file,
 		MapCache: cache,
 	}
 
+	if options != nil {
+		fileCache.FileCacheOptions = options[0]
+	}
+
+	return fileCache
+
 }
 
 //OpenFileCache loaded the cache from an existing cache file
-func OpenFileCache(filePath string) (*FileCache, error) {
+func OpenFileCache(filePath string, options ...FileCacheOptions) (*FileCache, error) {
 	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return nil, err
 	}
 
+	var codec Codec = JSONCodec
+	if options != nil {
+		codec = options[0].codec()
+	}
+
 	fileR, err := os.OpenFile(filePath, os.O_RDONLY, 0644)
-	mapCache, err := loadMapCacheFromFile(fileR)
+	if err != nil {
+		return nil, err
+	}
+	mapCache, provenances, err := loadMapCacheFromFile(fileR, bodyDir(filePath), codec)
 	if err != nil {
 		return nil, err
 	}
@@ -79,56 +284,96 @@ func OpenFileCache(filePath string) (*FileCache, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newFileCache(filePath, file, mapCache), nil
+	fileCache := newFileCache(filePath, file, mapCache, options)
+	fileCache.provenances = provenances
+	return fileCache, nil
 
 }
 
-const scannerMaxInt = int(^uint(0) >> 1)
+//ReadFileCache loads the entries of an existing cache file into a MapCache without opening the file for writing, e.g.
+//to read the cache of another process. Changes of the file after loading are not seen
+func ReadFileCache(filePath string, options ...FileCacheOptions) (*MapCache, error) {
 
-func loadMapCacheFromFile(file *os.File) (*MapCache, error) {
+	var codec Codec = JSONCodec
+	if options != nil {
+		codec = options[0].codec()
+	}
 
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 4096), scannerMaxInt)
-	responses := map[string]*http.Response{}
-	for scanner.Scan() {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	mapCache, _, err := loadMapCacheFromFile(file, bodyDir(filePath), codec)
+	return mapCache, err
+}
 
-		readBytes := scanner.Bytes()
+func loadMapCacheFromFile(file *os.File, bodyDir string, codec Codec) (*MapCache, map[string]*Provenance, error) {
+
+	decoder := codec.NewDecoder(file)
+	responses := map[string]*http.Response{}
+	provenances := map[string]*Provenance{}
+	for {
 
 		var entry FileCacheEntry
-		err := json.Unmarshal(readBytes, &entry)
+		err := decodeEntry(decoder, &entry)
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return nil, err
+			return nil, nil, wrapDecodeError(err)
+		}
+		if entry.Response == nil {
+			delete(responses, entry.Request)
+			delete(provenances, entry.Request)
+			continue
+		}
+		res, err := entry.Response.Parse()
+		if err != nil {
+			return nil, nil, wrapDecodeError(err)
+		}
+		if entry.Response.BodyFile != "" {
+			res.Body = &fileBody{dir: bodyDir, name: entry.Response.BodyFile}
+		}
+		responses[entry.Request] = res
+		if entry.Provenance != nil {
+			provenances[entry.Request] = entry.Provenance
+		} else {
+			delete(provenances, entry.Request)
 		}
-		responses[entry.Request] = entry.Response.ToResponse()
 
 	}
 
 	return &MapCache{
 		cache: responses,
-	}, nil
+	}, provenances, nil
 
 }
 
 //OpenOrCreateFileCache open the existing cache file or creates a new
-func OpenOrCreateFileCache(filePath string) (*FileCache, error) {
+func OpenOrCreateFileCache(filePath string, options ...FileCacheOptions) (*FileCache, error) {
 
 	_, err := os.Stat(filePath)
 	if err == nil {
-		return OpenFileCache(filePath)
+		return OpenFileCache(filePath, options...)
 	}
 	if errors.Is(err, os.ErrNotExist) {
-		return NewFileCache(filePath)
+		return NewFileCache(filePath, options...)
 	}
 
 	return nil, err
 }
 
 //NewFileCache create a new FileCache overriding the cache file
-func NewFileCache(filePath string) (*FileCache, error) {
+func NewFileCache(filePath string, options ...FileCacheOptions) (*FileCache, error) {
 	create, err := os.Create(filePath)
 	if err != nil {
 		return nil, err
 	}
+	err = os.RemoveAll(bodyDir(filePath))
+	if err != nil {
+		return nil, err
+	}
 	err = create.Close()
 	if err != nil {
 		return nil, err
@@ -138,6 +383,6 @@ func NewFileCache(filePath string) (*FileCache, error) {
 		return nil, err
 	}
 
-	return newFileCache(filePath, file, NewMapCache()), nil
+	return newFileCache(filePath, file, NewMapCache(), options), nil
 
 }
@@ -3,7 +3,9 @@ package CachedHttpClient
 import (
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -133,3 +135,82 @@ func TestAppendFileCache(t *testing.T) {
 
 	}
 }
+
+func TestFileCache_DeleteKey(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		_, _ = writer.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	fileCache, err := NewFileCache("tmp/delete.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	client := http.Client{Transport: &CachedTransport{Cache: fileCache, Fallback: http.DefaultTransport}}
+	for _, path := range []string{"/keep", "/delete"} {
+		_, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	for _, key := range fileCache.Keys() {
+		if strings.Contains(key, "/delete") {
+			err = fileCache.DeleteKey(key)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+		}
+	}
+	if err := fileCache.DeleteKey("missing"); err != NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+
+	reopened, err := OpenFileCache("tmp/delete.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	keys := reopened.Keys()
+	if len(keys) != 1 || !strings.Contains(keys[0], "/keep") {
+		t.Error("expected only the kept entry after reopening, got", keys)
+	}
+}
+
+func TestReadFileCache(t *testing.T) {
+
+	fileCache, err := NewFileCache("tmp/read.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := fileCache.Set(lruTestRequest(t, "/read"), lruTestResponse("read")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	info, err := os.Stat("tmp/read.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	mapCache, err := ReadFileCache("tmp/read.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := mapCache.Get(lruTestRequest(t, "/read")); err != nil {
+		t.Error("expected the stored entry, got", err)
+	}
+	if after, err := os.Stat("tmp/read.cache"); err != nil || after.Size() != info.Size() {
+		t.Error("expected the file to be unchanged, got", after, err)
+	}
+
+	if _, err := ReadFileCache("tmp/missing.cache"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,164 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//isCacheable reports if res may be stored for req following RFC 7234 3, shared selects the rules for shared caches.
+//Besides policy.Storable POST requests cached by PostCaching and requests with a TTL set by WithTTL are stored,
+//206 responses only for range requests. Error responses need an explicit lifetime, which NegativeCaching sets
+func isCacheable(req *http.Request, res *http.Response, shared bool) bool {
+
+	if !policy.CacheableMethod(req.Method) && !isPostCached(req) {
+		return false
+	}
+	if policy.ForbidsStoring(req, res, shared) {
+		return false
+	}
+	if res.StatusCode == http.StatusPartialContent && rangeFromContext(req.Context()) == "" {
+		//partial responses are only stored under the key of their range
+		return false
+	}
+
+	if _, ok := policy.ExplicitFreshnessLifetime(res, shared); ok {
+		return true
+	}
+	if _, ok := ttlFromContext(req.Context()); ok {
+		return true
+	}
+	if res.StatusCode >= 400 {
+		//a heuristic lifetime would keep serving the error after the origin recovered
+		return false
+	}
+
+	return policy.ParseCacheControl(res.Header).Has("public") || policy.HeuristicallyCacheable(res.StatusCode)
+}
+
+//storedResponse returns the copy of res which is stored without the hop-by-hop header fields, the noise header
+//fields of the response and its request and the transfer coding, shared caches also remove the header fields listed by private="field-name"
+//(RFC 7234 5.2.2.6). res is returned if nothing is removed
+func storedResponse(res *http.Response, shared bool, noise []string) *http.Response {
+
+	fields := append(hopByHopFields(res.Header), noiseFields(res.Header, noise)...)
+	if shared {
+		fields = append(fields, policy.ParseCacheControl(res.Header).Fields("private")...)
+	}
+	//the request is stored to match the Vary header against later requests
+	request := res.Request
+	if request != nil {
+		request = stripNoiseHeaders(request, noise)
+	}
+	if len(fields) == 0 && res.TransferEncoding == nil && request == res.Request {
+		return res
+	}
+
+	stored := *res
+	stored.Request = request
+	stored.Header = res.Header.Clone()
+	for _, field := range fields {
+		stored.Header.Del(field)
+	}
+	stored.TransferEncoding = nil
+	return &stored
+}
+
+//reusedResponse removes the header fields listed by no-cache="field-name" from the cached response res which is
+//served without revalidation, they must not be reused without a successful revalidation (RFC 9111 5.2.2.4).
+//Hop-by-hop fields of entries stored before they were removed on store are never replayed either. The clock of the
+//entry is replaced by the Age header with its current age at now (RFC 9111 5.1)
+func reusedResponse(res *http.Response, now time.Time) *http.Response {
+
+	hopByHop, noCache := hopByHopFields(res.Header), policy.ParseCacheControl(res.Header).Fields("no-cache")
+	seconds := int64(policy.CurrentAge(res, now) / time.Second)
+
+	//the header is copied in one pass with all values in one slice, a hitResponse reuses its header and slice
+	header, values, age := http.Header(nil), []string(nil), ""
+	if hit := hitOf(res); hit != nil {
+		if hit.header == nil {
+			hit.header = make(http.Header, len(res.Header)+1)
+		}
+		for field := range hit.header {
+			delete(hit.header, field)
+		}
+		header, values, age = hit.header, hit.values[:0], hit.ageValue(seconds)
+	} else {
+		header, age = make(http.Header, len(res.Header)+1), strconv.FormatInt(seconds, 10)
+	}
+	size := 1
+	for _, fieldValues := range res.Header {
+		size += len(fieldValues)
+	}
+	if cap(values) < size {
+		values = make([]string, 0, size)
+	}
+	for field, fieldValues := range res.Header {
+		if field == policy.RequestTimeHeader || field == policy.ResponseTimeHeader || field == "Age" ||
+			containsField(hopByHop, field) || containsField(noCache, field) {
+			continue
+		}
+		if fieldValues == nil {
+			header[field] = nil
+			continue
+		}
+		start := len(values)
+		values = append(values, fieldValues...)
+		header[field] = values[start:len(values):len(values)]
+	}
+	values = append(values, age)
+	header["Age"] = values[len(values)-1 : len(values) : len(values)]
+	if hit := hitOf(res); hit != nil {
+		hit.values = values
+	}
+
+	res.Header = header
+	res.TransferEncoding = nil
+	return res
+}
+
+//containsField reports if fields holds the canonical field name field
+func containsField(fields []string, field string) bool {
+	for _, name := range fields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+//withClock returns a copy of the stored response with the times the request was sent and the response was received,
+//they are used to compute the age of the response when it is served
+func withClock(stored *http.Response, requested time.Time, responded time.Time) *http.Response {
+	clocked := *stored
+	clocked.Header = stored.Header.Clone()
+	clocked.Header.Set(policy.RequestTimeHeader, requested.UTC().Format(time.RFC3339Nano))
+	clocked.Header.Set(policy.ResponseTimeHeader, responded.UTC().Format(time.RFC3339Nano))
+	return &clocked
+}
+
+//hopByHopHeaders only apply to a single connection (RFC 7230 6.1), they are neither stored nor replayed
+var hopByHopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Transfer-Encoding", "Upgrade"}
+
+//hopByHopFields returns the hop-by-hop fields present in header including the fields named in Connection
+func hopByHopFields(header http.Header) []string {
+
+	var fields []string
+	for _, field := range hopByHopHeaders {
+		if _, ok := header[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	for _, line := range header["Connection"] {
+		for _, field := range strings.Split(line, ",") {
+			field = http.CanonicalHeaderKey(strings.TrimSpace(field))
+			if _, ok := header[field]; ok && field != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
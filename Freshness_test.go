@@ -0,0 +1,414 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+func TestIsCacheable(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		method    string
+		reqHeader http.Header
+		status    int
+		resHeader http.Header
+		shared    bool
+		cacheable bool
+	}{
+		{"plain get", "GET", http.Header{}, 200, http.Header{}, false, true},
+		{"post", "POST", http.Header{}, 200, http.Header{"Cache-Control": {"max-age=60"}}, false, false},
+		{"no-store response", "GET", http.Header{}, 200, http.Header{"Cache-Control": {"no-store"}}, false, false},
+		{"no-store request", "GET", http.Header{"Cache-Control": {"no-store"}}, 200, http.Header{}, false, false},
+		{"private private cache", "GET", http.Header{}, 200, http.Header{"Cache-Control": {"private"}}, false, true},
+		{"private shared cache", "GET", http.Header{}, 200, http.Header{"Cache-Control": {"private"}}, true, false},
+		{"private fields shared cache", "GET", http.Header{}, 200, http.Header{"Cache-Control": {`private="Set-Cookie"`}}, true, true},
+		{"private empty fields shared cache", "GET", http.Header{}, 200, http.Header{"Cache-Control": {`private=""`}}, true, false},
+		{"authorization private fields", "GET", http.Header{"Authorization": {"Basic Zm9v"}}, 200, http.Header{"Cache-Control": {`private="Set-Cookie", max-age=60`}}, true, false},
+		{"authorization private fields s-maxage", "GET", http.Header{"Authorization": {"Basic Zm9v"}}, 200, http.Header{"Cache-Control": {`private="Set-Cookie", s-maxage=60`}}, true, true},
+		{"authorization shared cache", "GET", http.Header{"Authorization": {"Basic Zm9v"}}, 200, http.Header{}, true, false},
+		{"authorization public", "GET", http.Header{"Authorization": {"Basic Zm9v"}}, 200, http.Header{"Cache-Control": {"public"}}, true, true},
+		{"server error", "GET", http.Header{}, 500, http.Header{}, false, false},
+		{"server error max-age", "GET", http.Header{}, 500, http.Header{"Cache-Control": {"max-age=5"}}, false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &http.Request{Method: test.method, Header: test.reqHeader}
+			res := &http.Response{StatusCode: test.status, Header: test.resHeader}
+			if cacheable := isCacheable(req, res, test.shared); cacheable != test.cacheable {
+				t.Error("expected cacheable", test.cacheable, "got", cacheable)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_CacheControl(t *testing.T) {
+
+	counter := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		counter++
+		writer.Header().Set("Cache-Control", r.URL.Query().Get("cc"))
+		fmt.Fprintf(writer, "%d", counter)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		cacheControl string
+		cached       bool
+	}{
+		{"max-age=60", true},
+		{"max-age=0", false},
+		{"no-store", false},
+		{"no-cache", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cacheControl, func(t *testing.T) {
+
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+			url := server.URL + "/?cc=" + test.cacheControl
+
+			var bodies []string
+			for i := 0; i < 2; i++ {
+				response, err := client.Get(url)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				body, err := ioutil.ReadAll(response.Body)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				bodies = append(bodies, string(body))
+			}
+
+			if (bodies[0] == bodies[1]) != test.cached {
+				t.Error("expected cached", test.cached, "got bodies", bodies)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_HeuristicLifetime(t *testing.T) {
+
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	origin := 0
+	transport := &CachedTransport{Cache: NewMapCache(), Clock: clock, StatusHeaders: true,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			origin++
+			res := lruTestResponse("content")
+			res.Header.Set("Date", clock.Now().Format(http.TimeFormat))
+			return res, nil
+		})}
+
+	//responses without freshness information and Last-Modified are not served forever
+	for i, step := range []struct {
+		advance  time.Duration
+		expected string
+	}{{0, CacheMiss}, {policy.HeuristicLifetime - time.Second, CacheHit}, {2 * time.Second, CacheMiss}} {
+		clock.Advance(step.advance)
+		res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if status := res.Header.Get(CacheStatusHeader); status != step.expected {
+			t.Errorf("step %d: expected %s, got %s after %d origin requests", i, step.expected, status, origin)
+		}
+	}
+}
+
+func TestCachedTransport_RoundTrip_PrivateFields(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Cache-Control", `max-age=60, private="Set-Cookie, x-user"`)
+		writer.Header().Set("Set-Cookie", "session=1")
+		writer.Header().Set("X-User", "alice")
+		writer.Header().Set("X-Shared", "yes")
+		fmt.Fprint(writer, "content")
+	}))
+	defer server.Close()
+
+	for _, shared := range []bool{true, false} {
+		t.Run(fmt.Sprint("shared ", shared), func(t *testing.T) {
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport, Shared: shared}}
+
+			var responses []*http.Response
+			for i := 0; i < 2; i++ {
+				response, err := client.Get(server.URL)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				body, err := ioutil.ReadAll(response.Body)
+				if err != nil || string(body) != "content" {
+					t.Error("unexpected body", string(body), err)
+				}
+				responses = append(responses, response)
+			}
+
+			if responses[0].Header.Get("Set-Cookie") == "" || responses[0].Header.Get("X-User") == "" {
+				t.Error("expected the origin response to keep the private fields", responses[0].Header)
+			}
+			cached := responses[1]
+			if cached.Header.Get("X-Shared") != "yes" {
+				t.Error("expected the response to be cached", cached.Header)
+			}
+			if (cached.Header.Get("Set-Cookie") == "" && cached.Header.Get("X-User") == "") != shared {
+				t.Error("expected the private fields to be removed only by shared caches", cached.Header)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_NoCacheFields(t *testing.T) {
+
+	counter := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		counter++
+		writer.Header().Set("Cache-Control", `max-age=60, no-cache="Set-Cookie"`)
+		writer.Header().Set("Set-Cookie", fmt.Sprint("session=", counter))
+		writer.Header().Set("X-Shared", "yes")
+		fmt.Fprint(writer, counter)
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+
+	var responses []*http.Response
+	var bodies []string
+	for i := 0; i < 3; i++ {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		responses = append(responses, response)
+		bodies = append(bodies, string(body))
+	}
+
+	if bodies[0] != "1" || bodies[1] != "1" || bodies[2] != "1" {
+		t.Error("expected the response to be reused without revalidation, got", bodies)
+	}
+	if responses[0].Header.Get("Set-Cookie") != "session=1" {
+		t.Error("expected the origin response to keep Set-Cookie", responses[0].Header)
+	}
+	for _, cached := range responses[1:] {
+		if cached.Header.Get("Set-Cookie") != "" || cached.Header.Get("X-Shared") != "yes" {
+			t.Error("expected only Set-Cookie to be removed from the reused response", cached.Header)
+		}
+	}
+}
+
+//roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCachedTransport_RoundTrip_HopByHop(t *testing.T) {
+
+	hopByHop := http.Header{
+		"Connection":        {"keep-alive, X-Hop"},
+		"Keep-Alive":        {"timeout=5"},
+		"Transfer-Encoding": {"chunked"},
+		"Upgrade":           {"h2c"},
+		"X-Hop":             {"1"},
+		"X-End-To-End":      {"1"},
+	}
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:       http.StatusOK,
+			Header:           hopByHop.Clone(),
+			TransferEncoding: []string{"chunked"},
+			ContentLength:    -1,
+			Body:             ioutil.NopCloser(strings.NewReader("content")),
+			Request:          req,
+		}, nil
+	})
+
+	check := func(t *testing.T, res *http.Response) {
+		for _, field := range []string{"Connection", "Keep-Alive", "Transfer-Encoding", "Upgrade", "X-Hop"} {
+			if res.Header.Get(field) != "" {
+				t.Error("expected", field, "to be removed", res.Header)
+			}
+		}
+		if res.Header.Get("X-End-To-End") != "1" || res.TransferEncoding != nil {
+			t.Error("unexpected replayed response", res.Header, res.TransferEncoding)
+		}
+		if body, _ := ioutil.ReadAll(res.Body); string(body) != "content" {
+			t.Error("unexpected body", string(body))
+		}
+	}
+
+	t.Run("stored", func(t *testing.T) {
+		cache := NewMapCache()
+		client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: fallback}}
+		res, err := client.Get("http://example.com/")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if res.Header.Get("X-Hop") == "" {
+			t.Error("expected the origin response to be unchanged")
+		}
+		_, _ = ioutil.ReadAll(res.Body)
+
+		stored, err := cache.Get(res.Request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		check(t, stored)
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		cache := NewMapCache()
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		res, _ := fallback(req)
+		//entries stored before hop-by-hop fields were removed on store
+		if err := cache.Set(req, res); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: fallback}}
+		replayed, err := client.Get("http://example.com/")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		check(t, replayed)
+	})
+}
+
+func TestCachedTransport_RoundTrip_Age(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=600")
+		header.Set("Age", "30")
+		header.Set("Date", "yesterday")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("content")), Request: req}, nil
+	})
+	cache := NewMapCache()
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: fallback}}
+
+	res, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, _ = ioutil.ReadAll(res.Body)
+	if _, err := http.ParseTime(res.Header.Get("Date")); err != nil {
+		t.Error("expected the invalid Date to be replaced, got", res.Header.Get("Date"))
+	}
+
+	stored, err := cache.Get(res.Request)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	requested, err := time.Parse(time.RFC3339Nano, stored.Header.Get(policy.RequestTimeHeader))
+	if err != nil {
+		t.Error(err)
+	}
+	responded, err := time.Parse(time.RFC3339Nano, stored.Header.Get(policy.ResponseTimeHeader))
+	if err != nil {
+		t.Error(err)
+	}
+	if responded.Before(requested) || time.Since(requested) > time.Minute {
+		t.Error("unexpected clock", requested, responded)
+	}
+
+	//the stored response is 2 minutes older than when it was received
+	stored.Header.Set(policy.ResponseTimeHeader, responded.Add(-2*time.Minute).Format(time.RFC3339Nano))
+	stored.Header.Set(policy.RequestTimeHeader, requested.Add(-2*time.Minute).Format(time.RFC3339Nano))
+	if err := cache.Set(res.Request, stored); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	hit, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if age := hit.Header.Get("Age"); age != "150" {
+		t.Error("expected Age 150, got", age)
+	}
+	if hit.Header.Get(policy.RequestTimeHeader) != "" || hit.Header.Get(policy.ResponseTimeHeader) != "" {
+		t.Error("expected the clock to be removed", hit.Header)
+	}
+}
+
+func TestCachedTransport_RoundTrip_RequestDirectives(t *testing.T) {
+
+	counter := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		counter++
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		header.Set("Date", time.Now().Add(-30*time.Second).UTC().Format(http.TimeFormat))
+		if req.URL.Path == "/stale" {
+			header.Set("Cache-Control", "max-age=10")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(fmt.Sprint(counter))), Request: req}, nil
+	})
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback, StaleWhileRevalidate: time.Hour}
+	get := func(path string, header ...string) string {
+		req, _ := http.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		res, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		return string(body)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		header []string
+		want   string
+	}{
+		{"stored", "/fresh", nil, "1"},
+		{"fresh", "/fresh", []string{"Cache-Control", "max-age=120"}, "1"},
+		{"no-cache", "/fresh", []string{"Cache-Control", "no-cache"}, "2"},
+		{"pragma", "/fresh", []string{"Pragma", "no-cache"}, "3"},
+		{"max-age", "/fresh", []string{"Cache-Control", "max-age=10"}, "4"},
+		{"min-fresh", "/fresh", []string{"Cache-Control", "min-fresh=45"}, "5"},
+		{"same entry", "/fresh", nil, "5"},
+		{"no-store", "/fresh", []string{"Cache-Control", "no-store, no-cache"}, "6"},
+		{"not stored", "/fresh", nil, "5"},
+		{"stale stored", "/stale", []string{"Cache-Control", "no-cache"}, "7"},
+		{"max-stale exceeded", "/stale", []string{"Cache-Control", "max-stale=5, max-age=10"}, "8"},
+		{"max-stale", "/stale", []string{"Cache-Control", "max-stale=60"}, "8"},
+		{"only-if-cached max-stale", "/stale", []string{"Cache-Control", "only-if-cached, max-stale"}, "8"},
+	}
+	for _, test := range tests {
+		if got := get(test.path, test.header...); got != test.want {
+			t.Errorf("%s: expected %s got %s", test.name, test.want, got)
+		}
+	}
+	if counter != 8 {
+		t.Error("expected max-stale not to refresh the stale response got", counter)
+	}
+}
@@ -0,0 +1,87 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+//GenerationHeader is the line the generation is mixed into the keys with, keys keep the request dump format
+const GenerationHeader = "X-Cache-Generation"
+
+//Generations holds the generation mixed into the keys of a cache. Changing the generation busts the whole logical
+//cache at once without deleting anything, setting the previous generation again rolls the change back. The entries of
+//other generations stay in the cache until they are evicted or deleted
+type Generations struct {
+	//Namespace returns the namespace of req, its host if nil
+	Namespace func(req *http.Request) string
+
+	mutex      sync.RWMutex
+	global     string
+	namespaces map[string]string
+}
+
+//NewGenerations returns Generations with global as generation of all namespaces
+func NewGenerations(global string) *Generations {
+	return &Generations{global: global, namespaces: map[string]string{}}
+}
+
+//Set sets the generation of all namespaces without one of their own and returns the previous one
+func (g *Generations) Set(generation string) (previous string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	previous, g.global = g.global, generation
+	return previous
+}
+
+//SetNamespace sets the generation of namespace and returns the previous one, an empty generation makes the namespace
+//use the global generation again
+func (g *Generations) SetNamespace(namespace string, generation string) (previous string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	previous = g.namespaces[namespace]
+	if generation == "" {
+		delete(g.namespaces, namespace)
+		return previous
+	}
+	if g.namespaces == nil {
+		g.namespaces = map[string]string{}
+	}
+	g.namespaces[namespace] = generation
+	return previous
+}
+
+//Generation returns the generation of namespace
+func (g *Generations) Generation(namespace string) string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if generation, ok := g.namespaces[namespace]; ok {
+		return generation
+	}
+	return g.global
+}
+
+//generation returns the generation of req, a generation set with WithGeneration takes precedence
+func (g *Generations) generation(req *http.Request) string {
+	if generation, ok := generationFromContext(req.Context()); ok {
+		return generation
+	}
+	if g == nil {
+		return ""
+	}
+	if g.Namespace != nil {
+		return g.Generation(g.Namespace(req))
+	}
+	return g.Generation(requestHost(req))
+}
+
+//WithGeneration returns a context making requests using it read and write the entries of generation, regardless of
+//the Generations of the cache
+func WithGeneration(ctx context.Context, generation string) context.Context {
+	return context.WithValue(ctx, generationContextKey, generation)
+}
+
+func generationFromContext(ctx context.Context) (string, bool) {
+	generation, ok := ctx.Value(generationContextKey).(string)
+	return generation, ok
+}
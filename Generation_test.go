@@ -0,0 +1,74 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGenerations(t *testing.T) {
+
+	counter := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		counter++
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+	})
+	generations := NewGenerations("v41")
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{}), Generations: generations})
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: fallback}}
+	get := func(ctx context.Context, rawURL string) string {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		response, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		return string(body)
+	}
+	ctx := context.Background()
+
+	get(ctx, "http://example.com/a")
+	get(ctx, "http://other.com/a")
+	if previous := generations.Set("v42"); previous != "v41" {
+		t.Error("expected the previous generation got", previous)
+	}
+	if body := get(ctx, "http://example.com/a"); body != "3" {
+		t.Error("expected the new generation to bust the cache got", body)
+	}
+	if len(cache.Keys()) != 3 {
+		t.Error("expected the entries of the previous generation to stay got", len(cache.Keys()))
+	}
+	generations.Set("v41")
+	if body := get(ctx, "http://example.com/a"); body != "1" {
+		t.Error("expected the rollback to serve the previous generation got", body)
+	}
+
+	generations.SetNamespace("other.com", "v43")
+	if body := get(ctx, "http://example.com/a"); body != "1" {
+		t.Error("expected other namespaces to keep their generation got", body)
+	}
+	if body := get(ctx, "http://other.com/a"); body != "4" {
+		t.Error("expected the namespace generation to bust the namespace got", body)
+	}
+	if previous := generations.SetNamespace("other.com", ""); previous != "v43" || generations.Generation("other.com") != "v41" {
+		t.Error("expected the namespace to use the global generation again got", previous, generations.Generation("other.com"))
+	}
+	if body := get(WithGeneration(ctx, "v42"), "http://example.com/a"); body != "3" {
+		t.Error("expected the generation of the context got", body)
+	}
+
+	key, _ := cache.Key(lruTestRequest(t, "/a"))
+	if !strings.HasPrefix(key, "GET /a\r\n"+GenerationHeader+": v41\r\nHost: ") {
+		t.Error("expected the generation after the request line got", key)
+	}
+	summary := summarizeKey(key)
+	if summary.Host == "" || summary.Target != "/a" {
+		t.Error("expected the key to keep the request dump format got", summary)
+	}
+}
@@ -0,0 +1,78 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//Guardrails bound the caching the origin asks for, e.g. so a misconfigured max-age=31536000 does not keep a response
+//for a year. Stored responses get the bounded max-age, entries stored before are bounded when they are read. The TTLs
+//of WithTTL and Rules are not bounded, Offline serves stale responses regardless of MaxStale and MustRevalidate
+type Guardrails struct {
+	//MaxAge is the longest freshness lifetime of a response regardless of its headers, unbounded if 0
+	MaxAge time.Duration
+	//MinTTL is the shortest freshness lifetime of a cacheable response, e.g. to absorb the max-age=0 of an overloaded
+	//origin. Responses with no-cache are still revalidated on every request
+	MinTTL time.Duration
+	//MaxStale is the longest time a response is served stale by stale-while-revalidate, stale-if-error, the max-stale
+	//of requests, StaleOnDeadline, CircuitBreaker or ConnectionBackoff, unbounded if 0
+	MaxStale time.Duration
+	//MustRevalidate never serves stale responses without revalidating them, as if they all had must-revalidate
+	MustRevalidate bool
+}
+
+//response returns a copy of res with max-age set to its freshness lifetime bounded by MaxAge and MinTTL, res if it
+//is within the bounds
+func (g *Guardrails) response(res *http.Response, shared bool) *http.Response {
+
+	if g == nil || res == nil || g.MaxAge <= 0 && g.MinTTL <= 0 {
+		return res
+	}
+	lifetime := policy.FreshnessLifetime(res, shared)
+	//max-age has a resolution of seconds, the bounds are rounded towards each other
+	maxAge := g.MaxAge / time.Second * time.Second
+	minTTL := (g.MinTTL + time.Second - 1) / time.Second * time.Second
+
+	bounded := lifetime
+	if g.MaxAge > 0 && lifetime > maxAge {
+		bounded = maxAge
+	} else if g.MinTTL > 0 && lifetime < minTTL {
+		cc := policy.ParseCacheControl(res.Header)
+		if cc.Has("no-cache") && len(cc.Fields("no-cache")) == 0 {
+			return res
+		}
+		bounded = minTTL
+	}
+	if bounded == lifetime {
+		return res
+	}
+
+	guarded := *res
+	guarded.Header = res.Header.Clone()
+	if guarded.Header == nil {
+		guarded.Header = http.Header{}
+	}
+	setMaxAge(guarded.Header, int64(bounded/time.Second))
+	return &guarded
+}
+
+//allowsStale reports if the stale response res may be served for req at now
+func (g *Guardrails) allowsStale(req *http.Request, res *http.Response, shared bool, now time.Time) bool {
+
+	if g == nil {
+		return true
+	}
+	if g.MustRevalidate {
+		return false
+	}
+	if g.MaxStale <= 0 {
+		return true
+	}
+	lifetime := policy.FreshnessLifetime(res, shared)
+	if ttl, ok := ttlFromContext(req.Context()); ok {
+		lifetime = ttl
+	}
+	return policy.CurrentAge(res, now)-lifetime <= g.MaxStale
+}
@@ -0,0 +1,96 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_Guardrails(t *testing.T) {
+
+	type step struct {
+		advance  time.Duration
+		fail     bool
+		expected string
+	}
+	tests := []struct {
+		name         string
+		cacheControl string
+		guardrails   Guardrails
+		transport    CachedTransport
+		steps        []step
+	}{
+		{"max age", "max-age=31536000", Guardrails{MaxAge: time.Minute}, CachedTransport{},
+			[]step{{0, false, CacheMiss}, {59 * time.Second, false, CacheHit}, {2 * time.Second, false, CacheMiss}}},
+		{"immutable", "max-age=600, immutable", Guardrails{MaxAge: time.Minute}, CachedTransport{},
+			[]step{{0, false, CacheMiss}, {2 * time.Minute, false, CacheMiss}}},
+		{"min ttl", "max-age=0", Guardrails{MinTTL: time.Minute}, CachedTransport{},
+			[]step{{0, false, CacheMiss}, {30 * time.Second, false, CacheHit}, {31 * time.Second, false, CacheMiss}}},
+		{"min ttl no-cache", "no-cache", Guardrails{MinTTL: time.Minute}, CachedTransport{},
+			[]step{{0, false, CacheMiss}, {time.Second, false, CacheMiss}}},
+		{"max stale", "max-age=60", Guardrails{MaxStale: time.Minute}, CachedTransport{StaleIfError: time.Hour},
+			[]step{{0, false, CacheMiss}, {90 * time.Second, true, CacheStale}, {time.Minute, true, ""}}},
+		{"must revalidate", "max-age=60", Guardrails{MustRevalidate: true}, CachedTransport{StaleWhileRevalidate: time.Hour},
+			[]step{{0, false, CacheMiss}, {90 * time.Second, false, CacheMiss}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			fail := false
+			clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+			transport := tt.transport
+			transport.Cache, transport.Clock, transport.StatusHeaders = NewMapCache(), clock, true
+			transport.Guardrails = &tt.guardrails
+			transport.Fallback = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if fail {
+					return nil, errors.New("origin down")
+				}
+				res := lruTestResponse("content")
+				res.Header.Set("Cache-Control", tt.cacheControl)
+				res.Header.Set("Date", clock.Now().Format(http.TimeFormat))
+				return res, nil
+			})
+
+			for i, step := range tt.steps {
+				clock.Advance(step.advance)
+				fail = step.fail
+				res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+				if step.expected == "" {
+					if err == nil {
+						t.Errorf("step %d: expected the origin error, got %s", i, res.Header.Get(CacheStatusHeader))
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = res.Body.Close()
+				if status := res.Header.Get(CacheStatusHeader); status != step.expected {
+					t.Errorf("step %d: expected %s, got %s", i, step.expected, status)
+				}
+				if step.expected == CacheMiss && res.Header.Get("Cache-Control") != tt.cacheControl {
+					t.Errorf("step %d: expected the origin headers for the caller, got %s", i, res.Header.Get("Cache-Control"))
+				}
+			}
+		})
+	}
+}
+
+func TestGuardrails_response(t *testing.T) {
+
+	res := lruTestResponse("content")
+	res.Header.Set("Cache-Control", "public, s-maxage=86400, max-age=3600")
+	res.Header.Set("Expires", "Thu, 01 Jan 2099 00:00:00 GMT")
+	guarded := (&Guardrails{MaxAge: 90 * time.Second}).response(res, true)
+	if cc := guarded.Header.Get("Cache-Control"); cc != "public, max-age=90" || guarded.Header.Get("Expires") != "" {
+		t.Error("expected the lifetime to be bounded, got", cc, guarded.Header.Get("Expires"))
+	}
+	if res.Header.Get("Cache-Control") != "public, s-maxage=86400, max-age=3600" {
+		t.Error("expected the response to be unchanged, got", res.Header.Get("Cache-Control"))
+	}
+	if (&Guardrails{MaxAge: time.Hour}).response(guarded, true) != guarded {
+		t.Error("expected a response within the bounds to be returned as is")
+	}
+}
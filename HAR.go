@@ -0,0 +1,338 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//harVersion is the version of the HTTP Archive format written by ExportHAR
+const harVersion = "1.2"
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+//ExportHAR writes the entries of cache as HTTP Archive 1.2 to w, e.g. to inspect them in browser devtools. The
+//request of an entry is taken from the stored response or parsed from the key, keys have to be in the request dump
+//format of MapCache or NewKeyFunc then and their scheme is assumed to be https
+func ExportHAR(w io.Writer, cache Inspector) error {
+
+	har := harFile{Log: harLog{Version: harVersion, Creator: harCreator{Name: "CachedHttpClient-Go", Version: harVersion}, Entries: []harEntry{}}}
+
+	for _, key := range cache.Keys() {
+		res, err := cache.GetKey(key)
+		if err == NotInCacheError {
+			//the entry was deleted since listing the keys
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		req, err := harKeyRequest(key, res)
+		if err != nil {
+			return err
+		}
+		entry, err := newHAREntry(req, res)
+		if err != nil {
+			return err
+		}
+		har.Log.Entries = append(har.Log.Entries, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(har)
+}
+
+//harKeyRequest returns the request stored with res or the request parsed from key
+func harKeyRequest(key string, res *http.Response) (*http.Request, error) {
+
+	if res.Request != nil && res.Request.URL != nil && res.Request.URL.Host != "" {
+		return res.Request, nil
+	}
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(key)))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Scheme = "https"
+	req.URL.Host = req.Host
+	return req, nil
+}
+
+func newHAREntry(req *http.Request, res *http.Response) (harEntry, error) {
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return harEntry{}, err
+	}
+	started := time.Now()
+	if date, err := http.ParseTime(res.Header.Get("Date")); err == nil {
+		started = date
+	}
+
+	entry := harEntry{
+		StartedDateTime: started.UTC().Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: harProto(req.Proto),
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: harQuery(req.URL.Query()),
+			HeadersSize: -1,
+			BodySize:    0,
+		},
+		Response: harResponse{
+			Status:      res.StatusCode,
+			StatusText:  strings.TrimSpace(strings.TrimPrefix(res.Status, strconv.Itoa(res.StatusCode))),
+			HTTPVersion: harProto(res.Proto),
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(res.Header),
+			Content:     harBody(res.Header.Get("Content-Type"), body),
+			RedirectURL: res.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(body),
+		},
+	}
+
+	if req.GetBody != nil {
+		reqBody, err := req.GetBody()
+		if err != nil {
+			return harEntry{}, err
+		}
+		text, err := ioutil.ReadAll(reqBody)
+		if err != nil {
+			return harEntry{}, err
+		}
+		entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(text)}
+		entry.Request.BodySize = len(text)
+	}
+	return entry, nil
+}
+
+func harProto(proto string) string {
+	if proto == "" {
+		return "HTTP/1.1"
+	}
+	return proto
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := []harNameValue{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+func harQuery(query url.Values) []harNameValue {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := []harNameValue{}
+	for _, name := range names {
+		for _, value := range query[name] {
+			values = append(values, harNameValue{Name: name, Value: value})
+		}
+	}
+	return values
+}
+
+//harBody stores body as text if it is valid UTF-8 and base64 encoded otherwise
+func harBody(contentType string, body []byte) harContent {
+	content := harContent{Size: len(body), MimeType: contentType}
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+//ImportHAR stores the responses of a HTTP Archive, e.g. recorded by browser devtools, in cache and returns their
+//number. Entries without response like blocked requests are skipped. The bodies of a HAR are decoded so
+//Content-Encoding and Content-Length are removed, as are the HTTP/2 pseudo headers
+func ImportHAR(r io.Reader, cache Cacher) (int, error) {
+
+	var har harFile
+	err := json.NewDecoder(r).Decode(&har)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, entry := range har.Log.Entries {
+		if entry.Response.Status == 0 {
+			continue
+		}
+		req, err := entry.Request.parse()
+		if err != nil {
+			return imported, err
+		}
+		res, err := entry.Response.parse(req)
+		if err != nil {
+			return imported, err
+		}
+		err = cache.Set(req, res)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+func (request harRequest) parse() (*http.Request, error) {
+
+	var body io.Reader
+	if request.PostData != nil {
+		body = strings.NewReader(request.PostData.Text)
+	}
+	req, err := http.NewRequest(request.Method, request.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = parseHARHeaders(request.Headers)
+	if req.Header.Get("Host") != "" {
+		req.Host = req.Header.Get("Host")
+		req.Header.Del("Host")
+	}
+	return req, nil
+}
+
+func (response harResponse) parse(req *http.Request) (*http.Response, error) {
+
+	body := []byte(response.Content.Text)
+	if response.Content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(response.Content.Text)
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+
+	header := parseHARHeaders(response.Headers)
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	major, minor, ok := http.ParseHTTPVersion(strings.ToUpper(response.HTTPVersion))
+	if !ok {
+		major, minor = 1, 1
+	}
+	statusText := response.StatusText
+	if statusText == "" {
+		statusText = http.StatusText(response.Status)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(response.Status) + " " + statusText,
+		StatusCode:    response.Status,
+		Proto:         "HTTP/" + strconv.Itoa(major) + "." + strconv.Itoa(minor),
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+//parseHARHeaders converts the headers of a HAR skipping HTTP/2 pseudo headers like :authority
+func parseHARHeaders(headers []harNameValue) http.Header {
+	header := http.Header{}
+	for _, field := range headers {
+		if strings.HasPrefix(field.Name, ":") {
+			continue
+		}
+		header.Add(field.Name, field.Value)
+	}
+	return header
+}
@@ -0,0 +1,131 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+//devtoolsHAR is shaped like a HAR saved by Chrome devtools: HTTP/2 pseudo headers, decoded bodies with their original
+//Content-Encoding, a base64 encoded binary body and a blocked request without response
+const devtoolsHAR = `{"log": {"version": "1.2", "creator": {"name": "WebInspector", "version": "537.36"}, "entries": [
+  {"startedDateTime": "2024-01-01T00:00:00.000Z", "time": 12.5,
+   "request": {"method": "GET", "url": "https://example.com/api?page=2", "httpVersion": "http/2.0",
+     "headers": [{"name": ":authority", "value": "example.com"}, {"name": "accept", "value": "application/json"}],
+     "queryString": [{"name": "page", "value": "2"}], "cookies": [], "headersSize": -1, "bodySize": 0},
+   "response": {"status": 200, "statusText": "", "httpVersion": "http/2.0",
+     "headers": [{"name": "content-type", "value": "application/json"}, {"name": "content-encoding", "value": "gzip"},
+       {"name": "content-length", "value": "20"}, {"name": "cache-control", "value": "max-age=60"}],
+     "content": {"size": 11, "mimeType": "application/json", "text": "{\"page\":2}"},
+     "cookies": [], "redirectURL": "", "headersSize": -1, "bodySize": 20},
+   "cache": {}, "timings": {"send": 0, "wait": 10, "receive": 2.5}},
+  {"startedDateTime": "2024-01-01T00:00:01.000Z", "time": 3,
+   "request": {"method": "GET", "url": "https://example.com/logo.png", "httpVersion": "http/2.0",
+     "headers": [], "queryString": [], "cookies": [], "headersSize": -1, "bodySize": 0},
+   "response": {"status": 200, "statusText": "OK", "httpVersion": "http/2.0",
+     "headers": [{"name": "content-type", "value": "image/png"}],
+     "content": {"size": 4, "mimeType": "image/png", "text": "iVBORw==", "encoding": "base64"},
+     "cookies": [], "redirectURL": "", "headersSize": -1, "bodySize": 4},
+   "cache": {}, "timings": {"send": 0, "wait": 2, "receive": 1}},
+  {"startedDateTime": "2024-01-01T00:00:02.000Z", "time": 0,
+   "request": {"method": "GET", "url": "https://ads.example.com/", "httpVersion": "",
+     "headers": [], "queryString": [], "cookies": [], "headersSize": -1, "bodySize": 0},
+   "response": {"status": 0, "statusText": "", "httpVersion": "", "headers": [],
+     "content": {"size": 0, "mimeType": ""}, "cookies": [], "redirectURL": "", "headersSize": -1, "bodySize": -1},
+   "cache": {}, "timings": {"send": 0, "wait": 0, "receive": 0}}
+]}}`
+
+func TestImportHAR(t *testing.T) {
+
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})})
+	imported, err := ImportHAR(strings.NewReader(devtoolsHAR), cache)
+	if err != nil || imported != 2 {
+		t.Error("expected 2 imported entries got", imported, err)
+		t.FailNow()
+	}
+
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Offline: true}}
+
+	tests := []struct {
+		url         string
+		body        []byte
+		contentType string
+	}{
+		{"https://example.com/api?page=2", []byte(`{"page":2}`), "application/json"},
+		{"https://example.com/logo.png", []byte{0x89, 'P', 'N', 'G'}, "image/png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			response, err := client.Get(tt.url)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, _ := ioutil.ReadAll(response.Body)
+			if !bytes.Equal(body, tt.body) {
+				t.Errorf("expected the body %q got %q", tt.body, body)
+			}
+			if response.Header.Get("Content-Type") != tt.contentType || response.Header.Get("Content-Encoding") != "" {
+				t.Error("expected the decoded headers got", response.Header)
+			}
+			if response.ProtoMajor != 2 {
+				t.Error("expected HTTP/2 got", response.Proto)
+			}
+		})
+	}
+}
+
+func TestExportHAR(t *testing.T) {
+
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})})
+	if _, err := ImportHAR(strings.NewReader(devtoolsHAR), cache); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	var export bytes.Buffer
+	if err := ExportHAR(&export, cache); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	var har harFile
+	if err := json.Unmarshal(export.Bytes(), &har); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 2 {
+		t.Error("expected a HAR 1.2 with 2 entries got", har.Log.Version, len(har.Log.Entries))
+	}
+	for _, entry := range har.Log.Entries {
+		if entry.Request.URL == "https://example.com/logo.png" && entry.Response.Content.Encoding != "base64" {
+			t.Error("expected the binary body to be base64 encoded got", entry.Response.Content)
+		}
+	}
+
+	reimported := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})})
+	if _, err := ImportHAR(bytes.NewReader(export.Bytes()), reimported); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	assertSameEntries(t, cache, reimported)
+
+	//entries loaded from a cache file have no request, it is parsed from the key
+	loaded := NewMapCache()
+	request, _ := http.NewRequest("GET", "http://example.com/loaded", nil)
+	if err := loaded.Set(request, &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader("loaded"))}); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	export.Reset()
+	if err := ExportHAR(&export, loaded); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !strings.Contains(export.String(), `"url": "https://example.com/loaded"`) {
+		t.Error("expected the URL parsed from the key in", export.String())
+	}
+}
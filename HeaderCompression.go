@@ -0,0 +1,228 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"sort"
+)
+
+//InvalidCompressedHeaderError is returned for a JsonResponse.CompressedHeader which can not be decoded
+var InvalidCompressedHeaderError = errors.New("invalid compressed header")
+
+//staticHeaderFields are the header fields encoded as a single byte, like the static table of HPACK. Entries are only
+//ever appended so stored entries stay readable, there is room for 128
+var staticHeaderFields = [][2]string{
+	{"Accept-Ranges", "bytes"},
+	{"Access-Control-Allow-Credentials", "true"},
+	{"Access-Control-Allow-Origin", "*"},
+	{"Cache-Control", "max-age=0"},
+	{"Cache-Control", "must-revalidate"},
+	{"Cache-Control", "no-cache"},
+	{"Cache-Control", "no-store"},
+	{"Cache-Control", "private"},
+	{"Cache-Control", "public"},
+	{"Cache-Control", "public, max-age=31536000, immutable"},
+	{"Connection", "close"},
+	{"Connection", "keep-alive"},
+	{"Content-Encoding", "br"},
+	{"Content-Encoding", "gzip"},
+	{"Content-Length", "0"},
+	{"Content-Type", "application/javascript"},
+	{"Content-Type", "application/javascript; charset=utf-8"},
+	{"Content-Type", "application/json"},
+	{"Content-Type", "application/json; charset=utf-8"},
+	{"Content-Type", "application/octet-stream"},
+	{"Content-Type", "application/problem+json"},
+	{"Content-Type", "application/xml"},
+	{"Content-Type", "image/gif"},
+	{"Content-Type", "image/jpeg"},
+	{"Content-Type", "image/png"},
+	{"Content-Type", "image/svg+xml"},
+	{"Content-Type", "image/webp"},
+	{"Content-Type", "text/css"},
+	{"Content-Type", "text/css; charset=utf-8"},
+	{"Content-Type", "text/html"},
+	{"Content-Type", "text/html; charset=utf-8"},
+	{"Content-Type", "text/javascript"},
+	{"Content-Type", "text/plain"},
+	{"Content-Type", "text/plain; charset=utf-8"},
+	{"Content-Type", "text/xml"},
+	{"Cross-Origin-Resource-Policy", "same-origin"},
+	{"Pragma", "no-cache"},
+	{"Referrer-Policy", "no-referrer"},
+	{"Referrer-Policy", "strict-origin-when-cross-origin"},
+	{"Server", "nginx"},
+	{"Server", "cloudflare"},
+	{"Strict-Transport-Security", "max-age=31536000"},
+	{"Strict-Transport-Security", "max-age=31536000; includeSubDomains"},
+	{"Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload"},
+	{"Transfer-Encoding", "chunked"},
+	{"Vary", "Accept-Encoding"},
+	{"Vary", "Accept"},
+	{"Vary", "Origin"},
+	{"Vary", "Accept-Encoding, Origin"},
+	{"X-Content-Type-Options", "nosniff"},
+	{"X-Frame-Options", "DENY"},
+	{"X-Frame-Options", "SAMEORIGIN"},
+	{"X-Xss-Protection", "0"},
+	{"X-Xss-Protection", "1; mode=block"},
+}
+
+//staticHeaderNames are the header names encoded as a single byte followed by the value. Entries are only ever
+//appended, there is room for 64
+var staticHeaderNames = []string{
+	"Accept-Ranges",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Origin",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Max-Age",
+	"Age",
+	"Alt-Svc",
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Length",
+	"Content-Location",
+	"Content-Range",
+	"Content-Security-Policy",
+	"Content-Type",
+	"Date",
+	"Etag",
+	"Expires",
+	"Last-Modified",
+	"Link",
+	"Location",
+	"Referrer-Policy",
+	"Retry-After",
+	"Server",
+	"Set-Cookie",
+	"Strict-Transport-Security",
+	"Vary",
+	"Via",
+	"Www-Authenticate",
+	"X-Cache",
+	"X-Powered-By",
+	"X-Request-Id",
+}
+
+//header field representations, the low bits of the first byte hold the index into the static tables
+const (
+	literalHeaderField = 0x00
+	indexedHeaderName  = 0x40
+	indexedHeaderField = 0x80
+)
+
+var staticHeaderFieldIndex, staticHeaderNameIndex = func() (map[[2]string]int, map[string]int) {
+	fields := map[[2]string]int{}
+	for i, field := range staticHeaderFields {
+		fields[field] = i
+	}
+	names := map[string]int{}
+	for i, name := range staticHeaderNames {
+		names[name] = i
+	}
+	return fields, names
+}()
+
+//encodeHeader encodes header with the static tables, the fields are written in the order of their names
+func encodeHeader(header http.Header) []byte {
+
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		for _, value := range header[name] {
+			if i, ok := staticHeaderFieldIndex[[2]string{name, value}]; ok {
+				buf.WriteByte(indexedHeaderField | byte(i))
+				continue
+			}
+			if i, ok := staticHeaderNameIndex[name]; ok {
+				buf.WriteByte(indexedHeaderName | byte(i))
+			} else {
+				buf.WriteByte(literalHeaderField)
+				writeHeaderString(&buf, name)
+			}
+			writeHeaderString(&buf, value)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeHeaderString(buf *bytes.Buffer, s string) {
+	length := make([]byte, binary.MaxVarintLen64)
+	buf.Write(length[:binary.PutUvarint(length, uint64(len(s)))])
+	buf.WriteString(s)
+}
+
+//decodeHeader decodes the header fields encoded by encodeHeader
+func decodeHeader(encoded []byte) (http.Header, error) {
+
+	header := http.Header{}
+	reader := bytes.NewReader(encoded)
+	for reader.Len() > 0 {
+		representation, _ := reader.ReadByte()
+		var name, value string
+		var err error
+		switch {
+		case representation&indexedHeaderField != 0:
+			i := int(representation &^ indexedHeaderField)
+			if i >= len(staticHeaderFields) {
+				return nil, InvalidCompressedHeaderError
+			}
+			header[staticHeaderFields[i][0]] = append(header[staticHeaderFields[i][0]], staticHeaderFields[i][1])
+			continue
+		case representation&indexedHeaderName != 0:
+			i := int(representation &^ indexedHeaderName)
+			if i >= len(staticHeaderNames) {
+				return nil, InvalidCompressedHeaderError
+			}
+			name = staticHeaderNames[i]
+		case representation == literalHeaderField:
+			if name, err = readHeaderString(reader); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, InvalidCompressedHeaderError
+		}
+		if value, err = readHeaderString(reader); err != nil {
+			return nil, err
+		}
+		header[name] = append(header[name], value)
+	}
+	return header, nil
+}
+
+func readHeaderString(reader *bytes.Reader) (string, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil || length > uint64(reader.Len()) {
+		return "", InvalidCompressedHeaderError
+	}
+	s := make([]byte, length)
+	_, _ = reader.Read(s)
+	return string(s), nil
+}
+
+//compressHeader replaces the header fields of response with their encoded form
+func compressHeader(response *JsonResponse) {
+	if response.CompressedHeader != nil || response.Header == nil {
+		return
+	}
+	response.CompressedHeader = encodeHeader(response.Header)
+	response.Header = nil
+}
+
+//decompressHeader returns a copy of the header fields of response in their original form
+func decompressHeader(response *JsonResponse) (http.Header, error) {
+	if response.CompressedHeader == nil {
+		return cloneHeader(response.Header), nil
+	}
+	return decodeHeader(response.CompressedHeader)
+}
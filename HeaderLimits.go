@@ -0,0 +1,100 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+//HeaderTruncatedHeader is set on stored responses whose header fields were removed by HeaderLimits, it holds the
+//number of removed fields
+const HeaderTruncatedHeader = "X-Cache-Header-Truncated"
+
+//HeaderLimits caps the header of stored responses, so a single response with a huge header can not bloat the entries of
+//the cache or exceed the value size of a backend like memcached. The limits apply to the header after the hop-by-hop
+//and noise fields are removed
+type HeaderLimits struct {
+	//MaxBytes limits the size of the header as written on the wire, "Name: value\r\n" per value, 0 means no limit
+	MaxBytes int
+	//MaxFields limits the number of values, 0 means no limit
+	MaxFields int
+	//Truncate stores responses exceeding a limit without the fields which do not fit, in the order of their names,
+	//and with HeaderTruncatedHeader. Otherwise they are not stored. The fields in essentialHeaderFields are never
+	//removed, responses exceeding a limit with them alone are not stored
+	Truncate bool
+}
+
+//essentialHeaderFields decide how a stored response is served, removing them would change the caching semantics
+var essentialHeaderFields = []string{"Age", "Cache-Control", "Content-Encoding", "Content-Length", "Content-Range",
+	"Content-Type", "Date", "Etag", "Expires", "Last-Modified", "Pragma", "Vary"}
+
+//limit returns stored or a copy of it without the fields removed to stay within the limits, ok is false if stored is
+//not stored
+func (l *HeaderLimits) limit(stored *http.Response) (res *http.Response, ok bool) {
+
+	if l == nil {
+		return stored, true
+	}
+	bytes, fields := headerSize(stored.Header)
+	if l.within(bytes, fields) {
+		return stored, true
+	}
+	if !l.Truncate {
+		return stored, false
+	}
+
+	header := http.Header{}
+	essential := map[string]bool{}
+	for _, field := range essentialHeaderFields {
+		if values, ok := stored.Header[field]; ok {
+			header[field] = values
+			essential[field] = true
+		}
+	}
+	names := make([]string, 0, len(stored.Header))
+	for name := range stored.Header {
+		if !essential[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	//the marker is part of the limited header, room is made for it up front
+	marker := http.Header{HeaderTruncatedHeader: {strconv.Itoa(len(names))}}
+	bytes, fields = headerSize(header)
+	markerBytes, markerFields := headerSize(marker)
+	bytes, fields = bytes+markerBytes, fields+markerFields
+	if !l.within(bytes, fields) {
+		return stored, false
+	}
+	removed := 0
+	for _, name := range names {
+		fieldBytes, fieldCount := headerSize(http.Header{name: stored.Header[name]})
+		if !l.within(bytes+fieldBytes, fields+fieldCount) {
+			removed++
+			continue
+		}
+		header[name] = stored.Header[name]
+		bytes, fields = bytes+fieldBytes, fields+fieldCount
+	}
+
+	limited := *stored
+	limited.Header = header.Clone()
+	limited.Header.Set(HeaderTruncatedHeader, strconv.Itoa(removed))
+	return &limited, true
+}
+
+func (l *HeaderLimits) within(bytes int, fields int) bool {
+	return (l.MaxBytes <= 0 || bytes <= l.MaxBytes) && (l.MaxFields <= 0 || fields <= l.MaxFields)
+}
+
+//headerSize returns the size of header on the wire and the number of its values
+func headerSize(header http.Header) (bytes int, fields int) {
+	for name, values := range header {
+		for _, value := range values {
+			bytes += len(name) + len(value) + len(": \r\n")
+			fields++
+		}
+	}
+	return bytes, fields
+}
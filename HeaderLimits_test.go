@@ -0,0 +1,103 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeaderLimits_limit(t *testing.T) {
+
+	header := http.Header{
+		"Cache-Control": {"max-age=60"},
+		"A":             {"1"},
+		"B":             {"22", strings.Repeat("2", 20)},
+		"C":             {"3"},
+	}
+
+	tests := []struct {
+		name     string
+		limits   *HeaderLimits
+		ok       bool
+		expected http.Header
+	}{
+		{"nil", nil, true, header},
+		{"within", &HeaderLimits{MaxFields: 5, MaxBytes: 1000}, true, header},
+		{"refused", &HeaderLimits{MaxFields: 4}, false, nil},
+		{"fields", &HeaderLimits{MaxFields: 4, Truncate: true}, true, http.Header{
+			"Cache-Control":       {"max-age=60"},
+			"A":                   {"1"},
+			"C":                   {"3"},
+			HeaderTruncatedHeader: {"1"},
+		}},
+		//"Cache-Control: max-age=60\r\n" and "X-Cache-Header-Truncated: 3\r\n" take 56 bytes, "A: 1\r\n" 6
+		{"bytes", &HeaderLimits{MaxBytes: 62, Truncate: true}, true, http.Header{
+			"Cache-Control":       {"max-age=60"},
+			"A":                   {"1"},
+			HeaderTruncatedHeader: {"2"},
+		}},
+		{"essential fields exceed", &HeaderLimits{MaxFields: 1, Truncate: true}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &http.Response{StatusCode: http.StatusOK, Header: header}
+			limited, ok := tt.limits.limit(stored)
+			if ok != tt.ok {
+				t.Errorf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(limited.Header, tt.expected) {
+				t.Errorf("expected %v got %v", tt.expected, limited.Header)
+			}
+			if len(header) != 4 {
+				t.Error("the header of the stored response was modified", header)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_HeaderLimits(t *testing.T) {
+
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		header.Set("X-Padding", strings.Repeat("x", 100))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+
+	for _, truncate := range []bool{false, true} {
+		cache := NewMapCache()
+		client := http.Client{Transport: &CachedTransport{
+			Cache:        cache,
+			Fallback:     origin,
+			HeaderLimits: &HeaderLimits{MaxBytes: 100, Truncate: truncate},
+		}}
+		response, err := client.Get("http://example.com/a")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if string(body) != "body" || response.Header.Get("X-Padding") == "" {
+			t.Error("expected the caller to get the complete response, truncate", truncate)
+		}
+
+		stored, err := cache.Get(lruTestRequest(t, "/a"))
+		if !truncate {
+			if err == nil {
+				t.Error("expected the response not to be stored")
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if stored.Header.Get("X-Padding") != "" || stored.Header.Get(HeaderTruncatedHeader) != "1" {
+			t.Error("expected the stored response to be truncated, got", stored.Header)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"time"
+)
+
+//Event describes a cache decision passed to Hooks
+type Event struct {
+	//Request is the request as used for the cache, after URLRewrites, HostAliases and NoiseHeaders were applied
+	Request *http.Request
+	//Key is the key of the request if the cache implements Keyer
+	Key string
+	//Response is the response served, received or stored, it is nil for errors and evictions. Its body must not be read
+	Response *http.Response
+	//Stale is set for hits on stale responses served within stale-while-revalidate or stale-if-error
+	Stale bool
+	//Err is the error of OnError events
+	Err error
+	//Start is the time the operation started and Duration the time it took: the cache lookup for hits, the origin
+	//request for misses and revalidations and Cacher.Set for stores
+	Start    time.Time
+	Duration time.Duration
+}
+
+//Hooks are called on the decisions of a CachedTransport, e.g. to log them. Hooks which are nil are not called,
+//the hooks are called synchronously and must be safe for concurrent use
+type Hooks struct {
+	//OnHit is called when a response is served from the cache
+	OnHit func(event Event)
+	//OnMiss is called when a request was sent to the origin unconditionally
+	OnMiss func(event Event)
+	//OnRevalidate is called when a conditional request was sent to the origin for a stale response
+	OnRevalidate func(event Event)
+	//OnStore is called when a response was stored
+	OnStore func(event Event)
+	//OnEvict is called by Evicted with the key of the evicted entry
+	OnEvict func(event Event)
+	//OnError is called when the cache lookup, the origin request or storing a response failed
+	OnError func(event Event)
+}
+
+//Evicted calls OnEvict, it matches LRUCacheOptions.OnEvict
+func (h *Hooks) Evicted(key string) {
+	if h == nil || h.OnEvict == nil {
+		return
+	}
+	h.OnEvict(Event{Key: key, Start: time.Now()})
+}
+
+//call calls hook with the event for req completed by the key and the duration since start
+func (h *Hooks) call(hook func(event Event), cache Cacher, event Event) {
+	if h == nil || hook == nil {
+		return
+	}
+	event.Duration = time.Since(event.Start)
+	if keyer, ok := cache.(Keyer); ok && event.Request != nil {
+		if key, err := keyer.Key(event.Request); err == nil {
+			event.Key = key
+		}
+	}
+	hook(event)
+}
+
+func (h *Hooks) hit(cache Cacher, req *http.Request, res *http.Response, stale bool, start time.Time) {
+	if h != nil {
+		h.call(h.OnHit, cache, Event{Request: req, Response: res, Stale: stale, Start: start})
+	}
+}
+
+//originResponse calls OnRevalidate or OnMiss for the outcome of an origin request and OnError if it failed
+func (h *Hooks) originResponse(cache Cacher, req *http.Request, res *http.Response, err error, conditional bool, start time.Time) {
+	if h == nil {
+		return
+	}
+	event := Event{Request: req, Response: res, Err: err, Start: start}
+	if conditional {
+		h.call(h.OnRevalidate, cache, event)
+	} else {
+		h.call(h.OnMiss, cache, event)
+	}
+	if err != nil {
+		h.call(h.OnError, cache, event)
+	}
+}
+
+func (h *Hooks) store(cache Cacher, req *http.Request, res *http.Response, err error, start time.Time) {
+	if h == nil {
+		return
+	}
+	if err != nil {
+		h.call(h.OnError, cache, Event{Request: req, Err: err, Start: start})
+		return
+	}
+	h.call(h.OnStore, cache, Event{Request: req, Response: res, Start: start})
+}
+
+func (h *Hooks) error(cache Cacher, req *http.Request, err error, start time.Time) {
+	if h != nil {
+		h.call(h.OnError, cache, Event{Request: req, Err: err, Start: start})
+	}
+}
@@ -0,0 +1,86 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_Hooks(t *testing.T) {
+
+	var events []string
+	record := func(name string) func(event Event) {
+		return func(event Event) {
+			if event.Start.IsZero() || event.Duration < 0 {
+				t.Error("expected the timing of", name, "to be set")
+			}
+			if name == "error" {
+				events = append(events, name+" "+event.Err.Error())
+				return
+			}
+			//the keys are requests dumps starting with the request line
+			events = append(events, name+" "+strings.SplitN(event.Key, "\r\n", 2)[0])
+		}
+	}
+	hooks := &Hooks{
+		OnHit:        record("hit"),
+		OnMiss:       record("miss"),
+		OnRevalidate: record("revalidate"),
+		OnStore:      record("store"),
+		OnEvict:      record("evict"),
+		OnError:      record("error"),
+	}
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/error" {
+			return nil, errors.New("origin failed")
+		}
+		header := http.Header{}
+		header.Set("ETag", `"1"`)
+		header.Set("Cache-Control", "max-age=60")
+		if req.URL.Path == "/b" {
+			header.Set("Cache-Control", "max-age=0")
+		}
+		if req.Header.Get("If-None-Match") == `"1"` {
+			return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello")), Request: req}, nil
+	})
+
+	client := http.Client{Transport: &CachedTransport{
+		Cache:    NewLRUCache(LRUCacheOptions{MapCacheOptions: MapCacheOptions{DontIncludeAllRequestHeaders: true}, MaxEntries: 1, OnEvict: hooks.Evicted}),
+		Fallback: fallback,
+		Hooks:    hooks,
+	}}
+
+	for _, path := range []string{"/a", "/a", "/b", "/b", "/error"} {
+		response, err := client.Get("http://example.com" + path)
+		if err != nil {
+			continue
+		}
+		_, _ = ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+	}
+
+	expected := []string{
+		"miss GET /a HTTP/1.1",
+		"store GET /a HTTP/1.1",
+		"hit GET /a HTTP/1.1",
+		"miss GET /b HTTP/1.1",
+		"evict GET /a HTTP/1.1",
+		"store GET /b HTTP/1.1",
+		"revalidate GET /b HTTP/1.1",
+		"store GET /b HTTP/1.1",
+		"miss GET /error HTTP/1.1",
+		"error origin failed",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events\n%q\ngot\n%q", expected, events)
+	}
+
+	var nilHooks *Hooks
+	nilHooks.Evicted("key")
+}
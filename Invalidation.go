@@ -0,0 +1,372 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var InvalidationNotSupportedError = errors.New("the cache does not support invalidating entries")
+
+//UnknownKeyFormatError is returned by InvalidateURL and reported to Hooks.OnError after unsafe requests if the cache
+//holds keys which were not stored through the transport and are not in the request dump format of MapCache or
+//NewKeyFunc. Their entries can not be found by their URL
+var UnknownKeyFormatError = errors.New("keys in an unknown format can not be invalidated by URL")
+
+//Invalidate deletes the entry stored under key, the Cache has to implement Inspector. With SoftDelete the entry is
+//soft deleted
+func (c *CachedTransport) Invalidate(key string) error {
+	inspector, ok := c.Cache.(Inspector)
+	if !ok {
+		return InvalidationNotSupportedError
+	}
+	return c.deleteKey(inspector, key)
+}
+
+//deleteKey deletes or soft deletes the entry stored under key
+func (c *CachedTransport) deleteKey(inspector Inspector, key string) error {
+	var err error
+	if c.SoftDelete != nil {
+		err = c.softDeleteKey(inspector, key)
+	} else {
+		err = inspector.DeleteKey(key)
+	}
+	if err == nil {
+		c.Events.invalidated(key)
+		c.Variants.Evicted(key)
+	}
+	return err
+}
+
+//InvalidateURL deletes the entries stored for rawURL with any method and returns their number. URLRewrites and
+//HostAliases are applied to rawURL like to requests, if it has no query the entries for all queries of its path are
+//deleted. The entries are found by an index of their URLs built from the keys of the Cache on the first invalidation
+//and kept up to date by the responses stored afterwards, so keys of any format stored through the transport are
+//found. Keys listed by the Cache in another format than the request dump of MapCache or NewKeyFunc are reported by
+//UnknownKeyFormatError together with the number of deleted entries
+func (c *CachedTransport) InvalidateURL(rawURL string) (int, error) {
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, err
+	}
+	inspector, ok := iterableCache(c.Cache)
+	if !ok {
+		return 0, InvalidationNotSupportedError
+	}
+	index := c.urlIndex()
+	index.activate(inspector)
+	deleted, err := c.invalidateIndexed(inspector, index, c.keyURL(target))
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, index.unknownKeysError()
+}
+
+//invalidateIndexed deletes the entries index holds for target
+func (c *CachedTransport) invalidateIndexed(inspector Inspector, index *urlIndex, target *url.URL) (int, error) {
+	deleted := 0
+	for _, key := range index.lookup(target) {
+		err := c.deleteKey(inspector, key)
+		if errors.Is(err, NotInCacheError) {
+			//evicted or deleted by its key
+			index.remove(key)
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		index.remove(key)
+		deleted++
+	}
+	return deleted, nil
+}
+
+//InvalidateMatching deletes the entries whose key match returns true for and returns their number, with SoftDelete
+//they are soft deleted. It lists all keys of the Cache, which has to have IterationCapability
+func (c *CachedTransport) InvalidateMatching(match func(key string) bool) (int, error) {
+
+	inspector, ok := iterableCache(c.Cache)
+	if !ok {
+		return 0, InvalidationNotSupportedError
+	}
+
+	deleted := 0
+	for _, key := range inspector.Keys() {
+		if !match(key) {
+			continue
+		}
+		err := c.deleteKey(inspector, key)
+		if errors.Is(err, NotInCacheError) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+//keyURL returns the URL the entries for requests to u are stored for
+func (c *CachedTransport) keyURL(u *url.URL) *url.URL {
+	keyURL := *u
+	if host, ok := c.URLRewrites[keyURL.Host]; ok {
+		keyURL.Host = host
+	}
+	if host, ok := c.HostAliases[keyURL.Host]; ok {
+		keyURL.Host = host
+	}
+	return &keyURL
+}
+
+//keyMatchesURL reports if key is the request dump of a request for target
+func keyMatchesURL(key string, target *url.URL) bool {
+
+	summary := summarizeKey(key)
+	if summary.Host != target.Host {
+		return false
+	}
+	requestURI, err := url.ParseRequestURI(summary.Target)
+	if err != nil {
+		return false
+	}
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if requestURI.EscapedPath() != path {
+		return false
+	}
+	return target.RawQuery == "" || requestURI.Query().Encode() == target.Query().Encode()
+}
+
+//isSafeMethod reports if method is safe (RFC 7231 4.2.1), unsafe methods invalidate the cached responses
+func isSafeMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+//invalidateAfterUnsafe invalidates the entries for the URL of req and the URLs in the Location and Content-Location
+//header fields of res on the same host after an unsafe request succeeded (RFC 7234 4.4)
+func (c *CachedTransport) invalidateAfterUnsafe(req *http.Request, res *http.Response) {
+
+	if res.StatusCode < 200 || res.StatusCode >= 400 {
+		return
+	}
+
+	targets := []*url.URL{req.URL}
+	for _, field := range []string{"Location", "Content-Location"} {
+		value := res.Header.Get(field)
+		if value == "" {
+			continue
+		}
+		location, err := req.URL.Parse(value)
+		if err != nil || location.Host != req.URL.Host {
+			//invalidating other hosts would allow denial of service attacks (RFC 7234 4.4)
+			continue
+		}
+		targets = append(targets, location)
+	}
+
+	inspector, ok := iterableCache(c.Cache)
+	if !ok {
+		return
+	}
+	start := time.Now()
+	index := c.urlIndex()
+	if index.activate(inspector) {
+		if err := index.unknownKeysError(); err != nil {
+			//reported once per build of the index, not on every unsafe request
+			c.Hooks.error(c.Cache, req, err, start)
+		}
+	}
+	for _, target := range targets {
+		if _, err := c.invalidateIndexed(inspector, index, c.keyURL(target)); err != nil {
+			c.Hooks.error(c.Cache, req, err, start)
+		}
+	}
+}
+
+//minIndexPruning is the number of keys added to a urlIndex after which it is pruned at the earliest
+const minIndexPruning = 1024
+
+//urlIndex maps the URLs of the entries of a cache to their keys, so invalidating a URL does not list all keys of the
+//cache. It is built from the keys of the cache on its first use and the responses stored afterwards are added to it.
+//Keys of deleted or evicted entries are pruned once more keys were added than the index held after it was built and
+//than minIndexPruning
+type urlIndex struct {
+	mutex sync.Mutex
+	//active is set once the index is used, responses are only added to an active index
+	active bool
+	//paths holds the keys of the entries by the host and path of their URL
+	paths map[string]map[string]indexedKey
+	//keys holds the host and path of the indexed keys
+	keys map[string]string
+	//unknown is the number of keys listed by the cache which are not in the request dump format
+	unknown int
+	//seq counts the added keys, added is their number since the last build and size the number of keys after it
+	seq   uint64
+	added int
+	size  int
+}
+
+type indexedKey struct {
+	//query is the encoded query of the URL
+	query string
+	seq   uint64
+}
+
+//urlIndexes holds the index of a transport
+var urlIndexes sync.Mutex
+
+//urlIndex returns the index of the URLs of the entries of the transport, it is created on first use
+func (c *CachedTransport) urlIndex() *urlIndex {
+	if index, ok := c.index.Load().(*urlIndex); ok {
+		return index
+	}
+	urlIndexes.Lock()
+	defer urlIndexes.Unlock()
+	if index, ok := c.index.Load().(*urlIndex); ok {
+		return index
+	}
+	index := &urlIndex{paths: map[string]map[string]indexedKey{}, keys: map[string]string{}}
+	c.index.Store(index)
+	return index
+}
+
+//indexStored adds the key of the response stored for req to the index if it is active
+func (c *CachedTransport) indexStored(req *http.Request) {
+	index, ok := c.index.Load().(*urlIndex)
+	if !ok {
+		return
+	}
+	index.mutex.Lock()
+	active := index.active
+	index.mutex.Unlock()
+	if !active {
+		return
+	}
+	if key, ok := cacheKey(c.Cache, req); ok {
+		index.add(req.URL, key)
+	}
+}
+
+//indexPath returns the host and path u is indexed by
+func indexPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return u.Host + path
+}
+
+func (i *urlIndex) add(u *url.URL, key string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.addLocked(indexPath(u), u.Query().Encode(), key)
+}
+
+func (i *urlIndex) addLocked(path string, query string, key string) {
+	if _, ok := i.keys[key]; ok {
+		return
+	}
+	i.seq++
+	i.added++
+	if i.paths[path] == nil {
+		i.paths[path] = map[string]indexedKey{}
+	}
+	i.paths[path][key] = indexedKey{query: query, seq: i.seq}
+	i.keys[key] = path
+}
+
+func (i *urlIndex) remove(key string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.removeLocked(key)
+}
+
+func (i *urlIndex) removeLocked(key string) {
+	path, ok := i.keys[key]
+	if !ok {
+		return
+	}
+	delete(i.keys, key)
+	delete(i.paths[path], key)
+	if len(i.paths[path]) == 0 {
+		delete(i.paths, path)
+	}
+}
+
+//lookup returns the keys of the entries for target, for all queries if target has none
+func (i *urlIndex) lookup(target *url.URL) []string {
+	query := target.Query().Encode()
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	var keys []string
+	for key, indexed := range i.paths[indexPath(target)] {
+		if target.RawQuery == "" || indexed.query == query {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+//activate builds the index from the keys of inspector on its first use and prunes it once enough keys were added. It
+//reports if the index was built
+func (i *urlIndex) activate(inspector Inspector) bool {
+
+	i.mutex.Lock()
+	if i.active && (i.added <= i.size || i.added <= minIndexPruning) {
+		i.mutex.Unlock()
+		return false
+	}
+	i.active = true
+	built := i.seq
+	i.mutex.Unlock()
+
+	//the keys are listed without holding the lock, responses stored meanwhile are added
+	listed := inspector.Keys()
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	current := make(map[string]bool, len(listed))
+	unknown := 0
+	for _, key := range listed {
+		current[key] = true
+		if _, ok := i.keys[key]; ok {
+			continue
+		}
+		summary := summarizeKey(key)
+		requestURI, err := url.ParseRequestURI(summary.Target)
+		if summary.Host == "" || err != nil {
+			unknown++
+			continue
+		}
+		requestURI.Host = summary.Host
+		i.addLocked(indexPath(requestURI), requestURI.Query().Encode(), key)
+	}
+	for key, path := range i.keys {
+		if !current[key] && i.paths[path][key].seq <= built {
+			i.removeLocked(key)
+		}
+	}
+	i.unknown, i.added, i.size = unknown, 0, len(i.keys)
+	return true
+}
+
+//unknownKeysError returns UnknownKeyFormatError if the cache listed keys the index can not hold
+func (i *urlIndex) unknownKeysError() error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.unknown == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d keys", UnknownKeyFormatError, i.unknown)
+}
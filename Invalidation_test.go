@@ -0,0 +1,192 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyMatchesURL(t *testing.T) {
+
+	key := "GET /a/b?y=2&x=1 HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	tests := []struct {
+		url     string
+		matches bool
+	}{
+		{"http://example.com/a/b", true},
+		{"http://example.com/a/b?x=1&y=2", true},
+		{"http://example.com/a/b?x=1", false},
+		{"http://example.com/a", false},
+		{"http://other.com/a/b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, err := url.Parse(tt.url)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if matches := keyMatchesURL(key, target); matches != tt.matches {
+				t.Error("expected", tt.matches, "got", matches)
+			}
+		})
+	}
+	if keyMatchesURL("custom key", &url.URL{Host: "example.com", Path: "/"}) {
+		t.Error("expected keys not in the request dump format not to match")
+	}
+}
+
+func TestCachedTransport_Invalidate(t *testing.T) {
+
+	counter := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		counter++
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		status := http.StatusOK
+		if !isSafeMethod(req.Method) {
+			header.Set("Location", req.Header.Get("X-Location"))
+			status, _ = strconv.Atoi(req.Header.Get("X-Status"))
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+	})
+
+	cache := NewMapCache()
+	transport := &CachedTransport{Cache: cache, Fallback: fallback, HostAliases: map[string]string{"www.example.com": "example.com"}}
+	client := http.Client{Transport: transport}
+
+	do := func(method string, url string, status string, location string) {
+		request, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header.Set("X-Status", status)
+		request.Header.Set("X-Location", location)
+		response, err := client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_ = response.Body.Close()
+	}
+	fill := func() {
+		for _, path := range []string{"/a", "/a?q=1", "/b", "/c"} {
+			do("GET", "http://example.com"+path, "", "")
+		}
+	}
+	remaining := func(expected int) {
+		if keys := cache.Keys(); len(keys) != expected {
+			t.Error("expected", expected, "entries got", len(keys))
+		}
+	}
+
+	tests := []struct {
+		name       string
+		invalidate func() (int, error)
+		remaining  int
+	}{
+		{"url", func() (int, error) { return transport.InvalidateURL("http://www.example.com/a") }, 2},
+		{"url with query", func() (int, error) { return transport.InvalidateURL("http://example.com/a?q=1") }, 3},
+		{"matching", func() (int, error) {
+			return transport.InvalidateMatching(func(key string) bool { return strings.HasPrefix(key, "GET /b") })
+		}, 3},
+		{"key", func() (int, error) { return 1, transport.Invalidate(cache.Keys()[0]) }, 3},
+		{"unsafe request", func() (int, error) { do("POST", "http://example.com/a", "201", "/b"); return 0, nil }, 1},
+		{"unsafe request to another host", func() (int, error) {
+			do("DELETE", "http://example.com/c", "204", "http://other.com/b")
+			return 0, nil
+		}, 3},
+		{"failed unsafe request", func() (int, error) { do("PUT", "http://example.com/a", "500", ""); return 0, nil }, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fill()
+			remaining(4)
+			if _, err := tt.invalidate(); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			remaining(tt.remaining)
+			fill()
+		})
+	}
+
+	_, err := (&CachedTransport{Cache: failingCache{}}).InvalidateURL("http://example.com/")
+	if !errors.Is(err, InvalidationNotSupportedError) {
+		t.Error("expected InvalidationNotSupportedError got", err)
+	}
+}
+
+//countingKeysCache counts the listings of its keys
+type countingKeysCache struct {
+	*MapCache
+	listed int
+}
+
+func (c *countingKeysCache) Keys() []string {
+	c.listed++
+	return c.MapCache.Keys()
+}
+
+func TestCachedTransport_InvalidateURL_Index(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=60")
+		if !isSafeMethod(req.Method) {
+			res.StatusCode = http.StatusNoContent
+		}
+		return res, nil
+	})
+	cache := &countingKeysCache{MapCache: NewMapCache(MapCacheOptions{KeyFunc: func(req *http.Request) string {
+		return "custom " + req.URL.Path
+	}})}
+	var reported []error
+	transport := &CachedTransport{Cache: cache, Fallback: fallback,
+		Hooks: &Hooks{OnError: func(event Event) { reported = append(reported, event.Err) }}}
+	do := func(method string, path string) {
+		req := lruTestRequest(t, path)
+		req.Method = method
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+
+	do(http.MethodGet, "/a")
+	do(http.MethodGet, "/b")
+	do(http.MethodPost, "/a")
+	if len(cache.Keys()) != 2 || len(reported) != 1 || !errors.Is(reported[0], UnknownKeyFormatError) {
+		t.Fatal("expected the keys stored before the index to be reported, got", reported)
+	}
+
+	//the responses stored once the index is built are found by their URL
+	do(http.MethodGet, "/c")
+	cache.listed = 0
+	for i := 0; i < 10; i++ {
+		do(http.MethodPut, "/c")
+		do(http.MethodGet, "/c")
+	}
+	if cache.listed != 0 {
+		t.Error("expected unsafe requests not to list the keys, listed", cache.listed)
+	}
+	if len(reported) != 1 {
+		t.Error("expected the unknown keys to be reported once, got", reported)
+	}
+	do(http.MethodDelete, "/c")
+	if keys := cache.Keys(); len(keys) != 2 {
+		t.Error("expected the entry of /c to be invalidated, got", keys)
+	}
+
+	deleted, err := transport.InvalidateURL("http://example.com/c")
+	if deleted != 0 || !errors.Is(err, UnknownKeyFormatError) {
+		t.Error("expected the unknown keys to be reported, got", deleted, err)
+	}
+}
@@ -0,0 +1,104 @@
+package CachedHttpClient
+
+import (
+	"sync"
+	"time"
+)
+
+//DefaultJanitorInterval is the time between two collections of a Janitor without Interval
+const DefaultJanitorInterval = 10 * time.Minute
+
+//Janitor removes the expired entries of a cache, once with Collect or periodically between Start and Stop. Backends
+//expiring their entries themselves, like a KVCache with Expire, are left alone. A DiskCache is collected using only
+//the metadata of its entries, other caches have to list their keys, see IterationCapability
+type Janitor struct {
+	Cache Cacher
+	//Interval is the time between two collections after Start, DefaultJanitorInterval if 0
+	Interval time.Duration
+	//MaxStale keeps the entries which have been stale for less. It should cover the StaleIfError and
+	//StaleWhileRevalidate of the transport, stale entries can still be revalidated with a conditional request
+	MaxStale time.Duration
+	//Shared selects the freshness rules of a shared cache
+	Shared bool
+	//Metrics counts the reclaimed entries and bytes if not nil
+	Metrics *Metrics
+	//Variants forgets the variants of the removed entries if not nil
+	Variants *VariantTracker
+	//Clock tells the time entries expire at and schedules the collections, SystemClock if nil
+	Clock Clock
+
+	//mutex guards done and stopped, which are nil while the Janitor is not started
+	mutex   sync.Mutex
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+//Collect removes the expired entries once and returns their number and the size of their bodies, as far as the cache
+//knows it. It fails with InspectionNotSupportedError if the keys of the cache can not be listed
+func (j *Janitor) Collect() (int, int64, error) {
+
+	now := clockOrDefault(j.Clock).Now()
+	var deleted int
+	var reclaimed int64
+	var err error
+	if disk, ok := j.Cache.(*DiskCache); ok {
+		deleted, reclaimed, err = disk.purgeExpired(j.MaxStale, now, j.Variants)
+	} else {
+		if CapabilitiesOf(j.Cache).Has(TTLCapability) {
+			return 0, 0, nil
+		}
+		inspector, ok := iterableCache(j.Cache)
+		if !ok {
+			return 0, 0, InspectionNotSupportedError
+		}
+		deleted, reclaimed, err = purgeExpired(inspector, j.Shared, j.MaxStale, now, j.Variants)
+	}
+	j.Metrics.reclaim(deleted, reclaimed)
+	return deleted, reclaimed, err
+}
+
+//Start calls Collect every Interval in a goroutine until Stop is called, errors are ignored. Starting a started
+//Janitor does nothing
+func (j *Janitor) Start() {
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.done != nil {
+		return
+	}
+	interval := j.Interval
+	if interval <= 0 {
+		interval = DefaultJanitorInterval
+	}
+
+	ticker := clockOrDefault(j.Clock).NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	j.done, j.stopped = done, stopped
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				_, _, _ = j.Collect()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+//Stop ends the collections started by Start and waits for a running one to finish, the Janitor can be started again
+func (j *Janitor) Stop() {
+
+	j.mutex.Lock()
+	done, stopped := j.done, j.stopped
+	j.done, j.stopped = nil, nil
+	j.mutex.Unlock()
+	if done == nil {
+		return
+	}
+	close(done)
+	<-stopped
+}
@@ -0,0 +1,128 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+//storeJanitorEntries stores responses received a minute ago, /expired has been stale for 59 seconds and /recent for
+//10 seconds
+func storeJanitorEntries(t *testing.T, cache Cacher) {
+	entries := []struct {
+		path         string
+		cacheControl string
+	}{{"/fresh", "max-age=3600"}, {"/expired", "max-age=1"}, {"/recent", "max-age=50"}, {"/forever", ""}}
+	for _, entry := range entries {
+		request := lruTestRequest(t, entry.path)
+		response := lruTestResponse(strings.Repeat("x", 100))
+		response.ContentLength = 100
+		response.Header.Set("Date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		if entry.cacheControl != "" {
+			response.Header.Set("Cache-Control", entry.cacheControl)
+		}
+		response.Request = request
+		if err := cache.Set(request, response); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestJanitor_Collect(t *testing.T) {
+
+	dir := "tmp/janitor"
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	disk, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caches := []struct {
+		name  string
+		cache Cacher
+	}{
+		{"map", NewMapCache()},
+		{"lru", NewLRUCache(LRUCacheOptions{MaxEntries: 10})},
+		{"disk", disk},
+	}
+	for _, tt := range caches {
+		t.Run(tt.name, func(t *testing.T) {
+			storeJanitorEntries(t, tt.cache)
+			metrics := NewMetrics()
+			janitor := &Janitor{Cache: tt.cache, MaxStale: 30 * time.Second, Metrics: metrics}
+			deleted, reclaimed, err := janitor.Collect()
+			if err != nil || deleted != 1 || reclaimed != 100 {
+				t.Error("expected the expired entry to be removed, got", deleted, reclaimed, err)
+			}
+			if stats := metrics.Stats(); stats.Reclaimed != 1 || stats.ReclaimedBytes != 100 {
+				t.Errorf("expected the reclaimed entry to be counted, got %+v", stats)
+			}
+			if _, err := tt.cache.Get(lruTestRequest(t, "/expired")); !errors.Is(err, NotInCacheError) {
+				t.Error("expected NotInCacheError, got", err)
+			}
+			for _, path := range []string{"/fresh", "/recent", "/forever"} {
+				if _, err := tt.cache.Get(lruTestRequest(t, path)); err != nil {
+					t.Error(path, err)
+				}
+			}
+		})
+	}
+}
+
+func TestJanitor_Collect_Backends(t *testing.T) {
+
+	kv := NewKVCache(newMemoryKVStore())
+	storeJanitorEntries(t, kv)
+	if deleted, _, err := (&Janitor{Cache: kv}).Collect(); err != nil || deleted != 0 {
+		t.Error("expected the expiry to be left to the backend, got", deleted, err)
+	}
+	if _, err := kv.Get(lruTestRequest(t, "/expired")); err != nil {
+		t.Error(err)
+	}
+
+	if _, _, err := (&Janitor{Cache: failingCache{}}).Collect(); !errors.Is(err, InspectionNotSupportedError) {
+		t.Error("expected InspectionNotSupportedError, got", err)
+	}
+}
+
+func TestJanitor_Start(t *testing.T) {
+
+	cache := NewMapCache()
+	storeJanitorEntries(t, cache)
+	metrics := NewMetrics()
+	janitor := &Janitor{Cache: cache, Interval: 5 * time.Millisecond, Metrics: metrics}
+	janitor.Start()
+	janitor.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for metrics.Stats().Reclaimed < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	janitor.Stop()
+	janitor.Stop()
+
+	if stats := metrics.Stats(); stats.Reclaimed != 2 || stats.ReclaimedBytes != 200 {
+		t.Errorf("expected both stale entries to be reclaimed, got %+v", stats)
+	}
+	if keys := cache.Keys(); len(keys) != 2 {
+		t.Error("expected the fresh entries to remain, got", len(keys))
+	}
+}
+
+func TestPurgeExpiredWithClock(t *testing.T) {
+
+	cache := NewMapCache()
+	storeJanitorEntries(t, cache)
+	clock := NewManualClock(time.Now().Add(time.Minute))
+	deleted, err := PurgeExpiredWithClock(cache, false, 30*time.Second, clock)
+	if err != nil || deleted != 2 {
+		t.Error("expected both entries stale for longer than 30 seconds at the clock to be deleted, got", deleted, err)
+	}
+	if keys := cache.Keys(); len(keys) != 2 {
+		t.Error("expected the fresh entries to remain, got", len(keys))
+	}
+}
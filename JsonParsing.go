@@ -1,18 +1,11 @@
 package CachedHttpClient
 
 import (
-	"bytes"
-	"crypto/dsa"
-	"crypto/ecdsa"
-	"crypto/ed25519"
-	"crypto/elliptic"
-	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"encoding/json"
-	"io/ioutil"
 	"math/big"
 	"net"
 	"net/http"
@@ -33,18 +26,47 @@ type JsonResponse struct {
 	Close            bool
 	Uncompressed     bool
 	Trailer          http.Header
-	Request          string
-	TLS              *JsonTlsConnectionState
+	//Request is the request the response was received for without its body and UnstoredRequestHeaders
+	Request *JsonRequest `json:",omitempty"`
+	TLS     *JsonTlsConnectionState
+	//VaryHeaders holds the values of the request headers named in the Vary header
+	VaryHeaders http.Header `json:",omitempty"`
+	//BodyFile names the file holding the body if it is not embedded in Body, see FileCache.BodyThreshold
+	BodyFile string `json:",omitempty"`
+	//BodyCompression names the Compressor Body is compressed with, see Compression
+	BodyCompression string `json:",omitempty"`
+	//CompressionDecision records why the body was compressed or not if Compression.Adaptive is set
+	CompressionDecision string `json:",omitempty"`
+	//CompressedHeader holds Header encoded with the static table of common fields if Compression.Headers is set,
+	//Header is nil then
+	CompressedHeader []byte `json:",omitempty"`
 }
 
+//NewJsonResponse converts res reading its body, res gets a body over the same bytes. The body of responses served
+//from the in memory caches is not copied but shared read-only with the JsonResponse
 func NewJsonResponse(res *http.Response) (*JsonResponse, error) {
-	var buf bytes.Buffer
-	_, err := buf.ReadFrom(res.Body)
+	body, err := readBody(res.Body, res.ContentLength)
 	if err != nil {
 		return nil, err
 	}
 
-	res.Body = ioutil.NopCloser(bytes.NewBuffer(buf.Bytes()))
+	res.Body = newBytesBody(body)
+
+	response, err := newJsonResponseHead(res)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = body
+	return response, nil
+}
+
+//newJsonResponseHead converts res without reading its body
+func newJsonResponseHead(res *http.Response) (*JsonResponse, error) {
+
+	tlsState, err := EncodeJsonTlsConnectionState(res.TLS)
+	if err != nil {
+		return nil, err
+	}
 
 	return &JsonResponse{
 		Status:           res.Status,
@@ -53,19 +75,54 @@ func NewJsonResponse(res *http.Response) (*JsonResponse, error) {
 		ProtoMajor:       res.ProtoMajor,
 		ProtoMinor:       res.ProtoMinor,
 		Header:           res.Header,
-		Body:             buf.Bytes(),
 		ContentLength:    res.ContentLength,
 		TransferEncoding: res.TransferEncoding,
 		Close:            res.Close,
 		Uncompressed:     res.Uncompressed,
-		Trailer:          res.Trailer,
-		Request:          "",
-		TLS:              NewJsonTlsConnectionState(res.TLS),
+		Trailer:          cloneHeader(res.Trailer),
+		Request:          newJsonRequestHead(res.Request),
+		TLS:              tlsState,
+		VaryHeaders:      varyHeaders(res),
 	}, nil
 }
+
+//ToResponse converts the JsonResponse back to a *http.Response
+//
+//Deprecated: use Parse, ToResponse panics if the response can not be converted. Certificates of the TLS state with
+//a public key which can not be converted have no PublicKey
 func (response *JsonResponse) ToResponse() *http.Response {
+	res, err := response.Parse()
+	if err != nil && !isUnknownPublicKeyError(err) {
+		panic(err)
+	}
+	return res
+}
+
+//Parse converts the JsonResponse back to a *http.Response. Every call returns a response with its own headers and
+//body reader, responses of the same JsonResponse can be consumed and modified concurrently. Like on a live response
+//the Trailer holds the values of the trailers once the body was read to the end. The body bytes and TLS
+//certificates are shared read-only. UnknownPublicKeyTypeError or UnknownCurveError is returned together with the
+//response if a certificate of the TLS state has a public key which can not be converted
+func (response *JsonResponse) Parse() (*http.Response, error) {
 	if response == nil {
-		return nil
+		return nil, nil
+	}
+
+	tlsState, keyErr := response.TLS.Parse()
+	if keyErr != nil && !isUnknownPublicKeyError(keyErr) {
+		return nil, keyErr
+	}
+	body, err := decompressBody(response)
+	if err != nil {
+		return nil, err
+	}
+	header, err := decompressHeader(response)
+	if err != nil {
+		return nil, err
+	}
+	req, err := response.Request.parse()
+	if err != nil {
+		return nil, err
 	}
 
 	var res = http.Response{
@@ -74,19 +131,140 @@ func (response *JsonResponse) ToResponse() *http.Response {
 		Proto:            response.Proto,
 		ProtoMajor:       response.ProtoMajor,
 		ProtoMinor:       response.ProtoMinor,
-		Header:           response.Header,
-		Body:             ioutil.NopCloser(bytes.NewBuffer(response.Body)),
+		Header:           header,
+		Body:             newBytesBody(body),
 		ContentLength:    response.ContentLength,
-		TransferEncoding: response.TransferEncoding,
+		TransferEncoding: cloneStrings(response.TransferEncoding),
 		Close:            response.Close,
 		Uncompressed:     response.Uncompressed,
-		Trailer:          response.Trailer,
-		Request:          nil,
-		TLS:              response.TLS.ToConnectionState(),
+		Trailer:          cloneHeader(response.Trailer),
+		Request:          req,
+		TLS:              tlsState,
+	}
+
+	if response.VaryHeaders != nil {
+		if res.Request == nil {
+			//only the headers the response varies on are known of the request
+			res.Request = &http.Request{Header: http.Header{}}
+		}
+		//the headers the response varies on are stored even if they are UnstoredRequestHeaders
+		for field, values := range response.VaryHeaders {
+			res.Request.Header[field] = cloneStrings(values)
+		}
+	}
+
+	return deferTrailer(&res), keyErr
+
+}
+
+//Clone returns a deep copy of the JsonResponse sharing no state with it but the read-only TLS certificates
+func (response *JsonResponse) Clone() *JsonResponse {
+	if response == nil {
+		return nil
+	}
+	clone := *response
+	clone.Header = cloneHeader(response.Header)
+	clone.Body = cloneBytes(response.Body)
+	clone.TransferEncoding = cloneStrings(response.TransferEncoding)
+	clone.Trailer = cloneHeader(response.Trailer)
+	clone.TLS = response.TLS.clone()
+	clone.VaryHeaders = cloneHeader(response.VaryHeaders)
+	clone.CompressedHeader = cloneBytes(response.CompressedHeader)
+	if response.Request != nil {
+		request := *response.Request
+		request.Header = cloneHeader(response.Request.Header)
+		request.Body = cloneBytes(response.Request.Body)
+		clone.Request = &request
+	}
+	return &clone
+}
+
+//UnstoredRequestHeaders are the credentials left out of the request stored with a response, unless the response
+//varies on them
+var UnstoredRequestHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+//newJsonRequestHead converts the request a response was received for without its body and UnstoredRequestHeaders,
+//nil if req has no URL
+func newJsonRequestHead(req *http.Request) *JsonRequest {
+	if req == nil || req.URL == nil {
+		return nil
+	}
+	header := cloneHeader(req.Header)
+	for _, name := range UnstoredRequestHeaders {
+		header.Del(name)
+	}
+	return &JsonRequest{Method: req.Method, URL: req.URL.String(), Header: header}
+}
+
+//parse reconstructs a request without body from r, nil if r is nil or was stored as empty string by earlier versions
+func (r *JsonRequest) parse() (*http.Request, error) {
+
+	if r == nil || r.URL == "" {
+		return nil, nil
+	}
+	requestURL, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, err
+	}
+	header := cloneHeader(r.Header)
+	if header == nil {
+		header = http.Header{}
 	}
 
-	return &res
+	return &http.Request{
+		Method:     r.Method,
+		URL:        requestURL,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Host:       requestURL.Host,
+	}, nil
+}
 
+//UnmarshalJSON decodes r, the empty string earlier versions stored as Request of a JsonResponse is decoded as empty
+//JsonRequest
+func (r *JsonRequest) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*r = JsonRequest{}
+		return nil
+	}
+	type plainJsonRequest JsonRequest
+	return json.Unmarshal(data, (*plainJsonRequest)(r))
+}
+
+//cloneHeader returns a deep copy of header keeping nil, http.Header.Clone returns a non-nil header for nil before
+//Go 1.19
+func cloneHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	return header.Clone()
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+func cloneBytes(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+	return append([]byte{}, value...)
+}
+
+func cloneByteSlices(values [][]byte) [][]byte {
+	if values == nil {
+		return nil
+	}
+	clone := make([][]byte, len(values))
+	for i, value := range values {
+		clone[i] = cloneBytes(value)
+	}
+	return clone
 }
 
 func responseToJSON(res *http.Response) ([]byte, error) {
@@ -118,10 +296,31 @@ type JsonTlsConnectionState struct {
 	TLSUnique                   []byte
 }
 
+//NewJsonTlsConnectionState converts tls for the JSON encoding
+//
+//Deprecated: use EncodeJsonTlsConnectionState, NewJsonTlsConnectionState panics if a certificate can not be converted
 func NewJsonTlsConnectionState(tls *tls.ConnectionState) *JsonTlsConnectionState {
+	state, err := EncodeJsonTlsConnectionState(tls)
+	if err != nil {
+		panic(err)
+	}
+	return state
+}
+
+//EncodeJsonTlsConnectionState converts tls for the JSON encoding
+func EncodeJsonTlsConnectionState(tls *tls.ConnectionState) (*JsonTlsConnectionState, error) {
 
 	if tls == nil {
-		return nil
+		return nil, nil
+	}
+
+	peerCertificates, err := encodeCertificates(tls.PeerCertificates)
+	if err != nil {
+		return nil, err
+	}
+	verifiedChains, err := encodeCertificateChains(tls.VerifiedChains)
+	if err != nil {
+		return nil, err
 	}
 
 	return &JsonTlsConnectionState{
@@ -132,17 +331,62 @@ func NewJsonTlsConnectionState(tls *tls.ConnectionState) *JsonTlsConnectionState
 		NegotiatedProtocol:          tls.NegotiatedProtocol,
 		NegotiatedProtocolIsMutual:  tls.NegotiatedProtocolIsMutual,
 		ServerName:                  tls.ServerName,
-		PeerCertificates:            NewJsonX509CertificateArray(tls.PeerCertificates),
-		VerifiedChains:              NewJsonX509CertificateArrayArray(tls.VerifiedChains),
+		PeerCertificates:            peerCertificates,
+		VerifiedChains:              verifiedChains,
 		SignedCertificateTimestamps: tls.SignedCertificateTimestamps,
 		OCSPResponse:                tls.OCSPResponse,
 		TLSUnique:                   tls.TLSUnique,
-	}
+	}, nil
 }
+
+//ToConnectionState converts the state back to a *tls.ConnectionState
+//
+//Deprecated: use Parse, ToConnectionState panics if a certificate can not be converted. Certificates with a public
+//key which can not be converted have no PublicKey
 func (state *JsonTlsConnectionState) ToConnectionState() *tls.ConnectionState {
+	connectionState, err := state.Parse()
+	if err != nil && !isUnknownPublicKeyError(err) {
+		panic(err)
+	}
+	return connectionState
+}
+
+//clone returns a copy of the state, the certificates are shared as they are read-only like in crypto/tls
+func (state *JsonTlsConnectionState) clone() *JsonTlsConnectionState {
 	if state == nil {
 		return nil
 	}
+	clone := *state
+	clone.PeerCertificates = append([]*JsonX509Certificate(nil), state.PeerCertificates...)
+	clone.VerifiedChains = nil
+	for _, chain := range state.VerifiedChains {
+		clone.VerifiedChains = append(clone.VerifiedChains, append([]*JsonX509Certificate(nil), chain...))
+	}
+	clone.SignedCertificateTimestamps = cloneByteSlices(state.SignedCertificateTimestamps)
+	clone.OCSPResponse = cloneBytes(state.OCSPResponse)
+	clone.TLSUnique = cloneBytes(state.TLSUnique)
+	return &clone
+}
+
+//Parse converts the state back to a *tls.ConnectionState. UnknownPublicKeyTypeError or UnknownCurveError is returned
+//together with the state if a certificate has a public key which can not be converted
+func (state *JsonTlsConnectionState) Parse() (*tls.ConnectionState, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	peerCertificates, keyErr := parseCertificates(state.PeerCertificates)
+	if keyErr != nil && !isUnknownPublicKeyError(keyErr) {
+		return nil, keyErr
+	}
+	verifiedChains, err := parseCertificateChains(state.VerifiedChains)
+	if err != nil && !isUnknownPublicKeyError(err) {
+		return nil, err
+	}
+	if keyErr == nil {
+		keyErr = err
+	}
+
 	return &tls.ConnectionState{
 		Version:                     state.Version,
 		HandshakeComplete:           state.HandshakeComplete,
@@ -151,12 +395,12 @@ func (state *JsonTlsConnectionState) ToConnectionState() *tls.ConnectionState {
 		NegotiatedProtocol:          state.NegotiatedProtocol,
 		NegotiatedProtocolIsMutual:  state.NegotiatedProtocolIsMutual,
 		ServerName:                  state.ServerName,
-		PeerCertificates:            ToX509CertificateArray(state.PeerCertificates),
-		VerifiedChains:              ToX509CertificateArrayArray(state.VerifiedChains),
-		SignedCertificateTimestamps: state.SignedCertificateTimestamps,
-		OCSPResponse:                state.OCSPResponse,
-		TLSUnique:                   state.TLSUnique,
-	}
+		PeerCertificates:            peerCertificates,
+		VerifiedChains:              verifiedChains,
+		SignedCertificateTimestamps: cloneByteSlices(state.SignedCertificateTimestamps),
+		OCSPResponse:                cloneBytes(state.OCSPResponse),
+		TLSUnique:                   cloneBytes(state.TLSUnique),
+	}, keyErr
 }
 
 type JsonX509Certificate struct {
@@ -205,14 +449,54 @@ type JsonX509Certificate struct {
 	PolicyIdentifiers           []asn1.ObjectIdentifier
 }
 
+//jsonX509Certificate has the fields of JsonX509Certificate without its MarshalJSON
+type jsonX509Certificate JsonX509Certificate
+
+//MarshalJSON encodes certificates holding only their DER encoding as {"Raw":...} instead of listing all empty fields
+func (certificate *JsonX509Certificate) MarshalJSON() ([]byte, error) {
+	if certificate.rawOnly() {
+		return json.Marshal(struct{ Raw []byte }{certificate.Raw})
+	}
+	return json.Marshal((*jsonX509Certificate)(certificate))
+}
+
 type JsonPublicKey struct {
 	PublicKey []byte
 	Type      string
 }
 
+//rawOnly reports if the certificate is parsed from its DER encoding alone. Parse only uses the other fields if the
+//DER encoding fails to parse and RawTBSCertificate is set, which every verbose certificate with a DER encoding has
+func (certificate *JsonX509Certificate) rawOnly() bool {
+	return len(certificate.Raw) > 0 && len(certificate.RawTBSCertificate) == 0
+}
+
+//ToCertificate converts the certificate back to a *x509.Certificate
+//
+//Deprecated: use Parse, ToCertificate returns certificates with a public key which can not be converted without
+//PublicKey and panics for other errors
 func (certificate *JsonX509Certificate) ToCertificate() *x509.Certificate {
+	cert, err := certificate.Parse()
+	if err != nil && !isUnknownPublicKeyError(err) {
+		panic(err)
+	}
+	return cert
+}
+
+//Parse converts the certificate back to a *x509.Certificate. Certificates are parsed from their DER encoding if it
+//is stored, else they are converted field by field and UnknownPublicKeyTypeError or UnknownCurveError is returned
+//together with the certificate without PublicKey for public keys which can not be converted, see
+//RegisterPublicKeyCodec
+func (certificate *JsonX509Certificate) Parse() (*x509.Certificate, error) {
 	if certificate == nil {
-		return nil
+		return nil, nil
+	}
+	if len(certificate.Raw) > 0 {
+		cert, err := x509.ParseCertificate(certificate.Raw)
+		if err == nil || certificate.RawTBSCertificate == nil {
+			return cert, err
+		}
+		//entries written before the certificates were parsed from their DER encoding keep working with the fields
 	}
 
 	cert := x509.Certificate{
@@ -261,76 +545,51 @@ func (certificate *JsonX509Certificate) ToCertificate() *x509.Certificate {
 		PolicyIdentifiers:           certificate.PolicyIdentifiers,
 	}
 
-	if certificate.PublicKey.Type == "" {
-		return &cert
+	publicKey, err := certificate.PublicKey.parse()
+	if err != nil {
+		//the certificate is usable for everything but verifying signatures
+		return &cert, err
 	}
+	cert.PublicKey = publicKey
+	return &cert, nil
 
-	var finalPublicKey interface{}
+}
 
-	var err error
+//NewJsonX509Certificate converts cert for the JSON encoding
+//
+//Deprecated: use EncodeJsonX509Certificate, NewJsonX509Certificate panics if the public key can not be converted
+func NewJsonX509Certificate(cert *x509.Certificate) *JsonX509Certificate {
+	certificate, err := EncodeJsonX509Certificate(cert)
+	if err != nil {
+		panic(err)
+	}
+	return certificate
+}
 
-	switch certificate.PublicKey.Type {
-	case "rsa.PublicKey":
-		publicKey := rsa.PublicKey{}
-		err = json.Unmarshal(certificate.PublicKey.PublicKey, &publicKey)
-		finalPublicKey = &publicKey
-	case "ecdsa.PublicKey":
-		type DummyKey struct {
-			Curve map[string]interface{}
-			X, Y  *big.Int
-		}
-		dummyKey := &DummyKey{}
-		err = json.Unmarshal(certificate.PublicKey.PublicKey, &dummyKey)
-		if err != nil {
-			break
-		}
-		switch dummyKey.Curve["Name"] {
-		case "P-256":
-			finalPublicKey = &ecdsa.PublicKey{
-				Curve: elliptic.P256(),
-				X:     dummyKey.X,
-				Y:     dummyKey.Y,
-			}
-		case "P-384":
-			finalPublicKey = &ecdsa.PublicKey{
-				Curve: elliptic.P384(),
-				X:     dummyKey.X,
-				Y:     dummyKey.Y,
-			}
-		case "P-521":
-			finalPublicKey = &ecdsa.PublicKey{
-				Curve: elliptic.P521(),
-				X:     dummyKey.X,
-				Y:     dummyKey.Y,
-			}
-		default:
-			panic("unknown elliptic curve" + dummyKey.Curve["Name"].(string))
-		}
+//EncodeJsonX509Certificate converts cert for the JSON encoding. Only the DER encoding of cert is stored unless it has
+//none, NewVerboseCertificatesCodec stores all fields. UnknownPublicKeyTypeError or UnknownCurveError is returned for
+//public keys of certificates without DER encoding which can not be converted
+func EncodeJsonX509Certificate(cert *x509.Certificate) (*JsonX509Certificate, error) {
 
-	case "dsa.PublicKey":
-		publicKey := dsa.PublicKey{}
-		err = json.Unmarshal(certificate.PublicKey.PublicKey, &publicKey)
-		finalPublicKey = &publicKey
+	if len(cert.Raw) > 0 {
+		return &JsonX509Certificate{Raw: cert.Raw}, nil
+	}
+	return encodeVerboseCertificate(cert)
+}
 
-	case "ed25519.PublicKey":
-		publicKey := ed25519.PublicKey{}
-		err = json.Unmarshal(certificate.PublicKey.PublicKey, &publicKey)
-		finalPublicKey = &publicKey
+//encodeVerboseCertificate converts all fields of cert
+func encodeVerboseCertificate(cert *x509.Certificate) (*JsonX509Certificate, error) {
 
-	default:
-		panic("unknown publickey format")
+	publicKey, err := encodePublicKey(cert.PublicKey)
+	if err != nil && len(cert.Raw) == 0 {
+		return nil, err
 	}
 	if err != nil {
-		panic(err)
+		//the public key is parsed from the DER encoding
+		publicKey = nil
 	}
-	cert.PublicKey = finalPublicKey
-	return &cert
-
-}
-
-func NewJsonX509Certificate(cert *x509.Certificate) *JsonX509Certificate {
 
-	jsonX509Certificate := &JsonX509Certificate{
+	return &JsonX509Certificate{
 		Raw:                         cert.Raw,
 		RawTBSCertificate:           cert.RawTBSCertificate,
 		RawSubjectPublicKeyInfo:     cert.RawSubjectPublicKeyInfo,
@@ -374,82 +633,135 @@ func NewJsonX509Certificate(cert *x509.Certificate) *JsonX509Certificate {
 		ExcludedURIDomains:          cert.ExcludedURIDomains,
 		CRLDistributionPoints:       cert.CRLDistributionPoints,
 		PolicyIdentifiers:           cert.PolicyIdentifiers,
-	}
+		PublicKey:                   publicKey,
+	}, nil
+}
 
-	marshal, err := json.Marshal(cert.PublicKey)
+//NewJsonX509CertificateArray converts certs for the JSON encoding
+//
+//Deprecated: NewJsonX509CertificateArray panics if a public key can not be converted
+func NewJsonX509CertificateArray(certs []*x509.Certificate) []*JsonX509Certificate {
+	array, err := encodeCertificates(certs)
 	if err != nil {
 		panic(err)
 	}
+	return array
+}
 
-	jsonPublicKey := &JsonPublicKey{
-		PublicKey: marshal,
+//NewJsonX509CertificateArrayArray converts certs for the JSON encoding
+//
+//Deprecated: NewJsonX509CertificateArrayArray panics if a public key can not be converted
+func NewJsonX509CertificateArrayArray(certs [][]*x509.Certificate) [][]*JsonX509Certificate {
+	array, err := encodeCertificateChains(certs)
+	if err != nil {
+		panic(err)
 	}
+	return array
+}
 
-	switch cert.PublicKey.(type) {
-	case *rsa.PublicKey:
-		jsonPublicKey.Type = "rsa.PublicKey"
-	case *ecdsa.PublicKey:
-		jsonPublicKey.Type = "ecdsa.PublicKey"
-	case *dsa.PublicKey:
-		jsonPublicKey.Type = "dsa.PublicKey"
-	case *ed25519.PublicKey:
-		jsonPublicKey.Type = "ed25519.PublicKey"
-	default:
-		panic("unknown publickey format")
+//ToX509CertificateArrayArray converts the certificates back to *x509.Certificate
+//
+//Deprecated: ToX509CertificateArrayArray panics if a certificate can not be converted, certificates with a public key
+//which can not be converted have no PublicKey
+func ToX509CertificateArrayArray(certificates [][]*JsonX509Certificate) [][]*x509.Certificate {
+	certs, err := parseCertificateChains(certificates)
+	if err != nil && !isUnknownPublicKeyError(err) {
+		panic(err)
 	}
-	jsonX509Certificate.PublicKey = jsonPublicKey
+	return certs
+}
 
-	return jsonX509Certificate
+//ToX509CertificateArray converts the certificates back to *x509.Certificate
+//
+//Deprecated: ToX509CertificateArray panics if a certificate can not be converted, certificates with a public key which
+//can not be converted have no PublicKey
+func ToX509CertificateArray(certificates []*JsonX509Certificate) []*x509.Certificate {
+	certs, err := parseCertificates(certificates)
+	if err != nil && !isUnknownPublicKeyError(err) {
+		panic(err)
+	}
+	return certs
 }
-func NewJsonX509CertificateArray(certs []*x509.Certificate) []*JsonX509Certificate {
+
+func encodeCertificates(certs []*x509.Certificate) ([]*JsonX509Certificate, error) {
 	if certs == nil {
-		return nil
+		return nil, nil
 	}
 	var array = make([]*JsonX509Certificate, len(certs))
 	for k, v := range certs {
-		array[k] = NewJsonX509Certificate(v)
+		certificate, err := EncodeJsonX509Certificate(v)
+		if err != nil {
+			return nil, err
+		}
+		array[k] = certificate
 	}
 
-	return array
+	return array, nil
 
 }
-func NewJsonX509CertificateArrayArray(certs [][]*x509.Certificate) [][]*JsonX509Certificate {
+
+func encodeCertificateChains(certs [][]*x509.Certificate) ([][]*JsonX509Certificate, error) {
 	if certs == nil {
-		return nil
+		return nil, nil
 	}
 	var array = make([][]*JsonX509Certificate, len(certs))
 	for k, v := range certs {
-		array[k] = NewJsonX509CertificateArray(v)
+		chain, err := encodeCertificates(v)
+		if err != nil {
+			return nil, err
+		}
+		array[k] = chain
 	}
 
-	return array
+	return array, nil
 
 }
 
-func ToX509CertificateArrayArray(certificates [][]*JsonX509Certificate) [][]*x509.Certificate {
+//parseCertificateChains converts the chains back, the first error of a public key which can not be converted is
+//returned together with them
+func parseCertificateChains(certificates [][]*JsonX509Certificate) ([][]*x509.Certificate, error) {
 	if certificates == nil {
-		return nil
+		return nil, nil
 	}
 	certs := make([][]*x509.Certificate, len(certificates))
 
+	var keyErr error
 	for k, v := range certificates {
-		certs[k] = ToX509CertificateArray(v)
+		chain, err := parseCertificates(v)
+		if err != nil && !isUnknownPublicKeyError(err) {
+			return nil, err
+		}
+		if keyErr == nil {
+			keyErr = err
+		}
+		certs[k] = chain
 	}
 
-	return certs
+	return certs, keyErr
 
 }
-func ToX509CertificateArray(certificates []*JsonX509Certificate) []*x509.Certificate {
+
+//parseCertificates converts the certificates back, the first error of a public key which can not be converted is
+//returned together with them
+func parseCertificates(certificates []*JsonX509Certificate) ([]*x509.Certificate, error) {
 
 	if certificates == nil {
-		return nil
+		return nil, nil
 	}
 
 	var certs = make([]*x509.Certificate, len(certificates))
 
+	var keyErr error
 	for k, v := range certificates {
-		certs[k] = v.ToCertificate()
+		cert, err := v.Parse()
+		if err != nil && !isUnknownPublicKeyError(err) {
+			return nil, err
+		}
+		if keyErr == nil {
+			keyErr = err
+		}
+		certs[k] = cert
 	}
 
-	return certs
+	return certs, keyErr
 }
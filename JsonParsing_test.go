@@ -1,16 +1,24 @@
 package CachedHttpClient
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -45,8 +53,7 @@ func TestJsonX509Certificate(t *testing.T) {
 
 				publicKey = &key.PublicKey
 			case "ed25519.PublicKey":
-				publicKeyO, _, err := ed25519.GenerateKey(rand.Reader)
-				publicKey = &publicKeyO
+				publicKey, _, err = ed25519.GenerateKey(rand.Reader)
 				if err != nil {
 					t.Error(err)
 					t.FailNow()
@@ -58,7 +65,11 @@ func TestJsonX509Certificate(t *testing.T) {
 
 			certificate.PublicKey = publicKey
 
-			jsonX509Certificate := NewJsonX509Certificate(&certificate)
+			jsonX509Certificate, err := EncodeJsonX509Certificate(&certificate)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
 
 			jsonBytes, err := json.Marshal(jsonX509Certificate)
 			if err != nil {
@@ -71,18 +82,227 @@ func TestJsonX509Certificate(t *testing.T) {
 				t.Error(err)
 				t.FailNow()
 			}
-			toCertificate := recreatedJsonCert.ToCertificate()
+			toCertificate, err := recreatedJsonCert.Parse()
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
 			equal := certificate.Equal(toCertificate)
 
 			if !equal {
 				t.Error("not equal")
 				t.FailNow()
 			}
+			if fmt.Sprintf("%T", toCertificate.PublicKey) != fmt.Sprintf("%T", publicKey) {
+				t.Error("expected a", fmt.Sprintf("%T", publicKey), "got", fmt.Sprintf("%T", toCertificate.PublicKey))
+			}
 
 		})
 	}
 }
 
+func TestJsonX509Certificate_Errors(t *testing.T) {
+
+	key := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: &elliptic.CurveParams{Name: "secp256k1"}, X: big.NewInt(1), Y: big.NewInt(2)}}
+	var err error
+
+	tests := []struct {
+		name        string
+		certificate *JsonX509Certificate
+		expected    error
+	}{
+		{"unknown type", &JsonX509Certificate{PublicKey: &JsonPublicKey{Type: "x25519.PublicKey", PublicKey: []byte("{}")}}, UnknownPublicKeyTypeError},
+		{"unknown curve", &JsonX509Certificate{PublicKey: &JsonPublicKey{Type: "ecdsa.PublicKey", PublicKey: []byte(`{"Curve":{"Name":"P-192"}}`)}}, UnknownCurveError},
+		{"corrupted key", &JsonX509Certificate{PublicKey: &JsonPublicKey{Type: "rsa.PublicKey", PublicKey: []byte("{")}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.certificate.Parse()
+			if err == nil || (tt.expected != nil && !errors.Is(err, tt.expected)) {
+				t.Error("expected", tt.expected, "got", err)
+			}
+
+			_, err = (&JsonResponse{TLS: &JsonTlsConnectionState{PeerCertificates: []*JsonX509Certificate{tt.certificate}}}).Parse()
+			if err == nil {
+				t.Error("expected the error to be returned by JsonResponse.Parse")
+			}
+		})
+	}
+
+	_, err = EncodeJsonX509Certificate(&x509.Certificate{PublicKey: &key.PublicKey})
+	if !errors.Is(err, UnknownCurveError) {
+		t.Error("expected UnknownCurveError, got", err)
+	}
+	_, err = EncodeJsonX509Certificate(&x509.Certificate{PublicKey: "key"})
+	if !errors.Is(err, UnknownPublicKeyTypeError) {
+		t.Error("expected UnknownPublicKeyTypeError, got", err)
+	}
+	_, err = NewJsonResponse(&http.Response{
+		Body: ioutil.NopCloser(strings.NewReader("")),
+		TLS:  &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{PublicKey: "key"}}},
+	})
+	if !errors.Is(err, UnknownPublicKeyTypeError) {
+		t.Error("expected NewJsonResponse to return UnknownPublicKeyTypeError, got", err)
+	}
+}
+
+func TestJsonX509Certificate_Raw(t *testing.T) {
+
+	//the P-224 key of the certificate can not be converted field by field, it is parsed from the DER encoding
+	key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	issuer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "example.com"}, DNSNames: []string{"example.com"}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, issuer)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	jsonX509Certificate, err := EncodeJsonX509Certificate(certificate)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, verbose := range []bool{false, true} {
+		t.Run(fmt.Sprint("verbose ", verbose), func(t *testing.T) {
+
+			codec := JSONCodec
+			if verbose {
+				codec = NewVerboseCertificatesCodec(JSONCodec)
+			}
+			entry := &FileCacheEntry{Request: "key", Response: &JsonResponse{StatusCode: http.StatusOK, TLS: &JsonTlsConnectionState{
+				PeerCertificates: []*JsonX509Certificate{jsonX509Certificate},
+				VerifiedChains:   [][]*JsonX509Certificate{{jsonX509Certificate}},
+			}}}
+			var encoded bytes.Buffer
+			if err := codec.Encode(&encoded, entry); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if compact := strings.Count(encoded.String(), "DNSNames") == 0; compact == verbose {
+				t.Error("expected the fields to be stored only if verbose, got", encoded.String())
+			}
+			if !jsonX509Certificate.rawOnly() || entry.Response.TLS.PeerCertificates[0] != jsonX509Certificate {
+				t.Error("expected the encoded entry to be unchanged")
+			}
+
+			var decoded FileCacheEntry
+			if err := codec.NewDecoder(&encoded).Decode(&decoded); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			for _, recreatedJsonCert := range []*JsonX509Certificate{decoded.Response.TLS.PeerCertificates[0], decoded.Response.TLS.VerifiedChains[0][0]} {
+				recreated, err := recreatedJsonCert.Parse()
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				if !reflect.DeepEqual(recreated, certificate) {
+					t.Error("the certificate changed")
+				}
+			}
+		})
+	}
+
+	_, err = (&JsonX509Certificate{Raw: []byte("no certificate")}).Parse()
+	if err == nil {
+		t.Error("expected an error for an invalid DER encoding")
+	}
+}
+
+type testPublicKey struct {
+	ID string
+}
+
+type testPublicKeyCodec struct{}
+
+func (testPublicKeyCodec) Encode(publicKey interface{}) ([]byte, bool, error) {
+	key, ok := publicKey.(*testPublicKey)
+	if !ok {
+		return nil, false, nil
+	}
+	return []byte(key.ID), true, nil
+}
+
+func (testPublicKeyCodec) Decode(encoded []byte) (interface{}, error) {
+	return &testPublicKey{ID: string(encoded)}, nil
+}
+
+func TestRegisterPublicKeyCodec(t *testing.T) {
+
+	certificate := &x509.Certificate{SerialNumber: big.NewInt(1), PublicKey: &testPublicKey{ID: "key"}}
+	if _, err := EncodeJsonX509Certificate(certificate); !errors.Is(err, UnknownPublicKeyTypeError) {
+		t.Error("expected UnknownPublicKeyTypeError before registering the codec, got", err)
+	}
+
+	RegisterPublicKeyCodec("test.PublicKey", testPublicKeyCodec{})
+	defer UnregisterPublicKeyCodec("test.PublicKey")
+	curve := &elliptic.CurveParams{Name: "test-curve"}
+	RegisterEllipticCurve(curve)
+	defer UnregisterEllipticCurve(curve.Name)
+	curveKey := &ecdsa.PublicKey{Curve: curve, X: big.NewInt(1), Y: big.NewInt(2)}
+
+	for _, publicKey := range []interface{}{certificate.PublicKey, curveKey} {
+		encoded, err := EncodeJsonX509Certificate(&x509.Certificate{SerialNumber: big.NewInt(1), PublicKey: publicKey})
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		parsed, err := encoded.Parse()
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !reflect.DeepEqual(parsed.PublicKey, publicKey) {
+			t.Error("expected", publicKey, "got", parsed.PublicKey)
+		}
+	}
+
+	UnregisterPublicKeyCodec("test.PublicKey")
+	UnregisterEllipticCurve(curve.Name)
+	if _, err := EncodeJsonX509Certificate(certificate); !errors.Is(err, UnknownPublicKeyTypeError) {
+		t.Error("expected UnknownPublicKeyTypeError after unregistering the codec, got", err)
+	}
+	if _, err := EncodeJsonX509Certificate(&x509.Certificate{SerialNumber: big.NewInt(1), PublicKey: curveKey}); !errors.Is(err, UnknownCurveError) {
+		t.Error("expected UnknownCurveError after unregistering the curve, got", err)
+	}
+}
+
+func TestJsonX509Certificate_UnknownPublicKey(t *testing.T) {
+
+	certificate := &JsonX509Certificate{SerialNumber: big.NewInt(7), PublicKey: &JsonPublicKey{Type: "ed448.PublicKey", PublicKey: []byte("{}")}}
+
+	parsed, err := certificate.Parse()
+	if !errors.Is(err, UnknownPublicKeyTypeError) || parsed == nil || parsed.SerialNumber.Int64() != 7 || parsed.PublicKey != nil {
+		t.Error("expected the certificate without public key and UnknownPublicKeyTypeError, got", parsed, err)
+	}
+	if converted := certificate.ToCertificate(); converted == nil || converted.PublicKey != nil {
+		t.Error("expected ToCertificate to return the certificate without public key, got", converted)
+	}
+
+	response := &JsonResponse{StatusCode: http.StatusOK, TLS: &JsonTlsConnectionState{PeerCertificates: []*JsonX509Certificate{certificate}}}
+	res, err := response.Parse()
+	if !errors.Is(err, UnknownPublicKeyTypeError) || res == nil || len(res.TLS.PeerCertificates) != 1 {
+		t.Error("expected the response together with UnknownPublicKeyTypeError, got", res, err)
+	}
+	if res := response.ToResponse(); res.StatusCode != http.StatusOK {
+		t.Error("expected ToResponse to return the response, got", res.StatusCode)
+	}
+}
+
 func TestJsonTlsConnectionState_ToConnectionState(t *testing.T) {
 
 	state := &JsonTlsConnectionState{}
@@ -114,3 +334,127 @@ func TestNewJsonResponse(t *testing.T) {
 	}
 
 }
+
+func TestJsonResponse_Parse_Independent(t *testing.T) {
+
+	response := &JsonResponse{
+		StatusCode:  http.StatusOK,
+		Header:      http.Header{"Content-Type": {"text/plain"}},
+		Body:        []byte(strings.Repeat("body", 1000)),
+		Trailer:     http.Header{"X-Trailer": {"value"}},
+		VaryHeaders: http.Header{"Accept": {"text/plain"}},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := response.Parse()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Header.Set("Warning", `110 - "Response is Stale"`)
+			res.Trailer.Del("X-Trailer")
+			res.Request.Header.Del("Accept")
+			body, _ := ioutil.ReadAll(res.Body)
+			if string(body) != string(response.Body) {
+				t.Error("expected the complete body got", len(body), "bytes")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if response.Header.Get("Warning") != "" || response.Trailer.Get("X-Trailer") == "" || response.VaryHeaders.Get("Accept") == "" {
+		t.Error("expected the JsonResponse to be unchanged got", response.Header, response.Trailer, response.VaryHeaders)
+	}
+}
+
+func TestJsonResponse_Clone(t *testing.T) {
+
+	response := &JsonResponse{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/plain"}},
+		Body:       []byte("body"),
+		TLS:        &JsonTlsConnectionState{OCSPResponse: []byte("ocsp")},
+	}
+
+	clone := response.Clone()
+	clone.Header.Set("Content-Type", "application/json")
+	clone.Body[0] = 'B'
+	clone.TLS.OCSPResponse[0] = 'O'
+
+	if response.Header.Get("Content-Type") != "text/plain" || string(response.Body) != "body" || string(response.TLS.OCSPResponse) != "ocsp" {
+		t.Error("expected the original to be unchanged got", response.Header, string(response.Body), string(response.TLS.OCSPResponse))
+	}
+	if clone.Trailer != nil || (*JsonResponse)(nil).Clone() != nil {
+		t.Error("expected nil to stay nil")
+	}
+}
+
+func TestJsonResponse_Request(t *testing.T) {
+
+	request, _ := http.NewRequest(http.MethodGet, "https://example.com/articles/1?page=2", nil)
+	request.Header.Set("Accept", "text/html")
+	request.Header.Set("Authorization", "Bearer secret")
+	request.Header.Set("Cookie", "session=secret")
+	response := &http.Response{
+		StatusCode: http.StatusFound,
+		Header:     http.Header{"Location": {"../2"}, "Vary": {"Cookie"}},
+		Body:       http.NoBody,
+		Request:    request,
+	}
+	jsonResponse, err := NewJsonResponse(response)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	marshal, err := json.Marshal(jsonResponse)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if strings.Contains(string(marshal), "Bearer secret") {
+		t.Error("expected the Authorization header not to be stored got", string(marshal))
+	}
+
+	var decoded JsonResponse
+	if err := json.Unmarshal(marshal, &decoded); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	res, err := decoded.Parse()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if res.Request == nil || res.Request.Method != http.MethodGet || res.Request.Host != "example.com" {
+		t.Error("expected the original request got", res.Request)
+		t.FailNow()
+	}
+	location, _ := res.Location()
+	if location == nil || location.String() != "https://example.com/2" {
+		t.Error("expected the relative redirect to be resolved against the original URL got", location)
+	}
+	if res.Request.Header.Get("Accept") != "text/html" || res.Request.Header.Get("Authorization") != "" {
+		t.Error("expected the headers without credentials got", res.Request.Header)
+	}
+	if res.Request.Header.Get("Cookie") != "session=secret" {
+		t.Error("expected the header the response varies on got", res.Request.Header)
+	}
+
+	var legacy JsonResponse
+	if err := json.Unmarshal([]byte(`{"StatusCode":200,"Request":""}`), &legacy); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	res, err = legacy.Parse()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if res.Request != nil {
+		t.Error("expected no request for entries of earlier versions got", res.Request)
+	}
+}
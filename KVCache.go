@@ -0,0 +1,260 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/kvcache"
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//KVStore is a remote key value store like Redis or Memcached. The package memcache implements it for Memcached, other
+//stores are plugged in by a small adapter around the client of the application, see the README for Redis. The methods
+//give up once ctx is done, for Get and Set it is the context of the request
+type KVStore = kvcache.Store
+
+//KVCache stores responses in a KVStore. The headers and the body of a response are stored under separate keys so
+//Peek and Fresh only transfer the headers, Get reads both keys in one round trip
+type KVCache struct {
+	//store holds the keys of the responses under the Prefix
+	store *kvcache.Cache
+	KVCacheOptions
+}
+
+type KVCacheOptions struct {
+	MapCacheOptions
+	//Codec encodes the headers, JSONCodec if nil
+	Codec Codec
+	//Compression compresses the bodies if not nil
+	Compression *Compression
+	//Digester hashes the keys to the keys in the store, SHA256Digester if nil
+	Digester Digester
+	//Prefix starts all keys in the store, "cachedhttp:" if empty. It is read by NewKVCache
+	Prefix string
+	//Shared selects the freshness rules of a shared cache
+	Shared bool
+	//Expire lets the keys expire MaxStale after the response stops being fresh, responses already expired for
+	//longer are not stored
+	Expire   bool
+	MaxStale time.Duration
+	//Clock tells the time the TTLs of Expire and Fresh are computed with, SystemClock if nil
+	Clock Clock
+}
+
+//NewKVCache creates a KVCache storing its responses in store
+func NewKVCache(store KVStore, options ...KVCacheOptions) *KVCache {
+
+	kvCache := &KVCache{}
+	if options != nil {
+		kvCache.KVCacheOptions = options[0]
+	}
+	kvCache.store = kvcache.New(store, kvCache.prefix())
+	return kvCache
+}
+
+func (o KVCacheOptions) codec() Codec {
+	if o.Codec == nil {
+		return JSONCodec
+	}
+	return o.Codec
+}
+
+//Key returns the key the response for req is stored under
+func (k *KVCache) Key(req *http.Request) (string, error) {
+	return k.MapCacheOptions.key(req)
+}
+
+//headKey and bodyKey return the keys below the Prefix holding the headers and the body of the response stored under key
+func (k *KVCache) headKey(key string) string {
+	return "h:" + hexDigest(k.Digester, []byte(key))
+}
+
+func (k *KVCache) bodyKey(key string) string {
+	return "b:" + hexDigest(k.Digester, []byte(key))
+}
+
+func (k *KVCache) prefix() string {
+	if k.Prefix == "" {
+		return "cachedhttp:"
+	}
+	return k.Prefix
+}
+
+func (k *KVCache) Get(req *http.Request) (*http.Response, error) {
+
+	key, err := k.Key(req)
+	if err != nil {
+		return nil, err
+	}
+	return k.get(req.Context(), key)
+}
+
+func (k *KVCache) Set(req *http.Request, res *http.Response) error {
+
+	key, err := k.Key(req)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if k.Expire {
+		now := clockOrDefault(k.Clock).Now()
+		ttl = policy.FreshnessLifetime(res, k.Shared) - policy.CurrentAge(res, now) + k.MaxStale
+		if ttl <= 0 {
+			//the response expired too long ago to be served
+			return nil
+		}
+	}
+
+	response, err := NewJsonResponse(res)
+	if err != nil {
+		return err
+	}
+	err = k.Compression.compress(response)
+	if err != nil {
+		return err
+	}
+	body := response.Body
+	response.Body = nil
+
+	var head bytes.Buffer
+	err = k.codec().Encode(&head, &FileCacheEntry{Version: EntrySchemaVersion, Request: key, Response: response})
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		body = []byte{}
+	}
+	return k.store.MSet(req.Context(), map[string][]byte{k.headKey(key): head.Bytes(), k.bodyKey(key): body}, ttl)
+}
+
+//decodeHead decodes the headers stored under key
+func (k *KVCache) decodeHead(key string, head []byte) (*JsonResponse, error) {
+
+	if head == nil {
+		return nil, NotInCacheError
+	}
+	var entry FileCacheEntry
+	err := decodeEntry(k.codec().NewDecoder(bytes.NewReader(head)), &entry)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Request != key || entry.Response == nil {
+		return nil, NotInCacheError
+	}
+	return entry.Response, nil
+}
+
+//GetKey returns the response stored under key, its headers and body are read in one round trip
+func (k *KVCache) GetKey(key string) (*http.Response, error) {
+	return k.get(context.Background(), key)
+}
+
+func (k *KVCache) get(ctx context.Context, key string) (*http.Response, error) {
+
+	values, err := k.store.MGet(ctx, k.headKey(key), k.bodyKey(key))
+	if err != nil {
+		return nil, err
+	}
+	response, err := k.decodeHead(key, values[0])
+	if err != nil {
+		return nil, err
+	}
+	if values[1] == nil {
+		return nil, NotInCacheError
+	}
+	response.Body = values[1]
+	res, err := response.Parse()
+	return res, wrapDecodeError(err)
+}
+
+//Peek returns the response stored under key without transferring its body, the body of the response is empty
+func (k *KVCache) Peek(ctx context.Context, key string) (*http.Response, error) {
+
+	values, err := k.store.MGet(ctx, k.headKey(key))
+	if err != nil {
+		return nil, err
+	}
+	response, err := k.decodeHead(key, values[0])
+	if err != nil {
+		return nil, err
+	}
+	//the body is not read so there is nothing to decompress
+	response.BodyCompression = ""
+	res, err := response.Parse()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = http.NoBody
+	return res, nil
+}
+
+//Fresh reports if the response for req is stored and fresh without transferring its body
+func (k *KVCache) Fresh(req *http.Request) (bool, error) {
+
+	key, err := k.Key(req)
+	if err != nil {
+		return false, err
+	}
+	res, err := k.Peek(req.Context(), key)
+	if err != nil {
+		return false, err
+	}
+	return policy.IsFresh(res, k.Shared, clockOrDefault(k.Clock).Now()), nil
+}
+
+//Capabilities reports TTLCapability and IterationCapability if the KVStore can Scan
+func (k *KVCache) Capabilities() CapabilitySet {
+	if k.store.SupportsScan() {
+		return TTLCapability | IterationCapability
+	}
+	return TTLCapability
+}
+
+//Keys returns the sorted keys of all responses, only their headers are transferred
+func (k *KVCache) Keys() []string {
+
+	ctx := context.Background()
+	heads, err := k.store.Scan(ctx, "h:")
+	if err != nil || len(heads) == 0 {
+		return nil
+	}
+	values, err := k.store.MGet(ctx, heads...)
+	if err != nil {
+		return nil
+	}
+	var keys []string
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		var entry FileCacheEntry
+		if decodeEntry(k.codec().NewDecoder(bytes.NewReader(value)), &entry) == nil {
+			keys = append(keys, entry.Request)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Values returns a cache for other values than responses in the same store, with the keys under namespace below the
+//Prefix. E.g. the results of computations are cached beside the responses with one store and one expiry
+//
+//	users := kvCache.Values("users")
+//	user, err := users.GetOrLoad(ctx, id, time.Hour, loadUser)
+func (k *KVCache) Values(namespace string) *kvcache.Cache {
+	return k.store.Namespace("v:" + namespace)
+}
+
+//DeleteKey removes the headers and the body stored under key
+func (k *KVCache) DeleteKey(key string) error {
+
+	ctx := context.Background()
+	if _, err := k.Peek(ctx, key); err != nil {
+		return err
+	}
+	return k.store.Del(ctx, k.headKey(key), k.bodyKey(key))
+}
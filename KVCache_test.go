@@ -0,0 +1,174 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//memoryKVStore is a KVStore in memory counting the round trips and the bytes read
+type memoryKVStore struct {
+	mutex      sync.Mutex
+	values     map[string][]byte
+	ttls       map[string]time.Duration
+	roundTrips int
+	bytesRead  int
+}
+
+func newMemoryKVStore() *memoryKVStore {
+	return &memoryKVStore{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (m *memoryKVStore) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.roundTrips++
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = m.values[key]
+		m.bytesRead += len(values[i])
+	}
+	return values, nil
+}
+
+func (m *memoryKVStore) MSet(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for key, value := range values {
+		m.values[key] = value
+		m.ttls[key] = ttl
+	}
+	return nil
+}
+
+func (m *memoryKVStore) Del(ctx context.Context, keys ...string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+func (m *memoryKVStore) Scan(ctx context.Context, prefix string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var keys []string
+	for key := range m.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func TestKVCache(t *testing.T) {
+
+	store := newMemoryKVStore()
+	cache := NewKVCache(store, KVCacheOptions{Compression: &Compression{}, Expire: true, MaxStale: time.Hour})
+
+	body := strings.Repeat("large body ", 100000)
+	response := lruTestResponse(body)
+	response.Header.Set("Cache-Control", "max-age=60")
+	if err := cache.Set(lruTestRequest(t, "/large"), response); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expired := lruTestResponse("expired")
+	expired.Header.Set("Cache-Control", "max-age=60")
+	expired.Header.Set("Age", "7200")
+	if err := cache.Set(lruTestRequest(t, "/expired"), expired); err != nil {
+		t.Error(err)
+	}
+	if len(store.values) != 2 {
+		t.Error("expected the headers and the body of the fresh response only got", len(store.values), "keys")
+	}
+	for key, ttl := range store.ttls {
+		if ttl < time.Hour || ttl > time.Hour+time.Minute {
+			t.Error("expected the keys to expire after the stale period got", key, ttl)
+		}
+	}
+
+	fresh, err := cache.Fresh(lruTestRequest(t, "/large"))
+	if err != nil || !fresh {
+		t.Error("expected a fresh response got", fresh, err)
+	}
+	if store.roundTrips != 1 || store.bytesRead > 1024 {
+		t.Error("expected only the headers to be read got", store.roundTrips, "round trips and", store.bytesRead, "bytes")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := cache.Get(lruTestRequest(t, "/large").WithContext(cancelled)); !errors.Is(err, context.Canceled) {
+		t.Error("expected the context of the request to reach the store got", err)
+	}
+
+	store.roundTrips = 0
+	res, err := cache.Get(lruTestRequest(t, "/large"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if read, _ := ioutil.ReadAll(res.Body); string(read) != body {
+		t.Error("expected the stored body got", len(read), "bytes")
+	}
+	if store.roundTrips != 1 {
+		t.Error("expected the headers and the body to be read in one round trip got", store.roundTrips)
+	}
+
+	keys := cache.Keys()
+	if len(keys) != 1 {
+		t.Error("expected one key got", keys)
+		t.FailNow()
+	}
+	if err := cache.DeleteKey(keys[0]); err != nil {
+		t.Error(err)
+	}
+	if _, err := cache.Get(lruTestRequest(t, "/large")); !errors.Is(err, NotInCacheError) {
+		t.Error("expected NotInCacheError got", err)
+	}
+	if len(store.values) != 0 {
+		t.Error("expected the headers and the body to be deleted got", len(store.values), "keys")
+	}
+}
+
+func TestKVCache_Values(t *testing.T) {
+
+	ctx := context.Background()
+	store := newMemoryKVStore()
+	kvCache := NewKVCache(store, KVCacheOptions{Prefix: "app:"})
+	if err := kvCache.Set(lruTestRequest(t, "/a"), lruTestResponse("a")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	users := kvCache.Values("users")
+	value, err := users.GetOrLoad(ctx, "1", time.Hour, func(ctx context.Context) ([]byte, error) {
+		return []byte("alice"), nil
+	})
+	if err != nil || string(value) != "alice" {
+		t.Error("unexpected value", string(value), err)
+	}
+	if string(store.values["app:v:users:1"]) != "alice" || store.ttls["app:v:users:1"] != time.Hour {
+		t.Error("expected the value to be stored beside the responses", store.values)
+	}
+	if keys := kvCache.Keys(); len(keys) != 1 {
+		t.Error("expected only the response in the keys, got", keys)
+	}
+	metrics := NewMetrics()
+	metrics.ObserveValues("users", users)
+	var prometheus strings.Builder
+	if err := metrics.WritePrometheus(&prometheus); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(prometheus.String(), `cachedhttpclient_values_loads_total{cache="users"} 1`) {
+		t.Error("expected the loads of the values in the metrics, got", prometheus.String())
+	}
+}
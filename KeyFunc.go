@@ -0,0 +1,122 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//KeyOptions describes which parts of a request NewKeyFunc includes in the cache key, the method, host and path
+//are always included
+type KeyOptions struct {
+	//Headers are included with their values
+	Headers []string
+	//HashedHeaders are included as hash of their values, e.g. Authorization
+	HashedHeaders []string
+	//Digester hashes the HashedHeaders, SHA256Digester if nil
+	Digester Digester
+	//IgnoreQueryParameters are removed from the query, e.g. tracking tokens
+	IgnoreQueryParameters []string
+	//NormalizedHeaders are included with the value returned by their normalizer, e.g. AcceptClassNormalizers
+	NormalizedHeaders map[string]HeaderNormalizer
+	//Pagination canonicalizes the pagination parameters of the query if not nil, e.g. &DefaultPagination
+	Pagination *PaginationOptions
+}
+
+//NewKeyFunc creates a KeyFunc for MapCacheOptions building keys from the request parts selected by options.
+//The keys have the format of a request dump so they work with MapCache.MigrateHosts
+func NewKeyFunc(options KeyOptions) func(req *http.Request) string {
+
+	headers := canonicalHeaderNames(options.Headers)
+	hashedHeaders := canonicalHeaderNames(options.HashedHeaders)
+
+	normalizers := map[string]HeaderNormalizer{}
+	var normalizedHeaders []string
+	for name, normalizer := range options.NormalizedHeaders {
+		name = http.CanonicalHeaderKey(name)
+		normalizers[name] = normalizer
+		normalizedHeaders = append(normalizedHeaders, name)
+	}
+	sort.Strings(normalizedHeaders)
+	digester := digestOrDefault(options.Digester)
+
+	return func(req *http.Request) string {
+
+		var key strings.Builder
+
+		key.WriteString(req.Method)
+		key.WriteString(" ")
+		key.WriteString(keyRequestURI(req, options.IgnoreQueryParameters, options.Pagination))
+		key.WriteString("\r\nHost: ")
+		key.WriteString(requestHost(req))
+		key.WriteString("\r\n")
+
+		for _, name := range headers {
+			for _, value := range req.Header[name] {
+				key.WriteString(name + ": " + value + "\r\n")
+			}
+		}
+		for _, name := range hashedHeaders {
+			values, ok := req.Header[name]
+			if !ok {
+				continue
+			}
+			key.WriteString(name + ": " + digester.Name() + "=" + hexDigest(digester, []byte(strings.Join(values, "\n"))) + "\r\n")
+		}
+		for _, name := range normalizedHeaders {
+			key.WriteString(name + ": " + normalizedHeader(req.Header, name, normalizers) + "\r\n")
+		}
+
+		key.WriteString("\r\n")
+		return key.String()
+	}
+}
+
+//keyRequestURI returns the path and the query of req with the ignored parameters removed, the pagination parameters
+//canonicalized and the remaining sorted
+func keyRequestURI(req *http.Request, ignoreQueryParameters []string, pagination *PaginationOptions) string {
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+
+	query := req.URL.Query()
+	for _, name := range ignoreQueryParameters {
+		query.Del(name)
+	}
+	pagination.canonicalize(query)
+	if encoded := query.Encode(); encoded != "" {
+		uri += "?" + encoded
+	}
+
+	return uri
+}
+
+//requestHost returns the host the request is sent to
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+//withKeyLine adds the line name: value after the request line of key, keys keep the request dump format
+func withKeyLine(key string, name string, value string) string {
+	if value == "" {
+		return key
+	}
+	line := name + ": " + value + "\r\n"
+	if index := strings.Index(key, "\r\n"); index >= 0 {
+		return key[:index+2] + line + key[index+2:]
+	}
+	return key + "\r\n" + line
+}
+
+func canonicalHeaderNames(names []string) []string {
+	canonical := make([]string, len(names))
+	for k, name := range names {
+		canonical[k] = http.CanonicalHeaderKey(name)
+	}
+	return canonical
+}
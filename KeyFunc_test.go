@@ -0,0 +1,138 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewKeyFunc(t *testing.T) {
+
+	keyFunc := NewKeyFunc(KeyOptions{
+		Headers:               []string{"accept-language"},
+		HashedHeaders:         []string{"Authorization"},
+		IgnoreQueryParameters: []string{"utm_source"},
+	})
+
+	newRequest := func(url string, header http.Header) *http.Request {
+		request, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header = header
+		return request
+	}
+
+	base := keyFunc(newRequest("http://example.com/path?b=2&a=1", http.Header{"Accept-Language": {"en"}}))
+
+	if key := keyFunc(newRequest("http://example.com/path?a=1&utm_source=mail&b=2", http.Header{"Accept-Language": {"en"}, "X-Request-Id": {"1"}})); key != base {
+		t.Error("keys differ", key, base)
+	}
+	if key := keyFunc(newRequest("http://example.com/path?a=1&b=2", http.Header{"Accept-Language": {"de"}})); key == base {
+		t.Error("selected header not part of the key")
+	}
+
+	authorized := keyFunc(newRequest("http://example.com/path", http.Header{"Authorization": {"Bearer secret"}}))
+	if strings.Contains(authorized, "secret") {
+		t.Error("hashed header in plain text", authorized)
+	}
+	if key := keyFunc(newRequest("http://example.com/path", http.Header{"Authorization": {"Bearer other"}})); key == authorized {
+		t.Error("hashed header not part of the key")
+	}
+
+	if _, ok := rewriteDumpHost(base, map[string]string{"example.com": "example.org"}); !ok {
+		t.Error("key not migratable", base)
+	}
+
+}
+
+func TestMapCache_KeyFunc(t *testing.T) {
+
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{IgnoreQueryParameters: []string{"token"}})})
+
+	request, err := http.NewRequest("GET", "http://example.com/?token=1", nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = cache.Set(request, &http.Response{StatusCode: http.StatusOK, Body: http.NoBody})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	request, err = http.NewRequest("GET", "http://example.com/?token=2", nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = cache.Get(request)
+	if err != nil {
+		t.Error(err)
+	}
+
+}
+
+func TestMapCache_Key_RequestDump(t *testing.T) {
+
+	//without KeyFunc the keys stay the request dumps the entries were stored under before it was added
+	for _, options := range []MapCacheOptions{{}, {IgnoreRequestBody: true}, {DontIncludeAllRequestHeaders: true}} {
+		newRequest := func() *http.Request {
+			req, err := http.NewRequest(http.MethodPost, "http://example.com/search", strings.NewReader("q=cache"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return req
+		}
+		expected, err := DumpRequest(newRequest(), !options.IgnoreRequestBody, options.DontIncludeAllRequestHeaders)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key, err := NewMapCache(options).Key(newRequest()); err != nil || key != string(expected) {
+			t.Errorf("%+v: expected the request dump as key, got %q %v", options, key, err)
+		}
+	}
+}
+
+func TestNewKeyFunc_Pagination(t *testing.T) {
+
+	pagination := DefaultPagination
+	pagination.DefaultLimit = 20
+	keyFunc := NewKeyFunc(KeyOptions{Pagination: &pagination})
+
+	tests := []struct {
+		name  string
+		urls  []string
+		equal bool
+	}{
+		{"spellings", []string{"/items?page=2&per_page=10", "/items?p=2&limit=10", "/items?offset=10&page_size=10"}, true},
+		{"leading zeros", []string{"/items?page=02", "/items?page=2"}, true},
+		{"first page", []string{"/items", "/items?page=1", "/items?offset=0", "/items?limit=20"}, true},
+		{"page to offset with default limit", []string{"/items?page=3", "/items?offset=40"}, true},
+		{"cursor", []string{"/items?after=abc&pageSize=5", "/items?cursor=abc&limit=5"}, true},
+		{"different pages", []string{"/items?page=2", "/items?page=3"}, false},
+		{"different cursors", []string{"/items?cursor=abc", "/items?cursor=abd"}, false},
+		{"different limits", []string{"/items?page=2&limit=10", "/items?page=2&limit=11"}, false},
+		{"no number", []string{"/items?page=last", "/items?p=last"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			for _, url := range tt.urls {
+				request, err := http.NewRequest("GET", "http://example.com"+url, nil)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				keys = append(keys, keyFunc(request))
+			}
+			for _, key := range keys[1:] {
+				if (key == keys[0]) != tt.equal {
+					t.Errorf("expected equal %v for %q and %q", tt.equal, keys[0], key)
+				}
+			}
+		})
+	}
+}
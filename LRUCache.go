@@ -0,0 +1,298 @@
+package CachedHttpClient
+
+import (
+	"container/list"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//LRUCache caches responses in memory up to a maximum number of entries and body bytes, evicting the least
+//recently used entries first
+type LRUCache struct {
+	LRUCacheOptions
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	//recency holds the *lruEntry values, the most recently used at the front. With EvictionSamples it holds them in
+	//the order they were stored and the use is tracked by lruEntry.used
+	recency *list.List
+	bytes   int64
+	//sampled holds the entries to draw the eviction samples from if EvictionSamples > 0
+	sampled []*lruEntry
+	clock   uint64
+	random  *rand.Rand
+}
+
+type LRUCacheOptions struct {
+	MapCacheOptions
+	//MaxEntries limits the number of entries, 0 means no limit
+	MaxEntries int
+	//MaxBytes limits the sum of the body sizes, 0 means no limit. Bodies larger than MaxBytes are not stored
+	MaxBytes int64
+	//OversizedPrefix > 0 stores a partial entry for responses with a body larger than MaxBytes, holding the first
+	//OversizedPrefix body bytes and a summary of the body, see PartialEntryClass. Partial entries are not served
+	OversizedPrefix int
+	//Digester hashes the bodies of partial entries, SHA256Digester if nil
+	Digester Digester
+	//OnEvict is called with the key of every entry evicted to stay within the limits if not nil, e.g. Metrics.Evicted
+	OnEvict func(key string)
+	//EvictionSamples > 0 approximates LRU like Redis does: each eviction draws EvictionSamples random entries and
+	//evicts the least recently used of them, hits only record the time of use. Larger samples are closer to LRU, 5
+	//is a good start for large caches
+	EvictionSamples int
+}
+
+type lruEntry struct {
+	//hits is accessed atomically and kept first for the 64-bit alignment atomic requires on 32-bit platforms
+	hits     int64
+	key      string
+	response *http.Response
+	body     []byte
+	//partial is set for the summaries of oversized responses
+	partial bool
+	//used and index are the time of the last use and the position in LRUCache.sampled if EvictionSamples > 0
+	used  uint64
+	index int
+}
+
+func NewLRUCache(options LRUCacheOptions) *LRUCache {
+	return &LRUCache{
+		LRUCacheOptions: options,
+		entries:         map[string]*list.Element{},
+		recency:         list.New(),
+		random:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+//Key returns the key the response for req is stored under
+func (l *LRUCache) Key(req *http.Request) (string, error) {
+	return l.MapCacheOptions.key(req)
+}
+
+func (l *LRUCache) Get(req *http.Request) (*http.Response, error) {
+
+	//the plain request dump is looked up without allocating the key
+	buffer := keyBuffers.Get().(*[]byte)
+	defer keyBuffers.Put(buffer)
+	dump, dumped := l.appendKey((*buffer)[:0], req)
+	*buffer = dump
+	key := ""
+	if !dumped {
+		var err error
+		key, err = l.Key(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var element *list.Element
+	var ok bool
+	if dumped {
+		element, ok = l.entries[string(dump)]
+	} else {
+		element, ok = l.entries[key]
+	}
+	if !ok || element.Value.(*lruEntry).partial {
+		return nil, NotInCacheError
+	}
+	l.use(element)
+	atomic.AddInt64(&element.Value.(*lruEntry).hits, 1)
+
+	return element.Value.(*lruEntry).toResponse(), nil
+}
+
+func (l *LRUCache) Set(req *http.Request, res *http.Response) error {
+
+	body, err := bufferBody(res)
+	if err != nil {
+		return err
+	}
+
+	key, err := l.Key(req)
+	if err != nil {
+		return err
+	}
+
+	stored := *res
+	stored.Body = nil
+	entry := &lruEntry{key: key, response: &stored, body: body}
+	if l.MaxBytes > 0 && int64(len(body)) > l.MaxBytes && l.OversizedPrefix > 0 {
+		prefix := l.OversizedPrefix
+		if int64(prefix) > l.MaxBytes {
+			prefix = int(l.MaxBytes)
+		}
+		partial := partialResponse(res, body, prefix, l.Digester)
+		entry = &lruEntry{key: key, response: partial, body: body[:partial.ContentLength], partial: true}
+		partial.Body = nil
+	}
+
+	l.mutex.Lock()
+
+	if element, ok := l.entries[key]; ok {
+		l.remove(element)
+	}
+	if l.MaxBytes > 0 && int64(len(entry.body)) > l.MaxBytes {
+		l.mutex.Unlock()
+		return nil
+	}
+
+	l.entries[key] = l.recency.PushFront(entry)
+	l.bytes += int64(len(entry.body))
+	if l.EvictionSamples > 0 {
+		entry.index = len(l.sampled)
+		l.sampled = append(l.sampled, entry)
+		l.clock++
+		entry.used = l.clock
+	}
+
+	var evicted []string
+	for (l.MaxEntries > 0 && len(l.entries) > l.MaxEntries) || (l.MaxBytes > 0 && l.bytes > l.MaxBytes) {
+		evicted = append(evicted, l.remove(l.victim()))
+	}
+	l.mutex.Unlock()
+
+	//OnEvict is called without holding the mutex so it may use the cache
+	if l.OnEvict != nil {
+		for _, key := range evicted {
+			l.OnEvict(key)
+		}
+	}
+
+	return nil
+}
+
+//use marks the entry of element as the most recently used, the caller holds the mutex
+func (l *LRUCache) use(element *list.Element) {
+	if l.EvictionSamples <= 0 {
+		l.recency.MoveToFront(element)
+		return
+	}
+	l.clock++
+	element.Value.(*lruEntry).used = l.clock
+}
+
+//victim returns the element to evict next, the caller holds the mutex
+func (l *LRUCache) victim() *list.Element {
+
+	if l.EvictionSamples <= 0 {
+		return l.recency.Back()
+	}
+
+	var oldest *lruEntry
+	if l.EvictionSamples >= len(l.sampled) {
+		//the samples would cover the whole cache, so the least recently used entry is evicted
+		for _, entry := range l.sampled {
+			if oldest == nil || entry.used < oldest.used {
+				oldest = entry
+			}
+		}
+		return l.entries[oldest.key]
+	}
+	for i := 0; i < l.EvictionSamples; i++ {
+		entry := l.sampled[l.random.Intn(len(l.sampled))]
+		if oldest == nil || entry.used < oldest.used {
+			oldest = entry
+		}
+	}
+	return l.entries[oldest.key]
+}
+
+//remove deletes the entry of element and returns its key, the caller holds the mutex
+func (l *LRUCache) remove(element *list.Element) string {
+	entry := l.recency.Remove(element).(*lruEntry)
+	delete(l.entries, entry.key)
+	l.bytes -= int64(len(entry.body))
+	if l.EvictionSamples > 0 {
+		last := l.sampled[len(l.sampled)-1]
+		l.sampled[entry.index] = last
+		last.index = entry.index
+		l.sampled[len(l.sampled)-1] = nil
+		l.sampled = l.sampled[:len(l.sampled)-1]
+	}
+	return entry.key
+}
+
+//Len returns the number of entries
+func (l *LRUCache) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.entries)
+}
+
+//Bytes returns the sum of the body sizes of all entries
+func (l *LRUCache) Bytes() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.bytes
+}
+
+//Keys returns the sorted keys of all entries
+func (l *LRUCache) Keys() []string {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	keys := make([]string, 0, len(l.entries))
+	for key := range l.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//GetKey returns the response stored under key without marking it as used
+func (l *LRUCache) GetKey(key string) (*http.Response, error) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, NotInCacheError
+	}
+	return element.Value.(*lruEntry).toResponse(), nil
+}
+
+//PeekKey returns the response stored under key without its body and without marking it as used
+func (l *LRUCache) PeekKey(key string) (*http.Response, int64, int64, error) {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, 0, 0, NotInCacheError
+	}
+	return element.Value.(*lruEntry).peek()
+}
+
+//DeleteKey removes the entry stored under key
+func (l *LRUCache) DeleteKey(key string) error {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return NotInCacheError
+	}
+	l.remove(element)
+	return nil
+}
+
+//peek returns the stored response without its body, the size of the body and the hits
+func (e *lruEntry) peek() (*http.Response, int64, int64, error) {
+	return peekedResponse(e.response), int64(len(e.body)), atomic.LoadInt64(&e.hits), nil
+}
+
+//toResponse returns a copy of the stored response with its own body reader
+func (e *lruEntry) toResponse() *http.Response {
+	return deferTrailer(newHitResponse(e.response, e.body))
+}
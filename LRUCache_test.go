@@ -0,0 +1,232 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func lruTestRequest(t *testing.T, path string) *http.Request {
+	request, err := http.NewRequest("GET", "http://example.com"+path, nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	return request
+}
+
+func lruTestResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(strings.NewReader(body))}
+}
+
+func TestLRUCache(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		options  LRUCacheOptions
+		bodies   []string
+		touch    string
+		expected []string
+		missing  []string
+	}{
+		{"max entries", LRUCacheOptions{MaxEntries: 2}, []string{"a", "b", "c"}, "", []string{"/b", "/c"}, []string{"/a"}},
+		{"max entries with touch", LRUCacheOptions{MaxEntries: 2}, []string{"a", "b"}, "/a", []string{"/a", "/c"}, []string{"/b"}},
+		{"max bytes", LRUCacheOptions{MaxBytes: 5}, []string{"aa", "bb", "cc"}, "", []string{"/b", "/c"}, []string{"/a"}},
+		{"body larger than max bytes", LRUCacheOptions{MaxBytes: 1}, []string{"a", "bb"}, "", []string{"/a"}, []string{"/b"}},
+		{"no limits", LRUCacheOptions{}, []string{"a", "b", "c"}, "", []string{"/a", "/b", "/c"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewLRUCache(tt.options)
+			paths := []string{"/a", "/b", "/c"}
+			for k, body := range tt.bodies {
+				if err := cache.Set(lruTestRequest(t, paths[k]), lruTestResponse(body)); err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+			}
+			if tt.touch != "" {
+				if _, err := cache.Get(lruTestRequest(t, tt.touch)); err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				if err := cache.Set(lruTestRequest(t, "/c"), lruTestResponse("c")); err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+			}
+
+			for _, path := range tt.expected {
+				res, err := cache.Get(lruTestRequest(t, path))
+				if err != nil {
+					t.Error(path, err)
+					continue
+				}
+				body, _ := ioutil.ReadAll(res.Body)
+				if len(body) == 0 {
+					t.Error("expected a body for", path)
+				}
+			}
+			for _, path := range tt.missing {
+				if _, err := cache.Get(lruTestRequest(t, path)); err != NotInCacheError {
+					t.Error("expected", path, "to be evicted, got", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLRUCache_Set(t *testing.T) {
+
+	cache := NewLRUCache(LRUCacheOptions{MaxEntries: 10})
+
+	response := lruTestResponse("content")
+	if err := cache.Set(lruTestRequest(t, "/"), response); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := cache.Set(lruTestRequest(t, "/"), lruTestResponse("other")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	if body, _ := ioutil.ReadAll(response.Body); string(body) != "content" {
+		t.Error("expected the caller to keep the body, got", string(body))
+	}
+	if cache.Len() != 1 || cache.Bytes() != int64(len("other")) {
+		t.Error("expected a single replaced entry, got", cache.Len(), cache.Bytes())
+	}
+
+	for i := 0; i < 2; i++ {
+		res, err := cache.Get(lruTestRequest(t, "/"))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if body, _ := ioutil.ReadAll(res.Body); string(body) != "other" {
+			t.Error("expected every Get to return the whole body, got", string(body))
+		}
+	}
+}
+
+func TestLRUCache_OversizedPrefix(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		keys        string
+	}{
+		{"json object", "application/json; charset=utf-8", `{"id":1,"items":[1,2,3],"next":{"page":2}}`, "id,items,next"},
+		{"json array", "application/json", `[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15]`, ""},
+		{"text", "text/plain", strings.Repeat("text ", 10), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewLRUCache(LRUCacheOptions{MaxBytes: 16, OversizedPrefix: 8})
+
+			response := lruTestResponse(tt.body)
+			response.Header.Set("Content-Type", tt.contentType)
+			if err := cache.Set(lruTestRequest(t, "/"), response); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if body, _ := ioutil.ReadAll(response.Body); string(body) != tt.body {
+				t.Error("expected the caller to keep the whole body, got", string(body))
+			}
+
+			if _, err := cache.Get(lruTestRequest(t, "/")); err != NotInCacheError {
+				t.Error("expected partial entries not to be served, got", err)
+			}
+
+			key, _ := cache.Key(lruTestRequest(t, "/"))
+			partial, err := cache.GetKey(key)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, _ := ioutil.ReadAll(partial.Body)
+			if string(body) != tt.body[:8] || cache.Bytes() != 8 {
+				t.Error("expected the first 8 body bytes, got", string(body), cache.Bytes())
+			}
+			if partial.Header.Get(EntryClassHeader) != PartialEntryClass || partial.Header.Get(BodyTruncatedHeader) != "true" {
+				t.Error("expected the entry to be marked as partial, got", partial.Header)
+			}
+			if size := partial.Header.Get(BodySizeHeader); size != strconv.Itoa(len(tt.body)) {
+				t.Error("expected the body size", len(tt.body), "got", size)
+			}
+			if len(partial.Header.Get(BodySHA256Header)) != 64 {
+				t.Error("expected the body hash, got", partial.Header.Get(BodySHA256Header))
+			}
+			if keys := partial.Header.Get(JSONKeysHeader); keys != tt.keys {
+				t.Error("expected the keys", tt.keys, "got", keys)
+			}
+		})
+	}
+}
+
+func TestLRUCache_EvictionSamples(t *testing.T) {
+
+	t.Run("samples covering the cache", func(t *testing.T) {
+		cache := NewLRUCache(LRUCacheOptions{MaxEntries: 2, EvictionSamples: 5})
+		for _, path := range []string{"/a", "/b"} {
+			if err := cache.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+		}
+		if _, err := cache.Get(lruTestRequest(t, "/a")); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if err := cache.Set(lruTestRequest(t, "/c"), lruTestResponse("/c")); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if _, err := cache.Get(lruTestRequest(t, "/b")); err != NotInCacheError {
+			t.Error("expected /b to be evicted, got", err)
+		}
+		for _, path := range []string{"/a", "/c"} {
+			if _, err := cache.Get(lruTestRequest(t, path)); err != nil {
+				t.Error(path, err)
+			}
+		}
+	})
+
+	t.Run("approximates LRU", func(t *testing.T) {
+		const size = 1000
+		var evicted []int
+		cache := NewLRUCache(LRUCacheOptions{MaxEntries: size, EvictionSamples: 10, OnEvict: func(key string) {
+			var n int
+			for _, line := range strings.Split(key, "\r\n") {
+				if strings.HasPrefix(line, "GET /") {
+					n, _ = strconv.Atoi(strings.TrimPrefix(strings.Fields(line)[1], "/"))
+				}
+			}
+			evicted = append(evicted, n)
+		}})
+		for k := 0; k < size+100; k++ {
+			if err := cache.Set(lruTestRequest(t, "/"+strconv.Itoa(k)), lruTestResponse("x")); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+		}
+		if cache.Len() != size || len(evicted) != 100 {
+			t.Errorf("expected %d entries and 100 evictions, got %d and %d", size, cache.Len(), len(evicted))
+		}
+		//with 10 samples an evicted entry is within the older half with a probability of 99.9%
+		var old int
+		for _, n := range evicted {
+			if n < size/2 {
+				old++
+			}
+		}
+		if old < 95 {
+			t.Errorf("expected the evicted entries to be old, %d of 100 were within the older half", old)
+		}
+	})
+}
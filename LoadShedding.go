@@ -0,0 +1,164 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var LoadSheddingError = errors.New("origin request shed, too many concurrent cache misses in protective mode")
+
+//LoadShedder detects a collapsed hit rate, e.g. after a deploy changed the cache keys, and protects the origin. At
+//the end of every Window with at least MinRequests requests, a hit rate of at most MaxHitRate and a mean latency of
+//the misses of at least MinMissLatency it enters the protective mode. In protective mode at most MaxConcurrentMisses
+//requests are sent to the origin at once, further misses fail with LoadSheddingError or are served a stale response
+//within its stale-if-error window. The protective mode is left after the first window which is not pathological
+type LoadShedder struct {
+	//Window is the period the hit rate is evaluated for, one minute if 0
+	Window time.Duration
+	//MinRequests is the number of requests in a window below which the hit rate is not evaluated, 100 if 0
+	MinRequests int
+	//MaxHitRate is the hit rate at or below which the hit rate counts as collapsed
+	MaxHitRate float64
+	//MinMissLatency is the mean latency of the misses at or above which they count as slow
+	MinMissLatency time.Duration
+	//MaxConcurrentMisses bounds the concurrent origin requests in protective mode, 0 only calls the hooks
+	MaxConcurrentMisses int
+	//OnProtect and OnRecover are called with the statistics of the window when the protective mode is entered and
+	//left if not nil, e.g. to alert
+	OnProtect func(stats LoadSheddingStats)
+	OnRecover func(stats LoadSheddingStats)
+	//Clock tells the time of the windows, SystemClock if nil. The latencies of the misses are measured in wall time
+	Clock Clock
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	hits        int
+	misses      int
+	shed        int
+	missLatency time.Duration
+	protecting  bool
+	inflight    int
+}
+
+//LoadSheddingStats are the statistics of a window of a LoadShedder
+type LoadSheddingStats struct {
+	Hits   int
+	Misses int
+	//Shed are the misses which were not sent to the origin
+	Shed            int
+	HitRate         float64
+	MeanMissLatency time.Duration
+}
+
+func (l *LoadShedder) window() time.Duration {
+	if l.Window <= 0 {
+		return time.Minute
+	}
+	return l.Window
+}
+
+func (l *LoadShedder) minRequests() int {
+	if l.MinRequests <= 0 {
+		return 100
+	}
+	return l.MinRequests
+}
+
+//Protecting reports if the LoadShedder is in protective mode
+func (l *LoadShedder) Protecting() bool {
+	if l == nil {
+		return false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.protecting
+}
+
+//hit records a response served from the cache
+func (l *LoadShedder) hit() {
+	if l == nil {
+		return
+	}
+	l.record(func() { l.hits++ })
+}
+
+//acquire admits a miss to the origin, release records its latency. In protective mode misses beyond
+//MaxConcurrentMisses are not admitted and acquire returns LoadSheddingError
+func (l *LoadShedder) acquire() (release func(), err error) {
+
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mutex.Lock()
+	if l.protecting && l.MaxConcurrentMisses > 0 && l.inflight >= l.MaxConcurrentMisses {
+		l.mutex.Unlock()
+		l.record(func() {
+			l.misses++
+			l.shed++
+		})
+		return nil, LoadSheddingError
+	}
+	l.inflight++
+	l.mutex.Unlock()
+
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			latency := time.Since(start)
+			l.record(func() {
+				l.inflight--
+				l.misses++
+				l.missLatency += latency
+			})
+		})
+	}, nil
+}
+
+//record applies update to the counters of the window and evaluates the window once it ended
+func (l *LoadShedder) record(update func()) {
+
+	now := clockOrDefault(l.Clock).Now()
+	l.mutex.Lock()
+	if l.windowStart.IsZero() {
+		l.windowStart = now
+	}
+	update()
+	if now.Sub(l.windowStart) < l.window() {
+		l.mutex.Unlock()
+		return
+	}
+
+	stats := l.stats()
+	pathological := stats.Hits+stats.Misses >= l.minRequests() && stats.HitRate <= l.MaxHitRate &&
+		stats.MeanMissLatency >= l.MinMissLatency
+	var hook func(stats LoadSheddingStats)
+	if pathological && !l.protecting {
+		hook = l.OnProtect
+	} else if !pathological && l.protecting {
+		hook = l.OnRecover
+	}
+	l.protecting = pathological
+	l.windowStart = now
+	l.hits, l.misses, l.shed, l.missLatency = 0, 0, 0, 0
+	l.mutex.Unlock()
+
+	if hook != nil {
+		hook(stats)
+	}
+}
+
+//stats returns the statistics of the current window, the mutex must be held
+func (l *LoadShedder) stats() LoadSheddingStats {
+
+	stats := LoadSheddingStats{Hits: l.hits, Misses: l.misses, Shed: l.shed}
+	if requests := l.hits + l.misses; requests > 0 {
+		stats.HitRate = float64(l.hits) / float64(requests)
+	}
+	if sent := l.misses - l.shed; sent > 0 {
+		stats.MeanMissLatency = l.missLatency / time.Duration(sent)
+	}
+	return stats
+}
@@ -0,0 +1,92 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_LoadShedder(t *testing.T) {
+
+	block := make(chan struct{})
+	started := make(chan struct{}, 1)
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/blocking" {
+			started <- struct{}{}
+			<-block
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", "no-store")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+	protected := make(chan LoadSheddingStats, 1)
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	shedder := &LoadShedder{Window: time.Minute, MinRequests: 3, MaxConcurrentMisses: 1, Clock: clock,
+		OnProtect: func(stats LoadSheddingStats) { protected <- stats }}
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, LoadShedder: shedder}}
+
+	get := func(path string) error {
+		response, err := client.Get("http://example.com" + path)
+		if err == nil {
+			_ = response.Body.Close()
+		}
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		if err := get("/"); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	clock.Advance(time.Minute)
+	if err := get("/"); err != nil {
+		t.Error(err)
+	}
+	select {
+	case stats := <-protected:
+		if stats.Misses != 4 || stats.HitRate != 0 {
+			t.Errorf("expected the statistics of the window got %+v", stats)
+		}
+	default:
+		t.Error("expected OnProtect to be called")
+	}
+	if !shedder.Protecting() {
+		t.Error("expected the protective mode")
+	}
+
+	done := make(chan error)
+	go func() { done <- get("/blocking") }()
+	<-started
+	if err := get("/"); !errors.Is(err, LoadSheddingError) {
+		t.Error("expected the miss beyond MaxConcurrentMisses to be shed got", err)
+	}
+	close(block)
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadShedder_Recover(t *testing.T) {
+
+	recovered := make(chan LoadSheddingStats, 1)
+	shedder := &LoadShedder{Window: time.Hour, MinRequests: 1, MaxHitRate: 0.1,
+		OnRecover: func(stats LoadSheddingStats) { recovered <- stats }}
+	shedder.protecting = true
+	shedder.windowStart = time.Now().Add(-time.Hour)
+
+	shedder.hit()
+	if shedder.Protecting() {
+		t.Error("expected the protective mode to be left")
+	}
+	select {
+	case stats := <-recovered:
+		if stats.Hits != 1 || stats.HitRate != 1 {
+			t.Errorf("expected the statistics of the window got %+v", stats)
+		}
+	default:
+		t.Error("expected OnRecover to be called")
+	}
+}
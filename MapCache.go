@@ -1,21 +1,32 @@
 package CachedHttpClient
 
 import (
-	"bytes"
-	"io/ioutil"
 	"net/http"
+	"sort"
+	"sync"
 )
 
 //MapCache caches the response in a map string -> *http.Response
 //
 type MapCache struct {
 	cache map[string]*http.Response
+	//hits counts the uses of the entries by Get since they were stored
+	hits map[string]int64
+	//mutex guards cache and hits, reading a stored response replaces its body so Get needs the write lock as well
+	mutex sync.Mutex
 	MapCacheOptions
 }
 
 type MapCacheOptions struct {
 	IgnoreRequestBody            bool
 	DontIncludeAllRequestHeaders bool
+	//KeyFunc replaces the request dump as key if not nil, see NewKeyFunc
+	KeyFunc func(req *http.Request) string
+	//Generations mixes the current generation into the keys if not nil, see WithGeneration for a single request
+	Generations *Generations
+	//Namespace is mixed into the keys to keep the entries of several clients sharing one backend apart, see
+	//WithNamespace for a single request and CachedTransport.InvalidateNamespace
+	Namespace string
 }
 
 func NewMapCache(options ...MapCacheOptions) *MapCache {
@@ -29,20 +40,93 @@ func NewMapCache(options ...MapCacheOptions) *MapCache {
 	return mapCache
 }
 
+//Key returns the key the response for req is stored under
+func (m *MapCache) Key(req *http.Request) (string, error) {
+	return m.MapCacheOptions.key(req)
+}
+
+//requestDirectiveHeaders are the header fields of the request directives, they are not part of the request dump keys
+var requestDirectiveHeaders = []string{"Cache-Control", "Pragma"}
+
+//key returns the KeyFunc result or the request dump selected by the options
+func (o MapCacheOptions) key(req *http.Request) (string, error) {
+	var key string
+	if o.KeyFunc != nil {
+		key = o.KeyFunc(req)
+	} else {
+		//the request directives select how the entry is used, not which entry
+		req = stripNoiseHeaders(req, requestDirectiveHeaders)
+		var dumped bool
+		if !o.DontIncludeAllRequestHeaders {
+			//the fast path of DumpRequest without the copy to bytes
+			key, dumped = dumpRequestOut(req)
+		}
+		if !dumped {
+			dumpRequest, err := DumpRequest(req, !o.IgnoreRequestBody, o.DontIncludeAllRequestHeaders)
+			if err != nil {
+				return "", err
+			}
+			key = string(dumpRequest)
+		}
+	}
+	key = withKeyLine(key, RequestBodyDigestHeader, bodyDigestFromContext(req.Context()))
+	key = withKeyLine(key, RangeKeyHeader, rangeFromContext(req.Context()))
+	key = withKeyLine(key, GenerationHeader, o.Generations.generation(req))
+	key = withKeyLine(key, PrincipalHeader, principalFromContext(req.Context()))
+	return withKeyLine(key, NamespaceHeader, o.namespace(req)), nil
+}
+
+//keyBuffers hold the keys appended by appendKey
+var keyBuffers = sync.Pool{New: func() interface{} { return new([]byte) }}
+
+//appendKey appends the key of req to dst if it is the plain request dump, e.g. to look it up in a map without
+//allocating the key. ok is false for the other keys, they are returned by key
+func (o MapCacheOptions) appendKey(dst []byte, req *http.Request) ([]byte, bool) {
+	if o.KeyFunc != nil || o.DontIncludeAllRequestHeaders {
+		return dst, false
+	}
+	for _, field := range requestDirectiveHeaders {
+		if _, ok := req.Header[field]; ok {
+			return dst, false
+		}
+	}
+	ctx := req.Context()
+	if bodyDigestFromContext(ctx) != "" || rangeFromContext(ctx) != "" || principalFromContext(ctx) != "" ||
+		o.namespace(req) != "" || o.Generations.generation(req) != "" {
+		return dst, false
+	}
+	return appendRequestDump(dst, req)
+}
+
+//namespace returns the namespace of req, a namespace set with WithNamespace takes precedence
+func (o MapCacheOptions) namespace(req *http.Request) string {
+	if namespace, ok := namespaceFromContext(req.Context()); ok {
+		return namespace
+	}
+	return o.Namespace
+}
+
 func (m *MapCache) Get(req *http.Request) (*http.Response, error) {
 
-	dumpRequest, err := DumpRequest(req, !m.IgnoreRequestBody, m.DontIncludeAllRequestHeaders)
+	key, err := m.Key(req)
 	if err != nil {
 		return nil, err
 	}
 
-	res, ok := m.cache[string(dumpRequest)]
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	res, ok := m.cache[key]
 	if ok {
 		cRep, err := CopyResponse(res)
 		if err != nil {
 			return nil, err
 		}
-		return cRep, nil
+		if m.hits == nil {
+			m.hits = map[string]int64{}
+		}
+		m.hits[key]++
+		return deferTrailer(cRep), nil
 	}
 	return nil, NotInCacheError
 
@@ -50,9 +134,10 @@ func (m *MapCache) Get(req *http.Request) (*http.Response, error) {
 
 func (m *MapCache) Set(req *http.Request, res *http.Response) error {
 
-	var buf bytes.Buffer
+	var body []byte
 	if res.Body != http.NoBody {
-		_, err := buf.ReadFrom(res.Body)
+		var err error
+		body, err = readBody(res.Body, res.ContentLength)
 		if err != nil {
 			return err
 		}
@@ -60,14 +145,90 @@ func (m *MapCache) Set(req *http.Request, res *http.Response) error {
 		if err != nil {
 			return err
 		}
-		res.Body = ioutil.NopCloser(&buf)
+		res.Body = newBytesBody(body)
 	}
 
-	dumpRequest, err := DumpRequest(req, !m.IgnoreRequestBody, m.DontIncludeAllRequestHeaders)
+	key, err := m.Key(req)
 	if err != nil {
 		return err
 	}
-	m.cache[string(dumpRequest)] = res
+
+	//the stored response gets its own reader so consuming the returned response does not drain the cache
+	stored := *res
+	if res.Body != http.NoBody {
+		stored.Body = newBytesBody(body)
+	}
+	m.mutex.Lock()
+	m.cache[key] = &stored
+	delete(m.hits, key)
+	m.mutex.Unlock()
 
 	return nil
 }
+
+//Keys returns the sorted keys of all entries
+func (m *MapCache) Keys() []string {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make([]string, 0, len(m.cache))
+	for key := range m.cache {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//GetKey returns a copy of the response stored under key
+func (m *MapCache) GetKey(key string) (*http.Response, error) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	res, ok := m.cache[key]
+	if !ok {
+		return nil, NotInCacheError
+	}
+	res, err := CopyResponse(res)
+	if err != nil {
+		return nil, err
+	}
+	return deferTrailer(res), nil
+}
+
+//DeleteKey removes the entry stored under key
+func (m *MapCache) DeleteKey(key string) error {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.cache[key]; !ok {
+		return NotInCacheError
+	}
+	delete(m.cache, key)
+	delete(m.hits, key)
+	return nil
+}
+
+//PeekKey returns the response stored under key without its body and without counting it as a hit
+func (m *MapCache) PeekKey(key string) (*http.Response, int64, int64, error) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	res, ok := m.cache[key]
+	if !ok {
+		return nil, 0, 0, NotInCacheError
+	}
+	size := res.ContentLength
+	if body, ok := res.Body.(*bytesBody); ok {
+		size = int64(len(body.data))
+	} else if res.Body == http.NoBody {
+		size = 0
+	}
+	if size < 0 {
+		size = -1
+	}
+	return peekedResponse(res), size, m.hits[key], nil
+}
@@ -0,0 +1,127 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//MatchAll returns a Matcher matching the requests all matchers match, the first difference is returned
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(req *http.Request, body []byte, recorded *JsonRequest) error {
+		for _, matcher := range matchers {
+			if err := matcher(req, body, recorded); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//MatchAny returns a Matcher matching the requests one of matchers matches, the differences of all matchers are
+//returned if none matches
+func MatchAny(matchers ...Matcher) Matcher {
+	return func(req *http.Request, body []byte, recorded *JsonRequest) error {
+		differences := make([]string, 0, len(matchers))
+		for _, matcher := range matchers {
+			err := matcher(req, body, recorded)
+			if err == nil {
+				return nil
+			}
+			differences = append(differences, err.Error())
+		}
+		if len(differences) == 0 {
+			return nil
+		}
+		return errors.New(strings.Join(differences, "\n"))
+	}
+}
+
+//MatchURLIgnoringQuery returns a Matcher matching the URL without the named query parameters, e.g. tracking tokens or
+//timestamps. The order of the remaining parameters is insignificant
+func MatchURLIgnoringQuery(ignored ...string) Matcher {
+	return func(req *http.Request, body []byte, recorded *JsonRequest) error {
+		recordedURL, err := url.Parse(recorded.URL)
+		if err != nil {
+			return diff("url", recorded.URL, req.URL.String())
+		}
+		return diff("url", withoutQueryParameters(recordedURL, ignored), withoutQueryParameters(req.URL, ignored))
+	}
+}
+
+//withoutQueryParameters returns u without the ignored query parameters and the others sorted
+func withoutQueryParameters(u *url.URL, ignored []string) string {
+	stripped := *u
+	query := u.Query()
+	for _, name := range ignored {
+		query.Del(name)
+	}
+	stripped.RawQuery = query.Encode()
+	stripped.ForceQuery = false
+	return stripped.String()
+}
+
+//MatchHeadersNormalized returns a Matcher matching the values of the named request headers regardless of the order
+//of their values and of the whitespace around them, "gzip, br" matches "br,gzip" and two fields with one value each
+func MatchHeadersNormalized(names ...string) Matcher {
+	return func(req *http.Request, body []byte, recorded *JsonRequest) error {
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			err := diff("header "+name, normalizedHeaderValues(recorded.Header[name]), normalizedHeaderValues(req.Header[name]))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+//normalizedHeaderValues splits the comma separated values, collapses their whitespace and sorts them
+func normalizedHeaderValues(values []string) string {
+	var normalized []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.Join(strings.Fields(element), " "); element != "" {
+				normalized = append(normalized, element)
+			}
+		}
+	}
+	sort.Strings(normalized)
+	return strings.Join(normalized, ", ")
+}
+
+//MatchJSONBody matches JSON bodies by their structure, the order of the object keys and the whitespace are
+//insignificant. Numbers are compared as they are written. Bodies which are no JSON are matched byte by byte
+func MatchJSONBody(req *http.Request, body []byte, recorded *JsonRequest) error {
+	recordedValue, recordedErr := decodeJSONBody(recorded.Body)
+	value, err := decodeJSONBody(body)
+	if recordedErr != nil || err != nil {
+		return MatchBody(req, body, recorded)
+	}
+	if reflect.DeepEqual(recordedValue, value) {
+		return nil
+	}
+	//maps are encoded with sorted keys, the difference shows in the canonical form
+	recordedJSON, _ := json.Marshal(recordedValue)
+	requestedJSON, _ := json.Marshal(value)
+	return diff("body", string(recordedJSON), string(requestedJSON))
+}
+
+//decodeJSONBody decodes body keeping numbers as they are written, an empty body fails
+func decodeJSONBody(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("more than one JSON value")
+	}
+	return value, nil
+}
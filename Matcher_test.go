@@ -0,0 +1,59 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+
+	recorded := &JsonRequest{
+		Method: http.MethodPost,
+		URL:    "https://example.com/search?q=go&page=2&utm_source=mail",
+		Header: http.Header{"Accept-Encoding": {"gzip, br"}, "Accept": {"application/json"}},
+		Body:   []byte(`{"query":"go","filters":{"lang":"en","sort":"date"},"ids":[1,2]}`),
+	}
+	tests := []struct {
+		name    string
+		matcher Matcher
+		url     string
+		header  http.Header
+		body    string
+		matches bool
+	}{
+		{"query ignored", MatchURLIgnoringQuery("utm_source"), "https://example.com/search?page=2&q=go&utm_source=feed", nil, "", true},
+		{"query without ignored parameter", MatchURLIgnoringQuery("utm_source"), "https://example.com/search?page=2&q=go", nil, "", true},
+		{"query differs", MatchURLIgnoringQuery("utm_source"), "https://example.com/search?page=3&q=go", nil, "", false},
+		{"path differs", MatchURLIgnoringQuery("utm_source"), "https://example.com/find?page=2&q=go", nil, "", false},
+		{"header order", MatchHeadersNormalized("accept-encoding"), "", http.Header{"Accept-Encoding": {"br,gzip"}}, "", true},
+		{"header fields", MatchHeadersNormalized("Accept-Encoding"), "", http.Header{"Accept-Encoding": {" br ", "gzip"}}, "", true},
+		{"header differs", MatchHeadersNormalized("Accept-Encoding"), "", http.Header{"Accept-Encoding": {"gzip"}}, "", false},
+		{"json structure", MatchJSONBody, "", nil, `{"ids": [1, 2], "filters": {"sort": "date", "lang": "en"}, "query": "go"}`, true},
+		{"json differs", MatchJSONBody, "", nil, `{"ids":[2,1],"filters":{"lang":"en","sort":"date"},"query":"go"}`, false},
+		{"no json", MatchJSONBody, "", nil, `query=go`, false},
+		{"all", MatchAll(MatchMethod, MatchJSONBody), "", nil, `{"query":"go","ids":[1,2],"filters":{"sort":"date","lang":"en"}}`, true},
+		{"all with difference", MatchAll(MatchMethod, MatchURL), "https://example.com/search?q=go", nil, "", false},
+		{"any", MatchAny(MatchURL, MatchURLIgnoringQuery("utm_source", "page")), "https://example.com/search?q=go", nil, "", true},
+		{"any without match", MatchAny(MatchURL, MatchBody), "https://example.com/search?q=go", nil, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			url := test.url
+			if url == "" {
+				url = recorded.URL
+			}
+			req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, values := range test.header {
+				req.Header[name] = values
+			}
+			err = test.matcher(req, []byte(test.body), recorded)
+			if (err == nil) != test.matches {
+				t.Error("expected match", test.matches, "got", err)
+			}
+		})
+	}
+}
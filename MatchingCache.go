@@ -0,0 +1,193 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+//MatchingCache finds the stored response for requests whose key differs from the key it was stored under but which
+//match the stored request by all Matchers, e.g. a URL with other tracking parameters, headers listing their values in
+//another order or a POST request cached by PostCaching with the same JSON body formatted differently. Get tries the
+//exact key first, then the requests with the same method, host and path stored through the MatchingCache. Cache needs
+//to be an Inspector for the matched entries to be read
+//
+//	cache := NewMatchingCache(NewMapCache(), MatchMethod, MatchURLIgnoringQuery("utm_source"), MatchJSONBody)
+type MatchingCache struct {
+	Cache    Cacher
+	Matchers []Matcher
+
+	mutex sync.Mutex
+	//stored holds the requests of the entries by their method, host and path
+	stored map[string][]*matchedRequest
+}
+
+//matchedRequest is a request stored through a MatchingCache and the key of its entry
+type matchedRequest struct {
+	key     string
+	request *JsonRequest
+}
+
+//NewMatchingCache creates a MatchingCache matching the requests to cache with matchers, DefaultMatchers if none are
+//given
+func NewMatchingCache(cache Cacher, matchers ...Matcher) *MatchingCache {
+	if len(matchers) == 0 {
+		matchers = DefaultMatchers
+	}
+	return &MatchingCache{Cache: cache, Matchers: matchers}
+}
+
+//matchBucket returns the method, host and path of req the stored requests are grouped by
+func matchBucket(req *http.Request) string {
+	return req.Method + " " + requestHost(req) + req.URL.EscapedPath()
+}
+
+//Key returns the key the response for req is stored under in Cache
+func (m *MatchingCache) Key(req *http.Request) (string, error) {
+	if keyer, ok := m.Cache.(Keyer); ok {
+		return keyer.Key(req)
+	}
+	return MapCacheOptions{}.key(req)
+}
+
+func (m *MatchingCache) Get(req *http.Request) (*http.Response, error) {
+
+	res, err := m.Cache.Get(req)
+	inspector, ok := m.Cache.(Inspector)
+	if !errors.Is(err, NotInCacheError) || !ok {
+		return res, err
+	}
+
+	body, err := keyRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	bucket := matchBucket(req)
+	m.mutex.Lock()
+	candidates := append([]*matchedRequest(nil), m.stored[bucket]...)
+	m.mutex.Unlock()
+
+	for _, candidate := range candidates {
+		if !m.matches(req, body, candidate.request) {
+			continue
+		}
+		res, err := inspector.GetKey(candidate.key)
+		if errors.Is(err, NotInCacheError) {
+			//the entry was evicted or deleted by the cache itself
+			m.forget(bucket, candidate.key)
+			continue
+		}
+		return res, err
+	}
+	return nil, NotInCacheError
+}
+
+//matches reports if all Matchers match recorded
+func (m *MatchingCache) matches(req *http.Request, body []byte, recorded *JsonRequest) bool {
+	for _, matcher := range m.Matchers {
+		if matcher(req, body, recorded) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+//Set stores res in Cache and records req for the matching of later requests
+func (m *MatchingCache) Set(req *http.Request, res *http.Response) error {
+
+	err := m.Cache.Set(req, res)
+	if err != nil {
+		return err
+	}
+	key, err := m.Key(req)
+	if err != nil {
+		return err
+	}
+	body, err := keyRequestBody(req)
+	if err != nil {
+		return err
+	}
+	request := &JsonRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: body}
+
+	bucket := matchBucket(req)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stored == nil {
+		m.stored = map[string][]*matchedRequest{}
+	}
+	for _, stored := range m.stored[bucket] {
+		if stored.key == key {
+			stored.request = request
+			return nil
+		}
+	}
+	m.stored[bucket] = append(m.stored[bucket], &matchedRequest{key: key, request: request})
+	return nil
+}
+
+//forget removes the request stored under key from bucket
+func (m *MatchingCache) forget(bucket string, key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stored := m.stored[bucket]
+	for i, request := range stored {
+		if request.key == key {
+			m.stored[bucket] = append(stored[:i:i], stored[i+1:]...)
+			break
+		}
+	}
+	if len(m.stored[bucket]) == 0 {
+		delete(m.stored, bucket)
+	}
+}
+
+func (m *MatchingCache) Capabilities() CapabilitySet {
+	return CapabilitiesOf(m.Cache)
+}
+
+//Keys returns the sorted keys of Cache if it is an Inspector
+func (m *MatchingCache) Keys() []string {
+	inspector, ok := m.Cache.(Inspector)
+	if !ok {
+		return nil
+	}
+	keys := inspector.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+//GetKey returns the response stored under key in Cache
+func (m *MatchingCache) GetKey(key string) (*http.Response, error) {
+	inspector, ok := m.Cache.(Inspector)
+	if !ok {
+		return nil, NotInCacheError
+	}
+	return inspector.GetKey(key)
+}
+
+//DeleteKey removes the response stored under key from Cache, it is not matched anymore
+func (m *MatchingCache) DeleteKey(key string) error {
+	inspector, ok := m.Cache.(Inspector)
+	if !ok {
+		return NotInCacheError
+	}
+	err := inspector.DeleteKey(key)
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	var buckets []string
+	for bucket, stored := range m.stored {
+		for _, request := range stored {
+			if request.key == key {
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+	m.mutex.Unlock()
+	for _, bucket := range buckets {
+		m.forget(bucket, key)
+	}
+	return nil
+}
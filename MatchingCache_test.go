@@ -0,0 +1,72 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMatchingCache(t *testing.T) {
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := ioutil.ReadAll(r.Body)
+		writer.Header().Set("Cache-Control", "max-age=60")
+		_, _ = writer.Write([]byte(r.URL.Path + " " + string(body)))
+	}))
+	defer server.Close()
+
+	cache := NewMatchingCache(NewMapCache(), MatchMethod, MatchURLIgnoringQuery("utm_source"), MatchJSONBody)
+	transport := &CachedTransport{Cache: cache, Fallback: http.DefaultTransport, PostCaching: &PostCaching{}, StatusHeaders: true}
+	client := &http.Client{Transport: transport}
+	do := func(method string, url string, body string) (string, string) {
+		req, err := http.NewRequest(method, server.URL+url, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read, _ := ioutil.ReadAll(response.Body)
+		return string(read), response.Header.Get(CacheStatusHeader)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+		status string
+	}{
+		{"stored", http.MethodGet, "/page?id=1&utm_source=mail", "", "MISS"},
+		{"other tracking parameter", http.MethodGet, "/page?utm_source=feed&id=1", "", "HIT"},
+		{"other parameter", http.MethodGet, "/page?id=2", "", "MISS"},
+		{"json stored", http.MethodPost, "/search", `{"query":"go","limit":10}`, "MISS"},
+		{"json reformatted", http.MethodPost, "/search", `{ "limit": 10, "query": "go" }`, "HIT"},
+		{"json differs", http.MethodPost, "/search", `{"query":"rust","limit":10}`, "MISS"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, status := do(test.method, test.url, test.body)
+			if status != test.status {
+				t.Error("expected", test.status, "got", status, body)
+			}
+		})
+	}
+	if requests != 4 {
+		t.Error("expected 4 origin requests, got", requests)
+	}
+
+	for _, key := range cache.Keys() {
+		if err := cache.DeleteKey(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, status := do(http.MethodGet, "/page?id=1&utm_source=other", ""); status != "MISS" {
+		t.Error("expected deleted entries not to be matched, got", status)
+	}
+}
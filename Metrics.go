@@ -0,0 +1,314 @@
+package CachedHttpClient
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Scax/CachedHttpClient-Go/kvcache"
+)
+
+//Metrics counts how requests of a CachedTransport were answered and the connections used for origin requests, see
+//Stats, Connections, Publish and PrometheusHandler.
+//All methods are safe for concurrent use and do nothing on a nil Metrics
+type Metrics struct {
+	//the counters are accessed atomically and kept first for the 64-bit alignment atomic requires on 32-bit platforms
+	hits          int64
+	staleHits     int64
+	misses        int64
+	revalidations int64
+	evictions     int64
+	storeErrors   int64
+	bytesServed   int64
+	reclaimed     int64
+	reclaimedSize int64
+
+	connectionsMutex sync.Mutex
+	//connections holds the connection statistics of the origin requests by host
+	connections map[string]*hostConnections
+
+	valuesMutex sync.Mutex
+	//values holds the value caches reported with the counters by name, see ObserveValues
+	values map[string]*kvcache.Cache
+}
+
+//MetricsSnapshot holds the values of the counters of Metrics at one point in time
+type MetricsSnapshot struct {
+	//Hits are responses served fresh from the cache
+	Hits int64
+	//StaleHits are stale responses served from the cache, within stale-while-revalidate or stale-if-error
+	StaleHits int64
+	//Misses are requests sent to the origin unconditionally
+	Misses int64
+	//Revalidations are conditional requests sent to the origin for a stale response
+	Revalidations int64
+	//Evictions are entries evicted by the cache, reported with Evicted
+	Evictions int64
+	//StoreErrors are responses the cache failed to store
+	StoreErrors int64
+	//BytesServed are the body bytes read by callers from responses served from the cache
+	BytesServed int64
+	//Reclaimed are expired entries removed by a Janitor
+	Reclaimed int64
+	//ReclaimedBytes are the body bytes of the expired entries removed by a Janitor, as far as the cache knows them
+	ReclaimedBytes int64
+}
+
+//NewMetrics creates Metrics with all counters at zero
+func NewMetrics() *Metrics {
+	return &Metrics{}
+}
+
+//HitRatio returns the share of requests served from the cache, fresh or stale, of all requests counted
+func (s MetricsSnapshot) HitRatio() float64 {
+	served := s.Hits + s.StaleHits
+	total := served + s.Misses + s.Revalidations
+	if total == 0 {
+		return 0
+	}
+	return float64(served) / float64(total)
+}
+
+//Stats returns a snapshot of the counters
+func (m *Metrics) Stats() MetricsSnapshot {
+	if m == nil {
+		return MetricsSnapshot{}
+	}
+	return MetricsSnapshot{
+		Hits:           atomic.LoadInt64(&m.hits),
+		StaleHits:      atomic.LoadInt64(&m.staleHits),
+		Misses:         atomic.LoadInt64(&m.misses),
+		Revalidations:  atomic.LoadInt64(&m.revalidations),
+		Evictions:      atomic.LoadInt64(&m.evictions),
+		StoreErrors:    atomic.LoadInt64(&m.storeErrors),
+		BytesServed:    atomic.LoadInt64(&m.bytesServed),
+		Reclaimed:      atomic.LoadInt64(&m.reclaimed),
+		ReclaimedBytes: atomic.LoadInt64(&m.reclaimedSize),
+	}
+}
+
+//Evicted counts an eviction, it matches LRUCacheOptions.OnEvict
+func (m *Metrics) Evicted(key string) {
+	if m != nil {
+		atomic.AddInt64(&m.evictions, 1)
+	}
+}
+
+//ObserveValues reports the Stats of values with the counters of m under name, e.g. a KVCache.Values namespace
+func (m *Metrics) ObserveValues(name string, values *kvcache.Cache) {
+	if m == nil {
+		return
+	}
+	m.valuesMutex.Lock()
+	defer m.valuesMutex.Unlock()
+	if m.values == nil {
+		m.values = map[string]*kvcache.Cache{}
+	}
+	m.values[name] = values
+}
+
+//Values returns the Stats of the value caches passed to ObserveValues by name
+func (m *Metrics) Values() map[string]kvcache.Stats {
+	if m == nil {
+		return nil
+	}
+	m.valuesMutex.Lock()
+	defer m.valuesMutex.Unlock()
+	stats := make(map[string]kvcache.Stats, len(m.values))
+	for name, values := range m.values {
+		stats[name] = values.Stats()
+	}
+	return stats
+}
+
+//Publish exports the snapshot returned by Stats, the Connections and the Values as the expvar variable name, it
+//panics if name is already used
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return struct {
+			MetricsSnapshot
+			Connections map[string]ConnectionStats
+			Values      map[string]kvcache.Stats
+		}{m.Stats(), m.Connections(), m.Values()}
+	}))
+}
+
+//prometheusMetrics describes the counters in the Prometheus text exposition format
+var prometheusMetrics = []struct {
+	name  string
+	help  string
+	value func(s MetricsSnapshot) int64
+}{
+	{"cachedhttpclient_hits_total", "Responses served fresh from the cache.", func(s MetricsSnapshot) int64 { return s.Hits }},
+	{"cachedhttpclient_stale_hits_total", "Stale responses served from the cache.", func(s MetricsSnapshot) int64 { return s.StaleHits }},
+	{"cachedhttpclient_misses_total", "Requests sent to the origin unconditionally.", func(s MetricsSnapshot) int64 { return s.Misses }},
+	{"cachedhttpclient_revalidations_total", "Conditional requests sent to the origin.", func(s MetricsSnapshot) int64 { return s.Revalidations }},
+	{"cachedhttpclient_evictions_total", "Entries evicted from the cache.", func(s MetricsSnapshot) int64 { return s.Evictions }},
+	{"cachedhttpclient_store_errors_total", "Responses the cache failed to store.", func(s MetricsSnapshot) int64 { return s.StoreErrors }},
+	{"cachedhttpclient_served_bytes_total", "Body bytes served from the cache.", func(s MetricsSnapshot) int64 { return s.BytesServed }},
+	{"cachedhttpclient_reclaimed_total", "Expired entries removed by a janitor.", func(s MetricsSnapshot) int64 { return s.Reclaimed }},
+	{"cachedhttpclient_reclaimed_bytes_total", "Body bytes of the expired entries removed by a janitor.", func(s MetricsSnapshot) int64 { return s.ReclaimedBytes }},
+}
+
+//WritePrometheus writes the counters to w in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	stats := m.Stats()
+	for _, metric := range prometheusMetrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", metric.name, metric.help, metric.name, metric.name, metric.value(stats))
+		if err != nil {
+			return err
+		}
+	}
+
+	connections := m.Connections()
+	hosts := make([]string, 0, len(connections))
+	for host := range connections {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, metric := range prometheusConnectionMetrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		if err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			_, err := fmt.Fprintf(w, "%s{host=%s} %d\n", metric.name, strconv.Quote(host), metric.value(connections[host]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	values := m.Values()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, metric := range prometheusValuesMetrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			_, err := fmt.Fprintf(w, "%s{cache=%s} %d\n", metric.name, strconv.Quote(name), metric.value(values[name]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//prometheusConnectionMetrics describes the ConnectionStats in the Prometheus text exposition format
+var prometheusConnectionMetrics = []struct {
+	name  string
+	help  string
+	kind  string
+	value func(s ConnectionStats) int64
+}{
+	{"cachedhttpclient_origin_requests_total", "Origin requests which got a connection.", "counter", func(s ConnectionStats) int64 { return s.Requests }},
+	{"cachedhttpclient_origin_reused_connections_total", "Origin requests which reused a keep-alive connection.", "counter", func(s ConnectionStats) int64 { return s.Reused }},
+	{"cachedhttpclient_origin_dials_total", "Connections opened to the origin.", "counter", func(s ConnectionStats) int64 { return s.Dials }},
+	{"cachedhttpclient_origin_tls_handshakes_total", "TLS handshakes with the origin.", "counter", func(s ConnectionStats) int64 { return s.TLSHandshakes }},
+	{"cachedhttpclient_origin_peak_concurrency", "Largest number of concurrent origin requests.", "gauge", func(s ConnectionStats) int64 { return s.PeakConcurrency }},
+}
+
+//prometheusValuesMetrics describes the Stats of the value caches in the Prometheus text exposition format
+var prometheusValuesMetrics = []struct {
+	name  string
+	help  string
+	value func(s kvcache.Stats) int64
+}{
+	{"cachedhttpclient_values_hits_total", "Values read from a value cache.", func(s kvcache.Stats) int64 { return s.Hits }},
+	{"cachedhttpclient_values_misses_total", "Values missing in a value cache.", func(s kvcache.Stats) int64 { return s.Misses }},
+	{"cachedhttpclient_values_loads_total", "Values loaded on a miss.", func(s kvcache.Stats) int64 { return s.Loads }},
+	{"cachedhttpclient_values_load_errors_total", "Values failing to load.", func(s kvcache.Stats) int64 { return s.LoadErrors }},
+	{"cachedhttpclient_values_store_errors_total", "Failed reads and writes of the store of a value cache.", func(s kvcache.Stats) int64 { return s.StoreErrors }},
+}
+
+//PrometheusHandler returns a handler serving the counters to be scraped by Prometheus
+func (m *Metrics) PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WritePrometheus(w)
+	})
+}
+
+func (m *Metrics) miss() {
+	if m != nil {
+		atomic.AddInt64(&m.misses, 1)
+	}
+}
+
+func (m *Metrics) revalidation() {
+	if m != nil {
+		atomic.AddInt64(&m.revalidations, 1)
+	}
+}
+
+func (m *Metrics) storeError() {
+	if m != nil {
+		atomic.AddInt64(&m.storeErrors, 1)
+	}
+}
+
+func (m *Metrics) reclaim(entries int, bytes int64) {
+	if m != nil {
+		atomic.AddInt64(&m.reclaimed, int64(entries))
+		atomic.AddInt64(&m.reclaimedSize, bytes)
+	}
+}
+
+//hit counts res as served from the cache, fresh or stale, and counts the bytes read from its body
+func (m *Metrics) hit(res *http.Response, stale bool) *http.Response {
+	if m == nil {
+		return res
+	}
+	if stale {
+		atomic.AddInt64(&m.staleHits, 1)
+	} else {
+		atomic.AddInt64(&m.hits, 1)
+	}
+	if res.Body != nil && res.Body != http.NoBody {
+		counting := &countingBody{ReadCloser: res.Body, metrics: m}
+		res.Body = counting
+		if body, ok := counting.ReadCloser.(SeekableBody); ok {
+			res.Body = &seekableCountingBody{countingBody: counting, body: body}
+		}
+	}
+	return res
+}
+
+//countingBody adds the bytes read from the body to the served bytes of metrics
+type countingBody struct {
+	io.ReadCloser
+	metrics *Metrics
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.metrics.bytesServed, int64(n))
+	return n, err
+}
+
+//seekableCountingBody keeps a SeekableBody seekable while counting the bytes read
+type seekableCountingBody struct {
+	*countingBody
+	body SeekableBody
+}
+
+func (b *seekableCountingBody) Seek(offset int64, whence int) (int64, error) {
+	return b.body.Seek(offset, whence)
+}
+
+func (b *seekableCountingBody) ReadAt(p []byte, off int64) (int, error) {
+	n, err := b.body.ReadAt(p, off)
+	atomic.AddInt64(&b.metrics.bytesServed, int64(n))
+	return n, err
+}
@@ -0,0 +1,110 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+//publishedMetrics numbers the expvar names of the runs of TestMetrics, expvar.Publish panics on reused names
+var publishedMetrics int32
+
+func TestMetrics(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("ETag", `"1"`)
+		header.Set("Cache-Control", "max-age=60")
+		if req.URL.Path == "/b" {
+			header.Set("Cache-Control", "max-age=0")
+		}
+		if req.Header.Get("If-None-Match") == `"1"` {
+			return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello")), Request: req}, nil
+	})
+
+	metrics := NewMetrics()
+	client := http.Client{Transport: &CachedTransport{
+		Cache:    NewLRUCache(LRUCacheOptions{MaxEntries: 1, OnEvict: metrics.Evicted}),
+		Fallback: fallback,
+		Metrics:  metrics,
+	}}
+
+	for _, path := range []string{"/a", "/a", "/b", "/b"} {
+		response, err := client.Get("http://example.com" + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_, _ = ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+	}
+
+	expected := MetricsSnapshot{Hits: 1, Misses: 2, Revalidations: 1, Evictions: 1, BytesServed: 5}
+	if stats := metrics.Stats(); stats != expected {
+		t.Errorf("expected %+v got %+v", expected, stats)
+	}
+	if ratio := metrics.Stats().HitRatio(); ratio != 0.25 {
+		t.Error("expected a hit ratio of 0.25 got", ratio)
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.PrometheusHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{"# TYPE cachedhttpclient_hits_total counter\n", "cachedhttpclient_hits_total 1\n", "cachedhttpclient_served_bytes_total 5\n"} {
+		if !strings.Contains(recorder.Body.String(), line) {
+			t.Errorf("expected %q in %s", line, recorder.Body.String())
+		}
+	}
+
+	name := "TestMetrics" + strconv.Itoa(int(atomic.AddInt32(&publishedMetrics, 1)))
+	metrics.Publish(name)
+	if published := expvar.Get(name).String(); !strings.Contains(published, `"Hits":1`) {
+		t.Error("expected the published stats to contain the hits, got", published)
+	}
+}
+
+func TestMetrics_StoreError(t *testing.T) {
+
+	metrics := NewMetrics()
+	transport := &CachedTransport{
+		Cache: failingCache{},
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			header := http.Header{}
+			header.Set("Cache-Control", "max-age=60")
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+		}),
+		Metrics: metrics,
+	}
+
+	_, err := (&http.Client{Transport: transport}).Get("http://example.com/")
+	if err == nil {
+		t.Error("expected the store error")
+	}
+	if stats := metrics.Stats(); stats.StoreErrors != 1 || stats.Misses != 1 {
+		t.Errorf("expected a store error and a miss got %+v", stats)
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.Evicted("key")
+	if stats := nilMetrics.Stats(); stats != (MetricsSnapshot{}) {
+		t.Error("expected empty stats for nil Metrics got", stats)
+	}
+}
+
+//failingCache never finds responses and fails to store them
+type failingCache struct{}
+
+func (failingCache) Get(req *http.Request) (*http.Response, error) {
+	return nil, NotInCacheError
+}
+
+func (failingCache) Set(req *http.Request, res *http.Response) error {
+	return errors.New("set failed")
+}
@@ -0,0 +1,69 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"strings"
+)
+
+//NamespaceHeader is the line the namespace is mixed into the keys with, keys keep the request dump format
+const NamespaceHeader = "X-Cache-Namespace"
+
+//WithNamespace returns a context making requests using it read and write the entries of namespace, regardless of the
+//Namespace of the cache, e.g. to keep the responses of the tenants of a service apart
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceContextKey, namespace)
+}
+
+func namespaceFromContext(ctx context.Context) (string, bool) {
+	namespace, ok := ctx.Value(namespaceContextKey).(string)
+	return namespace, ok
+}
+
+//KeyNamespace returns the namespace mixed into key, empty for keys without one
+func KeyNamespace(key string) string {
+	return keyLineValue(key, NamespaceHeader)
+}
+
+//keyLineValue returns the value of the line name in the header of key, empty if there is none
+func keyLineValue(key string, name string) string {
+	prefix := name + ": "
+	lines := strings.Split(key, "\r\n")
+	for _, line := range lines[1:] {
+		if line == "" {
+			//the request body follows
+			break
+		}
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix)
+		}
+	}
+	return ""
+}
+
+//InvalidateNamespace deletes the entries of namespace and returns their number, the Cache has to implement Inspector.
+//With a cache shared by several services, e.g. a KVCache in one Redis, the entries of the other namespaces are kept
+func (c *CachedTransport) InvalidateNamespace(namespace string) (int, error) {
+	if namespace == "" {
+		//every key without a namespace would match
+		return 0, nil
+	}
+	return c.InvalidateMatching(func(key string) bool {
+		return KeyNamespace(key) == namespace
+	})
+}
+
+//NamespaceStats reads all entries of cache to summarize them per namespace like CacheStats, entries without a
+//namespace are summarized under ""
+func NamespaceStats(cache Inspector) map[string]AdminStats {
+
+	byNamespace := map[string][]string{}
+	for _, key := range cache.Keys() {
+		namespace := KeyNamespace(key)
+		byNamespace[namespace] = append(byNamespace[namespace], key)
+	}
+	stats := make(map[string]AdminStats, len(byNamespace))
+	for namespace, keys := range byNamespace {
+		stats[namespace] = cacheStats(cache, keys, nil)
+	}
+	return stats
+}
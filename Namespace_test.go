@@ -0,0 +1,83 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+
+	counter := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		counter++
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+	})
+	//two services sharing one store
+	store := newMemoryKVStore()
+	orders := &CachedTransport{Cache: NewKVCache(store, KVCacheOptions{MapCacheOptions: MapCacheOptions{Namespace: "orders"}}), Fallback: fallback}
+	users := &CachedTransport{Cache: NewKVCache(store, KVCacheOptions{MapCacheOptions: MapCacheOptions{Namespace: "users"}}), Fallback: fallback}
+	get := func(transport *CachedTransport, ctx context.Context) string {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/a", nil)
+		response, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		return string(body)
+	}
+	ctx := context.Background()
+
+	if get(orders, ctx) != "1" || get(users, ctx) != "2" {
+		t.Error("expected the namespaces not to share entries")
+	}
+	if body := get(users, WithNamespace(ctx, "tenant-a")); body != "3" {
+		t.Error("expected WithNamespace to select its own entries got", body)
+	}
+	if body := get(orders, ctx); body != "1" {
+		t.Error("expected the entry of the namespace got", body)
+	}
+
+	stats := NamespaceStats(orders.Cache.(Inspector))
+	if len(stats) != 3 || stats["orders"].Entries != 1 || stats["tenant-a"].Entries != 1 {
+		t.Error("expected the entries per namespace got", stats)
+	}
+
+	deleted, err := orders.InvalidateNamespace("users")
+	if err != nil || deleted != 1 {
+		t.Error("expected the entry of the namespace to be deleted got", deleted, err)
+	}
+	if body := get(orders, ctx); body != "1" {
+		t.Error("expected other namespaces to keep their entries got", body)
+	}
+	if body := get(users, ctx); body != "4" {
+		t.Error("expected the namespace to be purged got", body)
+	}
+	if deleted, _ := orders.InvalidateNamespace(""); deleted != 0 {
+		t.Error("expected no entries to be deleted for the empty namespace got", deleted)
+	}
+}
+
+func TestKeyNamespace(t *testing.T) {
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"GET / HTTP/1.1\r\nX-Cache-Namespace: orders\r\nHost: example.com\r\n\r\n", "orders"},
+		{"GET / HTTP/1.1\r\nHost: example.com\r\n\r\nX-Cache-Namespace: body", ""},
+		{withKeyLine("GET example.com/", NamespaceHeader, "users"), "users"},
+		{"GET example.com/", ""},
+	}
+	for _, tt := range tests {
+		if got := KeyNamespace(tt.key); got != tt.want {
+			t.Errorf("KeyNamespace(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
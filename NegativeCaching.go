@@ -0,0 +1,51 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//NegativeCaching caches error responses (4xx and 5xx) without freshness information of the origin for a short TTL,
+//so a failing origin is not hit by every request. Responses with an explicit lifetime or no-store are not changed
+type NegativeCaching struct {
+	//StatusTTLs maps status codes to the TTL of responses with them, e.g. http.StatusNotFound
+	StatusTTLs map[int]time.Duration
+	//ClassTTLs maps status classes to the TTL of responses of the class not in StatusTTLs, 4 for 4xx and 5 for 5xx
+	ClassTTLs map[int]time.Duration
+}
+
+//ttl returns the TTL for responses with status
+func (n *NegativeCaching) ttl(status int) (time.Duration, bool) {
+	if n == nil || status < 400 {
+		return 0, false
+	}
+	if ttl, ok := n.StatusTTLs[status]; ok {
+		return ttl, ttl > 0
+	}
+	ttl, ok := n.ClassTTLs[status/100]
+	return ttl, ok && ttl > 0
+}
+
+//response returns a copy of res with max-age set to the TTL of its status if it is an error response without an
+//explicit lifetime, otherwise res
+func (n *NegativeCaching) response(res *http.Response, shared bool) *http.Response {
+
+	ttl, ok := n.ttl(res.StatusCode)
+	if !ok {
+		return res
+	}
+	if _, explicit := policy.ExplicitFreshnessLifetime(res, shared); explicit {
+		return res
+	}
+
+	negative := *res
+	negative.Header = res.Header.Clone()
+	if negative.Header == nil {
+		negative.Header = http.Header{}
+	}
+	negative.Header.Add("Cache-Control", "max-age="+strconv.FormatInt(int64(ttl/time.Second), 10))
+	return &negative
+}
@@ -0,0 +1,77 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_NegativeCaching(t *testing.T) {
+
+	negativeCaching := &NegativeCaching{
+		StatusTTLs: map[int]time.Duration{http.StatusTooManyRequests: 5 * time.Second, http.StatusBadGateway: 0},
+		ClassTTLs:  map[int]time.Duration{4: time.Minute, 5: 10 * time.Second},
+	}
+
+	tests := []struct {
+		name            string
+		status          int
+		cacheControl    string
+		negativeCaching *NegativeCaching
+		cached          bool
+		maxAge          string
+	}{
+		{"5xx", http.StatusServiceUnavailable, "", negativeCaching, true, "max-age=10"},
+		{"status ttl", http.StatusTooManyRequests, "", negativeCaching, true, "max-age=5"},
+		{"status disabled", http.StatusBadGateway, "", negativeCaching, false, ""},
+		{"4xx", http.StatusNotFound, "", negativeCaching, true, "max-age=60"},
+		{"origin lifetime", http.StatusServiceUnavailable, "max-age=1", negativeCaching, true, "max-age=1"},
+		{"no-store", http.StatusServiceUnavailable, "no-store", negativeCaching, false, ""},
+		{"success", http.StatusOK, "", negativeCaching, true, ""},
+		{"off by default", http.StatusServiceUnavailable, "", nil, false, ""},
+		{"heuristically cacheable off by default", http.StatusNotFound, "", nil, false, ""},
+		{"public off by default", http.StatusGone, "public", nil, false, ""},
+		{"origin lifetime by default", http.StatusNotFound, "max-age=60", nil, true, "max-age=60"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			requests := 0
+			fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				requests++
+				header := http.Header{}
+				if tt.cacheControl != "" {
+					header.Set("Cache-Control", tt.cacheControl)
+				}
+				return &http.Response{StatusCode: tt.status, Header: header, Body: ioutil.NopCloser(strings.NewReader("error")), Request: req}, nil
+			})
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, NegativeCaching: tt.negativeCaching}}
+
+			var responses []*http.Response
+			for i := 0; i < 2; i++ {
+				response, err := client.Get("http://example.com/")
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				if body, _ := ioutil.ReadAll(response.Body); string(body) != "error" {
+					t.Error("expected the body, got", string(body))
+				}
+				responses = append(responses, response)
+			}
+
+			if cached := requests == 1; cached != tt.cached {
+				t.Error("expected cached", tt.cached, "got", requests, "requests")
+			}
+			if cacheControl := responses[0].Header.Get("Cache-Control"); cacheControl != tt.cacheControl {
+				t.Error("expected the origin Cache-Control for the caller, got", cacheControl)
+			}
+			if tt.cached && tt.maxAge != "" && !strings.Contains(strings.Join(responses[1].Header["Cache-Control"], ","), tt.maxAge) {
+				t.Error("expected", tt.maxAge, "got", responses[1].Header["Cache-Control"])
+			}
+		})
+	}
+}
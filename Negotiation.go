@@ -0,0 +1,131 @@
+package CachedHttpClient
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//HeaderNormalizer maps the values of a header field to the value used in cache keys and for Vary matching,
+//requests with the same normalized value share a cache entry
+type HeaderNormalizer func(values []string) string
+
+//AcceptClassNormalizers partition the cache by the class of the Accept header instead of its raw value,
+//use it as KeyOptions.NormalizedHeaders and CachedTransport.VaryNormalizers
+var AcceptClassNormalizers = map[string]HeaderNormalizer{
+	"Accept": NormalizeAcceptClass,
+}
+
+//NormalizeAcceptClass is a HeaderNormalizer reducing an Accept header to the class of its preferred media range:
+//json, xml, html, text, binary or any
+func NormalizeAcceptClass(values []string) string {
+
+	preferred, preferredQuality := "", -1.0
+	for _, value := range values {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+				quality = q
+			}
+			//the first media range wins on equal quality, browsers list their preference first
+			if quality > preferredQuality {
+				preferred, preferredQuality = mediaType, quality
+			}
+		}
+	}
+
+	return mediaTypeClass(preferred)
+}
+
+//mediaTypeClass maps a media type to its class
+func mediaTypeClass(mediaType string) string {
+
+	switch {
+	case mediaType == "" || mediaType == "*/*":
+		return "any"
+	case strings.HasSuffix(mediaType, "json"):
+		return "json"
+	case strings.HasSuffix(mediaType, "html"):
+		return "html"
+	case strings.HasSuffix(mediaType, "xml"):
+		return "xml"
+	case strings.HasPrefix(mediaType, "text/"):
+		return "text"
+	}
+	return "binary"
+}
+
+//LanguageNormalizers partition the cache by the preferred of the supported languages instead of the raw
+//Accept-Language header, use it as KeyOptions.NormalizedHeaders and CachedTransport.VaryNormalizers
+func LanguageNormalizers(defaultLanguage string, supported ...string) map[string]HeaderNormalizer {
+	return map[string]HeaderNormalizer{
+		"Accept-Language": NewLanguageNormalizer(defaultLanguage, supported...),
+	}
+}
+
+//NewLanguageNormalizer creates a HeaderNormalizer reducing an Accept-Language header to the supported language
+//with the highest quality, languages match by their prefix (RFC 4647 3.4) so de-CH selects de. Headers without a
+//supported language are normalized to defaultLanguage
+func NewLanguageNormalizer(defaultLanguage string, supported ...string) HeaderNormalizer {
+
+	languages := map[string]string{}
+	for _, language := range supported {
+		languages[strings.ToLower(language)] = language
+	}
+
+	return func(values []string) string {
+
+		preferred, preferredQuality := defaultLanguage, 0.0
+		for _, value := range values {
+			for _, languageRange := range strings.Split(value, ",") {
+				parts := strings.Split(languageRange, ";")
+				tag := strings.ToLower(strings.TrimSpace(parts[0]))
+				quality := 1.0
+				for _, param := range parts[1:] {
+					param = strings.TrimSpace(param)
+					if strings.HasPrefix(param, "q=") {
+						if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+							quality = q
+						}
+					}
+				}
+				//the first language range wins on equal quality
+				if quality <= preferredQuality {
+					continue
+				}
+				if language, ok := lookupLanguage(languages, tag); ok {
+					preferred, preferredQuality = language, quality
+				}
+			}
+		}
+		return preferred
+	}
+}
+
+//lookupLanguage returns the supported language matching tag, removing subtags from the end of tag until one matches
+func lookupLanguage(languages map[string]string, tag string) (string, bool) {
+	for tag != "" {
+		if language, ok := languages[tag]; ok {
+			return language, true
+		}
+		end := strings.LastIndex(tag, "-")
+		if end < 0 {
+			break
+		}
+		tag = tag[:end]
+	}
+	return "", false
+}
+
+//normalizedHeader returns the value of the header field name in header used for keys and Vary matching
+func normalizedHeader(header http.Header, name string, normalizers map[string]HeaderNormalizer) string {
+	if normalize, ok := normalizers[name]; ok {
+		return normalize(header[name])
+	}
+	return normalizeHeaderValues(header[name])
+}
@@ -0,0 +1,121 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeAcceptClass(t *testing.T) {
+
+	tests := []struct {
+		accept string
+		class  string
+	}{
+		{"", "any"},
+		{"*/*", "any"},
+		{"application/json", "json"},
+		{"application/problem+json", "json"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8", "html"},
+		{"application/xml;q=0.5, application/json", "json"},
+		{"text/xml", "xml"},
+		{"text/plain", "text"},
+		{"image/png", "binary"},
+		{"application/octet-stream", "binary"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.accept, func(t *testing.T) {
+			if class := NormalizeAcceptClass([]string{test.accept}); class != test.class {
+				t.Error("expected", test.class, "got", class)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_AcceptClass(t *testing.T) {
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		requests++
+		writer.Header().Set("Vary", "Accept")
+		_, _ = writer.Write([]byte(NormalizeAcceptClass(r.Header["Accept"])))
+	}))
+	defer server.Close()
+
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{NormalizedHeaders: AcceptClassNormalizers})})
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport, VaryNormalizers: AcceptClassNormalizers}}
+
+	accepts := []struct {
+		accept string
+		class  string
+	}{
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "html"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8", "html"},
+		{"application/json", "json"},
+		{"application/json, text/plain;q=0.5", "json"},
+	}
+
+	for _, accept := range accepts {
+		request, err := http.NewRequest("GET", server.URL, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header.Set("Accept", accept.accept)
+		response, err := client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if string(body) != accept.class {
+			t.Error("wrong variant for", accept.accept, "got", string(body))
+		}
+	}
+
+	if requests != 2 || len(cache.cache) != 2 {
+		t.Error("expected one request and entry per class, got", requests, len(cache.cache))
+	}
+
+}
+
+func TestNewLanguageNormalizer(t *testing.T) {
+
+	normalize := NewLanguageNormalizer("en", "en", "de", "fr", "pt-BR")
+
+	tests := []struct {
+		acceptLanguage string
+		language       string
+	}{
+		{"", "en"},
+		{"*", "en"},
+		{"de", "de"},
+		{"de-CH", "de"},
+		{"DE-ch", "de"},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", "fr"},
+		{"ja, de;q=0.5", "de"},
+		{"ja, zh-CN;q=0.8", "en"},
+		{"en;q=0.2, de;q=0.8", "de"},
+		{"pt-BR", "pt-BR"},
+		{"pt-PT", "en"},
+		{"de;q=0", "en"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.acceptLanguage, func(t *testing.T) {
+			if language := normalize([]string{test.acceptLanguage}); language != test.language {
+				t.Error("expected", test.language, "got", language)
+			}
+		})
+	}
+
+	if len(LanguageNormalizers("en", "de")) != 1 {
+		t.Error("expected a normalizer for Accept-Language")
+	}
+}
@@ -0,0 +1,53 @@
+package CachedHttpClient
+
+import "net/http"
+
+//DefaultNoiseHeaders are request and response headers set per request by tracing and logging systems. They differ for
+//every request so including them in keys defeats the cache, see CachedTransport.NoiseHeaders
+var DefaultNoiseHeaders = []string{
+	"X-Request-Id",
+	"X-Correlation-Id",
+	"Traceparent",
+	"Tracestate",
+	"X-B3-Traceid",
+	"X-B3-Spanid",
+	"X-B3-Parentspanid",
+	"X-B3-Sampled",
+	"X-Amzn-Trace-Id",
+	"Date",
+}
+
+//stripNoiseHeaders returns req without the noise header fields, req is only cloned if it has any of them
+func stripNoiseHeaders(req *http.Request, noise []string) *http.Request {
+
+	var fields []string
+	for _, field := range noise {
+		field = http.CanonicalHeaderKey(field)
+		if _, ok := req.Header[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return req
+	}
+
+	stripped := req.Clone(req.Context())
+	for _, field := range fields {
+		stripped.Header.Del(field)
+	}
+	return stripped
+}
+
+//noiseFields returns the noise header fields present in the response header, the Date of a response is kept as its
+//age is computed from it
+func noiseFields(header http.Header, noise []string) []string {
+
+	var fields []string
+	for _, field := range noise {
+		field = http.CanonicalHeaderKey(field)
+		if _, ok := header[field]; ok && field != "Date" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,77 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_NoiseHeaders(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		noise  []string
+		cached bool
+	}{
+		{"default noise headers", DefaultNoiseHeaders, true},
+		{"lower case names", []string{"x-request-id", "traceparent"}, true},
+		{"no noise headers", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			counter := 0
+			fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Header.Get("X-Request-Id") == "" {
+					t.Error("expected the noise headers to be sent to the origin")
+				}
+				counter++
+				header := http.Header{}
+				header.Set("Cache-Control", "max-age=60")
+				header.Set("Vary", "X-Request-Id")
+				header.Set("X-Request-Id", req.Header.Get("X-Request-Id"))
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+			})
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, NoiseHeaders: tt.noise}}
+
+			var bodies []string
+			for i := 0; i < 2; i++ {
+				request, err := http.NewRequest("GET", "http://example.com/", nil)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				request.Header.Set("X-Request-Id", strconv.Itoa(i))
+				request.Header.Set("Traceparent", "00-"+strconv.Itoa(i)+"-01")
+
+				response, err := client.Do(request)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				body, err := ioutil.ReadAll(response.Body)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				bodies = append(bodies, string(body))
+
+				if i == 1 && tt.cached {
+					if id := response.Header.Get("X-Request-Id"); id != "" {
+						t.Error("expected the noise header not to be stored, got", id)
+					}
+					if response.Header.Get("Date") == "" {
+						t.Error("expected the Date of the response to be stored")
+					}
+				}
+			}
+
+			if (bodies[0] == bodies[1]) != tt.cached {
+				t.Error("expected cached", tt.cached, "got bodies", bodies)
+			}
+		})
+	}
+}
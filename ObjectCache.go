@@ -0,0 +1,277 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+)
+
+//DefaultMaxObjectMetadataSize is the size of the object metadata ObjectCache uses if
+//ObjectCacheOptions.MaxMetadataSize is 0, the limit of the user metadata of S3
+const DefaultMaxObjectMetadataSize = 2 << 10
+
+//the names of the object metadata written by ObjectCache, lower case since S3 and GCS do not keep the case
+const (
+	//objectHeadMetadata holds the base64 encoded entry without the body if it fits into the metadata
+	objectHeadMetadata = "cachedhttp-head"
+	//objectHeadSizeMetadata holds the size of the entry without the body written before the body otherwise
+	objectHeadSizeMetadata = "cachedhttp-head-size"
+)
+
+//ObjectStore is an object storage bucket like S3 or GCS. The objectstore module implements it for S3 (s3store) and
+//GCS (gcsstore), other buckets need a small adapter around their client, see the README. The methods give up once
+//ctx is done, for Get and Set of ObjectCache it is the context of the request
+type ObjectStore interface {
+	//Put stores the object name with metadata and the content read from body, size is the length of the content
+	Put(ctx context.Context, name string, metadata map[string]string, body io.Reader, size int64) error
+	//Get returns the metadata and a reader of the content of the object name, NotInCacheError if there is none. The
+	//content is read while the caller reads the body
+	Get(ctx context.Context, name string) (metadata map[string]string, body io.ReadCloser, err error)
+	//Delete removes the object name, NotInCacheError if there is none
+	Delete(ctx context.Context, name string) error
+	//List returns the names of the objects starting with prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+//ObjectCache stores every response as an object in an ObjectStore, meant for huge and long-lived responses like
+//reports and exports. The entry without the body is kept in the object metadata and the body is the content of the
+//object, so bodies are streamed: Get returns the response while its body is still read from the bucket and Set
+//spools the body to a temporary file instead of memory, uploads it from there and serves the caller from the file.
+//Entries too large for the metadata are written before the body
+type ObjectCache struct {
+	store ObjectStore
+	ObjectCacheOptions
+}
+
+type ObjectCacheOptions struct {
+	MapCacheOptions
+	//Codec encodes the entries without their bodies, JSONCodec if nil
+	Codec Codec
+	//Digester hashes the keys to the object names, SHA256Digester if nil
+	Digester Digester
+	//Prefix starts the names of all objects, "cachedhttp/" if empty
+	Prefix string
+	//MaxMetadataSize limits the size of the object metadata, DefaultMaxObjectMetadataSize if 0. GCS allows 8 KB
+	MaxMetadataSize int
+	//SpoolDir is the directory of the temporary files holding the bodies while they are uploaded, os.TempDir if empty
+	SpoolDir string
+}
+
+//NewObjectCache creates an ObjectCache storing its responses in store
+func NewObjectCache(store ObjectStore, options ...ObjectCacheOptions) *ObjectCache {
+	objectCache := &ObjectCache{store: store}
+	if options != nil {
+		objectCache.ObjectCacheOptions = options[0]
+	}
+	return objectCache
+}
+
+func (o ObjectCacheOptions) codec() Codec {
+	if o.Codec == nil {
+		return JSONCodec
+	}
+	return o.Codec
+}
+
+func (o ObjectCacheOptions) prefix() string {
+	if o.Prefix == "" {
+		return "cachedhttp/"
+	}
+	return o.Prefix
+}
+
+//Key returns the key the response for req is stored under
+func (o *ObjectCache) Key(req *http.Request) (string, error) {
+	return o.MapCacheOptions.key(req)
+}
+
+//name returns the name of the object the entry for key is stored in
+func (o *ObjectCache) name(key string) string {
+	return o.prefix() + hexDigest(o.Digester, []byte(key))
+}
+
+func (o *ObjectCache) Get(req *http.Request) (*http.Response, error) {
+
+	key, err := o.Key(req)
+	if err != nil {
+		return nil, err
+	}
+	return o.get(req.Context(), key)
+}
+
+func (o *ObjectCache) Set(req *http.Request, res *http.Response) error {
+
+	key, err := o.Key(req)
+	if err != nil {
+		return err
+	}
+	response, err := newJsonResponseHead(res)
+	if err != nil {
+		return err
+	}
+	var head bytes.Buffer
+	err = o.codec().Encode(&head, &FileCacheEntry{Version: EntrySchemaVersion, Request: key, Response: response})
+	if err != nil {
+		return err
+	}
+
+	spool, size, err := o.spool(res)
+	if err != nil {
+		return err
+	}
+	//the caller reads the body from the spool file once it is uploaded
+	res.Body = spool
+
+	metadata := map[string]string{}
+	var content io.Reader = spool
+	if encoded := base64.StdEncoding.EncodeToString(head.Bytes()); len(objectHeadMetadata)+len(encoded) <= o.maxMetadataSize() {
+		metadata[objectHeadMetadata] = encoded
+	} else {
+		metadata[objectHeadSizeMetadata] = strconv.Itoa(head.Len())
+		content = io.MultiReader(&head, spool)
+		size += int64(head.Len())
+	}
+	err = o.store.Put(req.Context(), o.name(key), metadata, content, size)
+	if _, seekErr := spool.Seek(0, io.SeekStart); err == nil {
+		err = seekErr
+	}
+	return err
+}
+
+func (o *ObjectCache) maxMetadataSize() int {
+	if o.MaxMetadataSize <= 0 {
+		return DefaultMaxObjectMetadataSize
+	}
+	return o.MaxMetadataSize
+}
+
+//spool copies the body of res to a temporary file which is removed when it is closed
+func (o *ObjectCache) spool(res *http.Response) (*spoolBody, int64, error) {
+
+	file, err := ioutil.TempFile(o.SpoolDir, "cachedhttp-object-")
+	if err != nil {
+		return nil, 0, err
+	}
+	spool := &spoolBody{File: file}
+	var size int64
+	if res.Body != nil {
+		size, err = io.Copy(file, res.Body)
+		_ = res.Body.Close()
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = spool.Close()
+		return nil, 0, err
+	}
+	return spool, size, nil
+}
+
+//spoolBody is a SeekableBody reading a temporary file which is removed on Close
+type spoolBody struct {
+	*os.File
+}
+
+func (s *spoolBody) Close() error {
+	err := s.File.Close()
+	if removeErr := os.Remove(s.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+//GetKey returns the response stored under key, its body is read from the object storage
+func (o *ObjectCache) GetKey(key string) (*http.Response, error) {
+	return o.get(context.Background(), key)
+}
+
+func (o *ObjectCache) get(ctx context.Context, key string) (*http.Response, error) {
+
+	metadata, body, err := o.store.Get(ctx, o.name(key))
+	if err != nil {
+		return nil, err
+	}
+	entry, err := o.decodeHead(metadata, body)
+	if err == nil && (entry.Request != key || entry.Response == nil) {
+		err = NotInCacheError
+	}
+	if err != nil {
+		_ = body.Close()
+		return nil, err
+	}
+	res, err := entry.Response.Parse()
+	if err != nil && !isUnknownPublicKeyError(err) {
+		_ = body.Close()
+		return nil, wrapDecodeError(err)
+	}
+	res.Body = body
+	return res, wrapDecodeError(err)
+}
+
+//decodeHead decodes the entry without the body from the metadata or the start of body
+func (o *ObjectCache) decodeHead(metadata map[string]string, body io.Reader) (*FileCacheEntry, error) {
+
+	var head []byte
+	if encoded, ok := metadata[objectHeadMetadata]; ok {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, wrapDecodeError(err)
+		}
+		head = decoded
+	} else {
+		size, err := strconv.Atoi(metadata[objectHeadSizeMetadata])
+		if err != nil || size < 0 {
+			return nil, NotInCacheError
+		}
+		//the buffer grows with the bytes read, a corrupted size does not allocate it at once
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, body, int64(size)); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, wrapDecodeError(err)
+		}
+		head = buf.Bytes()
+	}
+	var entry FileCacheEntry
+	if err := decodeEntry(o.codec().NewDecoder(bytes.NewReader(head)), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+//Keys returns the sorted keys of all entries, every object is opened to read its entry without reading the body
+func (o *ObjectCache) Keys() []string {
+
+	ctx := context.Background()
+	names, err := o.store.List(ctx, o.prefix())
+	if err != nil {
+		return []string{}
+	}
+	keys := []string{}
+	for _, name := range names {
+		metadata, body, err := o.store.Get(ctx, name)
+		if err != nil {
+			continue
+		}
+		entry, err := o.decodeHead(metadata, body)
+		_ = body.Close()
+		if err == nil {
+			keys = append(keys, entry.Request)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//DeleteKey removes the object of the entry stored under key
+func (o *ObjectCache) DeleteKey(key string) error {
+	return o.store.Delete(context.Background(), o.name(key))
+}
@@ -0,0 +1,155 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//memoryObjectStore is an ObjectStore in memory
+type memoryObjectStore struct {
+	mutex    sync.Mutex
+	objects  map[string][]byte
+	metadata map[string]map[string]string
+}
+
+func newMemoryObjectStore() *memoryObjectStore {
+	return &memoryObjectStore{objects: map[string][]byte{}, metadata: map[string]map[string]string{}}
+}
+
+func (m *memoryObjectStore) Put(ctx context.Context, name string, metadata map[string]string, body io.Reader, size int64) error {
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if int64(len(content)) != size {
+		return io.ErrShortWrite
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.objects[name] = content
+	m.metadata[name] = metadata
+	return nil
+}
+
+func (m *memoryObjectStore) Get(ctx context.Context, name string) (map[string]string, io.ReadCloser, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	content, ok := m.objects[name]
+	if !ok {
+		return nil, nil, NotInCacheError
+	}
+	return m.metadata[name], ioutil.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *memoryObjectStore) Delete(ctx context.Context, name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.objects[name]; !ok {
+		return NotInCacheError
+	}
+	delete(m.objects, name)
+	delete(m.metadata, name)
+	return nil
+}
+
+func (m *memoryObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var names []string
+	for name := range m.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func TestObjectCache(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		maxMetadataSize int
+		inMetadata      bool
+	}{
+		{"head in metadata", 0, true},
+		{"head before body", 64, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := newMemoryObjectStore()
+			cache := NewObjectCache(store, ObjectCacheOptions{MaxMetadataSize: tt.maxMetadataSize})
+			body := strings.Repeat("report ", 1000)
+			res := lruTestResponse(body)
+			res.Header.Set("Content-Type", "text/plain")
+			if err := cache.Set(lruTestRequest(t, "/report"), res); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			//the caller reads the body from the spool file
+			served, _ := ioutil.ReadAll(res.Body)
+			if err := res.Body.Close(); err != nil || string(served) != body {
+				t.Error("expected the body to stay readable, got", len(served), err)
+			}
+
+			for name, metadata := range store.metadata {
+				if _, ok := metadata[objectHeadMetadata]; ok != tt.inMetadata {
+					t.Error("expected the head in the metadata", tt.inMetadata, "got", metadata)
+				}
+				if tt.inMetadata && string(store.objects[name]) != body {
+					t.Error("expected the object to hold the body only")
+				}
+			}
+
+			cached, err := cache.Get(lruTestRequest(t, "/report"))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			cachedBody, _ := ioutil.ReadAll(cached.Body)
+			_ = cached.Body.Close()
+			if string(cachedBody) != body || cached.Header.Get("Content-Type") != "text/plain" {
+				t.Error("expected the stored response, got", cached.Header, len(cachedBody))
+			}
+
+			keys := cache.Keys()
+			if len(keys) != 1 || !strings.Contains(keys[0], "/report") {
+				t.Error("expected the key of the report, got", keys)
+			}
+			if err := cache.DeleteKey(keys[0]); err != nil {
+				t.Error(err)
+			}
+			if _, err := cache.Get(lruTestRequest(t, "/report")); err != NotInCacheError {
+				t.Error("expected NotInCacheError after deleting, got", err)
+			}
+		})
+	}
+}
+
+func TestObjectCache_Transport(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=3600")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("export")), Request: req}, nil
+	})
+	client := http.Client{Transport: &CachedTransport{Cache: NewObjectCache(newMemoryObjectStore()), Fallback: fallback}}
+	for i := 0; i < 2; i++ {
+		res, err := client.Get("http://example.com/export")
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if string(body) != "export" {
+			t.Error("expected the export, got", string(body))
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//CacheMissError is returned in CachedTransport.Offline mode for requests without a stored response
+var CacheMissError = errors.New("the response is not in the cache and the origin is offline")
+
+//gatewayTimeoutBody is the body of the 504 response to only-if-cached requests without stored response
+const gatewayTimeoutBody = "504 Gateway Timeout: the response is not in the cache (only-if-cached)"
+
+//onlyIfCached reports if the client only accepts a stored response (RFC 7234 5.2.1.7)
+func onlyIfCached(req *http.Request) bool {
+	return policy.ParseCacheControl(req.Header).Has("only-if-cached")
+}
+
+//gatewayTimeout returns the 504 response to an only-if-cached request without stored response
+func gatewayTimeout(req *http.Request) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	return &http.Response{
+		Status:        "504 Gateway Timeout",
+		StatusCode:    http.StatusGatewayTimeout,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(gatewayTimeoutBody))),
+		ContentLength: int64(len(gatewayTimeoutBody)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,122 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_Offline(t *testing.T) {
+
+	online := true
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !online {
+			t.Error("expected the origin not to be contacted, got", req.Method, req.URL)
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", req.URL.Query().Get("cc"))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(req.URL.Path)), Request: req}, nil
+	})
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback}
+	client := http.Client{Transport: transport}
+
+	for _, url := range []string{"http://example.com/fresh?cc=max-age=60", "http://example.com/stale?cc=max-age=0,must-revalidate"} {
+		response, err := client.Get(url)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_ = response.Body.Close()
+	}
+
+	online = false
+	transport.Offline = true
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		body    string
+		warning bool
+		err     error
+	}{
+		{"fresh", "GET", "http://example.com/fresh?cc=max-age=60", "/fresh", false, nil},
+		{"stale", "GET", "http://example.com/stale?cc=max-age=0,must-revalidate", "/stale", true, nil},
+		{"missing", "GET", "http://example.com/missing", "", false, CacheMissError},
+		{"unsafe", "POST", "http://example.com/fresh?cc=max-age=60", "", false, CacheMissError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			response, err := client.Do(request)
+			if !errors.Is(err, tt.err) {
+				t.Error("expected", tt.err, "got", err)
+			}
+			if err != nil {
+				return
+			}
+			body, _ := ioutil.ReadAll(response.Body)
+			if string(body) != tt.body {
+				t.Error("expected", tt.body, "got", string(body))
+			}
+			if warning := response.Header.Get("Warning") != ""; warning != tt.warning {
+				t.Error("expected warning", tt.warning, "got", response.Header.Get("Warning"))
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_OnlyIfCached(t *testing.T) {
+
+	requests := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+	//the default keys include the Cache-Control header of the request
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})})
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: fallback}}
+
+	get := func(url string) *http.Response {
+		request, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header.Set("Cache-Control", "only-if-cached")
+		response, err := client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		_ = response.Body.Close()
+		return response
+	}
+
+	if response := get("http://example.com/"); response.StatusCode != http.StatusGatewayTimeout {
+		t.Error("expected 504 for a missing response got", response.StatusCode)
+	}
+
+	response, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = response.Body.Close()
+
+	if response := get("http://example.com/"); response.StatusCode != http.StatusOK {
+		t.Error("expected the stored response got", response.StatusCode)
+	}
+	if requests != 1 {
+		t.Error("expected a single origin request got", requests)
+	}
+}
@@ -0,0 +1,97 @@
+package CachedHttpClient
+
+import (
+	"net/url"
+	"strconv"
+)
+
+//PaginationOptions describes the query parameters selecting a page of an API response, NewKeyFunc canonicalizes
+//them so equivalent requests for the same page share an entry:
+//the spellings of a parameter are renamed to page, cursor, offset or limit, numbers lose their leading zeros,
+//pages are converted to offsets if the limit is known and the first page, the zero offset and DefaultLimit are
+//removed
+type PaginationOptions struct {
+	//PageParameters are the spellings of the 1-based page number
+	PageParameters []string
+	//CursorParameters are the spellings of opaque cursors, their value is kept
+	CursorParameters []string
+	//OffsetParameters are the spellings of the 0-based offset of the first item
+	OffsetParameters []string
+	//LimitParameters are the spellings of the number of items per page
+	LimitParameters []string
+	//DefaultLimit is the limit of the API if the request has none, 0 if unknown
+	DefaultLimit int
+}
+
+//DefaultPagination lists common spellings of pagination parameters
+var DefaultPagination = PaginationOptions{
+	PageParameters:   []string{"page", "p", "page_number", "pageNumber"},
+	CursorParameters: []string{"cursor", "after", "page_token", "pageToken", "next_token", "continuation"},
+	OffsetParameters: []string{"offset", "skip", "start"},
+	LimitParameters:  []string{"limit", "per_page", "perPage", "page_size", "pageSize", "size", "count"},
+}
+
+//canonicalize rewrites the pagination parameters of query to their canonical form
+func (p *PaginationOptions) canonicalize(query url.Values) {
+
+	if p == nil {
+		return
+	}
+
+	page, hasPage := takeNumber(query, p.PageParameters)
+	offset, hasOffset := takeNumber(query, p.OffsetParameters)
+	limit, hasLimit := takeNumber(query, p.LimitParameters)
+	cursor, hasCursor := takeParameter(query, p.CursorParameters)
+
+	if !hasLimit && p.DefaultLimit > 0 {
+		limit, hasLimit = p.DefaultLimit, true
+	}
+	if hasPage && hasLimit && !hasOffset {
+		offset, hasOffset = (page-1)*limit, true
+		hasPage = false
+	}
+
+	if hasPage && page != 1 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if hasOffset && offset != 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	if hasLimit && limit != p.DefaultLimit {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if hasCursor {
+		query.Set("cursor", cursor)
+	}
+}
+
+//takeParameter removes all spellings of a parameter from query and returns the value of the first one set
+func takeParameter(query url.Values, spellings []string) (string, bool) {
+	value, found := "", false
+	for _, name := range spellings {
+		if values, ok := query[name]; ok {
+			if !found && len(values) > 0 {
+				value, found = values[0], true
+			}
+			query.Del(name)
+		}
+	}
+	return value, found
+}
+
+//takeNumber is takeParameter for numeric parameters, values which are no numbers are kept unchanged
+func takeNumber(query url.Values, spellings []string) (int, bool) {
+	for _, name := range spellings {
+		values, ok := query[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		number, err := strconv.Atoi(values[0])
+		if err != nil || number < 0 {
+			return 0, false
+		}
+		_, _ = takeParameter(query, spellings)
+		return number, true
+	}
+	return 0, false
+}
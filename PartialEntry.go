@@ -0,0 +1,92 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//PartialEntryClass is the EntryClassHeader value of summaries stored for responses too large to be cached, see
+//LRUCacheOptions.OversizedPrefix. Partial entries can be inspected but are never served
+const PartialEntryClass = "partial"
+
+//BodySizeHeader is set on partial entries to the size of the complete body
+const BodySizeHeader = "X-Cache-Body-Size"
+
+//BodySHA256Header is set on partial entries to the hex encoded SHA-256 hash of the complete body if the Digester is
+//SHA256Digester
+const BodySHA256Header = "X-Cache-Body-Sha256"
+
+//BodyDigestHeader is set on partial entries to the name of the Digester and the hex encoded hash of the complete body,
+//e.g. sha256=9f86...
+const BodyDigestHeader = "X-Cache-Body-Digest"
+
+//JSONKeysHeader is set on partial entries of JSON objects to the comma separated keys of the object
+const JSONKeysHeader = "X-Cache-Json-Keys"
+
+//maxSummarizedJSONKeys limits the number of keys listed in JSONKeysHeader
+const maxSummarizedJSONKeys = 100
+
+//partialResponse returns a summary of res with its complete body: the first prefix bytes of the body, its size and
+//hash and for JSON objects their top-level keys
+func partialResponse(res *http.Response, body []byte, prefix int, digester Digester) *http.Response {
+
+	partial := *res
+	partial.Header = res.Header.Clone()
+	if partial.Header == nil {
+		partial.Header = http.Header{}
+	}
+
+	digester = digestOrDefault(digester)
+	digest := hexDigest(digester, body)
+	partial.Header.Set(EntryClassHeader, PartialEntryClass)
+	partial.Header.Set(BodyTruncatedHeader, "true")
+	partial.Header.Set(BodySizeHeader, strconv.Itoa(len(body)))
+	partial.Header.Set(BodyDigestHeader, digester.Name()+"="+digest)
+	if digester.Name() == SHA256Digester.Name() {
+		partial.Header.Set(BodySHA256Header, digest)
+	}
+	if keys, ok := jsonObjectKeys(partial.Header.Get("Content-Type"), body); ok {
+		partial.Header.Set(JSONKeysHeader, strings.Join(keys, ","))
+	}
+
+	if prefix > len(body) {
+		prefix = len(body)
+	}
+	partial.ContentLength = int64(prefix)
+	partial.Body = ioutil.NopCloser(bytes.NewReader(body[:prefix]))
+	return &partial
+}
+
+//jsonObjectKeys returns the top-level keys of body if it is a JSON object
+func jsonObjectKeys(contentType string, body []byte) ([]string, bool) {
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('{') {
+		return nil, false
+	}
+
+	var keys []string
+	for decoder.More() && len(keys) < maxSummarizedJSONKeys {
+		token, err := decoder.Token()
+		if err != nil {
+			return keys, len(keys) > 0
+		}
+		keys = append(keys, token.(string))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return keys, true
+		}
+	}
+	return keys, true
+}
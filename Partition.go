@@ -0,0 +1,75 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"net/http"
+)
+
+//PrincipalHeader is the line the principal of a partition is mixed into the keys with, keys keep the request dump
+//format
+const PrincipalHeader = "X-Cache-Principal"
+
+//PrincipalFunc returns the principal a request is made for, e.g. a user ID, empty for anonymous requests. See
+//CachedTransport.Principal
+type PrincipalFunc func(req *http.Request) string
+
+//WithPrincipal returns a context making requests using it read and write the partition of principal, regardless of
+//CachedTransport.Principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalContextKey, principal)
+}
+
+func principalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalContextKey).(string)
+	return principal
+}
+
+//AuthorizationPrincipal is a PrincipalFunc partitioning by the hash of the Authorization header, the credentials
+//themselves are not part of the keys
+func AuthorizationPrincipal(req *http.Request) string {
+	authorization := req.Header.Get("Authorization")
+	if authorization == "" {
+		return ""
+	}
+	return hexDigest(nil, []byte(authorization))
+}
+
+//KeyPrincipal returns the principal mixed into key, empty for keys of the shared space
+func KeyPrincipal(key string) string {
+	return keyLineValue(key, PrincipalHeader)
+}
+
+//partitioned returns keyReq with the principal of req in its context, req is the request of the caller with all its
+//headers. keyReq is returned for anonymous requests
+func (c *CachedTransport) partitioned(req *http.Request, keyReq *http.Request) *http.Request {
+	if _, ok := req.Context().Value(principalContextKey).(string); ok || c.Principal == nil {
+		return keyReq
+	}
+	principal := c.Principal(req)
+	if principal == "" {
+		return keyReq
+	}
+	return keyReq.WithContext(WithPrincipal(keyReq.Context(), principal))
+}
+
+//sharedFor reports if the rules of a shared cache decide what is stored for keyReq. A partition is private to its
+//principal, with Principal set the space of anonymous requests is shared by everyone so private responses and
+//responses to requests with Authorization are not stored there
+func (c *CachedTransport) sharedFor(keyReq *http.Request) bool {
+	if principalFromContext(keyReq.Context()) != "" {
+		return false
+	}
+	return c.Shared || c.Principal != nil
+}
+
+//InvalidatePrincipal deletes the entries in the partition of principal and returns their number, e.g. when the user
+//logs out. The Cache has to implement Inspector
+func (c *CachedTransport) InvalidatePrincipal(principal string) (int, error) {
+	if principal == "" {
+		//every key of the shared space would match
+		return 0, nil
+	}
+	return c.InvalidateMatching(func(key string) bool {
+		return KeyPrincipal(key) == principal
+	})
+}
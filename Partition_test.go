@@ -0,0 +1,98 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestCachedTransport_Principal(t *testing.T) {
+
+	origin := 0
+	transport := &CachedTransport{
+		//the keys leave out Authorization, only the partitions keep the users apart
+		Cache:     NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})}),
+		Shared:    true,
+		Principal: AuthorizationPrincipal,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			origin++
+			res := lruTestResponse("data of " + req.Header.Get("Authorization"))
+			res.Header.Set("Cache-Control", "private, max-age=60")
+			res.Request = req
+			return res, nil
+		}),
+	}
+	get := func(authorization string) string {
+		req := lruTestRequest(t, "/me")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		return string(body)
+	}
+
+	steps := []struct {
+		authorization string
+		expected      string
+		origin        int
+	}{
+		{"Bearer alice", "data of Bearer alice", 1},
+		{"Bearer alice", "data of Bearer alice", 1},
+		{"Bearer bob", "data of Bearer bob", 2},
+		{"Bearer bob", "data of Bearer bob", 2},
+		//private responses are not stored for anonymous requests
+		{"", "data of ", 3},
+		{"", "data of ", 4},
+	}
+	for i, step := range steps {
+		if body := get(step.authorization); body != step.expected || origin != step.origin {
+			t.Errorf("step %d: expected %q after %d origin requests, got %q after %d", i, step.expected, step.origin, body, origin)
+		}
+	}
+
+	alice := AuthorizationPrincipal(&http.Request{Header: http.Header{"Authorization": {"Bearer alice"}}})
+	for _, key := range transport.Cache.(*MapCache).Keys() {
+		if principal := KeyPrincipal(key); principal == "" || principal == "Bearer alice" {
+			t.Errorf("expected a hashed principal in %q", key)
+		}
+	}
+	if invalidated, err := transport.InvalidatePrincipal(alice); err != nil || invalidated != 1 {
+		t.Error("expected the entry of alice to be invalidated, got", invalidated, err)
+	}
+	if get("Bearer alice"); origin != 5 {
+		t.Error("expected a new origin request for alice, got", origin)
+	}
+	if get("Bearer bob"); origin != 5 {
+		t.Error("expected the entry of bob to remain, got", origin)
+	}
+}
+
+func TestWithPrincipal(t *testing.T) {
+
+	origin := 0
+	transport := &CachedTransport{Cache: NewMapCache(), Coalescer: NewCoalescer(),
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			origin++
+			res := lruTestResponse("content")
+			res.Header.Set("Cache-Control", "max-age=60")
+			return res, nil
+		})}
+
+	for _, principal := range []string{"user-1", "user-2", "user-1", ""} {
+		req := lruTestRequest(t, "/")
+		req = req.WithContext(WithPrincipal(req.Context(), principal))
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+	}
+	if origin != 3 {
+		t.Error("expected an entry per principal and one shared entry, got", origin, "origin requests")
+	}
+}
@@ -0,0 +1,308 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//DefaultPeerPath is the path ServeHTTP of a PeerGroup is reachable at on the peers if PeerGroup.Path is empty
+const DefaultPeerPath = "/_cachedhttp/peer"
+
+//DefaultPeerReplicas is the number of points per peer on the hash ring of a PeerGroup if PeerGroup.Replicas is 0
+const DefaultPeerReplicas = 50
+
+//PeerDiscovery returns the base URLs of all instances of a PeerGroup including the own one, e.g. from a service
+//registry
+type PeerDiscovery func(ctx context.Context) ([]string, error)
+
+//StaticPeers returns a PeerDiscovery with the fixed base URLs peers
+func StaticPeers(peers ...string) PeerDiscovery {
+	return func(ctx context.Context) ([]string, error) {
+		return peers, nil
+	}
+}
+
+//DNSPeers returns a PeerDiscovery with a peer per address host resolves to, e.g. the headless service of a
+//Kubernetes deployment. The base URLs are scheme://address:port
+func DNSPeers(scheme string, host string, port int) PeerDiscovery {
+	return func(ctx context.Context) ([]string, error) {
+		addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		peers := make([]string, len(addresses))
+		for i, address := range addresses {
+			peers[i] = scheme + "://" + net.JoinHostPort(address, strconv.Itoa(port))
+		}
+		return peers, nil
+	}
+}
+
+//PeerGroup lets a fleet of instances share their caches in the style of groupcache: every cache key is owned by one
+//peer selected by consistent hashing, the other peers fetch the response from the owner instead of the origin. The
+//owner serves it from its cache or fetches it from the origin once for the whole fleet. Only GET requests are
+//forwarded, if the owner fails the request is fetched by the own Transport. Responses from peers are not stored
+//locally.
+//Every instance mounts the PeerGroup at Path of its server and uses it as the http.RoundTripper of its client
+//
+//	group := NewPeerGroup("http://10.0.0.1:8080", transport, DNSPeers("http", "cache.internal", 8080))
+//	_ = group.RefreshPeers(ctx)
+//	stop := group.RefreshPeersEvery(time.Minute)
+//	http.Handle(DefaultPeerPath, group)
+//	client := http.Client{Transport: group}
+type PeerGroup struct {
+	//Self is the base URL of this instance as returned by Discovery
+	Self string
+	//Transport fetches and caches the responses of the keys this instance owns
+	Transport *CachedTransport
+	//Discovery lists the peers for RefreshPeers
+	Discovery PeerDiscovery
+	//Client sends the requests to the peers, http.DefaultClient if nil
+	Client *http.Client
+	//Path is the path ServeHTTP is reachable at on every peer, DefaultPeerPath if empty
+	Path string
+	//Replicas is the number of points per peer on the hash ring, DefaultPeerReplicas if 0. More points spread the
+	//keys more evenly
+	Replicas int
+	//Clock schedules RefreshPeersEvery, SystemClock if nil
+	Clock Clock
+
+	mutex sync.RWMutex
+	peers []string
+	ring  *peerRing
+}
+
+//NewPeerGroup creates a PeerGroup for the instance self, it has no peers until RefreshPeers or SetPeers is called
+func NewPeerGroup(self string, transport *CachedTransport, discovery PeerDiscovery) *PeerGroup {
+	return &PeerGroup{Self: self, Transport: transport, Discovery: discovery}
+}
+
+//SetPeers replaces the peers, peers includes Self
+func (g *PeerGroup) SetPeers(peers ...string) {
+	replicas := g.Replicas
+	if replicas <= 0 {
+		replicas = DefaultPeerReplicas
+	}
+	ring := newPeerRing(peers, replicas)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.peers = append([]string(nil), peers...)
+	g.ring = ring
+}
+
+//Peers returns the current peers
+func (g *PeerGroup) Peers() []string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return append([]string(nil), g.peers...)
+}
+
+//RefreshPeers replaces the peers with the ones returned by Discovery, they are kept if it fails
+func (g *PeerGroup) RefreshPeers(ctx context.Context) error {
+	peers, err := g.Discovery(ctx)
+	if err != nil {
+		return err
+	}
+	g.SetPeers(peers...)
+	return nil
+}
+
+//RefreshPeersEvery calls RefreshPeers every interval of the Clock until stop is called, errors are ignored
+func (g *PeerGroup) RefreshPeersEvery(interval time.Duration) (stop func()) {
+
+	ticker := clockOrDefault(g.Clock).NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				_ = g.RefreshPeers(context.Background())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+//Owner returns the peer owning the cache key of req, Self if there are no peers
+func (g *PeerGroup) Owner(req *http.Request) (string, error) {
+
+	key, err := g.Transport.requestKey(req)
+	if err != nil {
+		return "", err
+	}
+
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if g.ring == nil || len(g.ring.hashes) == 0 {
+		return g.Self, nil
+	}
+	return g.ring.owner(key), nil
+}
+
+//RoundTrip fetches the response for req from the peer owning its key or from Transport if this instance owns it
+func (g *PeerGroup) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.Method != http.MethodGet || noCacheFromContext(req.Context()) {
+		return g.Transport.RoundTrip(req)
+	}
+	owner, err := g.Owner(req)
+	if err != nil {
+		return nil, err
+	}
+	if owner == g.Self {
+		return g.Transport.RoundTrip(req)
+	}
+
+	res, err := g.fetchFromPeer(owner, req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		//the owner is not reachable, the origin is asked directly
+		return g.Transport.RoundTrip(req)
+	}
+	return res, nil
+}
+
+//fetchFromPeer sends req to ServeHTTP of peer
+func (g *PeerGroup) fetchFromPeer(peer string, req *http.Request) (*http.Response, error) {
+
+	body, err := json.Marshal(&JsonRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header})
+	if err != nil {
+		return nil, err
+	}
+	peerReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, strings.TrimSuffix(peer, "/")+g.path(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	peerReq.Header.Set("Content-Type", "application/json")
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	peerRes, err := client.Do(peerReq)
+	if err != nil {
+		return nil, err
+	}
+	defer peerRes.Body.Close()
+	data, err := ioutil.ReadAll(peerRes.Body)
+	if err != nil {
+		return nil, err
+	}
+	if peerRes.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer %s answered %d: %s", peer, peerRes.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var response JsonResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	res, err := response.Parse()
+	if err != nil && !isUnknownPublicKeyError(err) {
+		return nil, err
+	}
+	res.Request = req
+	return res, nil
+}
+
+//ServeHTTP answers the requests forwarded by the other peers from Transport. It fetches any URL it is sent, so it
+//must only be reachable by the peers
+func (g *PeerGroup) ServeHTTP(writer http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var forwarded JsonRequest
+	if err := json.NewDecoder(r.Body).Decode(&forwarded); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if forwarded.Method != http.MethodGet {
+		http.Error(writer, "peers only forward GET requests", http.StatusBadRequest)
+		return
+	}
+	req, err := forwarded.parse()
+	if err != nil || req == nil {
+		http.Error(writer, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	res, err := g.Transport.RoundTrip(req.WithContext(r.Context()))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+		return
+	}
+	response, err := NewJsonResponse(res)
+	_ = res.Body.Close()
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+		return
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, _ = writer.Write(data)
+}
+
+func (g *PeerGroup) path() string {
+	if g.Path == "" {
+		return DefaultPeerPath
+	}
+	return g.Path
+}
+
+//peerRing is a consistent hash ring, adding or removing a peer only moves the keys of its points
+type peerRing struct {
+	hashes []uint32
+	peers  map[uint32]string
+}
+
+func newPeerRing(peers []string, replicas int) *peerRing {
+	ring := &peerRing{peers: map[uint32]string{}}
+	for _, peer := range peers {
+		for i := 0; i < replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + peer))
+			ring.hashes = append(ring.hashes, hash)
+			ring.peers[hash] = peer
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+	return ring
+}
+
+//owner returns the peer of the first point after the hash of key, the ring must not be empty
+func (r *peerRing) owner(key string) string {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.peers[r.hashes[i]]
+}
@@ -0,0 +1,120 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPeerGroup(t *testing.T) {
+
+	var mutex sync.Mutex
+	fetched := map[string]int{}
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		fetched[req.URL.Path]++
+		mutex.Unlock()
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body of " + req.URL.Path)), Request: req}, nil
+	})
+
+	var groups []*PeerGroup
+	var servers []*httptest.Server
+	var peers []string
+	for i := 0; i < 3; i++ {
+		group := NewPeerGroup("", &CachedTransport{Cache: NewMapCache(), Fallback: origin}, nil)
+		mux := http.NewServeMux()
+		mux.Handle(DefaultPeerPath, group)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+		group.Self = server.URL
+		groups = append(groups, group)
+		servers = append(servers, server)
+		peers = append(peers, server.URL)
+	}
+	for _, group := range groups {
+		group.Discovery = StaticPeers(peers...)
+		if err := group.RefreshPeers(context.Background()); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	get := func(group *PeerGroup, path string) {
+		client := http.Client{Transport: group}
+		res, err := client.Get("http://example.com" + path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if string(body) != "body of "+path {
+			t.Error("expected the body of", path, "got", string(body))
+		}
+	}
+
+	owners := map[string]int{}
+	for i := 0; i < 30; i++ {
+		path := "/" + strconv.Itoa(i)
+		owner, err := groups[0].Owner(lruTestRequest(t, path))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		owners[owner]++
+		for _, group := range groups {
+			get(group, path)
+		}
+	}
+	if len(owners) != len(peers) {
+		t.Error("expected the keys to be spread over all peers, got", owners)
+	}
+	for path, count := range fetched {
+		if count != 1 {
+			t.Error("expected", path, "to be fetched from the origin once, got", count)
+		}
+	}
+
+	//the requests for the keys of a failed owner are fetched by the asking instance
+	servers[2].Close()
+	for i := 0; i < 30; i++ {
+		get(groups[0], "/"+strconv.Itoa(i))
+	}
+}
+
+func TestPeerRing(t *testing.T) {
+
+	peers := []string{"http://a", "http://b", "http://c"}
+	ring := newPeerRing(peers, DefaultPeerReplicas)
+	reduced := newPeerRing(peers[:2], DefaultPeerReplicas)
+
+	for i := 0; i < 1000; i++ {
+		key := "key " + strconv.Itoa(i)
+		owner := ring.owner(key)
+		if owner != peers[2] && reduced.owner(key) != owner {
+			t.Error("expected", key, "to stay with", owner, "got", reduced.owner(key))
+		}
+		if again := newPeerRing([]string{"http://c", "http://a", "http://b"}, DefaultPeerReplicas).owner(key); i < 10 && again != owner {
+			t.Error("expected the owner to be independent of the order of the peers, got", owner, again)
+		}
+	}
+}
+
+func TestPeerGroup_NoPeers(t *testing.T) {
+
+	group := NewPeerGroup("http://self", &CachedTransport{Cache: NewMapCache()}, StaticPeers())
+	if err := group.RefreshPeers(context.Background()); err != nil {
+		t.Error(err)
+	}
+	owner, err := group.Owner(lruTestRequest(t, "/"))
+	if err != nil || owner != "http://self" {
+		t.Error("expected to own all keys without peers, got", owner, err)
+	}
+}
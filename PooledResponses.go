@@ -0,0 +1,82 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+//hitResponse holds a response served from LRUCache together with its body, reader and header, so a hit takes a
+//single allocation. With CachedTransport.PoolResponses it is recycled for another hit once the body of the fresh hit
+//it was returned as is closed
+type hitResponse struct {
+	//served is 1 while the response is returned to a caller of a transport with PoolResponses, accessed atomically
+	served   int32
+	response http.Response
+	body     bytesBody
+	reader   bytes.Reader
+	//header and values back the header of the served response, see reusedResponse
+	header http.Header
+	values []string
+	//age is the Age value of ageSeconds, the hits of one second share it
+	ageSeconds int64
+	age        string
+}
+
+//hitResponses are the recycled hitResponse
+var hitResponses = sync.Pool{New: func() interface{} { return &hitResponse{} }}
+
+//newHitResponse returns a hitResponse for a copy of res reading body, res.Body is ignored
+func newHitResponse(res *http.Response, body []byte) *http.Response {
+
+	hit := hitResponses.Get().(*hitResponse)
+	hit.response = *res
+	if body == nil {
+		hit.response.Body = http.NoBody
+		return &hit.response
+	}
+	hit.reader.Reset(body)
+	hit.body = bytesBody{Reader: &hit.reader, data: body, hit: hit}
+	hit.response.Body = &hit.body
+	return &hit.response
+}
+
+//hitOf returns the hitResponse res is, nil for other responses
+func hitOf(res *http.Response) *hitResponse {
+	body, ok := res.Body.(*bytesBody)
+	if !ok || body.hit == nil || &body.hit.response != res {
+		return nil
+	}
+	return body.hit
+}
+
+//ageValue returns the Age value for seconds
+func (h *hitResponse) ageValue(seconds int64) string {
+	if h.age == "" || h.ageSeconds != seconds {
+		h.age, h.ageSeconds = strconv.FormatInt(seconds, 10), seconds
+	}
+	return h.age
+}
+
+//poolResponse makes res recycled once its body is closed if it is a hitResponse
+func poolResponse(res *http.Response) {
+	if hit := hitOf(res); hit != nil {
+		atomic.StoreInt32(&hit.served, 1)
+	}
+}
+
+//release recycles the hitResponse once the body of the response it was served as is closed
+func (h *hitResponse) release() {
+	if !atomic.CompareAndSwapInt32(&h.served, 1, 0) {
+		return
+	}
+	//closing the body again before the response is reused does nothing
+	h.response, h.body = http.Response{Body: &h.body}, bytesBody{}
+	h.reader.Reset(nil)
+	for i := range h.values {
+		h.values[i] = ""
+	}
+	hitResponses.Put(h)
+}
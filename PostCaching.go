@@ -0,0 +1,278 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+//RequestBodyDigestHeader is the key line holding the digest of the body of cached POST requests
+const RequestBodyDigestHeader = "X-Cache-Request-Body-Digest"
+
+//DefaultPostCachingMaxBodySize is the MaxBodySize of PostCaching if it is 0
+const DefaultPostCachingMaxBodySize = 1 << 20
+
+var GraphQLMutationError = errors.New("GraphQL mutations and subscriptions are not cached")
+
+//PostCaching makes a CachedTransport cache the responses of POST requests which do not change anything, e.g.
+//GraphQL queries or search APIs. Instead of the body a digest of the body returned by Normalize and of Headers is
+//part of the key. The matched requests do not invalidate the cached responses for their URL
+type PostCaching struct {
+	//Match selects the POST requests which are cached, all if nil
+	Match func(req *http.Request) bool
+	//Normalize returns the body the digest is computed of, e.g. NormalizeGraphQL. Requests it fails for are sent
+	//to the origin without caching
+	Normalize func(body []byte) ([]byte, error)
+	//Headers are included in the digest with their values, e.g. Content-Type
+	Headers []string
+	//Digester computes the digest, SHA256Digester if nil
+	Digester Digester
+	//MaxBodySize is the size in bytes of the largest body which is cached, DefaultPostCachingMaxBodySize if 0
+	MaxBodySize int64
+}
+
+func (p *PostCaching) maxBodySize() int64 {
+	if p.MaxBodySize <= 0 {
+		return DefaultPostCachingMaxBodySize
+	}
+	return p.MaxBodySize
+}
+
+//requests returns req with a body which can be read again and keyReq carrying the digest of the body if the
+//response for req is cached, else req and keyReq as they are
+func (p *PostCaching) requests(req *http.Request, keyReq *http.Request) (*http.Request, *http.Request, error) {
+
+	if p == nil || req.Method != http.MethodPost || p.Match != nil && !p.Match(req) {
+		return req, keyReq, nil
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		read, err := ioutil.ReadAll(io.LimitReader(req.Body, p.maxBodySize()+1))
+		if err != nil {
+			return nil, nil, err
+		}
+		if int64(len(read)) > p.maxBodySize() {
+			//the body is sent as it is, consisting of the part read and the rest
+			req = req.Clone(req.Context())
+			req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(read), req.Body), Closer: req.Body}
+			return req, keyReq, nil
+		}
+		if err := req.Body.Close(); err != nil {
+			return nil, nil, err
+		}
+		body = read
+	}
+	req = withRequestBody(req, body)
+
+	normalized := body
+	if p.Normalize != nil {
+		var err error
+		normalized, err = p.Normalize(body)
+		if err != nil {
+			return req, keyReq, nil
+		}
+	}
+	digested := bytes.NewBuffer(nil)
+	for _, name := range canonicalHeaderNames(p.Headers) {
+		for _, value := range req.Header[name] {
+			digested.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+	digested.WriteString("\r\n")
+	digested.Write(normalized)
+	digester := digestOrDefault(p.Digester)
+	digest := digester.Name() + "=" + hexDigest(digester, digested.Bytes())
+
+	ctx := context.WithValue(keyReq.Context(), bodyDigestContextKey, digest)
+	//the body is kept for caches comparing it, e.g. MatchingCache with MatchJSONBody
+	keyReq = keyReq.WithContext(context.WithValue(ctx, requestBodyContextKey, body))
+	keyReq.Body = http.NoBody
+	keyReq.GetBody = nil
+	keyReq.ContentLength = 0
+	return req, keyReq, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+//withRequestBody returns a copy of req sending body which can be read again with GetBody
+func withRequestBody(req *http.Request, body []byte) *http.Request {
+	req = req.Clone(req.Context())
+	req.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		req.Body = http.NoBody
+		req.GetBody = func() (io.ReadCloser, error) { return http.NoBody, nil }
+		return req
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) { return ioutil.NopCloser(bytes.NewReader(body)), nil }
+	return req
+}
+
+//keyRequestBody returns the body of a key request, the body of requests cached by PostCaching is kept in their context
+func keyRequestBody(req *http.Request) ([]byte, error) {
+	if body, ok := req.Context().Value(requestBodyContextKey).([]byte); ok {
+		return body, nil
+	}
+	if req.GetBody == nil {
+		return nil, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func bodyDigestFromContext(ctx context.Context) string {
+	digest, _ := ctx.Value(bodyDigestContextKey).(string)
+	return digest
+}
+
+//isPostCached reports if keyReq is the key request of a POST request cached by PostCaching
+func isPostCached(keyReq *http.Request) bool {
+	return bodyDigestFromContext(keyReq.Context()) != ""
+}
+
+//rewindBody returns req with an unread body if it was cached by PostCaching, the body may have been sent already
+//by an earlier attempt of the same request, e.g. for a refresh or a revalidation
+func rewindBody(req *http.Request, keyReq *http.Request) (*http.Request, error) {
+	if !isPostCached(keyReq) || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	rewound := *req
+	rewound.Body = body
+	return &rewound, nil
+}
+
+//NormalizeGraphQL is a Normalize function for PostCaching normalizing GraphQL requests with a JSON body: whitespace,
+//commas and comments of the query are removed where they are insignificant and the variables are sorted by name.
+//Queries with a mutation or subscription fail with GraphQLMutationError so they are not cached
+func NormalizeGraphQL(body []byte) ([]byte, error) {
+
+	var request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName,omitempty"`
+		Variables     map[string]interface{} `json:"variables,omitempty"`
+		Extensions    map[string]interface{} `json:"extensions,omitempty"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, err
+	}
+	request.Query = normalizeGraphQLQuery(request.Query)
+	if graphQLChanges(request.Query) {
+		return nil, GraphQLMutationError
+	}
+	//maps are encoded with sorted keys
+	return json.Marshal(request)
+}
+
+//normalizeGraphQLQuery removes comments and keeps whitespace and commas only between two names, strings are kept
+//as they are
+func normalizeGraphQLQuery(query string) string {
+
+	var normalized strings.Builder
+	//last is the last byte written to normalized
+	var last byte
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+			space = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			space = true
+		case c == '"':
+			end := graphQLStringEnd(query, i)
+			if space && isGraphQLNameChar(last) {
+				normalized.WriteByte(' ')
+			}
+			normalized.WriteString(query[i:end])
+			last = '"'
+			i = end - 1
+			space = false
+		default:
+			if space && isGraphQLNameChar(c) && isGraphQLNameChar(last) {
+				normalized.WriteByte(' ')
+			}
+			normalized.WriteByte(c)
+			last = c
+			space = false
+		}
+	}
+	return normalized.String()
+}
+
+//graphQLStringEnd returns the index after the string or block string starting at start
+func graphQLStringEnd(query string, start int) int {
+	if strings.HasPrefix(query[start:], `"""`) {
+		for i := start + 3; i < len(query); i++ {
+			if query[i] == '\\' && strings.HasPrefix(query[i+1:], `"""`) {
+				i += 3
+				continue
+			}
+			if strings.HasPrefix(query[i:], `"""`) {
+				return i + 3
+			}
+		}
+		return len(query)
+	}
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+//graphQLChanges reports if the normalized query defines a mutation or a subscription
+func graphQLChanges(query string) bool {
+
+	depth := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '"':
+			i = graphQLStringEnd(query, i) - 1
+		case c == '{' || c == '(':
+			depth++
+		case c == '}' || c == ')':
+			depth--
+		case depth == 0 && isGraphQLNameChar(c) && (i == 0 || !isGraphQLNameChar(query[i-1])):
+			end := i
+			for end < len(query) && isGraphQLNameChar(query[end]) {
+				end++
+			}
+			if name := query[i:end]; name == "mutation" || name == "subscription" {
+				return true
+			}
+			i = end - 1
+		}
+	}
+	return false
+}
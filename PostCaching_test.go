@@ -0,0 +1,112 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_PostCaching(t *testing.T) {
+
+	var bodies []string
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if failures > 0 {
+			failures--
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.Header().Set("Cache-Control", "max-age=60")
+		_, _ = writer.Write(body)
+	}))
+	defer server.Close()
+
+	cache := NewMapCache()
+	client := &http.Client{Transport: &CachedTransport{
+		Cache:       cache,
+		Fallback:    http.DefaultTransport,
+		Retry:       &Retry{InitialBackoff: 1},
+		PostCaching: &PostCaching{Match: func(req *http.Request) bool { return req.URL.Path == "/graphql" }, Normalize: NormalizeGraphQL},
+	}}
+	post := func(path string, body string) string {
+		response, err := client.Post(server.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		read, _ := ioutil.ReadAll(response.Body)
+		return string(read)
+	}
+
+	query := `{"query":"query Article($id: ID!) { article(id: $id) { title } }","variables":{"id":"1","lang":"de"}}`
+	failures = 1
+	if body := post("/graphql", query); body != query {
+		t.Error("expected the retried request to send the body again got", body)
+	}
+	same := `{"variables":{"lang":"de","id":"1"},"query":"# the article\nquery Article($id: ID!) {\n  article(id: $id) {\n    title\n  }\n}"}`
+	if body := post("/graphql", same); body != query {
+		t.Error("expected the cached response for the normalized query got", body)
+	}
+	if len(bodies) != 2 {
+		t.Error("expected the same query to be sent once got", bodies)
+	}
+	other := `{"query":"query Article($id: ID!) { article(id: $id) { title } }","variables":{"id":"2"}}`
+	if body := post("/graphql", other); body != other || len(bodies) != 3 {
+		t.Error("expected other variables to be sent to the origin got", body, len(bodies))
+	}
+	if len(cache.Keys()) != 2 {
+		t.Error("expected an entry per query got", len(cache.Keys()))
+	}
+
+	mutation := `{"query":"mutation { publish(id: \"1\") { id } }"}`
+	post("/graphql", mutation)
+	post("/graphql", mutation)
+	if len(bodies) != 5 {
+		t.Error("expected the mutations to be sent every time got", len(bodies))
+	}
+	if len(cache.Keys()) != 0 {
+		t.Error("expected the mutation to invalidate the cached queries got", len(cache.Keys()))
+	}
+	post("/search", "q=cache")
+	if len(bodies) != 6 || bodies[5] != "q=cache" || len(cache.Keys()) != 0 {
+		t.Error("expected unmatched POST requests to be sent uncached got", bodies, len(cache.Keys()))
+	}
+}
+
+func TestNormalizeGraphQL(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+		err      error
+	}{
+		{"whitespace", `{"query":"query  A {\n a , b\n}"}`, `{"query":"query A{a b}"}`, nil},
+		{"comment", `{"query":"{ a # comment, with \"quotes\"\n b }"}`, `{"query":"{a b}"}`, nil},
+		{"string", `{"query":"{ a(s: \"x  , # y\") }"}`, `{"query":"{a(s:\"x  , # y\")}"}`, nil},
+		{"block string", `{"query":"{ a(s: \"\"\" x \\\"\"\" y \"\"\") }"}`, `{"query":"{a(s:\"\"\" x \\\"\"\" y \"\"\")}"}`, nil},
+		{"variables", `{"query":"{a}","variables":{"b":1.50,"a":[2,1]}}`, `{"query":"{a}","variables":{"a":[2,1],"b":1.50}}`, nil},
+		{"mutation", `{"query":"mutation M { a }"}`, "", GraphQLMutationError},
+		{"subscription", `{"query":"subscription { a }"}`, "", GraphQLMutationError},
+		{"mutation field", `{"query":"query { mutation }"}`, `{"query":"query{mutation}"}`, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, err := NormalizeGraphQL([]byte(test.body))
+			if !errors.Is(err, test.err) {
+				t.Error("expected", test.err, "got", err)
+			}
+			if string(normalized) != test.expected {
+				t.Error("expected", test.expected, "got", string(normalized))
+			}
+		})
+	}
+	if _, err := NormalizeGraphQL([]byte("not json")); err == nil {
+		t.Error("expected an error for a body which is not JSON")
+	}
+}
@@ -0,0 +1,154 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//Prefetcher warms a cache by requesting URLs through a client using a CachedTransport, e.g. at startup before
+//traffic arrives
+type Prefetcher struct {
+	//Client sends the requests, its Transport is the CachedTransport to warm
+	Client *http.Client
+	//Parallelism is the maximum number of concurrent requests, values below 1 mean 1
+	Parallelism int
+	//ForceRefresh fetches the URLs from the origin even if the cache has fresh responses, see WithForceRefresh
+	ForceRefresh bool
+}
+
+//PrefetchResult is the outcome of prefetching one URL
+type PrefetchResult struct {
+	URL string
+	//StatusCode is the status of the response, 0 if Err is set
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+}
+
+//NewPrefetcher creates a Prefetcher sending at most parallelism concurrent requests with client
+func NewPrefetcher(client *http.Client, parallelism int) *Prefetcher {
+	return &Prefetcher{Client: client, Parallelism: parallelism}
+}
+
+//Prefetch requests all urls and returns their results in the order of urls
+func (p *Prefetcher) Prefetch(ctx context.Context, urls []string) []PrefetchResult {
+
+	results := make([]PrefetchResult, len(urls))
+	requested := make([]bool, len(urls))
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for k := range urls {
+			select {
+			case indices <- k:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	p.work(ctx, func(fetch func(url string) PrefetchResult) {
+		for k := range indices {
+			results[k] = fetch(urls[k])
+			requested[k] = true
+		}
+	})
+
+	//URLs not requested because ctx is done get its error
+	for k := range results {
+		if !requested[k] {
+			results[k] = PrefetchResult{URL: urls[k], Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+//PrefetchChannel requests the URLs received from urls until it is closed or ctx is done and sends their results in
+//the order they complete. The returned channel is closed once all requests are done
+func (p *Prefetcher) PrefetchChannel(ctx context.Context, urls <-chan string) <-chan PrefetchResult {
+
+	results := make(chan PrefetchResult)
+	go func() {
+		defer close(results)
+		p.work(ctx, func(fetch func(url string) PrefetchResult) {
+			for {
+				select {
+				case url, ok := <-urls:
+					if !ok {
+						return
+					}
+					select {
+					case results <- fetch(url):
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}()
+	return results
+}
+
+//work runs worker Parallelism times concurrently and waits for them, fetch requests a url with ctx
+func (p *Prefetcher) work(ctx context.Context, worker func(fetch func(url string) PrefetchResult)) {
+
+	parallelism := p.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var wait sync.WaitGroup
+	wait.Add(parallelism)
+	for i := 0; i < parallelism; i++ {
+		go func() {
+			defer wait.Done()
+			worker(func(url string) PrefetchResult {
+				return p.fetch(ctx, url)
+			})
+		}()
+	}
+	wait.Wait()
+}
+
+//fetch requests url and reads the response completely so it is stored
+func (p *Prefetcher) fetch(ctx context.Context, url string) PrefetchResult {
+
+	start := time.Now()
+	result := PrefetchResult{URL: url}
+
+	if p.ForceRefresh {
+		ctx = WithForceRefresh(ctx)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	client := p.Client
+	if client == nil {
+		client = DefaultCashedClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		result.Err = err
+		result.Duration = time.Since(start)
+		return result
+	}
+	result.StatusCode = res.StatusCode
+
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	closeErr := res.Body.Close()
+	if err == nil {
+		err = closeErr
+	}
+	result.Err = err
+	result.Duration = time.Since(start)
+	return result
+}
@@ -0,0 +1,95 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPrefetcher(t *testing.T) {
+
+	var mutex sync.Mutex
+	requests, active, maxActive := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mutex.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+
+		if r.URL.Path == "/missing" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writer.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(writer, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var urls []string
+	for i := 0; i < 10; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", server.URL, i))
+	}
+	urls = append(urls, server.URL+"/missing", "http://[::1]:namedport")
+
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+	prefetcher := NewPrefetcher(client, 3)
+
+	results := prefetcher.Prefetch(context.Background(), urls)
+	if len(results) != len(urls) {
+		t.Fatal("expected a result per URL got", len(results))
+	}
+	for k, result := range results {
+		if result.URL != urls[k] {
+			t.Error("expected the results in the order of the URLs, got", result.URL, "for", urls[k])
+		}
+		switch {
+		case k < 10 && (result.Err != nil || result.StatusCode != http.StatusOK):
+			t.Error("expected", result.URL, "to be fetched, got", result.StatusCode, result.Err)
+		case k == 10 && result.StatusCode != http.StatusNotFound:
+			t.Error("expected the status of the missing URL, got", result.StatusCode)
+		case k == 11 && result.Err == nil:
+			t.Error("expected an error for the invalid URL")
+		}
+	}
+	if maxActive > 3 {
+		t.Error("expected at most 3 concurrent requests got", maxActive)
+	}
+
+	fetched := requests
+	urlChannel := make(chan string, len(urls))
+	for _, url := range urls[:10] {
+		urlChannel <- url
+	}
+	close(urlChannel)
+	received := 0
+	for result := range prefetcher.PrefetchChannel(context.Background(), urlChannel) {
+		received++
+		if result.Err != nil {
+			t.Error(result.Err)
+		}
+	}
+	if received != 10 || requests != fetched {
+		t.Error("expected 10 results served from the cache, got", received, "results and", requests-fetched, "requests")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, result := range prefetcher.Prefetch(ctx, urls[:3]) {
+		if result.Err == nil {
+			t.Error("expected the context error for", result.URL)
+		}
+	}
+}
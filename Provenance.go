@@ -0,0 +1,123 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+//modulePath is the module path of this library in the build info of binaries using it
+const modulePath = "github.com/Scax/CachedHttpClient-Go"
+
+//Provenance records which writer stored an entry of a FileCache and under which rules, e.g. to find out which
+//instance of a mixed-version fleet cached a response. It is recorded if FileCacheOptions.RecordProvenance is set
+type Provenance struct {
+	//Version is the module version of this library the writer was built with, "(devel)" if it is unknown
+	Version string
+	//Codec names the Codec of the writer, e.g. "json" or "encrypted(gob)"
+	Codec string
+	//PolicyHash identifies the storage options and FileCacheOptions.Policy of the writer, entries written under the
+	//same rules have the same hash
+	PolicyHash string
+	Hostname   string
+	StoredAt   time.Time
+}
+
+var moduleVersionOnce sync.Once
+var moduleVersion string
+
+//libraryVersion returns the version of this module from the build info of the running binary
+func libraryVersion() string {
+	moduleVersionOnce.Do(func() {
+		moduleVersion = "(devel)"
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Main.Path == modulePath && info.Main.Version != "" {
+			moduleVersion = info.Main.Version
+		}
+		for _, dependency := range info.Deps {
+			if dependency.Path == modulePath {
+				moduleVersion = dependency.Version
+			}
+		}
+	})
+	return moduleVersion
+}
+
+//codecName returns the name of codec recorded in Provenance.Codec
+func codecName(codec Codec) string {
+	switch c := codec.(type) {
+	case jsonCodec:
+		return "json"
+	case gobCodec:
+		return "gob"
+	case wireCodec:
+		return "wire"
+	case *encryptedCodec:
+		return "encrypted(" + codecName(c.codec) + ")"
+	case *verboseCertificatesCodec:
+		return "verbose(" + codecName(c.codec) + ")"
+	default:
+		return fmt.Sprintf("%T", codec)
+	}
+}
+
+//provenance returns the Provenance of an entry written now
+func (f *FileCache) provenance() *Provenance {
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+	return &Provenance{
+		Version:    libraryVersion(),
+		Codec:      codecName(f.codec()),
+		PolicyHash: f.policyHash(),
+		Hostname:   hostname,
+		StoredAt:   time.Now().UTC(),
+	}
+}
+
+//policyHash hashes the options deciding how entries are stored and the Policy of the writer
+func (f *FileCache) policyHash() string {
+
+	policy := fmt.Sprintf("codec=%s\nbodyThreshold=%d\ndigester=%s\n", codecName(f.codec()), f.BodyThreshold, digestOrDefault(f.Digester).Name())
+	if c := f.Compression; c != nil {
+		encoding := GzipCompressor.Encoding()
+		if c.Compressor != nil {
+			encoding = c.Compressor.Encoding()
+		}
+		policy += fmt.Sprintf("compression=%s minSize=%d contentTypes=%v adaptive=%t\n", encoding, c.MinSize, c.ContentTypes, c.Adaptive)
+	}
+	policy += "policy=" + f.Policy
+	return hexDigest(SHA256Digester, []byte(policy))[:16]
+}
+
+//Provenance returns the provenance recorded for the entry stored under key, NotInCacheError if there is none
+func (f *FileCache) Provenance(key string) (*Provenance, error) {
+	f.provenanceMutex.Lock()
+	defer f.provenanceMutex.Unlock()
+	provenance, ok := f.provenances[key]
+	if !ok {
+		return nil, NotInCacheError
+	}
+	return provenance, nil
+}
+
+//setProvenance remembers the provenance of the entry for key, nil forgets it
+func (f *FileCache) setProvenance(key string, provenance *Provenance) {
+	f.provenanceMutex.Lock()
+	defer f.provenanceMutex.Unlock()
+	if provenance == nil {
+		delete(f.provenances, key)
+		return
+	}
+	if f.provenances == nil {
+		f.provenances = map[string]*Provenance{}
+	}
+	f.provenances[key] = provenance
+}
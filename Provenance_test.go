@@ -0,0 +1,85 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFileCache_Provenance(t *testing.T) {
+
+	options := FileCacheOptions{RecordProvenance: true, Policy: "config-v1"}
+	fileCache, err := NewFileCache("tmp/provenance.cache", options)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	request := lruTestRequest(t, "/a")
+	if err := fileCache.Set(request, lruTestResponse("a")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	key, _ := fileCache.Key(request)
+
+	provenance, err := fileCache.Provenance(key)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	hostname, _ := os.Hostname()
+	if provenance.Version == "" || provenance.Codec != "json" || provenance.Hostname != hostname || provenance.StoredAt.IsZero() {
+		t.Errorf("expected the writer to be recorded got %+v", provenance)
+	}
+
+	if err := fileCache.Compact(); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reopened, err := OpenFileCache("tmp/provenance.cache", FileCacheOptions{Policy: "config-v2"})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	loaded, err := reopened.Provenance(key)
+	if err != nil || *loaded != *provenance {
+		t.Errorf("expected the provenance %+v to survive compacting and reopening got %+v %v", provenance, loaded, err)
+	}
+	if reopened.policyHash() == provenance.PolicyHash {
+		t.Error("expected another policy to have another hash")
+	}
+
+	if err := reopened.DeleteKey(key); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, err := reopened.Provenance(key); !errors.Is(err, NotInCacheError) {
+		t.Error("expected the provenance to be deleted with the entry got", err)
+	}
+}
+
+func TestCodecName(t *testing.T) {
+
+	encrypted, err := NewEncryptedCodec(GobCodec, EncryptionKeys{CurrentID: "1", Keys: map[string][]byte{"1": bytes.Repeat([]byte{1}, 32)}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	tests := []struct {
+		codec Codec
+		name  string
+	}{
+		{JSONCodec, "json"},
+		{GobCodec, "gob"},
+		{WireCodec, "wire"},
+		{encrypted, "encrypted(gob)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if name := codecName(tt.codec); name != tt.name {
+				t.Error("expected", tt.name, "got", name)
+			}
+		})
+	}
+}
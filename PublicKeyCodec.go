@@ -0,0 +1,244 @@
+package CachedHttpClient
+
+import (
+	"crypto/dsa"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+//PublicKeyCodec converts the public keys of one type for JsonX509Certificate, see RegisterPublicKeyCodec
+type PublicKeyCodec interface {
+	//Encode returns the encoding of publicKey, ok is false for public keys of other types
+	Encode(publicKey interface{}) (encoded []byte, ok bool, err error)
+	//Decode returns the public key of encoded
+	Decode(encoded []byte) (interface{}, error)
+}
+
+//UnknownPublicKeyTypeError is returned for certificates with a type of public key which can not be converted
+var UnknownPublicKeyTypeError = errors.New("unknown public key type")
+
+//UnknownCurveError is returned for ECDSA public keys on a curve which can not be converted
+var UnknownCurveError = errors.New("unknown elliptic curve")
+
+var publicKeyCodecsMutex sync.RWMutex
+
+//publicKeyCodecNames are the names of publicKeyCodecs in the order they are tried when encoding
+var publicKeyCodecNames = []string{"rsa.PublicKey", "ecdsa.PublicKey", "dsa.PublicKey", "ed25519.PublicKey"}
+
+//publicKeyCodecs are the codecs by the name stored in JsonPublicKey.Type
+var publicKeyCodecs = map[string]PublicKeyCodec{
+	"rsa.PublicKey":     rsaPublicKeyCodec{},
+	"ecdsa.PublicKey":   ecdsaPublicKeyCodec{},
+	"dsa.PublicKey":     dsaPublicKeyCodec{},
+	"ed25519.PublicKey": ed25519PublicKeyCodec{},
+}
+
+//ellipticCurves are the curves of ECDSA public keys by their name
+var ellipticCurves = map[string]elliptic.Curve{
+	"P-224": elliptic.P224(),
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+//RegisterPublicKeyCodec adds codec for the public keys of certificates which are converted field by field, see
+//NewVerboseCertificatesCodec. name is stored with the encoded keys to find codec again, registering a name again replaces
+//its codec. The codecs are tried in the order they were first registered, after the ones for RSA, ECDSA, DSA and
+//Ed25519 keys
+func RegisterPublicKeyCodec(name string, codec PublicKeyCodec) {
+	publicKeyCodecsMutex.Lock()
+	defer publicKeyCodecsMutex.Unlock()
+	if _, ok := publicKeyCodecs[name]; !ok {
+		publicKeyCodecNames = append(publicKeyCodecNames, name)
+	}
+	publicKeyCodecs[name] = codec
+}
+
+//RegisterEllipticCurve adds curve to the curves of ECDSA public keys by the name of its parameters, e.g. for
+//secp256k1. P-224, P-256, P-384 and P-521 are registered
+func RegisterEllipticCurve(curve elliptic.Curve) {
+	publicKeyCodecsMutex.Lock()
+	defer publicKeyCodecsMutex.Unlock()
+	ellipticCurves[curve.Params().Name] = curve
+}
+
+//UnregisterPublicKeyCodec removes the codec registered as name, e.g. once a test is done with it. Removing the codecs
+//of RSA, ECDSA, DSA or Ed25519 keys is possible but leaves their keys unconvertible
+func UnregisterPublicKeyCodec(name string) {
+	publicKeyCodecsMutex.Lock()
+	defer publicKeyCodecsMutex.Unlock()
+	if _, ok := publicKeyCodecs[name]; !ok {
+		return
+	}
+	delete(publicKeyCodecs, name)
+	for i, registered := range publicKeyCodecNames {
+		if registered == name {
+			publicKeyCodecNames = append(publicKeyCodecNames[:i:i], publicKeyCodecNames[i+1:]...)
+			break
+		}
+	}
+}
+
+//UnregisterEllipticCurve removes the curve registered by the name of its parameters
+func UnregisterEllipticCurve(name string) {
+	publicKeyCodecsMutex.Lock()
+	defer publicKeyCodecsMutex.Unlock()
+	delete(ellipticCurves, name)
+}
+
+//isUnknownPublicKeyError reports if err is returned for a public key which can not be converted
+func isUnknownPublicKeyError(err error) bool {
+	return errors.Is(err, UnknownPublicKeyTypeError) || errors.Is(err, UnknownCurveError)
+}
+
+//encodePublicKey converts publicKey for the JSON encoding
+func encodePublicKey(publicKey interface{}) (*JsonPublicKey, error) {
+
+	if publicKey == nil {
+		return &JsonPublicKey{}, nil
+	}
+
+	publicKeyCodecsMutex.RLock()
+	defer publicKeyCodecsMutex.RUnlock()
+	for _, name := range publicKeyCodecNames {
+		encoded, ok, err := publicKeyCodecs[name].Encode(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &JsonPublicKey{PublicKey: encoded, Type: name}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %T", UnknownPublicKeyTypeError, publicKey)
+}
+
+//parse returns the public key, nil if the certificate has none
+func (key *JsonPublicKey) parse() (interface{}, error) {
+
+	if key == nil || key.Type == "" {
+		return nil, nil
+	}
+
+	publicKeyCodecsMutex.RLock()
+	codec, ok := publicKeyCodecs[key.Type]
+	publicKeyCodecsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", UnknownPublicKeyTypeError, key.Type)
+	}
+	return codec.Decode(key.PublicKey)
+}
+
+type rsaPublicKeyCodec struct{}
+
+func (rsaPublicKeyCodec) Encode(publicKey interface{}) ([]byte, bool, error) {
+	key, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, false, nil
+	}
+	encoded, err := json.Marshal(key)
+	return encoded, true, err
+}
+
+func (rsaPublicKeyCodec) Decode(encoded []byte) (interface{}, error) {
+	publicKey := &rsa.PublicKey{}
+	err := json.Unmarshal(encoded, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
+
+//ecdsaPublicKeyCodec stores the curve by its name, the curves have to be in ellipticCurves. Encode is called holding
+//publicKeyCodecsMutex
+type ecdsaPublicKeyCodec struct{}
+
+//jsonECDSAPublicKey is the JSON encoding of an ECDSA public key, the curve is stored by its name
+type jsonECDSAPublicKey struct {
+	Curve struct {
+		Name string
+	}
+	X, Y *big.Int
+}
+
+func (ecdsaPublicKeyCodec) Encode(publicKey interface{}) ([]byte, bool, error) {
+	key, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, false, nil
+	}
+	if key.Curve == nil {
+		return nil, true, UnknownCurveError
+	}
+	name := key.Curve.Params().Name
+	if _, ok := ellipticCurves[name]; !ok {
+		return nil, true, fmt.Errorf("%w %q", UnknownCurveError, name)
+	}
+	ecdsaKey := jsonECDSAPublicKey{X: key.X, Y: key.Y}
+	ecdsaKey.Curve.Name = name
+	encoded, err := json.Marshal(ecdsaKey)
+	return encoded, true, err
+}
+
+func (ecdsaPublicKeyCodec) Decode(encoded []byte) (interface{}, error) {
+	publicKey := jsonECDSAPublicKey{}
+	err := json.Unmarshal(encoded, &publicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyCodecsMutex.RLock()
+	curve, ok := ellipticCurves[publicKey.Curve.Name]
+	publicKeyCodecsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", UnknownCurveError, publicKey.Curve.Name)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: publicKey.X, Y: publicKey.Y}, nil
+}
+
+type dsaPublicKeyCodec struct{}
+
+func (dsaPublicKeyCodec) Encode(publicKey interface{}) ([]byte, bool, error) {
+	key, ok := publicKey.(*dsa.PublicKey)
+	if !ok {
+		return nil, false, nil
+	}
+	encoded, err := json.Marshal(key)
+	return encoded, true, err
+}
+
+func (dsaPublicKeyCodec) Decode(encoded []byte) (interface{}, error) {
+	publicKey := &dsa.PublicKey{}
+	err := json.Unmarshal(encoded, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
+
+type ed25519PublicKeyCodec struct{}
+
+func (ed25519PublicKeyCodec) Encode(publicKey interface{}) ([]byte, bool, error) {
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		encoded, err := json.Marshal(key)
+		return encoded, true, err
+	case *ed25519.PublicKey:
+		encoded, err := json.Marshal(*key)
+		return encoded, true, err
+	}
+	return nil, false, nil
+}
+
+func (ed25519PublicKeyCodec) Decode(encoded []byte) (interface{}, error) {
+	var publicKey ed25519.PublicKey
+	err := json.Unmarshal(encoded, &publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey, nil
+}
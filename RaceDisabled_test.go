@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package CachedHttpClient
+
+//raceEnabled is set when the tests run with the race detector
+const raceEnabled = false
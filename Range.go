@@ -0,0 +1,211 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//RangeKeyHeader is the key line holding the range of a stored 206 Partial Content response
+const RangeKeyHeader = "X-Cache-Range"
+
+//isRangeRequest reports if req is a GET request for a byte range which is not handled yet
+func isRangeRequest(req *http.Request) bool {
+	return req.Method == http.MethodGet && req.Header.Get("Range") != "" &&
+		!noCacheFromContext(req.Context()) && rangeFromContext(req.Context()) == ""
+}
+
+func rangeFromContext(ctx context.Context) string {
+	byteRange, _ := ctx.Value(rangeContextKey).(string)
+	return byteRange
+}
+
+func storedOnlyFromContext(ctx context.Context) bool {
+	storedOnly, _ := ctx.Value(storedOnlyContextKey).(bool)
+	return storedOnly
+}
+
+//roundTripRange serves a range request from the stored complete response if there is one, honoring If-Range.
+//Otherwise the request is sent to the origin and a 206 Partial Content response is stored for exactly this range,
+//requests with If-Range are not cached then
+func (c *CachedTransport) roundTripRange(req *http.Request) (*http.Response, error) {
+
+	complete := req.Clone(context.WithValue(req.Context(), storedOnlyContextKey, true))
+	complete.Header.Del("Range")
+	complete.Header.Del("If-Range")
+	res, err := c.RoundTrip(complete)
+	if err == nil {
+		res.Request = req
+		if res.StatusCode != http.StatusOK || !ifRangeMatches(req, res) {
+			//the range is ignored for other responses and for changed representations (RFC 7233 3.1, 3.2)
+			return res, nil
+		}
+		return serveRange(req, res)
+	}
+
+	if req.Header.Get("If-Range") != "" {
+		return c.RoundTrip(req.WithContext(WithNoCache(req.Context())))
+	}
+	byteRange := strings.Join(strings.Fields(req.Header.Get("Range")), "")
+	return c.RoundTrip(req.WithContext(context.WithValue(req.Context(), rangeContextKey, byteRange)))
+}
+
+//rangeKeyRequest returns keyReq without the range header fields if it is the key request of a range request, the
+//range is part of the key through the context
+func rangeKeyRequest(keyReq *http.Request) *http.Request {
+	if rangeFromContext(keyReq.Context()) == "" {
+		return keyReq
+	}
+	keyReq = keyReq.Clone(keyReq.Context())
+	keyReq.Header.Del("Range")
+	keyReq.Header.Del("If-Range")
+	return keyReq
+}
+
+//completeKeyRequest returns the key request of the complete response for the key request of a range request, used
+//if the origin ignored the range
+func completeKeyRequest(keyReq *http.Request) *http.Request {
+	if rangeFromContext(keyReq.Context()) == "" {
+		return keyReq
+	}
+	return keyReq.WithContext(context.WithValue(keyReq.Context(), rangeContextKey, ""))
+}
+
+//ifRangeMatches reports if the If-Range validator of req matches the stored response res, an entity tag must match
+//strongly and a date exactly the Last-Modified of res (RFC 7233 3.2)
+func ifRangeMatches(req *http.Request, res *http.Response) bool {
+
+	ifRange := strings.TrimSpace(req.Header.Get("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == res.Header.Get("ETag")
+	}
+	date, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	return err == nil && date.Equal(lastModified)
+}
+
+//parseByteRange returns the first byte and the length of the single range of header for a body of size bytes.
+//ok is false if header is no single byte range, which is ignored, satisfiable is false if the range is beyond size
+func parseByteRange(header string, size int64) (start int64, length int64, ok bool, satisfiable bool) {
+
+	spec := strings.Join(strings.Fields(header), "")
+	if !strings.HasPrefix(spec, "bytes=") || strings.Contains(spec, ",") {
+		return 0, 0, false, false
+	}
+	first, last := strings.TrimPrefix(spec, "bytes="), ""
+	dash := strings.IndexByte(first, '-')
+	if dash < 0 {
+		return 0, 0, false, false
+	}
+	first, last = first[:dash], first[dash+1:]
+
+	if first == "" {
+		//suffix range of the last bytes
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return 0, 0, false, false
+		}
+		if suffix == 0 || size == 0 {
+			return 0, 0, true, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, suffix, true, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return 0, 0, true, false
+	}
+	return start, end - start + 1, true, true
+}
+
+//serveRange returns the 206 Partial Content response with the range of req cut from the complete response res, a
+//416 response if the range is not satisfiable or res if the Range header is no single byte range
+func serveRange(req *http.Request, res *http.Response) (*http.Response, error) {
+
+	body, err := seekableBody(res)
+	if err != nil {
+		return nil, err
+	}
+	size, err := body.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	start, length, ok, satisfiable := parseByteRange(req.Header.Get("Range"), size)
+	if !ok {
+		return res, nil
+	}
+	partial := *res
+	partial.Header = res.Header.Clone()
+	partial.TransferEncoding = nil
+	if !satisfiable {
+		_ = body.Close()
+		message := "416 Requested Range Not Satisfiable"
+		partial.Status = message
+		partial.StatusCode = http.StatusRequestedRangeNotSatisfiable
+		partial.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		partial.Header.Set("Content-Type", "text/plain; charset=utf-8")
+		partial.Header.Set("Content-Length", strconv.Itoa(len(message)))
+		partial.Body = ioutil.NopCloser(strings.NewReader(message))
+		partial.ContentLength = int64(len(message))
+		return &partial, nil
+	}
+
+	partial.Status = "206 Partial Content"
+	partial.StatusCode = http.StatusPartialContent
+	partial.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+	partial.Header.Set("Content-Length", strconv.FormatInt(length, 10))
+	partial.Header.Set("Accept-Ranges", "bytes")
+	partial.Body = readCloser{Reader: io.NewSectionReader(body, start, length), Closer: body}
+	partial.ContentLength = length
+	if partial.Header.Get("Date") == "" {
+		partial.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	return &partial, nil
+}
+
+//seekableBody returns the body of res as SeekableBody, buffering it if it is none
+func seekableBody(res *http.Response) (SeekableBody, error) {
+	if body, ok := res.Body.(SeekableBody); ok {
+		return body, nil
+	}
+	if res.Body == nil || res.Body == http.NoBody {
+		return newBytesBody(nil).(SeekableBody), nil
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return newBytesBody(data).(SeekableBody), nil
+}
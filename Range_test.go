@@ -0,0 +1,110 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_Range(t *testing.T) {
+
+	const content = "0123456789"
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+" "+r.Header.Get("Range"))
+		writer.Header().Set("Cache-Control", "max-age=60")
+		writer.Header().Set("ETag", `"v1"`)
+		http.ServeContent(writer, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+	get := func(path string, header http.Header) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		response, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		return response, string(body)
+	}
+
+	get("/complete", nil)
+	tests := []struct {
+		name         string
+		header       http.Header
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"range", http.Header{"Range": {"bytes=2-5"}}, http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"open range", http.Header{"Range": {"bytes=7-"}}, http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"suffix range", http.Header{"Range": {"bytes=-3"}}, http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"unsatisfiable", http.Header{"Range": {"bytes=20-"}}, http.StatusRequestedRangeNotSatisfiable, "416 Requested Range Not Satisfiable", "bytes */10"},
+		{"multiple ranges", http.Header{"Range": {"bytes=0-1,4-5"}}, http.StatusOK, content, ""},
+		{"if-range match", http.Header{"Range": {"bytes=0-1"}, "If-Range": {`"v1"`}}, http.StatusPartialContent, "01", "bytes 0-1/10"},
+		{"if-range changed", http.Header{"Range": {"bytes=0-1"}, "If-Range": {`"v0"`}}, http.StatusOK, content, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, body := get("/complete", test.header)
+			if response.StatusCode != test.status || body != test.body || response.Header.Get("Content-Range") != test.contentRange {
+				t.Error("expected", test.status, test.body, test.contentRange, "got", response.StatusCode, body, response.Header.Get("Content-Range"))
+			}
+		})
+	}
+	if len(requests) != 1 {
+		t.Error("expected the ranges to be served from the complete response got", requests)
+	}
+
+	requests = nil
+	for i := 0; i < 2; i++ {
+		if response, body := get("/partial", http.Header{"Range": {"bytes=0-3"}}); response.StatusCode != http.StatusPartialContent || body != "0123" {
+			t.Error("expected the partial response got", response.StatusCode, body)
+		}
+	}
+	get("/partial", http.Header{"Range": {"bytes=4-5"}})
+	if _, body := get("/partial", nil); body != content {
+		t.Error("expected the complete response got", body)
+	}
+	expected := []string{"/partial bytes=0-3", "/partial bytes=4-5", "/partial "}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Error("expected the partial response to be stored for its range only", expected, "got", requests)
+	}
+}
+
+func TestParseByteRange(t *testing.T) {
+
+	tests := []struct {
+		header      string
+		start       int64
+		length      int64
+		ok          bool
+		satisfiable bool
+	}{
+		{"bytes=0-0", 0, 1, true, true},
+		{"bytes=5-100", 5, 5, true, true},
+		{"bytes=-100", 0, 10, true, true},
+		{"bytes=-0", 0, 0, true, false},
+		{"bytes=10-", 0, 0, true, false},
+		{"bytes=5-4", 0, 0, false, false},
+		{"bytes=0-1,3-4", 0, 0, false, false},
+		{"items=0-1", 0, 0, false, false},
+		{"bytes=a-b", 0, 0, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			start, length, ok, satisfiable := parseByteRange(test.header, 10)
+			if start != test.start || length != test.length || ok != test.ok || satisfiable != test.satisfiable {
+				t.Error("expected", test.start, test.length, test.ok, test.satisfiable, "got", start, length, ok, satisfiable)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var RateLimitedError = errors.New("origin request rate limit of the host exceeded")
+
+//RateLimiter limits the origin requests per host with a token bucket, e.g. for third party APIs with a rate limit.
+//Requests beyond the limit wait for their turn up to MaxWait, requests which would wait longer fail at once with
+//RateLimitedError. Responses served from the cache are not limited
+type RateLimiter struct {
+	//Rate is the number of requests per second to every host, 0 does not limit hosts not in HostRates
+	Rate float64
+	//HostRates overrides Rate for the hosts it contains, e.g. "api.example.com"
+	HostRates map[string]float64
+	//Burst is the number of requests sent at once before Rate applies, 1 if 0
+	Burst int
+	//MaxWait is the longest a request waits for its turn, negative waits until the context of the request is done
+	MaxWait time.Duration
+	//Clock tells the time the buckets refill with, SystemClock if nil. Requests wait for their turn on a timer of the
+	//time package, with a ManualClock the tokens only refill when it is advanced
+	Clock Clock
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	//tokens are the requests which can be sent now, negative if requests are waiting
+	tokens float64
+	last   time.Time
+}
+
+//NewRateLimiter creates a RateLimiter allowing rate requests per second to every host, requests wait up to maxWait
+func NewRateLimiter(rate float64, maxWait time.Duration) *RateLimiter {
+	return &RateLimiter{Rate: rate, MaxWait: maxWait}
+}
+
+func (r *RateLimiter) rate(host string) float64 {
+	if rate, ok := r.HostRates[host]; ok {
+		return rate
+	}
+	return r.Rate
+}
+
+func (r *RateLimiter) burst() float64 {
+	if r.Burst <= 0 {
+		return 1
+	}
+	return float64(r.Burst)
+}
+
+//reserve takes a token of the bucket of host and returns how long to wait until it is due, it takes no token and
+//returns false if the wait is longer than MaxWait
+func (r *RateLimiter) reserve(host string, now time.Time) (time.Duration, bool) {
+
+	rate := r.rate(host)
+	if rate <= 0 {
+		return 0, true
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.buckets == nil {
+		r.buckets = map[string]*tokenBucket{}
+	}
+	bucket, ok := r.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{tokens: r.burst(), last: now}
+		r.buckets[host] = bucket
+	}
+	if elapsed := now.Sub(bucket.last); elapsed > 0 {
+		bucket.tokens += elapsed.Seconds() * rate
+		if bucket.tokens > r.burst() {
+			bucket.tokens = r.burst()
+		}
+		bucket.last = now
+	}
+
+	var wait time.Duration
+	if bucket.tokens < 1 {
+		wait = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+	if r.MaxWait >= 0 && wait > r.MaxWait {
+		return wait, false
+	}
+	bucket.tokens--
+	return wait, true
+}
+
+//cancel returns the token taken by reserve for a request which was not sent
+func (r *RateLimiter) cancel(host string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if bucket, ok := r.buckets[host]; ok {
+		bucket.tokens++
+	}
+}
+
+//wait blocks until req may be sent to its host
+func (r *RateLimiter) wait(req *http.Request) error {
+
+	if r == nil {
+		return nil
+	}
+	host := req.URL.Host
+	wait, ok := r.reserve(host, clockOrDefault(r.Clock).Now())
+	if !ok {
+		return RateLimitedError
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		r.cancel(host)
+		return req.Context().Err()
+	}
+}
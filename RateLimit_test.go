@@ -0,0 +1,99 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_RateLimiter(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})
+
+	tests := []struct {
+		name    string
+		limiter *RateLimiter
+		path    string
+		err     error
+		minWait time.Duration
+	}{
+		{"fail fast", &RateLimiter{Rate: 10}, "/b", RateLimitedError, 0},
+		{"queue", &RateLimiter{Rate: 10, MaxWait: time.Second}, "/b", nil, 50 * time.Millisecond},
+		{"cache hits are not limited", &RateLimiter{Rate: 10}, "/a", nil, 0},
+		{"unlimited host", &RateLimiter{Rate: 10, HostRates: map[string]float64{"example.com": 0}}, "/b", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, RateLimiter: tt.limiter}}
+			response, err := client.Get("http://example.com/a")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			_ = response.Body.Close()
+
+			start := time.Now()
+			_, err = client.Get("http://example.com" + tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Error("expected", tt.err, "got", err)
+			}
+			if waited := time.Since(start); waited < tt.minWait {
+				t.Error("expected to wait for the next token at least", tt.minWait, "got", waited)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_reserve(t *testing.T) {
+
+	limiter := &RateLimiter{Rate: 2, Burst: 2, MaxWait: -1}
+	now := time.Now()
+	var waits []time.Duration
+	for i := 0; i < 4; i++ {
+		wait, ok := limiter.reserve("example.com", now)
+		if !ok {
+			t.Error("expected unlimited waiting")
+		}
+		waits = append(waits, wait)
+	}
+	expected := []time.Duration{0, 0, 500 * time.Millisecond, time.Second}
+	for i := range expected {
+		if waits[i] != expected[i] {
+			t.Error("expected the waits", expected, "got", waits)
+			break
+		}
+	}
+
+	if wait, _ := limiter.reserve("example.com", now.Add(10*time.Second)); wait != 0 {
+		t.Error("expected the bucket to be refilled got a wait of", wait)
+	}
+	if wait, _ := limiter.reserve("other.com", now); wait != 0 {
+		t.Error("expected a bucket per host got a wait of", wait)
+	}
+}
+
+func TestRateLimiter_wait_Cancel(t *testing.T) {
+
+	limiter := &RateLimiter{Rate: 1, MaxWait: -1}
+	request, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if err := limiter.wait(request); err != nil {
+		t.Error(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(request.WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected the deadline of the request got", err)
+	}
+	if tokens := limiter.buckets["example.com"].tokens; tokens < -0.1 {
+		t.Error("expected the token of the cancelled request to be returned got", tokens)
+	}
+}
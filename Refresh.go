@@ -0,0 +1,185 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//refreshQueueSize is the number of refreshes a Refresher queues, further refreshes are dropped until it has room
+const refreshQueueSize = 64
+
+//minRefreshPruning is the number of hit counters before a Refresher removes the ones of expired responses
+const minRefreshPruning = 1024
+
+//maxRefreshCounters bounds the hit counters of a Refresher, they are all reset if half as many remain after pruning
+const maxRefreshCounters = 1 << 16
+
+//Refresher refreshes frequently used responses in the background before they expire so hot keys never miss.
+//A response is refreshed once it had MinHits fresh hits and less than Threshold of its freshness lifetime remains.
+//Refreshes run one at a time in a goroutine started by NewRefresher until Shutdown is called
+type Refresher struct {
+	//Threshold is the share of the freshness lifetime, e.g. 0.1 refreshes when less than 10% of it remains
+	Threshold float64
+	//MinHits is the number of fresh hits after which a response is refreshed
+	MinHits int
+
+	mutex sync.Mutex
+	//hits counts the fresh hits per key since the response was last refreshed
+	hits map[string]refreshHits
+	//pruneAt is the number of counters at which the counters of expired responses are removed
+	pruneAt int
+	//pending holds the keys queued for a refresh
+	pending map[string]bool
+	closed  bool
+	queue   chan refreshJob
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+//refreshHits counts the fresh hits of the response which expires at expires, the counter is reset when the key has
+//a response with another expiry, e.g. after it was stored again
+type refreshHits struct {
+	count   int
+	expires time.Time
+}
+
+type refreshJob struct {
+	key       string
+	transport *CachedTransport
+	req       *http.Request
+	keyReq    *http.Request
+	stale     *http.Response
+}
+
+//NewRefresher creates a Refresher for CachedTransport.Refresher and starts its goroutine
+func NewRefresher(threshold float64, minHits int) *Refresher {
+	r := &Refresher{
+		Threshold: threshold,
+		MinHits:   minHits,
+		hits:      map[string]refreshHits{},
+		pruneAt:   minRefreshPruning,
+		pending:   map[string]bool{},
+		queue:     make(chan refreshJob, refreshQueueSize),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+func (r *Refresher) run() {
+	defer close(r.stopped)
+	for {
+		select {
+		case job := <-r.queue:
+			job.transport.refresh(job.req, job.keyReq, job.stale)
+			r.mutex.Lock()
+			delete(r.pending, job.key)
+			r.mutex.Unlock()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+//Shutdown stops the Refresher, queued refreshes are dropped. It waits for a running refresh until ctx is done
+func (r *Refresher) Shutdown(ctx context.Context) error {
+
+	r.mutex.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.done)
+	}
+	r.mutex.Unlock()
+
+	select {
+	case <-r.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//hit counts a fresh hit on res and queues a refresh if res is hot and close to its expiry
+func (r *Refresher) hit(c *CachedTransport, req *http.Request, keyReq *http.Request, res *http.Response, now time.Time) {
+
+	if r == nil {
+		return
+	}
+
+	lifetime := policy.FreshnessLifetime(res, c.Shared)
+	if lifetime <= 0 {
+		return
+	}
+	key := refreshKey(c.Cache, keyReq)
+
+	r.mutex.Lock()
+	if r.closed || r.pending[key] {
+		r.mutex.Unlock()
+		return
+	}
+	remaining := lifetime - policy.CurrentAge(res, now)
+	expires := now.Add(remaining)
+	hits, ok := r.hits[key]
+	if !ok && len(r.hits) >= r.pruneAt {
+		r.prune(now)
+	}
+	//the expiry is derived from the Date header, it differs by less than a second for the same response
+	if diff := hits.expires.Sub(expires); !ok || diff > time.Second || diff < -time.Second {
+		hits = refreshHits{expires: expires}
+	}
+	hits.count++
+	r.hits[key] = hits
+	if hits.count < r.MinHits || float64(remaining) >= r.Threshold*float64(lifetime) {
+		r.mutex.Unlock()
+		return
+	}
+	r.pending[key] = true
+	delete(r.hits, key)
+	r.mutex.Unlock()
+
+	stale, err := CopyResponse(res)
+	if err == nil {
+		select {
+		case r.queue <- refreshJob{key: key, transport: c, req: req, keyReq: keyReq, stale: stale}:
+			return
+		default:
+			//the queue is full, the response is refreshed on a later hit
+		}
+	}
+	r.mutex.Lock()
+	delete(r.pending, key)
+	r.mutex.Unlock()
+}
+
+//prune removes the counters of the responses expired at now, the responses are no longer hit fresh. All counters are
+//reset if half of maxRefreshCounters remain, e.g. of evicted responses with long lifetimes. r.mutex is held
+func (r *Refresher) prune(now time.Time) {
+
+	for key, hits := range r.hits {
+		if !now.Before(hits.expires) {
+			delete(r.hits, key)
+		}
+	}
+	if len(r.hits) >= maxRefreshCounters/2 {
+		r.hits = map[string]refreshHits{}
+	}
+	r.pruneAt = 2 * len(r.hits)
+	if r.pruneAt < minRefreshPruning {
+		r.pruneAt = minRefreshPruning
+	}
+}
+
+//refreshKey identifies the response for req, the cache key if the cache has one
+func refreshKey(cache Cacher, req *http.Request) string {
+	if keyer, ok := cache.(Keyer); ok {
+		if key, err := keyer.Key(req); err == nil {
+			return key
+		}
+	}
+	return req.Method + " " + req.URL.String()
+}
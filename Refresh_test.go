@@ -0,0 +1,113 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresher(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		age       string
+		minHits   int
+		refreshed bool
+	}{
+		{"hot and close to expiry", "95", 2, true},
+		{"not hot", "95", 5, false},
+		{"not close to expiry", "50", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var requests int32
+			fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				header := http.Header{}
+				header.Set("Cache-Control", "max-age=100")
+				if atomic.AddInt32(&requests, 1) == 1 {
+					header.Set("Age", tt.age)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+			})
+
+			refresher := NewRefresher(0.1, tt.minHits)
+			client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, Refresher: refresher}}
+
+			for i := 0; i < 3; i++ {
+				response, err := client.Get("http://example.com/")
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				_ = response.Body.Close()
+			}
+
+			for i := 0; i < 100 && atomic.LoadInt32(&requests) == 1; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			if err := refresher.Shutdown(context.Background()); err != nil {
+				t.Error(err)
+			}
+			if refreshed := atomic.LoadInt32(&requests) == 2; refreshed != tt.refreshed {
+				t.Error("expected refreshed", tt.refreshed, "got", atomic.LoadInt32(&requests), "requests")
+			}
+
+			response, err := client.Get("http://example.com/")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			_ = response.Body.Close()
+			if tt.refreshed && response.Header.Get("Age") == tt.age {
+				t.Error("expected the refreshed response to be served")
+			}
+		})
+	}
+}
+
+func TestRefresher_hits(t *testing.T) {
+
+	refresher := NewRefresher(0.1, 100)
+	defer func() { _ = refresher.Shutdown(context.Background()) }()
+	transport := &CachedTransport{Cache: NewMapCache()}
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	hit := func(path string, stored time.Time, now time.Time) {
+		req := lruTestRequest(t, path)
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=60")
+		res.Header.Set("Date", stored.Format(http.TimeFormat))
+		refresher.hit(transport, req, req, res, now)
+	}
+	count := func(path string) int {
+		refresher.mutex.Lock()
+		defer refresher.mutex.Unlock()
+		return refresher.hits[refreshKey(transport.Cache, lruTestRequest(t, path))].count
+	}
+
+	hit("/a", start, start)
+	hit("/a", start, start.Add(10*time.Second))
+	if hits := count("/a"); hits != 2 {
+		t.Error("expected 2 hits, got", hits)
+	}
+	hit("/a", start.Add(30*time.Second), start.Add(30*time.Second))
+	if hits := count("/a"); hits != 1 {
+		t.Error("expected the counter to restart for the response stored again, got", hits)
+	}
+
+	refresher.mutex.Lock()
+	refresher.pruneAt = 2
+	refresher.mutex.Unlock()
+	hit("/b", start, start)
+	hit("/c", start.Add(2*time.Minute), start.Add(2*time.Minute))
+	refresher.mutex.Lock()
+	defer refresher.mutex.Unlock()
+	if len(refresher.hits) != 1 || refresher.pruneAt != minRefreshPruning {
+		t.Error("expected the counters of the expired responses to be removed, got", refresher.hits)
+	}
+}
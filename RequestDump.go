@@ -0,0 +1,145 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//dumpExcludedHeaders are the header fields httputil.DumpRequestOut does not write from the Header of a request
+var dumpExcludedHeaders = map[string]bool{
+	"Host":              true,
+	"User-Agent":        true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Trailer":           true,
+}
+
+//defaultDumpUserAgent is the User-Agent the Transport of httputil.DumpRequestOut sends if a request has none
+const defaultDumpUserAgent = "Go-http-client/1.1"
+
+//dumpRequestOut returns the same dump as httputil.DumpRequestOut for GET and HEAD requests without a body, written
+//directly instead of through a Transport with a fake connection which allocates about a hundred objects per request
+//and dominated the hit path. ok is false for all other requests and for requests a Transport would alter or reject,
+//they are dumped by httputil.DumpRequestOut
+func dumpRequestOut(req *http.Request) (dump string, ok bool) {
+	dumped, ok := appendRequestDump(nil, req)
+	return string(dumped), ok
+}
+
+//appendRequestDump appends the dump of dumpRequestOut to dst, it allocates only if dst is too small for the dump
+func appendRequestDump(dst []byte, req *http.Request) ([]byte, bool) {
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodHead || req.URL == nil || req.URL.Opaque != "" ||
+		(req.URL.Scheme != "http" && req.URL.Scheme != "https") ||
+		req.Body != nil && req.Body != http.NoBody || req.ContentLength != 0 || req.TransferEncoding != nil ||
+		req.Close || len(req.Trailer) != 0 {
+		return dst, false
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	requestURI := req.URL.RequestURI()
+	if !isPlainDumpHost(host) || hasControlByte(requestURI, false) {
+		return dst, false
+	}
+
+	size := len(method) + len(requestURI) + len(host) + len(defaultDumpUserAgent) + 64
+	keys := make([]string, 0, len(req.Header))
+	for key, values := range req.Header {
+		if key != http.CanonicalHeaderKey(key) || !isDumpToken(key) {
+			return dst, false
+		}
+		for _, value := range values {
+			if hasControlByte(value, true) {
+				return dst, false
+			}
+			size += len(key) + len(value) + 4
+		}
+		if !dumpExcludedHeaders[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	if cap(dst)-len(dst) < size {
+		grown := make([]byte, len(dst), len(dst)+size)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = append(dst, method...)
+	dst = append(dst, ' ')
+	dst = append(dst, requestURI...)
+	dst = append(dst, " HTTP/1.1\r\nHost: "...)
+	dst = append(dst, host...)
+	dst = append(dst, "\r\n"...)
+
+	userAgent := defaultDumpUserAgent
+	if _, ok := req.Header["User-Agent"]; ok {
+		userAgent = strings.Trim(req.Header.Get("User-Agent"), " \t")
+	}
+	if userAgent != "" {
+		dst = appendDumpHeader(dst, "User-Agent", userAgent)
+	}
+	for _, key := range keys {
+		for _, value := range req.Header[key] {
+			dst = appendDumpHeader(dst, key, strings.Trim(value, " \t"))
+		}
+	}
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && method != http.MethodHead {
+		//the Transport asks for gzip itself
+		dst = appendDumpHeader(dst, "Accept-Encoding", "gzip")
+	}
+	return append(dst, "\r\n"...), true
+}
+
+func appendDumpHeader(dst []byte, key string, value string) []byte {
+	dst = append(dst, key...)
+	dst = append(dst, ": "...)
+	dst = append(dst, value...)
+	return append(dst, "\r\n"...)
+}
+
+//isPlainDumpHost reports if host is written by a Transport as it is, names with other characters may be converted
+//to punycode, have a zone removed or be dropped
+func isPlainDumpHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte(".-:[]", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+//isDumpToken reports if key is a valid header field name
+func isDumpToken(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+//hasControlByte reports if s holds an ASCII control character, tabs are allowed with allowTab
+func hasControlByte(s string, allowTab bool) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' && !(allowTab && s[i] == '\t') || s[i] == 0x7f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"testing"
+)
+
+func TestDumpRequestOut(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		header http.Header
+		host   string
+		fast   bool
+	}{
+		{"plain", "GET", "http://example.com/a?b=c", nil, "", true},
+		{"https", "GET", "https://example.com:8443/a", nil, "", true},
+		{"no method", "", "http://example.com/", nil, "", true},
+		{"head", "HEAD", "http://example.com/a", nil, "", true},
+		{"headers", "GET", "http://example.com/a", http.Header{"X-B": {" 2 ", "1"}, "Accept": {"application/json"}, "X-A": {"a\tb"}}, "", true},
+		{"host", "GET", "http://example.com/a", nil, "other.example.com", true},
+		{"ipv6", "GET", "http://[::1]:8080/a", nil, "", true},
+		{"user agent", "GET", "http://example.com/", http.Header{"User-Agent": {"agent/1.0"}}, "", true},
+		{"empty user agent", "GET", "http://example.com/", http.Header{"User-Agent": {""}}, "", true},
+		{"accept encoding", "GET", "http://example.com/", http.Header{"Accept-Encoding": {"br"}}, "", true},
+		{"empty accept encoding", "GET", "http://example.com/", http.Header{"Accept-Encoding": {""}}, "", true},
+		{"range", "GET", "http://example.com/", http.Header{"Range": {"bytes=0-1"}}, "", true},
+		{"excluded headers", "GET", "http://example.com/", http.Header{"Content-Length": {"5"}, "Host": {"x"}, "Trailer": {"X"}}, "", true},
+		{"escaped path", "GET", "http://example.com/a%20b/%C3%A4", nil, "", true},
+		{"post", "POST", "http://example.com/", nil, "", false},
+		{"lower case header", "GET", "http://example.com/", http.Header{"x-lower": {"1"}}, "", false},
+		{"newline in value", "GET", "http://example.com/", http.Header{"X-A": {"a\r\nb"}}, "", false},
+		{"unicode host", "GET", "http://bücher.example/", nil, "", false},
+		{"zone", "GET", "http://[fe80::1%25en0]/", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			req.Method = tt.method
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+
+			dump, ok := dumpRequestOut(req)
+			if ok != tt.fast {
+				t.Error("expected the fast path", tt.fast, "got", ok)
+			}
+			if !ok {
+				return
+			}
+			expected, err := httputil.DumpRequestOut(req, true)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if dump != string(expected) {
+				t.Errorf("dump differs from httputil.DumpRequestOut\n%q\n%q", dump, expected)
+			}
+		})
+	}
+}
+
+func BenchmarkDumpRequest(b *testing.B) {
+	req, _ := http.NewRequest("GET", "https://example.com/articles?page=2", nil)
+	req.Header.Set("Accept", "application/json")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DumpRequest(req, false, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDumpRequest_Body(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req, _ := http.NewRequest("POST", "https://example.com/graphql", strings.NewReader(`{"query":"{a}"}`))
+		if _, err := DumpRequest(req, false, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//DefaultRetryStatusCodes are the status codes retried by Retry if StatusCodes is nil
+var DefaultRetryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+//Retry retries the origin requests of cache misses which fail or get a response with one of StatusCodes. The wait
+//before every further attempt doubles starting at InitialBackoff, a Retry-After header of the response is waited
+//for instead. If all attempts fail a stale response is served within its stale-if-error window
+type Retry struct {
+	//MaxAttempts is the number of requests sent including the first one, 3 if 0
+	MaxAttempts int
+	//InitialBackoff is the wait before the second attempt, 100ms if 0
+	InitialBackoff time.Duration
+	//MaxBackoff limits the wait before an attempt, 10s if 0. Responses asking for a longer Retry-After are not
+	//retried
+	MaxBackoff time.Duration
+	//Jitter is the fraction of the wait which is randomized, between 0 and 1, so clients do not retry in lockstep
+	Jitter float64
+	//StatusCodes are the status codes of the responses which are retried, DefaultRetryStatusCodes if nil
+	StatusCodes []int
+}
+
+func (r *Retry) maxAttempts() int {
+	if r.MaxAttempts <= 0 {
+		return 3
+	}
+	return r.MaxAttempts
+}
+
+func (r *Retry) maxBackoff() time.Duration {
+	if r.MaxBackoff <= 0 {
+		return 10 * time.Second
+	}
+	return r.MaxBackoff
+}
+
+//backoff returns the wait before the attempt following attempt, the first attempt is 1
+func (r *Retry) backoff(attempt int) time.Duration {
+
+	backoff := r.InitialBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+	for i := 1; i < attempt && backoff < r.maxBackoff(); i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff() {
+		backoff = r.maxBackoff()
+	}
+	if r.Jitter > 0 {
+		backoff -= time.Duration(float64(backoff) * r.Jitter * rand.Float64())
+	}
+	return backoff
+}
+
+func (r *Retry) retries(res *http.Response, err error) bool {
+	if errors.Is(err, CircuitOpenError) || errors.Is(err, RateLimitedError) || errors.Is(err, ConnectionBackoffError) {
+		//retrying would fail the same way without reaching the origin
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	statusCodes := r.StatusCodes
+	if statusCodes == nil {
+		statusCodes = DefaultRetryStatusCodes
+	}
+	for _, status := range statusCodes {
+		if res.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+//retryAfter returns the wait requested by the Retry-After header of res, in seconds or as HTTP date (RFC 7231 7.1.3)
+func retryAfter(res *http.Response, now time.Time) (time.Duration, bool) {
+
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := date.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+//roundTrip sends req with send until it succeeds, the attempts are used up or the context of req is done. Requests
+//with a body are only retried if they have GetBody. The response or error of the last attempt is returned
+func (r *Retry) roundTrip(req *http.Request, send func(req *http.Request) (*http.Response, error)) (*http.Response, error) {
+
+	if r == nil {
+		return send(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, err := send(req)
+		if attempt >= r.maxAttempts() || !r.retries(res, err) {
+			return res, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return res, err
+		}
+
+		wait := r.backoff(attempt)
+		if err == nil {
+			if after, ok := retryAfter(res, time.Now()); ok {
+				if after > r.maxBackoff() {
+					return res, err
+				}
+				wait = after
+			}
+			//the connection is reused if the body is read to the end
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			retry := *req
+			retry.Body = body
+			req = &retry
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_Retry(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		retry      *Retry
+		failures   int
+		retryAfter string
+		attempts   int
+		status     int
+	}{
+		{"disabled", nil, 1, "", 1, http.StatusServiceUnavailable},
+		{"recovers", &Retry{InitialBackoff: time.Millisecond}, 2, "", 3, http.StatusOK},
+		{"attempts used up", &Retry{MaxAttempts: 2, InitialBackoff: time.Millisecond}, 5, "", 2, http.StatusServiceUnavailable},
+		{"status not retried", &Retry{InitialBackoff: time.Millisecond, StatusCodes: []int{http.StatusBadGateway}}, 1, "", 1, http.StatusServiceUnavailable},
+		{"retry after", &Retry{InitialBackoff: time.Hour}, 1, "0", 2, http.StatusOK},
+		{"retry after too long", &Retry{InitialBackoff: time.Millisecond, MaxBackoff: time.Second}, 1, "120", 1, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				header := http.Header{}
+				status := http.StatusOK
+				if attempts <= tt.failures {
+					status = http.StatusServiceUnavailable
+					if tt.retryAfter != "" {
+						header.Set("Retry-After", tt.retryAfter)
+					}
+				}
+				return &http.Response{StatusCode: status, Header: header, Body: ioutil.NopCloser(strings.NewReader("body")), Request: req}, nil
+			})
+			transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback, Retry: tt.retry}
+
+			response, err := (&http.Client{Transport: transport}).Get("http://example.com/")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if response.StatusCode != tt.status || attempts != tt.attempts {
+				t.Error("expected status", tt.status, "after", tt.attempts, "attempts got", response.StatusCode, "after", attempts)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_RetryStaleIfError(t *testing.T) {
+
+	attempts := 0
+	failing := false
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if failing {
+			return nil, errors.New("connection refused")
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=0")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("stale")), Request: req}, nil
+	})
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback, StaleIfError: time.Minute, Retry: &Retry{InitialBackoff: time.Millisecond}}
+	client := &http.Client{Transport: transport}
+
+	if _, err := client.Get("http://example.com/"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	failing = true
+	attempts = 0
+	response, err := client.Get("http://example.com/")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if body, _ := ioutil.ReadAll(response.Body); string(body) != "stale" || attempts != 3 {
+		t.Error("expected the stale response after 3 attempts got", string(body), "after", attempts)
+	}
+}
+
+func TestRetry_backoff(t *testing.T) {
+
+	retry := &Retry{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if backoff := retry.backoff(attempt + 1); backoff != expected {
+			t.Error("expected", expected, "after attempt", attempt+1, "got", backoff)
+		}
+	}
+
+	retry.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if backoff := retry.backoff(1); backoff < 500*time.Millisecond || backoff > time.Second {
+			t.Error("expected the backoff to be reduced by at most half got", backoff)
+		}
+	}
+}
+
+func TestRetry_roundTrip_Body(t *testing.T) {
+
+	attempts := 0
+	send := func(req *http.Request) (*http.Response, error) {
+		attempts++
+		body, _ := ioutil.ReadAll(req.Body)
+		if string(body) != "payload" {
+			t.Error("expected the body in every attempt got", string(body))
+		}
+		return nil, errors.New("failed")
+	}
+	retry := &Retry{InitialBackoff: time.Millisecond}
+
+	request, _ := http.NewRequest("GET", "http://example.com/", strings.NewReader("payload"))
+	if _, err := retry.roundTrip(request, send); err == nil || attempts != 3 {
+		t.Error("expected 3 attempts got", attempts, err)
+	}
+
+	attempts = 0
+	request.GetBody = nil
+	request.Body = ioutil.NopCloser(strings.NewReader("payload"))
+	if _, err := retry.roundTrip(request, send); err == nil || attempts != 1 {
+		t.Error("expected requests without GetBody not to be retried got", attempts, "attempts")
+	}
+}
@@ -0,0 +1,66 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//notModifiedIgnoredHeaders are not copied from a 304 response onto the stored response, they describe the empty
+//304 message and not the stored representation
+var notModifiedIgnoredHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+}
+
+//conditionalRequest creates a copy of req asking the origin to validate the stale response using its ETag and
+//Last-Modified headers. ok is false if the stale response has no validator or the request is already conditional
+func conditionalRequest(req *http.Request, stale *http.Response) (conditional *http.Request, ok bool) {
+
+	if stale == nil {
+		return nil, false
+	}
+	if req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return nil, false
+	}
+
+	etag := stale.Header.Get("ETag")
+	lastModified := stale.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil, false
+	}
+
+	conditional = req.Clone(req.Context())
+	if etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	return conditional, true
+}
+
+//mergeNotModified returns a copy of the stale response with the headers of the 304 response notModified applied
+//(RFC 7234 4.3.4), the body of the stale response is reused
+func mergeNotModified(stale *http.Response, notModified *http.Response) *http.Response {
+
+	merged := *stale
+	merged.Header = stale.Header.Clone()
+	if merged.Header == nil {
+		merged.Header = http.Header{}
+	}
+
+	for name, values := range notModified.Header {
+		if notModifiedIgnoredHeaders[name] {
+			continue
+		}
+		merged.Header[name] = append([]string(nil), values...)
+	}
+	//the clock of the stale response is replaced by the one of the revalidation when the merged response is stored
+	merged.Header.Del(policy.RequestTimeHeader)
+	merged.Header.Del(policy.ResponseTimeHeader)
+
+	return &merged
+}
@@ -0,0 +1,87 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_Revalidation(t *testing.T) {
+
+	fullResponses, notModifiedResponses := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Cache-Control", "max-age=0")
+		writer.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModifiedResponses++
+			writer.Header().Set("X-Revalidations", fmt.Sprint(notModifiedResponses))
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fullResponses++
+		fmt.Fprint(writer, "content")
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}}
+
+	for i := 0; i < 3; i++ {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if string(body) != "content" {
+			t.Error("wrong body", string(body))
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Error("wrong status", response.StatusCode)
+		}
+		if i > 0 && response.Header.Get("X-Revalidations") != fmt.Sprint(i) {
+			t.Error("headers of the 304 response not merged", response.Header)
+		}
+	}
+
+	if fullResponses != 1 || notModifiedResponses != 2 {
+		t.Error("expected 1 full and 2 conditional responses, got", fullResponses, notModifiedResponses)
+	}
+
+}
+
+func TestConditionalRequest(t *testing.T) {
+
+	request, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	stale := &http.Response{Header: http.Header{
+		"Etag":          {`"abc"`},
+		"Last-Modified": {"Sat, 09 Nov 2019 02:41:51 GMT"},
+	}}
+
+	conditional, ok := conditionalRequest(request, stale)
+	if !ok {
+		t.Error("no conditional request created")
+		t.FailNow()
+	}
+	if conditional.Header.Get("If-None-Match") != `"abc"` || conditional.Header.Get("If-Modified-Since") != "Sat, 09 Nov 2019 02:41:51 GMT" {
+		t.Error("wrong conditional headers", conditional.Header)
+	}
+	if request.Header.Get("If-None-Match") != "" {
+		t.Error("original request modified")
+	}
+
+	if _, ok := conditionalRequest(request, &http.Response{Header: http.Header{}}); ok {
+		t.Error("conditional request without validators")
+	}
+
+}
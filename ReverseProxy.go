@@ -0,0 +1,59 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+//ProxyNoiseHeaders are the request headers a reverse proxy sets per client, NewReverseProxy leaves them out of the keys
+//so all clients share the entries
+var ProxyNoiseHeaders = []string{"X-Forwarded-For", "Forwarded", "X-Real-Ip"}
+
+//NewReverseProxy returns an httputil.ReverseProxy to target whose origin requests go through a copy of transport, e.g.
+//as a small caching edge in front of an internal API. The copy shares the Cache, Metrics and all other options of
+//transport, it applies the rules of a shared cache and adds ProxyNoiseHeaders to the NoiseHeaders. Errors are answered
+//with 504 Gateway Timeout for CacheMissError of an Offline transport, 503 Service Unavailable if the origin is
+//protected by a LoadShedder, RateLimiter, CircuitBreaker or ConnectionBackoff and 502 Bad Gateway otherwise, they
+//are logged to ErrorLog if it is set. Director, ModifyResponse and ErrorHandler of the returned proxy can be replaced
+//
+//	target, _ := url.Parse("http://api.internal:8080")
+//	http.Handle("/", NewReverseProxy(target, &CachedTransport{Cache: NewLRUCache(LRUCacheOptions{MaxBytes: 1 << 30})}))
+func NewReverseProxy(target *url.URL, transport *CachedTransport) *httputil.ReverseProxy {
+
+	edge := *transport
+	edge.Shared = true
+	edge.NoiseHeaders = append(append([]string(nil), transport.NoiseHeaders...), ProxyNoiseHeaders...)
+	if edge.Fallback == nil {
+		edge.Fallback = http.DefaultTransport
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = &edge
+	proxy.ErrorHandler = func(writer http.ResponseWriter, req *http.Request, err error) {
+		if errors.Is(err, context.Canceled) {
+			//the client went away, there is no one to answer
+			return
+		}
+		status := proxyErrorStatus(err)
+		if proxy.ErrorLog != nil {
+			proxy.ErrorLog.Printf("reverse proxy %s %s: %v", req.Method, req.URL, err)
+		}
+		http.Error(writer, http.StatusText(status), status)
+	}
+	return proxy
+}
+
+//proxyErrorStatus returns the status a reverse proxy answers the error of a CachedTransport with
+func proxyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, CacheMissError):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, LoadSheddingError), errors.Is(err, RateLimitedError), errors.Is(err, CircuitOpenError),
+		errors.Is(err, ConnectionBackoffError):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
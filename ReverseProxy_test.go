@@ -0,0 +1,77 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewReverseProxy(t *testing.T) {
+
+	var requests int32
+	origin := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writer.Header().Set("Cache-Control", "max-age=60")
+		_, _ = writer.Write([]byte("api " + req.URL.Path))
+	}))
+	defer origin.Close()
+	target, err := url.Parse(origin.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := NewMetrics()
+	transport := &CachedTransport{Cache: NewMapCache(), Metrics: metrics}
+	proxy := NewReverseProxy(target, transport)
+
+	//the clients differ in X-Forwarded-For and share the entry
+	for _, client := range []string{"10.0.0.1:1234", "10.0.0.2:1234"} {
+		req := httptest.NewRequest(http.MethodGet, "http://edge.example.com/items", nil)
+		req.RemoteAddr = client
+		recorder := httptest.NewRecorder()
+		proxy.ServeHTTP(recorder, req)
+		if recorder.Code != http.StatusOK || recorder.Body.String() != "api /items" {
+			t.Error("wrong response", recorder.Code, recorder.Body.String())
+		}
+	}
+	if requests != 1 {
+		t.Error("expected 1 origin request, got", requests)
+	}
+	if stats := metrics.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Error("expected the metrics of the transport to count, got", stats)
+	}
+	if transport.Shared || transport.NoiseHeaders != nil || transport.Fallback != nil {
+		t.Error("the transport was changed")
+	}
+}
+
+func TestNewReverseProxy_Errors(t *testing.T) {
+
+	target, _ := url.Parse("http://api.internal")
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"offline", CacheMissError, http.StatusGatewayTimeout},
+		{"shed", LoadSheddingError, http.StatusServiceUnavailable},
+		{"origin", errors.New("connection refused"), http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := NewReverseProxy(target, &CachedTransport{Cache: NewMapCache(), Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				return nil, tt.err
+			})})
+			recorder := httptest.NewRecorder()
+			proxy.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "http://edge.example.com/", nil))
+			body, _ := ioutil.ReadAll(recorder.Body)
+			if recorder.Code != tt.expected {
+				t.Error("expected", tt.expected, "got", recorder.Code, string(body))
+			}
+		})
+	}
+}
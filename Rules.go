@@ -0,0 +1,270 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//CacheRule declares how the requests matching it are cached. A rule matches if Host, Path and Regexp all match, empty
+//patterns match everything
+type CacheRule struct {
+	//Name identifies the rule in errors
+	Name string `json:",omitempty"`
+	//Host matches the host of the request with the glob syntax of path.Match, e.g. "*.example.com"
+	Host string `json:",omitempty"`
+	//Path matches the path of the request with the glob syntax of path.Match, "**" matches across "/" as well, e.g.
+	//"/api/**"
+	Path string `json:",omitempty"`
+	//Regexp matches the URL of the request, e.g. `^https://api\.example\.com/v[0-9]+/users`
+	Regexp string `json:",omitempty"`
+	//Methods are the methods matching the rule, all if empty
+	Methods []string `json:",omitempty"`
+
+	//NoCache bypasses the cache like WithNoCache
+	NoCache bool `json:",omitempty"`
+	//TTL makes responses fresh for TTL after their creation like WithTTL if not 0
+	TTL RuleDuration `json:",omitempty"`
+	//StaleWhileRevalidate and StaleIfError replace the windows of the CachedTransport if not 0
+	StaleWhileRevalidate RuleDuration `json:",omitempty"`
+	StaleIfError         RuleDuration `json:",omitempty"`
+	//Key selects the parts of the request in the cache key like NewKeyFunc if not nil, see Rules.KeyFunc
+	Key *KeyOptions `json:",omitempty"`
+}
+
+//RuleDuration is a time.Duration written as a string like "90s" or "1h" in JSON, numbers are read as seconds
+type RuleDuration time.Duration
+
+func (d RuleDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *RuleDuration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = RuleDuration(seconds * float64(time.Second))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = RuleDuration(duration)
+	return nil
+}
+
+//Rules selects the CacheRule of a request, the first matching rule applies. Rules are declared in code with NewRules
+//or loaded from a JSON file with LoadRules, CachedTransport.Rules applies them to every request. Overrides set on the
+//context of a request, like WithTTL, take precedence over the rules
+type Rules struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	CacheRule
+	path    *regexp.Regexp
+	regexp  *regexp.Regexp
+	keyFunc func(req *http.Request) string
+}
+
+//NewRules compiles rules, it fails for invalid patterns
+func NewRules(rules ...CacheRule) (*Rules, error) {
+
+	compiled := make([]compiledRule, len(rules))
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprint("rule ", i)
+		}
+		compiled[i].CacheRule = rule
+		if _, err := path.Match(rule.Host, ""); err != nil {
+			return nil, fmt.Errorf("%s: host %q: %w", name, rule.Host, err)
+		}
+		if rule.Path != "" {
+			pattern, err := globRegexp(rule.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: path %q: %w", name, rule.Path, err)
+			}
+			compiled[i].path = pattern
+		}
+		if rule.Regexp != "" {
+			pattern, err := regexp.Compile(rule.Regexp)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			compiled[i].regexp = pattern
+		}
+		if rule.Key != nil {
+			compiled[i].keyFunc = NewKeyFunc(*rule.Key)
+		}
+	}
+	return &Rules{rules: compiled}, nil
+}
+
+//ReadRules reads a JSON array of CacheRules from r
+func ReadRules(r io.Reader) (*Rules, error) {
+	var rules []CacheRule
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, err
+	}
+	return NewRules(rules...)
+}
+
+//LoadRules reads the JSON array of CacheRules in the file at path
+func LoadRules(path string) (*Rules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadRules(file)
+}
+
+//globRegexp compiles a glob of path.Match with "**" matching across "/" to a regexp
+func globRegexp(glob string) (*regexp.Regexp, error) {
+
+	if _, err := path.Match(strings.Replace(glob, "**", "*", -1), ""); err != nil {
+		return nil, err
+	}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			pattern.WriteString(".*")
+			i++
+		case c == '*':
+			pattern.WriteString("[^/]*")
+		case c == '?':
+			pattern.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			//the classes of path.Match, negated by "^", are valid in regexps
+			pattern.WriteString(glob[i : i+end+1])
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			pattern.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	pattern.WriteString("$")
+	return regexp.Compile(pattern.String())
+}
+
+//Match returns the first rule matching req, nil if there is none
+func (r *Rules) Match(req *http.Request) *CacheRule {
+	if rule := r.match(req); rule != nil {
+		return &rule.CacheRule
+	}
+	return nil
+}
+
+func (r *Rules) match(req *http.Request) *compiledRule {
+	if r == nil {
+		return nil
+	}
+	for i := range r.rules {
+		if r.rules[i].matches(req) {
+			return &r.rules[i]
+		}
+	}
+	return nil
+}
+
+func (r *compiledRule) matches(req *http.Request) bool {
+
+	if len(r.Methods) > 0 {
+		method := req.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		found := false
+		for _, m := range r.Methods {
+			found = found || strings.EqualFold(m, method)
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.Host != "" {
+		if ok, _ := path.Match(r.Host, strings.ToLower(req.URL.Hostname())); !ok {
+			return false
+		}
+	}
+	if r.path != nil && !r.path.MatchString(req.URL.EscapedPath()) {
+		return false
+	}
+	return r.regexp == nil || r.regexp.MatchString(req.URL.String())
+}
+
+//apply returns req with the overrides of its rule on its context, overrides already set on the context are kept
+func (r *Rules) apply(req *http.Request) *http.Request {
+
+	rule := r.match(req)
+	if rule == nil {
+		return req
+	}
+	ctx := context.WithValue(req.Context(), ruleContextKey, rule)
+	if rule.NoCache {
+		ctx = WithNoCache(ctx)
+	}
+	if _, ok := ttlFromContext(ctx); !ok && rule.TTL != 0 {
+		ctx = WithTTL(ctx, time.Duration(rule.TTL))
+	}
+	return req.WithContext(ctx)
+}
+
+func ruleFromContext(ctx context.Context) *compiledRule {
+	rule, _ := ctx.Value(ruleContextKey).(*compiledRule)
+	return rule
+}
+
+//KeyFunc returns a KeyFunc for MapCacheOptions using the Key of the rule of a request and fallback for requests
+//without one, NewKeyFunc(KeyOptions{}) if fallback is nil. With CachedTransport.Rules the rule selected for the
+//request by the transport is used, so URLRewrites do not change the rule
+func (r *Rules) KeyFunc(fallback func(req *http.Request) string) func(req *http.Request) string {
+	if fallback == nil {
+		fallback = NewKeyFunc(KeyOptions{})
+	}
+	return func(req *http.Request) string {
+		rule := ruleFromContext(req.Context())
+		if rule == nil {
+			rule = r.match(req)
+		}
+		if rule != nil && rule.keyFunc != nil {
+			return rule.keyFunc(req)
+		}
+		return fallback(req)
+	}
+}
+
+//ruleStaleWindow returns the window of directive for stale responses to req, the window of its rule or fallback
+func ruleStaleWindow(req *http.Request, directive string, fallback time.Duration) time.Duration {
+	rule := ruleFromContext(req.Context())
+	if rule == nil {
+		return fallback
+	}
+	window := rule.StaleWhileRevalidate
+	if directive == "stale-if-error" {
+		window = rule.StaleIfError
+	}
+	if window == 0 {
+		return fallback
+	}
+	return time.Duration(window)
+}
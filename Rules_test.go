@@ -0,0 +1,124 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRulesJSON = `[
+	{"Name": "no-cache", "Path": "/api/session/**", "NoCache": true},
+	{"Name": "users", "Host": "*.example.com", "Path": "/api/users/*", "Methods": ["GET"], "TTL": "1h",
+		"StaleIfError": 3600, "Key": {"HashedHeaders": ["Authorization"]}}
+]`
+
+func TestCachedTransport_RoundTrip_Rules(t *testing.T) {
+
+	rules, err := ReadRules(strings.NewReader(testRulesJSON))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	counter := 0
+	failing := false
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return nil, errors.New("connection refused")
+		}
+		counter++
+		//only the TTL of the rule makes the responses fresh
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=0")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+	})
+	cache := NewMapCache(MapCacheOptions{KeyFunc: rules.KeyFunc(nil)})
+	transport := &CachedTransport{Cache: cache, Fallback: fallback, Rules: rules}
+	get := func(ctx context.Context, rawURL string, authorization string) string {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		req.Header.Set("Authorization", authorization)
+		res, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			return err.Error()
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		return string(body)
+	}
+	ctx := context.Background()
+
+	if get(ctx, "http://api.example.com/api/users/1", "a") != "1" || get(ctx, "http://api.example.com/api/users/1", "a") != "1" {
+		t.Error("expected the TTL of the rule to cache the response")
+	}
+	if body := get(ctx, "http://api.example.com/api/users/1", "b"); body != "2" {
+		t.Error("expected the hashed header of the rule in the key got", body)
+	}
+	if key := cache.Keys()[0]; strings.Contains(key, "Authorization: a") {
+		t.Error("expected the Authorization header to be hashed got", key)
+	}
+	if get(ctx, "http://api.example.com/api/session/current/token", "a") != "3" || get(ctx, "http://api.example.com/api/session/current/token", "a") != "4" {
+		t.Error("expected the NoCache rule to bypass the cache")
+	}
+	if a, b := get(ctx, "http://api.other.com/api/users/1", "a"), get(ctx, "http://api.other.com/api/users/1", "a"); a != "5" || b != "6" {
+		t.Error("expected requests matching no rule not to be cached got", a, b)
+	}
+
+	//the TTL set on the context takes precedence, the stale-if-error window of the rule serves the stale response
+	if body := get(WithTTL(ctx, 0), "http://api.example.com/api/users/1", "a"); body != "7" {
+		t.Error("expected WithTTL to take precedence got", body)
+	}
+	failing = true
+	if body := get(WithTTL(ctx, 0), "http://api.example.com/api/users/1", "a"); body != "7" {
+		t.Error("expected the stale-if-error window of the rule got", body)
+	}
+}
+
+func TestNewRules(t *testing.T) {
+
+	rules, err := NewRules(
+		CacheRule{Regexp: `^https://cdn\.example\.com/.*\.js$`, TTL: RuleDuration(24 * time.Hour)},
+		CacheRule{Path: "/static/**/*.[cj]ss"},
+		CacheRule{Host: "example.com", Path: "/?"},
+	)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://cdn.example.com/app/main.js", 0},
+		{"http://cdn.example.com/app/main.js", -1},
+		{"http://example.com/static/a/b/site.css", 1},
+		{"http://example.com/static/site.css", -1},
+		{"http://example.com/a", 2},
+		{"http://EXAMPLE.com/a", 2},
+		{"http://example.com/ab", -1},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+		rule := rules.Match(req)
+		got := -1
+		for i := range rules.rules {
+			if rule == &rules.rules[i].CacheRule {
+				got = i
+			}
+		}
+		if got != tt.want {
+			t.Errorf("Match(%s) = rule %d, want %d", tt.url, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []CacheRule{{Path: "/[a"}, {Host: "[a"}, {Regexp: "("}} {
+		if _, err := NewRules(invalid); err == nil {
+			t.Error("expected an error for", invalid)
+		}
+	}
+	if _, err := ReadRules(strings.NewReader(`[{"Expiry": "1h"}]`)); err == nil {
+		t.Error("expected an error for unknown fields")
+	}
+}
@@ -0,0 +1,92 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+//SeekableBody is implemented by the bodies of responses served from MapCache, LRUCache, FileCache and DiskCache.
+//Consumers like archive/zip or http.ServeContent can seek in them or read at an offset without buffering the body
+//again
+//
+//	if body, ok := res.Body.(SeekableBody); ok {
+//		http.ServeContent(writer, req, name, modTime, body)
+//	}
+type SeekableBody interface {
+	io.ReadCloser
+	io.Seeker
+	io.ReaderAt
+}
+
+//bytesBody is a SeekableBody over a body held in memory, data is shared read-only by all readers of the body
+type bytesBody struct {
+	*bytes.Reader
+	data []byte
+	//trailer is filled once the body was read to the end if not nil, see deferTrailer
+	trailer *deferredTrailer
+	//hit is the hitResponse the body belongs to if not nil, it is recycled on Close
+	hit *hitResponse
+}
+
+//newBytesBody returns a SeekableBody reading data
+func newBytesBody(data []byte) io.ReadCloser {
+	return &bytesBody{Reader: bytes.NewReader(data), data: data}
+}
+
+func (b *bytesBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		b.trailer.fill()
+	}
+	return n, err
+}
+
+//WriteTo is used by io.Copy instead of Read
+func (b *bytesBody) WriteTo(w io.Writer) (int64, error) {
+	n, err := b.Reader.WriteTo(w)
+	if err == nil {
+		b.trailer.fill()
+	}
+	return n, err
+}
+
+func (b *bytesBody) Close() error {
+	if b.hit != nil {
+		b.hit.release()
+	}
+	return nil
+}
+
+//reopen returns an unread body for the same data
+func (b *bytesBody) reopen() *bytesBody {
+	return &bytesBody{Reader: bytes.NewReader(b.data), data: b.data}
+}
+
+//open opens the file of the body on first use
+func (b *fileBody) open() error {
+	if b.file != nil {
+		return nil
+	}
+	file, err := os.Open(filepath.Join(b.dir, b.name))
+	if err != nil {
+		return err
+	}
+	b.file = file
+	return nil
+}
+
+func (b *fileBody) Seek(offset int64, whence int) (int64, error) {
+	if err := b.open(); err != nil {
+		return 0, err
+	}
+	return b.file.Seek(offset, whence)
+}
+
+func (b *fileBody) ReadAt(p []byte, off int64) (int, error) {
+	if err := b.open(); err != nil {
+		return 0, err
+	}
+	return b.file.ReadAt(p, off)
+}
@@ -0,0 +1,108 @@
+package CachedHttpClient
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeekableBody(t *testing.T) {
+
+	body := strings.Repeat("0123456789", 100)
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+
+	fileCache, err := NewFileCache("tmp/seekable.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	largeFileCache, err := NewFileCache("tmp/seekable-large.cache")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	largeFileCache.BodyThreshold = 100
+	if err := os.RemoveAll("tmp/seekable-disk"); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	diskCache, err := NewDiskCache("tmp/seekable-disk")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	tests := []struct {
+		name  string
+		cache Cacher
+	}{
+		{"MapCache", NewMapCache()},
+		{"LRUCache", NewLRUCache(LRUCacheOptions{})},
+		{"FileCache", fileCache},
+		{"FileCache body file", largeFileCache},
+		{"DiskCache", diskCache},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			metrics := NewMetrics()
+			client := http.Client{Transport: &CachedTransport{Cache: tt.cache, Fallback: fallback, Metrics: metrics}}
+			for i := 0; i < 2; i++ {
+				response, err := client.Get("http://example.com/")
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				_, _ = ioutil.ReadAll(response.Body)
+				_ = response.Body.Close()
+			}
+
+			response, err := client.Get("http://example.com/")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			defer response.Body.Close()
+			seekable, ok := response.Body.(SeekableBody)
+			if !ok {
+				t.Errorf("expected a SeekableBody got %T", response.Body)
+				t.FailNow()
+			}
+
+			if _, err := seekable.Seek(995, io.SeekStart); err != nil {
+				t.Error(err)
+			}
+			if rest, _ := ioutil.ReadAll(seekable); string(rest) != "56789" {
+				t.Error("expected the end of the body got", string(rest))
+			}
+			at := make([]byte, 3)
+			if n, err := seekable.ReadAt(at, 11); n != 3 || err != nil || string(at) != "123" {
+				t.Error("expected 123 at offset 11 got", string(at[:n]), err)
+			}
+
+			if _, err := seekable.Seek(0, io.SeekStart); err != nil {
+				t.Error(err)
+			}
+			request := httptest.NewRequest("GET", "/", nil)
+			request.Header.Set("Range", "bytes=10-19")
+			recorder := httptest.NewRecorder()
+			http.ServeContent(recorder, request, "body.txt", time.Time{}, seekable)
+			if recorder.Code != http.StatusPartialContent || recorder.Body.String() != "0123456789" {
+				t.Error("expected the requested range got", recorder.Code, recorder.Body.String())
+			}
+			if served := metrics.Stats().BytesServed; served < 1000 {
+				t.Error("expected the bytes read through the seekable body to be counted got", served)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+//Entry re-serves a cached response to clients. ServeHTTP answers conditional requests with the stored ETag and
+//Last-Modified and Range requests by seeking in the body, e.g. to serve cached assets from a handler
+//
+//	res, err := cache.Get(req)
+//	if err == nil {
+//		NewEntry(res).ServeHTTP(writer, req)
+//	}
+type Entry struct {
+	Response *http.Response
+}
+
+//NewEntry creates an Entry serving res, the body of res is closed by ServeHTTP
+func NewEntry(res *http.Response) *Entry {
+	return &Entry{Response: res}
+}
+
+//entryHeaders are the fields of the stored response set by http.ServeContent itself
+var entryHeaders = []string{"Content-Length", "Content-Range", "Accept-Ranges"}
+
+//ServeHTTP writes the response to writer. Responses with status 200 are served with http.ServeContent, the body is
+//buffered if it is no SeekableBody. Ranges of bodies stored with a Content-Encoding are ranges of the encoded body
+func (e *Entry) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+
+	res := e.Response
+	header := writer.Header()
+	for field, values := range res.Header {
+		header[field] = append([]string(nil), values...)
+	}
+	for _, field := range append(hopByHopFields(res.Header), entryHeaders...) {
+		header.Del(field)
+	}
+
+	var body io.ReadCloser = http.NoBody
+	if res.Body != nil {
+		body = res.Body
+	}
+	defer body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		writer.WriteHeader(res.StatusCode)
+		if req.Method != http.MethodHead {
+			_, _ = io.Copy(writer, body)
+		}
+		return
+	}
+
+	content, ok := body.(io.ReadSeeker)
+	if !ok {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadGateway)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	var modTime time.Time
+	if lastModified, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		modTime = lastModified
+	}
+	//the name is empty so the Content-Type is only sniffed if the stored response has none
+	http.ServeContent(writer, req, "", modTime, content)
+}
@@ -0,0 +1,71 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEntry_ServeHTTP(t *testing.T) {
+
+	cache := NewMapCache()
+	response := lruTestResponse("0123456789")
+	response.Header.Set("Content-Type", "text/plain")
+	response.Header.Set("ETag", `"v1"`)
+	response.Header.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+	response.Header.Set("Connection", "close")
+	if err := cache.Set(lruTestRequest(t, "/asset"), response); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+		body   string
+	}{
+		{"full", nil, http.StatusOK, "0123456789"},
+		{"range", map[string]string{"Range": "bytes=2-4"}, http.StatusPartialContent, "234"},
+		{"if none match", map[string]string{"If-None-Match": `"v1"`}, http.StatusNotModified, ""},
+		{"if modified since", map[string]string{"If-Modified-Since": "Mon, 02 Jan 2006 15:04:05 GMT"}, http.StatusNotModified, ""},
+		{"if range changed", map[string]string{"Range": "bytes=2-4", "If-Range": `"v0"`}, http.StatusOK, "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := cache.Get(lruTestRequest(t, "/asset"))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			request := httptest.NewRequest("GET", "/asset", nil)
+			for field, value := range tt.header {
+				request.Header.Set(field, value)
+			}
+			recorder := httptest.NewRecorder()
+			NewEntry(res).ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status || recorder.Body.String() != tt.body {
+				t.Error("expected", tt.status, tt.body, "got", recorder.Code, recorder.Body.String())
+			}
+			if recorder.Header().Get("ETag") != `"v1"` || recorder.Header().Get("Connection") != "" {
+				t.Error("expected the stored headers without hop-by-hop fields got", recorder.Header())
+			}
+		})
+	}
+}
+
+func TestEntry_ServeHTTP_NotOK(t *testing.T) {
+
+	response := lruTestResponse("missing")
+	response.StatusCode = http.StatusNotFound
+	request := httptest.NewRequest("GET", "/asset", nil)
+	request.Header.Set("Range", "bytes=0-1")
+	recorder := httptest.NewRecorder()
+	NewEntry(response).ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotFound || recorder.Body.String() != "missing" {
+		t.Error("expected the stored status and body got", recorder.Code, recorder.Body.String())
+	}
+}
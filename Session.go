@@ -0,0 +1,117 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//Session memoizes the responses of a CachedTransport for a short scope like one inbound request, e.g. to dedupe the
+//identical sub-requests of a page. The responses of GET and HEAD requests are kept in memory and reused for the
+//lifetime of the session regardless of their freshness, so all requests of the scope see the same snapshot. Concurrent
+//identical requests are sent once. Responses with no-store are not kept. The session is a http.RoundTripper layered
+//on the transport, requests it does not answer go through the transport and its cache
+//
+//	session := transport.Session(r.Context())
+//	client := http.Client{Transport: session}
+type Session struct {
+	transport *CachedTransport
+	coalescer Coalescer
+
+	mutex sync.Mutex
+	//responses holds the memoized responses by key, it is nil once the session is closed
+	responses map[string]*http.Response
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+//Session creates a Session on the transport which is closed when ctx is done, e.g. with the context of an inbound
+//request, or by Close
+func (c *CachedTransport) Session(ctx context.Context) *Session {
+
+	session := &Session{transport: c, responses: map[string]*http.Response{}, stop: make(chan struct{})}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				session.Close()
+			case <-session.stop:
+			}
+		}()
+	}
+	return session
+}
+
+//Close drops the memoized responses, later requests go through the transport
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		s.mutex.Lock()
+		s.responses = nil
+		s.mutex.Unlock()
+		close(s.stop)
+	})
+}
+
+//Len returns the number of memoized responses
+func (s *Session) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.responses)
+}
+
+//RoundTrip returns a copy of the memoized response for req or fetches it through the transport
+func (s *Session) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead || noCacheFromContext(req.Context()) {
+		return s.transport.RoundTrip(req)
+	}
+	key, err := s.transport.requestKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	memoized, ok := s.responses[key]
+	s.mutex.Unlock()
+	if ok {
+		res, err := CopyResponse(memoized)
+		if err != nil {
+			return nil, err
+		}
+		res.Request = req
+		return res, nil
+	}
+
+	//the response is memoized before the coalescer shares it, the waiters copy it concurrently
+	res, shared, err := s.coalescer.do(req.Context(), key, func() (*http.Response, error) {
+		res, err := s.transport.RoundTrip(req)
+		if err != nil || policy.ParseCacheControl(res.Header).Has("no-store") {
+			return res, err
+		}
+		//CopyResponse buffers the body of res, the caller and the session read their own copy
+		memoized, err := CopyResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		s.mutex.Lock()
+		if s.responses != nil {
+			s.responses[key] = memoized
+		}
+		s.mutex.Unlock()
+		return res, nil
+	})
+	if shared && err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) && req.Context().Err() == nil {
+		//the request which was sent gave up, this one is sent on its own
+		return s.transport.RoundTrip(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		res.Request = req
+	}
+	return res, nil
+}
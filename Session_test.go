@@ -0,0 +1,89 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+
+	var counter int64
+	release := make(chan struct{})
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/slow" {
+			<-release
+		}
+		count := atomic.AddInt64(&counter, 1)
+		header := http.Header{}
+		header.Set("Cache-Control", req.URL.Query().Get("cc"))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.FormatInt(count, 10))), Request: req}, nil
+	})
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := transport.Session(ctx)
+	client := http.Client{Transport: session}
+	get := func(url string) string {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		return string(body)
+	}
+
+	//responses the transport does not cache are memoized by the session
+	first := get("http://example.com/?cc=no-cache")
+	if again := get("http://example.com/?cc=no-cache"); again != first {
+		t.Error("expected the memoized response", first, "got", again)
+	}
+	noStore := get("http://example.com/?cc=no-store")
+	if again := get("http://example.com/?cc=no-store"); again == noStore {
+		t.Error("expected no-store responses not to be memoized, got", again)
+	}
+
+	//concurrent identical requests are sent once
+	var wait sync.WaitGroup
+	bodies := make([]string, 3)
+	for i := range bodies {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			bodies[i] = get("http://example.com/slow")
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wait.Wait()
+	if bodies[0] != bodies[1] || bodies[1] != bodies[2] {
+		t.Error("expected one response for the concurrent requests, got", bodies)
+	}
+	if session.Len() != 2 {
+		t.Error("expected 2 memoized responses, got", session.Len())
+	}
+
+	//the session is torn down with its context, the requests go through the transport again
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for session.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if session.Len() != 0 {
+		t.Error("expected the session to be closed with its context")
+	}
+	if again := get("http://example.com/?cc=no-cache"); again == first {
+		t.Error("expected a new response after the session was closed, got", again)
+	}
+	if session.Len() != 0 {
+		t.Error("expected a closed session not to memoize responses")
+	}
+}
@@ -0,0 +1,167 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+//DefaultShards is the number of shards of a ShardedCache if ShardedCacheOptions.Shards is 0
+const DefaultShards = 256
+
+//ShardedCache caches responses in memory like MapCache, the entries are spread over shards by the hash of their key,
+//each with its own lock. Requests for different keys rarely wait for each other, so the cache scales with the number
+//of cores where MapCache serializes all requests on its single mutex
+type ShardedCache struct {
+	ShardedCacheOptions
+	shards []cacheShard
+}
+
+type ShardedCacheOptions struct {
+	MapCacheOptions
+	//Shards is the number of shards, DefaultShards if 0
+	Shards int
+}
+
+type cacheShard struct {
+	//mutex guards entries, hits only take the read lock since the entries are never modified
+	mutex   sync.RWMutex
+	entries map[string]*lruEntry
+}
+
+func NewShardedCache(options ShardedCacheOptions) *ShardedCache {
+	if options.Shards <= 0 {
+		options.Shards = DefaultShards
+	}
+	shards := make([]cacheShard, options.Shards)
+	for i := range shards {
+		shards[i].entries = map[string]*lruEntry{}
+	}
+	return &ShardedCache{ShardedCacheOptions: options, shards: shards}
+}
+
+//Key returns the key the response for req is stored under
+func (s *ShardedCache) Key(req *http.Request) (string, error) {
+	return s.MapCacheOptions.key(req)
+}
+
+//shard returns the shard of key selected by its FNV-1a hash
+func (s *ShardedCache) shard(key string) *cacheShard {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return &s.shards[hash%uint64(len(s.shards))]
+}
+
+func (s *ShardedCache) Get(req *http.Request) (*http.Response, error) {
+
+	key, err := s.Key(req)
+	if err != nil {
+		return nil, err
+	}
+
+	shard := s.shard(key)
+	shard.mutex.RLock()
+	entry, ok := shard.entries[key]
+	shard.mutex.RUnlock()
+	if !ok {
+		return nil, NotInCacheError
+	}
+	atomic.AddInt64(&entry.hits, 1)
+	return entry.toResponse(), nil
+}
+
+func (s *ShardedCache) Set(req *http.Request, res *http.Response) error {
+
+	body, err := bufferBody(res)
+	if err != nil {
+		return err
+	}
+	key, err := s.Key(req)
+	if err != nil {
+		return err
+	}
+
+	stored := *res
+	stored.Body = nil
+	entry := &lruEntry{key: key, response: &stored, body: body}
+
+	shard := s.shard(key)
+	shard.mutex.Lock()
+	shard.entries[key] = entry
+	shard.mutex.Unlock()
+	return nil
+}
+
+//Len returns the number of entries
+func (s *ShardedCache) Len() int {
+	entries := 0
+	for i := range s.shards {
+		s.shards[i].mutex.RLock()
+		entries += len(s.shards[i].entries)
+		s.shards[i].mutex.RUnlock()
+	}
+	return entries
+}
+
+//Keys returns the sorted keys of all entries, the shards are listed one after another so entries stored meanwhile
+//may be missing
+func (s *ShardedCache) Keys() []string {
+
+	var keys []string
+	for i := range s.shards {
+		s.shards[i].mutex.RLock()
+		for key := range s.shards[i].entries {
+			keys = append(keys, key)
+		}
+		s.shards[i].mutex.RUnlock()
+	}
+	sort.Strings(keys)
+	if keys == nil {
+		keys = []string{}
+	}
+	return keys
+}
+
+//GetKey returns the response stored under key
+func (s *ShardedCache) GetKey(key string) (*http.Response, error) {
+
+	shard := s.shard(key)
+	shard.mutex.RLock()
+	entry, ok := shard.entries[key]
+	shard.mutex.RUnlock()
+	if !ok {
+		return nil, NotInCacheError
+	}
+	return entry.toResponse(), nil
+}
+
+//PeekKey returns the response stored under key without its body and without counting it as a hit
+func (s *ShardedCache) PeekKey(key string) (*http.Response, int64, int64, error) {
+
+	shard := s.shard(key)
+	shard.mutex.RLock()
+	entry, ok := shard.entries[key]
+	shard.mutex.RUnlock()
+	if !ok {
+		return nil, 0, 0, NotInCacheError
+	}
+	return entry.peek()
+}
+
+//DeleteKey removes the entry stored under key
+func (s *ShardedCache) DeleteKey(key string) error {
+
+	shard := s.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if _, ok := shard.entries[key]; !ok {
+		return NotInCacheError
+	}
+	delete(shard.entries, key)
+	return nil
+}
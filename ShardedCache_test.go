@@ -0,0 +1,126 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestShardedCache(t *testing.T) {
+
+	cache := NewShardedCache(ShardedCacheOptions{Shards: 4})
+	paths := []string{"/a", "/b", "/c", "/d", "/e"}
+	for _, path := range paths {
+		if err := cache.Set(lruTestRequest(t, path), lruTestResponse("body of "+path)); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	if cache.Len() != len(paths) || len(cache.Keys()) != len(paths) {
+		t.Error("expected", len(paths), "entries, got", cache.Keys())
+	}
+
+	for _, path := range paths {
+		//every response has its own body reader
+		for i := 0; i < 2; i++ {
+			res, err := cache.Get(lruTestRequest(t, path))
+			if err != nil {
+				t.Error(path, err)
+				continue
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			if string(body) != "body of "+path {
+				t.Errorf("unexpected body %q for %s", body, path)
+			}
+		}
+	}
+
+	key, _ := cache.Key(lruTestRequest(t, "/a"))
+	if err := cache.DeleteKey(key); err != nil {
+		t.Error(err)
+	}
+	if err := cache.DeleteKey(key); err != NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+	if _, err := cache.Get(lruTestRequest(t, "/a")); err != NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+	if keys := NewShardedCache(ShardedCacheOptions{}).Keys(); keys == nil || len(keys) != 0 {
+		t.Error("expected no keys, got", keys)
+	}
+}
+
+func TestShardedCache_Concurrent(t *testing.T) {
+
+	cache := NewShardedCache(ShardedCacheOptions{})
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				path := fmt.Sprintf("/%d", i)
+				req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+				if worker%2 == 0 {
+					_ = cache.Set(req, lruTestResponse(path))
+					continue
+				}
+				if res, err := cache.Get(req); err == nil {
+					if body, _ := ioutil.ReadAll(res.Body); string(body) != path {
+						t.Errorf("unexpected body %q for %s", body, path)
+					}
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+	if cache.Len() != 100 {
+		t.Error("expected 100 entries, got", cache.Len())
+	}
+}
+
+//BenchmarkCaches_ParallelGet compares parallel hits of the in memory caches, run it with e.g. -cpu 1,2,4,8 to see
+//ShardedCache scale with GOMAXPROCS while MapCache and LRUCache serialize on their mutex
+func BenchmarkCaches_ParallelGet(b *testing.B) {
+
+	caches := []struct {
+		name  string
+		cache Cacher
+	}{
+		{"MapCache", NewMapCache(MapCacheOptions{KeyFunc: benchmarkCacheKey})},
+		{"LRUCache", NewLRUCache(LRUCacheOptions{MapCacheOptions: MapCacheOptions{KeyFunc: benchmarkCacheKey}})},
+		{"ShardedCache", NewShardedCache(ShardedCacheOptions{MapCacheOptions: MapCacheOptions{KeyFunc: benchmarkCacheKey}})},
+	}
+	requests := make([]*http.Request, 1024)
+	for i := range requests {
+		requests[i], _ = http.NewRequest("GET", fmt.Sprintf("http://example.com/%d", i), nil)
+	}
+
+	for _, c := range caches {
+		for _, req := range requests {
+			if err := c.cache.Set(req, lruTestResponse("body")); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.Run(c.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					res, err := c.cache.Get(requests[i%len(requests)])
+					if err != nil {
+						b.Fatal(err)
+					}
+					_ = res.Body.Close()
+					i++
+				}
+			})
+		})
+	}
+}
+
+//benchmarkCacheKey keys requests by their URL so the benchmark measures the caches instead of the request dumps
+func benchmarkCacheKey(req *http.Request) string {
+	return req.URL.String()
+}
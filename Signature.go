@@ -0,0 +1,223 @@
+package CachedHttpClient
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//RequestSigner modifies requests before they are sent to the origin, e.g. to add authentication. It is applied after
+//the cache key was computed, so signatures and timestamps do not change the key
+type RequestSigner func(req *http.Request) error
+
+//HTTPSignatureOptions configures NewHTTPSigner
+type HTTPSignatureOptions struct {
+	//Label names the signature in the Signature and Signature-Input headers, defaults to sig1
+	Label string
+	KeyID string
+	//Algorithm is one of hmac-sha256, ed25519, ecdsa-p256-sha256, rsa-pss-sha512 and rsa-v1_5-sha256
+	Algorithm string
+	//Key is a []byte for hmac-sha256, a ed25519.PrivateKey, a *ecdsa.PrivateKey or a *rsa.PrivateKey
+	Key interface{}
+	//Components are the covered derived components (e.g. @method, @target-uri) and header names,
+	//defaults to @method, @authority and @target-uri
+	Components []string
+}
+
+var UnsupportedSignatureAlgorithmError = errors.New("unsupported signature algorithm or key type")
+
+//NewHTTPSigner creates a RequestSigner signing requests with HTTP Message Signatures (RFC 9421)
+func NewHTTPSigner(options HTTPSignatureOptions) (RequestSigner, error) {
+
+	if options.Label == "" {
+		options.Label = "sig1"
+	}
+	components := []string{"@method", "@authority", "@target-uri"}
+	if options.Components != nil {
+		components = make([]string, len(options.Components))
+		for k, component := range options.Components {
+			components[k] = strings.ToLower(component)
+		}
+	}
+
+	sign, err := signatureFunc(options.Algorithm, options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(req *http.Request) error {
+
+		params := signatureParams(components, time.Now(), options.KeyID, options.Algorithm)
+		base, err := signatureBase(req, components, params)
+		if err != nil {
+			return err
+		}
+
+		signature, err := sign([]byte(base))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Signature-Input", options.Label+"="+params)
+		req.Header.Set("Signature", options.Label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+		return nil
+	}, nil
+}
+
+//signatureParams serializes the @signature-params component
+func signatureParams(components []string, created time.Time, keyID string, algorithm string) string {
+
+	quoted := make([]string, len(components))
+	for k, component := range components {
+		quoted[k] = strconv.Quote(component)
+	}
+
+	params := "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(created.Unix(), 10)
+	if keyID != "" {
+		params += ";keyid=" + strconv.Quote(keyID)
+	}
+	if algorithm != "" {
+		params += ";alg=" + strconv.Quote(algorithm)
+	}
+	return params
+}
+
+//signatureBase creates the signature base of req (RFC 9421 2.5)
+func signatureBase(req *http.Request, components []string, params string) (string, error) {
+
+	var base strings.Builder
+
+	for _, component := range components {
+		value, err := componentValue(req, component)
+		if err != nil {
+			return "", err
+		}
+		base.WriteString(strconv.Quote(component) + ": " + value + "\n")
+	}
+	base.WriteString(`"@signature-params": ` + params)
+
+	return base.String(), nil
+}
+
+//componentValue returns the value of a derived component or header field of req
+func componentValue(req *http.Request, component string) (string, error) {
+
+	switch component {
+	case "@method":
+		return strings.ToUpper(req.Method), nil
+	case "@target-uri":
+		return req.URL.String(), nil
+	case "@authority":
+		return strings.ToLower(requestHost(req)), nil
+	case "@scheme":
+		return strings.ToLower(req.URL.Scheme), nil
+	case "@request-target":
+		return req.URL.RequestURI(), nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	}
+
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("unsupported signature component %s", component)
+	}
+
+	values, ok := req.Header[http.CanonicalHeaderKey(component)]
+	if !ok {
+		return "", fmt.Errorf("signature component %s missing in request", component)
+	}
+	trimmed := make([]string, len(values))
+	for k, value := range values {
+		trimmed[k] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+//signatureFunc returns the function signing signature bases with algorithm and key
+func signatureFunc(algorithm string, key interface{}) (func(base []byte) ([]byte, error), error) {
+
+	switch k := key.(type) {
+	case []byte:
+		if algorithm != "hmac-sha256" {
+			break
+		}
+		return func(base []byte) ([]byte, error) {
+			mac := hmac.New(sha256.New, k)
+			mac.Write(base)
+			return mac.Sum(nil), nil
+		}, nil
+
+	case ed25519.PrivateKey:
+		if algorithm != "ed25519" {
+			break
+		}
+		return func(base []byte) ([]byte, error) {
+			return ed25519.Sign(k, base), nil
+		}, nil
+
+	case *ecdsa.PrivateKey:
+		if algorithm != "ecdsa-p256-sha256" {
+			break
+		}
+		return func(base []byte) ([]byte, error) {
+			digest := sha256.Sum256(base)
+			r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+			if err != nil {
+				return nil, err
+			}
+			//RFC 9421 3.3.4: r and s as fixed size big-endian integers
+			signature := make([]byte, 64)
+			rBytes, sBytes := r.Bytes(), s.Bytes()
+			copy(signature[32-len(rBytes):32], rBytes)
+			copy(signature[64-len(sBytes):], sBytes)
+			return signature, nil
+		}, nil
+
+	case *rsa.PrivateKey:
+		switch algorithm {
+		case "rsa-pss-sha512":
+			return func(base []byte) ([]byte, error) {
+				digest := sha512.Sum512(base)
+				return rsa.SignPSS(rand.Reader, k, crypto.SHA512, digest[:], &rsa.PSSOptions{SaltLength: 64})
+			}, nil
+		case "rsa-v1_5-sha256":
+			return func(base []byte) ([]byte, error) {
+				digest := sha256.Sum256(base)
+				return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+			}, nil
+		}
+	}
+
+	return nil, UnsupportedSignatureAlgorithmError
+}
+
+//sign applies the Signer of the transport to a copy of req
+func (c *CachedTransport) sign(req *http.Request) (*http.Request, error) {
+
+	if c.Signer == nil {
+		return req, nil
+	}
+
+	signed := req.Clone(req.Context())
+	err := c.Signer(signed)
+	if err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
@@ -0,0 +1,91 @@
+package CachedHttpClient
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewHTTPSigner(t *testing.T) {
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	signer, err := NewHTTPSigner(HTTPSignatureOptions{
+		KeyID:      "test-key",
+		Algorithm:  "ed25519",
+		Key:        privateKey,
+		Components: []string{"@method", "@authority", "@path", "Accept"},
+	})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	var signedRequests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		signedRequests = append(signedRequests, r)
+		writer.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport, Signer: signer}}
+
+	for i := 0; i < 2; i++ {
+		request, err := http.NewRequest("GET", server.URL+"/path", nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header.Set("Accept", "application/json")
+		_, err = client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if request.Header.Get("Signature") != "" {
+			t.Error("request of the caller modified")
+		}
+	}
+
+	if len(signedRequests) != 1 {
+		t.Error("signature changed the cache key, origin requests:", len(signedRequests))
+		t.FailNow()
+	}
+
+	received := signedRequests[0]
+	input := received.Header.Get("Signature-Input")
+	if !strings.HasPrefix(input, `sig1=("@method" "@authority" "@path" "accept");created=`) || !strings.HasSuffix(input, `;keyid="test-key";alg="ed25519"`) {
+		t.Error("wrong Signature-Input", input)
+	}
+
+	received.URL.Host = received.Host
+	base, err := signatureBase(received, []string{"@method", "@authority", "@path", "accept"}, strings.TrimPrefix(input, "sig1="))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimPrefix(received.Header.Get("Signature"), "sig1="), ":"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if !ed25519.Verify(publicKey, []byte(base), signature) {
+		t.Error("invalid signature for base", base)
+	}
+
+}
+
+func TestNewHTTPSigner_UnsupportedKey(t *testing.T) {
+	_, err := NewHTTPSigner(HTTPSignatureOptions{Algorithm: "ed25519", Key: []byte("secret")})
+	if err != UnsupportedSignatureAlgorithmError {
+		t.Error("expected UnsupportedSignatureAlgorithmError, got", err)
+	}
+}
@@ -0,0 +1,182 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var NotSoftDeletedError = errors.New("the entry is not soft deleted or its restore window closed")
+
+//SoftDelete makes the invalidations of a CachedTransport soft: the entries stay in the cache but are not served,
+//Restore undoes an accidental invalidation within Window. CollectSoftDeleted deletes the entries whose window
+//closed. Storing a new response for a soft deleted key ends its soft deletion
+type SoftDelete struct {
+	//Window is the time a soft deleted entry can be restored
+	Window time.Duration
+
+	mutex sync.Mutex
+	//deleted holds the time the soft deleted keys were deleted at
+	deleted map[string]time.Time
+}
+
+func NewSoftDelete(window time.Duration) *SoftDelete {
+	return &SoftDelete{Window: window, deleted: map[string]time.Time{}}
+}
+
+//Deleted returns the soft deleted keys with the time they were deleted at
+func (s *SoftDelete) Deleted() map[string]time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	deleted := make(map[string]time.Time, len(s.deleted))
+	for key, at := range s.deleted {
+		deleted[key] = at
+	}
+	return deleted
+}
+
+func (s *SoftDelete) markDeleted(key string, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.deleted == nil {
+		s.deleted = map[string]time.Time{}
+	}
+	if _, ok := s.deleted[key]; !ok {
+		s.deleted[key] = now
+	}
+}
+
+func (s *SoftDelete) isDeleted(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.deleted[key]
+	return ok
+}
+
+//restore ends the soft deletion of key if its window is still open
+func (s *SoftDelete) restore(key string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	at, ok := s.deleted[key]
+	if !ok || now.Sub(at) > s.Window {
+		return false
+	}
+	delete(s.deleted, key)
+	return true
+}
+
+//hides reports if the response for req is soft deleted
+func (s *SoftDelete) hides(cache Cacher, req *http.Request) bool {
+	if s == nil {
+		return false
+	}
+	key, ok := cacheKey(cache, req)
+	return ok && s.isDeleted(key)
+}
+
+//stored ends the soft deletion of the key of req after a new response was stored for it
+func (s *SoftDelete) stored(cache Cacher, req *http.Request) {
+	if s == nil {
+		return
+	}
+	if key, ok := cacheKey(cache, req); ok {
+		s.mutex.Lock()
+		delete(s.deleted, key)
+		s.mutex.Unlock()
+	}
+}
+
+//cacheKey returns the key of req if cache implements Keyer
+func cacheKey(cache Cacher, req *http.Request) (string, bool) {
+	keyer, ok := cache.(Keyer)
+	if !ok {
+		return "", false
+	}
+	key, err := keyer.Key(req)
+	return key, err == nil
+}
+
+//softDeleteKey soft deletes the entry stored under key if it exists
+func (c *CachedTransport) softDeleteKey(inspector Inspector, key string) error {
+	res, err := inspector.GetKey(key)
+	if err != nil {
+		return err
+	}
+	if res.Body != nil {
+		_ = res.Body.Close()
+	}
+	if c.SoftDelete.isDeleted(key) {
+		return NotInCacheError
+	}
+	c.SoftDelete.markDeleted(key, c.now())
+	return nil
+}
+
+//Restore serves the soft deleted entry stored under key again, it fails with NotSoftDeletedError if the entry is
+//not soft deleted or its restore window closed
+func (c *CachedTransport) Restore(key string) error {
+	if c.SoftDelete == nil || !c.SoftDelete.restore(key, c.now()) {
+		return NotSoftDeletedError
+	}
+	return nil
+}
+
+//CollectSoftDeleted deletes the soft deleted entries whose restore window closed and returns their number
+func (c *CachedTransport) CollectSoftDeleted() (int, error) {
+
+	if c.SoftDelete == nil {
+		return 0, nil
+	}
+	inspector, ok := c.Cache.(Inspector)
+	if !ok {
+		return 0, InvalidationNotSupportedError
+	}
+
+	deadline := c.now().Add(-c.SoftDelete.Window)
+	collected := 0
+	for key, at := range c.SoftDelete.Deleted() {
+		if at.After(deadline) || !c.SoftDelete.isDeleted(key) {
+			continue
+		}
+		err := inspector.DeleteKey(key)
+		if err != nil && !errors.Is(err, NotInCacheError) {
+			return collected, err
+		}
+		c.SoftDelete.mutex.Lock()
+		//the key is only forgotten if it was not stored again meanwhile
+		if c.SoftDelete.deleted[key].Equal(at) {
+			delete(c.SoftDelete.deleted, key)
+		}
+		c.SoftDelete.mutex.Unlock()
+		if err == nil {
+			collected++
+		}
+	}
+	return collected, nil
+}
+
+//CollectSoftDeletedEvery calls CollectSoftDeleted every interval of the Clock until stop is called, errors are ignored
+func (c *CachedTransport) CollectSoftDeletedEvery(interval time.Duration) (stop func()) {
+
+	ticker := clockOrDefault(c.Clock).NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				_, _ = c.CollectSoftDeleted()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_SoftDelete(t *testing.T) {
+
+	counter := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		counter++
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+	})
+	cache := NewMapCache()
+	transport := &CachedTransport{Cache: cache, Fallback: fallback, SoftDelete: NewSoftDelete(time.Hour)}
+	client := http.Client{Transport: transport}
+	get := func(path string) string {
+		response, err := client.Get("http://example.com" + path)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		return string(body)
+	}
+
+	get("/a")
+	get("/b")
+	deleted, err := transport.InvalidateURL("http://example.com/a")
+	if err != nil || deleted != 1 {
+		t.Error("expected one soft deleted entry got", deleted, err)
+	}
+	if len(cache.Keys()) != 2 {
+		t.Error("expected the soft deleted entry to stay in the cache got", len(cache.Keys()))
+	}
+	keyA, _ := cache.Key(lruTestRequest(t, "/a"))
+	if _, ok := transport.SoftDelete.Deleted()[keyA]; !ok {
+		t.Error("expected the key of /a to be soft deleted got", transport.SoftDelete.Deleted())
+	}
+	if err := transport.Invalidate(keyA); !errors.Is(err, NotInCacheError) {
+		t.Error("expected NotInCacheError for an entry soft deleted twice got", err)
+	}
+
+	if err := transport.Restore(keyA); err != nil {
+		t.Error(err)
+	}
+	if body := get("/a"); body != "1" {
+		t.Error("expected the restored response got", body)
+	}
+	if err := transport.Restore(keyA); !errors.Is(err, NotSoftDeletedError) {
+		t.Error("expected NotSoftDeletedError got", err)
+	}
+
+	keyB, _ := cache.Key(lruTestRequest(t, "/b"))
+	if err := transport.Invalidate(keyB); err != nil {
+		t.Error(err)
+	}
+	if body := get("/b"); body != "3" {
+		t.Error("expected the soft deleted response not to be served got", body)
+	}
+	if len(transport.SoftDelete.Deleted()) != 0 {
+		t.Error("expected the new response to end the soft deletion got", transport.SoftDelete.Deleted())
+	}
+
+	if err := transport.Invalidate(keyA); err != nil {
+		t.Error(err)
+	}
+	if collected, err := transport.CollectSoftDeleted(); err != nil || collected != 0 {
+		t.Error("expected no entry to be collected within the window got", collected, err)
+	}
+	transport.SoftDelete.Window = 0
+	if err := transport.Restore(keyA); !errors.Is(err, NotSoftDeletedError) {
+		t.Error("expected the restore window to be closed got", err)
+	}
+	if collected, err := transport.CollectSoftDeleted(); err != nil || collected != 1 {
+		t.Error("expected the entry to be collected got", collected, err)
+	}
+	if len(cache.Keys()) != 1 || len(transport.SoftDelete.Deleted()) != 0 {
+		t.Error("expected the entry to be deleted got", len(cache.Keys()), "keys and", transport.SoftDelete.Deleted())
+	}
+}
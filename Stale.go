@@ -0,0 +1,152 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//staleWarning is added to responses served while stale (RFC 7234 5.5.1)
+const staleWarning = `110 - "Response is Stale"`
+
+//staleWindow returns the window a stale res may be served in after its freshness lifetime ended, taken from the
+//directive of the response or the fallback duration if the origin did not send it
+func staleWindow(res *http.Response, directive string, fallback time.Duration) time.Duration {
+
+	if window, ok := policy.ParseCacheControl(res.Header).Seconds(directive); ok {
+		return window
+	}
+	return fallback
+}
+
+//canServeStale reports if res is at most window past its freshness lifetime and the origin does not forbid
+//serving it stale
+func canServeStale(res *http.Response, shared bool, now time.Time, window time.Duration) bool {
+
+	if window <= 0 {
+		return false
+	}
+
+	cc := policy.ParseCacheControl(res.Header)
+	if cc.Has("must-revalidate") || cc.Has("no-cache") || (shared && cc.Has("proxy-revalidate")) {
+		return false
+	}
+
+	return policy.CurrentAge(res, now)-policy.FreshnessLifetime(res, shared) <= window
+}
+
+//serveStale returns the stale response for req marked with a stale warning at now
+func serveStale(req *http.Request, stale *http.Response, now time.Time) *http.Response {
+	stale = reusedResponse(stale, now)
+	stale.Header = stale.Header.Clone()
+	if stale.Header == nil {
+		stale.Header = http.Header{}
+	}
+	stale.Header.Add("Warning", staleWarning)
+	stale.Request = req
+	return stale
+}
+
+//servesWhileRevalidating reports if the stale response is served while it is refreshed in the background, within
+//its stale-while-revalidate window or by StaleOnDeadline. Requests with no-cache, max-age or min-fresh are not
+func (c *CachedTransport) servesWhileRevalidating(req *http.Request, keyReq *http.Request, stale *http.Response, now time.Time) bool {
+	if policy.RequestForbidsStale(keyReq) {
+		return false
+	}
+	window := staleWindow(stale, "stale-while-revalidate", ruleStaleWindow(req, "stale-while-revalidate", c.StaleWhileRevalidate))
+	return canServeStale(stale, c.Shared, now, window) || c.StaleOnDeadline.serveStale(req, stale, c.Shared, now)
+}
+
+//isOriginError reports if the outcome of an origin request allows falling back to a stale response
+//following stale-if-error (RFC 5861 4)
+func isOriginError(res *http.Response, err error) bool {
+	return err != nil || res.StatusCode >= http.StatusInternalServerError
+}
+
+//revalidations holds the keys of the stale responses revalidated in the background by a transport, concurrent stale
+//hits within stale-while-revalidate send a single request to the origin. Keys are removed once their refresh is done
+type revalidations struct {
+	mutex   sync.Mutex
+	pending map[string]bool
+}
+
+//revalidationSets guards the creation of the revalidations of a transport
+var revalidationSets sync.Mutex
+
+//pendingRevalidations returns the revalidations of the transport, they are created on first use
+func (c *CachedTransport) pendingRevalidations() *revalidations {
+	if r, ok := c.revalidations.Load().(*revalidations); ok {
+		return r
+	}
+	revalidationSets.Lock()
+	defer revalidationSets.Unlock()
+	if r, ok := c.revalidations.Load().(*revalidations); ok {
+		return r
+	}
+	r := &revalidations{pending: map[string]bool{}}
+	c.revalidations.Store(r)
+	return r
+}
+
+//revalidateInBackground refreshes the stale response for req in the background unless a refresh of its key is
+//running already
+func (c *CachedTransport) revalidateInBackground(req *http.Request, keyReq *http.Request, stale *http.Response) error {
+
+	key := refreshKey(c.Cache, keyReq)
+	r := c.pendingRevalidations()
+	r.mutex.Lock()
+	if r.pending[key] {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.pending[key] = true
+	r.mutex.Unlock()
+	done := func() {
+		r.mutex.Lock()
+		delete(r.pending, key)
+		r.mutex.Unlock()
+	}
+
+	background, err := CopyResponse(stale)
+	if err != nil {
+		done()
+		return err
+	}
+	go func() {
+		defer done()
+		c.refresh(req, keyReq, background)
+	}()
+	return nil
+}
+
+//refresh fetches req in the background to update the stale entry, it is detached from the cancellation of the
+//caller which may already be done when the refresh starts and bounded by RefreshTimeout. Nothing is fetched in
+//Offline mode
+func (c *CachedTransport) refresh(req *http.Request, keyReq *http.Request, stale *http.Response) {
+
+	if c.Offline {
+		return
+	}
+
+	timeout := c.RefreshTimeout
+	if timeout <= 0 {
+		timeout = DefaultRefreshTimeout
+	}
+	ctx, cancel := context.WithTimeout(detach(req.Context()), timeout)
+	defer cancel()
+	req = req.Clone(ctx)
+	keyReq = keyReq.Clone(ctx)
+
+	response, err := c.fetch(req, keyReq, stale)
+	if err != nil {
+		return
+	}
+
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+	_ = response.Body.Close()
+}
@@ -0,0 +1,80 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+//latencyWeight is the weight of a new observation in the moving average of the origin latency
+const latencyWeight = 0.2
+
+//StaleOnDeadline serves stale responses immediately, refreshing them in the background, if the deadline of the
+//request context leaves less time than the origin of the request is estimated to need. The latency of an origin is
+//estimated from the moving average of the observed origin requests
+type StaleOnDeadline struct {
+	//MaxStale is how long after their freshness lifetime ended responses may be served, must-revalidate and no-cache
+	//are honored
+	MaxStale time.Duration
+	//Latencies are fixed latency estimates by host replacing the observed ones
+	Latencies map[string]time.Duration
+	//DefaultLatency is the estimate for hosts without observations, 0 never serves stale responses for them
+	DefaultLatency time.Duration
+
+	mutex sync.Mutex
+	//observed are the moving averages of the latency by host
+	observed map[string]time.Duration
+}
+
+//NewStaleOnDeadline creates a StaleOnDeadline for CachedTransport.StaleOnDeadline serving responses at most maxStale
+//past their freshness lifetime
+func NewStaleOnDeadline(maxStale time.Duration) *StaleOnDeadline {
+	return &StaleOnDeadline{MaxStale: maxStale}
+}
+
+//Latency returns the latency estimate of host
+func (s *StaleOnDeadline) Latency(host string) time.Duration {
+	if latency, ok := s.Latencies[host]; ok {
+		return latency
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if latency, ok := s.observed[host]; ok {
+		return latency
+	}
+	return s.DefaultLatency
+}
+
+//observe adds the latency of an origin request to host to the moving average
+func (s *StaleOnDeadline) observe(host string, latency time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.observed == nil {
+		s.observed = map[string]time.Duration{}
+	}
+	average, ok := s.observed[host]
+	if !ok {
+		s.observed[host] = latency
+		return
+	}
+	s.observed[host] = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(average))
+}
+
+//serveStale reports if stale should be served for req because its deadline is too short for an origin request
+func (s *StaleOnDeadline) serveStale(req *http.Request, stale *http.Response, shared bool, now time.Time) bool {
+	if s == nil {
+		return false
+	}
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return false
+	}
+	latency := s.Latency(req.URL.Host)
+	if latency <= 0 || deadline.Sub(now) >= latency {
+		return false
+	}
+	return canServeStale(stale, shared, now, s.MaxStale)
+}
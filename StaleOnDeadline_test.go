@@ -0,0 +1,109 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_StaleOnDeadline(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		cacheControl string
+		timeout      time.Duration
+		stale        bool
+	}{
+		{"short deadline", "max-age=0", 100 * time.Millisecond, true},
+		{"long deadline", "max-age=0", time.Minute, false},
+		{"no deadline", "max-age=0", 0, false},
+		{"must-revalidate", "max-age=0, must-revalidate", 100 * time.Millisecond, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+
+			var requests int32
+			fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				count := atomic.AddInt32(&requests, 1)
+				header := http.Header{}
+				header.Set("Cache-Control", tt.cacheControl)
+				return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(int(count)))), Request: req}, nil
+			})
+			staleOnDeadline := NewStaleOnDeadline(time.Hour)
+			staleOnDeadline.Latencies = map[string]time.Duration{"example.com": time.Second}
+			transport := &CachedTransport{Cache: NewMapCache(), Fallback: fallback, StaleOnDeadline: staleOnDeadline}
+			client := http.Client{Transport: transport}
+
+			response, err := client.Get("http://example.com/")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			_ = response.Body.Close()
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			request, err := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			response, err = client.Do(request)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, _ := ioutil.ReadAll(response.Body)
+
+			if stale := string(body) == "1"; stale != tt.stale {
+				t.Error("expected stale", tt.stale, "got body", string(body))
+			}
+			if tt.stale && response.Header.Get("Warning") == "" {
+				t.Error("expected a stale warning")
+			}
+			for i := 0; i < 100 && atomic.LoadInt32(&requests) < 2; i++ {
+				time.Sleep(time.Millisecond)
+			}
+			if atomic.LoadInt32(&requests) != 2 {
+				t.Error("expected the stale response to be refreshed")
+			}
+			waitForRevalidations(t, transport)
+		})
+	}
+}
+
+func TestStaleOnDeadline_Latency(t *testing.T) {
+
+	staleOnDeadline := &StaleOnDeadline{DefaultLatency: time.Second, Latencies: map[string]time.Duration{"fixed.com": time.Minute}}
+
+	staleOnDeadline.observe("example.com", 100*time.Millisecond)
+	staleOnDeadline.observe("example.com", 200*time.Millisecond)
+	staleOnDeadline.observe("fixed.com", time.Millisecond)
+
+	tests := []struct {
+		host    string
+		latency time.Duration
+	}{
+		{"example.com", 120 * time.Millisecond},
+		{"fixed.com", time.Minute},
+		{"other.com", time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if latency := staleOnDeadline.Latency(tt.host); latency != tt.latency {
+				t.Error("expected", tt.latency, "got", latency)
+			}
+		})
+	}
+}
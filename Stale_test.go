@@ -0,0 +1,219 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_StaleWhileRevalidate(t *testing.T) {
+
+	counter := 0
+	requests := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		counter++
+		writer.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprint(writer, counter)
+		select {
+		case requests <- struct{}{}:
+		default:
+		}
+	}))
+	defer server.Close()
+
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}
+	client := http.Client{Transport: transport}
+
+	get := func() *http.Response {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return response
+	}
+	body := func(response *http.Response) string {
+		bytes, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return string(bytes)
+	}
+
+	if b := body(get()); b != "1" {
+		t.Error("wrong body", b)
+	}
+	<-requests
+
+	response := get()
+	if b := body(response); b != "1" {
+		t.Error("stale response not served, got", b)
+	}
+	if response.Header.Get("Warning") != staleWarning {
+		t.Error("stale response not marked", response.Header)
+	}
+
+	select {
+	case <-requests:
+	case <-time.After(5 * time.Second):
+		t.Error("no background refresh")
+		t.FailNow()
+	}
+
+	//the refresh is stored after the origin responded
+	for i := 0; i < 100; i++ {
+		if b := body(get()); b != "1" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("refreshed response not stored")
+
+}
+
+func TestCachedTransport_RoundTrip_StaleWhileRevalidate_Deduplicated(t *testing.T) {
+
+	var requests int32
+	release := make(chan struct{})
+	refreshed := make(chan struct{})
+	transport := &CachedTransport{Cache: NewMapCache(), StatusHeaders: true,
+		Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			count := atomic.AddInt32(&requests, 1)
+			if count > 1 {
+				<-release
+				defer close(refreshed)
+			}
+			header := http.Header{}
+			header.Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(int(count)))), Request: req}, nil
+		})}
+	res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = ioutil.ReadAll(res.Body)
+
+	var wait sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = res.Body.Close()
+			if status := res.Header.Get(CacheStatusHeader); status != CacheStale {
+				t.Error("expected the stale response, got", status)
+			}
+		}()
+	}
+	wait.Wait()
+	close(release)
+	<-refreshed
+	if count := atomic.LoadInt32(&requests); count != 2 {
+		t.Error("expected a single background refresh, got", count-1)
+	}
+}
+
+func TestCachedTransport_RoundTrip_StaleIfError(t *testing.T) {
+
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		if failing {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.Header().Set("Cache-Control", "max-age=0")
+		fmt.Fprint(writer, "content")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		staleError time.Duration
+		stale      bool
+	}{
+		{"disabled", 0, false},
+		{"forced", time.Minute, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			failing = false
+			transport := &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport, StaleIfError: test.staleError}
+			client := http.Client{Transport: transport}
+
+			_, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			failing = true
+			response, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			if (response.StatusCode == http.StatusOK) != test.stale {
+				t.Error("expected stale", test.stale, "got status", response.StatusCode)
+			}
+		})
+	}
+
+}
+
+func TestCanServeStale(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-time.Minute).Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		window time.Duration
+		stale  bool
+	}{
+		{"within window", http.Header{"Date": {date}, "Cache-Control": {"max-age=30"}}, time.Minute, true},
+		{"outside window", http.Header{"Date": {date}, "Cache-Control": {"max-age=30"}}, 10 * time.Second, false},
+		{"no window", http.Header{"Date": {date}, "Cache-Control": {"max-age=30"}}, 0, false},
+		{"must-revalidate", http.Header{"Date": {date}, "Cache-Control": {"max-age=30, must-revalidate"}}, time.Minute, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := &http.Response{Header: test.header}
+			if stale := canServeStale(res, false, now, test.window); stale != test.stale {
+				t.Error("expected", test.stale, "got", stale)
+			}
+		})
+	}
+}
+
+//waitForRevalidations waits until the background revalidations of transport are done
+func waitForRevalidations(t *testing.T, transport *CachedTransport) {
+
+	r := transport.pendingRevalidations()
+	for i := 0; i < 1000; i++ {
+		r.mutex.Lock()
+		pending := len(r.pending)
+		r.mutex.Unlock()
+		if pending == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("expected the background revalidations to be done")
+}
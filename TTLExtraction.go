@@ -0,0 +1,54 @@
+package CachedHttpClient
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//TTLExtractor returns the freshness lifetime of res from its body, e.g. the TTL of the records of a DNS message.
+//ok is false if body holds none
+type TTLExtractor func(res *http.Response, body []byte) (ttl time.Duration, ok bool)
+
+//extractTTL returns cacheable with the max-age found by the TTLExtractors for its media type if the origin sent no
+//freshness information. The body of response is buffered for the extractor, cacheable is response or a copy with
+//other headers reading the same body
+func (c *CachedTransport) extractTTL(response *http.Response, cacheable *http.Response) (*http.Response, error) {
+
+	if len(c.TTLExtractors) == 0 {
+		return cacheable, nil
+	}
+	if _, explicit := policy.ExplicitFreshnessLifetime(cacheable, c.Shared); explicit {
+		return cacheable, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(cacheable.Header.Get("Content-Type"))
+	if err != nil {
+		return cacheable, nil
+	}
+	extractor, ok := c.TTLExtractors[mediaType]
+	if !ok {
+		extractor, ok = c.TTLExtractors[mediaType[:strings.IndexByte(mediaType, '/')+1]]
+	}
+	if !ok {
+		return cacheable, nil
+	}
+
+	body, err := bufferBody(response)
+	if err != nil {
+		return nil, err
+	}
+	cacheable.Body = response.Body
+	ttl, ok := extractor(cacheable, body)
+	if !ok {
+		return cacheable, nil
+	}
+
+	extracted := *cacheable
+	extracted.Header = cacheable.Header.Clone()
+	extracted.Header.Add("Cache-Control", "max-age="+strconv.FormatInt(int64(ttl/time.Second), 10))
+	return &extracted, nil
+}
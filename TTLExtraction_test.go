@@ -0,0 +1,58 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCachedTransport_RoundTrip_TTLExtractors(t *testing.T) {
+
+	message := dnsTestMessage([]uint32{60}, nil)
+	var requests int
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		header := http.Header{}
+		header.Set("Content-Type", DNSMessageMediaType)
+		header.Set("Date", time.Now().Add(-2*time.Minute).UTC().Format(http.TimeFormat))
+		if req.URL.Query().Get("fresh") != "" {
+			header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(bytes.NewReader(message)), ContentLength: -1, Request: req}, nil
+	})
+	client := http.Client{Transport: &CachedTransport{
+		Cache:         NewMapCache(),
+		Fallback:      origin,
+		TTLExtractors: map[string]TTLExtractor{DNSMessageMediaType: DNSMessageTTL},
+	}}
+
+	tests := []struct {
+		name     string
+		url      string
+		requests int
+	}{
+		{"miss", "https://dns.example/dns-query?dns=AAAB&fresh=1", 1},
+		{"hit within the TTL", "https://dns.example/dns-query?dns=AAAB&fresh=1", 1},
+		{"miss", "https://dns.example/dns-query?dns=AAAC", 2},
+		{"expired TTL", "https://dns.example/dns-query?dns=AAAC", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.Get(tt.url)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, _ := ioutil.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if !bytes.Equal(body, message) {
+				t.Error("the DNS message changed")
+			}
+			if requests != tt.requests {
+				t.Errorf("expected %d origin requests, got %d", tt.requests, requests)
+			}
+		})
+	}
+}
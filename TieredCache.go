@@ -0,0 +1,211 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//TieredCache keeps recently used responses in a fast Hot cache and moves responses not accessed for DemoteAfter to
+//a cheaper Cold cache, e.g. an LRUCache in front of a FileCache with Compression. Responses found in the Cold cache
+//are promoted back to the Hot cache. Demotion is done by Demote or periodically by DemoteEvery
+type TieredCache struct {
+	Hot  AdminCache
+	Cold Cacher
+	//DemoteAfter is the time since the last access after which Demote moves a response to the Cold cache
+	DemoteAfter time.Duration
+	//Clock tells the time of the accesses and schedules DemoteEvery, SystemClock if nil
+	Clock Clock
+
+	mutex sync.Mutex
+	//entries holds the hot entries by their key in the Hot cache
+	entries map[string]*tieredEntry
+}
+
+type tieredEntry struct {
+	//request is needed to store the response in the Cold cache
+	request    *http.Request
+	lastAccess time.Time
+}
+
+func NewTieredCache(hot AdminCache, cold Cacher, demoteAfter time.Duration) *TieredCache {
+	return &TieredCache{
+		Hot:         hot,
+		Cold:        cold,
+		DemoteAfter: demoteAfter,
+		entries:     map[string]*tieredEntry{},
+	}
+}
+
+//Key returns the key the response for req is stored under in the Hot cache
+func (t *TieredCache) Key(req *http.Request) (string, error) {
+	return t.Hot.Key(req)
+}
+
+func (t *TieredCache) Get(req *http.Request) (*http.Response, error) {
+
+	key, err := t.Key(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.Hot.Get(req)
+	if err == nil {
+		t.accessed(key, req)
+		return res, nil
+	}
+	if !errors.Is(err, NotInCacheError) {
+		return nil, err
+	}
+
+	res, err = t.Cold.Get(req)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.promote(key, req, res)
+}
+
+//Set stores res in the Hot cache
+func (t *TieredCache) Set(req *http.Request, res *http.Response) error {
+
+	key, err := t.Key(req)
+	if err != nil {
+		return err
+	}
+	err = t.Hot.Set(req, res)
+	if err != nil {
+		return err
+	}
+	t.accessed(key, req)
+	return nil
+}
+
+//promote moves res from the Cold to the Hot cache, Set replaces the body of res with one the caller can still read
+func (t *TieredCache) promote(key string, req *http.Request, res *http.Response) error {
+
+	err := t.Hot.Set(req, res)
+	if err != nil {
+		return err
+	}
+	t.accessed(key, req)
+	return deleteCold(t.Cold, req)
+}
+
+//Demote moves the responses not accessed for DemoteAfter to the Cold cache and returns their number
+func (t *TieredCache) Demote() (int, error) {
+
+	deadline := clockOrDefault(t.Clock).Now().Add(-t.DemoteAfter)
+
+	t.mutex.Lock()
+	candidates := map[string]*http.Request{}
+	for key, entry := range t.entries {
+		if entry.lastAccess.Before(deadline) {
+			candidates[key] = entry.request
+		}
+	}
+	t.mutex.Unlock()
+
+	demoted := 0
+	for key, req := range candidates {
+		res, err := t.Hot.GetKey(key)
+		if errors.Is(err, NotInCacheError) {
+			//the response was removed from the Hot cache, e.g. evicted
+			t.forget(key, deadline)
+			continue
+		}
+		if err != nil {
+			return demoted, err
+		}
+		err = t.Cold.Set(req, res)
+		if err != nil {
+			return demoted, err
+		}
+		_ = res.Body.Close()
+		if !t.forget(key, deadline) {
+			//the response was accessed while it was demoted, it stays in both caches
+			continue
+		}
+		err = t.Hot.DeleteKey(key)
+		if err != nil && !errors.Is(err, NotInCacheError) {
+			return demoted, err
+		}
+		demoted++
+	}
+	return demoted, nil
+}
+
+//DemoteEvery calls Demote every interval of the Clock until stop is called, errors are ignored
+func (t *TieredCache) DemoteEvery(interval time.Duration) (stop func()) {
+
+	ticker := clockOrDefault(t.Clock).NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C():
+				_, _ = t.Demote()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+//accessed records the access to the hot entry key
+func (t *TieredCache) accessed(key string, req *http.Request) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.entries == nil {
+		t.entries = map[string]*tieredEntry{}
+	}
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &tieredEntry{request: req.Clone(context.Background())}
+		t.entries[key] = entry
+	}
+	entry.lastAccess = clockOrDefault(t.Clock).Now()
+}
+
+//forget removes the hot entry key if it was not accessed since deadline and reports if it was removed
+func (t *TieredCache) forget(key string, deadline time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	entry, ok := t.entries[key]
+	if ok && !entry.lastAccess.Before(deadline) {
+		return false
+	}
+	delete(t.entries, key)
+	return true
+}
+
+//deleteCold removes the response for req from cache if it supports deleting entries
+func deleteCold(cache Cacher, req *http.Request) error {
+
+	inspector, ok := cache.(Inspector)
+	if !ok {
+		return nil
+	}
+	keyer, ok := cache.(Keyer)
+	if !ok {
+		return nil
+	}
+	key, err := keyer.Key(req)
+	if err != nil {
+		return err
+	}
+	err = inspector.DeleteKey(key)
+	if errors.Is(err, NotInCacheError) {
+		return nil
+	}
+	return err
+}
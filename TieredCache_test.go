@@ -0,0 +1,82 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestTieredCache(t *testing.T) {
+
+	hot := NewMapCache()
+	cold := NewLRUCache(LRUCacheOptions{})
+	cache := NewTieredCache(hot, cold, time.Hour)
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache.Clock = clock
+
+	for _, path := range []string{"/a", "/b"} {
+		if err := cache.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	demoted, err := cache.Demote()
+	if err != nil || demoted != 0 {
+		t.Error("expected no recently accessed response to be demoted, got", demoted, err)
+	}
+
+	clock.Advance(time.Hour + time.Minute)
+	demoted, err = cache.Demote()
+	if err != nil || demoted != 2 {
+		t.Error("expected both responses to be demoted, got", demoted, err)
+	}
+	if len(hot.Keys()) != 0 || cold.Len() != 2 {
+		t.Error("expected the responses to be moved to the cold cache, got", len(hot.Keys()), "hot and", cold.Len(), "cold")
+	}
+
+	response, err := cache.Get(lruTestRequest(t, "/a"))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil || string(body) != "/a" {
+		t.Error("expected the body /a got", string(body), err)
+	}
+	if _, err := hot.Get(lruTestRequest(t, "/a")); err != nil {
+		t.Error("expected the response to be promoted, got", err)
+	}
+	if cold.Len() != 1 {
+		t.Error("expected the promoted response to be removed from the cold cache, got", cold.Len(), "entries")
+	}
+
+	if _, err := cache.Get(lruTestRequest(t, "/c")); !errors.Is(err, NotInCacheError) {
+		t.Error("expected NotInCacheError got", err)
+	}
+}
+
+func TestTieredCache_DemoteEvery(t *testing.T) {
+
+	hot := NewMapCache()
+	cache := NewTieredCache(hot, NewLRUCache(LRUCacheOptions{}), time.Hour)
+	clock := NewManualClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	cache.Clock = clock
+	if err := cache.Set(lruTestRequest(t, "/a"), lruTestResponse("a")); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	stop := cache.DemoteEvery(time.Minute)
+	defer stop()
+
+	clock.Advance(time.Hour + time.Minute)
+	for i := 0; i < 1000 && len(hot.Keys()) > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if len(hot.Keys()) != 0 {
+		t.Error("expected the response to be demoted")
+	}
+	stop()
+}
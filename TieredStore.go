@@ -0,0 +1,258 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+//WritePolicy selects when TieredStore writes a response to its persistent L2 cache
+type WritePolicy int
+
+const (
+	//WriteThrough writes to L2 before Set returns, a response is durable once Set returned
+	WriteThrough WritePolicy = iota
+	//WriteBehind writes to L1 and queues the write to L2 for a background worker, responses queued but not yet
+	//written are lost on a crash
+	WriteBehind
+)
+
+//DefaultWriteBehindQueueSize is the number of queued writes of a WriteBehind TieredStore if QueueSize is 0
+const DefaultWriteBehindQueueSize = 1024
+
+//TieredStore composes a fast in-memory L1 cache, e.g. an LRUCache, in front of a slower persistent L2 cache, e.g. a
+//DiskCache. Responses missing in L1 are read from L2 and stored in L1 (read-through). Unlike TieredCache every
+//response is kept in L2, L1 only holds copies of the recently used ones
+type TieredStore struct {
+	L1 Cacher
+	L2 Cacher
+	TieredStoreOptions
+
+	//mutex guards queue and closed, Set holds it for reading while queueing a write
+	mutex  sync.RWMutex
+	queue  chan tieredWrite
+	closed bool
+	done   chan struct{}
+}
+
+type TieredStoreOptions struct {
+	Policy WritePolicy
+	//QueueSize is the number of writes a WriteBehind store queues, DefaultWriteBehindQueueSize if 0. Set writes to
+	//L2 itself while the queue is full
+	QueueSize int
+	//OnWriteError is called with the errors of the writes to L2 done by the background worker if not nil
+	OnWriteError func(req *http.Request, err error)
+}
+
+//tieredWrite is a queued write to L2, flushed is closed by the worker once all earlier writes are done
+type tieredWrite struct {
+	req     *http.Request
+	res     *http.Response
+	flushed chan struct{}
+}
+
+//NewTieredStore creates a TieredStore, a WriteBehind store starts its background worker which is stopped by Close
+func NewTieredStore(l1, l2 Cacher, options ...TieredStoreOptions) *TieredStore {
+
+	store := &TieredStore{L1: l1, L2: l2}
+	if options != nil {
+		store.TieredStoreOptions = options[0]
+	}
+	if store.Policy == WriteBehind {
+		size := store.QueueSize
+		if size <= 0 {
+			size = DefaultWriteBehindQueueSize
+		}
+		store.queue = make(chan tieredWrite, size)
+		store.done = make(chan struct{})
+		go store.work()
+	}
+	return store
+}
+
+//Key returns the key the response for req is stored under in L2, or in L1 if L2 is no Keyer
+func (t *TieredStore) Key(req *http.Request) (string, error) {
+	if keyer, ok := t.L2.(Keyer); ok {
+		return keyer.Key(req)
+	}
+	if keyer, ok := t.L1.(Keyer); ok {
+		return keyer.Key(req)
+	}
+	return MapCacheOptions{}.key(req)
+}
+
+func (t *TieredStore) Get(req *http.Request) (*http.Response, error) {
+
+	res, err := t.L1.Get(req)
+	if err == nil || !errors.Is(err, NotInCacheError) {
+		return res, err
+	}
+	res, err = t.L2.Get(req)
+	if err != nil {
+		return nil, err
+	}
+	//Set replaces the body of res with one the caller can still read
+	return res, t.L1.Set(req, res)
+}
+
+//Set stores res in both caches according to Policy
+func (t *TieredStore) Set(req *http.Request, res *http.Response) error {
+
+	if t.Policy != WriteBehind {
+		err := t.L2.Set(req, res)
+		if err != nil {
+			return err
+		}
+		return t.L1.Set(req, res)
+	}
+
+	copied, err := CopyResponse(res)
+	if err != nil {
+		return err
+	}
+	copied.Header = res.Header.Clone()
+	err = t.L1.Set(req, res)
+	if err != nil {
+		return err
+	}
+
+	write := tieredWrite{req: req.Clone(context.Background()), res: copied}
+	t.mutex.RLock()
+	if !t.closed {
+		select {
+		case t.queue <- write:
+			t.mutex.RUnlock()
+			return nil
+		default:
+		}
+	}
+	t.mutex.RUnlock()
+	//the queue is full or closed
+	return t.L2.Set(write.req, write.res)
+}
+
+//work writes the queued responses to L2 until the queue is closed
+func (t *TieredStore) work() {
+
+	defer close(t.done)
+	for write := range t.queue {
+		if write.flushed != nil {
+			close(write.flushed)
+			continue
+		}
+		err := t.L2.Set(write.req, write.res)
+		if err != nil && t.OnWriteError != nil {
+			t.OnWriteError(write.req, err)
+		}
+	}
+}
+
+//Flush waits until the writes queued before it are written to L2 or ctx is done
+func (t *TieredStore) Flush(ctx context.Context) error {
+
+	t.mutex.RLock()
+	if t.queue == nil || t.closed {
+		t.mutex.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	select {
+	case t.queue <- tieredWrite{flushed: flushed}:
+	case <-ctx.Done():
+		t.mutex.RUnlock()
+		return ctx.Err()
+	}
+	t.mutex.RUnlock()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//Close writes the queued responses to L2 and stops the background worker, later writes are done by Set itself
+func (t *TieredStore) Close() error {
+
+	t.mutex.Lock()
+	if t.queue == nil || t.closed {
+		t.mutex.Unlock()
+		return nil
+	}
+	t.closed = true
+	close(t.queue)
+	t.mutex.Unlock()
+
+	<-t.done
+	return nil
+}
+
+//Capabilities returns the capabilities both caches have
+func (t *TieredStore) Capabilities() CapabilitySet {
+	return commonCapabilities(t.L1, t.L2) &^ ByteAccountingCapability
+}
+
+//Keys returns the sorted keys of the caches which are an Inspector
+func (t *TieredStore) Keys() []string {
+
+	unique := map[string]bool{}
+	for _, cache := range []Cacher{t.L1, t.L2} {
+		if inspector, ok := cache.(Inspector); ok {
+			for _, key := range inspector.Keys() {
+				unique[key] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(unique))
+	for key := range unique {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//GetKey returns the response stored under key in L1 or else in L2
+func (t *TieredStore) GetKey(key string) (*http.Response, error) {
+
+	for _, cache := range []Cacher{t.L1, t.L2} {
+		inspector, ok := cache.(Inspector)
+		if !ok {
+			continue
+		}
+		res, err := inspector.GetKey(key)
+		if !errors.Is(err, NotInCacheError) {
+			return res, err
+		}
+	}
+	return nil, NotInCacheError
+}
+
+//DeleteKey removes the response stored under key from both caches, queued writes are flushed first so they do not
+//store the response again
+func (t *TieredStore) DeleteKey(key string) error {
+
+	err := t.Flush(context.Background())
+	if err != nil {
+		return err
+	}
+	deleted := false
+	for _, cache := range []Cacher{t.L1, t.L2} {
+		inspector, ok := cache.(Inspector)
+		if !ok {
+			continue
+		}
+		err := inspector.DeleteKey(key)
+		if err == nil {
+			deleted = true
+		} else if !errors.Is(err, NotInCacheError) {
+			return err
+		}
+	}
+	if !deleted {
+		return NotInCacheError
+	}
+	return nil
+}
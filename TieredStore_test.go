@@ -0,0 +1,108 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestTieredStore(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		policy WritePolicy
+	}{
+		{"WriteThrough", WriteThrough},
+		{"WriteBehind", WriteBehind},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			dir := "tmp/tiered-store-" + tt.name
+			if err := os.RemoveAll(dir); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			l2, err := NewDiskCache(dir)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			l1 := NewLRUCache(LRUCacheOptions{})
+			store := NewTieredStore(l1, l2, TieredStoreOptions{Policy: tt.policy})
+			defer store.Close()
+
+			for _, path := range []string{"/a", "/b"} {
+				if err := store.Set(lruTestRequest(t, path), lruTestResponse(path)); err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+			}
+			if err := store.Flush(context.Background()); err != nil {
+				t.Error(err)
+			}
+			if l1.Len() != 2 || len(l2.Keys()) != 2 {
+				t.Error("expected the responses in both tiers got", l1.Len(), "and", len(l2.Keys()))
+			}
+
+			//a new store starts with an empty L1 and reads through to L2
+			l1 = NewLRUCache(LRUCacheOptions{})
+			reopened := NewTieredStore(l1, l2, TieredStoreOptions{Policy: tt.policy})
+			defer reopened.Close()
+			response, err := reopened.Get(lruTestRequest(t, "/a"))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if body, _ := ioutil.ReadAll(response.Body); string(body) != "/a" {
+				t.Error("expected the body read from L2 got", string(body))
+			}
+			if l1.Len() != 1 {
+				t.Error("expected the response read from L2 to populate L1 got", l1.Len())
+			}
+
+			key, _ := reopened.Key(lruTestRequest(t, "/b"))
+			if err := reopened.DeleteKey(key); err != nil {
+				t.Error(err)
+			}
+			if _, err := reopened.Get(lruTestRequest(t, "/b")); !errors.Is(err, NotInCacheError) {
+				t.Error("expected NotInCacheError got", err)
+			}
+			if keys := reopened.Keys(); len(keys) != 1 {
+				t.Error("expected one key got", keys)
+			}
+		})
+	}
+}
+
+func TestTieredStore_WriteBehindErrors(t *testing.T) {
+
+	failed := make(chan *http.Request, 1)
+	store := NewTieredStore(NewMapCache(), failingCache{}, TieredStoreOptions{
+		Policy:       WriteBehind,
+		OnWriteError: func(req *http.Request, err error) { failed <- req },
+	})
+
+	if err := store.Set(lruTestRequest(t, "/a"), lruTestResponse("/a")); err != nil {
+		t.Error("expected the write to L2 to be deferred got", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Error(err)
+	}
+	select {
+	case req := <-failed:
+		if req.URL.Path != "/a" {
+			t.Error("expected the failed request got", req.URL)
+		}
+	default:
+		t.Error("expected OnWriteError to be called")
+	}
+
+	if err := store.Set(lruTestRequest(t, "/b"), lruTestResponse("/b")); err == nil {
+		t.Error("expected writes after Close to be done by Set")
+	}
+}
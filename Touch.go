@@ -0,0 +1,84 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+//Touch extends the freshness of the entry stored for a GET request of rawURL by extendBy without contacting the
+//origin, e.g. when the origin announced that the resource did not change. A stale entry becomes fresh for extendBy
+//from now. Like after a revalidation the clock of the entry restarts now, its Cache-Control gets a max-age with the
+//extended remaining lifetime replacing max-age, s-maxage and Expires. URLRewrites and HostAliases are applied to
+//rawURL like to requests. Entries with no-cache are left unchanged. It fails with
+//NotInCacheError if there is no entry
+func (c *CachedTransport) Touch(rawURL string, extendBy time.Duration) error {
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	keyReq := c.keyRequest(rewriteRequest(req, c.URLRewrites))
+	if c.SoftDelete.hides(c.Cache, keyReq) {
+		return NotInCacheError
+	}
+	res, err := c.Cache.Get(keyReq)
+	if err != nil {
+		return err
+	}
+
+	touched, ok := touchedResponse(res, c.Shared, extendBy, c.now())
+	if !ok {
+		if res.Body != nil {
+			_ = res.Body.Close()
+		}
+		return nil
+	}
+	return c.Cache.Set(keyReq, touched)
+}
+
+//touchedResponse returns a copy of the stored response res which is fresh for extendBy longer at now, ok is false if
+//the freshness of res can not be extended
+func touchedResponse(res *http.Response, shared bool, extendBy time.Duration, now time.Time) (touched *http.Response, ok bool) {
+
+	cc := policy.ParseCacheControl(res.Header)
+	if cc.Has("no-cache") && len(cc.Fields("no-cache")) == 0 {
+		return res, false
+	}
+	remaining := policy.FreshnessLifetime(res, shared) - policy.CurrentAge(res, now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	remaining += extendBy
+
+	extended := *res
+	extended.Header = res.Header.Clone()
+	//max-age is rounded up, the entry is never fresh shorter than requested
+	setMaxAge(extended.Header, int64((remaining+time.Second-1)/time.Second))
+	extended.Header.Del("Age")
+	extended.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	return withClock(&extended, now, now), true
+}
+
+//setMaxAge replaces max-age, s-maxage and Expires of header by max-age=seconds, the other directives are kept
+func setMaxAge(header http.Header, seconds int64) {
+	var directives []string
+	for _, line := range header["Cache-Control"] {
+		for _, directive := range strings.Split(line, ",") {
+			directive = strings.TrimSpace(directive)
+			name := strings.ToLower(directive)
+			if equals := strings.IndexByte(name, '='); equals >= 0 {
+				name = strings.TrimSpace(name[:equals])
+			}
+			if directive != "" && name != "max-age" && name != "s-maxage" {
+				directives = append(directives, directive)
+			}
+		}
+	}
+	directives = append(directives, "max-age="+strconv.FormatInt(seconds, 10))
+	header.Set("Cache-Control", strings.Join(directives, ", "))
+	header.Del("Expires")
+}
@@ -0,0 +1,86 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Scax/CachedHttpClient-Go/policy"
+)
+
+func TestCachedTransport_Touch(t *testing.T) {
+
+	counter := 0
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		counter++
+		header := http.Header{}
+		header.Set("Cache-Control", req.URL.Query().Get("cc"))
+		header.Set("Date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(strconv.Itoa(counter))), Request: req}, nil
+	})
+	cache := NewMapCache()
+	transport := &CachedTransport{Cache: cache, Fallback: fallback, HostAliases: map[string]string{"www.example.com": "example.com"}}
+	client := http.Client{Transport: transport}
+
+	get := func(url string) string {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		return string(body)
+	}
+
+	tests := []struct {
+		name     string
+		url      string
+		touchURL string
+		extendBy time.Duration
+		fresh    bool
+		maxAge   int64
+	}{
+		{"stale", "http://example.com/?cc=max-age%3D30", "http://example.com/?cc=max-age%3D30", time.Hour, true, 3600},
+		{"fresh", "http://example.com/?cc=public,+max-age%3D120", "http://example.com/?cc=public,+max-age%3D120", time.Hour, true, 3660},
+		{"shared max age", "http://example.com/?cc=s-maxage%3D30,+max-age%3D600", "http://www.example.com/?cc=s-maxage%3D30,+max-age%3D600", time.Minute, true, 600},
+		{"no-cache", "http://example.com/?cc=no-cache,+max-age%3D600", "http://example.com/?cc=no-cache,+max-age%3D600", time.Hour, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			first := get(tt.url)
+			if err := transport.Touch(tt.touchURL, tt.extendBy); err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if again := get(tt.url); (again == first) != tt.fresh {
+				t.Error("expected a cached response", tt.fresh, "got", first, again)
+			}
+			if !tt.fresh {
+				return
+			}
+
+			stored, err := cache.Get(lruTestRequest(t, strings.TrimPrefix(tt.url, "http://example.com")))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			maxAge, ok := policy.ParseCacheControl(stored.Header).Seconds("max-age")
+			if !ok || int64(maxAge/time.Second) < tt.maxAge-2 || int64(maxAge/time.Second) > tt.maxAge {
+				t.Error("expected max-age about", tt.maxAge, "got", stored.Header.Get("Cache-Control"))
+			}
+			if strings.Contains(stored.Header.Get("Cache-Control"), "s-maxage") {
+				t.Error("expected s-maxage to be replaced, got", stored.Header.Get("Cache-Control"))
+			}
+		})
+	}
+
+	if err := transport.Touch("http://example.com/missing", time.Hour); !errors.Is(err, NotInCacheError) {
+		t.Error("expected NotInCacheError, got", err)
+	}
+}
@@ -0,0 +1,131 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+//Span attributes set by CachedTransport
+const (
+	//ResultAttribute is set on the RoundTrip span to hit, stale, miss or bypass
+	ResultAttribute = "cache.result"
+	//KeyAttribute is set on the RoundTrip span to the SHA-256 hash of the cache key, keys contain request headers
+	//which may be secret
+	KeyAttribute = "cache.key"
+	//HitAttribute is set on the lookup span, true if a response was stored, fresh or not
+	HitAttribute = "cache.hit"
+	//RevalidationAttribute is set on the origin span, true for conditional requests
+	RevalidationAttribute = "cache.revalidation"
+	//StatusCodeAttribute is set on the origin span to the status code of the response
+	StatusCodeAttribute = "http.status_code"
+)
+
+//Span names used by CachedTransport
+const (
+	RoundTripSpan = "CachedHttpClient.RoundTrip"
+	LookupSpan    = "CachedHttpClient.Lookup"
+	OriginSpan    = "CachedHttpClient.Origin"
+)
+
+//Tracer starts spans, the oteltracing module adapts an OpenTelemetry trace.Tracer
+type Tracer interface {
+	//Start returns a context containing the new span which is a child of the span in ctx if there is one
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+//Span is a traced operation started by a Tracer
+type Span interface {
+	//SetAttribute sets a string, bool or int attribute
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+//startSpan starts a span with the Tracer, the span does nothing without one
+func (c *CachedTransport) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if c.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.Tracer.Start(ctx, name)
+}
+
+//setKeyAttribute sets the hash of the key of req on span if the cache implements Keyer
+func (c *CachedTransport) setKeyAttribute(span Span, req *http.Request) {
+	if c.Tracer == nil {
+		return
+	}
+	keyer, ok := c.Cache.(Keyer)
+	if !ok {
+		return
+	}
+	key, err := keyer.Key(req)
+	if err != nil {
+		return
+	}
+	hash := sha256.Sum256([]byte(key))
+	span.SetAttribute(KeyAttribute, hex.EncodeToString(hash[:]))
+}
+
+//lookup gets the response for keyReq from the Cache in a lookup span, the Cache gets the span with the context of
+//keyReq
+func (c *CachedTransport) lookup(keyReq *http.Request) (*http.Response, error) {
+
+	ctx, span := c.startSpan(keyReq.Context(), LookupSpan)
+	defer span.End()
+	if c.Tracer != nil {
+		keyReq = keyReq.WithContext(ctx)
+	}
+	res, err := c.Cache.Get(keyReq)
+	span.SetAttribute(HitAttribute, err == nil)
+	if err != nil && !errors.Is(err, NotInCacheError) {
+		span.RecordError(err)
+	}
+	return res, err
+}
+
+//roundTripOrigin sends req to the Fallback in an origin span, tracing its connection for the Metrics and its latency
+//for StaleOnDeadline
+func (c *CachedTransport) roundTripOrigin(req *http.Request, conditional bool) (*http.Response, error) {
+
+	ctx, span := c.startSpan(req.Context(), OriginSpan)
+	defer span.End()
+	span.SetAttribute(RevalidationAttribute, conditional)
+
+	req, done := c.Metrics.traceConnections(req.WithContext(ctx))
+	defer done()
+	if err := c.RateLimiter.wait(req); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	connected, err := c.ConnectionBackoff.allow(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	record, err := c.CircuitBreaker.allow(req.URL.Host)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	start := time.Now()
+	response, err := c.Fallback.RoundTrip(req)
+	connected(err)
+	record(response, err)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute(StatusCodeAttribute, response.StatusCode)
+	c.StaleOnDeadline.observe(req.URL.Host, time.Since(start))
+	return response, nil
+}
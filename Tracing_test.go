@@ -0,0 +1,127 @@
+package CachedHttpClient
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type spanContextKey struct{}
+
+//recordingTracer records the started spans
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(spanContextKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attributes: map[string]interface{}{}}
+	t.mutex.Lock()
+	t.spans = append(t.spans, span)
+	t.mutex.Unlock()
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)                      { s.err = err }
+func (s *recordedSpan) End()                                       { s.ended = true }
+
+func TestCachedTransport_RoundTrip_Tracer(t *testing.T) {
+
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if _, ok := req.Context().Value(spanContextKey{}).(*recordedSpan); !ok {
+			t.Error("expected the origin request to carry the span")
+		}
+		if req.URL.Path == "/error" {
+			return nil, errors.New("origin failed")
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello")), Request: req}, nil
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		context func(ctx context.Context) context.Context
+		result  string
+		origin  bool
+		err     bool
+	}{
+		{"miss", "/", nil, "miss", true, false},
+		{"hit", "/", nil, "hit", false, false},
+		{"bypass", "/", WithNoCache, "bypass", true, false},
+		{"origin error", "/error", nil, "miss", true, true},
+	}
+
+	tracer := &recordingTracer{}
+	client := http.Client{Transport: &CachedTransport{Cache: NewMapCache(), Fallback: fallback, Tracer: tracer}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer.spans = nil
+
+			request, err := http.NewRequest("GET", "http://example.com"+tt.path, nil)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if tt.context != nil {
+				request = request.WithContext(tt.context(request.Context()))
+			}
+			response, err := client.Do(request)
+			if err == nil {
+				_ = response.Body.Close()
+			}
+
+			spans := 1
+			if tt.result != "bypass" {
+				spans++
+			}
+			if tt.origin {
+				spans++
+			}
+			if len(tracer.spans) != spans {
+				t.Fatal("expected", spans, "spans got", len(tracer.spans))
+			}
+			roundTrip := tracer.spans[0]
+			if roundTrip.name != RoundTripSpan || !roundTrip.ended || roundTrip.attributes[ResultAttribute] != tt.result {
+				t.Errorf("unexpected round trip span %+v", roundTrip)
+			}
+			if tt.result != "bypass" && len(roundTrip.attributes[KeyAttribute].(string)) != 64 {
+				t.Error("expected the hashed key, got", roundTrip.attributes[KeyAttribute])
+			}
+			if (roundTrip.err != nil) != tt.err {
+				t.Error("expected error", tt.err, "got", roundTrip.err)
+			}
+			if tt.result != "bypass" {
+				lookup := tracer.spans[1]
+				if lookup.name != LookupSpan || lookup.parent != roundTrip || !lookup.ended || lookup.attributes[HitAttribute] != (tt.result == "hit") {
+					t.Errorf("unexpected lookup span %+v", lookup)
+				}
+			}
+			if tt.origin {
+				origin := tracer.spans[spans-1]
+				if origin.name != OriginSpan || origin.parent != roundTrip || !origin.ended || origin.attributes[RevalidationAttribute] != false {
+					t.Errorf("unexpected origin span %+v", origin)
+				}
+				if !tt.err && origin.attributes[StatusCodeAttribute] != http.StatusOK {
+					t.Error("expected the status code on the origin span, got", origin.attributes[StatusCodeAttribute])
+				}
+			}
+		})
+	}
+}
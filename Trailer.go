@@ -0,0 +1,92 @@
+package CachedHttpClient
+
+import (
+	"io"
+	"net/http"
+)
+
+//deferredTrailer fills the Trailer of a served response once its body was read to the end, like net/http does for
+//live responses. Until then the Trailer only holds the declared keys with nil values
+type deferredTrailer struct {
+	//trailer is the Trailer map of the served response
+	trailer http.Header
+	//values are the stored trailer values, they are shared read-only
+	values http.Header
+	filled bool
+}
+
+func (t *deferredTrailer) fill() {
+	if t == nil || t.filled {
+		return
+	}
+	t.filled = true
+	for key, values := range t.values {
+		t.trailer[key] = cloneStrings(values)
+	}
+}
+
+//copyTrailer gives res, the copy of a response with the deferred trailer t, its own Trailer filled once the body of
+//res was read to the end. The values of t are used even if t was filled already as the body of res is unread
+func copyTrailer(res *http.Response, t *deferredTrailer) *deferredTrailer {
+	if t == nil {
+		return nil
+	}
+	res.Trailer = declaredTrailer(t.values)
+	return &deferredTrailer{trailer: res.Trailer, values: t.values}
+}
+
+//declaredTrailer returns the keys of trailer with nil values
+func declaredTrailer(trailer http.Header) http.Header {
+	declared := make(http.Header, len(trailer))
+	for key := range trailer {
+		declared[key] = nil
+	}
+	return declared
+}
+
+//hasTrailerValues reports if a key of trailer has a value
+func hasTrailerValues(trailer http.Header) bool {
+	for _, values := range trailer {
+		if len(values) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//deferTrailer replaces the Trailer of the cached response res, which is filled already, by the declared keys and
+//fills their values once the body of res was read to the end. Clients relying on the net/http semantics of trailers
+//like gRPC-web see them as on a live response. The bodies of the caches keep being SeekableBody
+func deferTrailer(res *http.Response) *http.Response {
+	if res == nil || !hasTrailerValues(res.Trailer) || res.Body == nil || res.Body == http.NoBody {
+		return res
+	}
+	values := res.Trailer
+	res.Trailer = declaredTrailer(values)
+	trailer := &deferredTrailer{trailer: res.Trailer, values: values}
+
+	//the bodies of cached responses belong to res alone
+	switch body := res.Body.(type) {
+	case *bytesBody:
+		body.trailer = trailer
+	case *fileBody:
+		body.trailer = trailer
+	default:
+		res.Body = &trailerBody{ReadCloser: body, trailer: trailer}
+	}
+	return res
+}
+
+//trailerBody fills the trailer of its response once body was read to the end
+type trailerBody struct {
+	io.ReadCloser
+	trailer *deferredTrailer
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.trailer.fill()
+	}
+	return n, err
+}
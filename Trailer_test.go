@@ -0,0 +1,127 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_Trailer(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Cache-Control", "max-age=60")
+		writer.Header().Set("Trailer", "Grpc-Status")
+		writer.Header().Set("Content-Type", "application/grpc-web")
+		_, _ = writer.Write([]byte("message"))
+		writer.(http.Flusher).Flush()
+		writer.Header().Set("Grpc-Status", "0")
+		//not declared by the Trailer header
+		writer.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+	}))
+	defer server.Close()
+
+	if err := os.RemoveAll("tmp/trailer"); err != nil {
+		t.Fatal(err)
+	}
+	diskCache, err := NewDiskCache("tmp/trailer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caches := []struct {
+		name  string
+		cache Cacher
+	}{
+		{"MapCache", NewMapCache()},
+		{"LRUCache", NewLRUCache(LRUCacheOptions{MaxEntries: 10})},
+		{"DiskCache", diskCache},
+	}
+	for _, c := range caches {
+		t.Run(c.name, func(t *testing.T) {
+			transport := &CachedTransport{Cache: c.cache, Fallback: http.DefaultTransport, StatusHeaders: true}
+			//ReadAll reads the body with Read, io.Copy with WriteTo
+			reads := []func(io.Reader) ([]byte, error){
+				ioutil.ReadAll,
+				func(body io.Reader) ([]byte, error) {
+					var buf bytes.Buffer
+					_, err := io.Copy(&buf, body)
+					return buf.Bytes(), err
+				},
+			}
+			for i, status := range []string{"MISS", "HIT", "HIT"} {
+				req, _ := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+				res, err := transport.RoundTrip(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Header.Get(CacheStatusHeader) != status {
+					t.Error("expected", status, "got", res.Header.Get(CacheStatusHeader))
+				}
+				if res.Trailer.Get("Grpc-Status") != "" || res.Trailer.Get("Grpc-Message") != "" {
+					t.Error(status, "expected the trailers to be unset before the body was read, got", res.Trailer)
+				}
+				body, err := reads[i%2](res.Body)
+				if err != nil || string(body) != "message" {
+					t.Error("unexpected body", string(body), err)
+				}
+				_ = res.Body.Close()
+				if res.Trailer.Get("Grpc-Status") != "0" || res.Trailer.Get("Grpc-Message") != "ok" {
+					t.Error(status, "expected the trailers after the body was read, got", res.Trailer)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyResponse_Trailer(t *testing.T) {
+
+	res := deferTrailer(&http.Response{Trailer: http.Header{"Checksum": {"abc"}}, Body: newBytesBody([]byte("body"))})
+	copied, err := CopyResponse(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = ioutil.ReadAll(res.Body)
+	if res.Trailer.Get("Checksum") != "abc" {
+		t.Error("expected the trailer of the read response, got", res.Trailer)
+	}
+	if _, ok := copied.Trailer["Checksum"]; !ok || copied.Trailer.Get("Checksum") != "" {
+		t.Error("expected the copy to declare the trailer without its value, got", copied.Trailer)
+	}
+	_, _ = ioutil.ReadAll(copied.Body)
+	if copied.Trailer.Get("Checksum") != "abc" {
+		t.Error("expected the trailer of the copy once it was read, got", copied.Trailer)
+	}
+}
+
+func TestCachedTransport_RoundTrip_NoTrailer(t *testing.T) {
+
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=60")
+		return res, nil
+	})}
+	res, err := transport.RoundTrip(lruTestRequest(t, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Trailer != nil {
+		t.Error("expected the response of the origin without Trailer, got", res.Trailer)
+	}
+
+	for _, trailer := range []http.Header{nil, {}} {
+		jsonResponse, err := NewJsonResponse(&http.Response{Trailer: trailer, Body: newBytesBody([]byte("body"))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := jsonResponse.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (parsed.Trailer == nil) != (trailer == nil) || len(parsed.Trailer) != 0 {
+			t.Errorf("expected the Trailer %#v, got %#v", trailer, parsed.Trailer)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+)
+
+//Transform rewrites the response res for req, e.g. to redact a body or to remove header fields. It may modify res
+//and return it
+type Transform func(req *http.Request, res *http.Response) (*http.Response, error)
+
+//StripHeaders returns a Transform removing the header fields names, e.g. StripHeaders("Set-Cookie")
+func StripHeaders(names ...string) Transform {
+	return func(req *http.Request, res *http.Response) (*http.Response, error) {
+		for _, name := range names {
+			res.Header.Del(name)
+		}
+		return res, nil
+	}
+}
+
+//ChainTransforms returns a Transform applying transforms in order, stopping at the first error
+func ChainTransforms(transforms ...Transform) Transform {
+	return func(req *http.Request, res *http.Response) (*http.Response, error) {
+		var err error
+		for _, transform := range transforms {
+			if res, err = transform(req, res); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+}
+
+//storeTransform returns the response stored for stored, transformed by StoreTransform if it is set. The body of
+//stored stays readable for the caller
+func (c *CachedTransport) storeTransform(req *http.Request, stored *http.Response) (*http.Response, error) {
+
+	if c.StoreTransform == nil {
+		return stored, nil
+	}
+	copied, err := CopyResponse(stored)
+	if err != nil {
+		return nil, err
+	}
+	copied.Header = stored.Header.Clone()
+	return c.StoreTransform(req, copied)
+}
@@ -0,0 +1,95 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_Transform(t *testing.T) {
+
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		header.Set("Set-Cookie", "session=secret")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(`{"token":"secret"}`)), Request: req}, nil
+	})
+	redact := func(req *http.Request, res *http.Response) (*http.Response, error) {
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		res.Body = ioutil.NopCloser(strings.NewReader(strings.Replace(string(body), "secret", "redacted", -1)))
+		return res, nil
+	}
+	cache := NewMapCache()
+	var served int
+	transport := &CachedTransport{
+		Cache:          cache,
+		Fallback:       origin,
+		StoreTransform: ChainTransforms(StripHeaders("Set-Cookie"), redact),
+		ServeTransform: func(req *http.Request, res *http.Response) (*http.Response, error) {
+			served++
+			res.Header.Set("X-Served", "cache")
+			return res, nil
+		},
+	}
+	client := http.Client{Transport: transport}
+
+	tests := []struct {
+		name, body, cookie, served string
+	}{
+		{name: "miss", body: `{"token":"secret"}`, cookie: "session=secret"},
+		{name: "hit", body: `{"token":"redacted"}`, served: "cache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.Get("http://example.com/a")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			body, _ := ioutil.ReadAll(response.Body)
+			_ = response.Body.Close()
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+			if got := response.Header.Get("Set-Cookie"); got != tt.cookie {
+				t.Errorf("Set-Cookie = %q, want %q", got, tt.cookie)
+			}
+			if got := response.Header.Get("X-Served"); got != tt.served {
+				t.Errorf("X-Served = %q, want %q", got, tt.served)
+			}
+		})
+	}
+	if served != 1 {
+		t.Errorf("ServeTransform called %d times, want 1", served)
+	}
+}
+
+func TestCachedTransport_RoundTrip_StoreTransformError(t *testing.T) {
+
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello")), Request: req}, nil
+	})
+	transformError := errors.New("transform failed")
+	cache := NewMapCache()
+	client := http.Client{Transport: &CachedTransport{
+		Cache:    cache,
+		Fallback: origin,
+		StoreTransform: func(req *http.Request, res *http.Response) (*http.Response, error) {
+			return nil, transformError
+		},
+	}}
+
+	if _, err := client.Get("http://example.com/a"); !errors.Is(err, transformError) {
+		t.Errorf("expected %v, got %v", transformError, err)
+	}
+	if keys := cache.Keys(); len(keys) != 0 {
+		t.Errorf("expected nothing to be stored, got %d entries", len(keys))
+	}
+}
@@ -0,0 +1,124 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strings"
+)
+
+//rewriteRequest returns a copy of req pointing to the host rewrites maps req.URL.Host to, req itself is returned if
+//no rewrite applies
+func rewriteRequest(req *http.Request, rewrites map[string]string) *http.Request {
+
+	newHost, ok := rewrites[req.URL.Host]
+	if !ok {
+		return req
+	}
+
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.Host = newHost
+	if rewritten.Host == "" || rewritten.Host == req.URL.Host {
+		rewritten.Host = newHost
+	}
+
+	return rewritten
+}
+
+//MigrateHosts moves all entries stored for a host in rewrites to the key of the host it is mapped to,
+//so a cache filled before an origin migration keeps serving with CachedTransport.URLRewrites set
+func (m *MapCache) MigrateHosts(rewrites map[string]string) error {
+	m.migrateHosts(rewrites)
+	return nil
+}
+
+//migrateHosts rekeys the entries and returns the moved responses by their new key
+func (m *MapCache) migrateHosts(rewrites map[string]string) map[string]*http.Response {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	moved := map[string]*http.Response{}
+
+	for key, res := range m.cache {
+		newKey, ok := rewriteDumpHost(key, rewrites)
+		if !ok {
+			continue
+		}
+		delete(m.cache, key)
+		m.cache[newKey] = res
+		moved[newKey] = res
+	}
+
+	return moved
+}
+
+//rewriteDumpHost replaces the Host header of a request dump created by DumpRequest, the request line of a dump
+//holds only the path so the Host header is the only place the host appears in
+func rewriteDumpHost(dump string, rewrites map[string]string) (string, bool) {
+
+	const hostPrefix = "\r\nHost: "
+
+	start := strings.Index(dump, hostPrefix)
+	if start < 0 {
+		return dump, false
+	}
+	start += len(hostPrefix)
+	end := strings.Index(dump[start:], "\r\n")
+	if end < 0 {
+		return dump, false
+	}
+	end += start
+
+	newHost, ok := rewrites[dump[start:end]]
+	if !ok {
+		return dump, false
+	}
+
+	return dump[:start] + newHost + dump[end:], true
+}
+
+//MigrateHosts moves the entries like MapCache.MigrateHosts and appends the moved entries to the cache file
+func (f *FileCache) MigrateHosts(rewrites map[string]string) error {
+
+	moved := f.MapCache.migrateHosts(rewrites)
+
+	f.MapCache.mutex.Lock()
+	defer f.MapCache.mutex.Unlock()
+
+	for key, res := range moved {
+		stored, err := CopyResponse(res)
+		if err != nil {
+			return err
+		}
+		err = f.write(key, stored)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//MigrateCache applies URLRewrites to the entries already in Cache, see MapCache.MigrateHosts
+func (c *CachedTransport) MigrateCache() error {
+
+	migrator, ok := c.Cache.(interface {
+		MigrateHosts(rewrites map[string]string) error
+	})
+	if !ok {
+		return MigrationNotSupportedError
+	}
+
+	return migrator.MigrateHosts(c.URLRewrites)
+}
+
+//AliasHosts declares the aliases as equivalent to the canonical host, responses fetched from any of them are stored
+//and found under the canonical host
+func (c *CachedTransport) AliasHosts(canonical string, aliases ...string) {
+
+	if c.HostAliases == nil {
+		c.HostAliases = map[string]string{}
+	}
+	for _, alias := range aliases {
+		c.HostAliases[alias] = canonical
+	}
+}
@@ -0,0 +1,140 @@
+package CachedHttpClient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCachedTransport_MigrateCache(t *testing.T) {
+
+	oldRequests, newRequests := 0, 0
+	oldServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		oldRequests++
+		fmt.Fprint(writer, "old ", r.URL.Path)
+	}))
+	defer oldServer.Close()
+	newServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		newRequests++
+		fmt.Fprint(writer, "new ", r.URL.Path)
+	}))
+	defer newServer.Close()
+
+	oldURL, err := url.Parse(oldServer.URL)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	newURL, err := url.Parse(newServer.URL)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}
+	client := http.Client{Transport: transport}
+
+	get := func(url string) string {
+		response, err := client.Get(url)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return string(body)
+	}
+
+	get(oldServer.URL + "/cached")
+
+	transport.URLRewrites = map[string]string{oldURL.Host: newURL.Host}
+	err = transport.MigrateCache()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	if body := get(oldServer.URL + "/cached"); body != "old /cached" {
+		t.Error("migrated entry not served, got", body)
+	}
+	if body := get(oldServer.URL + "/uncached"); body != "new /uncached" {
+		t.Error("request not rewritten, got", body)
+	}
+	if oldRequests != 1 || newRequests != 1 {
+		t.Error("expected one request per server, got", oldRequests, newRequests)
+	}
+
+}
+
+func TestRewriteDumpHost(t *testing.T) {
+
+	dump := "GET /path HTTP/1.1\r\nHost: old.example.com\r\nUser-Agent: Go-http-client/1.1\r\n\r\n"
+
+	rewritten, ok := rewriteDumpHost(dump, map[string]string{"old.example.com": "new.example.com"})
+	if !ok {
+		t.Error("dump not rewritten")
+		t.FailNow()
+	}
+	if rewritten != "GET /path HTTP/1.1\r\nHost: new.example.com\r\nUser-Agent: Go-http-client/1.1\r\n\r\n" {
+		t.Error("wrong dump", rewritten)
+	}
+
+	if _, ok := rewriteDumpHost(dump, map[string]string{"other.example.com": "new.example.com"}); ok {
+		t.Error("unmapped host rewritten")
+	}
+
+}
+
+func TestCachedTransport_AliasHosts(t *testing.T) {
+
+	requests := map[string]int{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+			requests[name]++
+			fmt.Fprint(writer, name, " ", r.URL.Path)
+		})
+	}
+	mirrorA := httptest.NewServer(handler("a"))
+	defer mirrorA.Close()
+	mirrorB := httptest.NewServer(handler("b"))
+	defer mirrorB.Close()
+
+	transport := &CachedTransport{Cache: NewMapCache(), Fallback: http.DefaultTransport}
+	transport.AliasHosts(mirrorA.Listener.Addr().String(), mirrorB.Listener.Addr().String())
+	client := http.Client{Transport: transport}
+
+	get := func(url string) string {
+		response, err := client.Get(url)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return string(body)
+	}
+
+	get(mirrorA.URL + "/shared")
+	if body := get(mirrorB.URL + "/shared"); body != "a /shared" {
+		t.Error("alias not served from the canonical entry, got", body)
+	}
+	if body := get(mirrorB.URL + "/other"); body != "b /other" {
+		t.Error("alias not fetched from its own host, got", body)
+	}
+	if body := get(mirrorA.URL + "/other"); body != "b /other" {
+		t.Error("response of the alias not stored under the canonical host, got", body)
+	}
+	if requests["a"] != 1 || requests["b"] != 1 {
+		t.Error("expected one request per mirror, got", requests)
+	}
+
+}
@@ -0,0 +1,290 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//VariantTracker counts the distinct variants stored per URL (host and path). Many variants of one URL usually mean
+//a misconfigured Vary header or a KeyFunc including a high cardinality header. The variants of a key are forgotten
+//when it is deleted by the transport, an AdminHandler, AdminService or Janitor with the tracker, evictions of the
+//cache are reported with Evicted. Variants of caches without Keyer are never forgotten
+type VariantTracker struct {
+	//Threshold is the number of variants of a URL above which OnThresholdExceeded is called
+	Threshold int
+	//OnThresholdExceeded is called once per URL when it exceeds Threshold
+	OnThresholdExceeded func(url string, variants int)
+
+	mutex sync.Mutex
+	//variants holds the keys of the variants per URL
+	variants map[string]map[uint64]string
+	//urls holds the URL of the keys
+	urls map[string]string
+}
+
+//NewVariantTracker creates a VariantTracker calling onThresholdExceeded for URLs with more than threshold variants
+func NewVariantTracker(threshold int, onThresholdExceeded func(url string, variants int)) *VariantTracker {
+	return &VariantTracker{
+		Threshold:           threshold,
+		OnThresholdExceeded: onThresholdExceeded,
+	}
+}
+
+//track records the variant of res stored for req, a variant is identified by the cache key and the values of the
+//request headers res varies on
+func (v *VariantTracker) track(cache Cacher, req *http.Request, res *http.Response, normalizers map[string]HeaderNormalizer) {
+
+	if v == nil {
+		return
+	}
+
+	var key string
+	if keyer, ok := cache.(Keyer); ok {
+		if k, err := keyer.Key(req); err == nil {
+			key = k
+		}
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	for _, field := range varyFields(res.Header) {
+		_, _ = hash.Write([]byte("\n" + field + ": " + normalizedHeader(req.Header, field, normalizers)))
+	}
+	variant := hash.Sum64()
+
+	url := requestHost(req) + req.URL.EscapedPath()
+
+	v.mutex.Lock()
+	if v.variants == nil {
+		v.variants = map[string]map[uint64]string{}
+		v.urls = map[string]string{}
+	}
+	urlVariants, ok := v.variants[url]
+	if !ok {
+		urlVariants = map[uint64]string{}
+		v.variants[url] = urlVariants
+	}
+	_, known := urlVariants[variant]
+	urlVariants[variant] = key
+	if key != "" {
+		v.urls[key] = url
+	}
+	count := len(urlVariants)
+	v.mutex.Unlock()
+
+	if !known && count == v.Threshold+1 && v.OnThresholdExceeded != nil {
+		v.OnThresholdExceeded(url, count)
+	}
+}
+
+//Evicted forgets the variants stored under key, it matches LRUCacheOptions.OnEvict
+func (v *VariantTracker) Evicted(key string) {
+
+	if v == nil || key == "" {
+		return
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	url, ok := v.urls[key]
+	if !ok {
+		return
+	}
+	delete(v.urls, key)
+	urlVariants := v.variants[url]
+	for variant, variantKey := range urlVariants {
+		if variantKey == key {
+			delete(urlVariants, variant)
+		}
+	}
+	if len(urlVariants) == 0 {
+		delete(v.variants, url)
+	}
+}
+
+//Count returns the number of variants stored for url, url is the host followed by the path
+func (v *VariantTracker) Count(url string) int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return len(v.variants[url])
+}
+
+//Counts returns the number of variants per URL
+func (v *VariantTracker) Counts() map[string]int {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	counts := make(map[string]int, len(v.variants))
+	for url, variants := range v.variants {
+		counts[url] = len(variants)
+	}
+	return counts
+}
+
+//Exceeded returns the URLs with more variants than Threshold
+func (v *VariantTracker) Exceeded() []string {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	var exceeded []string
+	for url, variants := range v.variants {
+		if len(variants) > v.Threshold {
+			exceeded = append(exceeded, url)
+		}
+	}
+	return exceeded
+}
+
+//Reset forgets all recorded variants
+func (v *VariantTracker) Reset() {
+	v.mutex.Lock()
+	v.variants = nil
+	v.urls = nil
+	v.mutex.Unlock()
+}
+
+//Variant is a response stored for a URL, see StoredVariants
+type Variant struct {
+	Key    string
+	Method string
+	//Vary are the header fields the response varies on
+	Vary []string
+	//Selected are the request header fields which selected the variant, the header lines of its key and the fields
+	//named in Vary with the values of the request the response was received for
+	Selected   http.Header
+	StatusCode int
+	//Representation are the Content-Type, Content-Language and Content-Encoding of the response
+	Representation http.Header
+}
+
+//representationHeaders are the header fields of the responses telling the variants of a URL apart
+var representationHeaders = []string{"Content-Type", "Content-Language", "Content-Encoding"}
+
+//StoredVariants returns the variants stored for rawURL with any method sorted by their key, the Cache has to
+//implement Inspector with IterationCapability. URLRewrites and HostAliases are applied to rawURL like to requests, if it has no query the
+//variants for all queries of its path are returned. Keys have to be in the request dump format of MapCache or
+//NewKeyFunc
+func (c *CachedTransport) StoredVariants(rawURL string) ([]Variant, error) {
+
+	inspector, ok := iterableCache(c.Cache)
+	if !ok {
+		return nil, InvalidationNotSupportedError
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	target = c.keyURL(target)
+
+	var variants []Variant
+	for _, key := range inspector.Keys() {
+		if !keyMatchesURL(key, target) {
+			continue
+		}
+		res, err := inspector.GetKey(key)
+		if errors.Is(err, NotInCacheError) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, newVariant(key, res))
+		if res.Body != nil {
+			_ = res.Body.Close()
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].Key < variants[j].Key
+	})
+	return variants, nil
+}
+
+//PurgeVariant deletes the variants stored for rawURL which were selected by the values of header and returns their
+//number, e.g. the German variant with Accept-Language: de. Fields missing in header select every value, with an empty
+//header all variants of rawURL are deleted. A single variant is deleted by its key with Invalidate
+func (c *CachedTransport) PurgeVariant(rawURL string, header http.Header) (int, error) {
+
+	variants, err := c.StoredVariants(rawURL)
+	if err != nil {
+		return 0, err
+	}
+
+	inspector := c.Cache.(Inspector)
+	deleted := 0
+	for _, variant := range variants {
+		if !variant.selectedBy(header) {
+			continue
+		}
+		err := c.deleteKey(inspector, variant.Key)
+		if errors.Is(err, NotInCacheError) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+//newVariant describes the response res stored under key
+func newVariant(key string, res *http.Response) Variant {
+
+	variant := Variant{
+		Key:            key,
+		Method:         summarizeKey(key).Method,
+		Vary:           varyFields(res.Header),
+		Selected:       keyHeader(key),
+		StatusCode:     res.StatusCode,
+		Representation: http.Header{},
+	}
+	for field, values := range varyHeaders(res) {
+		variant.Selected[field] = values
+	}
+	for _, field := range representationHeaders {
+		if values, ok := res.Header[field]; ok {
+			variant.Representation[field] = append([]string(nil), values...)
+		}
+	}
+	return variant
+}
+
+//selectedBy reports if the fields of header have the same values in Selected, list elements are compared ignoring
+//the whitespace around them
+func (v Variant) selectedBy(header http.Header) bool {
+	for field, values := range header {
+		field = http.CanonicalHeaderKey(field)
+		selected, ok := v.Selected[field]
+		if !ok || normalizeHeaderValues(selected) != normalizeHeaderValues(values) {
+			return false
+		}
+	}
+	return true
+}
+
+//keyHeader returns the header lines of key in the request dump format without the Host line
+func keyHeader(key string) http.Header {
+
+	header := http.Header{}
+	lines := strings.Split(key, "\r\n")
+	for _, line := range lines[1:] {
+		if line == "" {
+			//the request body follows
+			break
+		}
+		colon := strings.Index(line, ":")
+		if colon <= 0 {
+			continue
+		}
+		field := http.CanonicalHeaderKey(line[:colon])
+		if field == "Host" {
+			continue
+		}
+		header[field] = append(header[field], strings.TrimSpace(line[colon+1:]))
+	}
+	return header
+}
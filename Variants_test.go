@@ -0,0 +1,176 @@
+package CachedHttpClient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVariantTracker(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		writer.Header().Set("Vary", "Accept")
+		fmt.Fprint(writer, "content")
+	}))
+	defer server.Close()
+
+	var alerts []string
+	tracker := NewVariantTracker(2, func(url string, variants int) {
+		alerts = append(alerts, fmt.Sprint(url, " ", variants))
+	})
+
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{Headers: []string{"X-Session"}})})
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport, Variants: tracker}}
+
+	requests := []struct{ session, accept string }{
+		{"1", "text/html"},
+		{"1", "text/html"},
+		{"1", "application/json"},
+		{"2", "text/html"},
+		{"3", "text/html"},
+	}
+	for _, r := range requests {
+		request, err := http.NewRequest("GET", server.URL+"/path?query=1", nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header.Set("X-Session", r.session)
+		request.Header.Set("Accept", r.accept)
+		_, err = client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	url := server.Listener.Addr().String() + "/path"
+	if count := tracker.Count(url); count != 4 {
+		t.Error("expected 4 variants, got", count, tracker.Counts())
+	}
+	if len(alerts) != 1 || alerts[0] != url+" 3" {
+		t.Error("expected one alert, got", alerts)
+	}
+	if exceeded := tracker.Exceeded(); len(exceeded) != 1 || exceeded[0] != url {
+		t.Error("wrong exceeded URLs", exceeded)
+	}
+
+	tracker.Reset()
+	if count := tracker.Count(url); count != 0 {
+		t.Error("variants not reset", count)
+	}
+
+}
+
+func TestVariantTracker_Evicted(t *testing.T) {
+
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		res := lruTestResponse("content")
+		res.Header.Set("Cache-Control", "max-age=60")
+		res.Header.Set("Vary", "Accept-Language")
+		res.Request = req
+		return res, nil
+	})
+	tracker := NewVariantTracker(10, nil)
+	cache := NewLRUCache(LRUCacheOptions{MapCacheOptions: MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{Headers: []string{"Accept-Language"}})},
+		MaxEntries: 2, OnEvict: tracker.Evicted})
+	transport := &CachedTransport{Cache: cache, Fallback: origin, Variants: tracker}
+
+	for _, language := range []string{"de", "en", "fr"} {
+		request := lruTestRequest(t, "/page")
+		request.Header.Set("Accept-Language", language)
+		if _, err := transport.RoundTrip(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := tracker.Count("example.com/page"); count != 2 {
+		t.Error("expected the evicted variant to be forgotten, got", count)
+	}
+
+	if deleted, err := transport.PurgeVariant("http://example.com/page", http.Header{"Accept-Language": {"en"}}); err != nil || deleted != 1 {
+		t.Fatal("expected the en variant to be purged, got", deleted, err)
+	}
+	if count := tracker.Count("example.com/page"); count != 1 {
+		t.Error("expected the purged variant to be forgotten, got", count)
+	}
+
+	service := &AdminService{Cache: cache, Variants: tracker}
+	if deleted, err := service.InvalidateAll(); err != nil || deleted != 1 {
+		t.Fatal("expected the last variant to be deleted, got", deleted, err)
+	}
+	if counts := tracker.Counts(); len(counts) != 0 {
+		t.Error("expected no variants, got", counts)
+	}
+}
+
+func TestCachedTransport_StoredVariants(t *testing.T) {
+
+	origin := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		language := req.Header.Get("Accept-Language")
+		res := lruTestResponse("content " + language)
+		res.Header.Set("Cache-Control", "max-age=60")
+		res.Header.Set("Vary", "Accept-Language")
+		res.Header.Set("Content-Language", language)
+		res.Request = req
+		return res, nil
+	})
+	transport := &CachedTransport{
+		Cache:       NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{Headers: []string{"Accept-Language"}})}),
+		Fallback:    origin,
+		HostAliases: map[string]string{"www.example.com": "example.com"},
+	}
+
+	for _, language := range []string{"de", "en", "fr"} {
+		request := lruTestRequest(t, "/page")
+		request.Header.Set("Accept-Language", language)
+		if _, err := transport.RoundTrip(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := transport.RoundTrip(lruTestRequest(t, "/other")); err != nil {
+		t.Fatal(err)
+	}
+
+	variants, err := transport.StoredVariants("http://www.example.com/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 3 {
+		t.Fatal("expected 3 variants, got", len(variants))
+	}
+	for i, language := range []string{"de", "en", "fr"} {
+		variant := variants[i]
+		if variant.Method != "GET" || len(variant.Vary) != 1 || variant.Vary[0] != "Accept-Language" {
+			t.Error("wrong variant", variant)
+		}
+		if variant.Selected.Get("Accept-Language") != language || variant.Representation.Get("Content-Language") != language {
+			t.Error("wrong selecting headers", variant.Selected, variant.Representation)
+		}
+		if variant.StatusCode != http.StatusOK {
+			t.Error("wrong status", variant.StatusCode)
+		}
+	}
+
+	deleted, err := transport.PurgeVariant("http://example.com/page", http.Header{"accept-language": {"en"}})
+	if err != nil || deleted != 1 {
+		t.Error("expected the en variant to be deleted, got", deleted, err)
+	}
+	variants, _ = transport.StoredVariants("http://example.com/page")
+	if len(variants) != 2 || variants[0].Selected.Get("Accept-Language") != "de" || variants[1].Selected.Get("Accept-Language") != "fr" {
+		t.Error("wrong remaining variants", variants)
+	}
+
+	deleted, err = transport.PurgeVariant("http://example.com/page", nil)
+	if err != nil || deleted != 2 {
+		t.Error("expected all variants to be deleted, got", deleted, err)
+	}
+	if keys := transport.Cache.(Inspector).Keys(); len(keys) != 1 {
+		t.Error("expected the other URL to be kept, got", keys)
+	}
+
+	if _, err := (&CachedTransport{Cache: failingCache{}}).StoredVariants("http://example.com/page"); !errors.Is(err, InvalidationNotSupportedError) {
+		t.Error("expected InvalidationNotSupportedError, got", err)
+	}
+}
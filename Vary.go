@@ -0,0 +1,75 @@
+package CachedHttpClient
+
+import (
+	"net/http"
+	"strings"
+)
+
+//varyFields returns the canonical header names listed in the Vary header of header
+func varyFields(header http.Header) []string {
+
+	var fields []string
+	for _, line := range header["Vary"] {
+		for _, field := range strings.Split(line, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			fields = append(fields, http.CanonicalHeaderKey(field))
+		}
+	}
+	return fields
+}
+
+//varyHeaders returns the values of the header fields res varies on in the request res was received for
+func varyHeaders(res *http.Response) http.Header {
+
+	fields := varyFields(res.Header)
+	if len(fields) == 0 || res.Request == nil {
+		return nil
+	}
+
+	headers := http.Header{}
+	for _, field := range fields {
+		if values, ok := res.Request.Header[field]; ok {
+			headers[field] = append([]string(nil), values...)
+		}
+	}
+	return headers
+}
+
+//varyMatches reports if the stored response res may be used for req, the header fields named in the Vary header
+//of res must have the same values in req and in the request res was received for (RFC 7234 4.1). Fields with a
+//normalizer are compared by their normalized values
+func varyMatches(req *http.Request, res *http.Response, normalizers map[string]HeaderNormalizer) bool {
+
+	fields := varyFields(res.Header)
+	if len(fields) == 0 {
+		return true
+	}
+	if res.Request == nil {
+		return false
+	}
+
+	for _, field := range fields {
+		if field == "*" {
+			return false
+		}
+		if normalizedHeader(req.Header, field, normalizers) != normalizedHeader(res.Request.Header, field, normalizers) {
+			return false
+		}
+	}
+	return true
+}
+
+//normalizeHeaderValues combines the values of a header field ignoring whitespace around the list elements
+func normalizeHeaderValues(values []string) string {
+
+	var elements []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			elements = append(elements, strings.TrimSpace(element))
+		}
+	}
+	return strings.Join(elements, ",")
+}
@@ -0,0 +1,113 @@
+package CachedHttpClient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCachedTransport_RoundTrip_Vary(t *testing.T) {
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		requests++
+		writer.Header().Set("Vary", "Accept-Language")
+		_, _ = writer.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	//the key ignores all headers so only the Vary header keeps the languages apart
+	cache := NewMapCache(MapCacheOptions{KeyFunc: NewKeyFunc(KeyOptions{})})
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport}}
+
+	get := func(language string) string {
+		request, err := http.NewRequest("GET", server.URL, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		request.Header.Set("Accept-Language", language)
+		response, err := client.Do(request)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return string(body)
+	}
+
+	for _, language := range []string{"en", "en", "de", "de"} {
+		if body := get(language); body != language {
+			t.Error("wrong variant served for", language, "got", body)
+		}
+	}
+	if requests != 2 {
+		t.Error("expected 2 origin requests, got", requests)
+	}
+
+}
+
+func TestVaryMatches(t *testing.T) {
+
+	stored := &http.Response{
+		Header:  http.Header{"Vary": {"accept-encoding, Accept"}},
+		Request: &http.Request{Header: http.Header{"Accept": {"text/html,application/json"}}},
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		matches bool
+	}{
+		{"same", http.Header{"Accept": {"text/html,application/json"}}, true},
+		{"whitespace", http.Header{"Accept": {"text/html, application/json"}}, true},
+		{"different", http.Header{"Accept": {"application/json"}}, false},
+		{"additional", http.Header{"Accept": {"text/html,application/json"}, "Accept-Encoding": {"gzip"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matches := varyMatches(&http.Request{Header: test.header}, stored, nil); matches != test.matches {
+				t.Error("expected", test.matches, "got", matches)
+			}
+		})
+	}
+
+	if varyMatches(&http.Request{Header: http.Header{}}, &http.Response{Header: http.Header{"Vary": {"*"}}, Request: &http.Request{}}, nil) {
+		t.Error("Vary: * matched")
+	}
+
+}
+
+func TestJsonResponse_VaryHeaders(t *testing.T) {
+
+	response := &http.Response{
+		Header:  http.Header{"Vary": {"Accept-Language"}},
+		Body:    ioutil.NopCloser(strings.NewReader("body")),
+		Request: &http.Request{Header: http.Header{"Accept-Language": {"de"}, "Cookie": {"secret"}}},
+	}
+
+	jsonResponse, err := NewJsonResponse(response)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if _, ok := jsonResponse.VaryHeaders["Cookie"]; ok {
+		t.Error("header not listed in Vary stored")
+	}
+
+	recreated := jsonResponse.ToResponse()
+	if !varyMatches(&http.Request{Header: http.Header{"Accept-Language": {"de"}}}, recreated, nil) {
+		t.Error("recreated response does not match")
+	}
+	if varyMatches(&http.Request{Header: http.Header{"Accept-Language": {"en"}}}, recreated, nil) {
+		t.Error("recreated response matches other variant")
+	}
+
+}
@@ -0,0 +1,66 @@
+package CachedHttpClient
+
+import (
+	"crypto/x509"
+	"io"
+)
+
+//verboseCertificatesCodec stores all fields of the certificates of the TLS state with the entries of codec
+type verboseCertificatesCodec struct {
+	codec Codec
+}
+
+//NewVerboseCertificatesCodec returns a Codec storing all fields of the certificates besides their DER encoding, for
+//human inspection of the stored entries, e.g. NewVerboseCertificatesCodec(JSONCodec) for FileCacheOptions.Codec.
+//Certificates with a DER encoding are parsed from it either way, the entries are decoded by codec as they are
+func NewVerboseCertificatesCodec(codec Codec) Codec {
+	return &verboseCertificatesCodec{codec: codec}
+}
+
+func (v *verboseCertificatesCodec) Encode(w io.Writer, entry *FileCacheEntry) error {
+
+	if entry.Response == nil || entry.Response.TLS == nil {
+		return v.codec.Encode(w, entry)
+	}
+	//the entry is shared with the cache, the copies get the verbose certificates
+	tls := *entry.Response.TLS
+	tls.PeerCertificates = verboseCertificates(tls.PeerCertificates)
+	if tls.VerifiedChains != nil {
+		tls.VerifiedChains = make([][]*JsonX509Certificate, len(entry.Response.TLS.VerifiedChains))
+		for i, chain := range entry.Response.TLS.VerifiedChains {
+			tls.VerifiedChains[i] = verboseCertificates(chain)
+		}
+	}
+	response := *entry.Response
+	response.TLS = &tls
+	verbose := *entry
+	verbose.Response = &response
+	return v.codec.Encode(w, &verbose)
+}
+
+func (v *verboseCertificatesCodec) NewDecoder(r io.Reader) EntryDecoder {
+	return v.codec.NewDecoder(r)
+}
+
+//verboseCertificates returns the certificates with all fields parsed from their DER encoding, certificates which do
+//not parse are kept as they are
+func verboseCertificates(certificates []*JsonX509Certificate) []*JsonX509Certificate {
+	if certificates == nil {
+		return nil
+	}
+	verbose := make([]*JsonX509Certificate, len(certificates))
+	for i, certificate := range certificates {
+		verbose[i] = certificate
+		if certificate == nil || !certificate.rawOnly() {
+			continue
+		}
+		cert, err := x509.ParseCertificate(certificate.Raw)
+		if err != nil {
+			continue
+		}
+		if encoded, err := encodeVerboseCertificate(cert); err == nil {
+			verbose[i] = encoded
+		}
+	}
+	return verbose
+}
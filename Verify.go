@@ -0,0 +1,113 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+//ResponseVerifier checks the integrity of a response, e.g. a detached signature or a digest header, body is the
+//complete body of res. A returned error prevents the response from being cached and served
+type ResponseVerifier func(res *http.Response, body []byte) error
+
+var VerificationError = errors.New("response verification failed")
+
+//digestAlgorithms are the hash algorithms of the HTTP digest fields (RFC 9530) VerifyContentDigest supports
+var digestAlgorithms = map[string]func() hash.Hash{
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+//VerifyContentDigest is a ResponseVerifier checking the Content-Digest and Repr-Digest headers (RFC 9530) against the
+//body, responses without digest headers pass. Every supported algorithm in the headers has to match
+func VerifyContentDigest(res *http.Response, body []byte) error {
+
+	for _, header := range []string{"Content-Digest", "Repr-Digest"} {
+		for _, line := range res.Header[header] {
+			for _, member := range strings.Split(line, ",") {
+
+				algorithm, value, ok := parseDigestMember(member)
+				if !ok {
+					return fmt.Errorf("malformed %s %q", header, member)
+				}
+				newHash, ok := digestAlgorithms[algorithm]
+				if !ok {
+					continue
+				}
+				h := newHash()
+				h.Write(body)
+				if !bytes.Equal(h.Sum(nil), value) {
+					return fmt.Errorf("%s %s does not match the body", header, algorithm)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+//parseDigestMember parses a dictionary member like sha-256=:base64: of a digest field
+func parseDigestMember(member string) (algorithm string, value []byte, ok bool) {
+
+	i := strings.IndexByte(member, '=')
+	if i < 0 {
+		return "", nil, false
+	}
+	algorithm = strings.ToLower(strings.TrimSpace(member[:i]))
+	encoded := strings.TrimSpace(member[i+1:])
+	if len(encoded) < 2 || encoded[0] != ':' || encoded[len(encoded)-1] != ':' {
+		return "", nil, false
+	}
+
+	value, err := base64.StdEncoding.DecodeString(encoded[1 : len(encoded)-1])
+	if err != nil {
+		return "", nil, false
+	}
+	return algorithm, value, true
+}
+
+//verify runs the Verifier of the transport on res, the body of res is buffered and replaced
+func (c *CachedTransport) verify(res *http.Response) error {
+
+	if c.Verifier == nil {
+		return nil
+	}
+
+	body, err := bufferBody(res)
+	if err != nil {
+		return err
+	}
+
+	err = c.Verifier(res, body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", VerificationError, err)
+	}
+	return nil
+}
+
+//bufferBody reads and closes the body of res and replaces it with a reader over the returned bytes
+func bufferBody(res *http.Response) ([]byte, error) {
+
+	if res.Body == nil || res.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	err = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,97 @@
+package CachedHttpClient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func contentDigest(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+func TestVerifyContentDigest(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header http.Header
+		valid  bool
+	}{
+		{"no digest", http.Header{}, true},
+		{"content digest", http.Header{"Content-Digest": {contentDigest("body")}}, true},
+		{"repr digest", http.Header{"Repr-Digest": {contentDigest("body")}}, true},
+		{"wrong digest", http.Header{"Content-Digest": {contentDigest("other")}}, false},
+		{"unknown algorithm", http.Header{"Content-Digest": {"md5=:AAAA:"}}, true},
+		{"malformed", http.Header{"Content-Digest": {"sha-256=abc"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifyContentDigest(&http.Response{Header: test.header}, []byte("body"))
+			if (err == nil) != test.valid {
+				t.Error("expected valid", test.valid, "got", err)
+			}
+		})
+	}
+}
+
+func TestCachedTransport_RoundTrip_Verifier(t *testing.T) {
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, r *http.Request) {
+		requests++
+		digest := contentDigest("content")
+		if r.URL.Path == "/tampered" {
+			digest = contentDigest("other")
+		}
+		writer.Header().Set("Content-Digest", digest)
+		_, _ = writer.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	cache := NewMapCache()
+	client := http.Client{Transport: &CachedTransport{Cache: cache, Fallback: http.DefaultTransport, Verifier: VerifyContentDigest}}
+
+	_, err := client.Get(server.URL + "/tampered")
+	if !errors.Is(err, VerificationError) {
+		t.Error("expected verification error, got", err)
+	}
+	if len(cache.cache) != 0 {
+		t.Error("unverified response stored")
+	}
+
+	_, err = client.Get(server.URL + "/valid")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	//corrupting the stored entry makes the transport fetch it again
+	for _, res := range cache.cache {
+		res.Body = ioutil.NopCloser(bytes.NewReader([]byte("corrupted")))
+	}
+
+	response, err := client.Get(server.URL + "/valid")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if string(body) != "content" {
+		t.Error("corrupted entry served", string(body))
+	}
+	if requests != 3 {
+		t.Error("expected 3 origin requests, got", requests)
+	}
+
+}
@@ -0,0 +1,337 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//WireCodec stores the responses in the HTTP/1.1 wire format written by httputil.DumpResponse, one after another, so a
+//cache file can be read by tools understanding raw HTTP responses. The parts of an entry which are no header fields of
+//the response, like the key, the stored request and the original framing header fields, are kept in X-Cache-Wire-*
+//header fields which are removed again on decoding. Deleted keys are written as 410 Gone responses with the header
+//field X-Cache-Wire-Deleted. The TLS state of the responses is not stored
+var WireCodec Codec = wireCodec{}
+
+//wireHeaderPrefix starts the names of the header fields holding the entry besides the response
+const wireHeaderPrefix = "X-Cache-Wire-"
+
+const (
+	wireKeyHeader                 = wireHeaderPrefix + "Key"
+	wireVersionHeader             = wireHeaderPrefix + "Version"
+	wireDeletedHeader             = wireHeaderPrefix + "Deleted"
+	wireProvenanceHeader          = wireHeaderPrefix + "Provenance"
+	wireRequestHeader             = wireHeaderPrefix + "Request"
+	wireRequestFieldHeader        = wireHeaderPrefix + "Request-Field"
+	wireVaryFieldHeader           = wireHeaderPrefix + "Vary-Field"
+	wireFieldHeader               = wireHeaderPrefix + "Field"
+	wireTrailerHeader             = wireHeaderPrefix + "Trailer"
+	wireContentLengthHeader       = wireHeaderPrefix + "Content-Length"
+	wireTransferEncodingHeader    = wireHeaderPrefix + "Transfer-Encoding"
+	wireCloseHeader               = wireHeaderPrefix + "Close"
+	wireUncompressedHeader        = wireHeaderPrefix + "Uncompressed"
+	wireBodyFileHeader            = wireHeaderPrefix + "Body-File"
+	wireBodyCompressionHeader     = wireHeaderPrefix + "Body-Compression"
+	wireCompressionDecisionHeader = wireHeaderPrefix + "Compression-Decision"
+)
+
+//InvalidWireEntryError is returned by the decoder of WireCodec for responses missing the fields of an entry
+var InvalidWireEntryError = errors.New("invalid wire entry")
+
+//wireFramingFields are written by httputil.DumpResponse from the fields of the response instead of its header, the
+//original values are kept in X-Cache-Wire-Field
+var wireFramingFields = []string{"Content-Length", "Transfer-Encoding", "Trailer"}
+
+type wireCodec struct{}
+
+func (wireCodec) Encode(w io.Writer, entry *FileCacheEntry) error {
+
+	response := entry.Response
+	if response == nil {
+		return writeWireDeleted(w, entry.Request)
+	}
+	header, err := decompressHeader(response)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		header = http.Header{}
+	}
+
+	meta := http.Header{}
+	meta.Set(wireKeyHeader, strconv.Quote(entry.Request))
+	if entry.Version != 0 {
+		meta.Set(wireVersionHeader, strconv.Itoa(entry.Version))
+	}
+	if entry.Provenance != nil {
+		meta.Set(wireProvenanceHeader, encodeWireProvenance(entry.Provenance))
+	}
+	if request := response.Request; request != nil {
+		meta.Set(wireRequestHeader, strconv.Quote(request.Method+" "+request.URL))
+		addWireFields(meta, wireRequestFieldHeader, request.Header)
+	}
+	addWireFields(meta, wireVaryFieldHeader, response.VaryHeaders)
+	addWireFields(meta, wireTrailerHeader, response.Trailer)
+	for _, field := range wireFramingFields {
+		if values, ok := header[field]; ok {
+			addWireFields(meta, wireFieldHeader, http.Header{field: values})
+			delete(header, field)
+		}
+	}
+	if response.ContentLength != int64(len(response.Body)) {
+		meta.Set(wireContentLengthHeader, strconv.FormatInt(response.ContentLength, 10))
+	}
+	for _, coding := range response.TransferEncoding {
+		meta.Add(wireTransferEncodingHeader, strconv.Quote(coding))
+	}
+	if response.Close {
+		meta.Set(wireCloseHeader, "true")
+	}
+	if response.Uncompressed {
+		meta.Set(wireUncompressedHeader, "true")
+	}
+	if response.BodyFile != "" {
+		meta.Set(wireBodyFileHeader, strconv.Quote(response.BodyFile))
+	}
+	if response.BodyCompression != "" {
+		meta.Set(wireBodyCompressionHeader, strconv.Quote(response.BodyCompression))
+	}
+	if response.CompressionDecision != "" {
+		meta.Set(wireCompressionDecisionHeader, strconv.Quote(response.CompressionDecision))
+	}
+	for field, values := range meta {
+		header[field] = values
+	}
+
+	//the body is always framed by its length, the stored values of the framing fields are restored on decoding
+	dump, err := httputil.DumpResponse(&http.Response{
+		Status:        response.Status,
+		StatusCode:    response.StatusCode,
+		ProtoMajor:    response.ProtoMajor,
+		ProtoMinor:    response.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+	}, true)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(dump)
+	return err
+}
+
+func (wireCodec) NewDecoder(r io.Reader) EntryDecoder {
+	return &wireDecoder{reader: bufio.NewReader(r)}
+}
+
+type wireDecoder struct {
+	reader *bufio.Reader
+}
+
+func (d *wireDecoder) Decode(entry *FileCacheEntry) error {
+
+	if _, err := d.reader.Peek(1); err != nil {
+		return err
+	}
+	res, err := http.ReadResponse(d.reader, nil)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	meta := http.Header{}
+	for field, values := range res.Header {
+		if strings.HasPrefix(field, wireHeaderPrefix) {
+			meta[field] = values
+		} else {
+			header[field] = values
+		}
+	}
+	for _, field := range wireFramingFields {
+		delete(header, field)
+	}
+
+	key, err := strconv.Unquote(meta.Get(wireKeyHeader))
+	if err != nil {
+		return InvalidWireEntryError
+	}
+	if meta.Get(wireDeletedHeader) == "true" {
+		*entry = FileCacheEntry{Request: key}
+		return nil
+	}
+	response := &JsonResponse{
+		Status:        res.Status,
+		StatusCode:    res.StatusCode,
+		Proto:         res.Proto,
+		ProtoMajor:    res.ProtoMajor,
+		ProtoMinor:    res.ProtoMinor,
+		Header:        header,
+		Body:          body,
+		ContentLength: int64(len(body)),
+		Close:         meta.Get(wireCloseHeader) == "true",
+		Uncompressed:  meta.Get(wireUncompressedHeader) == "true",
+	}
+
+	var provenance *Provenance
+	if value := meta.Get(wireProvenanceHeader); value != "" {
+		if provenance, err = decodeWireProvenance(value); err != nil {
+			return err
+		}
+	}
+	if value := meta.Get(wireRequestHeader); value != "" {
+		line, err := strconv.Unquote(value)
+		if err != nil {
+			return InvalidWireEntryError
+		}
+		space := strings.IndexByte(line, ' ')
+		if space < 0 {
+			return InvalidWireEntryError
+		}
+		requestHeader, err := wireFields(meta[wireRequestFieldHeader], true)
+		if err != nil {
+			return err
+		}
+		response.Request = &JsonRequest{Method: line[:space], URL: line[space+1:], Header: requestHeader}
+	}
+	if response.VaryHeaders, err = wireFields(meta[wireVaryFieldHeader], false); err != nil {
+		return err
+	}
+	if response.Trailer, err = wireFields(meta[wireTrailerHeader], false); err != nil {
+		return err
+	}
+	framing, err := wireFields(meta[wireFieldHeader], false)
+	if err != nil {
+		return err
+	}
+	for field, values := range framing {
+		header[field] = values
+	}
+	if value := meta.Get(wireContentLengthHeader); value != "" {
+		if response.ContentLength, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return InvalidWireEntryError
+		}
+	}
+	for _, value := range meta[wireTransferEncodingHeader] {
+		coding, err := strconv.Unquote(value)
+		if err != nil {
+			return InvalidWireEntryError
+		}
+		response.TransferEncoding = append(response.TransferEncoding, coding)
+	}
+	for name, target := range map[string]*string{
+		wireBodyFileHeader:            &response.BodyFile,
+		wireBodyCompressionHeader:     &response.BodyCompression,
+		wireCompressionDecisionHeader: &response.CompressionDecision,
+	} {
+		if value := meta.Get(name); value != "" {
+			if *target, err = strconv.Unquote(value); err != nil {
+				return InvalidWireEntryError
+			}
+		}
+	}
+
+	var version int
+	if value := meta.Get(wireVersionHeader); value != "" {
+		if version, err = strconv.Atoi(value); err != nil {
+			return InvalidWireEntryError
+		}
+	}
+
+	*entry = FileCacheEntry{Version: version, Request: key, Response: response, Provenance: provenance}
+	return nil
+}
+
+//writeWireDeleted writes the entry removing key
+func writeWireDeleted(w io.Writer, key string) error {
+	dump, err := httputil.DumpResponse(&http.Response{
+		StatusCode:    http.StatusGone,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{wireKeyHeader: {strconv.Quote(key)}, wireDeletedHeader: {"true"}},
+		Body:          http.NoBody,
+		ContentLength: 0,
+	}, true)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(dump)
+	return err
+}
+
+//addWireFields adds every value of fields to meta as a quoted "Name: value" line under name
+func addWireFields(meta http.Header, name string, fields http.Header) {
+	for field, values := range fields {
+		for _, value := range values {
+			meta.Add(name, strconv.Quote(field+": "+value))
+		}
+	}
+}
+
+//wireFields parses the lines written by addWireFields, empty returns an empty header instead of nil for no lines
+func wireFields(lines []string, empty bool) (http.Header, error) {
+	if len(lines) == 0 {
+		if empty {
+			return http.Header{}, nil
+		}
+		return nil, nil
+	}
+	fields := http.Header{}
+	for _, line := range lines {
+		unquoted, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, InvalidWireEntryError
+		}
+		colon := strings.Index(unquoted, ": ")
+		if colon < 0 {
+			return nil, InvalidWireEntryError
+		}
+		//the names are stored as they were, not canonicalized
+		field := unquoted[:colon]
+		fields[field] = append(fields[field], unquoted[colon+2:])
+	}
+	return fields, nil
+}
+
+//encodeWireProvenance encodes provenance as URL query
+func encodeWireProvenance(provenance *Provenance) string {
+	return url.Values{
+		"version":    {provenance.Version},
+		"codec":      {provenance.Codec},
+		"policyHash": {provenance.PolicyHash},
+		"hostname":   {provenance.Hostname},
+		"storedAt":   {provenance.StoredAt.Format(time.RFC3339Nano)},
+	}.Encode()
+}
+
+func decodeWireProvenance(value string) (*Provenance, error) {
+	values, err := url.ParseQuery(value)
+	if err != nil {
+		return nil, InvalidWireEntryError
+	}
+	storedAt, err := time.Parse(time.RFC3339Nano, values.Get("storedAt"))
+	if err != nil {
+		return nil, InvalidWireEntryError
+	}
+	return &Provenance{
+		Version:    values.Get("version"),
+		Codec:      values.Get("codec"),
+		PolicyHash: values.Get("policyHash"),
+		Hostname:   values.Get("hostname"),
+		StoredAt:   storedAt,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package CachedHttpClient
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWireCodec(t *testing.T) {
+
+	entries := []FileCacheEntry{
+		{
+			Request: "GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			Response: &JsonResponse{
+				Status:           "200 OK",
+				StatusCode:       200,
+				Proto:            "HTTP/1.1",
+				ProtoMajor:       1,
+				ProtoMinor:       1,
+				Header:           http.Header{"Content-Type": {"application/octet-stream"}, "Vary": {"Cookie"}, "Content-Length": {"6"}},
+				Body:             []byte{0, 1, 2, '\r', '\n', 255},
+				ContentLength:    6,
+				TransferEncoding: []string{"chunked"},
+				Uncompressed:     true,
+				Trailer:          http.Header{"Checksum": {"abc"}},
+				Request:          &JsonRequest{Method: "GET", URL: "https://example.com/a", Header: http.Header{"Accept": {"*/*", "text/plain"}}},
+				VaryHeaders:      http.Header{"Cookie": {"session=1"}},
+				BodyCompression:  "gzip",
+			},
+			Provenance: &Provenance{Version: "v1.2.3", Codec: "wire", PolicyHash: "0123", Hostname: "host", StoredAt: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)},
+		},
+		{
+			Request: "key without a request line",
+			Response: &JsonResponse{
+				Status:        "304 Not Modified",
+				StatusCode:    304,
+				Proto:         "HTTP/2.0",
+				ProtoMajor:    2,
+				Header:        http.Header{"Etag": {`"v1"`}},
+				Body:          []byte{},
+				ContentLength: -1,
+				Close:         true,
+				BodyFile:      "bodies/0123",
+			},
+		},
+		{Request: "deleted key"},
+	}
+
+	var buf bytes.Buffer
+	for i := range entries {
+		if err := WireCodec.Encode(&buf, &entries[i]); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	//the file is a sequence of raw HTTP responses
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf.Bytes())), nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "application/octet-stream" || !bytes.Equal(body, entries[0].Response.Body) {
+		t.Error("expected the first entry to be readable as response, got", res.Status, res.Header, body)
+	}
+
+	decoder := WireCodec.NewDecoder(&buf)
+	for i, expected := range entries {
+		var entry FileCacheEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !reflect.DeepEqual(entry, expected) {
+			t.Errorf("entry %d changed\nexpected %+v\n%+v\ngot      %+v\n%+v", i, expected, expected.Response, entry, entry.Response)
+		}
+	}
+	var entry FileCacheEntry
+	if err := decoder.Decode(&entry); err != io.EOF {
+		t.Error("expected io.EOF after the last entry, got", err)
+	}
+
+	truncated := WireCodec.NewDecoder(bytes.NewReader([]byte("HTTP/1.1 200 OK\r\nContent-Length: 1\r\n\r\n")))
+	if err := truncated.Decode(&entry); err == nil {
+		t.Error("expected an error for a truncated entry")
+	}
+}
@@ -0,0 +1,109 @@
+//Command apimirror mirrors an upstream API, requests to it are answered from a persistent cache and forwarded to the
+//upstream on misses. Stale responses are served while the upstream fails and -offline serves the cache only
+//
+//	apimirror -upstream https://api.example.com [-listen :8080] [-cache dir] [-stale-if-error 1h] [-offline]
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"time"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//config are the settings of the mirror
+type config struct {
+	listen       string
+	upstream     *url.URL
+	dir          string
+	staleIfError time.Duration
+	offline      bool
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "apimirror:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+
+	cfg, err := parseFlags(args, os.Stderr)
+	if err != nil {
+		return err
+	}
+	handler, err := newMirror(cfg, http.DefaultTransport)
+	if err != nil {
+		return err
+	}
+	log.Printf("apimirror of %s listening on %s", cfg.upstream, cfg.listen)
+	return http.ListenAndServe(cfg.listen, handler)
+}
+
+func parseFlags(args []string, output io.Writer) (*config, error) {
+
+	flags := flag.NewFlagSet("apimirror", flag.ContinueOnError)
+	flags.SetOutput(output)
+	cfg := &config{}
+	upstream := flags.String("upstream", "", "URL of the mirrored API")
+	flags.StringVar(&cfg.listen, "listen", ":8080", "address to listen on")
+	flags.StringVar(&cfg.dir, "cache", "apimirror-cache", "directory of the cache")
+	flags.DurationVar(&cfg.staleIfError, "stale-if-error", time.Hour, "how long stale responses are served while the upstream fails")
+	flags.BoolVar(&cfg.offline, "offline", false, "serve the cache only and never contact the upstream")
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if *upstream == "" {
+		return nil, errors.New("missing -upstream")
+	}
+	cfg.upstream, err = url.Parse(*upstream)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//newMirror returns the handler forwarding requests to the upstream through a CachedTransport using fallback
+func newMirror(cfg *config, fallback http.RoundTripper) (http.Handler, error) {
+
+	//the key ignores the request headers like X-Forwarded-For so all clients share the responses, Vary is still honored
+	keyFunc := CachedHttpClient.NewKeyFunc(CachedHttpClient.KeyOptions{})
+	cache, err := CachedHttpClient.NewDiskCache(cfg.dir, CachedHttpClient.DiskCacheOptions{MapCacheOptions: CachedHttpClient.MapCacheOptions{KeyFunc: keyFunc}})
+	if err != nil {
+		return nil, err
+	}
+	transport := &CachedHttpClient.CachedTransport{
+		Cache:        cache,
+		Fallback:     fallback,
+		StaleIfError: cfg.staleIfError,
+		Offline:      cfg.offline,
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(cfg.upstream)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		//the upstream is addressed by its own name, not by the name the mirror was reached with
+		req.Host = cfg.upstream.Host
+	}
+	proxy.Transport = transport
+	proxy.ErrorHandler = func(writer http.ResponseWriter, req *http.Request, err error) {
+		status := http.StatusBadGateway
+		if errors.Is(err, CachedHttpClient.CacheMissError) {
+			status = http.StatusGatewayTimeout
+		}
+		http.Error(writer, err.Error(), status)
+	}
+	return proxy, nil
+}
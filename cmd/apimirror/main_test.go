@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestMirror(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "apimirror")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	requests := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		requests++
+		writer.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(writer, "response for ", req.URL.Path)
+	}))
+	defer upstream.Close()
+
+	get := func(cfg *config, path string) (int, string) {
+		mirror, err := newMirror(cfg, http.DefaultTransport)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		recorder := httptest.NewRecorder()
+		mirror.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder.Code, recorder.Body.String()
+	}
+
+	cfg, err := parseFlags([]string{"-upstream", upstream.URL, "-cache", dir}, ioutil.Discard)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for i := 0; i < 2; i++ {
+		if status, body := get(cfg, "/users"); status != http.StatusOK || body != "response for /users" {
+			t.Error("expected the upstream response got", status, body)
+		}
+	}
+	if requests != 1 {
+		t.Error("expected the second request to be served from the cache got", requests, "upstream requests")
+	}
+
+	cfg.offline = true
+	if status, _ := get(cfg, "/users"); status != http.StatusOK {
+		t.Error("expected the cached response offline got", status)
+	}
+	if status, _ := get(cfg, "/other"); status != http.StatusGatewayTimeout {
+		t.Error("expected a gateway timeout for uncached responses offline got", status)
+	}
+
+	if _, err := parseFlags(nil, ioutil.Discard); err == nil {
+		t.Error("expected an error without -upstream")
+	}
+}
@@ -0,0 +1,320 @@
+//Command cachedhttp inspects and manages the cache files of CachedHttpClient.FileCache
+//
+//	cachedhttp [flags] <cache file> <command> [arguments]
+//
+//Commands:
+//
+//	keys [query]             lists the entries with their index, method, host and target
+//	show <index|query>       shows status, headers, TLS summary, provenance and a body preview of an entry
+//	delete <index|query>     deletes an entry
+//	purge <query>            deletes all entries whose key contains query
+//	purge-expired            deletes the entries stale for longer than -max-stale
+//	stats                    prints entry count, body bytes and entries per host and status
+//	compact                  rewrites the cache file with the current entries only
+//
+//Entries are selected by the index printed by keys or by a query matching exactly one key
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//bodyPreviewLimit is the number of body bytes printed by show
+const bodyPreviewLimit = 2048
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cachedhttp:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+
+	flags := flag.NewFlagSet("cachedhttp", flag.ContinueOnError)
+	codec := flags.String("codec", "json", "codec of the cache file: json or gob")
+	keyFile := flags.String("key-file", "", "file holding the AES key of an encrypted cache file")
+	keyID := flags.String("key-id", "", "ID of the AES key of -key-file")
+	shared := flags.Bool("shared", false, "apply the freshness rules of a shared cache for purge-expired")
+	maxStale := flags.Duration("max-stale", 0, "how long entries may be stale before purge-expired deletes them")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: cachedhttp [flags] <cache file> keys|show|delete|purge|purge-expired|stats|compact [arguments]")
+		flags.PrintDefaults()
+	}
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return errors.New("missing cache file or command")
+	}
+
+	options, err := fileCacheOptions(*codec, *keyFile, *keyID)
+	if err != nil {
+		return err
+	}
+	cache, err := CachedHttpClient.OpenFileCache(flags.Arg(0), options)
+	if err != nil {
+		return err
+	}
+	command, arguments := flags.Arg(1), flags.Args()[2:]
+
+	switch command {
+	case "keys":
+		return listKeys(stdout, cache, strings.Join(arguments, " "))
+	case "show":
+		key, err := selectKey(cache, arguments)
+		if err != nil {
+			return err
+		}
+		return showEntry(stdout, cache, key)
+	case "delete":
+		key, err := selectKey(cache, arguments)
+		if err != nil {
+			return err
+		}
+		return cache.DeleteKey(key)
+	case "purge":
+		if len(arguments) == 0 {
+			return errors.New("purge needs a query, use purge-expired or delete the cache file to remove all entries")
+		}
+		deleted := 0
+		for _, key := range matchingKeys(cache, strings.Join(arguments, " ")) {
+			err := cache.DeleteKey(key)
+			if err != nil {
+				return err
+			}
+			deleted++
+		}
+		fmt.Fprintln(stdout, "deleted", deleted, "entries")
+		return nil
+	case "purge-expired":
+		deleted, err := CachedHttpClient.PurgeExpired(cache, *shared, *maxStale)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "deleted", deleted, "entries")
+		return nil
+	case "stats":
+		return printStats(stdout, CachedHttpClient.CacheStats(cache))
+	case "compact":
+		return cache.Compact()
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+//fileCacheOptions returns the options to open a cache file written with the named codec and AES key
+func fileCacheOptions(codecName string, keyFile string, keyID string) (CachedHttpClient.FileCacheOptions, error) {
+
+	var codec CachedHttpClient.Codec
+	switch codecName {
+	case "json":
+		codec = CachedHttpClient.JSONCodec
+	case "gob":
+		codec = CachedHttpClient.GobCodec
+	default:
+		return CachedHttpClient.FileCacheOptions{}, fmt.Errorf("unknown codec %q", codecName)
+	}
+
+	if keyFile != "" {
+		key, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return CachedHttpClient.FileCacheOptions{}, err
+		}
+		codec, err = CachedHttpClient.NewEncryptedCodec(codec, CachedHttpClient.EncryptionKeys{CurrentID: keyID, Keys: map[string][]byte{keyID: key}})
+		if err != nil {
+			return CachedHttpClient.FileCacheOptions{}, err
+		}
+	}
+	return CachedHttpClient.FileCacheOptions{Codec: codec}, nil
+}
+
+//matchingKeys returns the keys containing query ignoring the case, all keys for an empty query
+func matchingKeys(cache *CachedHttpClient.FileCache, query string) []string {
+
+	keys := cache.Keys()
+	if query == "" {
+		return keys
+	}
+	query = strings.ToLower(query)
+	var matching []string
+	for _, key := range keys {
+		if strings.Contains(strings.ToLower(key), query) {
+			matching = append(matching, key)
+		}
+	}
+	return matching
+}
+
+//selectKey returns the key with the index printed by keys or the only key matching the query of arguments
+func selectKey(cache *CachedHttpClient.FileCache, arguments []string) (string, error) {
+
+	if len(arguments) == 0 {
+		return "", errors.New("missing the index or a query of the entry")
+	}
+	keys := cache.Keys()
+	if index, err := strconv.Atoi(arguments[0]); err == nil && len(arguments) == 1 {
+		if index < 0 || index >= len(keys) {
+			return "", fmt.Errorf("no entry with index %d, the cache has %d entries", index, len(keys))
+		}
+		return keys[index], nil
+	}
+
+	matching := matchingKeys(cache, strings.Join(arguments, " "))
+	switch len(matching) {
+	case 0:
+		return "", errors.New("no key matches the query")
+	case 1:
+		return matching[0], nil
+	default:
+		return "", fmt.Errorf("%d keys match the query, use the index printed by keys", len(matching))
+	}
+}
+
+//listKeys prints the index and a summary of the keys containing query
+func listKeys(w io.Writer, cache *CachedHttpClient.FileCache, query string) error {
+
+	query = strings.ToLower(query)
+	for index, key := range cache.Keys() {
+		if query != "" && !strings.Contains(strings.ToLower(key), query) {
+			continue
+		}
+		_, err := fmt.Fprintf(w, "%d\t%s\n", index, summarizeKey(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//summarizeKey returns the request line and host of keys in the request dump format, other keys are quoted
+func summarizeKey(key string) string {
+
+	lines := strings.Split(key, "\r\n")
+	fields := strings.Fields(lines[0])
+	if len(lines) < 2 || len(fields) < 2 {
+		return strconv.Quote(key)
+	}
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "Host: ") {
+			return fields[0] + " " + strings.TrimPrefix(line, "Host: ") + fields[1]
+		}
+	}
+	return fields[0] + " " + fields[1]
+}
+
+//showEntry prints the status, headers, TLS summary, provenance and a body preview of the entry stored under key
+func showEntry(w io.Writer, cache *CachedHttpClient.FileCache, key string) error {
+
+	res, err := cache.GetKey(key)
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if res.Body != nil {
+		body, err = ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Key:\n\t%s\n", strings.ReplaceAll(strings.TrimSpace(key), "\r\n", "\n\t"))
+	fmt.Fprintf(&out, "Status: %s %s\n", res.Proto, res.Status)
+	fmt.Fprintln(&out, "Headers:")
+	names := make([]string, 0, len(res.Header))
+	for name := range res.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range res.Header[name] {
+			fmt.Fprintf(&out, "\t%s: %s\n", name, value)
+		}
+	}
+	if res.TLS != nil {
+		fmt.Fprintf(&out, "TLS: %s\n", summarizeTLS(res.TLS))
+	}
+	if provenance, err := cache.Provenance(key); err == nil {
+		fmt.Fprintf(&out, "Provenance: version %s, codec %s, policy %s, host %s, stored %s\n", provenance.Version,
+			provenance.Codec, provenance.PolicyHash, provenance.Hostname, provenance.StoredAt.Format(time.RFC3339))
+	}
+
+	fmt.Fprintf(&out, "Body: %d bytes\n", len(body))
+	preview := body
+	if len(preview) > bodyPreviewLimit {
+		preview = preview[:bodyPreviewLimit]
+	}
+	if utf8.Valid(preview) {
+		out.Write(preview)
+	} else {
+		fmt.Fprintf(&out, "%x", preview)
+	}
+	if len(preview) < len(body) {
+		fmt.Fprintf(&out, "\n... %d more bytes", len(body)-len(preview))
+	}
+	out.WriteString("\n")
+
+	_, err = io.WriteString(w, out.String())
+	return err
+}
+
+//summarizeTLS returns the version, cipher suite, server name and leaf certificate of state
+func summarizeTLS(state *tls.ConnectionState) string {
+
+	versions := map[uint16]string{tls.VersionTLS10: "TLS 1.0", tls.VersionTLS11: "TLS 1.1", tls.VersionTLS12: "TLS 1.2", tls.VersionTLS13: "TLS 1.3"}
+	version, ok := versions[state.Version]
+	if !ok {
+		version = fmt.Sprintf("0x%04x", state.Version)
+	}
+	summary := version + ", " + tls.CipherSuiteName(state.CipherSuite)
+	if state.ServerName != "" {
+		summary += ", server name " + state.ServerName
+	}
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		summary += fmt.Sprintf(", certificate %s issued by %s valid until %s", leaf.Subject.CommonName,
+			leaf.Issuer.CommonName, leaf.NotAfter.Format(time.RFC3339))
+	}
+	return summary
+}
+
+//printStats prints the entry count, body bytes and the entries per host and status of stats
+func printStats(w io.Writer, stats CachedHttpClient.AdminStats) error {
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Entries: %d\nBody bytes: %d\n", stats.Entries, stats.Bytes)
+	for _, group := range []struct {
+		title  string
+		counts map[string]int
+	}{{"Hosts", stats.Hosts}, {"Statuses", stats.Statuses}} {
+		fmt.Fprintf(&out, "%s:\n", group.title)
+		names := make([]string, 0, len(group.counts))
+		for name := range group.counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&out, "\t%s\t%d\n", name, group.counts[name])
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+func TestRun(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cachedhttp")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	cacheFile := filepath.Join(dir, "request.cache")
+
+	cache, err := CachedHttpClient.NewFileCache(cacheFile, CachedHttpClient.FileCacheOptions{RecordProvenance: true})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	keyFunc := CachedHttpClient.NewKeyFunc(CachedHttpClient.KeyOptions{})
+	cache.KeyFunc = keyFunc
+	for _, entry := range []struct {
+		path         string
+		cacheControl string
+	}{{"/fresh", "max-age=3600"}, {"/expired", "max-age=1"}, {"/other", "max-age=3600"}} {
+		request, _ := http.NewRequest("GET", "http://example.com"+entry.path, nil)
+		header := http.Header{}
+		header.Set("Cache-Control", entry.cacheControl)
+		header.Set("Date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		response := &http.Response{Status: "200 OK", StatusCode: http.StatusOK, Proto: "HTTP/1.1", Header: header, Body: ioutil.NopCloser(strings.NewReader("body of " + entry.path))}
+		if err := cache.Set(request, response); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		contains []string
+		err      bool
+	}{
+		{"keys", []string{"keys"}, []string{"0\tGET example.com/expired", "1\tGET example.com/fresh"}, false},
+		{"show by index", []string{"show", "1"}, []string{"Status: HTTP/1.1 200 OK", "Cache-Control: max-age=3600", "Provenance: version", "body of /fresh"}, false},
+		{"show by query", []string{"show", "expired"}, []string{"body of /expired"}, false},
+		{"ambiguous query", []string{"show", "example.com"}, nil, true},
+		{"stats", []string{"stats"}, []string{"Entries: 3", "example.com\t3", "200\t3"}, false},
+		{"purge expired", []string{"purge-expired"}, []string{"deleted 1 entries"}, false},
+		{"delete", []string{"delete", "other"}, nil, false},
+		{"keys after delete", []string{"keys"}, []string{"0\tGET example.com/fresh"}, false},
+		{"compact", []string{"compact"}, nil, false},
+		{"unknown command", []string{"unknown"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := run(append([]string{cacheFile}, tt.args...), &out)
+			if (err != nil) != tt.err {
+				t.Error("expected error", tt.err, "got", err)
+			}
+			for _, expected := range tt.contains {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected %q in %q", expected, out.String())
+				}
+			}
+		})
+	}
+}
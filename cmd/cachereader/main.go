@@ -0,0 +1,61 @@
+//Command cachereader is a C shared library reading the cache files of CachedHttpClient.FileCache, so tools in other
+//languages can look up and decode the entries written by Go services without reimplementing the format. Build it with
+//
+//	go build -buildmode=c-shared -o libcachereader.so ./cmd/cachereader
+//
+//which also writes the header libcachereader.h. The library never writes to the cache files. All functions return a
+//JSON object which the caller releases with CacheReaderFree, it holds Error if the call failed:
+//
+//	CacheReaderOpen(path, codec)                   {"Handle":1} loads the cache file written with the codec "json", "gob" or "wire"
+//	CacheReaderKeys(handle, query)                 {"Keys":[...]} the keys containing query ignoring the case
+//	CacheReaderGet(handle, key)                    {"Entry":{...}} the entry stored under key
+//	CacheReaderLookup(handle, method, url, header) {"Entry":{...}} the entry of a request, header is a JSON object of lists
+//	CacheReaderClose(handle)                       {} releases the loaded cache
+//
+//Entries are encoded like the responses in a cache file written with JSONCodec, the body is base64 encoded
+package main
+
+//#include <stdlib.h>
+import "C"
+
+import (
+	"unsafe"
+)
+
+//export CacheReaderOpen
+func CacheReaderOpen(path *C.char, codec *C.char) *C.char {
+	return cString(openCache(C.GoString(path), C.GoString(codec)))
+}
+
+//export CacheReaderKeys
+func CacheReaderKeys(handle C.longlong, query *C.char) *C.char {
+	return cString(listKeys(int64(handle), C.GoString(query)))
+}
+
+//export CacheReaderGet
+func CacheReaderGet(handle C.longlong, key *C.char) *C.char {
+	return cString(getEntry(int64(handle), C.GoString(key)))
+}
+
+//export CacheReaderLookup
+func CacheReaderLookup(handle C.longlong, method *C.char, url *C.char, header *C.char) *C.char {
+	return cString(lookupEntry(int64(handle), C.GoString(method), C.GoString(url), C.GoString(header)))
+}
+
+//export CacheReaderClose
+func CacheReaderClose(handle C.longlong) *C.char {
+	return cString(closeCache(int64(handle)))
+}
+
+//export CacheReaderFree
+func CacheReaderFree(value *C.char) {
+	C.free(unsafe.Pointer(value))
+}
+
+//cString returns the JSON encoding of result allocated by C
+func cString(result result) *C.char {
+	return C.CString(string(result.encode()))
+}
+
+//main is required by -buildmode=c-shared, it is never called
+func main() {}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+func TestReader(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "cachereader")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	for _, codec := range []string{"json", "gob", "wire"} {
+		t.Run(codec, func(t *testing.T) {
+
+			cacheFile := filepath.Join(dir, codec+".cache")
+			cache, err := CachedHttpClient.NewFileCache(cacheFile, CachedHttpClient.FileCacheOptions{Codec: codecs[codec]})
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			for _, path := range []string{"/a", "/b"} {
+				request, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+				header := http.Header{}
+				header.Set("Cache-Control", "max-age=3600")
+				response := &http.Response{Status: "200 OK", StatusCode: http.StatusOK, Proto: "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1, Header: header, Body: ioutil.NopCloser(strings.NewReader("body of " + path))}
+				if err := cache.Set(request, response); err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+			}
+
+			opened := openCache(cacheFile, codec)
+			if opened.Error != "" {
+				t.Error(opened.Error)
+				t.FailNow()
+			}
+			defer closeCache(opened.Handle)
+
+			keys := listKeys(opened.Handle, "/B")
+			if keys.Error != "" || len(keys.Keys) != 1 {
+				t.Error("expected one key for /b, got", keys)
+				t.FailNow()
+			}
+			entry := getEntry(opened.Handle, keys.Keys[0])
+			if entry.Error != "" || string(entry.Entry.Body) != "body of /b" {
+				t.Error("expected the entry of /b, got", entry)
+			}
+
+			found := lookupEntry(opened.Handle, "GET", "http://example.com/a", `{}`)
+			if found.Error != "" {
+				t.Error(found.Error)
+				t.FailNow()
+			}
+			if string(found.Entry.Body) != "body of /a" || found.Entry.Header.Get("Cache-Control") != "max-age=3600" {
+				t.Error("expected the entry of /a, got", found)
+			}
+
+			if invalid := lookupEntry(opened.Handle, "GET", "http://example.com/a", "["); invalid.Error == "" {
+				t.Error("expected an error for an invalid header, got", invalid)
+			}
+
+			var decoded result
+			if err := json.Unmarshal(found.encode(), &decoded); err != nil || string(decoded.Entry.Body) != "body of /a" {
+				t.Error("expected the encoded entry of /a, got", decoded, err)
+			}
+		})
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		result result
+	}{
+		{"unknown codec", openCache("request.cache", "xml")},
+		{"missing file", openCache(filepath.Join(os.TempDir(), "cachereader-missing.cache"), "json")},
+		{"unknown handle keys", listKeys(-1, "")},
+		{"unknown handle get", getEntry(-1, "key")},
+		{"unknown handle lookup", lookupEntry(-1, "GET", "http://example.com", "")},
+		{"unknown handle close", closeCache(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.result.Error == "" {
+				t.Error("expected an error, got", tt.result)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//result is the JSON object returned by the exported functions
+type result struct {
+	Handle int64                          `json:",omitempty"`
+	Keys   []string                       `json:",omitempty"`
+	Entry  *CachedHttpClient.JsonResponse `json:",omitempty"`
+	Error  string                         `json:",omitempty"`
+}
+
+var unknownHandleError = errors.New("unknown handle")
+
+var (
+	cachesMutex sync.Mutex
+	caches      = map[int64]*CachedHttpClient.MapCache{}
+	lastHandle  int64
+)
+
+func (r result) encode() []byte {
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		encoded, _ = json.Marshal(result{Error: err.Error()})
+	}
+	return encoded
+}
+
+func failed(err error) result {
+	return result{Error: err.Error()}
+}
+
+//codecs are the codecs of the cache files by the name passed to CacheReaderOpen
+var codecs = map[string]CachedHttpClient.Codec{
+	"":     CachedHttpClient.JSONCodec,
+	"json": CachedHttpClient.JSONCodec,
+	"gob":  CachedHttpClient.GobCodec,
+	"wire": CachedHttpClient.WireCodec,
+}
+
+func openCache(path string, codecName string) result {
+
+	codec, ok := codecs[codecName]
+	if !ok {
+		return failed(fmt.Errorf("unknown codec %q", codecName))
+	}
+	cache, err := CachedHttpClient.ReadFileCache(path, CachedHttpClient.FileCacheOptions{Codec: codec})
+	if err != nil {
+		return failed(err)
+	}
+
+	cachesMutex.Lock()
+	defer cachesMutex.Unlock()
+	lastHandle++
+	caches[lastHandle] = cache
+	return result{Handle: lastHandle}
+}
+
+func loadedCache(handle int64) (*CachedHttpClient.MapCache, error) {
+	cachesMutex.Lock()
+	defer cachesMutex.Unlock()
+	cache, ok := caches[handle]
+	if !ok {
+		return nil, unknownHandleError
+	}
+	return cache, nil
+}
+
+func closeCache(handle int64) result {
+	cachesMutex.Lock()
+	defer cachesMutex.Unlock()
+	if _, ok := caches[handle]; !ok {
+		return failed(unknownHandleError)
+	}
+	delete(caches, handle)
+	return result{}
+}
+
+func listKeys(handle int64, query string) result {
+
+	cache, err := loadedCache(handle)
+	if err != nil {
+		return failed(err)
+	}
+	keys := []string{}
+	for _, key := range cache.Keys() {
+		if strings.Contains(strings.ToLower(key), strings.ToLower(query)) {
+			keys = append(keys, key)
+		}
+	}
+	return result{Keys: keys}
+}
+
+func getEntry(handle int64, key string) result {
+
+	cache, err := loadedCache(handle)
+	if err != nil {
+		return failed(err)
+	}
+	return entryResult(cache.GetKey(key))
+}
+
+func lookupEntry(handle int64, method string, url string, header string) result {
+
+	cache, err := loadedCache(handle)
+	if err != nil {
+		return failed(err)
+	}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return failed(err)
+	}
+	if header != "" {
+		if err := json.Unmarshal([]byte(header), &req.Header); err != nil {
+			return failed(err)
+		}
+	}
+	return entryResult(cache.Get(req))
+}
+
+func entryResult(res *http.Response, err error) result {
+	if err != nil {
+		return failed(err)
+	}
+	entry, err := CachedHttpClient.NewJsonResponse(res)
+	if err != nil {
+		return failed(err)
+	}
+	return result{Entry: entry}
+}
@@ -0,0 +1,111 @@
+//Command cachingproxy is a caching forward HTTP proxy, clients configured with it as their HTTP proxy get responses
+//from a shared cache
+//
+//	cachingproxy [-listen :8080] [-cache dir] [-max-bytes n]
+//
+//Responses are stored in a DiskCache below -cache or in memory if it is empty. HTTPS requests tunneled with CONNECT
+//can not be cached and are rejected
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//hopByHopHeaders are not forwarded by the proxy (RFC 7230 6.1)
+var hopByHopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+func main() {
+
+	flags := flag.NewFlagSet("cachingproxy", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "address to listen on")
+	dir := flags.String("cache", "", "directory of the cache, in memory if empty")
+	maxBytes := flags.Int64("max-bytes", 256<<20, "maximum body bytes of the in-memory cache")
+	_ = flags.Parse(os.Args[1:])
+
+	cache, err := newCache(*dir, *maxBytes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cachingproxy:", err)
+		os.Exit(1)
+	}
+	transport := &CachedHttpClient.CachedTransport{Cache: cache, Fallback: http.DefaultTransport, Shared: true}
+	log.Printf("cachingproxy listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, newProxy(transport)))
+}
+
+//newCache returns a DiskCache below dir or an LRUCache limited to maxBytes if dir is empty. The keys ignore the
+//request headers so all clients share the responses, Vary is still honored
+func newCache(dir string, maxBytes int64) (CachedHttpClient.Cacher, error) {
+	keyFunc := CachedHttpClient.NewKeyFunc(CachedHttpClient.KeyOptions{})
+	if dir == "" {
+		return CachedHttpClient.NewLRUCache(CachedHttpClient.LRUCacheOptions{MapCacheOptions: CachedHttpClient.MapCacheOptions{KeyFunc: keyFunc}, MaxBytes: maxBytes}), nil
+	}
+	return CachedHttpClient.NewDiskCache(dir, CachedHttpClient.DiskCacheOptions{MapCacheOptions: CachedHttpClient.MapCacheOptions{KeyFunc: keyFunc}, Shared: true})
+}
+
+//newProxy returns the handler forwarding the proxy requests of clients with transport
+func newProxy(transport http.RoundTripper) http.Handler {
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+
+		if req.Method == http.MethodConnect {
+			http.Error(writer, "CONNECT is not supported, HTTPS responses can not be cached", http.StatusMethodNotAllowed)
+			return
+		}
+		if !req.URL.IsAbs() {
+			http.Error(writer, "not a proxy request, the request target must be an absolute URL", http.StatusBadRequest)
+			return
+		}
+
+		outgoing, err := http.NewRequestWithContext(req.Context(), req.Method, req.URL.String(), req.Body)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.ContentLength == 0 {
+			outgoing.Body = nil
+		}
+		outgoing.ContentLength = req.ContentLength
+		outgoing.Header = req.Header.Clone()
+		removeHopByHop(outgoing.Header)
+
+		response, err := transport.RoundTrip(outgoing)
+		if err != nil {
+			status := http.StatusBadGateway
+			if errors.Is(err, CachedHttpClient.LoadSheddingError) {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(writer, err.Error(), status)
+			return
+		}
+		defer response.Body.Close()
+
+		removeHopByHop(response.Header)
+		for field, values := range response.Header {
+			writer.Header()[field] = values
+		}
+		writer.WriteHeader(response.StatusCode)
+		_, _ = io.Copy(writer, response.Body)
+	})
+}
+
+//removeHopByHop removes the hop-by-hop fields and the fields named in Connection from header
+func removeHopByHop(header http.Header) {
+	for _, line := range header["Connection"] {
+		for _, field := range strings.Split(line, ",") {
+			header.Del(strings.TrimSpace(field))
+		}
+	}
+	for _, field := range hopByHopHeaders {
+		header.Del(field)
+	}
+}
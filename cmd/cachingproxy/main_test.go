@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+func TestProxy(t *testing.T) {
+
+	requests := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.Header.Get("Proxy-Connection") != "" {
+			t.Error("expected hop-by-hop fields not to be forwarded")
+		}
+		writer.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprint(writer, "origin response")
+	}))
+	defer origin.Close()
+
+	cache, err := newCache("", 1<<20)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	proxy := httptest.NewServer(newProxy(&CachedHttpClient.CachedTransport{Cache: cache, Fallback: http.DefaultTransport, Shared: true}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(origin.URL)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if string(body) != "origin response" {
+			t.Error("expected the origin response got", string(body))
+		}
+	}
+	if requests != 1 {
+		t.Error("expected the second request to be served from the cache got", requests, "origin requests")
+	}
+
+	response, err := http.Get(proxy.URL + "/relative")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Error("expected requests which are no proxy requests to be rejected got", response.StatusCode)
+	}
+}
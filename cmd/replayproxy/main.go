@@ -0,0 +1,134 @@
+//Command replayproxy records the interactions with an upstream in a cassette and replays them later without the
+//upstream, e.g. to run the integration tests of an application in any language against recorded fixtures
+//
+//	replayproxy -mode record -upstream https://api.example.com -cassette fixtures.json [-listen :8080]
+//	replayproxy -mode replay -upstream https://api.example.com -cassette fixtures.json [-listen :8080]
+//
+//In record mode the cassette is written after every interaction. In replay mode requests without a recorded
+//interaction get a 502 response describing the differences to the closest recorded request, GET /_replay/used
+//answers 200 once every interaction was replayed and 409 before
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//usedPath reports if the replayed cassette was used completely
+const usedPath = "/_replay/used"
+
+//config are the settings of the proxy
+type config struct {
+	listen   string
+	mode     string
+	upstream *url.URL
+	cassette string
+}
+
+//roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replayproxy:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+
+	cfg, err := parseFlags(args, os.Stderr)
+	if err != nil {
+		return err
+	}
+	handler, err := newHandler(cfg, http.DefaultTransport)
+	if err != nil {
+		return err
+	}
+	log.Printf("replayproxy in %s mode for %s listening on %s", cfg.mode, cfg.upstream, cfg.listen)
+	return http.ListenAndServe(cfg.listen, handler)
+}
+
+func parseFlags(args []string, output io.Writer) (*config, error) {
+
+	flags := flag.NewFlagSet("replayproxy", flag.ContinueOnError)
+	flags.SetOutput(output)
+	cfg := &config{}
+	upstream := flags.String("upstream", "", "URL of the recorded upstream")
+	flags.StringVar(&cfg.mode, "mode", "replay", "record or replay")
+	flags.StringVar(&cfg.cassette, "cassette", "cassette.json", "file of the cassette")
+	flags.StringVar(&cfg.listen, "listen", ":8080", "address to listen on")
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.mode != "record" && cfg.mode != "replay" {
+		return nil, fmt.Errorf("unknown mode %q", cfg.mode)
+	}
+	if *upstream == "" {
+		return nil, errors.New("missing -upstream")
+	}
+	cfg.upstream, err = url.Parse(*upstream)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+//newHandler returns the handler recording the interactions with the upstream using fallback or replaying them
+func newHandler(cfg *config, fallback http.RoundTripper) (http.Handler, error) {
+
+	proxy := httputil.NewSingleHostReverseProxy(cfg.upstream)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = cfg.upstream.Host
+		//the client address differs between recording and replaying
+		req.Header.Del("X-Forwarded-For")
+	}
+	proxy.ErrorHandler = func(writer http.ResponseWriter, req *http.Request, err error) {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+	}
+
+	if cfg.mode == "record" {
+		recorder := CachedHttpClient.NewRecorder(cfg.cassette, fallback)
+		proxy.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res, err := recorder.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			return res, recorder.Save()
+		})
+		return proxy, nil
+	}
+
+	replayer, err := CachedHttpClient.OpenReplayer(cfg.cassette)
+	if err != nil {
+		return nil, err
+	}
+	proxy.Transport = replayer
+	mux := http.NewServeMux()
+	mux.Handle("/", proxy)
+	mux.HandleFunc(usedPath, func(writer http.ResponseWriter, req *http.Request) {
+		if !replayer.Used() {
+			http.Error(writer, "not every interaction was replayed", http.StatusConflict)
+			return
+		}
+		fmt.Fprintln(writer, "every interaction was replayed")
+	})
+	return mux, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "replayproxy")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	cassette := filepath.Join(dir, "cassette.json")
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(writer, "recorded ", req.URL.Path)
+	}))
+
+	serve := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		return recorder
+	}
+
+	cfg, err := parseFlags([]string{"-mode", "record", "-upstream", upstream.URL, "-cassette", cassette}, ioutil.Discard)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	recording, err := newHandler(cfg, http.DefaultTransport)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, path := range []string{"/a", "/b"} {
+		if response := serve(recording, path); response.Body.String() != "recorded "+path {
+			t.Error("expected the upstream response got", response.Code, response.Body.String())
+		}
+	}
+	upstream.Close()
+
+	cfg.mode = "replay"
+	replaying, err := newHandler(cfg, http.DefaultTransport)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if response := serve(replaying, "/a"); response.Body.String() != "recorded /a" {
+		t.Error("expected the recorded response got", response.Code, response.Body.String())
+	}
+	if response := serve(replaying, usedPath); response.Code != http.StatusConflict {
+		t.Error("expected the cassette not to be used completely got", response.Code)
+	}
+	if response := serve(replaying, "/b"); response.Body.String() != "recorded /b" {
+		t.Error("expected the recorded response got", response.Code, response.Body.String())
+	}
+	if response := serve(replaying, usedPath); response.Code != http.StatusOK {
+		t.Error("expected the cassette to be used completely got", response.Code)
+	}
+	if response := serve(replaying, "/c"); response.Code != http.StatusBadGateway || !strings.Contains(response.Body.String(), "closest recorded request") {
+		t.Error("expected the differences to the closest recorded request got", response.Code, response.Body.String())
+	}
+
+	if _, err := parseFlags([]string{"-mode", "other", "-upstream", "http://example.com"}, ioutil.Discard); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
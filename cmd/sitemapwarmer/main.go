@@ -0,0 +1,141 @@
+//Command sitemapwarmer warms a DiskCache with the pages listed in sitemaps, e.g. before a mirror built on the same
+//cache directory goes live. Sitemap indexes are followed
+//
+//	sitemapwarmer [-cache dir] [-parallelism 8] [-force] <sitemap URL>...
+//
+//Every URL is printed with its status and duration, the command fails if a URL could not be fetched
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//maxSitemaps limits the sitemaps read including those listed in sitemap indexes
+const maxSitemaps = 1000
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, http.DefaultTransport)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sitemapwarmer:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer, fallback http.RoundTripper) error {
+
+	flags := flag.NewFlagSet("sitemapwarmer", flag.ContinueOnError)
+	dir := flags.String("cache", "sitemapwarmer-cache", "directory of the cache")
+	parallelism := flags.Int("parallelism", 8, "maximum number of concurrent requests")
+	force := flags.Bool("force", false, "fetch the pages even if the cache has fresh responses")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("missing sitemap URL")
+	}
+
+	keyFunc := CachedHttpClient.NewKeyFunc(CachedHttpClient.KeyOptions{})
+	cache, err := CachedHttpClient.NewDiskCache(*dir, CachedHttpClient.DiskCacheOptions{MapCacheOptions: CachedHttpClient.MapCacheOptions{KeyFunc: keyFunc}})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Transport: &CachedHttpClient.CachedTransport{Cache: cache, Fallback: fallback}}
+
+	ctx := context.Background()
+	//the sitemaps themselves are fetched past the cache so changes are seen
+	urls, err := readSitemaps(ctx, &http.Client{Transport: fallback}, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	prefetcher := CachedHttpClient.NewPrefetcher(client, *parallelism)
+	prefetcher.ForceRefresh = *force
+	failed := 0
+	for _, result := range prefetcher.Prefetch(ctx, urls) {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(stdout, "%s\terror: %v\n", result.URL, result.Err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%d\t%s\n", result.URL, result.StatusCode, result.Duration)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d URLs failed", failed, len(urls))
+	}
+	return nil
+}
+
+//sitemap is a urlset or a sitemapindex (sitemaps.org protocol)
+type sitemap struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+//readSitemaps returns the page URLs listed in the sitemaps, the sitemaps listed in sitemap indexes are read too
+func readSitemaps(ctx context.Context, client *http.Client, sitemapURLs []string) ([]string, error) {
+
+	var urls []string
+	seen := map[string]bool{}
+	queue := append([]string(nil), sitemapURLs...)
+	for len(queue) > 0 {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seen[sitemapURL] {
+			continue
+		}
+		if len(seen) >= maxSitemaps {
+			return nil, fmt.Errorf("more than %d sitemaps", maxSitemaps)
+		}
+		seen[sitemapURL] = true
+
+		parsed, err := fetchSitemap(ctx, client, sitemapURL)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range parsed.URLs {
+			urls = append(urls, entry.Loc)
+		}
+		for _, entry := range parsed.Sitemaps {
+			queue = append(queue, entry.Loc)
+		}
+	}
+	return urls, nil
+}
+
+func fetchSitemap(ctx context.Context, client *http.Client, sitemapURL string) (*sitemap, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sitemap %s: %s", sitemapURL, response.Status)
+	}
+
+	var parsed sitemap
+	err = xml.NewDecoder(response.Body).Decode(&parsed)
+	if err != nil {
+		return nil, fmt.Errorf("sitemap %s: %w", sitemapURL, err)
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "sitemapwarmer")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	var mutex sync.Mutex
+	pages := map[string]int{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(writer, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><sitemap><loc>%s/pages.xml</loc></sitemap></sitemapindex>`, server.URL)
+		case "/pages.xml":
+			fmt.Fprintf(writer, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>%[1]s/a</loc></url><url><loc>%[1]s/b</loc></url></urlset>`, server.URL)
+		default:
+			mutex.Lock()
+			pages[req.URL.Path]++
+			mutex.Unlock()
+			writer.Header().Set("Cache-Control", "max-age=60")
+			fmt.Fprint(writer, "page")
+		}
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		var out strings.Builder
+		err := run([]string{"-cache", dir, server.URL + "/sitemap.xml"}, &out, http.DefaultTransport)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		if !strings.Contains(out.String(), server.URL+"/a\t200") || !strings.Contains(out.String(), server.URL+"/b\t200") {
+			t.Error("expected the results of both pages got", out.String())
+		}
+	}
+	if pages["/a"] != 1 || pages["/b"] != 1 {
+		t.Error("expected the pages to be fetched once and then served from the cache got", pages)
+	}
+
+	if err := run([]string{"-cache", dir, server.URL + "/a"}, ioutil.Discard, http.DefaultTransport); err == nil {
+		t.Error("expected an error for a page which is no sitemap")
+	}
+	if err := run([]string{"-cache", dir}, ioutil.Discard, http.DefaultTransport); err == nil {
+		t.Error("expected an error without sitemap URL")
+	}
+}
@@ -0,0 +1,202 @@
+//Package grpcadmin serves the cache management API of proto/cache_admin.proto over gRPC. It is a separate module so
+//CachedHttpClient stays free of the gRPC dependencies
+package grpcadmin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+	"github.com/Scax/CachedHttpClient-Go/grpcadmin/adminpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//Server implements the CacheAdmin service by calling the methods of Service with the same name, with the context of
+//each call passed to Service.Authorize
+type Server struct {
+	adminpb.UnimplementedCacheAdminServer
+	Service *CachedHttpClient.AdminService
+}
+
+//NewServer creates a Server for service
+func NewServer(service *CachedHttpClient.AdminService) *Server {
+	return &Server{Service: service}
+}
+
+//Register registers a Server for service with registrar, e.g. a grpc.Server
+func Register(registrar grpc.ServiceRegistrar, service *CachedHttpClient.AdminService) {
+	adminpb.RegisterCacheAdminServer(registrar, NewServer(service))
+}
+
+func (s *Server) List(ctx context.Context, req *adminpb.ListRequest) (*adminpb.ListResponse, error) {
+
+	page, err := s.Service.WithContext(ctx).ListPage(req.GetQuery(), req.GetCursor(), int(req.GetLimit()))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	res := &adminpb.ListResponse{NextCursor: page.Next, TotalEstimate: int64(page.Total)}
+	for _, key := range page.Keys {
+		res.Keys = append(res.Keys, toKey(key))
+	}
+	return res, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *adminpb.GetRequest) (*adminpb.Entry, error) {
+
+	res, err := s.Service.WithContext(ctx).Get(req.GetKey())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	entry, err := toEntry(req.GetKey(), res)
+	if res.Body != nil {
+		_ = res.Body.Close()
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return entry, nil
+}
+
+func (s *Server) Put(ctx context.Context, req *adminpb.PutRequest) (*adminpb.PutResponse, error) {
+
+	request, err := http.NewRequestWithContext(ctx, req.GetMethod(), req.GetUrl(), nil)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	request.Header = toHeader(req.GetRequestHeaders())
+	code := int(req.GetStatusCode())
+	if code < 100 || code > 999 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status code %d", code)
+	}
+	res := &http.Response{
+		Status:        fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode:    code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        toHeader(req.GetHeaders()),
+		Body:          ioutil.NopCloser(bytes.NewReader(req.GetBody())),
+		ContentLength: int64(len(req.GetBody())),
+		Request:       request,
+	}
+
+	key, err := s.Service.WithContext(ctx).Put(request, res)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &adminpb.PutResponse{Key: key}, nil
+}
+
+//Invalidate deletes all keys only with All set, an empty Query fails with InvalidArgument otherwise
+func (s *Server) Invalidate(ctx context.Context, req *adminpb.InvalidateRequest) (*adminpb.InvalidateResponse, error) {
+
+	service := s.Service.WithContext(ctx)
+	var deleted int
+	var err error
+	switch {
+	case req.GetAll() && req.GetQuery() != "":
+		return nil, status.Error(codes.InvalidArgument, "all deletes every key, the query has to be empty")
+	case req.GetAll():
+		deleted, err = service.InvalidateAll()
+	default:
+		deleted, err = service.Invalidate(req.GetQuery())
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &adminpb.InvalidateResponse{Deleted: int64(deleted)}, nil
+}
+
+func (s *Server) Stats(ctx context.Context, _ *adminpb.StatsRequest) (*adminpb.StatsResponse, error) {
+
+	stats := s.Service.WithContext(ctx).Stats()
+	return &adminpb.StatsResponse{
+		Entries:  int64(stats.Entries),
+		Bytes:    stats.Bytes,
+		Hosts:    toCounts(stats.Hosts),
+		Statuses: toCounts(stats.Statuses),
+		Variants: toCounts(stats.Variants),
+	}, nil
+}
+
+func (s *Server) Export(req *adminpb.ExportRequest, stream adminpb.CacheAdmin_ExportServer) error {
+
+	err := s.Service.WithContext(stream.Context()).Export(req.GetQuery(), func(key string, res *http.Response) error {
+		entry, err := toEntry(key, res)
+		if err != nil {
+			return err
+		}
+		return stream.Send(entry)
+	})
+	return statusError(err)
+}
+
+//statusError maps the errors of AdminService to the status codes of gRPC, other errors are returned as they are
+func statusError(err error) error {
+
+	switch err {
+	case nil:
+		return nil
+	case CachedHttpClient.NotInCacheError:
+		return status.Error(codes.NotFound, err.Error())
+	case CachedHttpClient.AdminForbiddenError:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case CachedHttpClient.AdminPurgeAllError, CachedHttpClient.InvalidCursorError:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return err
+}
+
+func toKey(key string) *adminpb.Key {
+	method, target, host := CachedHttpClient.SummarizeKey(key)
+	return &adminpb.Key{Key: key, Method: method, Target: target, Host: host}
+}
+
+//toEntry reads the body of res, the caller closes it
+func toEntry(key string, res *http.Response) (*adminpb.Entry, error) {
+
+	entry := &adminpb.Entry{Key: toKey(key), StatusCode: int32(res.StatusCode), Status: res.Status, Proto: res.Proto}
+	names := make([]string, 0, len(res.Header))
+	for name := range res.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		entry.Headers = append(entry.Headers, &adminpb.Header{Name: name, Values: res.Header[name]})
+	}
+	if res.Body != nil {
+		body, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+		entry.Body = body
+	}
+	return entry, nil
+}
+
+func toHeader(headers []*adminpb.Header) http.Header {
+	header := http.Header{}
+	for _, h := range headers {
+		for _, value := range h.GetValues() {
+			header.Add(h.GetName(), value)
+		}
+	}
+	return header
+}
+
+func toCounts(counts map[string]int) map[string]int64 {
+	if counts == nil {
+		return nil
+	}
+	converted := make(map[string]int64, len(counts))
+	for name, count := range counts {
+		converted[name] = int64(count)
+	}
+	return converted
+}
@@ -0,0 +1,141 @@
+package grpcadmin
+
+import (
+	"context"
+	"io"
+	"net"
+	"regexp"
+	"testing"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+	"github.com/Scax/CachedHttpClient-Go/grpcadmin/adminpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testClient(t *testing.T, service *CachedHttpClient.AdminService) (adminpb.CacheAdminClient, func()) {
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	Register(server, service)
+	go func() { _ = server.Serve(listener) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+	if err != nil {
+		server.Stop()
+		t.Fatal(err)
+	}
+	return adminpb.NewCacheAdminClient(conn), func() {
+		_ = conn.Close()
+		server.Stop()
+	}
+}
+
+func TestServer(t *testing.T) {
+
+	client, stop := testClient(t, CachedHttpClient.NewAdminService(CachedHttpClient.NewLRUCache(CachedHttpClient.LRUCacheOptions{})))
+	defer stop()
+	ctx := context.Background()
+
+	keys := map[string]string{}
+	for _, path := range []string{"/a", "/b", "/other"} {
+		res, err := client.Put(ctx, &adminpb.PutRequest{Method: "GET", Url: "http://example.com" + path, StatusCode: 200,
+			Headers: []*adminpb.Header{{Name: "Content-Type", Values: []string{"text/plain"}}}, Body: []byte("body of " + path)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[path] = res.GetKey()
+	}
+	if _, err := client.Put(ctx, &adminpb.PutRequest{Method: "GET", Url: "http://example.com/", StatusCode: 0}); status.Code(err) != codes.InvalidArgument {
+		t.Error("expected an invalid status code to be rejected, got", err)
+	}
+
+	list, err := client.List(ctx, &adminpb.ListRequest{Query: "example.com", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetKeys()) != 2 || list.GetNextCursor() == "" || list.GetTotalEstimate() != 3 {
+		t.Errorf("unexpected first page %v", list)
+	}
+	if key := list.GetKeys()[0]; key.GetMethod() != "GET" || key.GetTarget() != "/a" || key.GetHost() != "example.com" {
+		t.Errorf("expected the summary of the key, got %v", key)
+	}
+	if _, err := client.List(ctx, &adminpb.ListRequest{Cursor: "not a cursor!"}); status.Code(err) != codes.InvalidArgument {
+		t.Error("expected an invalid cursor to be rejected, got", err)
+	}
+
+	entry, err := client.Get(ctx, &adminpb.GetRequest{Key: keys["/a"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(entry.GetBody()) != "body of /a" || entry.GetStatusCode() != 200 || entry.GetHeaders()[0].GetName() != "Content-Type" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+	if _, err := client.Get(ctx, &adminpb.GetRequest{Key: "missing"}); status.Code(err) != codes.NotFound {
+		t.Error("expected NotFound, got", err)
+	}
+
+	stats, err := client.Stats(ctx, &adminpb.StatsRequest{})
+	if err != nil || stats.GetEntries() != 3 || stats.GetHosts()["example.com"] != 3 {
+		t.Errorf("unexpected stats %v %v", stats, err)
+	}
+
+	stream, err := client.Export(ctx, &adminpb.ExportRequest{Query: "/other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := 0
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(entry.GetBody()) != "body of /other" {
+			t.Errorf("unexpected exported entry %v", entry)
+		}
+		exported++
+	}
+	if exported != 1 {
+		t.Error("expected 1 exported entry, got", exported)
+	}
+
+	if _, err := client.Invalidate(ctx, &adminpb.InvalidateRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Error("expected an empty query to be rejected, got", err)
+	}
+	if _, err := client.Invalidate(ctx, &adminpb.InvalidateRequest{Query: "/a", All: true}); status.Code(err) != codes.InvalidArgument {
+		t.Error("expected a query with all to be rejected, got", err)
+	}
+	if res, err := client.Invalidate(ctx, &adminpb.InvalidateRequest{Query: "/other"}); err != nil || res.GetDeleted() != 1 {
+		t.Error("expected 1 deleted key, got", res, err)
+	}
+	if res, err := client.Invalidate(ctx, &adminpb.InvalidateRequest{All: true}); err != nil || res.GetDeleted() != 2 {
+		t.Error("expected the remaining keys to be deleted, got", res, err)
+	}
+}
+
+func TestServer_Authorize(t *testing.T) {
+
+	cache := CachedHttpClient.NewLRUCache(CachedHttpClient.LRUCacheOptions{})
+	service := CachedHttpClient.NewAdminService(cache)
+	client, stop := testClient(t, service)
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := client.Put(ctx, &adminpb.PutRequest{Method: "GET", Url: "http://example.com/", StatusCode: 200}); err != nil {
+		t.Fatal(err)
+	}
+	service.Authorize = CachedHttpClient.AdminRules(CachedHttpClient.AdminRule{
+		Operations: []CachedHttpClient.AdminOperation{CachedHttpClient.AdminPutOperation},
+		Keys:       regexp.MustCompile(`\r\nHost: api\.example\.com\r\n`),
+	})
+	if _, err := client.Put(ctx, &adminpb.PutRequest{Method: "GET", Url: "http://example.com/", StatusCode: 200}); status.Code(err) != codes.PermissionDenied {
+		t.Error("expected PermissionDenied, got", err)
+	}
+}
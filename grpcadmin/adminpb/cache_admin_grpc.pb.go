@@ -0,0 +1,331 @@
+// Cache management API of CachedHttpClient, implemented by CachedHttpClient.AdminService.
+// The code generated from this file (protoc-gen-go, protoc-gen-go-grpc) is in the grpcadmin module, its server
+// delegates each call to the method of AdminService with the same name.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: cache_admin.proto
+
+package adminpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CacheAdmin_List_FullMethodName       = "/cachedhttpclient.admin.v1.CacheAdmin/List"
+	CacheAdmin_Get_FullMethodName        = "/cachedhttpclient.admin.v1.CacheAdmin/Get"
+	CacheAdmin_Put_FullMethodName        = "/cachedhttpclient.admin.v1.CacheAdmin/Put"
+	CacheAdmin_Invalidate_FullMethodName = "/cachedhttpclient.admin.v1.CacheAdmin/Invalidate"
+	CacheAdmin_Stats_FullMethodName      = "/cachedhttpclient.admin.v1.CacheAdmin/Stats"
+	CacheAdmin_Export_FullMethodName     = "/cachedhttpclient.admin.v1.CacheAdmin/Export"
+)
+
+// CacheAdminClient is the client API for CacheAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CacheAdminClient interface {
+	// List returns a page of the keys containing query, all keys for an empty query (AdminService.ListPage).
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Get returns the entry stored under key.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Entry, error)
+	// Put stores response for the request and returns its key.
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
+	// Invalidate deletes the keys containing query, all keys only with all set.
+	Invalidate(ctx context.Context, in *InvalidateRequest, opts ...grpc.CallOption) (*InvalidateResponse, error)
+	// Stats summarizes the entries.
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Export streams the entries of the keys containing query.
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entry], error)
+}
+
+type cacheAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCacheAdminClient(cc grpc.ClientConnInterface) CacheAdminClient {
+	return &cacheAdminClient{cc}
+}
+
+func (c *cacheAdminClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, CacheAdmin_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAdminClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, CacheAdmin_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAdminClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PutResponse)
+	err := c.cc.Invoke(ctx, CacheAdmin_Put_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAdminClient) Invalidate(ctx context.Context, in *InvalidateRequest, opts ...grpc.CallOption) (*InvalidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InvalidateResponse)
+	err := c.cc.Invoke(ctx, CacheAdmin_Invalidate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAdminClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, CacheAdmin_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheAdminClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Entry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CacheAdmin_ServiceDesc.Streams[0], CacheAdmin_Export_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExportRequest, Entry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CacheAdmin_ExportClient = grpc.ServerStreamingClient[Entry]
+
+// CacheAdminServer is the server API for CacheAdmin service.
+// All implementations must embed UnimplementedCacheAdminServer
+// for forward compatibility.
+type CacheAdminServer interface {
+	// List returns a page of the keys containing query, all keys for an empty query (AdminService.ListPage).
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Get returns the entry stored under key.
+	Get(context.Context, *GetRequest) (*Entry, error)
+	// Put stores response for the request and returns its key.
+	Put(context.Context, *PutRequest) (*PutResponse, error)
+	// Invalidate deletes the keys containing query, all keys only with all set.
+	Invalidate(context.Context, *InvalidateRequest) (*InvalidateResponse, error)
+	// Stats summarizes the entries.
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Export streams the entries of the keys containing query.
+	Export(*ExportRequest, grpc.ServerStreamingServer[Entry]) error
+	mustEmbedUnimplementedCacheAdminServer()
+}
+
+// UnimplementedCacheAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCacheAdminServer struct{}
+
+func (UnimplementedCacheAdminServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedCacheAdminServer) Get(context.Context, *GetRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCacheAdminServer) Put(context.Context, *PutRequest) (*PutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
+func (UnimplementedCacheAdminServer) Invalidate(context.Context, *InvalidateRequest) (*InvalidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Invalidate not implemented")
+}
+func (UnimplementedCacheAdminServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedCacheAdminServer) Export(*ExportRequest, grpc.ServerStreamingServer[Entry]) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedCacheAdminServer) mustEmbedUnimplementedCacheAdminServer() {}
+func (UnimplementedCacheAdminServer) testEmbeddedByValue()                    {}
+
+// UnsafeCacheAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CacheAdminServer will
+// result in compilation errors.
+type UnsafeCacheAdminServer interface {
+	mustEmbedUnimplementedCacheAdminServer()
+}
+
+func RegisterCacheAdminServer(s grpc.ServiceRegistrar, srv CacheAdminServer) {
+	// If the following call pancis, it indicates UnimplementedCacheAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CacheAdmin_ServiceDesc, srv)
+}
+
+func _CacheAdmin_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAdminServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheAdmin_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAdminServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAdmin_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAdminServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheAdmin_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAdminServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAdmin_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAdminServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheAdmin_Put_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAdminServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAdmin_Invalidate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InvalidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAdminServer).Invalidate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheAdmin_Invalidate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAdminServer).Invalidate(ctx, req.(*InvalidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAdmin_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheAdminServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheAdmin_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheAdminServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheAdmin_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheAdminServer).Export(m, &grpc.GenericServerStream[ExportRequest, Entry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CacheAdmin_ExportServer = grpc.ServerStreamingServer[Entry]
+
+// CacheAdmin_ServiceDesc is the grpc.ServiceDesc for CacheAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CacheAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cachedhttpclient.admin.v1.CacheAdmin",
+	HandlerType: (*CacheAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _CacheAdmin_List_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _CacheAdmin_Get_Handler,
+		},
+		{
+			MethodName: "Put",
+			Handler:    _CacheAdmin_Put_Handler,
+		},
+		{
+			MethodName: "Invalidate",
+			Handler:    _CacheAdmin_Invalidate_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _CacheAdmin_Stats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Export",
+			Handler:       _CacheAdmin_Export_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cache_admin.proto",
+}
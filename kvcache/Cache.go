@@ -0,0 +1,205 @@
+//Package kvcache caches byte values in a key value store like Redis under a prefix per namespace, the keys expire in
+//the store. The KVCache of CachedHttpClient stores its responses with it, applications cache the results of other
+//computations beside them in the same store with a Namespace and read them through with GetOrLoad
+package kvcache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//Store is a remote key value store like Redis or Memcached. The package memcache implements it for Memcached, other
+//stores are implemented by a small adapter around the client of the application. The methods give up once ctx is done
+type Store interface {
+	//MGet returns the values of keys in their order, nil for missing keys. All keys are read in one round trip and
+	//atomically, e.g. with MGET
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	//MSet stores values atomically, e.g. with SET in a MULTI transaction. The keys expire after ttl, they do not
+	//expire if ttl is 0
+	MSet(ctx context.Context, values map[string][]byte, ttl time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	//Scan returns the keys starting with prefix
+	Scan(ctx context.Context, prefix string) ([]string, error)
+}
+
+//ScanReporter is implemented by stores which can not always Scan, e.g. memcached. Stores without it are assumed to
+//support Scan
+type ScanReporter interface {
+	SupportsScan() bool
+}
+
+//NotFoundError is returned by Get for keys without a value
+var NotFoundError = errors.New("key not found")
+
+//Cache stores values in a Store with Prefix prepended to their keys. Cache is a Store itself, the keys passed to it
+//and returned by Scan are without Prefix. All methods are safe for concurrent use
+type Cache struct {
+	//the counters are accessed atomically and kept first for the 64-bit alignment atomic requires on 32-bit platforms
+	hits        int64
+	misses      int64
+	loads       int64
+	loadErrors  int64
+	storeErrors int64
+
+	store  Store
+	prefix string
+
+	loadsMutex sync.Mutex
+	//loading holds the loads in progress by key
+	loading map[string]*pendingLoad
+}
+
+//Stats holds the counters of a Cache
+type Stats struct {
+	//Hits and Misses are the keys read by Get and GetOrLoad which had a value or not
+	Hits   int64
+	Misses int64
+	//Loads are the calls of the load functions of GetOrLoad, LoadErrors the ones failing
+	Loads      int64
+	LoadErrors int64
+	//StoreErrors are the failed reads and writes of GetOrLoad which did not fail the call
+	StoreErrors int64
+}
+
+//pendingLoad is a call of a load function other callers for the same key wait for
+type pendingLoad struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+//New creates a Cache storing its values in store under prefix
+func New(store Store, prefix string) *Cache {
+	return &Cache{store: store, prefix: prefix, loading: map[string]*pendingLoad{}}
+}
+
+//Namespace returns a Cache for the keys under name in the same Store, e.g. to keep the values of a computation apart
+//from HTTP responses. It has its own Stats
+func (c *Cache) Namespace(name string) *Cache {
+	return New(c.store, c.prefix+name+":")
+}
+
+//Prefix returns the prefix of the keys in the Store
+func (c *Cache) Prefix() string {
+	return c.prefix
+}
+
+//SupportsScan reports if the Store can Scan, see ScanReporter
+func (c *Cache) SupportsScan() bool {
+	reporter, ok := c.store.(ScanReporter)
+	return !ok || reporter.SupportsScan()
+}
+
+//Stats returns a snapshot of the counters
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:        atomic.LoadInt64(&c.hits),
+		Misses:      atomic.LoadInt64(&c.misses),
+		Loads:       atomic.LoadInt64(&c.loads),
+		LoadErrors:  atomic.LoadInt64(&c.loadErrors),
+		StoreErrors: atomic.LoadInt64(&c.storeErrors),
+	}
+}
+
+func (c *Cache) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.store.MGet(ctx, prefixed...)
+}
+
+func (c *Cache) MSet(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	prefixed := make(map[string][]byte, len(values))
+	for key, value := range values {
+		prefixed[c.prefix+key] = value
+	}
+	return c.store.MSet(ctx, prefixed, ttl)
+}
+
+func (c *Cache) Del(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.store.Del(ctx, prefixed...)
+}
+
+func (c *Cache) Scan(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := c.store.Scan(ctx, c.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, c.prefix)
+	}
+	return keys, nil
+}
+
+//Get returns the value of key, NotFoundError if it has none
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	values, err := c.MGet(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, NotFoundError
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return values[0], nil
+}
+
+//Set stores value under key, it expires after ttl or never if ttl is 0
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if value == nil {
+		value = []byte{}
+	}
+	return c.MSet(ctx, map[string][]byte{key: value}, ttl)
+}
+
+//GetOrLoad returns the value of key, on a miss it is loaded with load and stored for ttl. Concurrent calls for the
+//same key wait for a single load. Errors of the Store do not fail the call, the value is loaded and the error is
+//counted in Stats.StoreErrors. Errors of load are returned and not cached
+func (c *Cache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, load func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if err != NotFoundError {
+		atomic.AddInt64(&c.storeErrors, 1)
+	}
+
+	c.loadsMutex.Lock()
+	if call, ok := c.loading[key]; ok {
+		c.loadsMutex.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &pendingLoad{done: make(chan struct{})}
+	c.loading[key] = call
+	c.loadsMutex.Unlock()
+
+	atomic.AddInt64(&c.loads, 1)
+	call.value, call.err = load(ctx)
+	if call.err != nil {
+		atomic.AddInt64(&c.loadErrors, 1)
+	} else if err := c.Set(ctx, key, call.value, ttl); err != nil {
+		atomic.AddInt64(&c.storeErrors, 1)
+	}
+
+	c.loadsMutex.Lock()
+	delete(c.loading, key)
+	c.loadsMutex.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
@@ -0,0 +1,176 @@
+package kvcache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//memoryStore is a Store in memory recording the TTLs
+type memoryStore struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+	ttls   map[string]time.Duration
+	err    error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (m *memoryStore) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = m.values[key]
+	}
+	return values, nil
+}
+
+func (m *memoryStore) MSet(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	for key, value := range values {
+		m.values[key] = value
+		m.ttls[key] = ttl
+	}
+	return nil
+}
+
+func (m *memoryStore) Del(ctx context.Context, keys ...string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, key := range keys {
+		delete(m.values, key)
+	}
+	return nil
+}
+
+func (m *memoryStore) Scan(ctx context.Context, prefix string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var keys []string
+	for key := range m.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func TestCache_Namespace(t *testing.T) {
+
+	ctx := context.Background()
+	store := newMemoryStore()
+	cache := New(store, "app:")
+	users := cache.Namespace("users")
+
+	if err := users.Set(ctx, "1", []byte("alice"), time.Minute); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if err := cache.Set(ctx, "1", nil, 0); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if value := store.values["app:users:1"]; string(value) != "alice" || store.ttls["app:users:1"] != time.Minute {
+		t.Errorf("unexpected stored value %q for %v", value, store.ttls["app:users:1"])
+	}
+	if value, err := users.Get(ctx, "1"); err != nil || string(value) != "alice" {
+		t.Error("unexpected value", string(value), err)
+	}
+	if value, err := cache.Get(ctx, "1"); err != nil || len(value) != 0 {
+		t.Error("expected an empty value, got", value, err)
+	}
+	if _, err := users.Get(ctx, "2"); err != NotFoundError {
+		t.Error("expected NotFoundError, got", err)
+	}
+	keys, err := users.Scan(ctx, "")
+	if err != nil || !reflect.DeepEqual(keys, []string{"1"}) {
+		t.Error("unexpected keys", keys, err)
+	}
+	if err := users.Del(ctx, "1"); err != nil {
+		t.Error(err)
+	}
+	if _, ok := store.values["app:users:1"]; ok {
+		t.Error("expected the key to be deleted")
+	}
+	if stats := users.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCache_GetOrLoad(t *testing.T) {
+
+	ctx := context.Background()
+	store := newMemoryStore()
+	cache := New(store, "")
+
+	release := make(chan struct{})
+	var loads int32
+	var mutex sync.Mutex
+	load := func(ctx context.Context) ([]byte, error) {
+		mutex.Lock()
+		loads++
+		mutex.Unlock()
+		<-release
+		return []byte("value"), nil
+	}
+
+	var wait sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			if value, err := cache.GetOrLoad(ctx, "key", time.Minute, load); err != nil || string(value) != "value" {
+				t.Error("unexpected value", string(value), err)
+			}
+		}()
+	}
+	//let the callers reach the pending load before it finishes
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wait.Wait()
+
+	if value, err := cache.GetOrLoad(ctx, "key", time.Minute, load); err != nil || string(value) != "value" {
+		t.Error("unexpected value", string(value), err)
+	}
+	if stats := cache.Stats(); stats.Loads != 1 || loads != 1 || stats.Hits < 1 {
+		t.Errorf("expected a single load, got %d and %+v", loads, stats)
+	}
+
+	loadError := errors.New("load failed")
+	_, err := cache.GetOrLoad(ctx, "failing", time.Minute, func(ctx context.Context) ([]byte, error) {
+		return nil, loadError
+	})
+	if err != loadError {
+		t.Error("expected the load error, got", err)
+	}
+	if _, ok := store.values["failing"]; ok {
+		t.Error("expected the failed load not to be stored")
+	}
+
+	store.err = errors.New("store down")
+	value, err := cache.GetOrLoad(ctx, "key", time.Minute, func(ctx context.Context) ([]byte, error) {
+		return []byte("loaded"), nil
+	})
+	if err != nil || string(value) != "loaded" {
+		t.Error("expected the value to be loaded while the store is down, got", string(value), err)
+	}
+	if stats := cache.Stats(); stats.StoreErrors != 2 || stats.LoadErrors != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
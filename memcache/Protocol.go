@@ -0,0 +1,149 @@
+package memcache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+//the opcodes of the binary protocol used by Store, the quiet variants only answer errors and GETKQ only hits
+const (
+	opNoop    byte = 0x0a
+	opGetKQ   byte = 0x0d
+	opSetQ    byte = 0x11
+	opDeleteQ byte = 0x14
+)
+
+const (
+	requestMagic  byte = 0x80
+	responseMagic byte = 0x81
+	headerSize         = 24
+)
+
+//the response statuses Store handles
+const (
+	statusOK          uint16 = 0x0000
+	statusKeyNotFound uint16 = 0x0001
+	statusValueTooBig uint16 = 0x0003
+)
+
+//maxRelativeExpiration is the longest expiration memcached takes in seconds from now, longer ones are unix times
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+//ValueTooLargeError is returned if memcached refused a value, MaxValueSize is larger than its item size limit
+var ValueTooLargeError = errors.New("memcached refused a value as too large")
+
+var invalidResponseError = errors.New("invalid memcached response")
+
+//request is a request of the binary protocol
+type request struct {
+	opcode byte
+	opaque uint32
+	key    string
+	extras []byte
+	value  []byte
+}
+
+//response is a response of the binary protocol
+type response struct {
+	opcode byte
+	status uint16
+	opaque uint32
+	extras []byte
+	key    string
+	value  []byte
+}
+
+func (r *request) write(w *bufio.Writer) error {
+
+	var header [headerSize]byte
+	header[0] = requestMagic
+	header[1] = r.opcode
+	binary.BigEndian.PutUint16(header[2:4], uint16(len(r.key)))
+	header[4] = byte(len(r.extras))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(r.extras)+len(r.key)+len(r.value)))
+	binary.BigEndian.PutUint32(header[12:16], r.opaque)
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(r.extras); err != nil {
+		return err
+	}
+	if _, err := w.WriteString(r.key); err != nil {
+		return err
+	}
+	_, err := w.Write(r.value)
+	return err
+}
+
+func readResponse(r *bufio.Reader) (*response, error) {
+
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if header[0] != responseMagic {
+		return nil, invalidResponseError
+	}
+	keyLength := int(binary.BigEndian.Uint16(header[2:4]))
+	extrasLength := int(header[4])
+	bodyLength := int(binary.BigEndian.Uint32(header[8:12]))
+	if extrasLength+keyLength > bodyLength {
+		return nil, invalidResponseError
+	}
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &response{
+		opcode: header[1],
+		status: binary.BigEndian.Uint16(header[6:8]),
+		opaque: binary.BigEndian.Uint32(header[12:16]),
+		extras: body[:extrasLength],
+		key:    string(body[extrasLength : extrasLength+keyLength]),
+		value:  body[extrasLength+keyLength:],
+	}, nil
+}
+
+//err returns the error of a failed response
+func (r *response) err() error {
+	switch r.status {
+	case statusOK:
+		return nil
+	case statusValueTooBig:
+		return ValueTooLargeError
+	}
+	return fmt.Errorf("memcached answered status %#04x: %s", r.status, r.value)
+}
+
+//flags returns the flags of a GET response
+func (r *response) flags() uint32 {
+	if len(r.extras) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(r.extras)
+}
+
+//setExtras returns the extras of a SET request with flags and the expiration of ttl
+func setExtras(flags uint32, ttl time.Duration, now time.Time) []byte {
+	extras := make([]byte, 8)
+	binary.BigEndian.PutUint32(extras[0:4], flags)
+	binary.BigEndian.PutUint32(extras[4:8], expiration(ttl, now))
+	return extras
+}
+
+//expiration converts ttl to the expiration of memcached: 0 never expires, up to 30 days are seconds from now and
+//longer ones the unix time they expire at. Partial seconds are rounded up so keys never expire early
+func expiration(ttl time.Duration, now time.Time) uint32 {
+	if ttl <= 0 {
+		return 0
+	}
+	seconds := (ttl + time.Second - 1) / time.Second
+	if seconds*time.Second > maxRelativeExpiration {
+		return uint32(now.Add(seconds * time.Second).Unix())
+	}
+	return uint32(seconds)
+}
@@ -0,0 +1,406 @@
+//Package memcache implements the kvcache.Store of KVCache with a memcached server, speaking the binary protocol over
+//pooled connections without a client library. Values above the item size limit of memcached are split into chunks
+//and the TTL of KVCache, derived from the freshness lifetime of the responses with KVCacheOptions.Expire, becomes the
+//expiration of the keys
+//
+//	cache := NewKVCache(memcache.New("127.0.0.1:11211"), KVCacheOptions{Expire: true, MaxStale: time.Hour})
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//DefaultMaxValueSize is the largest value stored as a single item if Options.MaxValueSize is 0, the 1 MB item size
+//limit of memcached minus room for the key and the item header
+const DefaultMaxValueSize = 1<<20 - 1024
+
+//DefaultMaxIdleConns is the number of idle connections kept if Options.MaxIdleConns is 0
+const DefaultMaxIdleConns = 2
+
+//maxKeyLength is the longest key memcached accepts
+const maxKeyLength = 250
+
+//chunkedFlag marks the items holding the number of chunks and the token of a value split into chunks
+const chunkedFlag uint32 = 1
+
+//ScanNotSupportedError is returned by Scan, memcached can not list its keys. KVCache.Keys returns no keys and the
+//admin UI lists nothing for a KVCache in memcached
+var ScanNotSupportedError = errors.New("memcached can not list its keys")
+
+//KeyTooLongError is returned for keys memcached does not accept, including the suffix of the chunk keys
+var KeyTooLongError = errors.New("key too long for memcached")
+
+type Options struct {
+	//MaxValueSize is the largest value stored as a single item, larger values are split into chunks of this size.
+	//DefaultMaxValueSize if 0, it has to be lowered for servers started with a smaller -I
+	MaxValueSize int
+	//MaxIdleConns is the number of idle connections kept for reuse, DefaultMaxIdleConns if 0
+	MaxIdleConns int
+	//Dialer opens the connections, a net.Dialer with a timeout of 5 seconds if nil
+	Dialer *net.Dialer
+}
+
+//Store is a kvcache.Store in the memcached server at Address. Memcached has no transactions: MSet writes the chunks of
+//a value before the item referencing them, so readers never see a partial value, but the values of one call are not
+//written atomically. Overwritten chunks are left to expire or to be evicted by memcached
+type Store struct {
+	Address string
+	Options
+
+	idle chan *conn
+}
+
+//conn is a connection to memcached with its buffers
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+//New creates a Store for the memcached server at address, e.g. "127.0.0.1:11211"
+func New(address string, options ...Options) *Store {
+	store := &Store{Address: address}
+	if options != nil {
+		store.Options = options[0]
+	}
+	idle := store.MaxIdleConns
+	if idle <= 0 {
+		idle = DefaultMaxIdleConns
+	}
+	store.idle = make(chan *conn, idle)
+	return store
+}
+
+//Close closes the idle connections
+func (s *Store) Close() error {
+	for {
+		select {
+		case c := <-s.idle:
+			_ = c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *Store) maxValueSize() int {
+	if s.MaxValueSize <= 0 {
+		return DefaultMaxValueSize
+	}
+	return s.MaxValueSize
+}
+
+//MGet reads the items of keys in one round trip and the chunks of split values in a second one. Values with missing
+//chunks are missing
+func (s *Store) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+
+	values := make([][]byte, len(keys))
+	flags := make([]uint32, len(keys))
+	err := s.do(ctx, func(c *conn) error {
+		return c.getMulti(keys, values, flags)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var chunkKeys []string
+	//chunked holds the index of the value and the range of its chunks in chunkKeys
+	type chunkedValue struct{ index, first, count int }
+	var chunked []chunkedValue
+	for i, value := range values {
+		if value == nil || flags[i] != chunkedFlag {
+			continue
+		}
+		count, token, ok := parseManifest(value)
+		if !ok {
+			values[i] = nil
+			continue
+		}
+		chunked = append(chunked, chunkedValue{index: i, first: len(chunkKeys), count: count})
+		for chunk := 0; chunk < count; chunk++ {
+			chunkKeys = append(chunkKeys, chunkKey(keys[i], token, chunk))
+		}
+	}
+	if len(chunked) == 0 {
+		return values, nil
+	}
+
+	chunks := make([][]byte, len(chunkKeys))
+	err = s.do(ctx, func(c *conn) error {
+		return c.getMulti(chunkKeys, chunks, make([]uint32, len(chunkKeys)))
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range chunked {
+		var joined []byte
+		for _, chunk := range chunks[value.first : value.first+value.count] {
+			if chunk == nil {
+				joined = nil
+				break
+			}
+			joined = append(joined, chunk...)
+		}
+		values[value.index] = joined
+	}
+	return values, nil
+}
+
+//MSet stores values in one round trip, the keys expire after ttl
+func (s *Store) MSet(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+
+	now := time.Now()
+	maxSize := s.maxValueSize()
+	var requests []*request
+	for key, value := range values {
+		if len(key) > maxKeyLength {
+			return KeyTooLongError
+		}
+		if len(value) <= maxSize {
+			requests = append(requests, &request{opcode: opSetQ, key: key, extras: setExtras(0, ttl, now), value: value})
+			continue
+		}
+
+		token, err := newToken()
+		if err != nil {
+			return err
+		}
+		count := (len(value) + maxSize - 1) / maxSize
+		if len(chunkKey(key, token, count)) > maxKeyLength {
+			return KeyTooLongError
+		}
+		for chunk := 0; chunk < count; chunk++ {
+			end := (chunk + 1) * maxSize
+			if end > len(value) {
+				end = len(value)
+			}
+			requests = append(requests, &request{opcode: opSetQ, key: chunkKey(key, token, chunk), extras: setExtras(0, ttl, now), value: value[chunk*maxSize : end]})
+		}
+		//the manifest is written last, readers find all chunks once they see it
+		requests = append(requests, &request{opcode: opSetQ, key: key, extras: setExtras(chunkedFlag, ttl, now), value: []byte(strconv.Itoa(count) + " " + token)})
+	}
+
+	return s.do(ctx, func(c *conn) error {
+		return c.quiet(requests, false)
+	})
+}
+
+//Del deletes keys and the chunks of the values split into chunks, missing keys are ignored
+func (s *Store) Del(ctx context.Context, keys ...string) error {
+
+	values := make([][]byte, len(keys))
+	flags := make([]uint32, len(keys))
+	return s.do(ctx, func(c *conn) error {
+		if err := c.getMulti(keys, values, flags); err != nil {
+			return err
+		}
+		var requests []*request
+		for i, key := range keys {
+			requests = append(requests, &request{opcode: opDeleteQ, key: key})
+			if flags[i] != chunkedFlag {
+				continue
+			}
+			if count, token, ok := parseManifest(values[i]); ok {
+				for chunk := 0; chunk < count; chunk++ {
+					requests = append(requests, &request{opcode: opDeleteQ, key: chunkKey(key, token, chunk)})
+				}
+			}
+		}
+		return c.quiet(requests, true)
+	})
+}
+
+//SupportsScan returns false, KVCache reports no IterationCapability for the store
+func (s *Store) SupportsScan() bool {
+	return false
+}
+
+//Scan fails with ScanNotSupportedError
+func (s *Store) Scan(ctx context.Context, prefix string) ([]string, error) {
+	return nil, ScanNotSupportedError
+}
+
+//do calls f with a connection, the connection is closed if f fails or ctx is done meanwhile
+func (s *Store) do(ctx context.Context, f func(c *conn) error) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := c.SetDeadline(deadline); err != nil {
+		_ = c.Close()
+		return err
+	}
+
+	//the connection is interrupted once ctx is done
+	var stop, stopped chan struct{}
+	if ctx.Done() != nil {
+		stop, stopped = make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				_ = c.SetDeadline(time.Unix(1, 0))
+			case <-stop:
+			}
+		}()
+	}
+	err = f(c)
+	if stop != nil {
+		close(stop)
+		<-stopped
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		_ = c.Close()
+		return ctxErr
+	}
+	if err != nil {
+		_ = c.Close()
+		return err
+	}
+	select {
+	case s.idle <- c:
+	default:
+		_ = c.Close()
+	}
+	return nil
+}
+
+//conn returns an idle connection or dials a new one
+func (s *Store) conn(ctx context.Context) (*conn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+	dialer := s.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: 5 * time.Second}
+	}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}, nil
+}
+
+//getMulti reads the values and flags of keys with GETKQ, values of missing keys stay nil
+func (c *conn) getMulti(keys []string, values [][]byte, flags []uint32) error {
+
+	for i, key := range keys {
+		if len(key) > maxKeyLength {
+			return KeyTooLongError
+		}
+		if err := (&request{opcode: opGetKQ, opaque: uint32(i), key: key}).write(c.writer); err != nil {
+			return err
+		}
+	}
+	return c.untilNoop(len(keys), func(res *response) error {
+		if res.opcode != opGetKQ || res.status == statusKeyNotFound {
+			return nil
+		}
+		if err := res.err(); err != nil {
+			return err
+		}
+		values[res.opaque] = res.value
+		if values[res.opaque] == nil {
+			values[res.opaque] = []byte{}
+		}
+		flags[res.opaque] = res.flags()
+		return nil
+	})
+}
+
+//quiet sends quiet requests, the first error answered fails. Missing keys are no error if ignoreNotFound is set
+func (c *conn) quiet(requests []*request, ignoreNotFound bool) error {
+
+	for i, req := range requests {
+		req.opaque = uint32(i)
+		if err := req.write(c.writer); err != nil {
+			return err
+		}
+	}
+	var first error
+	err := c.untilNoop(len(requests), func(res *response) error {
+		if ignoreNotFound && res.status == statusKeyNotFound {
+			return nil
+		}
+		if err := res.err(); err != nil && first == nil {
+			first = err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return first
+}
+
+//untilNoop sends a NOOP and passes the responses to handle until its response arrives, the responses of the quiet
+//requests before it are all received then
+func (c *conn) untilNoop(opaque int, handle func(res *response) error) error {
+
+	if err := (&request{opcode: opNoop, opaque: uint32(opaque)}).write(c.writer); err != nil {
+		return err
+	}
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	var handleErr error
+	for {
+		res, err := readResponse(c.reader)
+		if err != nil {
+			return err
+		}
+		if res.opcode == opNoop {
+			return handleErr
+		}
+		if res.opaque >= uint32(opaque) {
+			return invalidResponseError
+		}
+		//the remaining responses are read to keep the connection usable
+		if err := handle(res); err != nil && handleErr == nil {
+			handleErr = err
+		}
+	}
+}
+
+//chunkKey returns the key of a chunk of the value stored under key
+func chunkKey(key string, token string, chunk int) string {
+	return key + "#" + token + "#" + strconv.Itoa(chunk)
+}
+
+//parseManifest parses the item of a value split into chunks
+func parseManifest(value []byte) (count int, token string, ok bool) {
+	fields := strings.Fields(string(value))
+	if len(fields) != 2 {
+		return 0, "", false
+	}
+	count, err := strconv.Atoi(fields[0])
+	if err != nil || count <= 0 {
+		return 0, "", false
+	}
+	return count, fields[1], true
+}
+
+//newToken returns a random token telling the chunks of different writes of a key apart
+func newToken() (string, error) {
+	var token [8]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token[:]), nil
+}
@@ -0,0 +1,229 @@
+package memcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//fakeServer answers the binary protocol requests of Store from memory, recording the expiration of the keys
+type fakeServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	items    map[string]fakeItem
+	maxSize  int
+}
+
+type fakeItem struct {
+	flags      uint32
+	expiration uint32
+	value      []byte
+}
+
+func newFakeServer(t *testing.T, maxSize int) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	server := &fakeServer{listener: listener, items: map[string]fakeItem{}, maxSize: maxSize}
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(c)
+		}
+	}()
+	return server
+}
+
+func (f *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+	reader, writer := bufio.NewReader(c), bufio.NewWriter(c)
+	for {
+		var header [headerSize]byte
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			return
+		}
+		keyLength := int(binary.BigEndian.Uint16(header[2:4]))
+		extrasLength := int(header[4])
+		body := make([]byte, binary.BigEndian.Uint32(header[8:12]))
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		opcode, opaque := header[1], binary.BigEndian.Uint32(header[12:16])
+		extras, key, value := body[:extrasLength], string(body[extrasLength:extrasLength+keyLength]), body[extrasLength+keyLength:]
+
+		f.mutex.Lock()
+		var res *response
+		switch opcode {
+		case opGetKQ:
+			if item, ok := f.items[key]; ok {
+				flags := make([]byte, 4)
+				binary.BigEndian.PutUint32(flags, item.flags)
+				res = &response{opcode: opcode, opaque: opaque, extras: flags, key: key, value: item.value}
+			}
+		case opSetQ:
+			if len(value) > f.maxSize {
+				res = &response{opcode: opcode, opaque: opaque, status: statusValueTooBig, value: []byte("Too large.")}
+				break
+			}
+			f.items[key] = fakeItem{flags: binary.BigEndian.Uint32(extras[0:4]), expiration: binary.BigEndian.Uint32(extras[4:8]), value: append([]byte(nil), value...)}
+		case opDeleteQ:
+			if _, ok := f.items[key]; !ok {
+				res = &response{opcode: opcode, opaque: opaque, status: statusKeyNotFound, value: []byte("Not found")}
+				break
+			}
+			delete(f.items, key)
+		case opNoop:
+			res = &response{opcode: opcode, opaque: opaque}
+		}
+		f.mutex.Unlock()
+
+		if res != nil {
+			var out [headerSize]byte
+			out[0] = responseMagic
+			out[1] = res.opcode
+			binary.BigEndian.PutUint16(out[2:4], uint16(len(res.key)))
+			out[4] = byte(len(res.extras))
+			binary.BigEndian.PutUint16(out[6:8], res.status)
+			binary.BigEndian.PutUint32(out[8:12], uint32(len(res.extras)+len(res.key)+len(res.value)))
+			binary.BigEndian.PutUint32(out[12:16], res.opaque)
+			_, _ = writer.Write(out[:])
+			_, _ = writer.Write(res.extras)
+			_, _ = writer.WriteString(res.key)
+			_, _ = writer.Write(res.value)
+			if opcode == opNoop {
+				if err := writer.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (f *fakeServer) keys() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var keys []string
+	for key := range f.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestStore(t *testing.T) {
+
+	server := newFakeServer(t, 64)
+	defer server.listener.Close()
+	store := New(server.listener.Addr().String(), Options{MaxValueSize: 64})
+	defer store.Close()
+	ctx := context.Background()
+
+	large := bytes.Repeat([]byte("0123456789"), 20)
+	err := store.MSet(ctx, map[string][]byte{"small": []byte("value"), "empty": {}, "large": large}, time.Minute)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if keys := server.keys(); len(keys) != 3+4 {
+		t.Error("expected the large value to be split into 4 chunks, got", keys)
+	}
+	server.mutex.Lock()
+	if expiration := server.items["small"].expiration; expiration != 60 {
+		t.Error("expected the keys to expire in 60 seconds, got", expiration)
+	}
+	server.mutex.Unlock()
+
+	values, err := store.MGet(ctx, "small", "missing", "large", "empty")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	expected := [][]byte{[]byte("value"), nil, large, {}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Error("expected", expected, "got", values)
+	}
+
+	if err := store.Del(ctx, "large", "missing"); err != nil {
+		t.Error(err)
+	}
+	if keys := server.keys(); len(keys) != 2 {
+		t.Error("expected the chunks to be deleted with their value, got", keys)
+	}
+
+	//a value whose chunks were evicted is missing
+	if err := store.MSet(ctx, map[string][]byte{"large": large}, 0); err != nil {
+		t.Error(err)
+	}
+	for _, key := range server.keys() {
+		if strings.HasPrefix(key, "large#") && strings.HasSuffix(key, "#1") {
+			server.mutex.Lock()
+			delete(server.items, key)
+			server.mutex.Unlock()
+		}
+	}
+	if values, err := store.MGet(ctx, "large"); err != nil || values[0] != nil {
+		t.Error("expected a value with a missing chunk to be missing, got", values, err)
+	}
+
+	if _, err := store.Scan(ctx, ""); err != ScanNotSupportedError {
+		t.Error("expected ScanNotSupportedError, got", err)
+	}
+	if err := store.MSet(ctx, map[string][]byte{strings.Repeat("k", 251): nil}, 0); err != KeyTooLongError {
+		t.Error("expected KeyTooLongError, got", err)
+	}
+}
+
+func TestStore_Errors(t *testing.T) {
+
+	server := newFakeServer(t, 16)
+	defer server.listener.Close()
+	store := New(server.listener.Addr().String(), Options{MaxValueSize: 64})
+	defer store.Close()
+
+	err := store.MSet(context.Background(), map[string][]byte{"large": bytes.Repeat([]byte("x"), 32)}, 0)
+	if err != ValueTooLargeError {
+		t.Error("expected ValueTooLargeError, got", err)
+	}
+	//the connection stays usable after an error answered by the server
+	if _, err := store.MGet(context.Background(), "large"); err != nil {
+		t.Error(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.MGet(ctx, "key"); err != context.Canceled {
+		t.Error("expected the canceled context to fail the call, got", err)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		ttl      time.Duration
+		expected uint32
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{90 * time.Second, 90},
+		{30 * 24 * time.Hour, uint32(30 * 24 * 60 * 60)},
+		{31 * 24 * time.Hour, uint32(now.Add(31 * 24 * time.Hour).Unix())},
+	}
+	for _, tt := range tests {
+		if expiration := expiration(tt.ttl, now); expiration != tt.expected {
+			t.Error("expected", tt.expected, "for", tt.ttl, "got", expiration)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+//Package gcsstore implements the CachedHttpClient.ObjectStore of an ObjectCache with a Google Cloud Storage bucket.
+//It uses the XML API of GCS through an authorized *http.Client, so it needs no dependencies besides the one creating
+//the client, e.g. golang.org/x/oauth2/google
+package gcsstore
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//DefaultEndpoint is the XML API of GCS used if Store.Endpoint is empty
+const DefaultEndpoint = "https://storage.googleapis.com"
+
+//DefaultMaxMetadataSize is the size of the custom metadata GCS allows, for ObjectCacheOptions.MaxMetadataSize
+const DefaultMaxMetadataSize = 8 << 10
+
+//metadataHeader starts the headers of the custom metadata
+const metadataHeader = "X-Goog-Meta-"
+
+//StatusError is returned for unexpected responses of GCS
+type StatusError struct {
+	Method     string
+	Name       string
+	StatusCode int
+	//Message is the start of the response body, usually an XML error document
+	Message string
+}
+
+func (s *StatusError) Error() string {
+	return fmt.Sprintf("gcs %s %s: status %d: %s", s.Method, s.Name, s.StatusCode, s.Message)
+}
+
+//Store keeps the objects in Bucket
+type Store struct {
+	//Client authorizes the requests with a token of the devstorage.read_write scope, e.g. created by
+	//google.DefaultClient of golang.org/x/oauth2/google
+	Client *http.Client
+	Bucket string
+	//Endpoint is the URL of the XML API, DefaultEndpoint if empty
+	Endpoint string
+}
+
+//New creates a Store for bucket
+func New(client *http.Client, bucket string) *Store {
+	return &Store{Client: client, Bucket: bucket}
+}
+
+func (s *Store) endpoint() string {
+	if s.Endpoint == "" {
+		return DefaultEndpoint
+	}
+	return strings.TrimSuffix(s.Endpoint, "/")
+}
+
+func (s *Store) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+	return s.Client
+}
+
+//url returns the URL of the object name, the bucket for an empty name
+func (s *Store) url(name string, query url.Values) (string, error) {
+
+	u, err := url.Parse(s.endpoint())
+	if err != nil {
+		return "", err
+	}
+	u.Path += "/" + s.Bucket
+	if name != "" {
+		u.Path += "/" + name
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+//do sends the request for the object name and returns the response if its status is one of expected, NotInCacheError
+//for 404 Not Found and StatusError otherwise
+func (s *Store) do(ctx context.Context, method string, name string, query url.Values, body io.Reader, prepare func(req *http.Request), expected ...int) (*http.Response, error) {
+
+	target, err := s.url(name, query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	res, err := s.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range expected {
+		if res.StatusCode == status {
+			return res, nil
+		}
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	_ = res.Body.Close()
+	if res.StatusCode == http.StatusNotFound && name != "" {
+		return nil, CachedHttpClient.NotInCacheError
+	}
+	return nil, &StatusError{Method: method, Name: name, StatusCode: res.StatusCode, Message: string(message)}
+}
+
+//Put uploads the object in a single request while body is read. The metadata names are sent as headers, GCS stores
+//them in lower case
+func (s *Store) Put(ctx context.Context, name string, metadata map[string]string, body io.Reader, size int64) error {
+
+	if size == 0 {
+		body = http.NoBody
+	} else {
+		//the client closes request bodies, body belongs to the caller which reads it again, e.g. the spool file of
+		//ObjectCache
+		body = ioutil.NopCloser(body)
+	}
+	res, err := s.do(ctx, http.MethodPut, name, nil, body, func(req *http.Request) {
+		req.ContentLength = size
+		for key, value := range metadata {
+			req.Header.Set(metadataHeader+key, value)
+		}
+	}, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *Store) Get(ctx context.Context, name string) (map[string]string, io.ReadCloser, error) {
+
+	res, err := s.do(ctx, http.MethodGet, name, nil, nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata := map[string]string{}
+	for header, values := range res.Header {
+		if strings.HasPrefix(header, metadataHeader) && len(values) > 0 {
+			metadata[strings.ToLower(strings.TrimPrefix(header, metadataHeader))] = values[0]
+		}
+	}
+	return metadata, res.Body, nil
+}
+
+func (s *Store) Delete(ctx context.Context, name string) error {
+
+	res, err := s.do(ctx, http.MethodDelete, name, nil, nil, nil, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+//listBucketResult is a page of the objects of a bucket
+type listBucketResult struct {
+	IsTruncated bool
+	NextMarker  string
+	Contents    []struct {
+		Key string
+	}
+}
+
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+
+	names := []string{}
+	marker := ""
+	for {
+		query := url.Values{"prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		res, err := s.do(ctx, http.MethodGet, "", query, nil, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			names = append(names, object.Key)
+		}
+		if !page.IsTruncated || page.NextMarker == "" || page.NextMarker == marker {
+			return names, nil
+		}
+		marker = page.NextMarker
+	}
+}
@@ -0,0 +1,181 @@
+package gcsstore
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+)
+
+//fakeGCS serves the XML API for the objects of bucket in memory, it lists one object per page
+type fakeGCS struct {
+	mutex   sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	headers map[string]http.Header
+}
+
+func (f *fakeGCS) ServeHTTP(writer http.ResponseWriter, req *http.Request) {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if req.Header.Get("Authorization") != "Bearer token" {
+		http.Error(writer, "<Error><Code>AuthenticationRequired</Code></Error>", http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(req.URL.Path, "/"+f.bucket)
+	if path == "" && req.Method == http.MethodGet {
+		var names []string
+		for name := range f.objects {
+			if strings.HasPrefix(name, req.URL.Query().Get("prefix")) && name > req.URL.Query().Get("marker") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var page listBucketResult
+		if len(names) > 1 {
+			page.IsTruncated, page.NextMarker, names = true, names[0], names[:1]
+		}
+		for _, name := range names {
+			page.Contents = append(page.Contents, struct{ Key string }{name})
+		}
+		_ = xml.NewEncoder(writer).Encode(struct {
+			XMLName xml.Name `xml:"ListBucketResult"`
+			listBucketResult
+		}{listBucketResult: page})
+		return
+	}
+
+	name := strings.TrimPrefix(path, "/")
+	switch req.Method {
+	case http.MethodPut:
+		content, _ := ioutil.ReadAll(req.Body)
+		if int64(len(content)) != req.ContentLength {
+			http.Error(writer, "length mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[name] = content
+		f.headers[name] = http.Header{}
+		for header, values := range req.Header {
+			if strings.HasPrefix(header, metadataHeader) {
+				f.headers[name][header] = values
+			}
+		}
+	case http.MethodGet:
+		content, ok := f.objects[name]
+		if !ok {
+			http.Error(writer, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		for header, values := range f.headers[name] {
+			writer.Header()[header] = values
+		}
+		_, _ = writer.Write(content)
+	case http.MethodDelete:
+		if _, ok := f.objects[name]; !ok {
+			http.Error(writer, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, name)
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+//bearerTransport authorizes the requests like the client of golang.org/x/oauth2
+type bearerTransport struct{}
+
+func (bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer token")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func testStore(t *testing.T) (*Store, func()) {
+	server := httptest.NewServer(&fakeGCS{bucket: "bucket", objects: map[string][]byte{}, headers: map[string]http.Header{}})
+	store := New(&http.Client{Transport: bearerTransport{}}, "bucket")
+	store.Endpoint = server.URL
+	return store, server.Close
+}
+
+func TestStore(t *testing.T) {
+
+	store, stop := testStore(t)
+	defer stop()
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "cache/a b", map[string]string{"cachedhttp-head": "head"}, strings.NewReader("content"), 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "cache/b", nil, strings.NewReader(""), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "other", nil, strings.NewReader(""), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, body, err := store.Get(ctx, "cache/a b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(body)
+	_ = body.Close()
+	if string(content) != "content" || metadata["cachedhttp-head"] != "head" {
+		t.Error("unexpected object", string(content), metadata)
+	}
+	if _, _, err := store.Get(ctx, "missing"); err != CachedHttpClient.NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+
+	if names, err := store.List(ctx, "cache/"); err != nil || strings.Join(names, ",") != "cache/a b,cache/b" {
+		t.Error("expected the objects of all pages with the prefix, got", names, err)
+	}
+
+	if err := store.Delete(ctx, "cache/a b"); err != nil {
+		t.Error(err)
+	}
+	if err := store.Delete(ctx, "cache/a b"); err != CachedHttpClient.NotInCacheError {
+		t.Error("expected NotInCacheError for a deleted object, got", err)
+	}
+
+	store.Client = http.DefaultClient
+	if _, _, err := store.Get(ctx, "cache/b"); err == nil || err.(*StatusError).StatusCode != http.StatusUnauthorized {
+		t.Error("expected the status error of an unauthorized request, got", err)
+	}
+}
+
+func TestStore_ObjectCache(t *testing.T) {
+
+	store, stop := testStore(t)
+	defer stop()
+	cache := CachedHttpClient.NewObjectCache(store, CachedHttpClient.ObjectCacheOptions{MaxMetadataSize: DefaultMaxMetadataSize})
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/report", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/csv"}},
+		Body: ioutil.NopCloser(strings.NewReader("a,b,c")), Request: req}
+	if err := cache.Set(req, res); err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	cached, err := cache.Get(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(cached.Body)
+	_ = cached.Body.Close()
+	if string(body) != "a,b,c" || cached.Header.Get("Content-Type") != "text/csv" {
+		t.Error("unexpected cached response", string(body), cached.Header)
+	}
+	if keys := cache.Keys(); len(keys) != 1 {
+		t.Error("expected 1 key, got", keys)
+	}
+}
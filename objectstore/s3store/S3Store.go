@@ -0,0 +1,94 @@
+//Package s3store implements the CachedHttpClient.ObjectStore of an ObjectCache with an S3 bucket
+package s3store
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+//Client are the operations of *s3.Client used by Store
+type Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	s3.ListObjectsV2APIClient
+}
+
+//Store keeps the objects in Bucket. Bodies are uploaded as they are read, an unseekable body requires a TLS endpoint
+//so the SDK sends its checksum as trailer. The metadata of S3 is limited to 2 KB, the default of
+//ObjectCacheOptions.MaxMetadataSize
+type Store struct {
+	Client Client
+	Bucket string
+}
+
+//New creates a Store for bucket, client is usually an *s3.Client
+func New(client Client, bucket string) *Store {
+	return &Store{Client: client, Bucket: bucket}
+}
+
+func (s *Store) Put(ctx context.Context, name string, metadata map[string]string, body io.Reader, size int64) error {
+	_, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.Bucket),
+		Key:           aws.String(name),
+		Metadata:      metadata,
+		Body:          body,
+		ContentLength: aws.Int64(size),
+	})
+	return err
+}
+
+func (s *Store) Get(ctx context.Context, name string) (map[string]string, io.ReadCloser, error) {
+
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(name)})
+	if err != nil {
+		return nil, nil, notInCache(err)
+	}
+	return out.Metadata, out.Body, nil
+}
+
+//Delete removes the object name. S3 does not report deleting a missing object, its existence is checked before
+func (s *Store) Delete(ctx context.Context, name string) error {
+
+	_, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(name)})
+	if err != nil {
+		return notInCache(err)
+	}
+	_, err = s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(name)})
+	return err
+}
+
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+
+	names := []string{}
+	pages := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{Bucket: aws.String(s.Bucket), Prefix: aws.String(prefix)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			names = append(names, aws.ToString(object.Key))
+		}
+	}
+	return names, nil
+}
+
+//notInCache maps the errors of missing objects to NotInCacheError, GetObject fails with NoSuchKey and HeadObject
+//with NotFound
+func notInCache(err error) error {
+
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return CachedHttpClient.NotInCacheError
+	}
+	return err
+}
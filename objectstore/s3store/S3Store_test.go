@@ -0,0 +1,165 @@
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+//memoryClient is a Client keeping the objects of one bucket in memory, it lists pageSize objects per page
+type memoryClient struct {
+	mutex    sync.Mutex
+	pageSize int
+	objects  map[string][]byte
+	metadata map[string]map[string]string
+}
+
+func newMemoryClient() *memoryClient {
+	return &memoryClient{pageSize: 1, objects: map[string][]byte{}, metadata: map[string]map[string]string{}}
+}
+
+func (m *memoryClient) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	content, err := ioutil.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) != aws.ToInt64(params.ContentLength) {
+		return nil, &types.InvalidRequest{Message: aws.String("content length mismatch")}
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.objects[aws.ToString(params.Key)] = content
+	m.metadata[aws.ToString(params.Key)] = params.Metadata
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *memoryClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	content, ok := m.objects[aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Metadata: m.metadata[aws.ToString(params.Key)], Body: ioutil.NopCloser(bytes.NewReader(content))}, nil
+}
+
+func (m *memoryClient) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.objects[aws.ToString(params.Key)]; !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{Metadata: m.metadata[aws.ToString(params.Key)]}, nil
+}
+
+func (m *memoryClient) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.objects, aws.ToString(params.Key))
+	delete(m.metadata, aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *memoryClient) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var keys []string
+	for key := range m.objects {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) && key > aws.ToString(params.ContinuationToken) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	out := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(len(keys) > m.pageSize)}
+	if len(keys) > m.pageSize {
+		keys = keys[:m.pageSize]
+		out.NextContinuationToken = aws.String(keys[len(keys)-1])
+	}
+	for _, key := range keys {
+		out.Contents = append(out.Contents, types.Object{Key: aws.String(key)})
+	}
+	return out, nil
+}
+
+func TestStore(t *testing.T) {
+
+	ctx := context.Background()
+	store := New(newMemoryClient(), "bucket")
+	if err := store.Put(ctx, "cache/a", map[string]string{"cachedhttp-head": "head"}, strings.NewReader("content"), 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "cache/b", nil, strings.NewReader(""), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(ctx, "other", nil, strings.NewReader(""), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, body, err := store.Get(ctx, "cache/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(body)
+	_ = body.Close()
+	if string(content) != "content" || metadata["cachedhttp-head"] != "head" {
+		t.Error("unexpected object", string(content), metadata)
+	}
+	if _, _, err := store.Get(ctx, "missing"); err != CachedHttpClient.NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+
+	if names, err := store.List(ctx, "cache/"); err != nil || strings.Join(names, ",") != "cache/a,cache/b" {
+		t.Error("expected the objects of all pages with the prefix, got", names, err)
+	}
+
+	if err := store.Delete(ctx, "cache/a"); err != nil {
+		t.Error(err)
+	}
+	if err := store.Delete(ctx, "cache/a"); err != CachedHttpClient.NotInCacheError {
+		t.Error("expected NotInCacheError for a deleted object, got", err)
+	}
+}
+
+func TestStore_ObjectCache(t *testing.T) {
+
+	cache := CachedHttpClient.NewObjectCache(New(newMemoryClient(), "bucket"), CachedHttpClient.ObjectCacheOptions{MaxMetadataSize: 64})
+	req, err := http.NewRequest(http.MethodGet, "http://example.com/report", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/csv"}},
+		Body: ioutil.NopCloser(strings.NewReader("a,b,c")), Request: req}
+	if err := cache.Set(req, res); err != nil {
+		t.Fatal(err)
+	}
+	_ = res.Body.Close()
+
+	cached, err := cache.Get(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(cached.Body)
+	_ = cached.Body.Close()
+	if string(body) != "a,b,c" || cached.Header.Get("Content-Type") != "text/csv" {
+		t.Error("unexpected cached response", string(body), cached.Header)
+	}
+	if keys := cache.Keys(); len(keys) != 1 {
+		t.Fatal("expected 1 key, got", keys)
+	}
+	if err := cache.DeleteKey(cache.Keys()[0]); err != nil {
+		t.Error(err)
+	}
+	if _, err := cache.Get(req); err != CachedHttpClient.NotInCacheError {
+		t.Error("expected NotInCacheError, got", err)
+	}
+}
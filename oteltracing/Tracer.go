@@ -0,0 +1,66 @@
+//Package oteltracing adapts an OpenTelemetry trace.Tracer to the CachedHttpClient.Tracer of a CachedTransport. It is a
+//separate module so CachedHttpClient stays free of the OpenTelemetry dependencies
+package oteltracing
+
+import (
+	"context"
+	"fmt"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+//Tracer starts the spans of a CachedTransport with Tracer
+type Tracer struct {
+	Tracer trace.Tracer
+}
+
+//New creates a Tracer for tracer, e.g. otel.Tracer("github.com/Scax/CachedHttpClient-Go")
+func New(tracer trace.Tracer) *Tracer {
+	return &Tracer{Tracer: tracer}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, CachedHttpClient.Span) {
+	ctx, span := t.Tracer.Start(ctx, name)
+	return ctx, Span{Span: span}
+}
+
+//Span sets the attributes and errors of a CachedTransport on Span
+type Span struct {
+	Span trace.Span
+}
+
+//SetAttribute sets string, bool, int, int64 and float64 values with their type and other values formatted with
+//fmt.Sprint
+func (s Span) SetAttribute(key string, value interface{}) {
+	s.Span.SetAttributes(keyValue(key, value))
+}
+
+//RecordError records err as exception event and sets the status of the span to Error
+func (s Span) RecordError(err error) {
+	s.Span.RecordError(err)
+	s.Span.SetStatus(codes.Error, err.Error())
+}
+
+func (s Span) End() {
+	s.Span.End()
+}
+
+func keyValue(key string, value interface{}) attribute.KeyValue {
+	switch value := value.(type) {
+	case string:
+		return attribute.String(key, value)
+	case bool:
+		return attribute.Bool(key, value)
+	case int:
+		return attribute.Int(key, value)
+	case int64:
+		return attribute.Int64(key, value)
+	case float64:
+		return attribute.Float64(key, value)
+	default:
+		return attribute.String(key, fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,116 @@
+package oteltracing
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	CachedHttpClient "github.com/Scax/CachedHttpClient-Go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTracer(t *testing.T) {
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	fallback := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/error" {
+			return nil, errors.New("origin failed")
+		}
+		header := http.Header{}
+		header.Set("Cache-Control", "max-age=60")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader("hello")), Request: req}, nil
+	})
+	client := http.Client{Transport: &CachedHttpClient.CachedTransport{Cache: CachedHttpClient.NewMapCache(),
+		Fallback: fallback, Tracer: New(provider.Tracer("test"))}}
+
+	for _, path := range []string{"/", "/", "/error"} {
+		response, err := client.Get("http://example.com" + path)
+		if err == nil {
+			_ = response.Body.Close()
+		}
+	}
+
+	spans := recorder.Ended()
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	expected := []string{
+		CachedHttpClient.LookupSpan, CachedHttpClient.OriginSpan, CachedHttpClient.RoundTripSpan,
+		CachedHttpClient.LookupSpan, CachedHttpClient.RoundTripSpan,
+		CachedHttpClient.LookupSpan, CachedHttpClient.OriginSpan, CachedHttpClient.RoundTripSpan,
+	}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatal("expected the spans", expected, "got", names)
+	}
+
+	roundTrip := spans[2]
+	for _, child := range spans[:2] {
+		if child.Parent().SpanID() != roundTrip.SpanContext().SpanID() {
+			t.Error("expected", child.Name(), "to be a child of the round trip span")
+		}
+	}
+	if value, ok := attributeValue(spans[0].Attributes(), CachedHttpClient.HitAttribute); !ok || value.AsBool() {
+		t.Error("expected the lookup to miss, got", value.Emit())
+	}
+	if value, ok := attributeValue(spans[1].Attributes(), CachedHttpClient.StatusCodeAttribute); !ok || value.AsInt64() != http.StatusOK {
+		t.Error("expected the status code on the origin span, got", value.Emit())
+	}
+	if value, ok := attributeValue(roundTrip.Attributes(), CachedHttpClient.ResultAttribute); !ok || value.AsString() != "miss" {
+		t.Error("expected a miss, got", value.Emit())
+	}
+	if value, ok := attributeValue(spans[3].Attributes(), CachedHttpClient.HitAttribute); !ok || !value.AsBool() {
+		t.Error("expected the lookup to hit, got", value.Emit())
+	}
+	if value, ok := attributeValue(spans[4].Attributes(), CachedHttpClient.ResultAttribute); !ok || value.AsString() != "hit" {
+		t.Error("expected a hit, got", value.Emit())
+	}
+
+	for _, span := range spans[6:] {
+		if span.Status().Code != codes.Error || len(span.Events()) != 1 || span.Events()[0].Name != "exception" {
+			t.Errorf("expected %s to record the error, got %+v %+v", span.Name(), span.Status(), span.Events())
+		}
+	}
+}
+
+func TestSpan_SetAttribute(t *testing.T) {
+
+	tests := []struct {
+		value    interface{}
+		expected attribute.Value
+	}{
+		{"hit", attribute.StringValue("hit")},
+		{true, attribute.BoolValue(true)},
+		{200, attribute.IntValue(200)},
+		{int64(5), attribute.Int64Value(5)},
+		{0.5, attribute.Float64Value(0.5)},
+		{[]byte("x"), attribute.StringValue("[120]")},
+	}
+	for _, tt := range tests {
+		if value := keyValue("key", tt.value).Value; value != tt.expected {
+			t.Error("expected", tt.expected.Emit(), "got", value.Emit())
+		}
+	}
+}
+
+//attributeValue returns the value of the attribute key
+func attributeValue(attributes []attribute.KeyValue, key string) (attribute.Value, bool) {
+	for _, kv := range attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
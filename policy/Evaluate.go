@@ -0,0 +1,90 @@
+package policy
+
+import (
+	"net/http"
+	"time"
+)
+
+//Action is what a cache does with a stored response for a request
+type Action int
+
+const (
+	//Fetch sends the request to the origin, nothing usable is stored
+	Fetch Action = iota
+	//Serve serves the stored response without contacting the origin
+	Serve
+	//Revalidate sends a conditional request with the validators of the stored response to the origin
+	Revalidate
+)
+
+func (a Action) String() string {
+	switch a {
+	case Serve:
+		return "serve"
+	case Revalidate:
+		return "revalidate"
+	default:
+		return "fetch"
+	}
+}
+
+//Decision is the result of Evaluate
+type Decision struct {
+	Action Action
+	//Age is the current age of the stored response
+	Age time.Duration
+	//Lifetime is the freshness lifetime of the stored response
+	Lifetime time.Duration
+	//Reason explains the Action
+	Reason string
+}
+
+//Cache evaluates requests with the rules of a private cache, or of a shared cache if Shared is set
+type Cache struct {
+	Shared bool
+}
+
+//Evaluate decides what a private cache does with the stored response cached for req at now, cached may be nil
+func Evaluate(req *http.Request, cached *http.Response, now time.Time) Decision {
+	return Cache{}.Evaluate(req, cached, now)
+}
+
+//Evaluate decides what the cache does with the stored response cached for req at now, cached may be nil. The
+//request directives no-cache, max-age, min-fresh and max-stale are honored. Stale responses served within
+//stale-while-revalidate or stale-if-error windows are configured on the client and not part of the decision
+func (c Cache) Evaluate(req *http.Request, cached *http.Response, now time.Time) Decision {
+
+	if cached == nil {
+		return Decision{Action: Fetch, Reason: "not stored"}
+	}
+
+	decision := Decision{Age: CurrentAge(cached, now)}
+	decision.Lifetime = FreshnessLifetime(cached, c.Shared)
+
+	fresh := IsFresh(cached, c.Shared, now)
+	if fresh && RequestAccepts(req, decision.Lifetime, decision.Age) {
+		decision.Action = Serve
+		decision.Reason = "fresh"
+		return decision
+	}
+	if !fresh && RequestAcceptsStale(req, cached, decision.Lifetime, decision.Age, c.Shared) {
+		decision.Action = Serve
+		decision.Reason = "stale within max-stale"
+		return decision
+	}
+
+	decision.Reason = "stale"
+	if fresh {
+		decision.Reason = "refused by the request directives"
+	}
+	if cc := ParseCacheControl(cached.Header); cc.Has("no-cache") && len(cc.Fields("no-cache")) == 0 {
+		decision.Reason = "no-cache"
+	}
+	if cached.Header.Get("ETag") != "" || cached.Header.Get("Last-Modified") != "" {
+		decision.Action = Revalidate
+		return decision
+	}
+	decision.Action = Fetch
+	decision.Reason += " without validators"
+	return decision
+}
@@ -0,0 +1,80 @@
+package policy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-time.Minute).Format(http.TimeFormat)
+	req := &http.Request{Method: http.MethodGet, Header: http.Header{}}
+
+	tests := []struct {
+		name     string
+		cached   *http.Response
+		shared   bool
+		action   Action
+		reason   string
+		lifetime time.Duration
+	}{
+		{"not stored", nil, false, Fetch, "not stored", 0},
+		{"fresh", &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=120"}}}, false, Serve, "fresh", 2 * time.Minute},
+		{"shared stale", &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=120, s-maxage=30"}}}, true, Fetch, "stale without validators", 30 * time.Second},
+		{"stale etag", &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=30"}, "Etag": {`"1"`}}}, false, Revalidate, "stale", 30 * time.Second},
+		{"no-cache", &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=120, no-cache"}, "Last-Modified": {date}}}, false, Revalidate, "no-cache", 2 * time.Minute},
+		{"no information", &http.Response{Header: http.Header{}}, false, Serve, "fresh", HeuristicLifetime},
+		{"no information expired", &http.Response{Header: http.Header{"Date": {now.Add(-time.Hour).Format(http.TimeFormat)}}}, false, Fetch,
+			"stale without validators", HeuristicLifetime},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := Cache{Shared: test.shared}.Evaluate(req, test.cached, now)
+			if decision.Action != test.action || decision.Reason != test.reason || decision.Lifetime != test.lifetime {
+				t.Error("expected", test.action, test.reason, test.lifetime, "got", decision.Action, decision.Reason, decision.Lifetime)
+			}
+		})
+	}
+	if decision := Evaluate(req, nil, now); decision.Action != Fetch || decision.Action.String() != "fetch" {
+		t.Error("expected a private cache to fetch got", decision)
+	}
+}
+
+func TestEvaluate_RequestDirectives(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-time.Minute).Format(http.TimeFormat)
+	fresh := &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=120"}, "Etag": {`"1"`}}}
+	stale := &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=30"}}}
+	mustRevalidate := &http.Response{Header: http.Header{"Date": {date}, "Cache-Control": {"max-age=30, must-revalidate"}}}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		cached *http.Response
+		action Action
+	}{
+		{"no-cache", http.Header{"Cache-Control": {"no-cache"}}, fresh, Revalidate},
+		{"pragma", http.Header{"Pragma": {"no-cache"}}, fresh, Revalidate},
+		{"pragma with cache-control", http.Header{"Pragma": {"no-cache"}, "Cache-Control": {"max-stale"}}, fresh, Serve},
+		{"max-age older", http.Header{"Cache-Control": {"max-age=30"}}, fresh, Revalidate},
+		{"max-age", http.Header{"Cache-Control": {"max-age=90"}}, fresh, Serve},
+		{"min-fresh", http.Header{"Cache-Control": {"min-fresh=90"}}, fresh, Revalidate},
+		{"min-fresh within", http.Header{"Cache-Control": {"min-fresh=30"}}, fresh, Serve},
+		{"max-stale", http.Header{"Cache-Control": {"max-stale"}}, stale, Serve},
+		{"max-stale within", http.Header{"Cache-Control": {"max-stale=30"}}, stale, Serve},
+		{"max-stale exceeded", http.Header{"Cache-Control": {"max-stale=10"}}, stale, Fetch},
+		{"max-stale must-revalidate", http.Header{"Cache-Control": {"max-stale"}}, mustRevalidate, Fetch},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &http.Request{Method: http.MethodGet, Header: test.header}
+			if decision := Evaluate(req, test.cached, now); decision.Action != test.action {
+				t.Error("expected", test.action, "got", decision.Action, decision.Reason)
+			}
+		})
+	}
+}
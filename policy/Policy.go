@@ -0,0 +1,382 @@
+//Package policy holds the HTTP caching rules of RFC 9111 applied by CachedHttpClient: parsing Cache-Control,
+//freshness lifetime, age and freshness of stored responses, whether a response may be stored and what a cache does
+//with a stored response for a request. CachedHttpClient uses this package itself, the decisions are the same
+package policy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//CacheControl holds the directives of a Cache-Control header, directives without an argument map to ""
+type CacheControl map[string]string
+
+//maxParsedCacheControls is the number of Cache-Control values parsedCacheControls holds before it is emptied
+const maxParsedCacheControls = 4096
+
+//parsedCacheControls memoizes the directives of single Cache-Control header values. The responses of a cache repeat
+//few values, so serving them does not parse and allocate the directives on every request
+var parsedCacheControls = struct {
+	sync.RWMutex
+	values map[string]CacheControl
+}{values: map[string]CacheControl{}}
+
+//ParseCacheControl parses all Cache-Control headers of header, directive names are lower cased. The directives of a
+//single header value are memoized, the returned CacheControl may be shared and must not be modified. It is nil
+//without Cache-Control header
+func ParseCacheControl(header http.Header) CacheControl {
+
+	lines := header["Cache-Control"]
+	switch len(lines) {
+	case 0:
+		return nil
+	case 1:
+		parsedCacheControls.RLock()
+		cc, ok := parsedCacheControls.values[lines[0]]
+		parsedCacheControls.RUnlock()
+		if ok {
+			return cc
+		}
+		cc = parseCacheControl(lines)
+		parsedCacheControls.Lock()
+		if len(parsedCacheControls.values) >= maxParsedCacheControls {
+			parsedCacheControls.values = map[string]CacheControl{}
+		}
+		parsedCacheControls.values[lines[0]] = cc
+		parsedCacheControls.Unlock()
+		return cc
+	}
+	return parseCacheControl(lines)
+}
+
+//parseCacheControl parses the Cache-Control header values lines
+func parseCacheControl(lines []string) CacheControl {
+
+	cc := CacheControl{}
+
+	for _, line := range lines {
+		for line != "" {
+			var part string
+			part, line = nextDirective(line)
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, value := part, ""
+			if i := strings.IndexByte(part, '='); i >= 0 {
+				name, value = part[:i], strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+			}
+			name = strings.ToLower(strings.TrimSpace(name))
+			if _, ok := cc[name]; ok {
+				//RFC 7234 5.2: the first occurrence of a duplicated directive wins
+				continue
+			}
+			cc[name] = value
+		}
+	}
+
+	return cc
+}
+
+//nextDirective returns the part of a Cache-Control header value up to the first comma outside of quoted strings and
+//the rest after the comma
+func nextDirective(line string) (string, string) {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return line[:i], line[i+1:]
+			}
+		}
+	}
+	return line, ""
+}
+
+//Has reports if directive is present
+func (c CacheControl) Has(directive string) bool {
+	_, ok := c[directive]
+	return ok
+}
+
+//Fields returns the field names listed in the quoted argument of directive, e.g. private="Set-Cookie, X-User"
+func (c CacheControl) Fields(directive string) []string {
+	if c[directive] == "" {
+		return nil
+	}
+	var fields []string
+	for _, field := range strings.Split(c[directive], ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			fields = append(fields, http.CanonicalHeaderKey(field))
+		}
+	}
+	return fields
+}
+
+//Seconds returns the delta-seconds argument of directive, invalid arguments are reported as not present
+func (c CacheControl) Seconds(directive string) (time.Duration, bool) {
+	value, ok := c[directive]
+	if !ok {
+		return 0, false
+	}
+	s, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || s < 0 {
+		return 0, false
+	}
+	return time.Duration(s) * time.Second, true
+}
+
+//cacheableMethods are the request methods which responses are stored for
+var cacheableMethods = map[string]bool{
+	http.MethodGet:  true,
+	http.MethodHead: true,
+}
+
+//CacheableMethod reports if the responses for requests with method are stored
+func CacheableMethod(method string) bool {
+	return cacheableMethods[method]
+}
+
+//heuristicallyCacheableStatus are the status codes defined as cacheable by default by RFC 7231 6.1
+var heuristicallyCacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusPartialContent:       true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+//HeuristicallyCacheable reports if responses with status are stored without explicit freshness information
+func HeuristicallyCacheable(status int) bool {
+	return heuristicallyCacheableStatus[status]
+}
+
+//ForbidsStoring reports if the directives of req or res or the Authorization header of req forbid storing res,
+//shared selects the rules for shared caches
+func ForbidsStoring(req *http.Request, res *http.Response, shared bool) bool {
+
+	reqCC := ParseCacheControl(req.Header)
+	resCC := ParseCacheControl(res.Header)
+
+	if reqCC.Has("no-store") || resCC.Has("no-store") {
+		return true
+	}
+	if shared && resCC.Has("private") && len(resCC.Fields("private")) == 0 {
+		//RFC 7234 5.2.2.6: private with field names only keeps the listed header fields from being stored
+		return true
+	}
+	if shared && req.Header.Get("Authorization") != "" &&
+		!resCC.Has("must-revalidate") && !resCC.Has("public") && !resCC.Has("s-maxage") {
+		return true
+	}
+	return false
+}
+
+//Storable reports if res may be stored for req following RFC 7234 3, shared selects the rules for shared caches
+func Storable(req *http.Request, res *http.Response, shared bool) bool {
+
+	if !CacheableMethod(req.Method) || ForbidsStoring(req, res, shared) {
+		return false
+	}
+	if _, ok := ExplicitFreshnessLifetime(res, shared); ok || ParseCacheControl(res.Header).Has("public") {
+		return true
+	}
+	return HeuristicallyCacheable(res.StatusCode)
+}
+
+//ExplicitFreshnessLifetime returns the freshness lifetime set by the origin through s-maxage, max-age or Expires
+func ExplicitFreshnessLifetime(res *http.Response, shared bool) (time.Duration, bool) {
+	return explicitFreshnessLifetime(res, ParseCacheControl(res.Header), shared)
+}
+
+//explicitFreshnessLifetime is ExplicitFreshnessLifetime with the parsed Cache-Control of res
+func explicitFreshnessLifetime(res *http.Response, cc CacheControl, shared bool) (time.Duration, bool) {
+
+	if shared {
+		if lifetime, ok := cc.Seconds("s-maxage"); ok {
+			return lifetime, true
+		}
+	}
+	if lifetime, ok := cc.Seconds("max-age"); ok {
+		return lifetime, true
+	}
+
+	if expiresHeader := res.Header.Get("Expires"); expiresHeader != "" {
+		expires, err := http.ParseTime(expiresHeader)
+		if err != nil {
+			//RFC 7234 5.3: invalid dates represent a time in the past
+			return 0, true
+		}
+		date, err := http.ParseTime(res.Header.Get("Date"))
+		if err != nil {
+			return 0, true
+		}
+		if lifetime := expires.Sub(date); lifetime > 0 {
+			return lifetime, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+//HeuristicLifetime is the freshness lifetime of responses without explicit expiration and without Last-Modified to
+//base a heuristic on (RFC 9111 4.2.2)
+const HeuristicLifetime = 5 * time.Minute
+
+//FreshnessLifetime returns how long res is fresh after its creation. Responses without explicit expiration use
+//10% of the time since Last-Modified, without Last-Modified HeuristicLifetime
+func FreshnessLifetime(res *http.Response, shared bool) time.Duration {
+	return freshnessLifetime(res, ParseCacheControl(res.Header), shared)
+}
+
+//freshnessLifetime is FreshnessLifetime with the parsed Cache-Control of res
+func freshnessLifetime(res *http.Response, cc CacheControl, shared bool) time.Duration {
+
+	if lifetime, ok := explicitFreshnessLifetime(res, cc, shared); ok {
+		return lifetime
+	}
+
+	lastModified, err := http.ParseTime(res.Header.Get("Last-Modified"))
+	if err != nil {
+		return HeuristicLifetime
+	}
+	date, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return HeuristicLifetime
+	}
+	if since := date.Sub(lastModified); since > 0 {
+		return since / 10
+	}
+	return 0
+}
+
+//RequestTimeHeader and ResponseTimeHeader hold the times a cache sent the request of a stored response and received
+//the response in RFC 3339 format with nanoseconds, the clock CurrentAge computes the age with
+const (
+	RequestTimeHeader  = "X-Cache-Request-Time"
+	ResponseTimeHeader = "X-Cache-Response-Time"
+)
+
+//CurrentAge returns the age of res at now following RFC 9111 4.2.3. Without RequestTimeHeader and ResponseTimeHeader
+//it is approximated from the Date and Age headers
+func CurrentAge(res *http.Response, now time.Time) time.Duration {
+
+	var ageValue time.Duration
+	//the headers are checked before parsing them, parse errors allocate
+	if age := strings.TrimSpace(res.Header.Get("Age")); age != "" {
+		if seconds, err := strconv.ParseInt(age, 10, 64); err == nil && seconds > 0 {
+			ageValue = time.Duration(seconds) * time.Second
+		}
+	}
+	date, dateErr := http.ParseTime(res.Header.Get("Date"))
+
+	requestHeader, responseHeader := res.Header.Get(RequestTimeHeader), res.Header.Get(ResponseTimeHeader)
+	var requestTime, responseTime time.Time
+	var requestErr, responseErr error
+	if requestHeader != "" && responseHeader != "" {
+		requestTime, requestErr = time.Parse(time.RFC3339Nano, requestHeader)
+		responseTime, responseErr = time.Parse(time.RFC3339Nano, responseHeader)
+	}
+	if requestHeader == "" || responseHeader == "" || requestErr != nil || responseErr != nil {
+		if dateErr == nil && now.Sub(date) > ageValue {
+			return now.Sub(date)
+		}
+		return ageValue
+	}
+
+	var apparentAge time.Duration
+	if dateErr == nil && responseTime.Sub(date) > 0 {
+		apparentAge = responseTime.Sub(date)
+	}
+	//the Age of the origin is corrected by the delay of the response, it was sent when the request was
+	correctedAgeValue := ageValue + responseTime.Sub(requestTime)
+	correctedInitialAge := apparentAge
+	if correctedAgeValue > correctedInitialAge {
+		correctedInitialAge = correctedAgeValue
+	}
+	return correctedInitialAge + now.Sub(responseTime)
+}
+
+//IsFresh reports if the stored response res may be served without contacting the origin
+func IsFresh(res *http.Response, shared bool, now time.Time) bool {
+
+	cc := ParseCacheControl(res.Header)
+	if cc.Has("no-cache") && len(cc.Fields("no-cache")) == 0 {
+		return false
+	}
+
+	return freshnessLifetime(res, cc, shared) > CurrentAge(res, now)
+}
+
+//RequestCacheControl parses the Cache-Control headers of req. Requests of HTTP/1.0 clients with Pragma: no-cache and
+//without Cache-Control get no-cache (RFC 9111 5.4)
+func RequestCacheControl(req *http.Request) CacheControl {
+	if _, ok := req.Header["Cache-Control"]; ok {
+		return ParseCacheControl(req.Header)
+	}
+	if pragma := req.Header.Get("Pragma"); pragma != "" && strings.Contains(strings.ToLower(pragma), "no-cache") {
+		return CacheControl{"no-cache": ""}
+	}
+	//a nil CacheControl has no directives, the common case does not allocate
+	return nil
+}
+
+//RequestForbidsStale reports if the directives of req rule out serving stale responses without validation, e.g.
+//within stale-while-revalidate: no-cache, max-age and min-fresh
+func RequestForbidsStale(req *http.Request) bool {
+	cc := RequestCacheControl(req)
+	return cc.Has("no-cache") || cc.Has("max-age") || cc.Has("min-fresh")
+}
+
+//RequestAccepts reports if req accepts a stored response with the freshness lifetime and age without validation
+//following the request directives no-cache, max-age and min-fresh (RFC 9111 5.2.1). The response has to be fresh
+func RequestAccepts(req *http.Request, lifetime time.Duration, age time.Duration) bool {
+
+	cc := RequestCacheControl(req)
+	if cc.Has("no-cache") {
+		return false
+	}
+	if maxAge, ok := cc.Seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	minFresh, _ := cc.Seconds("min-fresh")
+	return lifetime > age && lifetime-age >= minFresh
+}
+
+//RequestAcceptsStale reports if the stale response res with the freshness lifetime and age is served for req within
+//its max-stale directive (RFC 9111 5.2.1.2). Responses with must-revalidate or no-cache, or proxy-revalidate in shared
+//caches, are never served stale
+func RequestAcceptsStale(req *http.Request, res *http.Response, lifetime time.Duration, age time.Duration, shared bool) bool {
+
+	cc := RequestCacheControl(req)
+	maxStale, ok := cc["max-stale"]
+	if !ok || cc.Has("no-cache") {
+		return false
+	}
+	if maxAge, ok := cc.Seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	resCC := ParseCacheControl(res.Header)
+	if resCC.Has("must-revalidate") || resCC.Has("no-cache") || shared && resCC.Has("proxy-revalidate") {
+		return false
+	}
+	if maxStale == "" {
+		//any staleness is accepted
+		return true
+	}
+	limit, ok := cc.Seconds("max-stale")
+	return ok && age-lifetime <= limit
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseCacheControl(t *testing.T) {
+
+	header := http.Header{}
+	header.Add("Cache-Control", `Max-Age=60, private="Set-Cookie, X-Token", no-cache`)
+	header.Add("Cache-Control", "max-age=10")
+
+	cc := ParseCacheControl(header)
+
+	if maxAge, ok := cc.Seconds("max-age"); !ok || maxAge != 60*time.Second {
+		t.Error("wrong max-age", maxAge)
+	}
+	if cc["private"] != "Set-Cookie, X-Token" {
+		t.Error("wrong private", cc["private"])
+	}
+	if !cc.Has("no-cache") {
+		t.Error("no-cache not parsed")
+	}
+
+}
+
+func TestIsFresh(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	date := now.Add(-time.Minute).Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		shared bool
+		fresh  bool
+	}{
+		{"no information", http.Header{}, false, true},
+		{"no information expired", http.Header{"Date": {now.Add(-time.Hour).Format(http.TimeFormat)}}, false, false},
+		{"max-age fresh", http.Header{"Date": {date}, "Cache-Control": {"max-age=120"}}, false, true},
+		{"max-age stale", http.Header{"Date": {date}, "Cache-Control": {"max-age=30"}}, false, false},
+		{"age header", http.Header{"Date": {date}, "Age": {"100"}, "Cache-Control": {"max-age=90"}}, false, false},
+		{"s-maxage private cache", http.Header{"Date": {date}, "Cache-Control": {"max-age=120, s-maxage=30"}}, false, true},
+		{"s-maxage shared cache", http.Header{"Date": {date}, "Cache-Control": {"max-age=120, s-maxage=30"}}, true, false},
+		{"expires fresh", http.Header{"Date": {date}, "Expires": {now.Add(time.Minute).Format(http.TimeFormat)}}, false, true},
+		{"expires stale", http.Header{"Date": {date}, "Expires": {date}}, false, false},
+		{"invalid expires", http.Header{"Date": {date}, "Expires": {"0"}}, false, false},
+		{"no-cache", http.Header{"Date": {date}, "Cache-Control": {"max-age=120, no-cache"}}, false, false},
+		{"no-cache fields", http.Header{"Date": {date}, "Cache-Control": {`max-age=120, no-cache="Set-Cookie"`}}, false, true},
+		{"no-cache fields stale", http.Header{"Date": {date}, "Cache-Control": {`max-age=30, no-cache="Set-Cookie"`}}, false, false},
+		{"last-modified heuristic", http.Header{"Date": {date}, "Last-Modified": {now.Add(-time.Hour).Format(http.TimeFormat)}}, false, true},
+		{"last-modified heuristic stale", http.Header{"Date": {date}, "Last-Modified": {now.Add(-5 * time.Minute).Format(http.TimeFormat)}}, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := &http.Response{Header: test.header}
+			if fresh := IsFresh(res, test.shared, now); fresh != test.fresh {
+				t.Error("expected fresh", test.fresh, "got", fresh)
+			}
+		})
+	}
+}
+
+func TestStorable(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		method   string
+		status   int
+		header   http.Header
+		storable bool
+	}{
+		{"plain get", http.MethodGet, http.StatusOK, http.Header{}, true},
+		{"post", http.MethodPost, http.StatusOK, http.Header{"Cache-Control": {"max-age=60"}}, false},
+		{"no-store", http.MethodGet, http.StatusOK, http.Header{"Cache-Control": {"no-store"}}, false},
+		{"server error", http.MethodGet, http.StatusInternalServerError, http.Header{}, false},
+		{"server error max-age", http.MethodGet, http.StatusInternalServerError, http.Header{"Cache-Control": {"max-age=5"}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &http.Request{Method: test.method, Header: http.Header{}}
+			res := &http.Response{StatusCode: test.status, Header: test.header}
+			if storable := Storable(req, res, false); storable != test.storable {
+				t.Error("expected storable", test.storable, "got", storable)
+			}
+		})
+	}
+}
+
+func TestCurrentAge(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) string {
+		return now.Add(d).Format(time.RFC3339Nano)
+	}
+	date := now.Add(-time.Minute).Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		age    time.Duration
+	}{
+		{"nothing", http.Header{}, 0},
+		{"date", http.Header{"Date": {date}}, time.Minute},
+		{"age larger than date", http.Header{"Date": {date}, "Age": {"100"}}, 100 * time.Second},
+		{"resident time", http.Header{"Date": {date}, RequestTimeHeader: {at(-time.Minute)}, ResponseTimeHeader: {at(-time.Minute)}}, time.Minute},
+		{"response delay", http.Header{"Date": {date}, "Age": {"10"}, RequestTimeHeader: {at(-80 * time.Second)}, ResponseTimeHeader: {at(-time.Minute)}}, 90 * time.Second},
+		{"apparent age", http.Header{"Date": {now.Add(-2 * time.Minute).Format(http.TimeFormat)}, RequestTimeHeader: {at(-time.Minute)}, ResponseTimeHeader: {at(-time.Minute)}}, 2 * time.Minute},
+		{"date in the future", http.Header{"Date": {now.Format(http.TimeFormat)}, RequestTimeHeader: {at(-time.Minute)}, ResponseTimeHeader: {at(-time.Minute)}}, time.Minute},
+		{"invalid clock", http.Header{"Date": {date}, RequestTimeHeader: {"yesterday"}, ResponseTimeHeader: {at(0)}}, time.Minute},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if age := CurrentAge(&http.Response{Header: test.header}, now); age != test.age {
+				t.Error("expected age", test.age, "got", age)
+			}
+		})
+	}
+}